@@ -0,0 +1,79 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+	for _, compression := range []primitive.Compression{primitive.CompressionLz4, primitive.CompressionSnappy} {
+		t.Run(string(compression), func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			lock := &sync.Mutex{}
+			var startupRequests []*message.Startup
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				newStartupRecorderHandler(lock, &startupRequests), client.RegisterHandler, newRowsHandler("origin"),
+				client.HandshakeHandler, client.NewSystemTablesHandler("cluster1", "dc1")}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				newStartupRecorderHandler(lock, &startupRequests), client.RegisterHandler, newRowsHandler("target"),
+				client.HandshakeHandler, client.NewSystemTablesHandler("cluster1", "dc1")}
+
+			testSetup.Client.CqlClient.Compression = compression
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			query := frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Query{Query: "SELECT * FROM ks.tb"})
+			response, err := testSetup.Client.CqlConnection.SendAndReceive(query)
+			require.Nil(t, err)
+			require.IsType(t, &message.RowsResult{}, response.Body.Message)
+			rows := response.Body.Message.(*message.RowsResult)
+			require.Equal(t, message.RowSet{{[]byte("origin")}}, rows.Data)
+
+			lock.Lock()
+			defer lock.Unlock()
+			require.NotEmpty(t, startupRequests)
+			for _, startup := range startupRequests {
+				require.Equal(t, primitive.CompressionNone, startup.GetCompression())
+			}
+		})
+	}
+}
+
+func newStartupRecorderHandler(lock *sync.Mutex, startupRequests *[]*message.Startup) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		if startup, ok := request.Body.Message.(*message.Startup); ok {
+			lock.Lock()
+			*startupRequests = append(*startupRequests, startup)
+			lock.Unlock()
+		}
+		return nil
+	}
+}
+
+func newRowsHandler(name string) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		if query, ok := request.Body.Message.(*message.Query); ok && query.Query == "SELECT * FROM ks.tb" {
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.RowsResult{
+				Metadata: &message.RowsMetadata{
+					ColumnCount: 1,
+					Columns: []*message.ColumnMetadata{
+						{Keyspace: "ks", Table: "tb", Name: "name", Type: datatype.Varchar},
+					},
+				},
+				Data: message.RowSet{{[]byte(name)}},
+			})
+		}
+		return nil
+	}
+}
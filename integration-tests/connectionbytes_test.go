@@ -0,0 +1,64 @@
+package integration_tests
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/env"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testkit"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// TestConnectionBytes checks the bytes of each connection that are reported by the zdm.clients introspection table.
+func TestConnectionBytes(t *testing.T) {
+	if !env.RunMockTests {
+		t.Skip("Skipping CQLServer tests, RUN_MOCKTESTS is false")
+	}
+
+	testSetup, err := testkit.NewSetup(context.Background(), map[string]string{
+		"proxy_introspection_enabled": "true",
+		"metrics_enabled":             "false",
+	})
+	require.Nil(t, err)
+	defer testSetup.Close()
+
+	conn, err := testSetup.Connect(context.Background(), primitive.ProtocolVersion4)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	query := "SELECT bytes_received, bytes_sent, origin_bytes_received, origin_bytes_sent, target_bytes_received, " +
+		"target_bytes_sent FROM zdm.clients"
+	getBytes := func() []int64 {
+		response, err := conn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Query{Query: query}))
+		require.Nil(t, err)
+		rows, ok := response.Body.Message.(*message.RowsResult)
+		require.True(t, ok, response.Body.Message)
+		require.Equal(t, 1, len(rows.Data))
+		values := make([]int64, 0, len(rows.Data[0]))
+		for _, column := range rows.Data[0] {
+			require.Equal(t, 8, len(column))
+			values = append(values, int64(binary.BigEndian.Uint64(column)))
+		}
+		return values
+	}
+
+	before := getBytes()
+	for _, value := range before {
+		require.Greater(t, value, int64(0), "the handshake was sent to both clusters")
+	}
+
+	insert := "INSERT INTO ks.tb (k, v) VALUES (1, 'a value that is large enough to be noticed')"
+	_, err = conn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Query{Query: insert}))
+	require.Nil(t, err)
+	after := getBytes()
+	for i := range before {
+		require.Greater(t, after[i], before[i])
+	}
+	// the insert is sent to both clusters and the client sent at least the insert and the previous query
+	require.GreaterOrEqual(t, after[3]-before[3], int64(len(insert)))
+	require.GreaterOrEqual(t, after[5]-before[5], int64(len(insert)))
+	require.GreaterOrEqual(t, after[0]-before[0], int64(len(insert)+len(query)))
+}
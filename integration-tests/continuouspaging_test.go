@@ -0,0 +1,82 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const continuousPagingQuery = "SELECT * FROM ks.continuous_paging"
+
+func TestContinuousPaging(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.RegisterHandler, newContinuousPagingHandler("origin", 5), client.HandshakeHandler,
+		client.NewSystemTablesHandler("cluster1", "dc1")}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.RegisterHandler, newContinuousPagingHandler("target", 5), client.HandshakeHandler,
+		client.NewSystemTablesHandler("cluster1", "dc1")}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersionDse2)
+	require.Nil(t, err)
+
+	query := frame.NewFrame(primitive.ProtocolVersionDse2, client.ManagedStreamId, &message.Query{
+		Query: continuousPagingQuery,
+		Options: &message.QueryOptions{
+			ContinuousPagingOptions: &message.ContinuousPagingOptions{},
+		},
+	})
+	inFlightRequest, err := testSetup.Client.CqlConnection.Send(query)
+	require.Nil(t, err)
+
+	for i := int32(1); i <= 5; i++ {
+		response, err := testSetup.Client.CqlConnection.Receive(inFlightRequest)
+		require.Nil(t, err)
+		require.NotNil(t, response, "page %d was not received", i)
+		require.IsType(t, &message.RowsResult{}, response.Body.Message)
+		rows := response.Body.Message.(*message.RowsResult)
+		require.Equal(t, i, rows.Metadata.ContinuousPageNumber)
+		require.Equal(t, i == 5, rows.Metadata.LastContinuousPage)
+		require.Equal(t, message.RowSet{{[]byte("origin")}}, rows.Data)
+	}
+	require.True(t, inFlightRequest.IsDone())
+}
+
+// newContinuousPagingHandler returns a handler that streams the pages of continuousPagingQuery, the last page is
+// returned by the handler and the other pages are sent directly on the connection.
+func newContinuousPagingHandler(name string, pages int32) client.RequestHandler {
+	newPage := func(request *frame.Frame, pageNumber int32) *frame.Frame {
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.RowsResult{
+			Metadata: &message.RowsMetadata{
+				ColumnCount: 1,
+				Columns: []*message.ColumnMetadata{
+					{Keyspace: "ks", Table: "continuous_paging", Name: "name", Type: datatype.Varchar},
+				},
+				ContinuousPageNumber: pageNumber,
+				LastContinuousPage:   pageNumber == pages,
+			},
+			Data: message.RowSet{{[]byte(name)}},
+		})
+	}
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || query.Query != continuousPagingQuery {
+			return nil
+		}
+		for i := int32(1); i < pages; i++ {
+			if err := conn.Send(newPage(request, i)); err != nil {
+				return nil
+			}
+		}
+		return newPage(request, pages)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/datastax/zdm-proxy/integration-tests/setup"
 	"github.com/datastax/zdm-proxy/integration-tests/simulacron"
 	"github.com/datastax/zdm-proxy/integration-tests/utils"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
 	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
@@ -774,12 +775,81 @@ func TestRefreshTopologyEventHandler(t *testing.T) {
 	}
 }
 
+func TestTopologyEventSubscription(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	originHandler := &atomic.Value{}
+	originHandler.Store(newRefreshTopologyTestHandler("cluster1", "dc1", "127.0.3.", map[string]int{}))
+
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) (response *frame.Frame) {
+			return originHandler.Load().(client.RequestHandler)(request, conn, ctx)
+		}}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		newRefreshTopologyTestHandler("cluster2", "dc1", "127.0.4.", map[string]int{})}
+	err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	events := make(chan *zdmproxy.TopologyEvent, 10)
+	unsubscribe, err := testSetup.Proxy.SubscribeToTopologyEvents(func(event *zdmproxy.TopologyEvent) {
+		events <- event
+	})
+	require.Nil(t, err)
+
+	serverConns, err := testSetup.Origin.CqlServer.AllAcceptedClients()
+	require.Nil(t, err)
+	require.Equal(t, 1, len(serverConns))
+	peerAddress := &primitive.Inet{Addr: net.ParseIP("127.0.3.1"), Port: int32(conf.OriginPort)}
+	sendEvent := func(event message.Message) {
+		require.Nil(t, serverConns[0].Send(frame.NewFrame(primitive.ProtocolVersion4, -1, event)))
+	}
+	requireEvent := func(eventType zdmproxy.TopologyEventType) {
+		select {
+		case event := <-events:
+			require.Equal(t, eventType, event.Type)
+			require.Equal(t, common.ClusterTypeOrigin, event.Cluster)
+			require.Equal(t, "127.0.3.1", event.Host.Address.String())
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "expected topology event", eventType)
+		}
+	}
+
+	originHandler.Store(newRefreshTopologyTestHandler("cluster1", "dc1", "127.0.3.", map[string]int{"dc1": 1}))
+	sendEvent(&message.TopologyChangeEvent{ChangeType: primitive.TopologyChangeTypeNewNode, Address: peerAddress})
+	requireEvent(zdmproxy.TopologyEventHostAdded)
+
+	sendEvent(&message.StatusChangeEvent{ChangeType: primitive.StatusChangeTypeDown, Address: peerAddress})
+	requireEvent(zdmproxy.TopologyEventHostDown)
+	sendEvent(&message.StatusChangeEvent{ChangeType: primitive.StatusChangeTypeUp, Address: peerAddress})
+	requireEvent(zdmproxy.TopologyEventHostUp)
+
+	originHandler.Store(newRefreshTopologyTestHandler("cluster1", "dc1", "127.0.3.", map[string]int{}))
+	sendEvent(&message.TopologyChangeEvent{ChangeType: primitive.TopologyChangeTypeRemovedNode, Address: peerAddress})
+	requireEvent(zdmproxy.TopologyEventHostRemoved)
+
+	unsubscribe()
+	originHandler.Store(newRefreshTopologyTestHandler("cluster1", "dc1", "127.0.3.", map[string]int{"dc1": 1}))
+	sendEvent(&message.TopologyChangeEvent{ChangeType: primitive.TopologyChangeTypeNewNode, Address: peerAddress})
+	require.Eventually(t, func() bool {
+		hosts, err := testSetup.Proxy.GetOriginControlConn().GetHostsInLocalDatacenter()
+		return err == nil && len(hosts) == 2
+	}, 5*time.Second, 50*time.Millisecond)
+	select {
+	case event := <-events:
+		require.Fail(t, "unexpected topology event after unsubscribing", event)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
 func checkRegisterMessages(t *testing.T, registerMessages []*message.Register, lock *sync.Mutex) {
 	lock.Lock()
 	defer lock.Unlock()
 	require.Equal(t, 1, len(registerMessages))
 	registerMsg := registerMessages[0]
-	require.Equal(t, []primitive.EventType{primitive.EventTypeTopologyChange}, registerMsg.EventTypes)
+	require.Equal(t, []primitive.EventType{
+		primitive.EventTypeTopologyChange, primitive.EventTypeStatusChange, primitive.EventTypeSchemaChange}, registerMsg.EventTypes)
 }
 
 func groupHostsPerDc(hosts []*zdmproxy.Host) map[string][]*zdmproxy.Host {
@@ -0,0 +1,100 @@
+package integration_tests
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/env"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testkit"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// TestRequestDeadline checks that a write with a zdm-deadline-ms custom payload returns the response of ORIGIN
+// without waiting for a slow TARGET, and a WRITE_TIMEOUT error if both clusters are slow.
+func TestRequestDeadline(t *testing.T) {
+	if !env.RunMockTests {
+		t.Skip("Skipping CQLServer tests, RUN_MOCKTESTS is false")
+	}
+
+	tests := []struct {
+		name             string
+		deadlinesEnabled bool
+		originLatency    bool
+		deadline         string
+		expectedMsg      message.Message
+		fast             bool
+	}{
+		{"target slow", true, false, "100", &message.VoidResult{}, true},
+		{"both slow", true, true, "100", &message.WriteTimeout{
+			ErrorMessage: "Operation timed out - the deadline of 100ms set by the client was exceeded",
+			Consistency:  primitive.ConsistencyLevelLocalQuorum,
+			BlockFor:     1,
+			WriteType:    primitive.WriteTypeSimple,
+		}, true},
+		{"no deadline", true, false, "", &message.VoidResult{}, false},
+		{"deadline larger than latency", true, false, "5000", &message.VoidResult{}, false},
+		{"deadlines disabled", false, false, "100", &message.VoidResult{}, false},
+	}
+
+	const insert = "INSERT INTO ks.t (a) VALUES (1)"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originLatencyProbability := "0"
+			if tt.originLatency {
+				originLatencyProbability = "1"
+			}
+			deadlinesEnabled := "false"
+			if tt.deadlinesEnabled {
+				deadlinesEnabled = "true"
+			}
+			testSetup, err := testkit.NewSetup(context.Background(), map[string]string{
+				"fault_injection_enabled":                    "true",
+				"fault_injection_origin_latency_probability": originLatencyProbability,
+				"fault_injection_origin_latency_ms":          "500",
+				"fault_injection_target_latency_probability": "1",
+				"fault_injection_target_latency_ms":          "500",
+				"proxy_request_deadlines_enabled":            deadlinesEnabled,
+				"metrics_enabled":                            "false",
+			})
+			require.Nil(t, err)
+			defer testSetup.Close()
+
+			conn, err := testSetup.Connect(context.Background(), primitive.ProtocolVersion4)
+			require.Nil(t, err)
+			defer conn.Close()
+
+			// the second request reuses the stream id of the first one while TARGET is still executing it
+			for i := 0; i < 2; i++ {
+				request := frame.NewFrame(primitive.ProtocolVersion4, 1, &message.Query{
+					Query:   insert,
+					Options: &message.QueryOptions{Consistency: primitive.ConsistencyLevelLocalQuorum},
+				})
+				if tt.deadline != "" {
+					request.SetCustomPayload(map[string][]byte{zdmproxy.DeadlineCustomPayloadKey: []byte(tt.deadline)})
+				}
+				start := time.Now()
+				response, err := conn.SendAndReceive(request)
+				require.Nil(t, err)
+				require.Equal(t, tt.expectedMsg, response.Body.Message)
+				if tt.fast && i == 0 {
+					require.Less(t, int64(time.Since(start)), int64(400*time.Millisecond))
+				} else if !tt.fast {
+					require.GreaterOrEqual(t, int64(time.Since(start)), int64(500*time.Millisecond))
+				}
+			}
+
+			// the late responses of TARGET are discarded instead of being returned for the next requests
+			require.Eventually(t, func() bool {
+				return len(testSetup.Target.Executions(insert)) == 2
+			}, 5*time.Second, 10*time.Millisecond)
+			response, err := conn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 1,
+				&message.Query{Query: "SELECT * FROM ks.t"}))
+			require.Nil(t, err)
+			require.IsType(t, &message.RowsResult{}, response.Body.Message)
+		})
+	}
+}
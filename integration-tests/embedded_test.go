@@ -0,0 +1,110 @@
+package integration_tests
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/env"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/embedded"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testkit"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestEmbeddedProxy checks the hooks and the metrics registry of a proxy started with the embedded package.
+func TestEmbeddedProxy(t *testing.T) {
+	if !env.RunMockTests {
+		t.Skip("Skipping CQLServer tests, RUN_MOCKTESTS is false")
+	}
+
+	credentials := &client.AuthCredentials{Username: testkit.DefaultUsername, Password: testkit.DefaultPassword}
+	origin, err := testkit.NewCluster("origin", "127.0.0.1", 0, credentials)
+	require.Nil(t, err)
+	require.Nil(t, origin.Start(context.Background()))
+	defer origin.Close()
+	target, err := testkit.NewCluster("target", "127.0.0.1", 0, credentials)
+	require.Nil(t, err)
+	require.Nil(t, target.Start(context.Background()))
+
+	ports, err := testkit.FreePorts("127.0.0.1", 1)
+	require.Nil(t, err)
+	conf, err := config.New().ParseSettings(map[string]string{
+		"origin_contact_points":           origin.Host(),
+		"origin_port":                     strconv.Itoa(origin.Port()),
+		"origin_username":                 testkit.DefaultUsername,
+		"origin_password":                 testkit.DefaultPassword,
+		"target_contact_points":           target.Host(),
+		"target_port":                     strconv.Itoa(target.Port()),
+		"target_username":                 testkit.DefaultUsername,
+		"target_password":                 testkit.DefaultPassword,
+		"proxy_listen_address":            "127.0.0.1",
+		"proxy_listen_port":               strconv.Itoa(ports[0]),
+		"heartbeat_interval_ms":           "100",
+		"heartbeat_retry_interval_min_ms": "50",
+		"heartbeat_retry_interval_max_ms": "100",
+		"heartbeat_failure_threshold":     "2",
+		"metrics_enabled":                 "true",
+	})
+	require.Nil(t, err)
+
+	lock := &sync.Mutex{}
+	var connectedClients []net.Addr
+	var downClusters []common.ClusterType
+	registry := prometheus.NewRegistry()
+	proxy, err := embedded.NewProxy(
+		embedded.WithConfig(conf),
+		embedded.WithMetricsRegistry(registry),
+		embedded.OnClientConnect(func(clientAddress net.Addr) {
+			lock.Lock()
+			defer lock.Unlock()
+			connectedClients = append(connectedClients, clientAddress)
+		}),
+		embedded.OnClusterDown(func(cluster common.ClusterType, err error) {
+			lock.Lock()
+			defer lock.Unlock()
+			downClusters = append(downClusters, cluster)
+		}))
+	require.Nil(t, err)
+	require.Nil(t, proxy.Start(context.Background()))
+	defer proxy.Shutdown(context.Background())
+
+	cqlClient := client.NewCqlClient(net.JoinHostPort("127.0.0.1", strconv.Itoa(ports[0])), credentials)
+	conn, err := cqlClient.ConnectAndInit(context.Background(), primitive.ProtocolVersion4, client.ManagedStreamId)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	lock.Lock()
+	require.Len(t, connectedClients, 1)
+	require.Equal(t, conn.LocalAddr().String(), connectedClients[0].String())
+	lock.Unlock()
+
+	metricFamilies, err := registry.Gather()
+	require.Nil(t, err)
+	names := make([]string, 0, len(metricFamilies))
+	for _, metricFamily := range metricFamilies {
+		names = append(names, metricFamily.GetName())
+	}
+	require.Contains(t, names, "zdm_proxy_request_duration_seconds")
+
+	require.Nil(t, target.Close())
+	require.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(downClusters) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	lock.Lock()
+	require.Equal(t, []common.ClusterType{common.ClusterTypeTarget}, downClusters)
+	lock.Unlock()
+
+	shutdownCtx, cancelFn := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFn()
+	require.Nil(t, proxy.Shutdown(shutdownCtx))
+	require.Nil(t, proxy.ZdmProxy())
+}
@@ -0,0 +1,173 @@
+package integration_tests
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/cqlserver"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRequestConnectionFailover(t *testing.T) {
+	for _, streamIdVirtualization := range []bool{false, true} {
+		t.Run(fmt.Sprintf("StreamIdVirtualization-%v", streamIdVirtualization), func(t *testing.T) {
+			testRequestConnectionFailover(t, streamIdVirtualization)
+		})
+	}
+}
+
+func testRequestConnectionFailover(t *testing.T, streamIdVirtualization bool) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.RequestConnectionFailoverEnabled = true
+	conf.StreamIdVirtualizationEnabled = streamIdVirtualization
+	conf.RequestConnectionMaxStreamIds = 32768
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	secondOrigin, err := cqlserver.NewCqlServerCluster(
+		"127.0.2.1", conf.OriginPort, conf.OriginUsername, conf.OriginPassword, false)
+	require.Nil(t, err)
+	defer secondOrigin.Close()
+
+	origins := map[string]*failoverTestServer{
+		"origin1": {},
+		"origin2": {},
+	}
+	testSetup.Origin.CqlServer.RequestHandlers = origins["origin1"].handlers(
+		"origin1", NewCustomSystemTablesHandler("cluster1", "dc1", "127.0.2.", map[string]int{"dc1": 1}, ""))
+	secondOrigin.CqlServer.RequestHandlers = origins["origin2"].handlers(
+		"origin2", NewCustomSystemTablesHandler("cluster1", "dc1", "127.0.1.", map[string]int{"dc1": 1}, ""))
+	testSetup.Target.CqlServer.RequestHandlers = (&failoverTestServer{}).handlers(
+		"target", client.NewSystemTablesHandler("cluster1", "dc1"))
+
+	require.Nil(t, secondOrigin.Start())
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	response, err := testSetup.Client.CqlConnection.SendAndReceive(
+		frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Query{Query: "USE ks"}))
+	require.Nil(t, err)
+	require.IsType(t, &message.SetKeyspaceResult{}, response.Body.Message)
+	response, err = testSetup.Client.CqlConnection.SendAndReceive(
+		frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Prepare{Query: "SELECT * FROM ks.tb WHERE k = ?"}))
+	require.Nil(t, err)
+	require.IsType(t, &message.PreparedResult{}, response.Body.Message)
+
+	lostOrigin := sendFailoverTestQuery(t, testSetup)
+	otherOrigin := "origin1"
+	if lostOrigin == "origin1" {
+		otherOrigin = "origin2"
+	}
+	require.Nil(t, origins[lostOrigin].requestConn.Load().(*client.CqlServerConnection).Close())
+
+	// requests that are written before the proxy notices that the connection was lost time out
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&origins[otherOrigin].prepares) == 1
+	}, 5*time.Second, 50*time.Millisecond)
+	require.Equal(t, `"ks"`, origins[otherOrigin].keyspace.Load())
+
+	require.Equal(t, otherOrigin, sendFailoverTestQuery(t, testSetup))
+	require.False(t, testSetup.Client.CqlConnection.IsClosed())
+}
+
+// sendFailoverTestQuery returns the name of the ORIGIN server that answered the query
+func sendFailoverTestQuery(t *testing.T, testSetup *setup.CqlServerTestSetup) string {
+	response, err := testSetup.Client.CqlConnection.SendAndReceive(
+		frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Query{Query: "SELECT * FROM ks.tb"}))
+	require.Nil(t, err)
+	rows, ok := response.Body.Message.(*message.RowsResult)
+	require.True(t, ok, response.Body.Message)
+	return string(rows.Data[0][0])
+}
+
+// failoverTestServer records the request connection and the USE and PREPARE requests received by a mock server
+type failoverTestServer struct {
+	requestConn atomic.Value
+	keyspace    atomic.Value
+	prepares    int32
+}
+
+func (recv *failoverTestServer) handlers(name string, systemTablesHandler client.RequestHandler) []client.RequestHandler {
+	rowsHandler := newRowsHandler(name)
+	return []client.RequestHandler{
+		client.RegisterHandler,
+		func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+			response := rowsHandler(request, conn, ctx)
+			if response != nil {
+				recv.requestConn.Store(conn)
+			}
+			return response
+		},
+		client.NewSetKeyspaceHandler(func(keyspace string) {
+			recv.keyspace.Store(keyspace)
+		}),
+		func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+			if prepare, ok := request.Body.Message.(*message.Prepare); ok {
+				atomic.AddInt32(&recv.prepares, 1)
+				return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.PreparedResult{
+					PreparedQueryId: []byte(prepare.Query),
+				})
+			}
+			return nil
+		},
+		client.HandshakeHandler,
+		systemTablesHandler,
+	}
+}
+
+func TestDatacenterFailover(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.OriginRemoteDatacenters = "dc2"
+	conf.HeartbeatIntervalMs = 200
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	remoteOrigin, err := cqlserver.NewCqlServerCluster(
+		"127.0.2.1", conf.OriginPort, conf.OriginUsername, conf.OriginPassword, false)
+	require.Nil(t, err)
+	defer remoteOrigin.Close()
+
+	localOriginHandlers := []client.RequestHandler{
+		client.RegisterHandler, client.HeartbeatHandler, client.HandshakeHandler,
+		NewCustomSystemTablesHandler("cluster1", "dc1", "127.0.2.", map[string]int{"dc2": 1}, ""),
+	}
+	testSetup.Origin.CqlServer.RequestHandlers = localOriginHandlers
+	remoteOrigin.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.RegisterHandler, client.HeartbeatHandler, client.HandshakeHandler,
+		NewCustomSystemTablesHandler("cluster1", "dc2", "127.0.1.", map[string]int{"dc1": 1}, ""),
+	}
+
+	require.Nil(t, remoteOrigin.Start())
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	controlConn := testSetup.Proxy.GetOriginControlConn()
+	require.Equal(t, "", controlConn.GetRemoteDatacenter())
+
+	require.Nil(t, testSetup.Origin.Close())
+	require.Eventually(t, func() bool {
+		contactPoint := controlConn.GetCurrentContactPoint()
+		return controlConn.GetRemoteDatacenter() == "dc2" &&
+			contactPoint != nil && contactPoint.GetEndpointIdentifier() == "127.0.2.1:9042"
+	}, 10*time.Second, 50*time.Millisecond)
+
+	// the control connection moves back to the local datacenter once one of its hosts is reachable again
+	testSetup.Origin, err = cqlserver.NewCqlServerCluster(
+		"127.0.1.1", conf.OriginPort, conf.OriginUsername, conf.OriginPassword, false)
+	require.Nil(t, err)
+	testSetup.Origin.CqlServer.RequestHandlers = localOriginHandlers
+	require.Nil(t, testSetup.Origin.Start())
+	require.Eventually(t, func() bool {
+		contactPoint := controlConn.GetCurrentContactPoint()
+		return controlConn.GetRemoteDatacenter() == "" &&
+			contactPoint != nil && contactPoint.GetEndpointIdentifier() == "127.0.1.1:9042"
+	}, 10*time.Second, 50*time.Millisecond)
+}
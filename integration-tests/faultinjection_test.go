@@ -0,0 +1,75 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestFaultInjection(t *testing.T) {
+	tests := []struct {
+		name            string
+		configure       func(conf *config.Config)
+		expectedMsg     message.Message
+		expectedLatency time.Duration
+	}{
+		{
+			name: "target error",
+			configure: func(conf *config.Config) {
+				conf.FaultInjectionTargetErrorProbability = 1
+				conf.FaultInjectionTargetErrorType = config.FaultErrorTypeWriteTimeout
+			},
+			expectedMsg: &message.WriteTimeout{
+				ErrorMessage: "Error injected by ZDM proxy",
+				Consistency:  primitive.ConsistencyLevelLocalQuorum,
+				Received:     1,
+				BlockFor:     2,
+				WriteType:    primitive.WriteTypeSimple,
+			},
+		},
+		{
+			name: "origin latency",
+			configure: func(conf *config.Config) {
+				conf.FaultInjectionOriginLatencyProbability = 1
+				conf.FaultInjectionOriginLatencyMs = 500
+			},
+			expectedMsg:     &message.VoidResult{},
+			expectedLatency: 500 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.FaultInjectionEnabled = true
+			tt.configure(conf)
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{client.RegisterHandler,
+				client.HeartbeatHandler, client.HandshakeHandler, client.NewSystemTablesHandler("cluster1", "dc1"),
+				handleWrites}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{client.RegisterHandler,
+				client.HeartbeatHandler, client.HandshakeHandler, client.NewSystemTablesHandler("cluster2", "dc2"),
+				handleWrites}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			request := frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{
+				Query: "INSERT INTO ks.t (k) VALUES (1)",
+			})
+			start := time.Now()
+			response, err := testSetup.Client.CqlConnection.SendAndReceive(request)
+			require.Nil(t, err)
+			require.Equal(t, tt.expectedMsg, response.Body.Message)
+			require.GreaterOrEqual(t, int64(time.Since(start)), int64(tt.expectedLatency))
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package integration_tests
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/datastax/zdm-proxy/integration-tests/env"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testkit"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestFleetState starts a proxy with a migration phase that differs from the shared fleet state and checks that the
+// proxy uses the fleet state and follows its changes.
+func TestFleetState(t *testing.T) {
+	if !env.RunMockTests {
+		t.Skip("Skipping CQLServer tests, RUN_MOCKTESTS is false")
+	}
+
+	lock := &sync.Mutex{}
+	document, modifyIndex := `{"migration_phase":"DUAL_WRITE"}`, 1
+	consul := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv/zdm/state" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		fmt.Fprintf(w, `[{"Key":"zdm/state","ModifyIndex":%d,"Value":"%v"}]`,
+			modifyIndex, base64.StdEncoding.EncodeToString([]byte(document)))
+	}))
+	defer consul.Close()
+
+	testSetup, err := testkit.NewSetup(context.Background(), map[string]string{
+		"migration_phase":               "SHADOW",
+		"fleet_config_backend":          "CONSUL",
+		"fleet_config_endpoint":         consul.URL,
+		"fleet_config_key":              "zdm/state",
+		"fleet_config_poll_interval_ms": "50",
+		"metrics_enabled":               "false",
+	})
+	require.Nil(t, err)
+	defer testSetup.Close()
+
+	require.Equal(t, "DUAL_WRITE", testSetup.Proxy.GetMigrationPhaseStatus().Phase)
+
+	lock.Lock()
+	document, modifyIndex = `{"migration_phase":"DUAL_WRITE_ASYNC_READ"}`, 2
+	lock.Unlock()
+	require.Eventually(t, func() bool {
+		return testSetup.Proxy.GetMigrationPhaseStatus().Phase == "DUAL_WRITE_ASYNC_READ"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	lock.Lock()
+	document, modifyIndex = `{"migration_phase":"ORIGIN_DECOMMISSIONED"}`, 3
+	lock.Unlock()
+	require.Eventually(t, func() bool {
+		return testSetup.Proxy.GetFleetStatus().Revision == "3"
+	}, 5*time.Second, 10*time.Millisecond)
+	status := testSetup.Proxy.GetFleetStatus()
+	require.Contains(t, status.LastError, "illegal migration phase transition")
+	require.Equal(t, "DUAL_WRITE_ASYNC_READ", testSetup.Proxy.GetMigrationPhaseStatus().Phase)
+}
@@ -0,0 +1,88 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRequestConnectionHeartbeats(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.RequestConnectionHeartbeatIntervalMs = 200
+	conf.RequestConnectionHeartbeatTimeoutMs = 1000
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	var originHeartbeats, targetHeartbeats int32
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		newHeartbeatRecorderHandler(&originHeartbeats, nil), client.RegisterHandler, newRowsHandler("origin"),
+		client.HandshakeHandler, client.NewSystemTablesHandler("cluster1", "dc1")}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		newHeartbeatRecorderHandler(&targetHeartbeats, nil), client.RegisterHandler, newRowsHandler("target"),
+		client.HandshakeHandler, client.NewSystemTablesHandler("cluster1", "dc1")}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&originHeartbeats) >= 3 && atomic.LoadInt32(&targetHeartbeats) >= 3
+	}, 5*time.Second, 50*time.Millisecond)
+
+	query := frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Query{Query: "SELECT * FROM ks.tb"})
+	response, err := testSetup.Client.CqlConnection.SendAndReceive(query)
+	require.Nil(t, err)
+	require.IsType(t, &message.RowsResult{}, response.Body.Message)
+	require.False(t, testSetup.Client.CqlConnection.IsClosed())
+}
+
+func TestRequestConnectionHeartbeatFailure(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.RequestConnectionHeartbeatIntervalMs = 200
+	conf.RequestConnectionHeartbeatTimeoutMs = 200
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	// unblocks the target handler before the servers are closed
+	unresponsive := make(chan bool)
+	defer close(unresponsive)
+
+	var originHeartbeats, targetHeartbeats int32
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		newHeartbeatRecorderHandler(&originHeartbeats, nil), client.RegisterHandler,
+		client.HandshakeHandler, client.NewSystemTablesHandler("cluster1", "dc1")}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		newHeartbeatRecorderHandler(&targetHeartbeats, unresponsive), client.RegisterHandler,
+		client.HandshakeHandler, client.NewSystemTablesHandler("cluster1", "dc1")}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	require.Eventually(t, func() bool {
+		return testSetup.Client.CqlConnection.IsClosed()
+	}, 5*time.Second, 50*time.Millisecond)
+	require.Equal(t, int32(1), atomic.LoadInt32(&targetHeartbeats))
+}
+
+// newHeartbeatRecorderHandler counts the heartbeats sent by the request connections of the proxy, the heartbeats
+// are only answered once unresponsive is closed if it is not nil.
+func newHeartbeatRecorderHandler(heartbeats *int32, unresponsive chan bool) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		if _, ok := request.Body.Message.(*message.Options); !ok || request.Header.StreamId >= 0 {
+			return nil
+		}
+		atomic.AddInt32(heartbeats, 1)
+		if unresponsive != nil {
+			<-unresponsive
+			return nil
+		}
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.Supported{})
+	}
+}
@@ -0,0 +1,33 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestClientIdleTimeout(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.ProxyClientIdleTimeoutMs = 500
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, true, true, true)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	// requests keep the connection open
+	for i := 0; i < 10; i++ {
+		options := frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Options{})
+		response, err := testSetup.Client.CqlConnection.SendAndReceive(options)
+		require.Nil(t, err)
+		require.IsType(t, &message.Supported{}, response.Body.Message)
+		time.Sleep(100 * time.Millisecond)
+	}
+	require.False(t, testSetup.Client.CqlConnection.IsClosed())
+
+	require.Eventually(t, func() bool {
+		return testSetup.Client.CqlConnection.IsClosed()
+	}, 5*time.Second, 50*time.Millisecond)
+}
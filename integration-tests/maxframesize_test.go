@@ -0,0 +1,70 @@
+package integration_tests
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestMaxFrameSize(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.ProxyMaxRequestFrameSizeBytes = 4096
+	conf.ProxyMaxResponseFrameSizeBytes = 4096
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		newLargeRowsHandler(10000), client.RegisterHandler, newRowsHandler("origin"),
+		client.HandshakeHandler, client.NewSystemTablesHandler("cluster1", "dc1")}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		newLargeRowsHandler(10000), client.RegisterHandler, newRowsHandler("target"),
+		client.HandshakeHandler, client.NewSystemTablesHandler("cluster1", "dc1")}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	// the client closes its connection when it receives these errors so a new one is opened for every request
+	sendQuery := func(query string) *frame.Frame {
+		cqlConn, err := testSetup.Client.CqlClient.ConnectAndInit(context.Background(), primitive.ProtocolVersion4, 0)
+		require.Nil(t, err)
+		defer cqlConn.Close()
+		response, err := cqlConn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Query{Query: query}))
+		require.Nil(t, err)
+		return response
+	}
+
+	// the response is replaced by an error so that the request doesn't time out
+	response := sendQuery("SELECT * FROM ks.large")
+	require.IsType(t, &message.ServerError{}, response.Body.Message)
+
+	response = sendQuery("SELECT * FROM ks.tb WHERE name = '" + strings.Repeat("a", 10000) + "'")
+	require.IsType(t, &message.ProtocolError{}, response.Body.Message)
+
+	response = sendQuery("SELECT * FROM ks.tb")
+	require.IsType(t, &message.RowsResult{}, response.Body.Message)
+}
+
+func newLargeRowsHandler(size int) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		if query, ok := request.Body.Message.(*message.Query); ok && query.Query == "SELECT * FROM ks.large" {
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.RowsResult{
+				Metadata: &message.RowsMetadata{
+					ColumnCount: 1,
+					Columns: []*message.ColumnMetadata{
+						{Keyspace: "ks", Table: "large", Name: "name", Type: datatype.Varchar},
+					},
+				},
+				Data: message.RowSet{{[]byte(strings.Repeat("a", size))}},
+			})
+		}
+		return nil
+	}
+}
@@ -41,6 +41,11 @@ var nodeMetrics = []metrics.Metric{
 
 	metrics.OpenOriginConnections,
 	metrics.OpenTargetConnections,
+
+	metrics.OriginHeartbeatFailures,
+	metrics.TargetHeartbeatFailures,
+	metrics.OriginStreamIdsExhausted,
+	metrics.TargetStreamIdsExhausted,
 }
 
 var proxyMetrics = []metrics.Metric{
@@ -52,6 +57,30 @@ var proxyMetrics = []metrics.Metric{
 
 	metrics.PSCacheSize,
 	metrics.PSCacheMissCount,
+	metrics.PSCacheHitCount,
+	metrics.PSCacheEvictionCount,
+	metrics.PSCacheRePrepareOrigin,
+	metrics.PSCacheRePrepareTarget,
+	metrics.PSCacheRePrepareFailedOrigin,
+	metrics.PSCacheRePrepareFailedTarget,
+	metrics.RetriesOrigin,
+	metrics.RetriesTarget,
+	metrics.RequestConnectionFailoversOrigin,
+	metrics.RequestConnectionFailoversTarget,
+	metrics.RequestConnectionFailoversFailedOrigin,
+	metrics.RequestConnectionFailoversFailedTarget,
+	metrics.DatacenterFailoverActiveOrigin,
+	metrics.DatacenterFailoverActiveTarget,
+	metrics.BufferPoolHits,
+	metrics.BufferPoolMisses,
+	metrics.OversizedRequestFrames,
+	metrics.OversizedResponseFrames,
+	metrics.DnsResolutionFailuresOrigin,
+	metrics.DnsResolutionFailuresTarget,
+	metrics.ConsistencyLevelOverridesOrigin,
+	metrics.ConsistencyLevelOverridesTarget,
+	metrics.SpeculativeReadWins,
+	metrics.SpeculativeReadLosses,
 
 	metrics.ProxyReadsTargetDuration,
 	metrics.ProxyReadsOriginDuration,
@@ -62,6 +91,16 @@ var proxyMetrics = []metrics.Metric{
 	metrics.InFlightWrites,
 
 	metrics.OpenClientConnections,
+	metrics.IdleClientConnectionsClosed,
+
+	metrics.LwtRequestCount,
+	metrics.LwtAppliedMismatchCount,
+	metrics.CounterWriteCount,
+	metrics.TargetCircuitBreakerSkippedWrites,
+	metrics.TargetCircuitBreakerOpen,
+	metrics.ShadowWrites,
+	metrics.ShadowWritesSkipped,
+	metrics.RequestDeadlinesExceeded,
 }
 
 var allMetrics = append(proxyMetrics, nodeMetrics...)
@@ -290,13 +329,29 @@ func checkMetrics(
 	if checkNodeMetrics {
 		if asyncEnabled {
 			require.Contains(t, lines, fmt.Sprintf("%v %v", getPrometheusNameWithNodeLabel(prefix, metrics.InFlightRequestsAsync, asyncHost), 0))
+			require.Contains(t, lines, fmt.Sprintf("%v %v", getPrometheusNameWithNodeLabel(prefix, metrics.AsyncStreamIdsExhausted, asyncHost), 0))
+			require.Contains(t, lines, fmt.Sprintf("%v %v", getPrometheusNameWithNodeLabel(prefix, metrics.AsyncHeartbeatFailures, asyncHost), 0))
 		} else {
 			require.NotContains(t, lines, fmt.Sprintf("%v", getPrometheusName(prefix, metrics.InFlightRequestsAsync)))
+			require.NotContains(t, lines, fmt.Sprintf("%v", getPrometheusName(prefix, metrics.AsyncStreamIdsExhausted)))
+			require.NotContains(t, lines, fmt.Sprintf("%v", getPrometheusName(prefix, metrics.AsyncHeartbeatFailures)))
 		}
 
 		require.Contains(t, lines, fmt.Sprintf("%v{node=\"%v\"} %v", getPrometheusName(prefix, metrics.OpenOriginConnections), originHost, openOriginConns))
 		require.Contains(t, lines, fmt.Sprintf("%v{node=\"%v\"} %v", getPrometheusName(prefix, metrics.OpenTargetConnections), targetHost, openTargetConns))
 
+		for _, bytesMetric := range []struct {
+			metric metrics.Metric
+			host   string
+		}{
+			{metrics.OriginBytesSent, originHost}, {metrics.OriginBytesReceived, originHost},
+			{metrics.TargetBytesSent, targetHost}, {metrics.TargetBytesReceived, targetHost},
+		} {
+			value, err := findMetricValue(lines, fmt.Sprintf("%v ", getPrometheusNameWithNodeLabel(prefix, bytesMetric.metric, bytesMetric.host)))
+			require.Nil(t, err)
+			require.Greater(t, value, 0.0, bytesMetric.metric.GetName())
+		}
+
 		if asyncEnabled {
 			require.Contains(t, lines, fmt.Sprintf("%v{node=\"%v\"} %v", getPrometheusName(prefix, metrics.OpenAsyncConnections), asyncHost, openAsyncConns))
 			require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.AsyncReadTimeouts, asyncHost)))
@@ -324,6 +379,8 @@ func checkMetrics(
 		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.OriginReadTimeouts, originHost)))
 		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.OriginWriteTimeouts, originHost)))
 		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.OriginOtherErrors, originHost)))
+		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.OriginHeartbeatFailures, originHost)))
+		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.OriginStreamIdsExhausted, originHost)))
 		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.OriginClientTimeouts, originHost)))
 		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.OriginUnavailableErrors, originHost)))
 		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.OriginReadFailures, originHost)))
@@ -333,6 +390,8 @@ func checkMetrics(
 		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.TargetReadTimeouts, targetHost)))
 		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.TargetWriteTimeouts, targetHost)))
 		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.TargetOtherErrors, targetHost)))
+		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.TargetHeartbeatFailures, targetHost)))
+		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.TargetStreamIdsExhausted, targetHost)))
 		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.TargetClientTimeouts, targetHost)))
 		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.TargetUnavailableErrors, targetHost)))
 		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.TargetReadFailures, targetHost)))
@@ -0,0 +1,72 @@
+package integration_tests
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/env"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testkit"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMigrationPhase(t *testing.T) {
+	if !env.RunMockTests {
+		t.Skip("Skipping CQLServer tests, RUN_MOCKTESTS is false")
+	}
+
+	const insert = "INSERT INTO ks.tb (k) VALUES (1)"
+	const selectQuery = "SELECT * FROM ks.tb"
+
+	testSetup, err := testkit.NewSetup(context.Background(), map[string]string{
+		"migration_phase": "target_primary",
+		"metrics_enabled": "false",
+	})
+	require.Nil(t, err)
+	defer testSetup.Close()
+
+	send := func(conn *client.CqlClientConnection, query string) {
+		response, err := conn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Query{Query: query}))
+		require.Nil(t, err)
+		_, ok := response.Body.Message.(message.Result)
+		require.True(t, ok, response.Body.Message)
+	}
+
+	targetPrimaryConn, err := testSetup.Connect(context.Background(), primitive.ProtocolVersion4)
+	require.Nil(t, err)
+	defer targetPrimaryConn.Close()
+	send(targetPrimaryConn, selectQuery)
+	send(targetPrimaryConn, insert)
+	testSetup.Origin.AssertNotExecuted(t, selectQuery)
+	testSetup.Target.AssertExecuted(t, selectQuery)
+	testSetup.Origin.AssertExecuted(t, insert)
+	testSetup.Target.AssertExecuted(t, insert)
+
+	err = testSetup.Proxy.SetMigrationPhase(common.MigrationPhaseShadow)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "illegal migration phase transition from TARGET_PRIMARY to SHADOW")
+
+	require.Nil(t, testSetup.Proxy.SetMigrationPhase(common.MigrationPhaseOriginDecommissioned))
+	testSetup.Origin.ClearStatements()
+	testSetup.Target.ClearStatements()
+
+	decommissionedConn, err := testSetup.Connect(context.Background(), primitive.ProtocolVersion4)
+	require.Nil(t, err)
+	defer decommissionedConn.Close()
+	send(decommissionedConn, insert)
+	testSetup.Origin.AssertNotExecuted(t, insert)
+	testSetup.Target.AssertExecutedTimes(t, insert, 1)
+
+	// the connection opened in the previous phase keeps its behavior until it is closed
+	send(targetPrimaryConn, insert)
+	testSetup.Origin.AssertExecutedTimes(t, insert, 1)
+	testSetup.Target.AssertExecutedTimes(t, insert, 2)
+
+	status := testSetup.Proxy.GetMigrationPhaseStatus()
+	require.Equal(t, "ORIGIN_DECOMMISSIONED", status.Phase)
+	require.Equal(t, int32(1), status.ClientConnections["TARGET_PRIMARY"])
+	require.Equal(t, int32(1), status.ClientConnections["ORIGIN_DECOMMISSIONED"])
+}
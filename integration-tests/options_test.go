@@ -7,6 +7,7 @@ import (
 	"github.com/datastax/go-cassandra-native-protocol/primitive"
 	"github.com/datastax/zdm-proxy/integration-tests/setup"
 	"github.com/stretchr/testify/require"
+	"sync/atomic"
 	"testing"
 )
 
@@ -31,6 +32,70 @@ func TestOptionsShouldComeFromTarget(t *testing.T) {
 
 }
 
+func TestOptionsAnsweredByProxy(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.ProxyAnswerOptions = true
+	conf.ProxySupportedOptions = "CUSTOM=a,b"
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	originOptions := map[string][]string{
+		"FROM":              {"origin"},
+		"COMPRESSION":       {"snappy", "lz4", "zstd"},
+		"PROTOCOL_VERSIONS": {"3/v3", "4/v4", "5/v5"},
+	}
+	targetOptions := map[string][]string{
+		"FROM":              {"target"},
+		"PROTOCOL_VERSIONS": {"4/v4", "5/v5"},
+	}
+	originRequests, targetRequests := new(int32), new(int32)
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		newCountingOptionsHandler(originOptions, originRequests), client.RegisterHandler, client.HandshakeHandler,
+		client.NewSystemTablesHandler("cluster2", "dc2")}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		newCountingOptionsHandler(targetOptions, targetRequests), client.RegisterHandler, client.HandshakeHandler,
+		client.NewSystemTablesHandler("cluster1", "dc1")}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+	originRequestsBefore, targetRequestsBefore := atomic.LoadInt32(originRequests), atomic.LoadInt32(targetRequests)
+
+	request := frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Options{})
+	response, err := testSetup.Client.CqlConnection.SendAndReceive(request)
+	require.Nil(t, err)
+	require.IsType(t, &message.Supported{}, response.Body.Message)
+	supported := response.Body.Message.(*message.Supported)
+	require.Equal(t, map[string][]string{
+		"FROM":              {"origin"},
+		"COMPRESSION":       {"lz4", "snappy"},
+		"PROTOCOL_VERSIONS": {"4/v4"},
+		"CUSTOM":            {"a", "b"},
+	}, supported.Options)
+
+	require.Equal(t, originRequestsBefore, atomic.LoadInt32(originRequests), "OPTIONS forwarded to origin")
+	require.Equal(t, targetRequestsBefore, atomic.LoadInt32(targetRequests), "OPTIONS forwarded to target")
+}
+
+// newCountingOptionsHandler answers OPTIONS requests with options and counts them
+func newCountingOptionsHandler(options map[string][]string, requests *int32) client.RequestHandler {
+	return func(
+		request *frame.Frame,
+		conn *client.CqlServerConnection,
+		ctx client.RequestHandlerContext,
+	) (response *frame.Frame) {
+		if _, ok := request.Body.Message.(*message.Options); ok {
+			atomic.AddInt32(requests, 1)
+			response = frame.NewFrame(
+				request.Header.Version,
+				request.Header.StreamId,
+				&message.Supported{Options: options},
+			)
+		}
+		return
+	}
+}
+
 func newOptionsHandler(from string) client.RequestHandler {
 	return func(
 		request *frame.Frame,
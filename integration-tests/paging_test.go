@@ -0,0 +1,101 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+)
+
+// TestPagingStateAfterPrimaryClusterChange pages through a result while the primary cluster changes, the next page
+// has to be requested from the cluster that returned the first one.
+func TestPagingStateAfterPrimaryClusterChange(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	lock := &sync.Mutex{}
+	receivedPagingStates := map[string][]string{}
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		newPagingHandler("origin", lock, receivedPagingStates), client.RegisterHandler,
+		client.HandshakeHandler, client.NewSystemTablesHandler("cluster1", "dc1")}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		newPagingHandler("target", lock, receivedPagingStates), client.RegisterHandler,
+		client.HandshakeHandler, client.NewSystemTablesHandler("cluster1", "dc1")}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	queryPage := func(pagingState []byte) *message.RowsResult {
+		query := frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Query{
+			Query:   "SELECT * FROM ks.paged",
+			Options: &message.QueryOptions{PageSize: 1, PagingState: pagingState},
+		})
+		response, err := testSetup.Client.CqlConnection.SendAndReceive(query)
+		require.Nil(t, err)
+		require.IsType(t, &message.RowsResult{}, response.Body.Message)
+		return response.Body.Message.(*message.RowsResult)
+	}
+
+	firstPage := queryPage(nil)
+	require.Equal(t, message.RowSet{{[]byte("origin")}}, firstPage.Data)
+	require.NotNil(t, firstPage.Metadata.PagingState)
+	require.NotEqual(t, []byte("origin-page-2"), firstPage.Metadata.PagingState, "paging state should be tagged")
+
+	testSetup.Proxy.Shutdown()
+	newConf := *conf
+	newConf.PrimaryCluster = config.PrimaryClusterTarget
+	testSetup.Proxy, err = setup.NewProxyInstanceWithConfig(&newConf)
+	require.Nil(t, err)
+	err = testSetup.Client.Connect(primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	secondPage := queryPage(firstPage.Metadata.PagingState)
+	require.Equal(t, message.RowSet{{[]byte("origin")}}, secondPage.Data)
+	require.Nil(t, secondPage.Metadata.PagingState)
+
+	// new queries are sent to the new primary cluster
+	firstPage = queryPage(nil)
+	require.Equal(t, message.RowSet{{[]byte("target")}}, firstPage.Data)
+
+	lock.Lock()
+	defer lock.Unlock()
+	require.Equal(t, []string{"origin-page-2"}, receivedPagingStates["origin"])
+	require.Empty(t, receivedPagingStates["target"])
+}
+
+// newPagingHandler returns a result of two pages, the paging state received by each cluster is recorded.
+func newPagingHandler(name string, lock *sync.Mutex, receivedPagingStates map[string][]string) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || query.Query != "SELECT * FROM ks.paged" {
+			return nil
+		}
+
+		var pagingState []byte
+		if query.Options == nil || query.Options.PagingState == nil {
+			pagingState = []byte(name + "-page-2")
+		} else {
+			lock.Lock()
+			receivedPagingStates[name] = append(receivedPagingStates[name], string(query.Options.PagingState))
+			lock.Unlock()
+		}
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.RowsResult{
+			Metadata: &message.RowsMetadata{
+				ColumnCount: 1,
+				Columns: []*message.ColumnMetadata{
+					{Keyspace: "ks", Table: "paged", Name: "name", Type: datatype.Varchar},
+				},
+				PagingState: pagingState,
+			},
+			Data: message.RowSet{{[]byte(name)}},
+		})
+	}
+}
@@ -575,6 +575,8 @@ func TestUnpreparedIdReplacement(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
 			conf.ReadMode = test.readMode
+			// UNPREPARED responses are only returned to the client when automatic re-preparation is disabled
+			conf.ReprepareOnUnprepared = false
 			dualReadsEnabled := test.readMode == config.ReadModeDualAsyncOnSecondary
 			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
 			require.Nil(t, err)
@@ -859,6 +861,156 @@ func TestUnpreparedIdReplacement(t *testing.T) {
 	}
 }
 
+func TestUnpreparedAutoReprepare(t *testing.T) {
+	type test struct {
+		name             string
+		query            string
+		read             bool
+		originUnprepared bool
+		targetUnprepared bool
+	}
+	tests := []test{
+		{
+			"reads_origin_unprepared",
+			"SELECT * FROM ks1.tb1",
+			true,
+			true,
+			false,
+		},
+		{
+			"writes_origin_unprepared",
+			"INSERT INTO ks1.tb1 (key, value) VALUES ('key', 'value')",
+			false,
+			true,
+			false,
+		},
+		{
+			"writes_target_unprepared",
+			"INSERT INTO ks1.tb1 (key, value) VALUES ('key', 'value')",
+			false,
+			false,
+			true,
+		},
+		{
+			"writes_both_unprepared",
+			"INSERT INTO ks1.tb1 (key, value) VALUES ('key', 'value')",
+			false,
+			true,
+			true,
+		}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.ReprepareOnUnprepared = true
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			originPreparedId := []byte{153, 7, 36, 50, 225, 104, 157, 89, 199, 177, 239, 231, 82, 201, 142, 253}
+			targetPreparedId := []byte{162, 8, 36, 51, 225, 104, 157, 89, 199, 177, 239, 231, 82, 201, 142, 253}
+
+			originLock := &sync.Mutex{}
+			originBatchMessages := make([]*message.Batch, 0)
+			originExecuteMessages := make([]*message.Execute, 0)
+			originPrepareMessages := make([]*message.Prepare, 0)
+			originKey := message.Column{0, 1}
+			originValue := message.Column{24, 51, 2}
+
+			targetLock := &sync.Mutex{}
+			targetBatchMessages := make([]*message.Batch, 0)
+			targetExecuteMessages := make([]*message.Execute, 0)
+			targetPrepareMessages := make([]*message.Prepare, 0)
+			targetKey := message.Column{2, 3, 4}
+			targetValue := message.Column{6, 121, 23}
+			originCtx := map[string]interface{}{}
+			targetCtx := map[string]interface{}{}
+
+			testSetup.Origin.CqlServer.RequestHandlers = []client2.RequestHandler{
+				client2.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+				NewPreparedTestHandler(originLock, &originPrepareMessages, &originExecuteMessages, &originBatchMessages,
+					"", originPreparedId, nil, originKey, originValue, originCtx, test.originUnprepared,
+					nil, nil, false)}
+			testSetup.Target.CqlServer.RequestHandlers = []client2.RequestHandler{
+				client2.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+				NewPreparedTestHandler(targetLock, &targetPrepareMessages, &targetExecuteMessages, &targetBatchMessages,
+					"", targetPreparedId, nil, targetKey, targetValue, targetCtx, test.targetUnprepared,
+					nil, nil, false)}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			prepareMsg := &message.Prepare{
+				Query:    test.query,
+				Keyspace: "",
+			}
+
+			prepareResp, err := testSetup.Client.CqlConnection.SendAndReceive(
+				frame.NewFrame(primitive.ProtocolVersion4, 10, prepareMsg))
+			require.Nil(t, err)
+
+			preparedResult, ok := prepareResp.Body.Message.(*message.PreparedResult)
+			require.True(t, ok, "prepared result was type %T", preparedResult)
+			require.Equal(t, originPreparedId, preparedResult.PreparedQueryId)
+
+			executeMsg := &message.Execute{
+				QueryId:          originPreparedId,
+				ResultMetadataId: nil,
+				Options:          &message.QueryOptions{},
+			}
+
+			// the proxy re-prepares the statement on its own so the client never sees UNPREPARED
+			executeResp, err := testSetup.Client.CqlConnection.SendAndReceive(
+				frame.NewFrame(primitive.ProtocolVersion4, 20, executeMsg))
+			require.Nil(t, err)
+
+			rowsResult, ok := executeResp.Body.Message.(*message.RowsResult)
+			require.True(t, ok, "rows result was type %T", executeResp.Body.Message)
+			require.Equal(t, message.Row{originKey, originValue}, rowsResult.Data[0])
+
+			originLock.Lock()
+			defer originLock.Unlock()
+			targetLock.Lock()
+			defer targetLock.Unlock()
+
+			expectedOriginPrepares := 1
+			expectedOriginExecutes := 1
+			if test.originUnprepared {
+				expectedOriginPrepares++
+				expectedOriginExecutes++
+				require.Equal(t, 1, originCtx["UNPREPARED_"+string(originPreparedId)])
+			} else {
+				require.Equal(t, nil, originCtx["UNPREPARED_"+string(originPreparedId)])
+			}
+			require.Equal(t, 1, originCtx["ROWS_"+string(originPreparedId)])
+			require.Equal(t, expectedOriginPrepares, len(originPrepareMessages))
+			require.Equal(t, expectedOriginExecutes, len(originExecuteMessages))
+			for _, prepare := range originPrepareMessages {
+				require.Equal(t, prepareMsg, prepare)
+			}
+
+			expectedTargetPrepares := 1
+			expectedTargetExecutes := 0
+			if !test.read {
+				expectedTargetExecutes = 1
+				if test.targetUnprepared {
+					expectedTargetPrepares++
+					expectedTargetExecutes++
+					require.Equal(t, 1, targetCtx["UNPREPARED_"+string(targetPreparedId)])
+				} else {
+					require.Equal(t, nil, targetCtx["UNPREPARED_"+string(targetPreparedId)])
+				}
+				require.Equal(t, 1, targetCtx["ROWS_"+string(targetPreparedId)])
+			}
+			require.Equal(t, expectedTargetPrepares, len(targetPrepareMessages))
+			require.Equal(t, expectedTargetExecutes, len(targetExecuteMessages))
+			for _, execute := range targetExecuteMessages {
+				require.Equal(t, targetPreparedId, execute.QueryId)
+			}
+		})
+	}
+}
+
 func NewPreparedTestHandler(
 	lock *sync.Mutex, preparedMessages *[]*message.Prepare, executeMessages *[]*message.Execute, batchMessages *[]*message.Batch,
 	batchQuery string, preparedId []byte, batchPreparedId []byte, key message.Column, value message.Column, context map[string]interface{}, unpreparedTest bool,
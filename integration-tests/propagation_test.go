@@ -0,0 +1,55 @@
+package integration_tests
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/env"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testkit"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// TestWarningsPolicy checks which warnings of the clusters are returned to the client for a write that is sent to
+// both clusters, depending on ZDM_WARNINGS_POLICY.
+func TestWarningsPolicy(t *testing.T) {
+	if !env.RunMockTests {
+		t.Skip("Skipping CQLServer tests, RUN_MOCKTESTS is false")
+	}
+
+	tests := []struct {
+		policy   string
+		warnings []string
+	}{
+		{"PRIMARY_ONLY", []string{"origin warning"}},
+		{"MERGE", []string{"origin warning", "Batch for [ks.tb] is of size 6KiB, exceeding specified threshold"}},
+		{"STRIP", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			testSetup, err := testkit.NewSetup(context.Background(), map[string]string{
+				"warnings_policy": tt.policy,
+				"metrics_enabled": "false",
+			})
+			require.Nil(t, err)
+			defer testSetup.Close()
+
+			query := "INSERT INTO ks.tb (k, v) VALUES (1, 'a')"
+			testSetup.Origin.Prime(&testkit.Prime{
+				Query: query, Response: &message.VoidResult{}, Warnings: []string{"origin warning"}})
+			testSetup.Target.Prime(&testkit.Prime{
+				Query: query, Response: &message.VoidResult{},
+				Warnings: []string{"Batch for [ks.tb] is of size 6KiB, exceeding specified threshold"}})
+
+			conn, err := testSetup.Connect(context.Background(), primitive.ProtocolVersion4)
+			require.Nil(t, err)
+			defer conn.Close()
+
+			response, err := conn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Query{Query: query}))
+			require.Nil(t, err)
+			require.IsType(t, &message.VoidResult{}, response.Body.Message)
+			require.Equal(t, tt.warnings, response.Body.Warnings)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package integration_tests
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/env"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testkit"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// TestProtocolVersionTranslation checks that v4 clients can use the proxy when TARGET only supports v3 if
+// ZDM_PROTOCOL_VERSION_TRANSLATION_ENABLED is enabled, and that they are forced to downgrade otherwise.
+func TestProtocolVersionTranslation(t *testing.T) {
+	if !env.RunMockTests {
+		t.Skip("Skipping CQLServer tests, RUN_MOCKTESTS is false")
+	}
+
+	newSetup := func(t *testing.T, translationEnabled string) *testkit.Setup {
+		credentials := &client.AuthCredentials{Username: testkit.DefaultUsername, Password: testkit.DefaultPassword}
+		origin, err := testkit.NewCluster("origin", "127.0.0.1", 0, credentials)
+		require.Nil(t, err)
+		target, err := testkit.NewCluster("target", "127.0.0.1", 0, credentials)
+		require.Nil(t, err)
+		target.CqlServer.RequestHandlers = append(
+			[]client.RequestHandler{testkit.MaxProtocolVersionHandler(primitive.ProtocolVersion3)},
+			target.CqlServer.RequestHandlers...)
+
+		testSetup := testkit.NewSetupWithClusters(origin, target)
+		err = testSetup.Start(context.Background(), map[string]string{
+			"primary_cluster":                      "TARGET",
+			"protocol_version_translation_enabled": translationEnabled,
+			"metrics_enabled":                      "false",
+		})
+		if err != nil {
+			testSetup.Close()
+		}
+		require.Nil(t, err)
+		return testSetup
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		testSetup := newSetup(t, "true")
+		defer testSetup.Close()
+
+		query := "SELECT v FROM ks.tb"
+		columns := []*message.ColumnMetadata{{Keyspace: "ks", Table: "tb", Name: "v", Type: datatype.Varchar}}
+		testSetup.Target.PrimeRows(query, columns, message.RowSet{{[]byte("target")}})
+
+		conn, err := testSetup.Connect(context.Background(), primitive.ProtocolVersion4)
+		require.Nil(t, err)
+		defer conn.Close()
+
+		response, err := conn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Query{Query: query}))
+		require.Nil(t, err)
+		require.Equal(t, primitive.ProtocolVersion4, response.Header.Version)
+		rows, ok := response.Body.Message.(*message.RowsResult)
+		require.True(t, ok, response.Body.Message)
+		require.Equal(t, message.RowSet{{[]byte("target")}}, rows.Data)
+
+		insert := "INSERT INTO ks.tb (k, v) VALUES (1, 'a')"
+		response, err = conn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Query{Query: insert}))
+		require.Nil(t, err)
+		require.IsType(t, &message.VoidResult{}, response.Body.Message)
+		testSetup.Origin.AssertExecuted(t, insert)
+		testSetup.Target.AssertExecuted(t, insert)
+
+		// unset values can't be sent to a v3 cluster
+		unsetInsert := "INSERT INTO ks.tb (k, v) VALUES (?, ?)"
+		response, err = conn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Query{
+			Query: unsetInsert,
+			Options: &message.QueryOptions{
+				PositionalValues: []*primitive.Value{primitive.NewValue([]byte{0, 0, 0, 1}), primitive.NewUnsetValue()}},
+		}))
+		require.Nil(t, err)
+		require.IsType(t, &message.Invalid{}, response.Body.Message)
+		testSetup.Origin.AssertNotExecuted(t, unsetInsert)
+		testSetup.Target.AssertNotExecuted(t, unsetInsert)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		testSetup := newSetup(t, "false")
+		defer testSetup.Close()
+
+		_, err := testSetup.Connect(context.Background(), primitive.ProtocolVersion4)
+		require.NotNil(t, err)
+
+		conn, err := testSetup.Connect(context.Background(), primitive.ProtocolVersion3)
+		require.Nil(t, err)
+		conn.Close()
+	})
+}
@@ -0,0 +1,60 @@
+package integration_tests
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/env"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testkit"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLargeReadResponseWarning checks that a warning is logged for the read responses that exceed
+// ZDM_PROXY_LARGE_RESPONSE_WARNING_THRESHOLD_BYTES, including the responses of async reads.
+func TestLargeReadResponseWarning(t *testing.T) {
+	if !env.RunMockTests {
+		t.Skip("Skipping CQLServer tests, RUN_MOCKTESTS is false")
+	}
+
+	buffer := createLogHooks(log.WarnLevel)
+	defer log.StandardLogger().ReplaceHooks(make(log.LevelHooks))
+
+	testSetup, err := testkit.NewSetup(context.Background(), map[string]string{
+		"read_mode": "DUAL_ASYNC_ON_SECONDARY",
+		"proxy_large_response_warning_threshold_bytes": "1000",
+		"metrics_enabled": "false",
+	})
+	require.Nil(t, err)
+	defer testSetup.Close()
+
+	query := "SELECT v FROM ks.tb"
+	columns := []*message.ColumnMetadata{{Keyspace: "ks", Table: "tb", Name: "v", Type: datatype.Varchar}}
+	largeRows := message.RowSet{}
+	for i := 0; i < 100; i++ {
+		largeRows = append(largeRows, message.Row{[]byte(strings.Repeat("a", 50))})
+	}
+	testSetup.Origin.PrimeRows(query, columns, message.RowSet{{[]byte("a")}})
+	testSetup.Target.PrimeRows(query, columns, largeRows)
+
+	conn, err := testSetup.Connect(context.Background(), primitive.ProtocolVersion4)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	response, err := conn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Query{Query: query}))
+	require.Nil(t, err)
+	rows, ok := response.Body.Message.(*message.RowsResult)
+	require.True(t, ok, response.Body.Message)
+	require.Equal(t, 1, len(rows.Data))
+
+	require.Eventually(t, func() bool {
+		return strings.Contains(buffer.String(), "Response of TARGET (async) to a read of ks.tb is larger than")
+	}, 5*time.Second, 50*time.Millisecond, buffer.String())
+	require.Contains(t, buffer.String(), "100 rows")
+	require.NotContains(t, buffer.String(), "Response of ORIGIN")
+}
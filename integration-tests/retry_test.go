@@ -0,0 +1,96 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/cqlserver"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+)
+
+func TestReadRetryOnAnotherHost(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.OriginRetryMaxAttempts = 1
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	secondOrigin, err := cqlserver.NewCqlServerCluster(
+		"127.0.2.1", conf.OriginPort, conf.OriginUsername, conf.OriginPassword, false)
+	require.Nil(t, err)
+	defer secondOrigin.Close()
+
+	// the read fails on the first connection that receives it, i.e. the request connection of the client
+	overloaded := &overloadedConnection{}
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		overloaded.handler("origin1"),
+		client.RegisterHandler,
+		client.HandshakeHandler,
+		NewCustomSystemTablesHandler("cluster1", "dc1", "127.0.2.", map[string]int{"dc1": 1}, ""),
+	}
+	secondOrigin.CqlServer.RequestHandlers = []client.RequestHandler{
+		overloaded.handler("origin2"),
+		client.RegisterHandler,
+		client.HandshakeHandler,
+		NewCustomSystemTablesHandler("cluster1", "dc1", "127.0.1.", map[string]int{"dc1": 1}, ""),
+	}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		newRowsHandler("target"),
+		client.RegisterHandler,
+		client.HandshakeHandler,
+		client.NewSystemTablesHandler("cluster1", "dc1"),
+	}
+
+	require.Nil(t, secondOrigin.Start())
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	for i := 0; i < 2; i++ {
+		response, err := testSetup.Client.CqlConnection.SendAndReceive(
+			frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Query{Query: "SELECT * FROM ks.tb"}))
+		require.Nil(t, err)
+		rows, ok := response.Body.Message.(*message.RowsResult)
+		require.True(t, ok, response.Body.Message)
+		require.NotEqual(t, overloaded.getServer(), string(rows.Data[0][0]))
+	}
+}
+
+// overloadedConnection answers the reads received on the first connection with OVERLOADED, the reads received on the
+// other connections return the name of the server
+type overloadedConnection struct {
+	lock   sync.Mutex
+	conn   *client.CqlServerConnection
+	server string
+}
+
+func (recv *overloadedConnection) handler(server string) client.RequestHandler {
+	rowsHandler := newRowsHandler(server)
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		response := rowsHandler(request, conn, ctx)
+		if response == nil {
+			return nil
+		}
+		recv.lock.Lock()
+		defer recv.lock.Unlock()
+		if recv.conn == nil {
+			recv.conn = conn
+			recv.server = server
+		}
+		if recv.conn == conn {
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.Overloaded{
+				ErrorMessage: "overloaded",
+			})
+		}
+		return response
+	}
+}
+
+func (recv *overloadedConnection) getServer() string {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.server
+}
@@ -122,18 +122,30 @@ func testHttpEndpointsWithProxyInitialized(
 	require.Equal(t, http.StatusOK, statusCode)
 	require.NotNil(t, report.OriginStatus)
 	require.NotNil(t, report.TargetStatus)
+	for _, controlConnStatus := range []*health.ControlConnStatus{report.OriginStatus, report.TargetStatus} {
+		require.NotNil(t, controlConnStatus.LastTopologyRefresh)
+		require.GreaterOrEqual(t, controlConnStatus.SecondsSinceLastTopologyRefresh, float64(0))
+		controlConnStatus.LastTopologyRefresh = nil
+		controlConnStatus.SecondsSinceLastTopologyRefresh = 0
+	}
 	require.Equal(t, &health.ControlConnStatus{
 		Addr:                  fmt.Sprintf("%s:%d", simulacronSetup.Origin.GetInitialContactPoint(), 9042),
 		CurrentFailureCount:   0,
 		FailureCountThreshold: conf.HeartbeatFailureThreshold,
+		UsableHosts:           1,
 		Status:                health.UP,
 	}, report.OriginStatus)
 	require.Equal(t, &health.ControlConnStatus{
 		Addr:                  fmt.Sprintf("%s:%d", simulacronSetup.Target.GetInitialContactPoint(), 9042),
 		CurrentFailureCount:   0,
 		FailureCountThreshold: conf.HeartbeatFailureThreshold,
+		UsableHosts:           1,
 		Status:                health.UP,
 	}, report.TargetStatus)
+	require.Equal(t, &health.ListenerStatus{
+		Addr:   fmt.Sprintf("127.0.0.1:%d", conf.ProxyListenPort),
+		Status: health.UP,
+	}, report.ListenerStatus)
 	require.Equal(t, health.UP, report.Status)
 }
 
@@ -0,0 +1,78 @@
+package integration_tests
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/zdm-proxy/integration-tests/env"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testkit"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+const schemaColumnsQuery = "SELECT keyspace_name, table_name, column_name, kind, type FROM system_schema.columns"
+
+var schemaColumnsMetadata = []*message.ColumnMetadata{
+	{Keyspace: "system_schema", Table: "columns", Name: "keyspace_name", Type: datatype.Varchar},
+	{Keyspace: "system_schema", Table: "columns", Name: "table_name", Type: datatype.Varchar},
+	{Keyspace: "system_schema", Table: "columns", Name: "column_name", Type: datatype.Varchar},
+	{Keyspace: "system_schema", Table: "columns", Name: "kind", Type: datatype.Varchar},
+	{Keyspace: "system_schema", Table: "columns", Name: "type", Type: datatype.Varchar},
+}
+
+func schemaColumnRow(keyspace string, table string, column string, kind string, typ string) message.Row {
+	return message.Row{[]byte(keyspace), []byte(table), []byte(column), []byte(kind), []byte(typ)}
+}
+
+// TestSchemaDrift checks that the schemas of ORIGIN and TARGET are compared with the control connections when
+// ZDM_SCHEMA_DRIFT_CHECK_INTERVAL_MS is set and that the report only contains the keyspaces in scope.
+func TestSchemaDrift(t *testing.T) {
+	if !env.RunMockTests {
+		t.Skip("Skipping CQLServer tests, RUN_MOCKTESTS is false")
+	}
+
+	testSetup, err := testkit.NewSetup(context.Background(), map[string]string{
+		"schema_drift_check_interval_ms": "3600000",
+		"schema_drift_keyspaces":         "ks",
+		"metrics_enabled":                "false",
+	})
+	require.Nil(t, err)
+	defer testSetup.Close()
+
+	// the first comparison runs in the background after the proxy has started
+	require.Eventually(t, func() bool {
+		return testSetup.Proxy.GetSchemaDriftReport().LastCheck != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Empty(t, testSetup.Proxy.GetSchemaDriftReport().Differences)
+
+	testSetup.Origin.PrimeRows(schemaColumnsQuery, schemaColumnsMetadata, message.RowSet{
+		schemaColumnRow("ks", "users", "id", "partition_key", "uuid"),
+		schemaColumnRow("ks", "users", "email", "regular", "text"),
+		schemaColumnRow("ks", "orders", "id", "partition_key", "uuid"),
+		schemaColumnRow("other_ks", "tb", "id", "partition_key", "uuid"),
+	})
+	testSetup.Target.PrimeRows(schemaColumnsQuery, schemaColumnsMetadata, message.RowSet{
+		schemaColumnRow("ks", "users", "id", "partition_key", "int"),
+		schemaColumnRow("ks", "orders", "id", "partition_key", "uuid"),
+	})
+
+	report, err := testSetup.Proxy.CheckSchemaDrift(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, []string{"ks"}, report.Keyspaces)
+	require.Equal(t, []*zdmproxy.SchemaDifference{
+		{Kind: zdmproxy.SchemaDifferenceColumnTypeMismatch, Keyspace: "ks", Table: "users", Column: "id",
+			Origin: "uuid", Target: "int"},
+		{Kind: zdmproxy.SchemaDifferenceMissingColumn, Cluster: common.ClusterTypeTarget, Keyspace: "ks",
+			Table: "users", Column: "email"},
+	}, report.Differences)
+
+	testSetup.Target.PrimeError(schemaColumnsQuery, &message.Unauthorized{ErrorMessage: "no access"})
+	_, err = testSetup.Proxy.CheckSchemaDrift(context.Background())
+	require.NotNil(t, err)
+	report = testSetup.Proxy.GetSchemaDriftReport()
+	require.Contains(t, report.LastError, "could not fetch the columns of TARGET")
+	require.Len(t, report.Differences, 2)
+}
@@ -393,6 +393,8 @@ func NewTestConfig(originHost string, targetHost string) *config.Config {
 
 	conf.OriginEnableHostAssignment = true
 	conf.TargetEnableHostAssignment = true
+	conf.OriginHostSelectionPolicy = config.HostSelectionPolicyRoundRobin
+	conf.TargetHostSelectionPolicy = config.HostSelectionPolicyRoundRobin
 
 	conf.OriginContactPoints = originHost
 	conf.OriginUsername = "cassandra"
@@ -409,7 +411,13 @@ func NewTestConfig(originHost string, targetHost string) *config.Config {
 	conf.MetricsAddress = "localhost"
 	conf.MetricsPort = 14001
 	conf.ProxyListenPort = 14002
+	conf.ProxyListenSocketPermissions = "0660"
+	conf.ProxyListenProxyProtocol = config.ProxyProtocolModeDisabled
+	conf.ProxyListenSocketProxyProtocol = config.ProxyProtocolModeDisabled
+	conf.ProxyProtocolHeaderTimeoutMs = 5000
 	conf.ProxyListenAddress = "localhost"
+	conf.ProxyClientIdleTimeoutMs = 0
+	conf.ProxyClientTcpKeepAliveMs = 15000
 
 	conf.OriginConnectionTimeoutMs = 30000
 	conf.TargetConnectionTimeoutMs = 30000
@@ -419,6 +427,14 @@ func NewTestConfig(originHost string, targetHost string) *config.Config {
 	conf.HeartbeatRetryIntervalMinMs = 250
 	conf.HeartbeatRetryBackoffFactor = 2
 	conf.HeartbeatFailureThreshold = 1
+	conf.HeartbeatRefreshContactPoints = true
+
+	conf.RequestConnectionHeartbeatIntervalMs = 30000
+	conf.RequestConnectionHeartbeatTimeoutMs = 5000
+	conf.RequestConnectionFailoverEnabled = false
+	conf.RequestConnectionFailoverMaxAttempts = 3
+	conf.OriginRemoteDatacenters = ""
+	conf.TargetRemoteDatacenters = ""
 
 	conf.MetricsOriginLatencyBucketsMs = "1, 4, 7, 10, 25, 40, 60, 80, 100, 150, 250, 500, 1000, 2500, 5000, 10000, 15000"
 	conf.MetricsTargetLatencyBucketsMs = "1, 4, 7, 10, 25, 40, 60, 80, 100, 150, 250, 500, 1000, 2500, 5000, 10000, 15000"
@@ -435,25 +451,86 @@ func NewTestConfig(originHost string, targetHost string) *config.Config {
 	conf.ResponseReadBufferSizeBytes = 32768
 
 	conf.ProxyMaxClientConnections = 1000
+	conf.ProxyMaxPreparedStatementCacheSize = 5000
+	conf.ProxyMaxRequestFrameSizeBytes = 268435456
+	conf.ProxyMaxResponseFrameSizeBytes = 268435456
+	conf.ProxyAnswerOptions = false
 
 	conf.RequestResponseMaxWorkers = -1
 	conf.WriteMaxWorkers = -1
 	conf.ReadMaxWorkers = -1
 	conf.ListenerMaxWorkers = -1
+	conf.SchedulerShards = -1
+	conf.WriteBufferPoolMaxBufferSizeBytes = 1048576
 
 	conf.EventQueueSizeFrames = 12
 
 	conf.AsyncConnectorWriteQueueSizeFrames = 2048
 	conf.AsyncConnectorWriteBufferSizeBytes = 4096
+	conf.AsyncConnectorMaxStreamIds = 2048
 
 	conf.PrimaryCluster = config.PrimaryClusterOrigin
 	conf.ReadMode = config.ReadModePrimaryOnly
+	conf.LwtPolicy = config.LwtPolicyBoth
+	conf.CounterWritePolicy = config.CounterWritePolicyBoth
+	conf.DdlPolicy = config.DdlPolicyBoth
+	conf.EventSourcePolicy = config.EventSourcePolicyPrimaryOnly
+	conf.WarningsPolicy = config.PropagationPolicyPrimaryOnly
+	conf.CustomPayloadPolicy = config.PropagationPolicyPrimaryOnly
+	conf.IpFamilyPreference = config.IpFamilyPreferenceV4
+	conf.DnsCacheMinTtlMs = 1000
+	conf.DnsCacheMaxTtlMs = 300000
+	conf.EventDedupWindowMs = 1000
 	conf.SystemQueriesMode = config.SystemQueriesModeOrigin
 	conf.AsyncHandshakeTimeoutMs = 4000
 
 	conf.ProxyRequestTimeoutMs = 10000
+	conf.ProxyMaxRequestTimeoutOverrideMs = 600000
+
+	conf.ReprepareOnUnprepared = true
+	conf.TagPagingStates = true
+
+	conf.OriginRetryBaseDelayMs = 100
+	conf.OriginRetryMaxDelayMs = 1000
+	conf.TargetRetryBaseDelayMs = 100
+	conf.TargetRetryMaxDelayMs = 1000
+
+	conf.TargetCircuitBreakerErrorRatePercent = 50
+	conf.TargetCircuitBreakerMinRequests = 20
+	conf.TargetCircuitBreakerWindowMs = 10000
+	conf.TargetCircuitBreakerCooldownMs = 30000
+	conf.TargetCircuitBreakerHalfOpenProbes = 5
+
+	conf.FailedWritesJournalPath = "zdm-failed-writes.jsonl"
+	conf.FailedWritesJournalMaxSizeMb = 1024
+	conf.FailedWritesJournalQueueSize = 10000
+
+	conf.AuditLogCategories = "DML,DDL,DCL"
+	conf.AuditLogQueueSize = 10000
+	conf.AuditLogFilePath = "zdm-audit.jsonl"
+	conf.AuditLogSyslogAddress = "unix:///dev/log"
+	conf.AuditLogKafkaTopic = "zdm-audit"
+
+	conf.FaultInjectionEnabled = false
+	conf.FaultInjectionOriginLatencyMs = 100
+	conf.FaultInjectionOriginErrorType = config.FaultErrorTypeOverloaded
+	conf.FaultInjectionTargetLatencyMs = 100
+	conf.FaultInjectionTargetErrorType = config.FaultErrorTypeOverloaded
+
+	conf.SecretsRefreshIntervalMs = 300000
+	conf.ContactPointsRefreshIntervalMs = 60000
+
+	conf.MetricsSinks = config.MetricsSinkPrometheus
+	conf.MetricsStatsdAddress = "localhost:8125"
+	conf.MetricsStatsdPrefix = "zdm"
+	conf.MetricsStatsdFlavor = config.StatsdFlavorDogStatsd
+	conf.MetricsStatsdFlushIntervalMs = 10000
+	conf.MetricsTableRequestsMaxTables = 100
+	conf.MetricsApplicationsMax = 100
 
 	conf.LogLevel = "INFO"
+	conf.LogFormat = config.LogFormatText
+	conf.LogRedaction = config.LogRedactionNone
 
 	return conf
 }
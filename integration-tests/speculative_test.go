@@ -0,0 +1,98 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/cqlserver"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSpeculativeReadOnAnotherHost(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.SpeculativeReadThresholdMs = 100
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	secondOrigin, err := cqlserver.NewCqlServerCluster(
+		"127.0.2.1", conf.OriginPort, conf.OriginUsername, conf.OriginPassword, false)
+	require.Nil(t, err)
+	defer secondOrigin.Close()
+
+	// the read is slow on the first connection that receives it, i.e. the request connection of the client
+	slow := &slowConnection{delay: time.Second}
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		slow.handler("origin1"),
+		client.RegisterHandler,
+		client.HandshakeHandler,
+		NewCustomSystemTablesHandler("cluster1", "dc1", "127.0.2.", map[string]int{"dc1": 1}, ""),
+	}
+	secondOrigin.CqlServer.RequestHandlers = []client.RequestHandler{
+		slow.handler("origin2"),
+		client.RegisterHandler,
+		client.HandshakeHandler,
+		NewCustomSystemTablesHandler("cluster1", "dc1", "127.0.1.", map[string]int{"dc1": 1}, ""),
+	}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		newRowsHandler("target"),
+		client.RegisterHandler,
+		client.HandshakeHandler,
+		client.NewSystemTablesHandler("cluster1", "dc1"),
+	}
+
+	require.Nil(t, secondOrigin.Start())
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	start := time.Now()
+	response, err := testSetup.Client.CqlConnection.SendAndReceive(
+		frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Query{Query: "SELECT * FROM ks.tb"}))
+	require.Nil(t, err)
+	require.Less(t, int64(time.Since(start)), int64(slow.delay))
+	rows, ok := response.Body.Message.(*message.RowsResult)
+	require.True(t, ok, response.Body.Message)
+	require.NotEqual(t, "target", string(rows.Data[0][0]))
+	require.NotEqual(t, slow.getServer(), string(rows.Data[0][0]))
+}
+
+// slowConnection delays the reads received on the first connection, the reads received on the other connections
+// return the name of the server right away
+type slowConnection struct {
+	delay  time.Duration
+	lock   sync.Mutex
+	conn   *client.CqlServerConnection
+	server string
+}
+
+func (recv *slowConnection) handler(server string) client.RequestHandler {
+	rowsHandler := newRowsHandler(server)
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		response := rowsHandler(request, conn, ctx)
+		if response == nil {
+			return nil
+		}
+		recv.lock.Lock()
+		if recv.conn == nil {
+			recv.conn = conn
+			recv.server = server
+		}
+		slow := recv.conn == conn
+		recv.lock.Unlock()
+		if slow {
+			time.Sleep(recv.delay)
+		}
+		return response
+	}
+}
+
+func (recv *slowConnection) getServer() string {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.server
+}
@@ -0,0 +1,91 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStreamIdOverflowConnection(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.StreamIdVirtualizationEnabled = true
+	conf.RequestConnectionMaxStreamIds = 1
+	conf.StreamIdOverflowConnectionEnabled = true
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	release := make(chan bool)
+	defer close(release)
+	queryConns := &sync.Map{}
+	rowsHandler := newRowsHandler("origin")
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.RegisterHandler,
+		func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+			query, ok := request.Body.Message.(*message.Query)
+			if !ok || query.Query != "SELECT * FROM ks.slow" {
+				return nil
+			}
+			queryConns.Store(conn, true)
+			<-release
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+		},
+		func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+			response := rowsHandler(request, conn, ctx)
+			if response != nil {
+				queryConns.Store(conn, true)
+			}
+			return response
+		},
+		client.HandshakeHandler,
+		client.NewSystemTablesHandler("cluster1", "dc1"),
+	}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	// the slow query holds the only stream id of the ORIGIN request connection
+	slowQuery, err := testSetup.Client.CqlConnection.Send(
+		frame.NewFrame(primitive.ProtocolVersion4, 10, &message.Query{Query: "SELECT * FROM ks.slow"}))
+	require.Nil(t, err)
+	require.Eventually(t, func() bool {
+		return countStreamIdTestConns(queryConns) == 1
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// the first query is rejected while the overflow connection is opened
+	require.IsType(t, &message.Overloaded{}, sendStreamIdTestQuery(t, testSetup))
+	require.Eventually(t, func() bool {
+		_, ok := sendStreamIdTestQuery(t, testSetup).(*message.RowsResult)
+		return ok
+	}, 5*time.Second, 50*time.Millisecond)
+	require.Equal(t, 2, countStreamIdTestConns(queryConns))
+
+	release <- true
+	response, err := testSetup.Client.CqlConnection.Receive(slowQuery)
+	require.Nil(t, err)
+	require.IsType(t, &message.VoidResult{}, response.Body.Message)
+	require.Equal(t, int16(10), response.Header.StreamId)
+}
+
+func sendStreamIdTestQuery(t *testing.T, testSetup *setup.CqlServerTestSetup) message.Message {
+	response, err := testSetup.Client.CqlConnection.SendAndReceive(
+		frame.NewFrame(primitive.ProtocolVersion4, 11, &message.Query{Query: "SELECT * FROM ks.tb"}))
+	require.Nil(t, err)
+	require.Equal(t, int16(11), response.Header.StreamId)
+	return response.Body.Message
+}
+
+func countStreamIdTestConns(conns *sync.Map) int {
+	count := 0
+	conns.Range(func(key, value interface{}) bool {
+		count++
+		return true
+	})
+	return count
+}
@@ -0,0 +1,119 @@
+package integration_tests
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/env"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testkit"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// TestSystemQueriesBypass checks that the system.peers_v2 query of a client that matches
+// ZDM_SYSTEM_QUERIES_BYPASS_CLIENTS or ZDM_SYSTEM_QUERIES_BYPASS_APPLICATIONS is sent to the cluster instead of being
+// answered by the proxy (which returns an error because the virtualized topology doesn't have peers_v2).
+func TestSystemQueriesBypass(t *testing.T) {
+	if !env.RunMockTests {
+		t.Skip("Skipping CQLServer tests, RUN_MOCKTESTS is false")
+	}
+
+	tests := []struct {
+		name            string
+		bypassClients   string
+		applicationName string
+		intercepted     bool
+	}{
+		{"no bypass", "", "", true},
+		{"other client", "10.0.0.0/8", "", true},
+		{"client address", "127.0.0.1", "", false},
+		{"client network", "127.0.0.0/8", "", false},
+		{"other application", "", "my-app", true},
+		{"application name", "", "repair-tool", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testSetup, err := testkit.NewSetup(context.Background(), map[string]string{
+				"proxy_topology_addresses":           "127.0.0.1",
+				"system_queries_bypass_clients":      tt.bypassClients,
+				"system_queries_bypass_applications": "repair-tool,other-tool",
+				"metrics_enabled":                    "false",
+			})
+			require.Nil(t, err)
+			defer testSetup.Close()
+
+			conn := connectWithApplicationName(t, testSetup, tt.applicationName)
+			defer conn.Close()
+
+			response, err := conn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 0,
+				&message.Query{Query: "SELECT * FROM system.peers_v2"}))
+			require.Nil(t, err)
+			if tt.intercepted {
+				require.IsType(t, &message.Invalid{}, response.Body.Message)
+				require.Equal(t, "unconfigured table peers_v2", response.Body.Message.(*message.Invalid).ErrorMessage)
+			} else {
+				require.IsType(t, &message.RowsResult{}, response.Body.Message)
+			}
+		})
+	}
+}
+
+// TestSystemQueriesCacheTtl checks that the intercepted system.local queries are answered after the topology
+// snapshot expired (ZDM_SYSTEM_QUERIES_CACHE_TTL_MS) and refreshed.
+func TestSystemQueriesCacheTtl(t *testing.T) {
+	if !env.RunMockTests {
+		t.Skip("Skipping CQLServer tests, RUN_MOCKTESTS is false")
+	}
+
+	testSetup, err := testkit.NewSetup(context.Background(), map[string]string{
+		"proxy_topology_addresses":    "127.0.0.1",
+		"system_queries_cache_ttl_ms": "50",
+		"metrics_enabled":             "false",
+	})
+	require.Nil(t, err)
+	defer testSetup.Close()
+
+	conn, err := testSetup.Connect(context.Background(), primitive.ProtocolVersion4)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	lastRefresh := testSetup.Proxy.GetOriginControlConn().GetLastTopologyRefreshTime()
+	for i := 0; i < 2; i++ {
+		time.Sleep(100 * time.Millisecond)
+		response, err := conn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 0,
+			&message.Query{Query: "SELECT rpc_address FROM system.local"}))
+		require.Nil(t, err)
+		require.IsType(t, &message.RowsResult{}, response.Body.Message)
+		require.Len(t, response.Body.Message.(*message.RowsResult).Data, 1)
+
+		refresh := testSetup.Proxy.GetOriginControlConn().GetLastTopologyRefreshTime()
+		require.True(t, refresh.After(lastRefresh), "the topology was not refreshed")
+		lastRefresh = refresh
+	}
+}
+
+func connectWithApplicationName(t *testing.T, testSetup *testkit.Setup, applicationName string) *client.CqlClientConnection {
+	credentials := &client.AuthCredentials{Username: testkit.DefaultUsername, Password: testkit.DefaultPassword}
+	conn, err := client.NewCqlClient(testSetup.ProxyAddress(), credentials).Connect(context.Background())
+	require.Nil(t, err)
+
+	startup := message.NewStartup()
+	if applicationName != "" {
+		startup.SetApplicationName(applicationName)
+	}
+	response, err := conn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 0, startup))
+	require.Nil(t, err)
+	authenticate, ok := response.Body.Message.(*message.Authenticate)
+	require.True(t, ok, "expected AUTHENTICATE, got %v", response.Body.Message)
+
+	authenticator := &client.PlainTextAuthenticator{Credentials: credentials}
+	token, err := authenticator.InitialResponse(authenticate.Authenticator)
+	require.Nil(t, err)
+	response, err = conn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 0, &message.AuthResponse{Token: token}))
+	require.Nil(t, err)
+	require.IsType(t, &message.AuthSuccess{}, response.Body.Message)
+	return conn
+}
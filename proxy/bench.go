@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/bench"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"strings"
+)
+
+// settingsFlag collects the repeated -set key=value flags.
+type settingsFlag map[string]string
+
+func (recv settingsFlag) String() string {
+	return fmt.Sprint(map[string]string(recv))
+}
+
+func (recv settingsFlag) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("expected key=value but got %v", value)
+	}
+	recv[parts[0]] = parts[1]
+	return nil
+}
+
+// runBench runs the "bench" subcommand and returns the exit code.
+func runBench(args []string) int {
+	conf := bench.NewConfig()
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	flags.StringVar(&conf.Workload, "workload", conf.Workload, "Workload to run: READ, WRITE or MIXED")
+	flags.IntVar(&conf.Connections, "connections", conf.Connections, "Number of client connections to the proxy")
+	flags.IntVar(&conf.Concurrency, "concurrency", conf.Concurrency, "Number of requests in flight on each connection")
+	flags.DurationVar(&conf.Duration, "duration", conf.Duration, "Duration of the measured run")
+	flags.DurationVar(&conf.Warmup, "warmup", conf.Warmup, "Duration of the warmup that precedes the measured run")
+	flags.Var(settingsFlag(conf.ProxySettings), "set",
+		"Proxy setting with the key of the config file, e.g. -set read_mode=dual_async_on_secondary (repeatable)")
+	logLevel := flags.String("log-level", "WARN", "Log level of the proxy")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	level, err := log.ParseLevel(*logLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid log level: %v\n", err)
+		return 2
+	}
+	log.SetLevel(level)
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	runSignalListener(cancelFunc)
+
+	result, err := bench.Run(ctx, conf)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Benchmark failed: %v\n", err)
+		return 1
+	}
+	fmt.Printf("%v workload, %d connections x %d requests in flight\n",
+		conf.Workload, conf.Connections, conf.Concurrency)
+	fmt.Print(result)
+	return 0
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"github.com/datastax/zdm-proxy/proxy/pkg/config"
 	"github.com/datastax/zdm-proxy/proxy/pkg/runner"
 	log "github.com/sirupsen/logrus"
@@ -10,6 +12,11 @@ import (
 	"syscall"
 )
 
+var configFile = flag.String("config", "",
+	"Path of a YAML config file, environment variables override the settings of the file (default: ZDM_CONFIG_FILE)")
+var validateConfig = flag.Bool("validate-config", false,
+	"Validate the configuration, print the effective settings (environment variables merged with the config file) and exit")
+
 func runSignalListener(cancelFunc context.CancelFunc) {
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
@@ -23,8 +30,32 @@ func runSignalListener(cancelFunc context.CancelFunc) {
 	}()
 }
 
+func loadConfig() (*config.Config, error) {
+	path := *configFile
+	if path == "" {
+		path = os.Getenv("ZDM_CONFIG_FILE")
+	}
+	if path == "" {
+		return config.New().ParseEnvVars()
+	}
+	return config.New().ParseConfigFileAndEnvVars(path)
+}
+
 func launchProxy(profilingSupported bool) {
-	conf, err := config.New().ParseEnvVars()
+	conf, err := loadConfig()
+	if *validateConfig {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+			os.Exit(1)
+		}
+		effectiveConfig, err := conf.MarshalConfigFile()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not print configuration: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(string(effectiveConfig))
+		os.Exit(0)
+	}
 	if err != nil {
 		log.Errorf("Error loading configuration: %v. Aborting startup.", err)
 		os.Exit(-1)
@@ -37,6 +68,13 @@ func launchProxy(profilingSupported bool) {
 	}
 	log.SetLevel(logLevel)
 
+	logFormatter, err := conf.ParseLogFormatter()
+	if err != nil {
+		log.Errorf("Error loading log format configuration: %v. Aborting startup.", err)
+		os.Exit(-1)
+	}
+	log.SetFormatter(logFormatter)
+
 	if profilingSupported {
 		log.Debugf("Proxy built with profiling support")
 	} else {
@@ -20,6 +20,10 @@ var displayVersion = flag.Bool("version", false, "Display the ZDM proxy version
 
 func main() {
 
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		os.Exit(runBench(os.Args[2:]))
+	}
+
 	flag.Parse()
 	if *displayVersion {
 		fmt.Printf("ZDM proxy version %v\n", ZdmVersionString)
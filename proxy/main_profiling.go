@@ -18,6 +18,10 @@ var memProfile = flag.String("mem_profile", "", "write memory profile to the spe
 
 func main() {
 
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		os.Exit(runBench(os.Args[2:]))
+	}
+
 	flag.Parse()
 
 	// the cpu profiling is enabled at startup and is periodically collected while the proxy is running
@@ -0,0 +1,60 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+func DefaultTargetCircuitBreakerHandler() http.Handler {
+	return TargetCircuitBreakerHandler(nil)
+}
+
+// TargetCircuitBreakerHandler reports the state of the TARGET circuit breaker as JSON on GET requests.
+//
+// A POST request with the override query parameter (AUTO, FORCE_OPEN or FORCE_CLOSED) lets operators stop or resume
+// sending writes to TARGET regardless of the error rate. The status code is 503 until the proxy has started and 404
+// if the circuit breaker is disabled.
+func TargetCircuitBreakerHandler(proxy *zdmproxy.ZdmProxy) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodPost {
+			http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if proxy == nil {
+			http.Error(rsp, "The proxy is starting up", http.StatusServiceUnavailable)
+			return
+		}
+
+		circuitBreaker := proxy.GetTargetCircuitBreaker()
+		if circuitBreaker == nil {
+			http.Error(rsp, "The TARGET circuit breaker is disabled, see ZDM_TARGET_CIRCUIT_BREAKER_ENABLED",
+				http.StatusNotFound)
+			return
+		}
+
+		if req.Method == http.MethodPost {
+			override, err := zdmproxy.ParseCircuitBreakerOverride(req.URL.Query().Get("override"))
+			if err != nil {
+				http.Error(rsp, err.Error(), http.StatusBadRequest)
+				return
+			}
+			log.Infof("TARGET circuit breaker override %v requested by %v.", override, req.RemoteAddr)
+			circuitBreaker.SetOverride(override)
+		}
+
+		bytes, err := json.Marshal(circuitBreaker.GetStatus())
+		if err != nil {
+			log.Errorf("Could not serialize TARGET circuit breaker status: %v", err)
+			http.Error(rsp, fmt.Sprintf("Internal server error: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		rsp.Header().Set("Content-Type", "application/json")
+		rsp.WriteHeader(http.StatusOK)
+		rsp.Write(bytes)
+	})
+}
@@ -0,0 +1,251 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func DefaultDebugHandler() http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		http.Error(rsp, "The debug endpoint is disabled, see ZDM_DEBUG_ENDPOINT_ENABLED", http.StatusNotFound)
+	})
+}
+
+// DebugHandler serves the endpoints of ZDM_DEBUG_ENDPOINT_ENABLED:
+//
+//   - /debug/pprof/ lists the runtime profiles, /debug/pprof/<name> returns one of them, /debug/pprof/profile a CPU
+//     profile of the given number of seconds (30 by default) and /debug/pprof/trace an execution trace (1 second by
+//     default), the same endpoints as net/http/pprof so go tool pprof can read them.
+//   - /debug/runtime returns the goroutine count, the memory and the GC stats as JSON.
+//   - a POST request to /debug/dump writes the runtime stats, the in-flight requests of each client connection and
+//     the stacks of all the goroutines to a file in ZDM_DEBUG_DUMP_DIRECTORY and returns the path of the file.
+//
+// The proxy is nil until it has started, the profiles are served anyway but the dumps don't have the client
+// connections. net/http/pprof isn't used because importing it registers its handlers on http.DefaultServeMux,
+// which serves the metrics, even if the debug endpoint is disabled.
+func DebugHandler(conf *config.Config, proxy *zdmproxy.ZdmProxy) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", handleProfile)
+	mux.HandleFunc("/debug/pprof/profile", handleCpuProfile)
+	mux.HandleFunc("/debug/pprof/trace", handleTrace)
+	mux.HandleFunc("/debug/runtime", func(rsp http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		bytes, err := json.Marshal(readRuntimeStats())
+		if err != nil {
+			log.Errorf("Could not serialize runtime stats: %v", err)
+			http.Error(rsp, fmt.Sprintf("Internal server error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		rsp.Header().Set("Content-Type", "application/json")
+		rsp.WriteHeader(http.StatusOK)
+		rsp.Write(bytes)
+	})
+	mux.HandleFunc("/debug/dump", func(rsp http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		path, err := writeDumpFile(conf.DebugDumpDirectory, proxy)
+		if err != nil {
+			log.Errorf("Diagnostics dump requested by %v failed: %v", req.RemoteAddr, err)
+			http.Error(rsp, fmt.Sprintf("Internal server error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		log.Infof("Diagnostics dump requested by %v written to %v.", req.RemoteAddr, path)
+
+		bytes, err := json.Marshal(map[string]string{"path": path})
+		if err != nil {
+			http.Error(rsp, fmt.Sprintf("Internal server error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		rsp.Header().Set("Content-Type", "application/json")
+		rsp.WriteHeader(http.StatusOK)
+		rsp.Write(bytes)
+	})
+	return mux
+}
+
+func handleProfile(rsp http.ResponseWriter, req *http.Request) {
+	name := strings.TrimPrefix(req.URL.Path, "/debug/pprof/")
+	if name == "" {
+		profiles := pprof.Profiles()
+		sort.Slice(profiles, func(i, j int) bool {
+			return profiles[i].Name() < profiles[j].Name()
+		})
+		rsp.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, profile := range profiles {
+			fmt.Fprintf(rsp, "%v (%d)\n", profile.Name(), profile.Count())
+		}
+		fmt.Fprintf(rsp, "profile\ntrace\n")
+		return
+	}
+
+	profile := pprof.Lookup(name)
+	if profile == nil {
+		http.Error(rsp, fmt.Sprintf("Unknown profile: %v", name), http.StatusNotFound)
+		return
+	}
+	debug, _ := strconv.Atoi(req.URL.Query().Get("debug"))
+	if debug == 0 {
+		rsp.Header().Set("Content-Type", "application/octet-stream")
+		rsp.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	} else {
+		rsp.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	if err := profile.WriteTo(rsp, debug); err != nil {
+		log.Errorf("Could not write %v profile: %v", name, err)
+	}
+}
+
+func handleCpuProfile(rsp http.ResponseWriter, req *http.Request) {
+	duration, err := parseSeconds(req, 30)
+	if err != nil {
+		http.Error(rsp, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rsp.Header().Set("Content-Type", "application/octet-stream")
+	rsp.Header().Set("Content-Disposition", `attachment; filename="profile"`)
+	if err = pprof.StartCPUProfile(rsp); err != nil {
+		http.Error(rsp, fmt.Sprintf("Could not start CPU profile: %v", err), http.StatusInternalServerError)
+		return
+	}
+	sleep(req, duration)
+	pprof.StopCPUProfile()
+}
+
+func handleTrace(rsp http.ResponseWriter, req *http.Request) {
+	duration, err := parseSeconds(req, 1)
+	if err != nil {
+		http.Error(rsp, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rsp.Header().Set("Content-Type", "application/octet-stream")
+	rsp.Header().Set("Content-Disposition", `attachment; filename="trace"`)
+	if err = trace.Start(rsp); err != nil {
+		http.Error(rsp, fmt.Sprintf("Could not start trace: %v", err), http.StatusInternalServerError)
+		return
+	}
+	sleep(req, duration)
+	trace.Stop()
+}
+
+func parseSeconds(req *http.Request, defaultSeconds int) (time.Duration, error) {
+	value := req.URL.Query().Get("seconds")
+	if value == "" {
+		return time.Duration(defaultSeconds) * time.Second, nil
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		return 0, fmt.Errorf("invalid value for seconds: %v", value)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// sleep returns early if the client goes away.
+func sleep(req *http.Request, duration time.Duration) {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-req.Context().Done():
+	}
+}
+
+type runtimeStats struct {
+	GoVersion      string    `json:"go_version"`
+	NumCpu         int       `json:"num_cpu"`
+	GoMaxProcs     int       `json:"go_max_procs"`
+	Goroutines     int       `json:"goroutines"`
+	HeapAllocBytes uint64    `json:"heap_alloc_bytes"`
+	HeapInuseBytes uint64    `json:"heap_inuse_bytes"`
+	HeapObjects    uint64    `json:"heap_objects"`
+	SysBytes       uint64    `json:"sys_bytes"`
+	NumGc          uint32    `json:"num_gc"`
+	LastGc         time.Time `json:"last_gc"`
+	GcPauseTotalMs float64   `json:"gc_pause_total_ms"`
+	GcCpuFraction  float64   `json:"gc_cpu_fraction"`
+	NextGcBytes    uint64    `json:"next_gc_bytes"`
+}
+
+func readRuntimeStats() *runtimeStats {
+	memStats := &runtime.MemStats{}
+	runtime.ReadMemStats(memStats)
+	return &runtimeStats{
+		GoVersion:      runtime.Version(),
+		NumCpu:         runtime.NumCPU(),
+		GoMaxProcs:     runtime.GOMAXPROCS(0),
+		Goroutines:     runtime.NumGoroutine(),
+		HeapAllocBytes: memStats.HeapAlloc,
+		HeapInuseBytes: memStats.HeapInuse,
+		HeapObjects:    memStats.HeapObjects,
+		SysBytes:       memStats.Sys,
+		NumGc:          memStats.NumGC,
+		LastGc:         time.Unix(0, int64(memStats.LastGC)).UTC(),
+		GcPauseTotalMs: float64(memStats.PauseTotalNs) / float64(time.Millisecond),
+		GcCpuFraction:  memStats.GCCPUFraction,
+		NextGcBytes:    memStats.NextGC,
+	}
+}
+
+// writeDumpFile writes a dump to a new file of the directory (the temporary directory if it is empty) and returns
+// the path of the file.
+func writeDumpFile(directory string, proxy *zdmproxy.ZdmProxy) (string, error) {
+	if directory == "" {
+		directory = os.TempDir()
+	}
+	now := time.Now().UTC()
+	path := filepath.Join(directory, fmt.Sprintf("zdm-dump-%v.txt", now.Format("20060102T150405.000")))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", fmt.Errorf("could not create dump file: %w", err)
+	}
+	err = writeDump(file, now, proxy)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not write dump file %v: %w", path, err)
+	}
+	return path, nil
+}
+
+func writeDump(w io.Writer, now time.Time, proxy *zdmproxy.ZdmProxy) error {
+	stats, err := json.MarshalIndent(readRuntimeStats(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err = fmt.Fprintf(w, "ZDM proxy diagnostics dump %v\n\nRuntime:\n%s\n\n", now.Format(time.RFC3339Nano), stats); err != nil {
+		return err
+	}
+
+	if proxy == nil {
+		_, err = fmt.Fprintf(w, "Client connections: unknown, the proxy is starting up\n")
+	} else {
+		err = proxy.WriteDiagnostics(w)
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err = fmt.Fprintf(w, "\nGoroutines:\n"); err != nil {
+		return err
+	}
+	return pprof.Lookup("goroutine").WriteTo(w, 2)
+}
@@ -0,0 +1,47 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+func DefaultFleetStateHandler() http.Handler {
+	return FleetStateHandler(nil)
+}
+
+// FleetStateHandler reports the last state read from the coordination backend of the fleet, its revision and the
+// last error as JSON. The state can only be changed in the backend. The status code is 503 until the proxy has started
+// and 404 if ZDM_FLEET_CONFIG_BACKEND is not set.
+func FleetStateHandler(proxy *zdmproxy.ZdmProxy) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if proxy == nil {
+			http.Error(rsp, "The proxy is starting up", http.StatusServiceUnavailable)
+			return
+		}
+
+		status := proxy.GetFleetStatus()
+		if status == nil {
+			http.Error(rsp, "The state is not shared with a fleet, see ZDM_FLEET_CONFIG_BACKEND", http.StatusNotFound)
+			return
+		}
+
+		bytes, err := json.Marshal(status)
+		if err != nil {
+			log.Errorf("Could not serialize fleet state status: %v", err)
+			http.Error(rsp, fmt.Sprintf("Internal server error: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		rsp.Header().Set("Content-Type", "application/json")
+		rsp.WriteHeader(http.StatusOK)
+		rsp.Write(bytes)
+	})
+}
@@ -0,0 +1,63 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+func DefaultMigrationPhaseHandler() http.Handler {
+	return MigrationPhaseHandler(nil)
+}
+
+// MigrationPhaseHandler reports the current migration phase as JSON on GET requests.
+//
+// A POST request with the phase query parameter moves the migration to the next phase or rolls it back to the
+// previous one, other transitions are rejected with 409 and unknown phases with 400. The status code is 503 until the
+// proxy has started and 404 if ZDM_MIGRATION_PHASE is not set.
+func MigrationPhaseHandler(proxy *zdmproxy.ZdmProxy) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodPost {
+			http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if proxy == nil {
+			http.Error(rsp, "The proxy is starting up", http.StatusServiceUnavailable)
+			return
+		}
+
+		if proxy.GetMigrationPhaseStatus() == nil {
+			http.Error(rsp, "The migration phase is not tracked, see ZDM_MIGRATION_PHASE", http.StatusNotFound)
+			return
+		}
+
+		if req.Method == http.MethodPost {
+			phase, err := config.ParseMigrationPhaseName(req.URL.Query().Get("phase"))
+			if err != nil {
+				http.Error(rsp, err.Error(), http.StatusBadRequest)
+				return
+			}
+			log.Infof("Migration phase %v requested by %v.", phase, req.RemoteAddr)
+			if err = proxy.SetMigrationPhase(phase); err != nil {
+				log.Warnf("Migration phase %v requested by %v rejected: %v", phase, req.RemoteAddr, err)
+				http.Error(rsp, err.Error(), http.StatusConflict)
+				return
+			}
+		}
+
+		bytes, err := json.Marshal(proxy.GetMigrationPhaseStatus())
+		if err != nil {
+			log.Errorf("Could not serialize migration phase status: %v", err)
+			http.Error(rsp, fmt.Sprintf("Internal server error: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		rsp.Header().Set("Content-Type", "application/json")
+		rsp.WriteHeader(http.StatusOK)
+		rsp.Write(bytes)
+	})
+}
@@ -0,0 +1,59 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+func DefaultSchemaDriftHandler() http.Handler {
+	return SchemaDriftHandler(nil)
+}
+
+// SchemaDriftHandler reports the differences between the schemas of ORIGIN and TARGET that were found by the last
+// comparison as JSON on GET requests. A POST request compares the schemas again before reporting them, 502 is
+// returned if the schema of a cluster could not be fetched. The status code is 503 until the proxy has started and
+// 404 if ZDM_SCHEMA_DRIFT_CHECK_INTERVAL_MS is 0.
+func SchemaDriftHandler(proxy *zdmproxy.ZdmProxy) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodPost {
+			http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if proxy == nil {
+			http.Error(rsp, "The proxy is starting up", http.StatusServiceUnavailable)
+			return
+		}
+
+		report := proxy.GetSchemaDriftReport()
+		if report == nil {
+			http.Error(rsp, "Schema drift detection is disabled, see ZDM_SCHEMA_DRIFT_CHECK_INTERVAL_MS",
+				http.StatusNotFound)
+			return
+		}
+
+		if req.Method == http.MethodPost {
+			var err error
+			log.Infof("Schema comparison requested by %v.", req.RemoteAddr)
+			report, err = proxy.CheckSchemaDrift(req.Context())
+			if err != nil {
+				http.Error(rsp, fmt.Sprintf("Could not compare the schemas: %v", err), http.StatusBadGateway)
+				return
+			}
+		}
+
+		bytes, err := json.Marshal(report)
+		if err != nil {
+			log.Errorf("Could not serialize schema drift report: %v", err)
+			http.Error(rsp, fmt.Sprintf("Internal server error: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		rsp.Header().Set("Content-Type", "application/json")
+		rsp.WriteHeader(http.StatusOK)
+		rsp.Write(bytes)
+	})
+}
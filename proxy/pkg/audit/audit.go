@@ -0,0 +1,206 @@
+package audit
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const (
+	OutcomeSuccess = "SUCCESS"
+	OutcomeError   = "ERROR"
+	OutcomeTimeout = "TIMEOUT"
+
+	// OutcomeSkipped means that the request was not sent to the cluster, e.g. because the TARGET circuit breaker
+	// is open.
+	OutcomeSkipped = "SKIPPED"
+
+	// maximum number of events passed to the sinks at once
+	maxBatchSize = 100
+)
+
+// Event records a statement that a client executed through the proxy. Events are serialized as JSON objects.
+type Event struct {
+	Timestamp     time.Time `json:"timestamp"`
+	ClientAddress string    `json:"client_address"`
+
+	// Username is the user that the client authenticated with, empty if authentication is disabled.
+	Username string `json:"username,omitempty"`
+
+	Category  string `json:"category"`
+	Operation string `json:"operation"` // QUERY, EXECUTE or BATCH
+	Keyspace  string `json:"keyspace,omitempty"`
+
+	// Statement is only set if ZDM_AUDIT_LOG_INCLUDE_STATEMENTS is enabled. Bound values are never included but
+	// literals in the statement are.
+	Statement string `json:"statement,omitempty"`
+
+	Clusters []*ClusterOutcome `json:"clusters"`
+}
+
+// ClusterOutcome is the result of a statement on one of the clusters.
+type ClusterOutcome struct {
+	Cluster string `json:"cluster"`
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Sink is the destination of the audit events. The proxy provides FILE, SYSLOG and KAFKA sinks (ZDM_AUDIT_LOG_SINKS),
+// programs that embed the proxy can register their own sinks with ZdmProxy.AddAuditSink before the proxy is started.
+//
+// Write is only called by the goroutine of the audit log, never concurrently. An event that can't be written is not
+// written again.
+type Sink interface {
+	// Name is used in logs.
+	Name() string
+	Write(events []*Event) error
+	Close() error
+}
+
+// Log passes the audit events to the sinks.
+//
+// Events are queued and written by a single goroutine so that the client requests never wait for the sinks. Events
+// are dropped (and counted as such) if the queue is full.
+type Log struct {
+	config *common.AuditLogConfig
+	sinks  []Sink
+	queue  chan *Event
+	wg     *sync.WaitGroup
+
+	closeLock *sync.RWMutex
+	closed    bool
+
+	writtenEvents metrics.Counter
+	droppedEvents metrics.Counter
+	sinkErrors    metrics.Counter
+
+	logger *log.Entry
+}
+
+// NewLog creates the metrics of the audit log and starts passing the events that are appended to the sinks. The sinks
+// are closed when the log is closed.
+func NewLog(
+	config *common.AuditLogConfig, sinks []Sink, metricFactory metrics.MetricFactory, logger *log.Entry) (*Log, error) {
+	writtenEvents, err := metricFactory.GetOrCreateCounter(metrics.AuditLogEvents)
+	if err != nil {
+		return nil, err
+	}
+	droppedEvents, err := metricFactory.GetOrCreateCounter(metrics.AuditLogDroppedEvents)
+	if err != nil {
+		return nil, err
+	}
+	sinkErrors, err := metricFactory.GetOrCreateCounter(metrics.AuditLogSinkErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Log{
+		config:        config,
+		sinks:         sinks,
+		queue:         make(chan *Event, config.QueueSize),
+		wg:            &sync.WaitGroup{},
+		closeLock:     &sync.RWMutex{},
+		writtenEvents: writtenEvents,
+		droppedEvents: droppedEvents,
+		sinkErrors:    sinkErrors,
+		logger:        logger,
+	}
+
+	_, err = metricFactory.GetOrCreateGaugeFunc(metrics.AuditLogPendingEvents, func() float64 {
+		return float64(len(l.queue))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	l.wg.Add(1)
+	go l.run()
+	return l, nil
+}
+
+// IsAudited returns true if the statements of this category are recorded (ZDM_AUDIT_LOG_CATEGORIES).
+func (l *Log) IsAudited(category common.AuditCategory) bool {
+	return l.config.Categories[category]
+}
+
+// IncludeStatements returns true if the events contain the statements (ZDM_AUDIT_LOG_INCLUDE_STATEMENTS).
+func (l *Log) IncludeStatements() bool {
+	return l.config.IncludeStatements
+}
+
+// Append queues the event without blocking, it returns false if the event was dropped.
+func (l *Log) Append(event *Event) bool {
+	l.closeLock.RLock()
+	defer l.closeLock.RUnlock()
+	if l.closed {
+		l.droppedEvents.Add(1)
+		return false
+	}
+	select {
+	case l.queue <- event:
+		return true
+	default:
+		l.droppedEvents.Add(1)
+		l.logger.Debugf("Audit log queue is full, dropping %v event of %v.", event.Category, event.ClientAddress)
+		return false
+	}
+}
+
+// Close writes the events that are still queued and closes the sinks.
+func (l *Log) Close() error {
+	l.closeLock.Lock()
+	if l.closed {
+		l.closeLock.Unlock()
+		return nil
+	}
+	l.closed = true
+	close(l.queue)
+	l.closeLock.Unlock()
+
+	l.wg.Wait()
+	var lastErr error
+	for _, sink := range l.sinks {
+		if err := sink.Close(); err != nil {
+			lastErr = fmt.Errorf("could not close audit log sink %v: %w", sink.Name(), err)
+		}
+	}
+	return lastErr
+}
+
+func (l *Log) run() {
+	defer l.wg.Done()
+	batch := make([]*Event, 0, maxBatchSize)
+	for event := range l.queue {
+		batch = append(batch[:0], event)
+	drain:
+		for len(batch) < maxBatchSize {
+			select {
+			case next, ok := <-l.queue:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, next)
+			default:
+				break drain
+			}
+		}
+		l.write(batch)
+	}
+}
+
+func (l *Log) write(batch []*Event) {
+	written := true
+	for _, sink := range l.sinks {
+		if err := sink.Write(batch); err != nil {
+			l.logger.Errorf("Could not write %d event(s) to audit log sink %v: %v", len(batch), sink.Name(), err)
+			l.sinkErrors.Add(len(batch))
+			written = false
+		}
+	}
+	if written {
+		l.writtenEvents.Add(len(batch))
+	}
+}
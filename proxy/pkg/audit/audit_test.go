@@ -0,0 +1,193 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics/noopmetrics"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeSink struct {
+	events []*Event
+	err    error
+	closed bool
+}
+
+func (recv *fakeSink) Name() string {
+	return "fake"
+}
+
+func (recv *fakeSink) Write(events []*Event) error {
+	if recv.err != nil {
+		return recv.err
+	}
+	recv.events = append(recv.events, events...)
+	return nil
+}
+
+func (recv *fakeSink) Close() error {
+	recv.closed = true
+	return nil
+}
+
+func newTestEvent(category string) *Event {
+	return &Event{
+		Timestamp:     time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		ClientAddress: "10.0.0.1:51234",
+		Username:      "app",
+		Category:      category,
+		Operation:     "QUERY",
+		Keyspace:      "ks1",
+		Clusters: []*ClusterOutcome{
+			{Cluster: "ORIGIN", Outcome: OutcomeSuccess},
+			{Cluster: "TARGET", Outcome: OutcomeError, Error: "Write timeout"},
+		},
+	}
+}
+
+func TestLog(t *testing.T) {
+	sink, failingSink := &fakeSink{}, &fakeSink{err: errors.New("unavailable")}
+	auditLog, err := NewLog(&common.AuditLogConfig{
+		Categories: map[common.AuditCategory]bool{common.AuditCategoryDml: true},
+		QueueSize:  10,
+	}, []Sink{sink, failingSink}, noopmetrics.NewNoopMetricFactory(), log.NewEntry(log.StandardLogger()))
+	require.Nil(t, err)
+
+	require.True(t, auditLog.IsAudited(common.AuditCategoryDml))
+	require.False(t, auditLog.IsAudited(common.AuditCategorySelect))
+	require.False(t, auditLog.IncludeStatements())
+
+	first, second := newTestEvent("DML"), newTestEvent("DML")
+	require.True(t, auditLog.Append(first))
+	require.True(t, auditLog.Append(second))
+	require.Nil(t, auditLog.Close())
+	require.False(t, auditLog.Append(first))
+
+	require.Equal(t, []*Event{first, second}, sink.events, "a failing sink doesn't prevent the writes to the others")
+	require.True(t, sink.closed)
+	require.True(t, failingSink.closed)
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := NewFileSink(path)
+	require.Nil(t, err)
+	events := []*Event{newTestEvent("DML"), newTestEvent("DDL")}
+	require.Nil(t, sink.Write(events))
+	require.Nil(t, sink.Close())
+
+	file, err := os.Open(path)
+	require.Nil(t, err)
+	defer file.Close()
+	var written []*Event
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		event := &Event{}
+		require.Nil(t, json.Unmarshal(scanner.Bytes(), event))
+		written = append(written, event)
+	}
+	require.Equal(t, events, written)
+}
+
+func TestSyslogSink_Udp(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer conn.Close()
+
+	sink, err := NewSyslogSink("udp", conn.LocalAddr().String())
+	require.Nil(t, err)
+	defer sink.Close()
+	event := newTestEvent("DDL")
+	require.Nil(t, sink.Write([]*Event{event}))
+
+	buf := make([]byte, 4096)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	require.Nil(t, err)
+	msg := string(buf[:n])
+	require.True(t, strings.HasPrefix(msg, "<134>1 2026-01-02T03:04:05Z "), msg)
+	require.Contains(t, msg, " zdm-proxy "+strconv.Itoa(os.Getpid())+" audit - {")
+	written := &Event{}
+	require.Nil(t, json.Unmarshal([]byte(msg[strings.Index(msg, "{"):]), written))
+	require.Equal(t, event, written)
+}
+
+func TestSyslogSink_Tcp(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer listener.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		length, err := reader.ReadString(' ')
+		if err != nil {
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(length))
+		msg := make([]byte, n)
+		_, _ = io.ReadFull(reader, msg)
+		received <- string(msg)
+	}()
+
+	sink, err := NewSyslogSink("tcp", listener.Addr().String())
+	require.Nil(t, err)
+	defer sink.Close()
+	require.Nil(t, sink.Write([]*Event{newTestEvent("DCL")}))
+
+	select {
+	case msg := <-received:
+		require.True(t, strings.HasPrefix(msg, "<134>1 "), msg)
+		require.True(t, strings.HasSuffix(msg, "}"), "the message is framed with its length")
+	case <-time.After(5 * time.Second):
+		t.Fatal("syslog message not received")
+	}
+}
+
+func TestKafkaRestSink(t *testing.T) {
+	var requests []*http.Request
+	var bodies []string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sink := NewKafkaRestSink(server.URL, "zdm-audit")
+	defer sink.Close()
+	events := []*Event{newTestEvent("DML"), newTestEvent("DDL")}
+	require.Nil(t, sink.Write(events))
+
+	require.Equal(t, 1, len(requests))
+	require.Equal(t, http.MethodPost, requests[0].Method)
+	require.Equal(t, "/topics/zdm-audit", requests[0].URL.Path)
+	require.Equal(t, kafkaRestContentType, requests[0].Header.Get("Content-Type"))
+	request := &kafkaRestRequest{}
+	require.Nil(t, json.Unmarshal([]byte(bodies[0]), request))
+	require.Equal(t, 2, len(request.Records))
+	require.Equal(t, events[1], request.Records[1].Value)
+
+	status = http.StatusNotFound
+	require.NotNil(t, sink.Write(events))
+}
@@ -0,0 +1,50 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// FileSink appends the events to a local file, one JSON object per line.
+type FileSink struct {
+	path   string
+	file   *os.File
+	writer *bufio.Writer
+}
+
+// NewFileSink opens (or creates) the audit log file.
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("could not open audit log %v: %w", path, err)
+	}
+	return &FileSink{path: path, file: file, writer: bufio.NewWriter(file)}, nil
+}
+
+func (recv *FileSink) Name() string {
+	return fmt.Sprintf("file(%v)", recv.path)
+}
+
+func (recv *FileSink) Write(events []*Event) error {
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		line = append(line, '\n')
+		if _, err = recv.writer.Write(line); err != nil {
+			return err
+		}
+	}
+	return recv.writer.Flush()
+}
+
+func (recv *FileSink) Close() error {
+	flushErr := recv.writer.Flush()
+	if err := recv.file.Close(); err != nil {
+		return err
+	}
+	return flushErr
+}
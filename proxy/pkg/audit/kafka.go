@@ -0,0 +1,73 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	kafkaRestContentType    = "application/vnd.kafka.json.v2+json"
+	kafkaRestRequestTimeout = 10 * time.Second
+)
+
+// KafkaRestSink produces the events to a Kafka topic through a Kafka REST proxy (v2 API), each event is the JSON value
+// of a record. The REST proxy is used instead of the Kafka protocol so that the proxy doesn't need a Kafka client.
+type KafkaRestSink struct {
+	topicUrl string
+	client   *http.Client
+}
+
+type kafkaRestRecord struct {
+	Value *Event `json:"value"`
+}
+
+type kafkaRestRequest struct {
+	Records []kafkaRestRecord `json:"records"`
+}
+
+// NewKafkaRestSink returns a sink that produces to topic through the REST proxy at restUrl, e.g.
+// http://kafka-rest:8082.
+func NewKafkaRestSink(restUrl string, topic string) *KafkaRestSink {
+	return &KafkaRestSink{
+		topicUrl: fmt.Sprintf("%v/topics/%v", restUrl, url.PathEscape(topic)),
+		client:   &http.Client{Timeout: kafkaRestRequestTimeout},
+	}
+}
+
+func (recv *KafkaRestSink) Name() string {
+	return fmt.Sprintf("kafka(%v)", recv.topicUrl)
+}
+
+func (recv *KafkaRestSink) Write(events []*Event) error {
+	request := &kafkaRestRequest{Records: make([]kafkaRestRecord, 0, len(events))}
+	for _, event := range events {
+		request.Records = append(request.Records, kafkaRestRecord{Value: event})
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	response, err := recv.client.Post(recv.topicUrl, kafkaRestContentType, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		responseBody, _ := ioutil.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("kafka REST proxy returned %v: %s", response.Status, responseBody)
+	}
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+	return nil
+}
+
+func (recv *KafkaRestSink) Close() error {
+	recv.client.CloseIdleConnections()
+	return nil
+}
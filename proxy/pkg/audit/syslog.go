@@ -0,0 +1,102 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	syslogFacilityLocal0 = 16
+	syslogSeverityInfo   = 6
+	syslogAppName        = "zdm-proxy"
+	syslogMsgId          = "audit"
+	syslogWriteTimeout   = 5 * time.Second
+)
+
+// SyslogSink sends the events to a syslog server as RFC 5424 messages with the JSON event as message. TCP messages
+// are framed with octet counting (RFC 6587). The connection is opened again after a failed write.
+//
+// The log/syslog package is not used because it doesn't build on Windows and it only sends RFC 3164 messages.
+type SyslogSink struct {
+	network  string
+	address  string
+	hostname string
+	pid      string
+	conn     net.Conn
+}
+
+// NewSyslogSink connects to the syslog server, network is udp, tcp or unixgram.
+func NewSyslogSink(network string, address string) (*SyslogSink, error) {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	sink := &SyslogSink{
+		network:  network,
+		address:  address,
+		hostname: hostname,
+		pid:      strconv.Itoa(os.Getpid()),
+	}
+	if err = sink.connect(); err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+func (recv *SyslogSink) Name() string {
+	return fmt.Sprintf("syslog(%v://%v)", recv.network, recv.address)
+}
+
+func (recv *SyslogSink) connect() error {
+	conn, err := net.DialTimeout(recv.network, recv.address, syslogWriteTimeout)
+	if err != nil {
+		return fmt.Errorf("could not connect to syslog server %v://%v: %w", recv.network, recv.address, err)
+	}
+	recv.conn = conn
+	return nil
+}
+
+func (recv *SyslogSink) Write(events []*Event) error {
+	if recv.conn == nil {
+		if err := recv.connect(); err != nil {
+			return err
+		}
+	}
+	for _, event := range events {
+		msg, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		line := recv.formatMessage(event.Timestamp, msg)
+		if recv.network == "tcp" {
+			line = append([]byte(strconv.Itoa(len(line))+" "), line...)
+		}
+		_ = recv.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout))
+		if _, err = recv.conn.Write(line); err != nil {
+			_ = recv.conn.Close()
+			recv.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// formatMessage returns <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func (recv *SyslogSink) formatMessage(timestamp time.Time, msg []byte) []byte {
+	header := fmt.Sprintf("<%d>1 %v %v %v %v %v - ", syslogFacilityLocal0*8+syslogSeverityInfo,
+		timestamp.UTC().Format(time.RFC3339Nano), recv.hostname, syslogAppName, recv.pid, syslogMsgId)
+	return append([]byte(header), msg...)
+}
+
+func (recv *SyslogSink) Close() error {
+	if recv.conn == nil {
+		return nil
+	}
+	err := recv.conn.Close()
+	recv.conn = nil
+	return err
+}
@@ -0,0 +1,280 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testkit"
+	"github.com/rs/zerolog"
+	log "github.com/sirupsen/logrus"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	WorkloadRead  = "READ"
+	WorkloadWrite = "WRITE"
+	WorkloadMixed = "MIXED"
+
+	benchHost = "127.0.0.1"
+
+	selectStatement = "SELECT v FROM bench.kv WHERE k = ?"
+	insertStatement = "INSERT INTO bench.kv (k, v) VALUES (?, ?)"
+)
+
+// Config describes the synthetic workload that Run sends through the proxy.
+type Config struct {
+	// Workload is READ (forwarded to the primary cluster only), WRITE (forwarded to both clusters) or MIXED (half of
+	// each).
+	Workload string
+
+	// Connections is the number of client connections to the proxy and Concurrency the number of requests that are
+	// in flight on each connection. The workload is closed-loop, so the throughput is the maximum throughput that the
+	// proxy sustains with Connections * Concurrency requests in flight.
+	Connections int
+	Concurrency int
+
+	Duration time.Duration
+	Warmup   time.Duration
+
+	ProtocolVersion primitive.ProtocolVersion
+
+	// ProxySettings override the settings of the proxy, the keys are the keys of the config file (e.g. read_mode).
+	// The contact points, ports and credentials of the clusters are managed by Run.
+	ProxySettings map[string]string
+}
+
+func NewConfig() *Config {
+	return &Config{
+		Workload:        WorkloadMixed,
+		Connections:     4,
+		Concurrency:     32,
+		Duration:        10 * time.Second,
+		Warmup:          2 * time.Second,
+		ProtocolVersion: primitive.ProtocolVersion4,
+		ProxySettings:   map[string]string{},
+	}
+}
+
+func (c *Config) validate() error {
+	switch c.Workload {
+	case WorkloadRead, WorkloadWrite, WorkloadMixed:
+	default:
+		return fmt.Errorf("invalid workload %v; possible values are: %v, %v and %v",
+			c.Workload, WorkloadRead, WorkloadWrite, WorkloadMixed)
+	}
+	if c.Connections <= 0 || c.Concurrency <= 0 {
+		return fmt.Errorf("the number of connections (%v) and the concurrency (%v) must be greater than 0",
+			c.Connections, c.Concurrency)
+	}
+	if c.Duration <= 0 || c.Warmup < 0 {
+		return fmt.Errorf("the duration (%v) must be greater than 0 and the warmup (%v) must not be negative",
+			c.Duration, c.Warmup)
+	}
+	return nil
+}
+
+// Result is the outcome of the measured part of a run, the warmup is excluded.
+type Result struct {
+	Requests   int64
+	Errors     int64
+	Duration   time.Duration
+	Throughput float64
+
+	P50 time.Duration
+	P99 time.Duration
+	Max time.Duration
+
+	// AllocsPerRequest and BytesPerRequest are the heap allocations of the whole process divided by the number of
+	// requests, so they include the allocations of the mock clients and clusters which are the same for every build
+	// of the proxy.
+	AllocsPerRequest float64
+	BytesPerRequest  float64
+}
+
+func (r *Result) String() string {
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "requests:    %d (%d errors) in %v\n", r.Requests, r.Errors, r.Duration.Round(time.Millisecond))
+	fmt.Fprintf(sb, "throughput:  %.0f requests/s\n", r.Throughput)
+	fmt.Fprintf(sb, "latency:     p50 %v, p99 %v, max %v\n", r.P50, r.P99, r.Max)
+	fmt.Fprintf(sb, "allocations: %.1f allocs/request, %.0f bytes/request\n", r.AllocsPerRequest, r.BytesPerRequest)
+	return sb.String()
+}
+
+// Run starts two mock clusters and a proxy in process, sends the workload through the proxy and reports the latency,
+// throughput and allocations of the requests. Everything is shut down before Run returns.
+func Run(ctx context.Context, conf *Config) (*Result, error) {
+	if err := conf.validate(); err != nil {
+		return nil, err
+	}
+
+	// the mock clusters also queue every request for CqlServerConnection.Receive and log an error for each request
+	// once the queue is full, these logs would dominate the cost of the mock clusters
+	previousLevel := zerolog.GlobalLevel()
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	defer zerolog.SetGlobalLevel(previousLevel)
+
+	ctx, cancelFn := context.WithCancel(ctx)
+	defer cancelFn()
+
+	credentials := &client.AuthCredentials{Username: testkit.DefaultUsername, Password: testkit.DefaultPassword}
+	clusters := make([]*testkit.Cluster, 0, 2)
+	for _, name := range []string{"origin", "target"} {
+		cluster, err := testkit.NewCluster(name, benchHost, 0, credentials)
+		if err != nil {
+			return nil, err
+		}
+		cluster.RecordStatements = false
+		if cluster.CqlServer.MaxConnections < conf.Connections*4 {
+			cluster.CqlServer.MaxConnections = conf.Connections * 4
+		}
+		if cluster.CqlServer.MaxInFlight < conf.Concurrency*4 {
+			cluster.CqlServer.MaxInFlight = conf.Concurrency * 4
+		}
+		cluster.PrimeRows(selectStatement, selectColumns, message.RowSet{{benchValue}})
+		clusters = append(clusters, cluster)
+	}
+	setup := testkit.NewSetupWithClusters(clusters[0], clusters[1])
+	defer setup.Close()
+	if err := setup.Start(ctx, conf.ProxySettings); err != nil {
+		return nil, err
+	}
+
+	connections := make([]*client.CqlClientConnection, 0, conf.Connections)
+	defer func() {
+		for _, connection := range connections {
+			_ = connection.Close()
+		}
+	}()
+	for i := 0; i < conf.Connections; i++ {
+		connection, err := setup.Connect(ctx, conf.ProtocolVersion)
+		if err != nil {
+			return nil, fmt.Errorf("could not connect to proxy: %w", err)
+		}
+		connections = append(connections, connection)
+	}
+
+	if conf.Warmup > 0 {
+		log.Infof("Warming up for %v...", conf.Warmup)
+		runWorkload(ctx, conf, connections, conf.Warmup)
+	}
+
+	log.Infof("Running %v workload for %v...", conf.Workload, conf.Duration)
+	runtime.GC()
+	memStatsBefore := &runtime.MemStats{}
+	runtime.ReadMemStats(memStatsBefore)
+	start := time.Now()
+	stats := runWorkload(ctx, conf, connections, conf.Duration)
+	elapsed := time.Since(start)
+	memStatsAfter := &runtime.MemStats{}
+	runtime.ReadMemStats(memStatsAfter)
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if len(stats.latencies) == 0 {
+		return nil, errors.New("no request completed successfully")
+	}
+
+	requests := int64(len(stats.latencies)) + stats.errors
+	sort.Slice(stats.latencies, func(i, j int) bool { return stats.latencies[i] < stats.latencies[j] })
+	return &Result{
+		Requests:         requests,
+		Errors:           stats.errors,
+		Duration:         elapsed,
+		Throughput:       float64(requests) / elapsed.Seconds(),
+		P50:              percentile(stats.latencies, 0.5),
+		P99:              percentile(stats.latencies, 0.99),
+		Max:              stats.latencies[len(stats.latencies)-1],
+		AllocsPerRequest: float64(memStatsAfter.Mallocs-memStatsBefore.Mallocs) / float64(requests),
+		BytesPerRequest:  float64(memStatsAfter.TotalAlloc-memStatsBefore.TotalAlloc) / float64(requests),
+	}, nil
+}
+
+type workloadStats struct {
+	latencies []time.Duration
+	errors    int64
+}
+
+// runWorkload sends requests on every connection with conf.Concurrency workers per connection until the duration
+// elapses. The latencies are the latencies of the successful requests.
+func runWorkload(
+	ctx context.Context, conf *Config, connections []*client.CqlClientConnection, duration time.Duration) *workloadStats {
+
+	deadline := time.Now().Add(duration)
+	lock := &sync.Mutex{}
+	stats := &workloadStats{}
+	wg := &sync.WaitGroup{}
+	for _, connection := range connections {
+		for i := 0; i < conf.Concurrency; i++ {
+			wg.Add(1)
+			go func(connection *client.CqlClientConnection, worker int) {
+				defer wg.Done()
+				workerStats := &workloadStats{}
+				for n := worker; ctx.Err() == nil && time.Now().Before(deadline); n++ {
+					request := newRequest(conf, n)
+					start := time.Now()
+					response, err := connection.SendAndReceive(request)
+					if err != nil {
+						log.Warnf("Request failed, stopping worker: %v", err)
+						workerStats.errors++
+						break
+					}
+					switch response.Body.Message.(type) {
+					case *message.RowsResult, *message.VoidResult:
+						workerStats.latencies = append(workerStats.latencies, time.Since(start))
+					default:
+						log.Debugf("Unexpected response: %v", response.Body.Message)
+						workerStats.errors++
+					}
+				}
+				lock.Lock()
+				stats.latencies = append(stats.latencies, workerStats.latencies...)
+				stats.errors += workerStats.errors
+				lock.Unlock()
+			}(connection, i)
+		}
+	}
+	wg.Wait()
+	return stats
+}
+
+// newRequest returns a new frame for every request because the client assigns the stream id of the frame.
+func newRequest(conf *Config, n int) *frame.Frame {
+	key := primitive.NewValue([]byte(strconv.Itoa(n)))
+	read := conf.Workload == WorkloadRead || (conf.Workload == WorkloadMixed && n%2 == 0)
+	query := &message.Query{Query: selectStatement, Options: &message.QueryOptions{
+		Consistency:      primitive.ConsistencyLevelLocalQuorum,
+		PositionalValues: []*primitive.Value{key},
+	}}
+	if !read {
+		query.Query = insertStatement
+		query.Options.PositionalValues = append(query.Options.PositionalValues, primitive.NewValue(benchValue))
+	}
+	return frame.NewFrame(conf.ProtocolVersion, client.ManagedStreamId, query)
+}
+
+var benchValue = []byte(strings.Repeat("v", 100))
+
+var selectColumns = []*message.ColumnMetadata{
+	{Keyspace: "bench", Table: "kv", Name: "v", Type: datatype.Blob},
+}
+
+func percentile(sortedLatencies []time.Duration, p float64) time.Duration {
+	idx := int(float64(len(sortedLatencies))*p+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	} else if idx >= len(sortedLatencies) {
+		idx = len(sortedLatencies) - 1
+	}
+	return sortedLatencies[idx]
+}
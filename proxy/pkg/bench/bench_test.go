@@ -0,0 +1,50 @@
+package bench
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	conf := NewConfig()
+	conf.Connections = 2
+	conf.Concurrency = 4
+	conf.Duration = 500 * time.Millisecond
+	conf.Warmup = 100 * time.Millisecond
+	conf.ProxySettings["metrics_enabled"] = "false"
+
+	result, err := Run(context.Background(), conf)
+	require.Nil(t, err)
+	require.Greater(t, result.Requests, int64(0))
+	require.Equal(t, int64(0), result.Errors)
+	require.Greater(t, result.Throughput, float64(0))
+	require.LessOrEqual(t, result.P50, result.P99)
+	require.LessOrEqual(t, result.P99, result.Max)
+	require.Greater(t, result.AllocsPerRequest, float64(0))
+}
+
+func TestRun_InvalidConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Workload = "SCAN"
+	_, err := Run(context.Background(), conf)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "possible values are: READ, WRITE and MIXED")
+
+	conf = NewConfig()
+	conf.ProxySettings["read_mode"] = "invalid"
+	_, err = Run(context.Background(), conf)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "ZDM_READ_MODE")
+}
+
+func TestPercentile(t *testing.T) {
+	latencies := make([]time.Duration, 0, 100)
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i))
+	}
+	require.Equal(t, time.Duration(50), percentile(latencies, 0.5))
+	require.Equal(t, time.Duration(99), percentile(latencies, 0.99))
+	require.Equal(t, time.Duration(1), percentile(latencies[:1], 0.99))
+}
@@ -3,6 +3,9 @@ package common
 import (
 	"fmt"
 	"net"
+	"regexp"
+	"sort"
+	"time"
 )
 
 // TopologyConfig contains configuration parameters for 2 features related to multi zdm-proxy instance deployment:
@@ -55,6 +58,159 @@ func (recv *ProxyTlsConfig) String() string {
 
 }
 
+// MetricsSinksConfig contains the metrics exporters that are enabled, Statsd is nil if the StatsD exporter is disabled.
+type MetricsSinksConfig struct {
+	Prometheus bool
+	Statsd     *StatsdConfig
+}
+
+type StatsdConfig struct {
+	Address       string
+	Prefix        string
+	Tags          []string // key:value pairs added to every metric
+	DogStatsd     bool     // send labels as DogStatsD tags instead of appending them to the metric name
+	FlushInterval time.Duration
+}
+
+func (recv *StatsdConfig) String() string {
+	return fmt.Sprintf("StatsdConfig{Address=%v, Prefix=%v, Tags=%v, DogStatsd=%v, FlushInterval=%v}",
+		recv.Address, recv.Prefix, recv.Tags, recv.DogStatsd, recv.FlushInterval)
+}
+
+// CircuitBreakerConfig contains the thresholds of the circuit breaker that protects the client requests from a
+// failing secondary cluster.
+type CircuitBreakerConfig struct {
+	ErrorRatePercent int // percentage of failed requests in a window that opens the circuit
+	MinRequests      int // minimum number of requests in a window before the error rate is evaluated
+	Window           time.Duration
+	Cooldown         time.Duration // time that the circuit stays open before probing the cluster again
+	HalfOpenProbes   int           // number of successful probes that close the circuit
+}
+
+func (recv *CircuitBreakerConfig) String() string {
+	return fmt.Sprintf("CircuitBreakerConfig{ErrorRatePercent=%v, MinRequests=%v, Window=%v, Cooldown=%v, "+
+		"HalfOpenProbes=%v}", recv.ErrorRatePercent, recv.MinRequests, recv.Window, recv.Cooldown, recv.HalfOpenProbes)
+}
+
+// FaultInjectionConfig contains the probabilities (between 0 and 1) of the faults that are injected in the responses
+// of a cluster.
+type FaultInjectionConfig struct {
+	LatencyProbability float64
+	Latency            time.Duration // delay added to a response
+	DropProbability    float64       // a dropped response makes the request time out
+	ErrorProbability   float64
+	ErrorType          FaultErrorType // error returned instead of the response
+}
+
+func (recv *FaultInjectionConfig) String() string {
+	return fmt.Sprintf("FaultInjectionConfig{LatencyProbability=%v, Latency=%v, DropProbability=%v, "+
+		"ErrorProbability=%v, ErrorType=%v}",
+		recv.LatencyProbability, recv.Latency, recv.DropProbability, recv.ErrorProbability, recv.ErrorType)
+}
+
+// FleetConfig contains the settings of the coordination backend from which the proxies of a fleet read their shared
+// runtime state.
+type FleetConfig struct {
+	Backend      string // ETCD or CONSUL
+	Endpoint     string // base URL of the HTTP API of the backend
+	Key          string
+	Token        string // may be a secret reference
+	PollInterval time.Duration
+}
+
+func (recv *FleetConfig) String() string {
+	return fmt.Sprintf("FleetConfig{Backend=%v, Endpoint=%v, Key=%v, PollInterval=%v}",
+		recv.Backend, recv.Endpoint, recv.Key, recv.PollInterval)
+}
+
+// FailedWritesJournalConfig contains the settings of the journal of writes that were applied to ORIGIN but not
+// to TARGET.
+type FailedWritesJournalConfig struct {
+	Path         string
+	MaxSizeBytes int64 // 0 means unlimited
+	QueueSize    int
+}
+
+func (recv *FailedWritesJournalConfig) String() string {
+	return fmt.Sprintf("FailedWritesJournalConfig{Path=%v, MaxSizeBytes=%v, QueueSize=%v}",
+		recv.Path, recv.MaxSizeBytes, recv.QueueSize)
+}
+
+// AuditLogConfig contains the settings of the audit log, the sinks that are disabled have an empty path or address.
+type AuditLogConfig struct {
+	FilePath          string
+	SyslogNetwork     string // udp, tcp or unixgram
+	SyslogAddress     string
+	KafkaRestUrl      string // base URL of a Kafka REST proxy
+	KafkaTopic        string
+	Categories        map[AuditCategory]bool
+	IncludeStatements bool
+	QueueSize         int
+}
+
+func (recv *AuditLogConfig) String() string {
+	categories := make([]string, 0, len(recv.Categories))
+	for category := range recv.Categories {
+		categories = append(categories, category.String())
+	}
+	sort.Strings(categories)
+	return fmt.Sprintf("AuditLogConfig{FilePath=%v, SyslogNetwork=%v, SyslogAddress=%v, KafkaRestUrl=%v, KafkaTopic=%v, "+
+		"Categories=%v, IncludeStatements=%v, QueueSize=%v}", recv.FilePath, recv.SyslogNetwork, recv.SyslogAddress,
+		recv.KafkaRestUrl, recv.KafkaTopic, categories, recv.IncludeStatements, recv.QueueSize)
+}
+
+// RetryPolicy configures the retries of the requests that failed on a cluster with a transient error.
+type RetryPolicy struct {
+	MaxAttempts int // retries after the first attempt, 0 disables retries
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// GetDelay returns the exponential backoff delay before the provided retry attempt (starting at 1).
+func (recv *RetryPolicy) GetDelay(attempt int) time.Duration {
+	delay := recv.BaseDelay
+	for i := 1; i < attempt && delay < recv.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > recv.MaxDelay {
+		return recv.MaxDelay
+	}
+	return delay
+}
+
+func (recv *RetryPolicy) String() string {
+	return fmt.Sprintf("RetryPolicy{MaxAttempts=%v, BaseDelay=%v, MaxDelay=%v}",
+		recv.MaxAttempts, recv.BaseDelay, recv.MaxDelay)
+}
+
+// RequestTimeouts configures how long the proxy waits for the responses of each type of request.
+type RequestTimeouts struct {
+	Default time.Duration // requests that are not reads, writes, PREPARE or DDL (e.g. OPTIONS, REGISTER)
+	Read    time.Duration
+	Write   time.Duration
+	Prepare time.Duration
+	Ddl     time.Duration
+
+	// upper bound of the timeouts requested by clients with a custom payload or a query comment
+	MaxOverride time.Duration
+}
+
+// Max returns the longest timeout that a request can have.
+func (recv *RequestTimeouts) Max() time.Duration {
+	max := recv.MaxOverride
+	for _, timeout := range []time.Duration{recv.Default, recv.Read, recv.Write, recv.Prepare, recv.Ddl} {
+		if timeout > max {
+			max = timeout
+		}
+	}
+	return max
+}
+
+func (recv *RequestTimeouts) String() string {
+	return fmt.Sprintf("RequestTimeouts{Default=%v, Read=%v, Write=%v, Prepare=%v, Ddl=%v, MaxOverride=%v}",
+		recv.Default, recv.Read, recv.Write, recv.Prepare, recv.Ddl, recv.MaxOverride)
+}
+
 type ReadMode struct {
 	slug string
 }
@@ -69,6 +225,83 @@ var (
 	ReadModeDualAsyncOnSecondary = ReadMode{"DUAL_ASYNC_ON_SECONDARY"}
 )
 
+// MigrationPhase is a step of the migration. Each phase implies the primary cluster, the read mode and which clusters
+// receive the writes, the phases are ordered and a migration moves forward (or rolls back) one phase at a time.
+type MigrationPhase struct {
+	slug string
+}
+
+func (r MigrationPhase) String() string {
+	return r.slug
+}
+
+var (
+	MigrationPhaseUndefined = MigrationPhase{""}
+
+	// MigrationPhaseShadow mirrors the writes to TARGET without waiting for its responses.
+	MigrationPhaseShadow = MigrationPhase{"SHADOW"}
+	// MigrationPhaseDualWrite sends the writes to both clusters and the reads to ORIGIN.
+	MigrationPhaseDualWrite = MigrationPhase{"DUAL_WRITE"}
+	// MigrationPhaseDualWriteAsyncRead also sends the reads to TARGET asynchronously.
+	MigrationPhaseDualWriteAsyncRead = MigrationPhase{"DUAL_WRITE_ASYNC_READ"}
+	// MigrationPhaseTargetPrimary sends the writes to both clusters and the reads to TARGET.
+	MigrationPhaseTargetPrimary = MigrationPhase{"TARGET_PRIMARY"}
+	// MigrationPhaseOriginDecommissioned sends the writes and the reads to TARGET only, it is the last phase.
+	MigrationPhaseOriginDecommissioned = MigrationPhase{"ORIGIN_DECOMMISSIONED"}
+)
+
+// MigrationPhases are the phases in the order of the migration.
+var MigrationPhases = []MigrationPhase{
+	MigrationPhaseShadow,
+	MigrationPhaseDualWrite,
+	MigrationPhaseDualWriteAsyncRead,
+	MigrationPhaseTargetPrimary,
+	MigrationPhaseOriginDecommissioned,
+}
+
+func (r MigrationPhase) PrimaryCluster() ClusterType {
+	if r == MigrationPhaseTargetPrimary || r == MigrationPhaseOriginDecommissioned {
+		return ClusterTypeTarget
+	}
+	return ClusterTypeOrigin
+}
+
+func (r MigrationPhase) ReadMode() ReadMode {
+	if r == MigrationPhaseDualWriteAsyncRead {
+		return ReadModeDualAsyncOnSecondary
+	}
+	return ReadModePrimaryOnly
+}
+
+func (r MigrationPhase) ShadowModeEnabled() bool {
+	return r == MigrationPhaseShadow
+}
+
+func (r MigrationPhase) OriginWritesEnabled() bool {
+	return r != MigrationPhaseOriginDecommissioned
+}
+
+// AllowedTransitions returns the phases that can follow this one: the next phase and, to roll back, the previous one.
+// ORIGIN is not up to date anymore once it was decommissioned, so there is no transition from the last phase.
+func (r MigrationPhase) AllowedTransitions() []MigrationPhase {
+	if r == MigrationPhaseOriginDecommissioned {
+		return nil
+	}
+	var transitions []MigrationPhase
+	for i, phase := range MigrationPhases {
+		if phase != r {
+			continue
+		}
+		if i+1 < len(MigrationPhases) {
+			transitions = append(transitions, MigrationPhases[i+1])
+		}
+		if i > 0 {
+			transitions = append(transitions, MigrationPhases[i-1])
+		}
+	}
+	return transitions
+}
+
 type SystemQueriesMode struct {
 	slug string
 }
@@ -83,6 +316,241 @@ var (
 	SystemQueriesModeTarget    = SystemQueriesMode{"TARGET"}
 )
 
+type LwtPolicy struct {
+	slug string
+}
+
+func (r LwtPolicy) String() string {
+	return r.slug
+}
+
+var (
+	LwtPolicyUndefined   = LwtPolicy{""}
+	LwtPolicyBoth        = LwtPolicy{"BOTH"}
+	LwtPolicyPrimaryOnly = LwtPolicy{"PRIMARY_ONLY"}
+	LwtPolicyReject      = LwtPolicy{"REJECT"}
+)
+
+type CounterWritePolicy struct {
+	slug string
+}
+
+func (r CounterWritePolicy) String() string {
+	return r.slug
+}
+
+var (
+	CounterWritePolicyUndefined   = CounterWritePolicy{""}
+	CounterWritePolicyBoth        = CounterWritePolicy{"BOTH"}
+	CounterWritePolicyPrimaryOnly = CounterWritePolicy{"PRIMARY_ONLY"}
+	CounterWritePolicyReject      = CounterWritePolicy{"REJECT"}
+)
+
+type DdlPolicy struct {
+	slug string
+}
+
+func (r DdlPolicy) String() string {
+	return r.slug
+}
+
+var (
+	DdlPolicyUndefined  = DdlPolicy{""}
+	DdlPolicyBoth       = DdlPolicy{"BOTH"}
+	DdlPolicyOriginOnly = DdlPolicy{"ORIGIN_ONLY"}
+	DdlPolicyReject     = DdlPolicy{"REJECT"}
+)
+
+// AuditCategory is the category of the statements recorded by the audit log.
+type AuditCategory struct {
+	slug string
+}
+
+func (r AuditCategory) String() string {
+	return r.slug
+}
+
+var (
+	AuditCategoryUndefined = AuditCategory{""}
+	AuditCategorySelect    = AuditCategory{"SELECT"}
+	AuditCategoryDml       = AuditCategory{"DML"}
+	AuditCategoryDdl       = AuditCategory{"DDL"}
+	AuditCategoryDcl       = AuditCategory{"DCL"}
+)
+
+// LogRedactionMode is how the literals and bound values of the statements are masked in the proxy logs.
+type LogRedactionMode struct {
+	slug string
+}
+
+func (r LogRedactionMode) String() string {
+	return r.slug
+}
+
+var (
+	LogRedactionModeUndefined = LogRedactionMode{""}
+	LogRedactionModeNone      = LogRedactionMode{"NONE"}
+	LogRedactionModeFull      = LogRedactionMode{"FULL"}
+	LogRedactionModeHash      = LogRedactionMode{"HASH"}
+	LogRedactionModeLength    = LogRedactionMode{"LENGTH"}
+)
+
+// FaultErrorType is the error response that fault injection returns instead of the response of a cluster.
+type FaultErrorType struct {
+	slug string
+}
+
+func (r FaultErrorType) String() string {
+	return r.slug
+}
+
+var (
+	FaultErrorTypeUndefined    = FaultErrorType{""}
+	FaultErrorTypeOverloaded   = FaultErrorType{"OVERLOADED"}
+	FaultErrorTypeServerError  = FaultErrorType{"SERVER_ERROR"}
+	FaultErrorTypeUnavailable  = FaultErrorType{"UNAVAILABLE"}
+	FaultErrorTypeReadTimeout  = FaultErrorType{"READ_TIMEOUT"}
+	FaultErrorTypeWriteTimeout = FaultErrorType{"WRITE_TIMEOUT"}
+)
+
+type EventSourcePolicy struct {
+	slug string
+}
+
+func (r EventSourcePolicy) String() string {
+	return r.slug
+}
+
+var (
+	EventSourcePolicyUndefined   = EventSourcePolicy{""}
+	EventSourcePolicyPrimaryOnly = EventSourcePolicy{"PRIMARY_ONLY"}
+	EventSourcePolicyBoth        = EventSourcePolicy{"BOTH"}
+	EventSourcePolicyNone        = EventSourcePolicy{"NONE"}
+)
+
+// PropagationPolicy is how the warnings or the custom payloads of the cluster responses are returned to the client.
+type PropagationPolicy struct {
+	slug string
+}
+
+func (r PropagationPolicy) String() string {
+	return r.slug
+}
+
+var (
+	PropagationPolicyUndefined   = PropagationPolicy{""}
+	PropagationPolicyPrimaryOnly = PropagationPolicy{"PRIMARY_ONLY"}
+	PropagationPolicyMerge       = PropagationPolicy{"MERGE"}
+	PropagationPolicyStrip       = PropagationPolicy{"STRIP"}
+)
+
+// SystemQueriesBypass is the client connections whose system.local and system.peers queries aren't intercepted when
+// the proxy topology is virtualized, they are matched by the address of the client or by the application name of the
+// STARTUP request.
+type SystemQueriesBypass struct {
+	ClientNetworks   []*net.IPNet
+	ApplicationNames map[string]bool
+}
+
+func (recv *SystemQueriesBypass) MatchesClientAddress(ip net.IP) bool {
+	for _, network := range recv.ClientNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (recv *SystemQueriesBypass) MatchesApplicationName(name string) bool {
+	return name != "" && recv.ApplicationNames[name]
+}
+
+// HostSelectionPolicy is how the proxy selects the host of a cluster that a client connection is assigned to.
+type HostSelectionPolicy struct {
+	slug string
+}
+
+func (r HostSelectionPolicy) String() string {
+	return r.slug
+}
+
+var (
+	HostSelectionPolicyUndefined        = HostSelectionPolicy{""}
+	HostSelectionPolicyRoundRobin       = HostSelectionPolicy{"ROUND_ROBIN"}
+	HostSelectionPolicyLeastConnections = HostSelectionPolicy{"LEAST_CONNECTIONS"}
+)
+
+type IpFamilyPreference struct {
+	slug string
+}
+
+func (r IpFamilyPreference) String() string {
+	return r.slug
+}
+
+var (
+	IpFamilyPreferenceUndefined = IpFamilyPreference{""}
+	IpFamilyPreferenceV4        = IpFamilyPreference{"V4"}
+	IpFamilyPreferenceV6        = IpFamilyPreference{"V6"}
+)
+
+type ProxyProtocolMode struct {
+	slug string
+}
+
+func (r ProxyProtocolMode) String() string {
+	return r.slug
+}
+
+var (
+	ProxyProtocolModeUndefined = ProxyProtocolMode{""}
+	ProxyProtocolModeDisabled  = ProxyProtocolMode{"DISABLED"}
+	ProxyProtocolModeOptional  = ProxyProtocolMode{"OPTIONAL"}
+	ProxyProtocolModeRequired  = ProxyProtocolMode{"REQUIRED"}
+)
+
+// FilterIpsByFamily returns the addresses of the preferred family if there is at least one, otherwise it returns the
+// addresses of the other family. The order of the addresses is preserved.
+func FilterIpsByFamily(ips []net.IP, preference IpFamilyPreference) []net.IP {
+	v4 := make([]net.IP, 0, len(ips))
+	v6 := make([]net.IP, 0, len(ips))
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			v4 = append(v4, ip)
+		} else if ip.To16() != nil {
+			v6 = append(v6, ip)
+		}
+	}
+	if preference == IpFamilyPreferenceV6 {
+		if len(v6) > 0 {
+			return v6
+		}
+		return v4
+	}
+	if len(v4) > 0 {
+		return v4
+	}
+	return v6
+}
+
+// SortIpsByFamily returns the addresses of the preferred family followed by the addresses of the other family. The
+// order of the addresses of each family is preserved.
+func SortIpsByFamily(ips []net.IP, preference IpFamilyPreference) []net.IP {
+	v4 := make([]net.IP, 0, len(ips))
+	v6 := make([]net.IP, 0, len(ips))
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			v4 = append(v4, ip)
+		} else if ip.To16() != nil {
+			v6 = append(v6, ip)
+		}
+	}
+	if preference == IpFamilyPreferenceV6 {
+		return append(v6, v4...)
+	}
+	return append(v4, v6...)
+}
+
 type ClusterType string
 
 const (
@@ -90,3 +558,87 @@ const (
 	ClusterTypeOrigin = ClusterType("ORIGIN")
 	ClusterTypeTarget = ClusterType("TARGET")
 )
+
+// QueryRewriteRule replaces the matches of a regular expression in the query strings of the statements that it
+// applies to before they are forwarded to ORIGIN, TARGET or both. Statements are selected by statement type (SELECT,
+// INSERT, UPDATE, DELETE, USE or OTHER), keyspace and table, empty values match every statement. The statements of a
+// BATCH are rewritten individually.
+type QueryRewriteRule struct {
+	Name           string   `json:"name"`
+	Cluster        string   `json:"cluster"`
+	StatementTypes []string `json:"statement_types"`
+	Keyspace       string   `json:"keyspace"`
+	Table          string   `json:"table"`
+	Match          string   `json:"match"`
+	Replace        string   `json:"replace"`
+
+	// set by config.ParseQueryRewriteRules
+	MatchRegexp   *regexp.Regexp `json:"-"`
+	ApplyToOrigin bool           `json:"-"`
+	ApplyToTarget bool           `json:"-"`
+}
+
+// NameMapping maps keyspace and table names of ORIGIN to the names that are used on TARGET. Table mappings take
+// precedence over the keyspace mapping of the keyspace that the table belongs to.
+type NameMapping struct {
+	Keyspaces map[string]string
+	Tables    map[QualifiedTableName]QualifiedTableName
+}
+
+type QualifiedTableName struct {
+	Keyspace string
+	Table    string
+}
+
+// MapName returns the TARGET names of an ORIGIN keyspace and table, or of a keyspace if table is empty. Names that
+// are not mapped are returned unchanged.
+func (recv *NameMapping) MapName(keyspace string, table string) (string, string) {
+	if table != "" {
+		if mapped, ok := recv.Tables[QualifiedTableName{Keyspace: keyspace, Table: table}]; ok {
+			return mapped.Keyspace, mapped.Table
+		}
+	}
+	if mapped, ok := recv.Keyspaces[keyspace]; ok {
+		return mapped, table
+	}
+	return keyspace, table
+}
+
+// OriginKeyspace returns the ORIGIN keyspace that is mapped to a TARGET keyspace, or the same keyspace if there is
+// no such mapping.
+func (recv *NameMapping) OriginKeyspace(targetKeyspace string) string {
+	for originKeyspace, mapped := range recv.Keyspaces {
+		if mapped == targetKeyspace {
+			return originKeyspace
+		}
+	}
+	return targetKeyspace
+}
+
+// TableRouting sends the reads and writes of some keyspaces and tables to a single cluster. Table rules take
+// precedence over the rule of the keyspace that the table belongs to.
+type TableRouting struct {
+	Keyspaces map[string]ClusterType
+	Tables    map[QualifiedTableName]ClusterType
+}
+
+// GetCluster returns the cluster that the requests of a table are routed to, or false if there is no rule for
+// the table or its keyspace.
+func (recv *TableRouting) GetCluster(keyspace string, table string) (ClusterType, bool) {
+	if cluster, ok := recv.Tables[QualifiedTableName{Keyspace: keyspace, Table: table}]; ok {
+		return cluster, true
+	}
+	cluster, ok := recv.Keyspaces[keyspace]
+	return cluster, ok
+}
+
+// CredentialMapping associates the credentials that an application uses to authenticate with the proxy
+// to the credentials that the proxy uses to connect to each cluster on behalf of that application.
+type CredentialMapping struct {
+	ClientUsername string `json:"client_username"`
+	ClientPassword string `json:"client_password"`
+	OriginUsername string `json:"origin_username"`
+	OriginPassword string `json:"origin_password"`
+	TargetUsername string `json:"target_username"`
+	TargetPassword string `json:"target_password"`
+}
@@ -3,12 +3,20 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
 	"github.com/datastax/zdm-proxy/proxy/pkg/common"
 	"github.com/kelseyhightower/envconfig"
 	log "github.com/sirupsen/logrus"
+	"io/ioutil"
 	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds the values of environment variables necessary for proper Proxy function.
@@ -16,58 +24,190 @@ type Config struct {
 
 	// Global bucket
 
-	PrimaryCluster          string `default:"ORIGIN" split_words:"true"`
-	ReadMode                string `default:"PRIMARY_ONLY" split_words:"true"`
-	ReplaceCqlFunctions     bool   `default:"false" split_words:"true"`
-	AsyncHandshakeTimeoutMs int    `default:"4000" split_words:"true"`
-	LogLevel                string `default:"INFO" split_words:"true"`
+	MigrationPhase               string `split_words:"true"`
+	PrimaryCluster               string `default:"ORIGIN" split_words:"true"`
+	ReadMode                     string `default:"PRIMARY_ONLY" split_words:"true"`
+	ShadowModeEnabled            bool   `default:"false" split_words:"true"`
+	SpeculativeReadThresholdMs   int    `default:"0" split_words:"true"`
+	LwtPolicy                    string `default:"BOTH" split_words:"true"`
+	CounterWritePolicy           string `default:"BOTH" split_words:"true"`
+	DdlPolicy                    string `default:"BOTH" split_words:"true"`
+	DdlSchemaAgreementTimeoutMs  int    `default:"0" split_words:"true"`
+	SchemaDriftCheckIntervalMs   int    `default:"0" split_words:"true"`
+	SchemaDriftKeyspaces         string `split_words:"true"`
+	EventSourcePolicy            string `default:"PRIMARY_ONLY" split_words:"true"`
+	EventDedupWindowMs           int    `default:"1000" split_words:"true"`
+	WarningsPolicy               string `default:"PRIMARY_ONLY" split_words:"true"`
+	CustomPayloadPolicy          string `default:"PRIMARY_ONLY" split_words:"true"`
+	ReplaceCqlFunctions          bool   `default:"false" split_words:"true"`
+	QualifyUnqualifiedStatements bool   `default:"false" split_words:"true"`
+	QueryRewriteRulesFile        string `split_words:"true"`
+	QueryRewriteDryRun           bool   `default:"false" split_words:"true"`
+	TargetNameMapping            string `split_words:"true"`
+	TableRoutingRules            string `split_words:"true"`
+	ReprepareOnUnprepared        bool   `default:"true" split_words:"true"`
+	InjectWriteTimestamp         bool   `default:"false" split_words:"true"`
+	TagPagingStates              bool   `default:"true" split_words:"true"`
+	OriginConsistencyOverride    string `split_words:"true"`
+	TargetConsistencyOverride    string `split_words:"true"`
+	RetryIdempotentTables        string `split_words:"true"`
+	AsyncHandshakeTimeoutMs      int    `default:"4000" split_words:"true"`
+	IpFamilyPreference           string `default:"V4" split_words:"true"`
+	DnsCacheMinTtlMs             int    `default:"1000" split_words:"true"`
+	DnsCacheMaxTtlMs             int    `default:"300000" split_words:"true"`
+	LogLevel                     string `default:"INFO" split_words:"true"`
+	LogFormat                    string `default:"TEXT" split_words:"true"`
+	LogRedaction                 string `default:"NONE" split_words:"true"`
+	LogRedactionHashKey          string `split_words:"true" json:"-"`
 
 	// Proxy Topology (also known as system.peers "virtualization") bucket
 
-	ProxyTopologyIndex     int    `default:"0" split_words:"true"`
+	ProxyTopologyIndex     int    `default:"-1" split_words:"true"`
 	ProxyTopologyAddresses string `split_words:"true"`
 	ProxyTopologyNumTokens int    `default:"8" split_words:"true"`
 
+	// system.local and system.peers queries are only intercepted if the proxy topology is virtualized
+	SystemQueriesCacheTtlMs         int    `default:"0" split_words:"true"`
+	SystemQueriesBypassClients      string `split_words:"true"`
+	SystemQueriesBypassApplications string `split_words:"true"`
+
 	// Origin bucket
 
 	OriginContactPoints           string `split_words:"true"`
 	OriginPort                    int    `default:"9042" split_words:"true"`
 	OriginSecureConnectBundlePath string `split_words:"true"`
 	OriginLocalDatacenter         string `split_words:"true"`
+	OriginRemoteDatacenters       string `split_words:"true"`
 	OriginUsername                string `required:"true" split_words:"true"`
 	OriginPassword                string `required:"true" split_words:"true" json:"-"`
 	OriginConnectionTimeoutMs     int    `default:"30000" split_words:"true"`
+	OriginHostSelectionPolicy     string `default:"ROUND_ROBIN" split_words:"true"`
+
+	OriginEgressProxyUrl      string `split_words:"true"`
+	OriginEgressProxyUsername string `split_words:"true"`
+	OriginEgressProxyPassword string `split_words:"true" json:"-"`
 
 	OriginTlsServerCaPath   string `split_words:"true"`
 	OriginTlsClientCertPath string `split_words:"true"`
 	OriginTlsClientKeyPath  string `split_words:"true"`
 
+	OriginRetryMaxAttempts int `default:"0" split_words:"true"`
+	OriginRetryBaseDelayMs int `default:"100" split_words:"true"`
+	OriginRetryMaxDelayMs  int `default:"1000" split_words:"true"`
+
 	// Target bucket
 
 	TargetContactPoints           string `split_words:"true"`
 	TargetPort                    int    `default:"9042" split_words:"true"`
 	TargetSecureConnectBundlePath string `split_words:"true"`
 	TargetLocalDatacenter         string `split_words:"true"`
+	TargetRemoteDatacenters       string `split_words:"true"`
 	TargetUsername                string `required:"true" split_words:"true"`
 	TargetPassword                string `required:"true" split_words:"true" json:"-"`
 	TargetConnectionTimeoutMs     int    `default:"30000" split_words:"true"`
+	TargetHostSelectionPolicy     string `default:"ROUND_ROBIN" split_words:"true"`
+
+	TargetEgressProxyUrl      string `split_words:"true"`
+	TargetEgressProxyUsername string `split_words:"true"`
+	TargetEgressProxyPassword string `split_words:"true" json:"-"`
 
 	TargetTlsServerCaPath   string `split_words:"true"`
 	TargetTlsClientCertPath string `split_words:"true"`
 	TargetTlsClientKeyPath  string `split_words:"true"`
 
+	TargetRetryMaxAttempts int `default:"0" split_words:"true"`
+	TargetRetryBaseDelayMs int `default:"100" split_words:"true"`
+	TargetRetryMaxDelayMs  int `default:"1000" split_words:"true"`
+
+	TargetCircuitBreakerEnabled          bool `default:"false" split_words:"true"`
+	TargetCircuitBreakerErrorRatePercent int  `default:"50" split_words:"true"`
+	TargetCircuitBreakerMinRequests      int  `default:"20" split_words:"true"`
+	TargetCircuitBreakerWindowMs         int  `default:"10000" split_words:"true"`
+	TargetCircuitBreakerCooldownMs       int  `default:"30000" split_words:"true"`
+	TargetCircuitBreakerHalfOpenProbes   int  `default:"5" split_words:"true"`
+
+	FailedWritesJournalEnabled   bool   `default:"false" split_words:"true"`
+	FailedWritesJournalPath      string `default:"zdm-failed-writes.jsonl" split_words:"true"`
+	FailedWritesJournalMaxSizeMb int    `default:"1024" split_words:"true"`
+	FailedWritesJournalQueueSize int    `default:"10000" split_words:"true"`
+
+	AuditLogSinks             string `split_words:"true"`
+	AuditLogCategories        string `default:"DML,DDL,DCL" split_words:"true"`
+	AuditLogIncludeStatements bool   `default:"false" split_words:"true"`
+	AuditLogQueueSize         int    `default:"10000" split_words:"true"`
+	AuditLogFilePath          string `default:"zdm-audit.jsonl" split_words:"true"`
+	AuditLogSyslogAddress     string `default:"unix:///dev/log" split_words:"true"`
+	AuditLogKafkaRestUrl      string `split_words:"true"`
+	AuditLogKafkaTopic        string `default:"zdm-audit" split_words:"true"`
+
+	FleetConfigBackend        string `split_words:"true"`
+	FleetConfigEndpoint       string `split_words:"true"`
+	FleetConfigKey            string `default:"zdm-proxy/state" split_words:"true"`
+	FleetConfigToken          string `split_words:"true" json:"-"`
+	FleetConfigPollIntervalMs int    `default:"2000" split_words:"true"`
+
+	FaultInjectionEnabled                  bool    `default:"false" split_words:"true"`
+	FaultInjectionOriginLatencyProbability float64 `default:"0" split_words:"true"`
+	FaultInjectionOriginLatencyMs          int     `default:"100" split_words:"true"`
+	FaultInjectionOriginDropProbability    float64 `default:"0" split_words:"true"`
+	FaultInjectionOriginErrorProbability   float64 `default:"0" split_words:"true"`
+	FaultInjectionOriginErrorType          string  `default:"OVERLOADED" split_words:"true"`
+	FaultInjectionTargetLatencyProbability float64 `default:"0" split_words:"true"`
+	FaultInjectionTargetLatencyMs          int     `default:"100" split_words:"true"`
+	FaultInjectionTargetDropProbability    float64 `default:"0" split_words:"true"`
+	FaultInjectionTargetErrorProbability   float64 `default:"0" split_words:"true"`
+	FaultInjectionTargetErrorType          string  `default:"OVERLOADED" split_words:"true"`
+
 	// Proxy bucket
 
-	ProxyListenAddress        string `default:"localhost" split_words:"true"`
-	ProxyListenPort           int    `default:"14002" split_words:"true"`
-	ProxyRequestTimeoutMs     int    `default:"10000" split_words:"true"`
-	ProxyMaxClientConnections int    `default:"1000" split_words:"true"`
+	ProxyListenAddress             string `default:"localhost" split_words:"true"`
+	ProxyListenPort                int    `default:"14002" split_words:"true"`
+	ProxyListenSocketPath          string `split_words:"true"`
+	ProxyListenSocketPermissions   string `default:"0660" split_words:"true"`
+	ProxyListenProxyProtocol       string `default:"DISABLED" split_words:"true"`
+	ProxyListenSocketProxyProtocol string `default:"DISABLED" split_words:"true"`
+	ProxyProtocolHeaderTimeoutMs   int    `default:"5000" split_words:"true"`
+	ProxyRequestTimeoutMs          int    `default:"10000" split_words:"true"`
+	ProxyMaxClientConnections      int    `default:"1000" split_words:"true"`
+	ProxyClientIdleTimeoutMs       int    `default:"0" split_words:"true"`
+	ProxyClientTcpKeepAliveMs      int    `default:"15000" split_words:"true"`
+
+	ProxyReadRequestTimeoutMs        int  `default:"0" split_words:"true"`
+	ProxyWriteRequestTimeoutMs       int  `default:"0" split_words:"true"`
+	ProxyPrepareRequestTimeoutMs     int  `default:"0" split_words:"true"`
+	ProxyDdlRequestTimeoutMs         int  `default:"0" split_words:"true"`
+	ProxyMaxRequestTimeoutOverrideMs int  `default:"600000" split_words:"true"`
+	ProxyRequestDeadlinesEnabled     bool `default:"false" split_words:"true"`
+
+	ProxyMaxPreparedStatementCacheSize int `default:"5000" split_words:"true"`
+
+	// frames with a larger body are rejected without being buffered, 0 disables the limit
+	ProxyMaxRequestFrameSizeBytes  int `default:"268435456" split_words:"true"`
+	ProxyMaxResponseFrameSizeBytes int `default:"268435456" split_words:"true"`
+
+	// reads with a larger response body are logged with a warning, 0 disables the warning
+	ProxyLargeResponseWarningThresholdBytes int `default:"0" split_words:"true"`
+
+	ProxyAnswerOptions    bool   `default:"false" split_words:"true"`
+	ProxySupportedOptions string `split_words:"true"`
+
+	// translate the frames of clients that use a protocol version that only one of the clusters supports
+	ProtocolVersionTranslationEnabled bool `default:"false" split_words:"true"`
 
 	ProxyTlsCaPath            string `split_words:"true"`
 	ProxyTlsCertPath          string `split_words:"true"`
 	ProxyTlsKeyPath           string `split_words:"true"`
 	ProxyTlsRequireClientAuth bool   `split_words:"true"`
 
+	ProxyClientUsername string `split_words:"true"`
+	ProxyClientPassword string `split_words:"true" json:"-"`
+
+	ProxyCredentialMappingFile string `split_words:"true"`
+
+	ProxyIntrospectionEnabled bool `default:"false" split_words:"true"`
+
+	SecretsRefreshIntervalMs int `default:"300000" split_words:"true"`
+
 	// Metrics bucket
 
 	MetricsEnabled bool   `default:"true" split_words:"true"`
@@ -78,14 +218,58 @@ type Config struct {
 	MetricsTargetLatencyBucketsMs    string `default:"1, 4, 7, 10, 25, 40, 60, 80, 100, 150, 250, 500, 1000, 2500, 5000, 10000, 15000" split_words:"true"`
 	MetricsAsyncReadLatencyBucketsMs string `default:"1, 4, 7, 10, 25, 40, 60, 80, 100, 150, 250, 500, 1000, 2500, 5000, 10000, 15000" split_words:"true"`
 
+	MetricsSinks                 string `default:"PROMETHEUS" split_words:"true"`
+	MetricsStatsdAddress         string `default:"localhost:8125" split_words:"true"`
+	MetricsStatsdPrefix          string `default:"zdm" split_words:"true"`
+	MetricsStatsdTags            string `split_words:"true"`
+	MetricsStatsdFlavor          string `default:"DOGSTATSD" split_words:"true"`
+	MetricsStatsdFlushIntervalMs int    `default:"10000" split_words:"true"`
+
+	MetricsTableRequestsEnabled   bool   `default:"false" split_words:"true"`
+	MetricsTableRequestsAllowList string `split_words:"true"`
+	MetricsTableRequestsMaxTables int    `default:"100" split_words:"true"`
+
+	MetricsApplicationsEnabled bool `default:"false" split_words:"true"`
+	MetricsApplicationsMax     int  `default:"100" split_words:"true"`
+
+	// the /admin/ endpoints change the state of the proxy, they are served on their own address and port and only if
+	// enabled because the metrics listener has no authentication
+	AdminEndpointEnabled bool   `default:"false" split_words:"true"`
+	AdminAddress         string `default:"localhost" split_words:"true"`
+	AdminPort            int    `default:"14002" split_words:"true"`
+
+	// served on the admin address and port, requires AdminEndpointEnabled
+	DebugEndpointEnabled bool   `default:"false" split_words:"true"`
+	DebugDumpDirectory   string `split_words:"true"`
+
 	// Heartbeat bucket
 
 	HeartbeatIntervalMs int `default:"30000" split_words:"true"`
 
-	HeartbeatRetryIntervalMinMs int     `default:"250" split_words:"true"`
-	HeartbeatRetryIntervalMaxMs int     `default:"30000" split_words:"true"`
-	HeartbeatRetryBackoffFactor float64 `default:"2" split_words:"true"`
-	HeartbeatFailureThreshold   int     `default:"1" split_words:"true"`
+	// reconnect policy of the control connections, the cluster is reported as down by the health check after
+	// HeartbeatFailureThreshold consecutive failed heartbeats or reconnection attempts
+	HeartbeatRetryIntervalMinMs   int     `default:"250" split_words:"true"`
+	HeartbeatRetryIntervalMaxMs   int     `default:"30000" split_words:"true"`
+	HeartbeatRetryBackoffFactor   float64 `default:"2" split_words:"true"`
+	HeartbeatFailureThreshold     int     `default:"1" split_words:"true"`
+	HeartbeatRefreshContactPoints bool    `default:"true" split_words:"true"`
+
+	// heartbeats of the Origin, Target and async request connections, 0 disables them
+	RequestConnectionHeartbeatIntervalMs int `default:"30000" split_words:"true"`
+	RequestConnectionHeartbeatTimeoutMs  int `default:"5000" split_words:"true"`
+
+	// reconnects the Origin and Target request connections to another host of the local DC when their host is lost
+	RequestConnectionFailoverEnabled     bool `default:"false" split_words:"true"`
+	RequestConnectionFailoverMaxAttempts int  `default:"3" split_words:"true"`
+
+	// assigns the stream ids of the Origin and Target request connections instead of reusing the stream ids of the
+	// client, an additional request connection is opened when all of them are in use if the overflow is enabled and the
+	// stream ids of the requests lost in a failover are released after the longest request timeout
+	StreamIdVirtualizationEnabled     bool `default:"false" split_words:"true"`
+	RequestConnectionMaxStreamIds     int  `default:"32768" split_words:"true"`
+	StreamIdOverflowConnectionEnabled bool `default:"false" split_words:"true"`
+
+	ContactPointsRefreshIntervalMs int `default:"60000" split_words:"true"`
 
 	//////////////////////////////////////////////////////////////////////
 	/// THE SETTINGS BELOW AREN'T SUPPORTED AND MAY CHANGE AT ANY TIME ///
@@ -110,22 +294,69 @@ type Config struct {
 	ResponseWriteBufferSizeBytes int `default:"8192" split_words:"true"`
 	ResponseReadBufferSizeBytes  int `default:"32768" split_words:"true"`
 
+	// write buffers larger than this are not reused, 0 disables the buffer pool
+	WriteBufferPoolMaxBufferSizeBytes int `default:"1048576" split_words:"true"`
+
 	RequestResponseMaxWorkers int `default:"-1" split_words:"true"`
 	WriteMaxWorkers           int `default:"-1" split_words:"true"`
 	ReadMaxWorkers            int `default:"-1" split_words:"true"`
 	ListenerMaxWorkers        int `default:"-1" split_words:"true"`
 
+	// number of queues the workers of each pool are split in, each connection is bound to one of them
+	SchedulerShards int `default:"-1" split_words:"true"`
+
 	EventQueueSizeFrames int `default:"12" split_words:"true"`
 
 	AsyncConnectorWriteQueueSizeFrames int `default:"2048" split_words:"true"`
 	AsyncConnectorWriteBufferSizeBytes int `default:"4096" split_words:"true"`
+	AsyncConnectorMaxStreamIds         int `default:"2048" split_words:"true"`
 }
 
+// maxStreamIds is the number of stream ids of a connection with protocol v3 or higher
+const maxStreamIds = 32768
+
 func (c *Config) String() string {
 	serializedConfig, _ := json.Marshal(c)
 	return string(serializedConfig)
 }
 
+var (
+	envVarWordsRegexp   = regexp.MustCompile("([^A-Z]+|[A-Z]+[^A-Z]+|[A-Z]+)")
+	envVarAcronymRegexp = regexp.MustCompile("([A-Z]+)([A-Z][^A-Z]+)")
+)
+
+// Settings returns the environment variable name and the value of each setting, sorted by name. Like String,
+// it leaves out the passwords.
+func (c *Config) Settings() [][2]string {
+	value := reflect.ValueOf(c).Elem()
+	settings := make([][2]string, 0, value.NumField())
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Tag.Get("json") == "-" {
+			continue
+		}
+
+		settings = append(settings, [2]string{envVarName(field.Name), fmt.Sprintf("%v", value.Field(i).Interface())})
+	}
+	sort.Slice(settings, func(i, j int) bool {
+		return settings[i][0] < settings[j][0]
+	})
+	return settings
+}
+
+// envVarName returns the name of the environment variable of a Config field, same rules as envconfig's split_words.
+func envVarName(fieldName string) string {
+	words := make([]string, 0)
+	for _, match := range envVarWordsRegexp.FindAllString(fieldName, -1) {
+		if acronym := envVarAcronymRegexp.FindStringSubmatch(match); len(acronym) == 3 {
+			words = append(words, acronym[1], acronym[2])
+		} else {
+			words = append(words, match)
+		}
+	}
+	return "ZDM_" + strings.ToUpper(strings.Join(words, "_"))
+}
+
 // New returns an empty Config struct
 func New() *Config {
 	return &Config{}
@@ -149,37 +380,99 @@ func (c *Config) ParseEnvVars() (*Config, error) {
 	return c, nil
 }
 
-func lookupFirstIp4(host string) (net.IP, error) {
-	ips, err := net.LookupIP(host)
+// lookupFirstIp resolves a host name (or parses an IP literal, with or without brackets) and returns the first address
+// of the family configured with ZDM_IP_FAMILY_PREFERENCE, or the first address of the other family if the host
+// doesn't have an address of the preferred family.
+func (c *Config) lookupFirstIp(host string) (net.IP, error) {
+	ipFamilyPreference, err := c.ParseIpFamilyPreference()
 	if err != nil {
 		return nil, err
 	}
-	for _, ip := range ips {
-		ip4 := ip.To4()
-		if ip4 != nil {
-			return ip4, nil
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		ips, err = net.LookupIP(host)
+		if err != nil {
+			return nil, err
+		}
+	}
+	ips = common.FilterIpsByFamily(ips, ipFamilyPreference)
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("could not resolve %v to an ip address", host)
+	}
+	if ip4 := ips[0].To4(); ip4 != nil {
+		return ip4, nil
+	}
+	return ips[0], nil
+}
+
+// ParseSystemQueriesBypass returns the client connections whose system.local and system.peers queries are sent to
+// the cluster instead of being intercepted, nil if ZDM_SYSTEM_QUERIES_BYPASS_CLIENTS and
+// ZDM_SYSTEM_QUERIES_BYPASS_APPLICATIONS are not set. The clients are a comma separated list of IP addresses and
+// CIDR ranges, the applications a comma separated list of the application names that clients send in the STARTUP
+// request.
+func (c *Config) ParseSystemQueriesBypass() (*common.SystemQueriesBypass, error) {
+	if isNotDefined(c.SystemQueriesBypassClients) && isNotDefined(c.SystemQueriesBypassApplications) {
+		return nil, nil
+	}
+
+	bypass := &common.SystemQueriesBypass{
+		ClientNetworks:   make([]*net.IPNet, 0),
+		ApplicationNames: make(map[string]bool),
+	}
+	for _, client := range strings.Split(c.SystemQueriesBypassClients, ",") {
+		client = strings.TrimSpace(client)
+		if client == "" {
+			continue
+		}
+		if !strings.Contains(client, "/") {
+			ip := net.ParseIP(client)
+			if ip == nil {
+				return nil, fmt.Errorf(
+					"invalid value for ZDM_SYSTEM_QUERIES_BYPASS_CLIENTS; %v is not an IP address or a CIDR range", client)
+			}
+			if ip.To4() != nil {
+				client += "/32"
+			} else {
+				client += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(client)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"invalid value for ZDM_SYSTEM_QUERIES_BYPASS_CLIENTS; %v is not an IP address or a CIDR range", client)
+		}
+		bypass.ClientNetworks = append(bypass.ClientNetworks, network)
+	}
+	for _, application := range strings.Split(c.SystemQueriesBypassApplications, ",") {
+		application = strings.TrimSpace(application)
+		if application != "" {
+			bypass.ApplicationNames[application] = true
 		}
 	}
-	return nil, fmt.Errorf("could not resolve %v to an ipv4 address", host)
+	return bypass, nil
 }
 
 func (c *Config) ParseTopologyConfig() (*common.TopologyConfig, error) {
 	var proxyAddressesTyped []net.IP
-	defaultLocalIp4Addr := net.IPv4(127, 0, 0, 1)
+	defaultLocalIpAddr := net.IPv4(127, 0, 0, 1)
+	if ipFamilyPreference, err := c.ParseIpFamilyPreference(); err == nil && ipFamilyPreference == common.IpFamilyPreferenceV6 {
+		defaultLocalIpAddr = net.IPv6loopback
+	}
 	if isNotDefined(c.ProxyTopologyAddresses) {
 		log.Debugf("[TopologyConfig] Proxy Topology Addresses not defined, attempting to use proxy listen address for system.local: %v.", c.ProxyListenAddress)
 		if isDefined(c.ProxyListenAddress) {
-			parsedListenAddress, err := lookupFirstIp4(c.ProxyListenAddress)
+			parsedListenAddress, err := c.lookupFirstIp(c.ProxyListenAddress)
 			if err != nil {
-				log.Debugf("[TopologyConfig] Could not resolve Proxy Listen Address to an IPv4 address: %v. Falling back to default: %v.", err, defaultLocalIp4Addr.String())
+				log.Debugf("[TopologyConfig] Could not resolve Proxy Listen Address to an IP address: %v. Falling back to default: %v.", err, defaultLocalIpAddr.String())
 			} else {
 				proxyAddressesTyped = []net.IP{parsedListenAddress}
 			}
 		} else {
-			log.Debugf("[TopologyConfig] Proxy Listen Address not defined, falling back to default: %v.", defaultLocalIp4Addr.String())
+			log.Debugf("[TopologyConfig] Proxy Listen Address not defined, falling back to default: %v.", defaultLocalIpAddr.String())
 		}
 		if len(proxyAddressesTyped) == 0 {
-			proxyAddressesTyped = []net.IP{defaultLocalIp4Addr}
+			proxyAddressesTyped = []net.IP{defaultLocalIpAddr}
 		}
 	} else {
 		proxyAddresses := strings.Split(strings.ReplaceAll(c.ProxyTopologyAddresses, " ", ""), ",")
@@ -189,10 +482,16 @@ func (c *Config) ParseTopologyConfig() (*common.TopologyConfig, error) {
 
 		proxyAddressesTyped = make([]net.IP, 0, len(proxyAddresses))
 		for i := 0; i < len(proxyAddresses); i++ {
-			proxyAddr := proxyAddresses[i]
+			proxyAddr := strings.TrimSuffix(strings.TrimPrefix(proxyAddresses[i], "["), "]")
 			parsedIp := net.ParseIP(proxyAddr)
 			if parsedIp == nil {
-				return nil, fmt.Errorf("invalid proxy address in ZDM_PROXY_TOPOLOGY_ADDRESSES env var: %v", proxyAddr)
+				// not an IP address, resolve it in case it is the hostname of a proxy instance
+				var err error
+				parsedIp, err = c.lookupFirstIp(proxyAddr)
+				if err != nil {
+					return nil, fmt.Errorf("invalid proxy address in ZDM_PROXY_TOPOLOGY_ADDRESSES env var: %v (%v)", proxyAddr, err)
+				}
+				log.Debugf("[TopologyConfig] Resolved proxy address %v to %v.", proxyAddr, parsedIp)
 			}
 			proxyAddressesTyped = append(proxyAddressesTyped, parsedIp)
 		}
@@ -201,6 +500,9 @@ func (c *Config) ParseTopologyConfig() (*common.TopologyConfig, error) {
 
 	proxyInstanceCount := len(proxyAddressesTyped)
 	proxyIndex := c.ProxyTopologyIndex
+	if proxyIndex == autoDetectProxyTopologyIndex {
+		proxyIndex = c.detectProxyTopologyIndex(proxyAddressesTyped)
+	}
 	if proxyIndex < 0 || proxyIndex >= proxyInstanceCount {
 		return nil, fmt.Errorf("invalid ZDM_PROXY_TOPOLOGY_INDEX and ZDM_PROXY_TOPOLOGY_ADDRESSES values; "+
 			"proxy index (%d) must be less than length of addresses (%d) and non negative", proxyIndex, proxyInstanceCount)
@@ -219,12 +521,65 @@ func (c *Config) ParseTopologyConfig() (*common.TopologyConfig, error) {
 	}, nil
 }
 
+const autoDetectProxyTopologyIndex = -1
+
+// detectProxyTopologyIndex finds the position of this proxy instance in the topology addresses by matching them
+// against the proxy listen address and the addresses of the local network interfaces.
+// It falls back to index 0 if there is no match.
+func (c *Config) detectProxyTopologyIndex(proxyAddresses []net.IP) int {
+	if len(proxyAddresses) == 1 {
+		return 0
+	}
+
+	localAddresses := make([]net.IP, 0)
+	if isDefined(c.ProxyListenAddress) {
+		listenAddress, err := c.lookupFirstIp(c.ProxyListenAddress)
+		if err == nil && !listenAddress.IsUnspecified() {
+			localAddresses = append(localAddresses, listenAddress)
+		}
+	}
+	interfaceAddresses, err := net.InterfaceAddrs()
+	if err != nil {
+		log.Debugf("[TopologyConfig] Could not get addresses of the local network interfaces: %v.", err)
+	}
+	for _, interfaceAddress := range interfaceAddresses {
+		if ipNet, ok := interfaceAddress.(*net.IPNet); ok {
+			localAddresses = append(localAddresses, ipNet.IP)
+		}
+	}
+
+	for _, localAddress := range localAddresses {
+		for i, proxyAddress := range proxyAddresses {
+			if proxyAddress.Equal(localAddress) {
+				log.Infof("[TopologyConfig] ZDM_PROXY_TOPOLOGY_INDEX not set, detected index %v from local address %v.",
+					i, localAddress)
+				return i
+			}
+		}
+	}
+
+	log.Warnf("[TopologyConfig] ZDM_PROXY_TOPOLOGY_INDEX not set and none of the local addresses match "+
+		"ZDM_PROXY_TOPOLOGY_ADDRESSES (%v), falling back to index 0. "+
+		"Set ZDM_PROXY_TOPOLOGY_INDEX explicitly so that every proxy instance has a different index.", proxyAddresses)
+	return 0
+}
+
 func (c *Config) Validate() error {
 	_, err := c.ParseLogLevel()
 	if err != nil {
 		return fmt.Errorf("invalid log level: %w", err)
 	}
 
+	_, err = c.ParseLogFormatter()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseLogRedaction()
+	if err != nil {
+		return err
+	}
+
 	_, err = c.ParseTargetContactPoints()
 	if err != nil {
 		return fmt.Errorf("invalid target configuration: %w", err)
@@ -235,6 +590,26 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid origin configuration: %w", err)
 	}
 
+	_, err = c.ParseOriginRemoteDatacenters()
+	if err != nil {
+		return fmt.Errorf("invalid origin configuration: %w", err)
+	}
+
+	_, err = c.ParseTargetRemoteDatacenters()
+	if err != nil {
+		return fmt.Errorf("invalid target configuration: %w", err)
+	}
+
+	_, err = c.ParseOriginEgressProxyUrl()
+	if err != nil {
+		return fmt.Errorf("invalid origin configuration: %w", err)
+	}
+
+	_, err = c.ParseTargetEgressProxyUrl()
+	if err != nil {
+		return fmt.Errorf("invalid target configuration: %w", err)
+	}
+
 	_, err = c.ParseOriginBuckets()
 	if err != nil {
 		return fmt.Errorf("could not parse origin buckets: %v", err)
@@ -245,11 +620,41 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("could not parse target buckets: %v", err)
 	}
 
+	_, err = c.ParseMetricsSinks()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseTableRequestsAllowList()
+	if err != nil {
+		return err
+	}
+
+	if c.MetricsTableRequestsMaxTables < 0 {
+		return fmt.Errorf("invalid value for ZDM_METRICS_TABLE_REQUESTS_MAX_TABLES (%v); it must not be negative",
+			c.MetricsTableRequestsMaxTables)
+	}
+
+	if c.MetricsApplicationsMax < 0 {
+		return fmt.Errorf("invalid value for ZDM_METRICS_APPLICATIONS_MAX (%v); it must not be negative",
+			c.MetricsApplicationsMax)
+	}
+
 	_, err = c.ParseTopologyConfig()
 	if err != nil {
 		return err
 	}
 
+	if c.SystemQueriesCacheTtlMs < 0 {
+		return fmt.Errorf("invalid value for ZDM_SYSTEM_QUERIES_CACHE_TTL_MS (%v); must be equal or greater than 0",
+			c.SystemQueriesCacheTtlMs)
+	}
+
+	_, err = c.ParseSystemQueriesBypass()
+	if err != nil {
+		return err
+	}
+
 	_, err = c.ParseOriginTlsConfig(false)
 	if err != nil {
 		return err
@@ -265,6 +670,11 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	_, err = c.ParseMigrationPhase()
+	if err != nil {
+		return err
+	}
+
 	_, err = c.ParsePrimaryCluster()
 	if err != nil {
 		return err
@@ -280,138 +690,1643 @@ func (c *Config) Validate() error {
 		return err
 	}
 
-	return nil
-}
+	_, err = c.ParseSpeculativeReadThreshold()
+	if err != nil {
+		return err
+	}
 
-const (
-	SystemQueriesModeOrigin = "ORIGIN"
-	SystemQueriesModeTarget = "TARGET"
-)
+	err = c.validateShadowMode()
+	if err != nil {
+		return err
+	}
 
-func (c *Config) ParseSystemQueriesMode() (common.SystemQueriesMode, error) {
-	switch strings.ToUpper(c.SystemQueriesMode) {
-	case SystemQueriesModeTarget:
-		return common.SystemQueriesModeTarget, nil
-	case SystemQueriesModeOrigin:
-		return common.SystemQueriesModeOrigin, nil
-	default:
-		return common.SystemQueriesModeUndefined, fmt.Errorf("invalid value for ZDM_SYSTEM_QUERIES_MODE; possible values are: %v and %v",
-			SystemQueriesModeTarget, SystemQueriesModeOrigin)
+	_, err = c.ParseLwtPolicy()
+	if err != nil {
+		return err
 	}
-}
 
-const (
-	PrimaryClusterOrigin = "ORIGIN"
-	PrimaryClusterTarget = "TARGET"
-)
+	_, err = c.ParseCounterWritePolicy()
+	if err != nil {
+		return err
+	}
 
-func (c *Config) ParsePrimaryCluster() (common.ClusterType, error) {
-	switch strings.ToUpper(c.PrimaryCluster) {
-	case PrimaryClusterOrigin:
-		return common.ClusterTypeOrigin, nil
-	case PrimaryClusterTarget:
-		return common.ClusterTypeTarget, nil
-	default:
-		return common.ClusterTypeNone, fmt.Errorf("invalid value for ZDM_PRIMARY_CLUSTER; possible values are: %v and %v",
-			PrimaryClusterOrigin, PrimaryClusterTarget)
+	_, err = c.ParseDdlPolicy()
+	if err != nil {
+		return err
 	}
-}
 
-const (
-	ReadModePrimaryOnly          = "PRIMARY_ONLY"
-	ReadModeDualAsyncOnSecondary = "DUAL_ASYNC_ON_SECONDARY"
-)
+	if c.DdlSchemaAgreementTimeoutMs < 0 {
+		return fmt.Errorf("invalid value for ZDM_DDL_SCHEMA_AGREEMENT_TIMEOUT_MS (%v); it must not be negative",
+			c.DdlSchemaAgreementTimeoutMs)
+	}
 
-func (c *Config) ParseReadMode() (common.ReadMode, error) {
-	switch strings.ToUpper(c.ReadMode) {
-	case ReadModePrimaryOnly:
-		return common.ReadModePrimaryOnly, nil
-	case ReadModeDualAsyncOnSecondary:
-		return common.ReadModeDualAsyncOnSecondary, nil
-	default:
-		return common.ReadModeUndefined, fmt.Errorf("invalid value for ZDM_READ_MODE; possible values are: %v and %v",
-			ReadModePrimaryOnly, ReadModeDualAsyncOnSecondary)
+	if c.SchemaDriftCheckIntervalMs < 0 {
+		return fmt.Errorf("invalid value for ZDM_SCHEMA_DRIFT_CHECK_INTERVAL_MS (%v); it must not be negative",
+			c.SchemaDriftCheckIntervalMs)
 	}
-}
 
-func (c *Config) ParseLogLevel() (log.Level, error) {
-	level, err := log.ParseLevel(strings.TrimSpace(c.LogLevel))
+	_, err = c.ParseEventSourcePolicy()
 	if err != nil {
-		var lvl log.Level
-		return lvl, fmt.Errorf("invalid log level, valid log levels are "+
-			"PANIC, FATAL, ERROR, WARN or WARNING, INFO, DEBUG and TRACE; original err: %w", err)
+		return err
 	}
 
-	return level, nil
-}
+	_, err = c.ParseWarningsPolicy()
+	if err != nil {
+		return err
+	}
 
-func (c *Config) ParseOriginBuckets() ([]float64, error) {
-	return c.parseBuckets(c.MetricsOriginLatencyBucketsMs)
-}
+	_, err = c.ParseOriginHostSelectionPolicy()
+	if err != nil {
+		return err
+	}
 
-func (c *Config) ParseTargetBuckets() ([]float64, error) {
-	return c.parseBuckets(c.MetricsTargetLatencyBucketsMs)
-}
+	_, err = c.ParseTargetHostSelectionPolicy()
+	if err != nil {
+		return err
+	}
 
-func (c *Config) ParseAsyncBuckets() ([]float64, error) {
-	return c.parseBuckets(c.MetricsAsyncReadLatencyBucketsMs)
-}
+	_, err = c.ParseCustomPayloadPolicy()
+	if err != nil {
+		return err
+	}
 
-func (c *Config) parseBuckets(bucketsConfigStr string) ([]float64, error) {
-	var bucketsArr []float64
-	bucketsStrArr := strings.Split(bucketsConfigStr, ",")
-	if len(bucketsStrArr) == 0 {
-		return nil, fmt.Errorf("unable to parse buckets from %v: at least one bucket is required", bucketsConfigStr)
+	_, err = c.ParseIpFamilyPreference()
+	if err != nil {
+		return err
 	}
 
-	for _, bucketStr := range bucketsStrArr {
-		bucket, err := strconv.ParseFloat(strings.TrimSpace(bucketStr), 64)
-		if err != nil {
-			return nil, fmt.Errorf(
-				"unable to parse buckets from %v: could not convert %v to float",
-				bucketsConfigStr,
-				bucketStr)
-		}
-		bucketsArr = append(bucketsArr, bucket/1000) // convert ms to seconds
+	if c.DnsCacheMinTtlMs < 0 {
+		return fmt.Errorf("invalid value for ZDM_DNS_CACHE_MIN_TTL_MS (%v); it must not be negative", c.DnsCacheMinTtlMs)
 	}
 
-	return bucketsArr, nil
-}
+	if c.DnsCacheMaxTtlMs < 0 {
+		return fmt.Errorf("invalid value for ZDM_DNS_CACHE_MAX_TTL_MS (%v); it must not be negative", c.DnsCacheMaxTtlMs)
+	}
 
-func (c *Config) ParseOriginContactPoints() ([]string, error) {
-	if isDefined(c.OriginSecureConnectBundlePath) && isDefined(c.OriginContactPoints) {
-		return nil, fmt.Errorf("OriginSecureConnectBundlePath and OriginContactPoints are mutually exclusive. Please specify only one of them.")
+	if c.DnsCacheMaxTtlMs > 0 && c.DnsCacheMinTtlMs > c.DnsCacheMaxTtlMs {
+		return fmt.Errorf("invalid value for ZDM_DNS_CACHE_MIN_TTL_MS (%v); it must not be greater than "+
+			"ZDM_DNS_CACHE_MAX_TTL_MS (%v)", c.DnsCacheMinTtlMs, c.DnsCacheMaxTtlMs)
 	}
 
-	if isDefined(c.OriginSecureConnectBundlePath) && isDefined(c.OriginLocalDatacenter) {
-		return nil, fmt.Errorf("OriginSecureConnectBundlePath and OriginLocalDatacenter are mutually exclusive. Please specify only one of them.")
+	_, err = c.ParseProxyListenSocketPermissions()
+	if err != nil {
+		return err
 	}
 
-	if isNotDefined(c.OriginSecureConnectBundlePath) && isNotDefined(c.OriginContactPoints) {
-		return nil, fmt.Errorf("Both OriginSecureConnectBundlePath and OriginContactPoints are empty. Please specify either one of them.")
+	_, err = c.ParseProxyListenProxyProtocol()
+	if err != nil {
+		return err
 	}
 
-	if isDefined(c.OriginContactPoints) && (c.OriginPort == 0) {
-		return nil, fmt.Errorf("OriginContactPoints was specified but the port is missing. Please provide OriginPort")
+	_, err = c.ParseProxyListenSocketProxyProtocol()
+	if err != nil {
+		return err
 	}
 
-	if (c.OriginEnableHostAssignment == false) && (isDefined(c.OriginLocalDatacenter)) {
-		return nil, fmt.Errorf("OriginLocalDatacenter was specified but OriginEnableHostAssignment is false. Please enable host assignment or don't set the datacenter.")
+	if c.ProxyProtocolHeaderTimeoutMs < 0 {
+		return fmt.Errorf("invalid value for ZDM_PROXY_PROTOCOL_HEADER_TIMEOUT_MS (%v); it must not be negative",
+			c.ProxyProtocolHeaderTimeoutMs)
 	}
 
-	if isNotDefined(c.OriginSecureConnectBundlePath) {
-		contactPoints := parseContactPoints(c.OriginContactPoints)
-		if len(contactPoints) <= 0 {
-			return nil, fmt.Errorf("could not parse origin contact points: %v", c.OriginContactPoints)
-		}
+	if c.ProxyClientIdleTimeoutMs < 0 {
+		return fmt.Errorf("invalid value for ZDM_PROXY_CLIENT_IDLE_TIMEOUT_MS (%v); it must not be negative",
+			c.ProxyClientIdleTimeoutMs)
+	}
 
-		return contactPoints, nil
+	if c.ProxyClientTcpKeepAliveMs < 0 {
+		return fmt.Errorf("invalid value for ZDM_PROXY_CLIENT_TCP_KEEP_ALIVE_MS (%v); it must not be negative",
+			c.ProxyClientTcpKeepAliveMs)
 	}
 
-	return nil, nil
-}
+	if c.EventDedupWindowMs < 0 {
+		return fmt.Errorf("invalid value for ZDM_EVENT_DEDUP_WINDOW_MS (%v); it must not be negative",
+			c.EventDedupWindowMs)
+	}
 
-func (c *Config) ParseTargetContactPoints() ([]string, error) {
+	_, err = c.ParseCredentialMappings()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseQueryRewriteRules()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseTargetNameMapping()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseTableRoutingRules()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseOriginConsistencyOverride()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseTargetConsistencyOverride()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseTargetCircuitBreakerConfig()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseOriginRetryPolicy()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseTargetRetryPolicy()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseRetryIdempotentTables()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseFailedWritesJournalConfig()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseAuditLogConfig()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseFleetConfig()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseOriginFaultInjectionConfig()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseTargetFaultInjectionConfig()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseRequestTimeouts()
+	if err != nil {
+		return err
+	}
+
+	if c.ProxyMaxPreparedStatementCacheSize <= 0 {
+		return fmt.Errorf("invalid ZDM_PROXY_MAX_PREPARED_STATEMENT_CACHE_SIZE (%v), it must be positive",
+			c.ProxyMaxPreparedStatementCacheSize)
+	}
+
+	err = c.validateAsyncConnectorMaxStreamIds()
+	if err != nil {
+		return err
+	}
+
+	if c.ProxyMaxRequestFrameSizeBytes < 0 {
+		return fmt.Errorf("invalid value for ZDM_PROXY_MAX_REQUEST_FRAME_SIZE_BYTES (%v); it must not be negative",
+			c.ProxyMaxRequestFrameSizeBytes)
+	}
+
+	if c.ProxyMaxResponseFrameSizeBytes < 0 {
+		return fmt.Errorf("invalid value for ZDM_PROXY_MAX_RESPONSE_FRAME_SIZE_BYTES (%v); it must not be negative",
+			c.ProxyMaxResponseFrameSizeBytes)
+	}
+
+	if c.ProxyLargeResponseWarningThresholdBytes < 0 {
+		return fmt.Errorf("invalid value for ZDM_PROXY_LARGE_RESPONSE_WARNING_THRESHOLD_BYTES (%v); it must not be negative",
+			c.ProxyLargeResponseWarningThresholdBytes)
+	}
+
+	_, err = c.ParseProxySupportedOptions()
+	if err != nil {
+		return err
+	}
+
+	if c.WriteBufferPoolMaxBufferSizeBytes < 0 {
+		return fmt.Errorf("invalid value for ZDM_WRITE_BUFFER_POOL_MAX_BUFFER_SIZE_BYTES (%v); it must not be negative",
+			c.WriteBufferPoolMaxBufferSizeBytes)
+	}
+
+	err = c.validateHeartbeats()
+	if err != nil {
+		return err
+	}
+
+	err = c.validateRequestConnectionHeartbeats()
+	if err != nil {
+		return err
+	}
+
+	if c.RequestConnectionFailoverEnabled && c.RequestConnectionFailoverMaxAttempts <= 0 {
+		return fmt.Errorf("invalid value for ZDM_REQUEST_CONNECTION_FAILOVER_MAX_ATTEMPTS (%v); it must be positive "+
+			"when request connection failover is enabled", c.RequestConnectionFailoverMaxAttempts)
+	}
+
+	err = c.validateAdminEndpoint()
+	if err != nil {
+		return err
+	}
+
+	err = c.validateStreamIdVirtualization()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateAdminEndpoint checks that the admin and debug endpoints are not served on the metrics listener.
+func (c *Config) validateAdminEndpoint() error {
+	if c.DebugEndpointEnabled && !c.AdminEndpointEnabled {
+		return fmt.Errorf("ZDM_DEBUG_ENDPOINT_ENABLED requires ZDM_ADMIN_ENDPOINT_ENABLED " +
+			"because the debug endpoint is served on ZDM_ADMIN_ADDRESS and ZDM_ADMIN_PORT")
+	}
+	if !c.AdminEndpointEnabled {
+		return nil
+	}
+	if c.AdminPort <= 0 || c.AdminPort > 65535 {
+		return fmt.Errorf("invalid value for ZDM_ADMIN_PORT (%v); it must be between 1 and 65535", c.AdminPort)
+	}
+	if c.AdminPort == c.MetricsPort {
+		return fmt.Errorf("ZDM_ADMIN_PORT (%v) must be different from ZDM_METRICS_PORT "+
+			"because the admin endpoints are not served on the metrics listener", c.AdminPort)
+	}
+	return nil
+}
+
+func (c *Config) validateAsyncConnectorMaxStreamIds() error {
+	if c.AsyncConnectorMaxStreamIds <= 0 || c.AsyncConnectorMaxStreamIds > maxStreamIds {
+		return fmt.Errorf("invalid value for ZDM_ASYNC_CONNECTOR_MAX_STREAM_IDS (%v); it must be between 1 and %v",
+			c.AsyncConnectorMaxStreamIds, maxStreamIds)
+	}
+	return nil
+}
+
+func (c *Config) validateHeartbeats() error {
+	if c.HeartbeatIntervalMs <= 0 {
+		return fmt.Errorf("invalid value for ZDM_HEARTBEAT_INTERVAL_MS (%v); it must be positive", c.HeartbeatIntervalMs)
+	}
+	if c.HeartbeatRetryIntervalMinMs <= 0 {
+		return fmt.Errorf("invalid value for ZDM_HEARTBEAT_RETRY_INTERVAL_MIN_MS (%v); it must be positive",
+			c.HeartbeatRetryIntervalMinMs)
+	}
+	if c.HeartbeatRetryIntervalMaxMs < c.HeartbeatRetryIntervalMinMs {
+		return fmt.Errorf("invalid value for ZDM_HEARTBEAT_RETRY_INTERVAL_MAX_MS (%v); it must not be lower than "+
+			"ZDM_HEARTBEAT_RETRY_INTERVAL_MIN_MS (%v)", c.HeartbeatRetryIntervalMaxMs, c.HeartbeatRetryIntervalMinMs)
+	}
+	if c.HeartbeatRetryBackoffFactor < 1 {
+		return fmt.Errorf("invalid value for ZDM_HEARTBEAT_RETRY_BACKOFF_FACTOR (%v); it must be at least 1",
+			c.HeartbeatRetryBackoffFactor)
+	}
+	if c.HeartbeatFailureThreshold <= 0 {
+		return fmt.Errorf("invalid value for ZDM_HEARTBEAT_FAILURE_THRESHOLD (%v); it must be positive",
+			c.HeartbeatFailureThreshold)
+	}
+	return nil
+}
+
+func (c *Config) validateRequestConnectionHeartbeats() error {
+	if c.RequestConnectionHeartbeatIntervalMs < 0 {
+		return fmt.Errorf("invalid value for ZDM_REQUEST_CONNECTION_HEARTBEAT_INTERVAL_MS (%v); it must not be negative",
+			c.RequestConnectionHeartbeatIntervalMs)
+	}
+	if c.RequestConnectionHeartbeatIntervalMs > 0 && c.RequestConnectionHeartbeatTimeoutMs <= 0 {
+		return fmt.Errorf("invalid value for ZDM_REQUEST_CONNECTION_HEARTBEAT_TIMEOUT_MS (%v); it must be positive "+
+			"when request connection heartbeats are enabled", c.RequestConnectionHeartbeatTimeoutMs)
+	}
+	return nil
+}
+
+func (c *Config) validateStreamIdVirtualization() error {
+	if c.StreamIdOverflowConnectionEnabled && !c.StreamIdVirtualizationEnabled {
+		return fmt.Errorf("ZDM_STREAM_ID_OVERFLOW_CONNECTION_ENABLED requires ZDM_STREAM_ID_VIRTUALIZATION_ENABLED " +
+			"because the stream ids of the client can not be sent on two connections")
+	}
+	if !c.StreamIdVirtualizationEnabled {
+		return nil
+	}
+	if c.RequestConnectionMaxStreamIds <= 0 || c.RequestConnectionMaxStreamIds > maxStreamIds {
+		return fmt.Errorf("invalid value for ZDM_REQUEST_CONNECTION_MAX_STREAM_IDS (%v); it must be between 1 and %v",
+			c.RequestConnectionMaxStreamIds, maxStreamIds)
+	}
+	return nil
+}
+
+// ProxyClientAuthEnabled returns true if the proxy should validate client credentials itself
+// instead of forwarding them to one of the clusters.
+func (c *Config) ProxyClientAuthEnabled() bool {
+	return c.ProxyClientUsername != "" || c.ProxyCredentialMappingFile != ""
+}
+
+// ParseCredentialMappings returns the client credentials accepted by the proxy and the cluster credentials that are
+// used for each of them. The entries come from ZDM_PROXY_CREDENTIAL_MAPPING_FILE (a JSON array) and from
+// ZDM_PROXY_CLIENT_USERNAME / ZDM_PROXY_CLIENT_PASSWORD, which are mapped to the ORIGIN and TARGET credentials.
+// Cluster credentials that are omitted in a mapping entry default to the ORIGIN and TARGET credentials.
+func (c *Config) ParseCredentialMappings() ([]*common.CredentialMapping, error) {
+	if (c.ProxyClientUsername == "") != (c.ProxyClientPassword == "") {
+		return nil, fmt.Errorf("ZDM_PROXY_CLIENT_USERNAME and ZDM_PROXY_CLIENT_PASSWORD must be set together")
+	}
+
+	var mappings []*common.CredentialMapping
+	if c.ProxyCredentialMappingFile != "" {
+		content, err := ioutil.ReadFile(c.ProxyCredentialMappingFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read ZDM_PROXY_CREDENTIAL_MAPPING_FILE: %w", err)
+		}
+		err = json.Unmarshal(content, &mappings)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse ZDM_PROXY_CREDENTIAL_MAPPING_FILE: %w", err)
+		}
+	}
+
+	if c.ProxyClientUsername != "" {
+		mappings = append(mappings, &common.CredentialMapping{
+			ClientUsername: c.ProxyClientUsername,
+			ClientPassword: c.ProxyClientPassword,
+		})
+	}
+
+	clientUsernames := make(map[string]bool, len(mappings))
+	for idx, mapping := range mappings {
+		if mapping == nil || mapping.ClientUsername == "" || mapping.ClientPassword == "" {
+			return nil, fmt.Errorf("invalid credential mapping at index %d: client_username and client_password are required", idx)
+		}
+		if clientUsernames[mapping.ClientUsername] {
+			return nil, fmt.Errorf("invalid credential mappings: client username %v is mapped more than once", mapping.ClientUsername)
+		}
+		clientUsernames[mapping.ClientUsername] = true
+
+		if (mapping.OriginUsername == "") != (mapping.OriginPassword == "") {
+			return nil, fmt.Errorf("invalid credential mapping for client username %v: "+
+				"origin_username and origin_password must be set together", mapping.ClientUsername)
+		}
+		if (mapping.TargetUsername == "") != (mapping.TargetPassword == "") {
+			return nil, fmt.Errorf("invalid credential mapping for client username %v: "+
+				"target_username and target_password must be set together", mapping.ClientUsername)
+		}
+		if mapping.OriginUsername == "" {
+			mapping.OriginUsername = c.OriginUsername
+			mapping.OriginPassword = c.OriginPassword
+		}
+		if mapping.TargetUsername == "" {
+			mapping.TargetUsername = c.TargetUsername
+			mapping.TargetPassword = c.TargetPassword
+		}
+	}
+
+	return mappings, nil
+}
+
+const (
+	QueryRewriteClusterOrigin = "ORIGIN"
+	QueryRewriteClusterTarget = "TARGET"
+	QueryRewriteClusterBoth   = "BOTH"
+)
+
+var queryRewriteStatementTypes = []string{"SELECT", "INSERT", "UPDATE", "DELETE", "USE", "OTHER"}
+
+// ParseQueryRewriteRules returns the rules of ZDM_QUERY_REWRITE_RULES_FILE (a JSON array), in the order in which they
+// are applied, or nil if the setting is empty. Rules apply to both clusters unless cluster is ORIGIN or TARGET.
+func (c *Config) ParseQueryRewriteRules() ([]*common.QueryRewriteRule, error) {
+	if c.QueryRewriteRulesFile == "" {
+		return nil, nil
+	}
+
+	content, err := ioutil.ReadFile(c.QueryRewriteRulesFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read ZDM_QUERY_REWRITE_RULES_FILE: %w", err)
+	}
+	var rules []*common.QueryRewriteRule
+	err = json.Unmarshal(content, &rules)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse ZDM_QUERY_REWRITE_RULES_FILE: %w", err)
+	}
+
+	names := make(map[string]bool, len(rules))
+	for idx, rule := range rules {
+		if rule == nil || rule.Name == "" || rule.Match == "" {
+			return nil, fmt.Errorf("invalid query rewrite rule at index %d: name and match are required", idx)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("invalid query rewrite rules: rule name %v is used more than once", rule.Name)
+		}
+		names[rule.Name] = true
+
+		switch strings.ToUpper(rule.Cluster) {
+		case "", QueryRewriteClusterBoth:
+			rule.ApplyToOrigin, rule.ApplyToTarget = true, true
+		case QueryRewriteClusterOrigin:
+			rule.ApplyToOrigin = true
+		case QueryRewriteClusterTarget:
+			rule.ApplyToTarget = true
+		default:
+			return nil, fmt.Errorf("invalid cluster in query rewrite rule %v; possible values are: %v, %v and %v",
+				rule.Name, QueryRewriteClusterOrigin, QueryRewriteClusterTarget, QueryRewriteClusterBoth)
+		}
+
+		for i, statementType := range rule.StatementTypes {
+			rule.StatementTypes[i] = strings.ToUpper(statementType)
+			if !containsString(queryRewriteStatementTypes, rule.StatementTypes[i]) {
+				return nil, fmt.Errorf("invalid statement type %v in query rewrite rule %v; possible values are: %v",
+					statementType, rule.Name, strings.Join(queryRewriteStatementTypes, ", "))
+			}
+		}
+
+		rule.MatchRegexp, err = regexp.Compile(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match in query rewrite rule %v: %w", rule.Name, err)
+		}
+	}
+	return rules, nil
+}
+
+// ParseTargetNameMapping returns the mapping of ZDM_TARGET_NAME_MAPPING, a comma separated list of
+// origin_keyspace:target_keyspace and origin_keyspace.origin_table:target_keyspace.target_table entries, or nil if
+// the setting is empty.
+func (c *Config) ParseTargetNameMapping() (*common.NameMapping, error) {
+	if strings.TrimSpace(c.TargetNameMapping) == "" {
+		return nil, nil
+	}
+
+	mapping := &common.NameMapping{
+		Keyspaces: make(map[string]string),
+		Tables:    make(map[common.QualifiedTableName]common.QualifiedTableName),
+	}
+	for _, entry := range strings.Split(c.TargetNameMapping, ",") {
+		entry = strings.TrimSpace(entry)
+		names := strings.Split(entry, ":")
+		if len(names) != 2 {
+			return nil, fmt.Errorf("invalid entry %v in ZDM_TARGET_NAME_MAPPING; "+
+				"expected origin_keyspace:target_keyspace or origin_keyspace.origin_table:target_keyspace.target_table", entry)
+		}
+		originNames := strings.Split(strings.TrimSpace(names[0]), ".")
+		targetNames := strings.Split(strings.TrimSpace(names[1]), ".")
+		if len(originNames) != len(targetNames) || len(originNames) > 2 ||
+			containsString(originNames, "") || containsString(targetNames, "") {
+			return nil, fmt.Errorf("invalid entry %v in ZDM_TARGET_NAME_MAPPING; "+
+				"expected origin_keyspace:target_keyspace or origin_keyspace.origin_table:target_keyspace.target_table", entry)
+		}
+
+		if len(originNames) == 1 {
+			if _, ok := mapping.Keyspaces[originNames[0]]; ok {
+				return nil, fmt.Errorf("invalid ZDM_TARGET_NAME_MAPPING: keyspace %v is mapped more than once", originNames[0])
+			}
+			mapping.Keyspaces[originNames[0]] = targetNames[0]
+		} else {
+			originTable := common.QualifiedTableName{Keyspace: originNames[0], Table: originNames[1]}
+			if _, ok := mapping.Tables[originTable]; ok {
+				return nil, fmt.Errorf("invalid ZDM_TARGET_NAME_MAPPING: table %v.%v is mapped more than once",
+					originTable.Keyspace, originTable.Table)
+			}
+			mapping.Tables[originTable] = common.QualifiedTableName{Keyspace: targetNames[0], Table: targetNames[1]}
+		}
+	}
+	return mapping, nil
+}
+
+// ParseTableRoutingRules returns the rules of ZDM_TABLE_ROUTING_RULES, a comma separated list of keyspace:CLUSTER and
+// keyspace.table:CLUSTER entries where CLUSTER is ORIGIN or TARGET, or nil if the setting is empty.
+func (c *Config) ParseTableRoutingRules() (*common.TableRouting, error) {
+	if strings.TrimSpace(c.TableRoutingRules) == "" {
+		return nil, nil
+	}
+
+	routing := &common.TableRouting{
+		Keyspaces: make(map[string]common.ClusterType),
+		Tables:    make(map[common.QualifiedTableName]common.ClusterType),
+	}
+	for _, entry := range strings.Split(c.TableRoutingRules, ",") {
+		entry = strings.TrimSpace(entry)
+		parts := strings.Split(entry, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid entry %v in ZDM_TABLE_ROUTING_RULES; "+
+				"expected keyspace:CLUSTER or keyspace.table:CLUSTER", entry)
+		}
+		names := strings.Split(strings.TrimSpace(parts[0]), ".")
+		if len(names) > 2 || containsString(names, "") {
+			return nil, fmt.Errorf("invalid entry %v in ZDM_TABLE_ROUTING_RULES; "+
+				"expected keyspace:CLUSTER or keyspace.table:CLUSTER", entry)
+		}
+		var cluster common.ClusterType
+		switch strings.ToUpper(strings.TrimSpace(parts[1])) {
+		case string(common.ClusterTypeOrigin):
+			cluster = common.ClusterTypeOrigin
+		case string(common.ClusterTypeTarget):
+			cluster = common.ClusterTypeTarget
+		default:
+			return nil, fmt.Errorf("invalid cluster in entry %v of ZDM_TABLE_ROUTING_RULES; possible values are: %v and %v",
+				entry, common.ClusterTypeOrigin, common.ClusterTypeTarget)
+		}
+
+		if len(names) == 1 {
+			if _, ok := routing.Keyspaces[names[0]]; ok {
+				return nil, fmt.Errorf("invalid ZDM_TABLE_ROUTING_RULES: keyspace %v is routed more than once", names[0])
+			}
+			routing.Keyspaces[names[0]] = cluster
+		} else {
+			table := common.QualifiedTableName{Keyspace: names[0], Table: names[1]}
+			if _, ok := routing.Tables[table]; ok {
+				return nil, fmt.Errorf("invalid ZDM_TABLE_ROUTING_RULES: table %v.%v is routed more than once",
+					table.Keyspace, table.Table)
+			}
+			routing.Tables[table] = cluster
+		}
+	}
+	return routing, nil
+}
+
+var consistencyOverrideNames = []string{
+	"ANY", "ONE", "TWO", "THREE", "QUORUM", "ALL", "LOCAL_QUORUM", "EACH_QUORUM", "LOCAL_ONE"}
+
+var consistencyOverrideLevels = map[string]primitive.ConsistencyLevel{
+	"ANY":          primitive.ConsistencyLevelAny,
+	"ONE":          primitive.ConsistencyLevelOne,
+	"TWO":          primitive.ConsistencyLevelTwo,
+	"THREE":        primitive.ConsistencyLevelThree,
+	"QUORUM":       primitive.ConsistencyLevelQuorum,
+	"ALL":          primitive.ConsistencyLevelAll,
+	"LOCAL_QUORUM": primitive.ConsistencyLevelLocalQuorum,
+	"EACH_QUORUM":  primitive.ConsistencyLevelEachQuorum,
+	"LOCAL_ONE":    primitive.ConsistencyLevelLocalOne,
+}
+
+// ParseOriginConsistencyOverride returns the consistency level that replaces the one of the requests sent to ORIGIN,
+// or nil if ZDM_ORIGIN_CONSISTENCY_OVERRIDE is empty.
+func (c *Config) ParseOriginConsistencyOverride() (*primitive.ConsistencyLevel, error) {
+	return parseConsistencyOverride("ZDM_ORIGIN_CONSISTENCY_OVERRIDE", c.OriginConsistencyOverride)
+}
+
+// ParseTargetConsistencyOverride returns the consistency level that replaces the one of the requests sent to TARGET,
+// or nil if ZDM_TARGET_CONSISTENCY_OVERRIDE is empty.
+func (c *Config) ParseTargetConsistencyOverride() (*primitive.ConsistencyLevel, error) {
+	return parseConsistencyOverride("ZDM_TARGET_CONSISTENCY_OVERRIDE", c.TargetConsistencyOverride)
+}
+
+func parseConsistencyOverride(name string, value string) (*primitive.ConsistencyLevel, error) {
+	if value == "" {
+		return nil, nil
+	}
+	consistency, ok := consistencyOverrideLevels[strings.ToUpper(value)]
+	if !ok {
+		return nil, fmt.Errorf("invalid value for %v; possible values are: %v",
+			name, strings.Join(consistencyOverrideNames, ", "))
+	}
+	return &consistency, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	SystemQueriesModeOrigin = "ORIGIN"
+	SystemQueriesModeTarget = "TARGET"
+)
+
+func (c *Config) ParseSystemQueriesMode() (common.SystemQueriesMode, error) {
+	switch strings.ToUpper(c.SystemQueriesMode) {
+	case SystemQueriesModeTarget:
+		return common.SystemQueriesModeTarget, nil
+	case SystemQueriesModeOrigin:
+		return common.SystemQueriesModeOrigin, nil
+	default:
+		return common.SystemQueriesModeUndefined, fmt.Errorf("invalid value for ZDM_SYSTEM_QUERIES_MODE; possible values are: %v and %v",
+			SystemQueriesModeTarget, SystemQueriesModeOrigin)
+	}
+}
+
+const (
+	MigrationPhaseShadow               = "SHADOW"
+	MigrationPhaseDualWrite            = "DUAL_WRITE"
+	MigrationPhaseDualWriteAsyncRead   = "DUAL_WRITE_ASYNC_READ"
+	MigrationPhaseTargetPrimary        = "TARGET_PRIMARY"
+	MigrationPhaseOriginDecommissioned = "ORIGIN_DECOMMISSIONED"
+)
+
+// ParseMigrationPhase returns the phase that the proxy starts in, common.MigrationPhaseUndefined if
+// ZDM_MIGRATION_PHASE is not set. The phase implies ZDM_PRIMARY_CLUSTER, ZDM_READ_MODE and ZDM_SHADOW_MODE_ENABLED so
+// these settings must be left to their default values or match the phase.
+func (c *Config) ParseMigrationPhase() (common.MigrationPhase, error) {
+	if !isDefined(c.MigrationPhase) {
+		return common.MigrationPhaseUndefined, nil
+	}
+	phase, err := ParseMigrationPhaseName(c.MigrationPhase)
+	if err != nil {
+		return common.MigrationPhaseUndefined, fmt.Errorf("invalid value for ZDM_MIGRATION_PHASE: %w", err)
+	}
+
+	primaryCluster := strings.ToUpper(c.PrimaryCluster)
+	if isDefined(primaryCluster) && primaryCluster != PrimaryClusterOrigin &&
+		primaryCluster != string(phase.PrimaryCluster()) {
+		return common.MigrationPhaseUndefined, fmt.Errorf("ZDM_PRIMARY_CLUSTER (%v) conflicts with "+
+			"ZDM_MIGRATION_PHASE (%v) which implies ZDM_PRIMARY_CLUSTER=%v", c.PrimaryCluster, phase, phase.PrimaryCluster())
+	}
+	readMode := strings.ToUpper(c.ReadMode)
+	if isDefined(readMode) && readMode != ReadModePrimaryOnly && readMode != phase.ReadMode().String() {
+		return common.MigrationPhaseUndefined, fmt.Errorf("ZDM_READ_MODE (%v) conflicts with "+
+			"ZDM_MIGRATION_PHASE (%v) which implies ZDM_READ_MODE=%v", c.ReadMode, phase, phase.ReadMode())
+	}
+	if c.ShadowModeEnabled && !phase.ShadowModeEnabled() {
+		return common.MigrationPhaseUndefined, fmt.Errorf("ZDM_SHADOW_MODE_ENABLED conflicts with "+
+			"ZDM_MIGRATION_PHASE (%v), shadow mode is the %v phase", phase, MigrationPhaseShadow)
+	}
+	return phase, nil
+}
+
+// ParseMigrationPhaseName parses the name of a migration phase, it is also used for the phase transitions requested
+// through the admin API.
+func ParseMigrationPhaseName(value string) (common.MigrationPhase, error) {
+	switch strings.ToUpper(value) {
+	case MigrationPhaseShadow:
+		return common.MigrationPhaseShadow, nil
+	case MigrationPhaseDualWrite:
+		return common.MigrationPhaseDualWrite, nil
+	case MigrationPhaseDualWriteAsyncRead:
+		return common.MigrationPhaseDualWriteAsyncRead, nil
+	case MigrationPhaseTargetPrimary:
+		return common.MigrationPhaseTargetPrimary, nil
+	case MigrationPhaseOriginDecommissioned:
+		return common.MigrationPhaseOriginDecommissioned, nil
+	default:
+		return common.MigrationPhaseUndefined, fmt.Errorf("unknown migration phase %v; "+
+			"possible values are: %v, %v, %v, %v and %v", value, MigrationPhaseShadow, MigrationPhaseDualWrite,
+			MigrationPhaseDualWriteAsyncRead, MigrationPhaseTargetPrimary, MigrationPhaseOriginDecommissioned)
+	}
+}
+
+// ParseShadowModeEnabled returns whether the proxy starts in shadow mode, according to ZDM_MIGRATION_PHASE if it is
+// set and ZDM_SHADOW_MODE_ENABLED otherwise.
+func (c *Config) ParseShadowModeEnabled() (bool, error) {
+	phase, err := c.ParseMigrationPhase()
+	if err != nil {
+		return false, err
+	}
+	if phase != common.MigrationPhaseUndefined {
+		return phase.ShadowModeEnabled(), nil
+	}
+	return c.ShadowModeEnabled, nil
+}
+
+const (
+	PrimaryClusterOrigin = "ORIGIN"
+	PrimaryClusterTarget = "TARGET"
+)
+
+// ParsePrimaryCluster returns the primary cluster of ZDM_MIGRATION_PHASE if it is set, ZDM_PRIMARY_CLUSTER otherwise.
+func (c *Config) ParsePrimaryCluster() (common.ClusterType, error) {
+	phase, err := c.ParseMigrationPhase()
+	if err != nil {
+		return common.ClusterTypeNone, err
+	}
+	if phase != common.MigrationPhaseUndefined {
+		return phase.PrimaryCluster(), nil
+	}
+
+	switch strings.ToUpper(c.PrimaryCluster) {
+	case PrimaryClusterOrigin:
+		return common.ClusterTypeOrigin, nil
+	case PrimaryClusterTarget:
+		return common.ClusterTypeTarget, nil
+	default:
+		return common.ClusterTypeNone, fmt.Errorf("invalid value for ZDM_PRIMARY_CLUSTER; possible values are: %v and %v",
+			PrimaryClusterOrigin, PrimaryClusterTarget)
+	}
+}
+
+const (
+	ReadModePrimaryOnly          = "PRIMARY_ONLY"
+	ReadModeDualAsyncOnSecondary = "DUAL_ASYNC_ON_SECONDARY"
+)
+
+// ParseReadMode returns the read mode of ZDM_MIGRATION_PHASE if it is set, ZDM_READ_MODE otherwise.
+func (c *Config) ParseReadMode() (common.ReadMode, error) {
+	phase, err := c.ParseMigrationPhase()
+	if err != nil {
+		return common.ReadModeUndefined, err
+	}
+	if phase != common.MigrationPhaseUndefined {
+		return phase.ReadMode(), nil
+	}
+
+	switch strings.ToUpper(c.ReadMode) {
+	case ReadModePrimaryOnly:
+		return common.ReadModePrimaryOnly, nil
+	case ReadModeDualAsyncOnSecondary:
+		return common.ReadModeDualAsyncOnSecondary, nil
+	default:
+		return common.ReadModeUndefined, fmt.Errorf("invalid value for ZDM_READ_MODE; possible values are: %v and %v",
+			ReadModePrimaryOnly, ReadModeDualAsyncOnSecondary)
+	}
+}
+
+// ParseSpeculativeReadThreshold returns 0 if speculative reads are disabled. In dual reads mode the speculative reads
+// are sent to the secondary cluster, otherwise they are sent to another host of the local datacenter of the cluster
+// that serves the read.
+func (c *Config) ParseSpeculativeReadThreshold() (time.Duration, error) {
+	if c.SpeculativeReadThresholdMs < 0 {
+		return 0, fmt.Errorf("invalid value for ZDM_SPECULATIVE_READ_THRESHOLD_MS (%v); it must not be negative",
+			c.SpeculativeReadThresholdMs)
+	}
+	return time.Duration(c.SpeculativeReadThresholdMs) * time.Millisecond, nil
+}
+
+// validateShadowMode checks that shadow mode can be used, writes are only mirrored to TARGET so ORIGIN has to be the
+// primary cluster.
+func (c *Config) validateShadowMode() error {
+	shadowModeEnabled, err := c.ParseShadowModeEnabled()
+	if err != nil || !shadowModeEnabled {
+		return err
+	}
+	primaryCluster, err := c.ParsePrimaryCluster()
+	if err != nil {
+		return err
+	}
+	if primaryCluster != common.ClusterTypeOrigin {
+		return fmt.Errorf("ZDM_SHADOW_MODE_ENABLED requires ZDM_PRIMARY_CLUSTER to be %v "+
+			"because writes are only mirrored to %v", PrimaryClusterOrigin, PrimaryClusterTarget)
+	}
+	return nil
+}
+
+const (
+	LwtPolicyBoth        = "BOTH"
+	LwtPolicyPrimaryOnly = "PRIMARY_ONLY"
+	LwtPolicyReject      = "REJECT"
+)
+
+func (c *Config) ParseLwtPolicy() (common.LwtPolicy, error) {
+	switch strings.ToUpper(c.LwtPolicy) {
+	case LwtPolicyBoth:
+		return common.LwtPolicyBoth, nil
+	case LwtPolicyPrimaryOnly:
+		return common.LwtPolicyPrimaryOnly, nil
+	case LwtPolicyReject:
+		return common.LwtPolicyReject, nil
+	default:
+		return common.LwtPolicyUndefined, fmt.Errorf("invalid value for ZDM_LWT_POLICY; possible values are: %v, %v and %v",
+			LwtPolicyBoth, LwtPolicyPrimaryOnly, LwtPolicyReject)
+	}
+}
+
+const (
+	MetricsSinkPrometheus = "PROMETHEUS"
+	MetricsSinkStatsd     = "STATSD"
+
+	StatsdFlavorDogStatsd = "DOGSTATSD"
+	StatsdFlavorStatsd    = "STATSD"
+)
+
+// ParseMetricsSinks returns the metrics exporters of ZDM_METRICS_SINKS, a comma separated list of PROMETHEUS
+// and STATSD.
+func (c *Config) ParseMetricsSinks() (*common.MetricsSinksConfig, error) {
+	sinksConfig := &common.MetricsSinksConfig{}
+	for _, sink := range strings.Split(c.MetricsSinks, ",") {
+		switch strings.ToUpper(strings.TrimSpace(sink)) {
+		case MetricsSinkPrometheus:
+			sinksConfig.Prometheus = true
+		case MetricsSinkStatsd:
+			statsdConfig, err := c.parseStatsdConfig()
+			if err != nil {
+				return nil, err
+			}
+			sinksConfig.Statsd = statsdConfig
+		default:
+			return nil, fmt.Errorf("invalid value for ZDM_METRICS_SINKS (%v); possible values are: %v, %v or both",
+				c.MetricsSinks, MetricsSinkPrometheus, MetricsSinkStatsd)
+		}
+	}
+	return sinksConfig, nil
+}
+
+func (c *Config) parseStatsdConfig() (*common.StatsdConfig, error) {
+	if _, _, err := net.SplitHostPort(c.MetricsStatsdAddress); err != nil {
+		return nil, fmt.Errorf("invalid value for ZDM_METRICS_STATSD_ADDRESS (%v): %w", c.MetricsStatsdAddress, err)
+	}
+
+	var dogStatsd bool
+	switch strings.ToUpper(strings.TrimSpace(c.MetricsStatsdFlavor)) {
+	case StatsdFlavorDogStatsd:
+		dogStatsd = true
+	case StatsdFlavorStatsd:
+		dogStatsd = false
+	default:
+		return nil, fmt.Errorf("invalid value for ZDM_METRICS_STATSD_FLAVOR; possible values are: %v and %v",
+			StatsdFlavorDogStatsd, StatsdFlavorStatsd)
+	}
+
+	tags := make([]string, 0)
+	if isDefined(c.MetricsStatsdTags) {
+		for _, tag := range strings.Split(c.MetricsStatsdTags, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "" {
+				continue
+			}
+			if !strings.Contains(tag, ":") {
+				return nil, fmt.Errorf("invalid value for ZDM_METRICS_STATSD_TAGS (%v); "+
+					"expected a comma separated list of <key>:<value>", c.MetricsStatsdTags)
+			}
+			tags = append(tags, tag)
+		}
+	}
+	if !dogStatsd && len(tags) > 0 {
+		return nil, fmt.Errorf("ZDM_METRICS_STATSD_TAGS requires ZDM_METRICS_STATSD_FLAVOR to be %v",
+			StatsdFlavorDogStatsd)
+	}
+
+	if c.MetricsStatsdFlushIntervalMs <= 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_METRICS_STATSD_FLUSH_INTERVAL_MS (%v); it must be positive",
+			c.MetricsStatsdFlushIntervalMs)
+	}
+
+	return &common.StatsdConfig{
+		Address:       c.MetricsStatsdAddress,
+		Prefix:        strings.TrimSuffix(strings.TrimSpace(c.MetricsStatsdPrefix), "."),
+		Tags:          tags,
+		DogStatsd:     dogStatsd,
+		FlushInterval: time.Duration(c.MetricsStatsdFlushIntervalMs) * time.Millisecond,
+	}, nil
+}
+
+const (
+	CounterWritePolicyBoth        = "BOTH"
+	CounterWritePolicyPrimaryOnly = "PRIMARY_ONLY"
+	CounterWritePolicyReject      = "REJECT"
+)
+
+func (c *Config) ParseCounterWritePolicy() (common.CounterWritePolicy, error) {
+	switch strings.ToUpper(c.CounterWritePolicy) {
+	case CounterWritePolicyBoth:
+		return common.CounterWritePolicyBoth, nil
+	case CounterWritePolicyPrimaryOnly:
+		return common.CounterWritePolicyPrimaryOnly, nil
+	case CounterWritePolicyReject:
+		return common.CounterWritePolicyReject, nil
+	default:
+		return common.CounterWritePolicyUndefined, fmt.Errorf(
+			"invalid value for ZDM_COUNTER_WRITE_POLICY; possible values are: %v, %v and %v",
+			CounterWritePolicyBoth, CounterWritePolicyPrimaryOnly, CounterWritePolicyReject)
+	}
+}
+
+const (
+	DdlPolicyBoth       = "BOTH"
+	DdlPolicyOriginOnly = "ORIGIN_ONLY"
+	DdlPolicyReject     = "REJECT"
+)
+
+func (c *Config) ParseDdlPolicy() (common.DdlPolicy, error) {
+	switch strings.ToUpper(c.DdlPolicy) {
+	case DdlPolicyBoth:
+		return common.DdlPolicyBoth, nil
+	case DdlPolicyOriginOnly:
+		return common.DdlPolicyOriginOnly, nil
+	case DdlPolicyReject:
+		return common.DdlPolicyReject, nil
+	default:
+		return common.DdlPolicyUndefined, fmt.Errorf("invalid value for ZDM_DDL_POLICY; possible values are: %v, %v and %v",
+			DdlPolicyBoth, DdlPolicyOriginOnly, DdlPolicyReject)
+	}
+}
+
+const (
+	EventSourcePolicyPrimaryOnly = "PRIMARY_ONLY"
+	EventSourcePolicyBoth        = "BOTH"
+	EventSourcePolicyNone        = "NONE"
+)
+
+func (c *Config) ParseEventSourcePolicy() (common.EventSourcePolicy, error) {
+	switch strings.ToUpper(c.EventSourcePolicy) {
+	case EventSourcePolicyPrimaryOnly:
+		return common.EventSourcePolicyPrimaryOnly, nil
+	case EventSourcePolicyBoth:
+		return common.EventSourcePolicyBoth, nil
+	case EventSourcePolicyNone:
+		return common.EventSourcePolicyNone, nil
+	default:
+		return common.EventSourcePolicyUndefined, fmt.Errorf(
+			"invalid value for ZDM_EVENT_SOURCE_POLICY; possible values are: %v, %v and %v",
+			EventSourcePolicyPrimaryOnly, EventSourcePolicyBoth, EventSourcePolicyNone)
+	}
+}
+
+const (
+	HostSelectionPolicyRoundRobin       = "ROUND_ROBIN"
+	HostSelectionPolicyLeastConnections = "LEAST_CONNECTIONS"
+)
+
+// ParseOriginHostSelectionPolicy returns how the ORIGIN host of each client connection is selected, it only takes
+// effect if ORIGIN host assignment is enabled.
+func (c *Config) ParseOriginHostSelectionPolicy() (common.HostSelectionPolicy, error) {
+	return parseHostSelectionPolicy("ZDM_ORIGIN_HOST_SELECTION_POLICY", c.OriginHostSelectionPolicy)
+}
+
+// ParseTargetHostSelectionPolicy returns how the TARGET host of each client connection is selected, it only takes
+// effect if TARGET host assignment is enabled.
+func (c *Config) ParseTargetHostSelectionPolicy() (common.HostSelectionPolicy, error) {
+	return parseHostSelectionPolicy("ZDM_TARGET_HOST_SELECTION_POLICY", c.TargetHostSelectionPolicy)
+}
+
+func parseHostSelectionPolicy(name string, value string) (common.HostSelectionPolicy, error) {
+	switch strings.ToUpper(value) {
+	case HostSelectionPolicyRoundRobin:
+		return common.HostSelectionPolicyRoundRobin, nil
+	case HostSelectionPolicyLeastConnections:
+		return common.HostSelectionPolicyLeastConnections, nil
+	default:
+		return common.HostSelectionPolicyUndefined, fmt.Errorf(
+			"invalid value for %v; possible values are: %v and %v",
+			name, HostSelectionPolicyRoundRobin, HostSelectionPolicyLeastConnections)
+	}
+}
+
+const (
+	PropagationPolicyPrimaryOnly = "PRIMARY_ONLY"
+	PropagationPolicyMerge       = "MERGE"
+	PropagationPolicyStrip       = "STRIP"
+)
+
+// ParseWarningsPolicy returns how the warnings of the cluster responses are returned to the client.
+func (c *Config) ParseWarningsPolicy() (common.PropagationPolicy, error) {
+	return parsePropagationPolicy("ZDM_WARNINGS_POLICY", c.WarningsPolicy)
+}
+
+// ParseCustomPayloadPolicy returns how the custom payloads of the cluster responses are returned to the client.
+func (c *Config) ParseCustomPayloadPolicy() (common.PropagationPolicy, error) {
+	return parsePropagationPolicy("ZDM_CUSTOM_PAYLOAD_POLICY", c.CustomPayloadPolicy)
+}
+
+func parsePropagationPolicy(name string, value string) (common.PropagationPolicy, error) {
+	switch strings.ToUpper(value) {
+	case PropagationPolicyPrimaryOnly:
+		return common.PropagationPolicyPrimaryOnly, nil
+	case PropagationPolicyMerge:
+		return common.PropagationPolicyMerge, nil
+	case PropagationPolicyStrip:
+		return common.PropagationPolicyStrip, nil
+	default:
+		return common.PropagationPolicyUndefined, fmt.Errorf(
+			"invalid value for %v; possible values are: %v, %v and %v",
+			name, PropagationPolicyPrimaryOnly, PropagationPolicyMerge, PropagationPolicyStrip)
+	}
+}
+
+const (
+	IpFamilyPreferenceV4 = "V4"
+	IpFamilyPreferenceV6 = "V6"
+)
+
+func (c *Config) ParseIpFamilyPreference() (common.IpFamilyPreference, error) {
+	switch strings.ToUpper(c.IpFamilyPreference) {
+	case IpFamilyPreferenceV4:
+		return common.IpFamilyPreferenceV4, nil
+	case IpFamilyPreferenceV6:
+		return common.IpFamilyPreferenceV6, nil
+	default:
+		return common.IpFamilyPreferenceUndefined, fmt.Errorf(
+			"invalid value for ZDM_IP_FAMILY_PREFERENCE; possible values are: %v and %v",
+			IpFamilyPreferenceV4, IpFamilyPreferenceV6)
+	}
+}
+
+// ParseProxySupportedOptions returns the options of ZDM_PROXY_SUPPORTED_OPTIONS, a semicolon separated list of
+// KEY=value1,value2 entries that replace the values of the SUPPORTED response returned by the proxy when
+// ZDM_PROXY_ANSWER_OPTIONS is enabled. A key without values (KEY=) is removed from the response.
+func (c *Config) ParseProxySupportedOptions() (map[string][]string, error) {
+	options := make(map[string][]string)
+	if isNotDefined(c.ProxySupportedOptions) {
+		return options, nil
+	}
+
+	for _, entry := range strings.Split(c.ProxySupportedOptions, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		keyValues := strings.SplitN(entry, "=", 2)
+		key := strings.TrimSpace(keyValues[0])
+		if len(keyValues) != 2 || key == "" {
+			return nil, fmt.Errorf("invalid entry %v in ZDM_PROXY_SUPPORTED_OPTIONS; expected KEY=value1,value2", entry)
+		}
+		if _, ok := options[key]; ok {
+			return nil, fmt.Errorf("invalid ZDM_PROXY_SUPPORTED_OPTIONS: option %v is set more than once", key)
+		}
+		values := make([]string, 0)
+		for _, value := range strings.Split(keyValues[1], ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		options[key] = values
+	}
+	return options, nil
+}
+
+// ParseProxyListenSocketPermissions parses the octal file mode (e.g. 0660) that is applied to the unix socket
+// created at ZDM_PROXY_LISTEN_SOCKET_PATH.
+func (c *Config) ParseProxyListenSocketPermissions() (os.FileMode, error) {
+	permissions, err := strconv.ParseUint(c.ProxyListenSocketPermissions, 8, 32)
+	if err != nil || permissions > 0777 {
+		return 0, fmt.Errorf("invalid value for ZDM_PROXY_LISTEN_SOCKET_PERMISSIONS (%v); "+
+			"it must be an octal file mode between 0000 and 0777", c.ProxyListenSocketPermissions)
+	}
+	return os.FileMode(permissions), nil
+}
+
+const (
+	ProxyProtocolModeDisabled = "DISABLED"
+	ProxyProtocolModeOptional = "OPTIONAL"
+	ProxyProtocolModeRequired = "REQUIRED"
+)
+
+// ParseProxyListenProxyProtocol returns whether connections to the TCP client listener must, may or must not start
+// with a PROXY protocol header.
+func (c *Config) ParseProxyListenProxyProtocol() (common.ProxyProtocolMode, error) {
+	return parseProxyProtocolMode(c.ProxyListenProxyProtocol, "ZDM_PROXY_LISTEN_PROXY_PROTOCOL")
+}
+
+// ParseProxyListenSocketProxyProtocol is like ParseProxyListenProxyProtocol but for the unix socket client listener.
+func (c *Config) ParseProxyListenSocketProxyProtocol() (common.ProxyProtocolMode, error) {
+	return parseProxyProtocolMode(c.ProxyListenSocketProxyProtocol, "ZDM_PROXY_LISTEN_SOCKET_PROXY_PROTOCOL")
+}
+
+func parseProxyProtocolMode(value string, envVarName string) (common.ProxyProtocolMode, error) {
+	switch strings.ToUpper(value) {
+	case ProxyProtocolModeDisabled:
+		return common.ProxyProtocolModeDisabled, nil
+	case ProxyProtocolModeOptional:
+		return common.ProxyProtocolModeOptional, nil
+	case ProxyProtocolModeRequired:
+		return common.ProxyProtocolModeRequired, nil
+	default:
+		return common.ProxyProtocolModeUndefined, fmt.Errorf(
+			"invalid value for %v; possible values are: %v, %v and %v",
+			envVarName, ProxyProtocolModeDisabled, ProxyProtocolModeOptional, ProxyProtocolModeRequired)
+	}
+}
+
+// ParseTargetCircuitBreakerConfig returns the settings of the circuit breaker that stops duplicating writes to
+// TARGET while TARGET is failing, nil is returned if ZDM_TARGET_CIRCUIT_BREAKER_ENABLED is false.
+func (c *Config) ParseTargetCircuitBreakerConfig() (*common.CircuitBreakerConfig, error) {
+	if !c.TargetCircuitBreakerEnabled {
+		return nil, nil
+	}
+
+	primaryCluster, err := c.ParsePrimaryCluster()
+	if err != nil {
+		return nil, err
+	}
+	if primaryCluster != common.ClusterTypeOrigin {
+		return nil, fmt.Errorf("ZDM_TARGET_CIRCUIT_BREAKER_ENABLED requires ZDM_PRIMARY_CLUSTER to be %v "+
+			"because writes can only be skipped on the secondary cluster", PrimaryClusterOrigin)
+	}
+
+	if c.TargetCircuitBreakerErrorRatePercent <= 0 || c.TargetCircuitBreakerErrorRatePercent > 100 {
+		return nil, fmt.Errorf("invalid value for ZDM_TARGET_CIRCUIT_BREAKER_ERROR_RATE_PERCENT (%v); "+
+			"it must be between 1 and 100", c.TargetCircuitBreakerErrorRatePercent)
+	}
+	if c.TargetCircuitBreakerMinRequests <= 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_TARGET_CIRCUIT_BREAKER_MIN_REQUESTS (%v); it must be positive",
+			c.TargetCircuitBreakerMinRequests)
+	}
+	if c.TargetCircuitBreakerWindowMs <= 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_TARGET_CIRCUIT_BREAKER_WINDOW_MS (%v); it must be positive",
+			c.TargetCircuitBreakerWindowMs)
+	}
+	if c.TargetCircuitBreakerCooldownMs <= 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_TARGET_CIRCUIT_BREAKER_COOLDOWN_MS (%v); it must be positive",
+			c.TargetCircuitBreakerCooldownMs)
+	}
+	if c.TargetCircuitBreakerHalfOpenProbes <= 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_TARGET_CIRCUIT_BREAKER_HALF_OPEN_PROBES (%v); it must be positive",
+			c.TargetCircuitBreakerHalfOpenProbes)
+	}
+
+	return &common.CircuitBreakerConfig{
+		ErrorRatePercent: c.TargetCircuitBreakerErrorRatePercent,
+		MinRequests:      c.TargetCircuitBreakerMinRequests,
+		Window:           time.Duration(c.TargetCircuitBreakerWindowMs) * time.Millisecond,
+		Cooldown:         time.Duration(c.TargetCircuitBreakerCooldownMs) * time.Millisecond,
+		HalfOpenProbes:   c.TargetCircuitBreakerHalfOpenProbes,
+	}, nil
+}
+
+func (c *Config) ParseOriginRetryPolicy() (*common.RetryPolicy, error) {
+	return parseRetryPolicy("ORIGIN", c.OriginRetryMaxAttempts, c.OriginRetryBaseDelayMs, c.OriginRetryMaxDelayMs)
+}
+
+func (c *Config) ParseTargetRetryPolicy() (*common.RetryPolicy, error) {
+	return parseRetryPolicy("TARGET", c.TargetRetryMaxAttempts, c.TargetRetryBaseDelayMs, c.TargetRetryMaxDelayMs)
+}
+
+func parseRetryPolicy(clusterPrefix string, maxAttempts int, baseDelayMs int, maxDelayMs int) (*common.RetryPolicy, error) {
+	if maxAttempts < 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_%v_RETRY_MAX_ATTEMPTS (%v); it must not be negative",
+			clusterPrefix, maxAttempts)
+	}
+	if baseDelayMs < 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_%v_RETRY_BASE_DELAY_MS (%v); it must not be negative",
+			clusterPrefix, baseDelayMs)
+	}
+	if maxDelayMs < baseDelayMs {
+		return nil, fmt.Errorf("invalid value for ZDM_%v_RETRY_MAX_DELAY_MS (%v); "+
+			"it must not be lower than ZDM_%v_RETRY_BASE_DELAY_MS (%v)", clusterPrefix, maxDelayMs, clusterPrefix, baseDelayMs)
+	}
+	return &common.RetryPolicy{
+		MaxAttempts: maxAttempts,
+		BaseDelay:   time.Duration(baseDelayMs) * time.Millisecond,
+		MaxDelay:    time.Duration(maxDelayMs) * time.Millisecond,
+	}, nil
+}
+
+// ParseRetryIdempotentTables returns the tables of ZDM_RETRY_IDEMPOTENT_TABLES, the writes to these tables can be
+// retried when they time out.
+func (c *Config) ParseRetryIdempotentTables() ([]string, error) {
+	return parseQualifiedTables("ZDM_RETRY_IDEMPOTENT_TABLES", c.RetryIdempotentTables)
+}
+
+// ParseSchemaDriftKeyspaces returns the ORIGIN keyspaces of ZDM_SCHEMA_DRIFT_KEYSPACES whose schema is compared with
+// TARGET, nil means all the keyspaces except the system keyspaces.
+func (c *Config) ParseSchemaDriftKeyspaces() []string {
+	if isNotDefined(c.SchemaDriftKeyspaces) {
+		return nil
+	}
+	keyspaces := make([]string, 0)
+	for _, keyspace := range strings.Split(c.SchemaDriftKeyspaces, ",") {
+		if keyspace = strings.TrimSpace(keyspace); keyspace != "" {
+			keyspaces = append(keyspaces, keyspace)
+		}
+	}
+	if len(keyspaces) == 0 {
+		return nil
+	}
+	return keyspaces
+}
+
+const (
+	FaultErrorTypeOverloaded   = "OVERLOADED"
+	FaultErrorTypeServerError  = "SERVER_ERROR"
+	FaultErrorTypeUnavailable  = "UNAVAILABLE"
+	FaultErrorTypeReadTimeout  = "READ_TIMEOUT"
+	FaultErrorTypeWriteTimeout = "WRITE_TIMEOUT"
+)
+
+// ParseOriginFaultInjectionConfig returns the faults injected in the responses of ORIGIN, nil is returned if
+// ZDM_FAULT_INJECTION_ENABLED is false or if all the probabilities of ORIGIN are 0.
+func (c *Config) ParseOriginFaultInjectionConfig() (*common.FaultInjectionConfig, error) {
+	return c.parseFaultInjectionConfig(common.ClusterTypeOrigin, c.FaultInjectionOriginLatencyProbability,
+		c.FaultInjectionOriginLatencyMs, c.FaultInjectionOriginDropProbability, c.FaultInjectionOriginErrorProbability,
+		c.FaultInjectionOriginErrorType)
+}
+
+// ParseTargetFaultInjectionConfig returns the faults injected in the responses of TARGET, nil is returned if
+// ZDM_FAULT_INJECTION_ENABLED is false or if all the probabilities of TARGET are 0.
+func (c *Config) ParseTargetFaultInjectionConfig() (*common.FaultInjectionConfig, error) {
+	return c.parseFaultInjectionConfig(common.ClusterTypeTarget, c.FaultInjectionTargetLatencyProbability,
+		c.FaultInjectionTargetLatencyMs, c.FaultInjectionTargetDropProbability, c.FaultInjectionTargetErrorProbability,
+		c.FaultInjectionTargetErrorType)
+}
+
+func (c *Config) parseFaultInjectionConfig(
+	clusterType common.ClusterType, latencyProbability float64, latencyMs int, dropProbability float64,
+	errorProbability float64, errorType string) (*common.FaultInjectionConfig, error) {
+	if !c.FaultInjectionEnabled {
+		return nil, nil
+	}
+
+	probabilities := []struct {
+		name  string
+		value float64
+	}{
+		{"LATENCY_PROBABILITY", latencyProbability},
+		{"DROP_PROBABILITY", dropProbability},
+		{"ERROR_PROBABILITY", errorProbability},
+	}
+	for _, probability := range probabilities {
+		if probability.value < 0 || probability.value > 1 {
+			return nil, fmt.Errorf("invalid value for ZDM_FAULT_INJECTION_%v_%v (%v); it must be between 0 and 1",
+				clusterType, probability.name, probability.value)
+		}
+	}
+	if latencyMs < 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_FAULT_INJECTION_%v_LATENCY_MS (%v); it must not be negative",
+			clusterType, latencyMs)
+	}
+
+	var parsedErrorType common.FaultErrorType
+	switch strings.ToUpper(strings.TrimSpace(errorType)) {
+	case FaultErrorTypeOverloaded:
+		parsedErrorType = common.FaultErrorTypeOverloaded
+	case FaultErrorTypeServerError:
+		parsedErrorType = common.FaultErrorTypeServerError
+	case FaultErrorTypeUnavailable:
+		parsedErrorType = common.FaultErrorTypeUnavailable
+	case FaultErrorTypeReadTimeout:
+		parsedErrorType = common.FaultErrorTypeReadTimeout
+	case FaultErrorTypeWriteTimeout:
+		parsedErrorType = common.FaultErrorTypeWriteTimeout
+	default:
+		return nil, fmt.Errorf("invalid value for ZDM_FAULT_INJECTION_%v_ERROR_TYPE (%v); possible values are: "+
+			"%v, %v, %v, %v and %v", clusterType, errorType, FaultErrorTypeOverloaded, FaultErrorTypeServerError,
+			FaultErrorTypeUnavailable, FaultErrorTypeReadTimeout, FaultErrorTypeWriteTimeout)
+	}
+
+	if latencyProbability == 0 && dropProbability == 0 && errorProbability == 0 {
+		return nil, nil
+	}
+	return &common.FaultInjectionConfig{
+		LatencyProbability: latencyProbability,
+		Latency:            time.Duration(latencyMs) * time.Millisecond,
+		DropProbability:    dropProbability,
+		ErrorProbability:   errorProbability,
+		ErrorType:          parsedErrorType,
+	}, nil
+}
+
+// ParseRequestTimeouts returns the timeout of each type of request, the types without a specific timeout use
+// ZDM_PROXY_REQUEST_TIMEOUT_MS.
+func (c *Config) ParseRequestTimeouts() (*common.RequestTimeouts, error) {
+	if c.ProxyRequestTimeoutMs <= 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_PROXY_REQUEST_TIMEOUT_MS (%v); it must be positive",
+			c.ProxyRequestTimeoutMs)
+	}
+
+	timeouts := make([]time.Duration, 0, 4)
+	for _, setting := range []struct {
+		name  string
+		value int
+	}{
+		{"ZDM_PROXY_READ_REQUEST_TIMEOUT_MS", c.ProxyReadRequestTimeoutMs},
+		{"ZDM_PROXY_WRITE_REQUEST_TIMEOUT_MS", c.ProxyWriteRequestTimeoutMs},
+		{"ZDM_PROXY_PREPARE_REQUEST_TIMEOUT_MS", c.ProxyPrepareRequestTimeoutMs},
+		{"ZDM_PROXY_DDL_REQUEST_TIMEOUT_MS", c.ProxyDdlRequestTimeoutMs},
+	} {
+		if setting.value < 0 {
+			return nil, fmt.Errorf("invalid value for %v (%v); it must not be negative", setting.name, setting.value)
+		}
+		timeoutMs := setting.value
+		if timeoutMs == 0 {
+			timeoutMs = c.ProxyRequestTimeoutMs
+		}
+		timeouts = append(timeouts, time.Duration(timeoutMs)*time.Millisecond)
+	}
+
+	if c.ProxyMaxRequestTimeoutOverrideMs < 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_PROXY_MAX_REQUEST_TIMEOUT_OVERRIDE_MS (%v); it must not be negative",
+			c.ProxyMaxRequestTimeoutOverrideMs)
+	}
+
+	return &common.RequestTimeouts{
+		Default:     time.Duration(c.ProxyRequestTimeoutMs) * time.Millisecond,
+		Read:        timeouts[0],
+		Write:       timeouts[1],
+		Prepare:     timeouts[2],
+		Ddl:         timeouts[3],
+		MaxOverride: time.Duration(c.ProxyMaxRequestTimeoutOverrideMs) * time.Millisecond,
+	}, nil
+}
+
+// ParseFailedWritesJournalConfig returns nil if ZDM_FAILED_WRITES_JOURNAL_ENABLED is false.
+func (c *Config) ParseFailedWritesJournalConfig() (*common.FailedWritesJournalConfig, error) {
+	if !c.FailedWritesJournalEnabled {
+		return nil, nil
+	}
+
+	path := strings.TrimSpace(c.FailedWritesJournalPath)
+	if path == "" {
+		return nil, fmt.Errorf("ZDM_FAILED_WRITES_JOURNAL_PATH is required when the failed writes journal is enabled")
+	}
+	if c.FailedWritesJournalMaxSizeMb < 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_FAILED_WRITES_JOURNAL_MAX_SIZE_MB (%v); it must not be negative",
+			c.FailedWritesJournalMaxSizeMb)
+	}
+	if c.FailedWritesJournalQueueSize <= 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_FAILED_WRITES_JOURNAL_QUEUE_SIZE (%v); it must be positive",
+			c.FailedWritesJournalQueueSize)
+	}
+
+	return &common.FailedWritesJournalConfig{
+		Path:         path,
+		MaxSizeBytes: int64(c.FailedWritesJournalMaxSizeMb) * 1024 * 1024,
+		QueueSize:    c.FailedWritesJournalQueueSize,
+	}, nil
+}
+
+const (
+	AuditLogSinkFile   = "FILE"
+	AuditLogSinkSyslog = "SYSLOG"
+	AuditLogSinkKafka  = "KAFKA"
+
+	AuditLogCategorySelect = "SELECT"
+	AuditLogCategoryDml    = "DML"
+	AuditLogCategoryDdl    = "DDL"
+	AuditLogCategoryDcl    = "DCL"
+)
+
+// ParseAuditLogConfig returns the settings of the audit log. ZDM_AUDIT_LOG_SINKS is a comma separated list of FILE,
+// SYSLOG and KAFKA, only the settings of these sinks are validated and set in the returned config. The audit log is
+// disabled if there are no sinks, unless sinks are registered with ZdmProxy.AddAuditSink.
+func (c *Config) ParseAuditLogConfig() (*common.AuditLogConfig, error) {
+	if c.AuditLogQueueSize <= 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_AUDIT_LOG_QUEUE_SIZE (%v); it must be positive",
+			c.AuditLogQueueSize)
+	}
+	auditLogConfig := &common.AuditLogConfig{
+		Categories:        make(map[common.AuditCategory]bool),
+		IncludeStatements: c.AuditLogIncludeStatements,
+		QueueSize:         c.AuditLogQueueSize,
+	}
+
+	for _, category := range strings.Split(c.AuditLogCategories, ",") {
+		switch strings.ToUpper(strings.TrimSpace(category)) {
+		case AuditLogCategorySelect:
+			auditLogConfig.Categories[common.AuditCategorySelect] = true
+		case AuditLogCategoryDml:
+			auditLogConfig.Categories[common.AuditCategoryDml] = true
+		case AuditLogCategoryDdl:
+			auditLogConfig.Categories[common.AuditCategoryDdl] = true
+		case AuditLogCategoryDcl:
+			auditLogConfig.Categories[common.AuditCategoryDcl] = true
+		case "":
+		default:
+			return nil, fmt.Errorf("invalid value for ZDM_AUDIT_LOG_CATEGORIES (%v); possible values are: %v, %v, %v and %v",
+				c.AuditLogCategories, AuditLogCategorySelect, AuditLogCategoryDml, AuditLogCategoryDdl, AuditLogCategoryDcl)
+		}
+	}
+
+	if isNotDefined(c.AuditLogSinks) {
+		return auditLogConfig, nil
+	}
+	for _, sink := range strings.Split(c.AuditLogSinks, ",") {
+		switch strings.ToUpper(strings.TrimSpace(sink)) {
+		case AuditLogSinkFile:
+			auditLogConfig.FilePath = strings.TrimSpace(c.AuditLogFilePath)
+			if auditLogConfig.FilePath == "" {
+				return nil, fmt.Errorf("ZDM_AUDIT_LOG_FILE_PATH is required when the %v audit log sink is enabled",
+					AuditLogSinkFile)
+			}
+		case AuditLogSinkSyslog:
+			network, address, err := parseSyslogAddress(c.AuditLogSyslogAddress)
+			if err != nil {
+				return nil, err
+			}
+			auditLogConfig.SyslogNetwork = network
+			auditLogConfig.SyslogAddress = address
+		case AuditLogSinkKafka:
+			restUrl, err := url.Parse(strings.TrimSpace(c.AuditLogKafkaRestUrl))
+			if err != nil || (restUrl.Scheme != "http" && restUrl.Scheme != "https") || restUrl.Host == "" {
+				return nil, fmt.Errorf("invalid value for ZDM_AUDIT_LOG_KAFKA_REST_URL (%v); "+
+					"it must be the http or https URL of a Kafka REST proxy", c.AuditLogKafkaRestUrl)
+			}
+			topic := strings.TrimSpace(c.AuditLogKafkaTopic)
+			if topic == "" {
+				return nil, fmt.Errorf("ZDM_AUDIT_LOG_KAFKA_TOPIC is required when the %v audit log sink is enabled",
+					AuditLogSinkKafka)
+			}
+			auditLogConfig.KafkaRestUrl = strings.TrimSuffix(restUrl.String(), "/")
+			auditLogConfig.KafkaTopic = topic
+		default:
+			return nil, fmt.Errorf("invalid value for ZDM_AUDIT_LOG_SINKS (%v); possible values are: %v, %v and %v",
+				c.AuditLogSinks, AuditLogSinkFile, AuditLogSinkSyslog, AuditLogSinkKafka)
+		}
+	}
+	return auditLogConfig, nil
+}
+
+// parseSyslogAddress parses ZDM_AUDIT_LOG_SYSLOG_ADDRESS, e.g. udp://localhost:514, tcp://localhost:601 or
+// unix:///dev/log.
+func parseSyslogAddress(value string) (string, string, error) {
+	syslogUrl, err := url.Parse(strings.TrimSpace(value))
+	if err == nil {
+		switch syslogUrl.Scheme {
+		case "udp", "tcp":
+			if _, _, err = net.SplitHostPort(syslogUrl.Host); err == nil {
+				return syslogUrl.Scheme, syslogUrl.Host, nil
+			}
+		case "unix":
+			if syslogUrl.Path != "" {
+				return "unixgram", syslogUrl.Path, nil
+			}
+		}
+	}
+	return "", "", fmt.Errorf("invalid value for ZDM_AUDIT_LOG_SYSLOG_ADDRESS (%v); "+
+		"expected udp://host:port, tcp://host:port or unix:///path/to/socket", value)
+}
+
+const (
+	FleetConfigBackendEtcd   = "ETCD"
+	FleetConfigBackendConsul = "CONSUL"
+)
+
+// ParseFleetConfig returns nil if ZDM_FLEET_CONFIG_BACKEND is not set. The endpoint defaults to the local agent of the
+// backend (http://localhost:2379 for etcd and http://localhost:8500 for Consul).
+func (c *Config) ParseFleetConfig() (*common.FleetConfig, error) {
+	if isNotDefined(c.FleetConfigBackend) {
+		return nil, nil
+	}
+
+	var defaultEndpoint string
+	backend := strings.ToUpper(strings.TrimSpace(c.FleetConfigBackend))
+	switch backend {
+	case FleetConfigBackendEtcd:
+		defaultEndpoint = "http://localhost:2379"
+	case FleetConfigBackendConsul:
+		defaultEndpoint = "http://localhost:8500"
+	default:
+		return nil, fmt.Errorf("invalid value for ZDM_FLEET_CONFIG_BACKEND (%v); possible values are: %v and %v",
+			c.FleetConfigBackend, FleetConfigBackendEtcd, FleetConfigBackendConsul)
+	}
+
+	endpoint := strings.TrimSpace(c.FleetConfigEndpoint)
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+	endpointUrl, err := url.Parse(endpoint)
+	if err != nil || (endpointUrl.Scheme != "http" && endpointUrl.Scheme != "https") || endpointUrl.Host == "" {
+		return nil, fmt.Errorf("invalid value for ZDM_FLEET_CONFIG_ENDPOINT (%v); "+
+			"it must be the http or https URL of the %v API", c.FleetConfigEndpoint, backend)
+	}
+	key := strings.Trim(strings.TrimSpace(c.FleetConfigKey), "/")
+	if key == "" {
+		return nil, fmt.Errorf("ZDM_FLEET_CONFIG_KEY is required when ZDM_FLEET_CONFIG_BACKEND is set")
+	}
+	if c.FleetConfigPollIntervalMs <= 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_FLEET_CONFIG_POLL_INTERVAL_MS (%v); it must be positive",
+			c.FleetConfigPollIntervalMs)
+	}
+
+	return &common.FleetConfig{
+		Backend:      backend,
+		Endpoint:     strings.TrimSuffix(endpointUrl.String(), "/"),
+		Key:          key,
+		Token:        c.FleetConfigToken,
+		PollInterval: time.Duration(c.FleetConfigPollIntervalMs) * time.Millisecond,
+	}, nil
+}
+
+func (c *Config) ParseLogLevel() (log.Level, error) {
+	level, err := log.ParseLevel(strings.TrimSpace(c.LogLevel))
+	if err != nil {
+		var lvl log.Level
+		return lvl, fmt.Errorf("invalid log level, valid log levels are "+
+			"PANIC, FATAL, ERROR, WARN or WARNING, INFO, DEBUG and TRACE; original err: %w", err)
+	}
+
+	return level, nil
+}
+
+const (
+	LogFormatText = "TEXT"
+	LogFormatJson = "JSON"
+)
+
+// ParseLogFormatter returns the logrus formatter for ZDM_LOG_FORMAT. The JSON format includes the connection_id and
+// request_id fields that the proxy attaches to the log lines of client connections and requests.
+func (c *Config) ParseLogFormatter() (log.Formatter, error) {
+	switch strings.ToUpper(strings.TrimSpace(c.LogFormat)) {
+	case LogFormatText:
+		return &log.TextFormatter{}, nil
+	case LogFormatJson:
+		return &log.JSONFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("invalid value for ZDM_LOG_FORMAT; possible values are: %v and %v",
+			LogFormatText, LogFormatJson)
+	}
+}
+
+const (
+	LogRedactionNone   = "NONE"
+	LogRedactionFull   = "FULL"
+	LogRedactionHash   = "HASH"
+	LogRedactionLength = "LENGTH"
+)
+
+// ParseLogRedaction returns how the literals and bound values of the statements written to the proxy logs are masked.
+func (c *Config) ParseLogRedaction() (common.LogRedactionMode, error) {
+	switch strings.ToUpper(strings.TrimSpace(c.LogRedaction)) {
+	case LogRedactionNone:
+		return common.LogRedactionModeNone, nil
+	case LogRedactionFull:
+		return common.LogRedactionModeFull, nil
+	case LogRedactionHash:
+		return common.LogRedactionModeHash, nil
+	case LogRedactionLength:
+		return common.LogRedactionModeLength, nil
+	default:
+		return common.LogRedactionModeUndefined, fmt.Errorf(
+			"invalid value for ZDM_LOG_REDACTION (%v); possible values are: %v, %v, %v and %v",
+			c.LogRedaction, LogRedactionNone, LogRedactionFull, LogRedactionHash, LogRedactionLength)
+	}
+}
+
+func (c *Config) ParseOriginBuckets() ([]float64, error) {
+	return c.parseBuckets(c.MetricsOriginLatencyBucketsMs)
+}
+
+func (c *Config) ParseTargetBuckets() ([]float64, error) {
+	return c.parseBuckets(c.MetricsTargetLatencyBucketsMs)
+}
+
+func (c *Config) ParseAsyncBuckets() ([]float64, error) {
+	return c.parseBuckets(c.MetricsAsyncReadLatencyBucketsMs)
+}
+
+// ParseTableRequestsAllowList returns the tables of ZDM_METRICS_TABLE_REQUESTS_ALLOW_LIST with the format
+// <keyspace>.<table>, an empty slice if the setting is not defined.
+func (c *Config) ParseTableRequestsAllowList() ([]string, error) {
+	return parseQualifiedTables("ZDM_METRICS_TABLE_REQUESTS_ALLOW_LIST", c.MetricsTableRequestsAllowList)
+}
+
+func parseQualifiedTables(settingName string, setting string) ([]string, error) {
+	if isNotDefined(setting) {
+		return []string{}, nil
+	}
+	tables := make([]string, 0)
+	for _, qualifiedTable := range strings.Split(setting, ",") {
+		qualifiedTable = strings.TrimSpace(qualifiedTable)
+		if qualifiedTable == "" {
+			continue
+		}
+		parts := strings.Split(qualifiedTable, ".")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid value for %v (%v); "+
+				"expected a comma separated list of <keyspace>.<table>", settingName, setting)
+		}
+		tables = append(tables, qualifiedTable)
+	}
+	return tables, nil
+}
+
+func (c *Config) parseBuckets(bucketsConfigStr string) ([]float64, error) {
+	var bucketsArr []float64
+	bucketsStrArr := strings.Split(bucketsConfigStr, ",")
+	if len(bucketsStrArr) == 0 {
+		return nil, fmt.Errorf("unable to parse buckets from %v: at least one bucket is required", bucketsConfigStr)
+	}
+
+	for _, bucketStr := range bucketsStrArr {
+		bucket, err := strconv.ParseFloat(strings.TrimSpace(bucketStr), 64)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"unable to parse buckets from %v: could not convert %v to float",
+				bucketsConfigStr,
+				bucketStr)
+		}
+		bucketsArr = append(bucketsArr, bucket/1000) // convert ms to seconds
+	}
+
+	return bucketsArr, nil
+}
+
+func (c *Config) ParseOriginContactPoints() ([]string, error) {
+	if isDefined(c.OriginSecureConnectBundlePath) && isDefined(c.OriginContactPoints) {
+		return nil, fmt.Errorf("OriginSecureConnectBundlePath and OriginContactPoints are mutually exclusive. Please specify only one of them.")
+	}
+
+	if isDefined(c.OriginSecureConnectBundlePath) && isDefined(c.OriginLocalDatacenter) {
+		return nil, fmt.Errorf("OriginSecureConnectBundlePath and OriginLocalDatacenter are mutually exclusive. Please specify only one of them.")
+	}
+
+	if isNotDefined(c.OriginSecureConnectBundlePath) && isNotDefined(c.OriginContactPoints) {
+		return nil, fmt.Errorf("Both OriginSecureConnectBundlePath and OriginContactPoints are empty. Please specify either one of them.")
+	}
+
+	if isDefined(c.OriginContactPoints) && (c.OriginPort == 0) {
+		return nil, fmt.Errorf("OriginContactPoints was specified but the port is missing. Please provide OriginPort")
+	}
+
+	if (c.OriginEnableHostAssignment == false) && (isDefined(c.OriginLocalDatacenter)) {
+		return nil, fmt.Errorf("OriginLocalDatacenter was specified but OriginEnableHostAssignment is false. Please enable host assignment or don't set the datacenter.")
+	}
+
+	if isNotDefined(c.OriginSecureConnectBundlePath) {
+		contactPoints := parseContactPoints(c.OriginContactPoints)
+		if len(contactPoints) <= 0 {
+			return nil, fmt.Errorf("could not parse origin contact points: %v", c.OriginContactPoints)
+		}
+
+		return contactPoints, nil
+	}
+
+	return nil, nil
+}
+
+func (c *Config) ParseTargetContactPoints() ([]string, error) {
 	if isDefined(c.TargetSecureConnectBundlePath) && isDefined(c.TargetContactPoints) {
 		return nil, fmt.Errorf("TargetSecureConnectBundlePath and TargetContactPoints are mutually exclusive. Please specify only one of them.")
 	}
@@ -444,6 +2359,107 @@ func (c *Config) ParseTargetContactPoints() ([]string, error) {
 	return nil, nil
 }
 
+// ParseOriginRemoteDatacenters returns the datacenters, in order of priority, that ORIGIN connections fall back to
+// when no host of the local datacenter is reachable. Returns nil if remote datacenter failover is disabled.
+func (c *Config) ParseOriginRemoteDatacenters() ([]string, error) {
+	return parseRemoteDatacenters(
+		"ZDM_ORIGIN_REMOTE_DATACENTERS", c.OriginRemoteDatacenters, c.OriginLocalDatacenter,
+		c.OriginSecureConnectBundlePath, c.OriginEnableHostAssignment)
+}
+
+// ParseTargetRemoteDatacenters returns the datacenters, in order of priority, that TARGET connections fall back to
+// when no host of the local datacenter is reachable. Returns nil if remote datacenter failover is disabled.
+func (c *Config) ParseTargetRemoteDatacenters() ([]string, error) {
+	return parseRemoteDatacenters(
+		"ZDM_TARGET_REMOTE_DATACENTERS", c.TargetRemoteDatacenters, c.TargetLocalDatacenter,
+		c.TargetSecureConnectBundlePath, c.TargetEnableHostAssignment)
+}
+
+func parseRemoteDatacenters(
+	name string, setting string, localDatacenter string, secureConnectBundlePath string, hostAssignment bool) ([]string, error) {
+	if isNotDefined(setting) {
+		return nil, nil
+	}
+
+	if isDefined(secureConnectBundlePath) {
+		return nil, fmt.Errorf("invalid value for %v (%v); it can not be used with a secure connect bundle", name, setting)
+	}
+	if !hostAssignment {
+		return nil, fmt.Errorf("invalid value for %v (%v); it requires host assignment to be enabled", name, setting)
+	}
+
+	datacenters := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, dc := range strings.Split(setting, ",") {
+		dc = strings.TrimSpace(dc)
+		if dc == "" {
+			continue
+		}
+		if dc == localDatacenter {
+			return nil, fmt.Errorf("invalid value for %v (%v); it must not contain the local datacenter", name, setting)
+		}
+		if seen[dc] {
+			return nil, fmt.Errorf("invalid value for %v (%v); datacenter %v is listed more than once", name, setting, dc)
+		}
+		seen[dc] = true
+		datacenters = append(datacenters, dc)
+	}
+	return datacenters, nil
+}
+
+const (
+	EgressProxySchemeSocks5 = "socks5"
+	EgressProxySchemeHttp   = "http"
+)
+
+// ParseOriginEgressProxyUrl returns the SOCKS5 or HTTP CONNECT proxy through which the connections to ORIGIN are
+// opened, or nil if they are opened directly.
+func (c *Config) ParseOriginEgressProxyUrl() (*url.URL, error) {
+	return parseEgressProxyUrl("ZDM_ORIGIN_EGRESS_PROXY_URL", c.OriginEgressProxyUrl)
+}
+
+// ParseTargetEgressProxyUrl returns the SOCKS5 or HTTP CONNECT proxy through which the connections to TARGET are
+// opened, or nil if they are opened directly.
+func (c *Config) ParseTargetEgressProxyUrl() (*url.URL, error) {
+	return parseEgressProxyUrl("ZDM_TARGET_EGRESS_PROXY_URL", c.TargetEgressProxyUrl)
+}
+
+func parseEgressProxyUrl(name string, setting string) (*url.URL, error) {
+	if isNotDefined(setting) {
+		return nil, nil
+	}
+
+	proxyUrl, err := url.Parse(strings.TrimSpace(setting))
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for %v (%v); %w", name, setting, err)
+	}
+	var defaultPort string
+	switch proxyUrl.Scheme {
+	case EgressProxySchemeSocks5:
+		defaultPort = "1080"
+	case EgressProxySchemeHttp:
+		defaultPort = "80"
+	default:
+		return nil, fmt.Errorf("invalid value for %v (%v); the scheme must be %v or %v",
+			name, setting, EgressProxySchemeSocks5, EgressProxySchemeHttp)
+	}
+	if proxyUrl.Hostname() == "" {
+		return nil, fmt.Errorf("invalid value for %v (%v); it must contain a host", name, setting)
+	}
+	if proxyUrl.User != nil {
+		// the url is logged with the rest of the configuration
+		return nil, fmt.Errorf("invalid value for %v; it must not contain credentials, "+
+			"use the _USERNAME and _PASSWORD settings instead", name)
+	}
+	if (proxyUrl.Path != "" && proxyUrl.Path != "/") || proxyUrl.RawQuery != "" {
+		return nil, fmt.Errorf("invalid value for %v (%v); it must not contain a path or a query", name, setting)
+	}
+	if proxyUrl.Port() == "" {
+		proxyUrl.Host = net.JoinHostPort(proxyUrl.Hostname(), defaultPort)
+	}
+	return &url.URL{Scheme: proxyUrl.Scheme, Host: proxyUrl.Host}, nil
+}
+
 func parseContactPoints(setting string) []string {
 	return strings.Split(strings.ReplaceAll(setting, " ", ""), ",")
 }
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestConfig_ProxyClientAuth(t *testing.T) {
+
+	type test struct {
+		name            string
+		envVars         []envVar
+		expectedEnabled bool
+		errExpected     bool
+		errMsg          string
+	}
+
+	tests := []test{
+		{
+			name: "Valid: proxy validates client credentials",
+			envVars: []envVar{
+				{"ZDM_PROXY_CLIENT_USERNAME", "app"},
+				{"ZDM_PROXY_CLIENT_PASSWORD", "secret"},
+			},
+			expectedEnabled: true,
+			errExpected:     false,
+			errMsg:          "",
+		},
+		{
+			name:            "Valid: client credentials forwarded to the clusters",
+			envVars:         []envVar{},
+			expectedEnabled: false,
+			errExpected:     false,
+			errMsg:          "",
+		},
+		{
+			name:            "Invalid: username without password",
+			envVars:         []envVar{{"ZDM_PROXY_CLIENT_USERNAME", "app"}},
+			expectedEnabled: false,
+			errExpected:     true,
+			errMsg:          "ZDM_PROXY_CLIENT_USERNAME and ZDM_PROXY_CLIENT_PASSWORD must be set together",
+		},
+		{
+			name:            "Invalid: password without username",
+			envVars:         []envVar{{"ZDM_PROXY_CLIENT_PASSWORD", "secret"}},
+			expectedEnabled: false,
+			errExpected:     true,
+			errMsg:          "ZDM_PROXY_CLIENT_USERNAME and ZDM_PROXY_CLIENT_PASSWORD must be set together",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatal("Unexpected configuration validation error, stopping test here")
+				}
+			}
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				require.False(t, tt.errExpected, "expected configuration validation error")
+				require.Equal(t, tt.expectedEnabled, conf.ProxyClientAuthEnabled())
+			}
+		})
+	}
+
+}
+
+func TestConfig_ParseCredentialMappings(t *testing.T) {
+	mappingFile, err := ioutil.TempFile("", "credential-mappings-*.json")
+	require.Nil(t, err)
+	defer os.Remove(mappingFile.Name())
+	_, err = mappingFile.WriteString(`[
+		{"client_username": "app1", "client_password": "secret1", "target_username": "token", "target_password": "AstraCS:app1"},
+		{"client_username": "app2", "client_password": "secret2", "origin_username": "app2_role", "origin_password": "app2_pwd"}
+	]`)
+	require.Nil(t, err)
+	require.Nil(t, mappingFile.Close())
+
+	clearAllEnvVars()
+	setOriginCredentialsEnvVars()
+	setTargetCredentialsEnvVars()
+	setOriginContactPointsAndPortEnvVars()
+	setTargetContactPointsAndPortEnvVars()
+	setEnvVar("ZDM_PROXY_CREDENTIAL_MAPPING_FILE", mappingFile.Name())
+	setEnvVar("ZDM_PROXY_CLIENT_USERNAME", "admin")
+	setEnvVar("ZDM_PROXY_CLIENT_PASSWORD", "admin_pwd")
+
+	conf, err := New().ParseEnvVars()
+	require.Nil(t, err)
+	require.True(t, conf.ProxyClientAuthEnabled())
+
+	mappings, err := conf.ParseCredentialMappings()
+	require.Nil(t, err)
+	require.Equal(t, []*common.CredentialMapping{
+		{
+			ClientUsername: "app1", ClientPassword: "secret1",
+			OriginUsername: conf.OriginUsername, OriginPassword: conf.OriginPassword,
+			TargetUsername: "token", TargetPassword: "AstraCS:app1",
+		},
+		{
+			ClientUsername: "app2", ClientPassword: "secret2",
+			OriginUsername: "app2_role", OriginPassword: "app2_pwd",
+			TargetUsername: conf.TargetUsername, TargetPassword: conf.TargetPassword,
+		},
+		{
+			ClientUsername: "admin", ClientPassword: "admin_pwd",
+			OriginUsername: conf.OriginUsername, OriginPassword: conf.OriginPassword,
+			TargetUsername: conf.TargetUsername, TargetPassword: conf.TargetPassword,
+		},
+	}, mappings)
+
+	setEnvVar("ZDM_PROXY_CLIENT_USERNAME", "app1")
+	_, err = New().ParseEnvVars()
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "client username app1 is mapped more than once")
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseCounterWritePolicy(t *testing.T) {
+
+	type test struct {
+		name                       string
+		envVars                    []envVar
+		expectedCounterWritePolicy common.CounterWritePolicy
+		errExpected                bool
+		errMsg                     string
+	}
+
+	tests := []test{
+		{
+			name:                       "Valid: counter writes forwarded to both clusters",
+			envVars:                    []envVar{{"ZDM_COUNTER_WRITE_POLICY", "BOTH"}},
+			expectedCounterWritePolicy: common.CounterWritePolicyBoth,
+			errExpected:                false,
+			errMsg:                     "",
+		},
+		{
+			name:                       "Valid: counter writes forwarded to primary cluster only",
+			envVars:                    []envVar{{"ZDM_COUNTER_WRITE_POLICY", "primary_only"}},
+			expectedCounterWritePolicy: common.CounterWritePolicyPrimaryOnly,
+			errExpected:                false,
+			errMsg:                     "",
+		},
+		{
+			name:                       "Valid: counter writes rejected",
+			envVars:                    []envVar{{"ZDM_COUNTER_WRITE_POLICY", "REJECT"}},
+			expectedCounterWritePolicy: common.CounterWritePolicyReject,
+			errExpected:                false,
+			errMsg:                     "",
+		},
+		{
+			name:                       "Invalid: unknown counter write policy",
+			envVars:                    []envVar{{"ZDM_COUNTER_WRITE_POLICY", "TARGET_ONLY"}},
+			expectedCounterWritePolicy: common.CounterWritePolicyUndefined,
+			errExpected:                true,
+			errMsg:                     "invalid value for ZDM_COUNTER_WRITE_POLICY; possible values are: BOTH, PRIMARY_ONLY and REJECT",
+		},
+		{
+			name:                       "Valid: counter write policy unset",
+			envVars:                    []envVar{},
+			expectedCounterWritePolicy: common.CounterWritePolicyBoth,
+			errExpected:                false,
+			errMsg:                     "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatal("Unexpected configuration validation error, stopping test here")
+				}
+			}
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualCounterWritePolicy, _ := conf.ParseCounterWritePolicy()
+				require.Equal(t, tt.expectedCounterWritePolicy, actualCounterWritePolicy)
+			}
+		})
+	}
+
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseLwtPolicy(t *testing.T) {
+
+	type test struct {
+		name              string
+		envVars           []envVar
+		expectedLwtPolicy common.LwtPolicy
+		errExpected       bool
+		errMsg            string
+	}
+
+	tests := []test{
+		{
+			name:              "Valid: LWTs forwarded to both clusters",
+			envVars:           []envVar{{"ZDM_LWT_POLICY", "BOTH"}},
+			expectedLwtPolicy: common.LwtPolicyBoth,
+			errExpected:       false,
+			errMsg:            "",
+		},
+		{
+			name:              "Valid: LWTs forwarded to primary cluster only",
+			envVars:           []envVar{{"ZDM_LWT_POLICY", "primary_only"}},
+			expectedLwtPolicy: common.LwtPolicyPrimaryOnly,
+			errExpected:       false,
+			errMsg:            "",
+		},
+		{
+			name:              "Valid: LWTs rejected",
+			envVars:           []envVar{{"ZDM_LWT_POLICY", "REJECT"}},
+			expectedLwtPolicy: common.LwtPolicyReject,
+			errExpected:       false,
+			errMsg:            "",
+		},
+		{
+			name:              "Invalid: unknown LWT policy",
+			envVars:           []envVar{{"ZDM_LWT_POLICY", "TARGET_ONLY"}},
+			expectedLwtPolicy: common.LwtPolicyUndefined,
+			errExpected:       true,
+			errMsg:            "invalid value for ZDM_LWT_POLICY; possible values are: BOTH, PRIMARY_ONLY and REJECT",
+		},
+		{
+			name:              "Valid: LWT policy unset",
+			envVars:           []envVar{},
+			expectedLwtPolicy: common.LwtPolicyBoth,
+			errExpected:       false,
+			errMsg:            "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatal("Unexpected configuration validation error, stopping test here")
+				}
+			}
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualLwtPolicy, _ := conf.ParseLwtPolicy()
+				require.Equal(t, tt.expectedLwtPolicy, actualLwtPolicy)
+			}
+		})
+	}
+
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseConfigFileAndEnvVars is like ParseEnvVars but the settings can also be provided in a YAML file.
+//
+// The keys of the file are the names of the environment variables in lower case and without the ZDM_ prefix
+// (e.g. origin_contact_points for ZDM_ORIGIN_CONTACT_POINTS). Lists of scalars are joined with commas. For each
+// setting, the environment variable takes precedence over the file which takes precedence over the default value.
+func (c *Config) ParseConfigFileAndEnvVars(path string) (*Config, error) {
+	fileSettings, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.loadSettings(fileSettings, os.LookupEnv)
+	if err != nil {
+		return nil, fmt.Errorf("could not load configuration from environment variables and config file %v: %w", path, err)
+	}
+
+	err = c.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Parsed configuration: %v", c)
+
+	return c, nil
+}
+
+// ParseSettings is like ParseConfigFileAndEnvVars but the settings are provided as a map with the keys of the config
+// file, the environment variables are ignored.
+func (c *Config) ParseSettings(settings map[string]string) (*Config, error) {
+	err := c.loadSettings(settings, func(string) (string, bool) { return "", false })
+	if err != nil {
+		return nil, fmt.Errorf("could not load configuration: %w", err)
+	}
+
+	err = c.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// MarshalConfigFile returns the settings in the format of the config file. Like String, it leaves out the passwords.
+func (c *Config) MarshalConfigFile() ([]byte, error) {
+	value := reflect.ValueOf(c).Elem()
+	settings := make(map[string]interface{}, value.NumField())
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Tag.Get("json") == "-" {
+			continue
+		}
+		settings[configFileKey(field.Name)] = value.Field(i).Interface()
+	}
+	return yaml.Marshal(settings)
+}
+
+func configFileKey(fieldName string) string {
+	return strings.ToLower(strings.TrimPrefix(envVarName(fieldName), "ZDM_"))
+}
+
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read config file: %w", err)
+	}
+
+	var document map[string]yaml.Node
+	err = yaml.Unmarshal(data, &document)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse config file %v: %w", path, err)
+	}
+
+	settings := make(map[string]string, len(document))
+	for key, node := range document {
+		switch node.Kind {
+		case yaml.ScalarNode:
+			if node.Tag == "!!null" {
+				continue
+			}
+			settings[key] = node.Value
+		case yaml.SequenceNode:
+			values := make([]string, 0, len(node.Content))
+			for _, item := range node.Content {
+				if item.Kind != yaml.ScalarNode {
+					return nil, fmt.Errorf("invalid value for %v in config file %v: lists can only contain scalars", key, path)
+				}
+				values = append(values, item.Value)
+			}
+			settings[key] = strings.Join(values, ",")
+		default:
+			return nil, fmt.Errorf("invalid value for %v in config file %v: only scalars and lists of scalars are supported",
+				key, path)
+		}
+	}
+	return settings, nil
+}
+
+// loadSettings fills out the fields of the Config struct like envconfig does (defaults and required settings) with
+// the config file as an additional source of values.
+func (c *Config) loadSettings(fileSettings map[string]string, lookupEnv func(string) (string, bool)) error {
+	value := reflect.ValueOf(c).Elem()
+	knownKeys := make(map[string]bool, value.NumField())
+	for i := 0; i < value.NumField(); i++ {
+		knownKeys[configFileKey(value.Type().Field(i).Name)] = true
+	}
+	unknownKeys := make([]string, 0)
+	for key := range fileSettings {
+		if !knownKeys[key] {
+			unknownKeys = append(unknownKeys, key)
+		}
+	}
+	if len(unknownKeys) > 0 {
+		sort.Strings(unknownKeys)
+		return fmt.Errorf("unknown settings in config file: %v", strings.Join(unknownKeys, ", "))
+	}
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name := envVarName(field.Name)
+		setting, ok := lookupEnv(name)
+		if !ok {
+			setting, ok = fileSettings[configFileKey(field.Name)]
+		}
+		if !ok {
+			setting, ok = field.Tag.Lookup("default")
+		}
+		if !ok {
+			if field.Tag.Get("required") == "true" {
+				return fmt.Errorf("required setting %v (%v in the config file) is missing", name, configFileKey(field.Name))
+			}
+			continue
+		}
+
+		err := setConfigField(value.Field(i), setting)
+		if err != nil {
+			return fmt.Errorf("invalid value for %v (%v): %w", name, setting, err)
+		}
+	}
+	return nil
+}
+
+// setConfigField parses a setting the same way envconfig does for the field types that Config uses.
+func setConfigField(field reflect.Value, setting string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(setting)
+	case reflect.Int:
+		parsed, err := strconv.ParseInt(setting, 0, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(parsed)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(setting)
+		if err != nil {
+			return err
+		}
+		field.SetBool(parsed)
+	case reflect.Float64:
+		parsed, err := strconv.ParseFloat(setting, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(parsed)
+	default:
+		return fmt.Errorf("unsupported setting type %v", field.Type())
+	}
+	return nil
+}
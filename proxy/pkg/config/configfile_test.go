@@ -0,0 +1,134 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "zdm-proxy-config")
+	require.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "zdm-proxy.yml")
+	require.Nil(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestConfig_ParseConfigFileAndEnvVars(t *testing.T) {
+	defer clearAllEnvVars()
+
+	clearAllEnvVars()
+	setOriginCredentialsEnvVars()
+	setEnvVar("ZDM_TARGET_PORT", "9043")
+
+	path := writeConfigFile(t, `
+origin_contact_points: [10.0.0.1, 10.0.0.2]
+origin_username: fileUser
+target_contact_points: 10.0.1.1
+target_username: targetUser
+target_password: targetPassword
+target_port: 9044
+proxy_listen_socket_permissions: 0600
+read_mode: dual_async_on_secondary
+metrics_enabled: false
+`)
+
+	conf, err := New().ParseConfigFileAndEnvVars(path)
+	require.Nil(t, err)
+	require.Equal(t, "originUser", conf.OriginUsername) // env var overrides the file
+	require.Equal(t, "10.0.0.1,10.0.0.2", conf.OriginContactPoints)
+	require.Equal(t, 9043, conf.TargetPort)
+	require.Equal(t, "0600", conf.ProxyListenSocketPermissions)
+	require.Equal(t, "dual_async_on_secondary", conf.ReadMode)
+	require.False(t, conf.MetricsEnabled)
+	require.Equal(t, 9042, conf.OriginPort) // default value
+}
+
+func TestConfig_ParseConfigFileAndEnvVarsErrors(t *testing.T) {
+	defer clearAllEnvVars()
+	clearAllEnvVars()
+
+	_, err := New().ParseConfigFileAndEnvVars(writeConfigFile(t, "origin_username: user\nunknown_setting: 1\n"))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "unknown settings in config file: unknown_setting")
+
+	_, err = New().ParseConfigFileAndEnvVars(writeConfigFile(t, "origin_username: user\n"))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "required setting ZDM_ORIGIN_PASSWORD (origin_password in the config file) is missing")
+
+	_, err = New().ParseConfigFileAndEnvVars(writeConfigFile(t, "origin_port: abc\n"))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "invalid value for ZDM_ORIGIN_PORT (abc)")
+
+	_, err = New().ParseConfigFileAndEnvVars(writeConfigFile(t, "origin_contact_points:\n  host: 10.0.0.1\n"))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "only scalars and lists of scalars are supported")
+}
+
+func TestConfig_ParseSettings(t *testing.T) {
+	defer clearAllEnvVars()
+	clearAllEnvVars()
+	setEnvVar("ZDM_ORIGIN_PORT", "9043")
+
+	conf, err := New().ParseSettings(map[string]string{
+		"origin_contact_points": "10.0.0.1",
+		"origin_username":       "originUser",
+		"origin_password":       "originPassword",
+		"target_contact_points": "10.0.1.1",
+		"target_username":       "targetUser",
+		"target_password":       "targetPassword",
+	})
+	require.Nil(t, err)
+	require.Equal(t, "10.0.0.1", conf.OriginContactPoints)
+	require.Equal(t, 9042, conf.OriginPort, "environment variables are ignored")
+
+	_, err = New().ParseSettings(map[string]string{"origin_username": "user"})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "required setting ZDM_ORIGIN_PASSWORD (origin_password in the config file) is missing")
+}
+
+func TestConfig_LoadSettingsMatchesEnvconfig(t *testing.T) {
+	defer clearAllEnvVars()
+
+	clearAllEnvVars()
+	setOriginCredentialsEnvVars()
+	setTargetCredentialsEnvVars()
+	setOriginContactPointsAndPortEnvVars()
+	setTargetContactPointsAndPortEnvVars()
+	setEnvVar("ZDM_METRICS_ENABLED", "false")
+	setEnvVar("ZDM_HEARTBEAT_RETRY_BACKOFF_FACTOR", "1.5")
+
+	expected, err := New().ParseEnvVars()
+	require.Nil(t, err)
+
+	conf := New()
+	require.Nil(t, conf.loadSettings(map[string]string{}, os.LookupEnv))
+	require.Equal(t, expected, conf)
+}
+
+func TestConfig_MarshalConfigFile(t *testing.T) {
+	defer clearAllEnvVars()
+
+	clearAllEnvVars()
+	setOriginCredentialsEnvVars()
+	setTargetCredentialsEnvVars()
+	setOriginContactPointsAndPortEnvVars()
+	setTargetContactPointsAndPortEnvVars()
+
+	conf, err := New().ParseEnvVars()
+	require.Nil(t, err)
+	marshaled, err := conf.MarshalConfigFile()
+	require.Nil(t, err)
+	require.NotContains(t, string(marshaled), "originPassword")
+
+	// the output can be used as a config file
+	clearAllEnvVars()
+	setOriginCredentialsEnvVars()
+	setTargetCredentialsEnvVars()
+	parsed, err := New().ParseConfigFileAndEnvVars(writeConfigFile(t, string(marshaled)))
+	require.Nil(t, err)
+	require.Equal(t, conf, parsed)
+}
@@ -0,0 +1,78 @@
+package embedded
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"github.com/jpillora/backoff"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// Option configures a Proxy created with NewProxy.
+type Option func(proxy *Proxy)
+
+// WithConfig sets the configuration of the proxy, e.g. one created with config.New().ParseSettings.
+func WithConfig(conf *config.Config) Option {
+	return func(proxy *Proxy) {
+		proxy.conf = conf
+	}
+}
+
+// WithLogger makes the proxy and its components log with the provided logger instead of the standard logger of logrus.
+func WithLogger(logger *log.Logger) Option {
+	return func(proxy *Proxy) {
+		proxy.logger = logger
+	}
+}
+
+// WithMetricsRegistry registers the Prometheus metrics of the proxy with the provided registerer instead of
+// prometheus.DefaultRegisterer, see zdmproxy.ZdmProxy.SetMetricsRegisterer.
+func WithMetricsRegistry(registerer prometheus.Registerer) Option {
+	return func(proxy *Proxy) {
+		proxy.metricsRegisterer = registerer
+	}
+}
+
+// WithStartupBackoff makes Start retry until the proxy starts, like the standalone executable does when the clusters
+// are not reachable yet.
+func WithStartupBackoff(b *backoff.Backoff) Option {
+	return func(proxy *Proxy) {
+		proxy.startupBackoff = b
+	}
+}
+
+// WithInterceptor adds a request interceptor, see zdmproxy.ZdmProxy.AddInterceptor.
+func WithInterceptor(interceptor zdmproxy.Interceptor) Option {
+	return func(proxy *Proxy) {
+		proxy.interceptors = append(proxy.interceptors, interceptor)
+	}
+}
+
+// WithDialer sets the dialer of the connections to a cluster, see zdmproxy.ZdmProxy.SetDialer.
+func WithDialer(clusterType common.ClusterType, dialer zdmproxy.Dialer) Option {
+	return func(proxy *Proxy) {
+		proxy.dialers[clusterType] = dialer
+	}
+}
+
+// WithHostSelectionPolicy sets the host selection policy of a cluster, see zdmproxy.ZdmProxy.SetHostSelectionPolicy.
+func WithHostSelectionPolicy(clusterType common.ClusterType, policy zdmproxy.HostSelectionPolicy) Option {
+	return func(proxy *Proxy) {
+		proxy.hostSelectionPolicies[clusterType] = policy
+	}
+}
+
+// OnClientConnect adds a hook that is called for each client connection accepted by the proxy.
+func OnClientConnect(listener zdmproxy.ClientConnectListener) Option {
+	return func(proxy *Proxy) {
+		proxy.clientConnectListeners = append(proxy.clientConnectListeners, listener)
+	}
+}
+
+// OnClusterDown adds a hook that is called when ORIGIN or TARGET becomes unreachable.
+func OnClusterDown(listener zdmproxy.ClusterDownListener) Option {
+	return func(proxy *Proxy) {
+		proxy.clusterDownListeners = append(proxy.clusterDownListeners, listener)
+	}
+}
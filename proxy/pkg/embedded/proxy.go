@@ -0,0 +1,170 @@
+// Package embedded runs the proxy inside another Go program, it is the API to use instead of starting the standalone
+// executable:
+//
+//	proxy, err := embedded.NewProxy(
+//		embedded.WithConfig(conf),
+//		embedded.OnClusterDown(func(cluster common.ClusterType, err error) { ... }))
+//	if err != nil { ... }
+//	if err = proxy.Start(ctx); err != nil { ... }
+//	defer proxy.Shutdown(context.Background())
+package embedded
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"github.com/jpillora/backoff"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"sync"
+)
+
+// Proxy is a proxy that is configured with options. It can be started again once a failed startup returned or once
+// a shutdown completed.
+type Proxy struct {
+	conf           *config.Config
+	logger         *log.Logger
+	startupBackoff *backoff.Backoff
+
+	metricsRegisterer      prometheus.Registerer
+	interceptors           []zdmproxy.Interceptor
+	dialers                map[common.ClusterType]zdmproxy.Dialer
+	hostSelectionPolicies  map[common.ClusterType]zdmproxy.HostSelectionPolicy
+	clientConnectListeners []zdmproxy.ClientConnectListener
+	clusterDownListeners   []zdmproxy.ClusterDownListener
+
+	lock     *sync.Mutex
+	started  bool
+	zdmProxy *zdmproxy.ZdmProxy
+}
+
+// NewProxy creates a proxy with the provided options and validates its configuration. Without WithConfig the
+// configuration is read from the ZDM_* environment variables, like the standalone executable does.
+func NewProxy(opts ...Option) (*Proxy, error) {
+	proxy := &Proxy{
+		dialers:               make(map[common.ClusterType]zdmproxy.Dialer),
+		hostSelectionPolicies: make(map[common.ClusterType]zdmproxy.HostSelectionPolicy),
+		lock:                  &sync.Mutex{},
+	}
+	for _, opt := range opts {
+		opt(proxy)
+	}
+
+	if proxy.conf == nil {
+		conf, err := config.New().ParseEnvVars()
+		if err != nil {
+			return nil, err
+		}
+		proxy.conf = conf
+	} else if err := proxy.conf.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return proxy, nil
+}
+
+// Start connects to ORIGIN and TARGET and starts accepting client connections, the context only applies to the
+// startup. With WithStartupBackoff the startup is retried until it succeeds or the context is canceled, in which
+// case zdmproxy.ShutdownErr is returned.
+func (recv *Proxy) Start(ctx context.Context) error {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if recv.started {
+		if recv.zdmProxy == nil {
+			return errors.New("proxy is shutting down")
+		}
+		return errors.New("proxy already started")
+	}
+
+	var zdmProxy *zdmproxy.ZdmProxy
+	var err error
+	if recv.startupBackoff != nil {
+		zdmProxy, err = zdmproxy.RunWithRetriesFunc(recv.newZdmProxy, ctx, recv.startupBackoff, recv.getLogger())
+	} else {
+		zdmProxy, err = zdmproxy.RunFunc(recv.newZdmProxy, ctx, recv.getLogger())
+	}
+	if err != nil {
+		return err
+	}
+	recv.started = true
+	recv.zdmProxy = zdmProxy
+	return nil
+}
+
+// Shutdown closes the client connections and the connections to the clusters. If the context is done before the
+// shutdown completes, its error is returned and the shutdown continues in the background. The proxy can be started
+// again once the shutdown completed.
+func (recv *Proxy) Shutdown(ctx context.Context) error {
+	recv.lock.Lock()
+	zdmProxy := recv.zdmProxy
+	recv.zdmProxy = nil
+	recv.lock.Unlock()
+	if zdmProxy == nil {
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		zdmProxy.Shutdown()
+		recv.lock.Lock()
+		recv.started = false
+		recv.lock.Unlock()
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ZdmProxy returns the running proxy (e.g. to serve its metrics or readiness with the handlers of the metrics and
+// health packages), nil if the proxy is not started or was shut down.
+func (recv *Proxy) ZdmProxy() *zdmproxy.ZdmProxy {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.zdmProxy
+}
+
+// Config returns the configuration of the proxy.
+func (recv *Proxy) Config() *config.Config {
+	return recv.conf
+}
+
+// newZdmProxy is called for each startup attempt.
+func (recv *Proxy) newZdmProxy() (*zdmproxy.ZdmProxy, error) {
+	zdmProxy, err := zdmproxy.NewZdmProxyWithLogger(recv.conf, recv.getLogger())
+	if err != nil {
+		return nil, err
+	}
+	if recv.metricsRegisterer != nil {
+		zdmProxy.SetMetricsRegisterer(recv.metricsRegisterer)
+	}
+	for _, interceptor := range recv.interceptors {
+		zdmProxy.AddInterceptor(interceptor)
+	}
+	for clusterType, dialer := range recv.dialers {
+		zdmProxy.SetDialer(clusterType, dialer)
+	}
+	for clusterType, policy := range recv.hostSelectionPolicies {
+		zdmProxy.SetHostSelectionPolicy(clusterType, policy)
+	}
+	for _, listener := range recv.clientConnectListeners {
+		zdmProxy.AddClientConnectListener(listener)
+	}
+	for _, listener := range recv.clusterDownListeners {
+		zdmProxy.AddClusterDownListener(listener)
+	}
+	return zdmProxy, nil
+}
+
+func (recv *Proxy) getLogger() *log.Logger {
+	if recv.logger == nil {
+		return log.StandardLogger()
+	}
+	return recv.logger
+}
@@ -0,0 +1,64 @@
+package embedded
+
+import (
+	"context"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+	"net"
+	"strconv"
+	"testing"
+)
+
+func newConfig(t *testing.T) *config.Config {
+	// nothing listens on the port once the listener is closed so the connections to the clusters are refused
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+	require.Nil(t, listener.Close())
+
+	conf, err := config.New().ParseSettings(map[string]string{
+		"origin_contact_points": "127.0.0.1",
+		"origin_port":           port,
+		"origin_username":       "user",
+		"origin_password":       "password",
+		"target_contact_points": "127.0.0.1",
+		"target_port":           port,
+		"target_username":       "user",
+		"target_password":       "password",
+		"metrics_enabled":       "false",
+	})
+	require.Nil(t, err)
+	return conf
+}
+
+func TestNewProxy_InvalidConfig(t *testing.T) {
+	conf := newConfig(t)
+	conf.HeartbeatIntervalMs = 0
+
+	_, err := NewProxy(WithConfig(conf))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "invalid config")
+}
+
+func TestProxy_StartFailure(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	proxy, err := NewProxy(WithConfig(newConfig(t)), WithLogger(logger))
+	require.Nil(t, err)
+
+	previousOut := log.StandardLogger().Out
+	previousHooks := len(log.StandardLogger().Hooks)
+	err = proxy.Start(context.Background())
+	require.NotNil(t, err)
+	require.Nil(t, proxy.ZdmProxy())
+	require.NotEmpty(t, hook.AllEntries(), "the proxy logs with the provided logger")
+	require.Equal(t, previousOut, log.StandardLogger().Out, "the standard logger is not modified")
+	require.Len(t, log.StandardLogger().Hooks, previousHooks, "the standard logger is not modified")
+
+	// a failed startup can be retried
+	err = proxy.Start(context.Background())
+	require.NotNil(t, err)
+	require.NotContains(t, err.Error(), "already started")
+	require.Nil(t, proxy.Shutdown(context.Background()))
+}
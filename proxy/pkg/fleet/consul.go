@@ -0,0 +1,73 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ConsulBackend reads the key from the KV store of Consul through the HTTP API of an agent.
+type ConsulBackend struct {
+	endpoint   string
+	key        string
+	token      string
+	httpClient *http.Client
+}
+
+// NewConsulBackend creates a backend for the Consul agent at endpoint, e.g. http://localhost:8500. The token, if not
+// empty, is an ACL token that is sent in the X-Consul-Token header.
+func NewConsulBackend(endpoint string, key string, token string, httpClient *http.Client) *ConsulBackend {
+	return &ConsulBackend{endpoint: endpoint, key: key, token: token, httpClient: httpClient}
+}
+
+type consulKvPair struct {
+	Value       []byte `json:"Value"`
+	ModifyIndex uint64 `json:"ModifyIndex"`
+}
+
+func (recv *ConsulBackend) Name() string {
+	return fmt.Sprintf("consul(%v/%v)", recv.endpoint, recv.key)
+}
+
+func (recv *ConsulBackend) Fetch(ctx context.Context) ([]byte, string, error) {
+	segments := strings.Split(recv.key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodGet, recv.endpoint+"/v1/kv/"+strings.Join(segments, "/"), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if recv.token != "" {
+		req.Header.Set("X-Consul-Token", recv.token)
+	}
+
+	rsp, err := recv.httpClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode == http.StatusNotFound {
+		_, _ = io.Copy(ioutil.Discard, rsp.Body)
+		return nil, "", nil
+	}
+	if rsp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(rsp.Body, 1024))
+		return nil, "", fmt.Errorf("consul returned %v: %s", rsp.Status, body)
+	}
+	var pairs []consulKvPair
+	if err = json.NewDecoder(rsp.Body).Decode(&pairs); err != nil {
+		return nil, "", fmt.Errorf("could not decode consul response: %w", err)
+	}
+	if len(pairs) == 0 {
+		return nil, "", nil
+	}
+	return pairs[0].Value, strconv.FormatUint(pairs[0].ModifyIndex, 10), nil
+}
@@ -0,0 +1,70 @@
+package fleet
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// EtcdBackend reads the key with the JSON gateway of the etcd v3 API, which etcd serves on its client port.
+type EtcdBackend struct {
+	endpoint   string
+	key        string
+	token      string
+	httpClient *http.Client
+}
+
+// NewEtcdBackend creates a backend for the etcd cluster at endpoint, e.g. http://localhost:2379. The token, if not
+// empty, is an etcd auth token that is sent in the Authorization header.
+func NewEtcdBackend(endpoint string, key string, token string, httpClient *http.Client) *EtcdBackend {
+	return &EtcdBackend{endpoint: endpoint, key: key, token: token, httpClient: httpClient}
+}
+
+type etcdRangeResponse struct {
+	Kvs []struct {
+		Value       []byte `json:"value"`
+		ModRevision string `json:"mod_revision"`
+	} `json:"kvs"`
+}
+
+func (recv *EtcdBackend) Name() string {
+	return fmt.Sprintf("etcd(%v/%v)", recv.endpoint, recv.key)
+}
+
+func (recv *EtcdBackend) Fetch(ctx context.Context) ([]byte, string, error) {
+	payload, err := json.Marshal(map[string]string{"key": base64.StdEncoding.EncodeToString([]byte(recv.key))})
+	if err != nil {
+		return nil, "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, recv.endpoint+"/v3/kv/range", bytes.NewReader(payload))
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if recv.token != "" {
+		req.Header.Set("Authorization", recv.token)
+	}
+
+	rsp, err := recv.httpClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(rsp.Body, 1024))
+		return nil, "", fmt.Errorf("etcd returned %v: %s", rsp.Status, body)
+	}
+	var rangeResponse etcdRangeResponse
+	if err = json.NewDecoder(rsp.Body).Decode(&rangeResponse); err != nil {
+		return nil, "", fmt.Errorf("could not decode etcd response: %w", err)
+	}
+	if len(rangeResponse.Kvs) == 0 {
+		return nil, "", nil
+	}
+	return rangeResponse.Kvs[0].Value, rangeResponse.Kvs[0].ModRevision, nil
+}
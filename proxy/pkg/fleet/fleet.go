@@ -0,0 +1,177 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// State is the runtime state that is shared by the proxies of a fleet. It is stored as a JSON object under a single
+// key of the coordination backend, e.g. {"migration_phase": "DUAL_WRITE"}.
+//
+// Empty fields are not managed by the fleet, each proxy keeps its own value for them.
+type State struct {
+	MigrationPhase               string `json:"migration_phase,omitempty"`
+	TargetCircuitBreakerOverride string `json:"target_circuit_breaker_override,omitempty"`
+}
+
+// Backend reads the document that contains the shared state from a coordination backend.
+type Backend interface {
+	Name() string
+
+	// Fetch returns the document and its revision, which changes every time the document is modified. A nil document
+	// is returned if the key doesn't exist.
+	Fetch(ctx context.Context) ([]byte, string, error)
+}
+
+// ApplyFunc applies a new state to the proxy. The initial state is the first state that is fetched, before the proxy
+// accepts client connections.
+type ApplyFunc func(state *State, initial bool) error
+
+type Status struct {
+	Backend   string     `json:"backend"`
+	Revision  string     `json:"revision,omitempty"`
+	State     *State     `json:"state,omitempty"`
+	LastSync  *time.Time `json:"last_sync,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// Watcher polls the backend and applies the state every time its revision changes. A state that could not be applied
+// is not applied again until the document is modified.
+type Watcher struct {
+	backend  Backend
+	interval time.Duration
+	apply    ApplyFunc
+
+	lock      *sync.Mutex
+	synced    bool
+	revision  string
+	state     *State
+	lastError string
+
+	lastSync int64 // unix nanoseconds, accessed atomically
+
+	updates metrics.Counter
+	errors  metrics.Counter
+
+	logger *log.Entry
+}
+
+func NewWatcher(
+	backend Backend, interval time.Duration, apply ApplyFunc, metricFactory metrics.MetricFactory,
+	logger *log.Entry) (*Watcher, error) {
+	updates, err := metricFactory.GetOrCreateCounter(metrics.FleetConfigUpdates)
+	if err != nil {
+		return nil, err
+	}
+	errors, err := metricFactory.GetOrCreateCounter(metrics.FleetConfigErrors)
+	if err != nil {
+		return nil, err
+	}
+	watcher := &Watcher{
+		backend:  backend,
+		interval: interval,
+		apply:    apply,
+		lock:     &sync.Mutex{},
+		updates:  updates,
+		errors:   errors,
+		logger:   logger,
+	}
+	_, err = metricFactory.GetOrCreateGaugeFunc(metrics.FleetConfigLastSync, func() float64 {
+		return float64(atomic.LoadInt64(&watcher.lastSync)) / float64(time.Second)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return watcher, nil
+}
+
+// Poll fetches the document and applies it if it changed since the last poll, it returns true if a new state was
+// applied.
+func (recv *Watcher) Poll(ctx context.Context) (bool, error) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	document, revision, err := recv.backend.Fetch(ctx)
+	if err != nil {
+		return false, recv.fail(fmt.Errorf("could not fetch the fleet state from %v: %w", recv.backend.Name(), err))
+	}
+	atomic.StoreInt64(&recv.lastSync, time.Now().UnixNano())
+	if recv.synced && revision == recv.revision {
+		return false, nil
+	}
+
+	initial := !recv.synced
+	recv.synced = true
+	recv.revision = revision
+	state := &State{}
+	if document != nil {
+		if err = json.Unmarshal(document, state); err != nil {
+			return false, recv.fail(fmt.Errorf("invalid fleet state at revision %v of %v: %w",
+				revision, recv.backend.Name(), err))
+		}
+	}
+	recv.state = state
+	if err = recv.apply(state, initial); err != nil {
+		return false, recv.fail(fmt.Errorf("could not apply the fleet state at revision %v: %w", revision, err))
+	}
+	recv.lastError = ""
+	recv.updates.Add(1)
+	return true, nil
+}
+
+// Run polls the backend every interval until ctx is canceled. An error is only logged once until the next successful
+// poll, so that an unreachable backend doesn't flood the logs.
+func (recv *Watcher) Run(ctx context.Context, wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(recv.interval)
+		defer ticker.Stop()
+		lastError := ""
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_, err := recv.Poll(ctx)
+				if err == nil {
+					if lastError != "" {
+						recv.logger.Infof("Fleet state is in sync with %v again.", recv.backend.Name())
+					}
+					lastError = ""
+				} else if ctx.Err() == nil && err.Error() != lastError {
+					recv.logger.Warn(err)
+					lastError = err.Error()
+				}
+			}
+		}
+	}()
+}
+
+func (recv *Watcher) Status() *Status {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	status := &Status{
+		Backend:   recv.backend.Name(),
+		Revision:  recv.revision,
+		State:     recv.state,
+		LastError: recv.lastError,
+	}
+	if lastSync := atomic.LoadInt64(&recv.lastSync); lastSync > 0 {
+		lastSyncTime := time.Unix(0, lastSync)
+		status.LastSync = &lastSyncTime
+	}
+	return status
+}
+
+func (recv *Watcher) fail(err error) error {
+	recv.lastError = err.Error()
+	recv.errors.Add(1)
+	return err
+}
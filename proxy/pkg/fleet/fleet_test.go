@@ -0,0 +1,135 @@
+package fleet
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics/noopmetrics"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeBackend struct {
+	document []byte
+	revision string
+	err      error
+}
+
+func (recv *fakeBackend) Name() string {
+	return "fake"
+}
+
+func (recv *fakeBackend) Fetch(_ context.Context) ([]byte, string, error) {
+	return recv.document, recv.revision, recv.err
+}
+
+func TestWatcher_Poll(t *testing.T) {
+	type application struct {
+		state   State
+		initial bool
+	}
+	var applied []application
+	var applyErr error
+	backend := &fakeBackend{}
+	watcher, err := NewWatcher(backend, time.Second, func(state *State, initial bool) error {
+		applied = append(applied, application{*state, initial})
+		return applyErr
+	}, noopmetrics.NewNoopMetricFactory(), log.NewEntry(log.StandardLogger()))
+	require.Nil(t, err)
+
+	changed, err := watcher.Poll(context.Background())
+	require.Nil(t, err)
+	require.True(t, changed, "the initial state is applied even if the key doesn't exist")
+	require.Equal(t, []application{{State{}, true}}, applied)
+
+	backend.document, backend.revision = []byte(`{"migration_phase":"DUAL_WRITE"}`), "7"
+	changed, err = watcher.Poll(context.Background())
+	require.Nil(t, err)
+	require.True(t, changed)
+	changed, err = watcher.Poll(context.Background())
+	require.Nil(t, err)
+	require.False(t, changed, "the same revision is only applied once")
+	require.Equal(t, application{State{MigrationPhase: "DUAL_WRITE"}, false}, applied[1])
+
+	applyErr = errors.New("illegal transition")
+	backend.document, backend.revision = []byte(`{"migration_phase":"ORIGIN_DECOMMISSIONED"}`), "8"
+	_, err = watcher.Poll(context.Background())
+	require.NotNil(t, err)
+	require.Contains(t, watcher.Status().LastError, "illegal transition")
+	changed, err = watcher.Poll(context.Background())
+	require.Nil(t, err)
+	require.False(t, changed, "a state that could not be applied is not applied again until it changes")
+	require.Equal(t, 3, len(applied))
+
+	backend.err = errors.New("connection refused")
+	_, err = watcher.Poll(context.Background())
+	require.NotNil(t, err)
+	status := watcher.Status()
+	require.Equal(t, "8", status.Revision)
+	require.Equal(t, "ORIGIN_DECOMMISSIONED", status.State.MigrationPhase)
+	require.NotNil(t, status.LastSync)
+}
+
+func TestEtcdBackend_Fetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v3/kv/range" || r.Header.Get("Authorization") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var request map[string]string
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&request))
+		key, _ := base64.StdEncoding.DecodeString(request["key"])
+		if string(key) != "zdm/state" {
+			w.Write([]byte(`{"header":{"revision":"12"}}`))
+			return
+		}
+		w.Write([]byte(`{"header":{"revision":"12"},"kvs":[{"key":"emRtL3N0YXRl","mod_revision":"11",` +
+			`"value":"` + base64.StdEncoding.EncodeToString([]byte(`{"migration_phase":"SHADOW"}`)) + `"}],"count":"1"}`))
+	}))
+	defer server.Close()
+
+	document, revision, err := NewEtcdBackend(server.URL, "zdm/state", "token", server.Client()).Fetch(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, `{"migration_phase":"SHADOW"}`, string(document))
+	require.Equal(t, "11", revision)
+
+	document, _, err = NewEtcdBackend(server.URL, "missing", "token", server.Client()).Fetch(context.Background())
+	require.Nil(t, err)
+	require.Nil(t, document)
+
+	_, _, err = NewEtcdBackend(server.URL, "zdm/state", "", server.Client()).Fetch(context.Background())
+	require.NotNil(t, err)
+}
+
+func TestConsulBackend_Fetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Consul-Token") != "acl" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/v1/kv/zdm/state" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`[{"LockIndex":0,"Key":"zdm/state","Flags":0,"CreateIndex":5,"ModifyIndex":42,"Value":"` +
+			base64.StdEncoding.EncodeToString([]byte(`{"target_circuit_breaker_override":"FORCE_OPEN"}`)) + `"}]`))
+	}))
+	defer server.Close()
+
+	document, revision, err := NewConsulBackend(server.URL, "zdm/state", "acl", server.Client()).Fetch(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, `{"target_circuit_breaker_override":"FORCE_OPEN"}`, string(document))
+	require.Equal(t, "42", revision)
+
+	document, _, err = NewConsulBackend(server.URL, "missing", "acl", server.Client()).Fetch(context.Background())
+	require.Nil(t, err)
+	require.Nil(t, document)
+
+	_, _, err = NewConsulBackend(server.URL, "zdm/state", "", server.Client()).Fetch(context.Background())
+	require.NotNil(t, err)
+}
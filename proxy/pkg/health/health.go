@@ -7,6 +7,7 @@ import (
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 	"net/http"
+	"time"
 )
 
 func DefaultReadinessHandler() http.Handler {
@@ -14,16 +15,31 @@ func DefaultReadinessHandler() http.Handler {
 }
 
 type StatusReport struct {
-	OriginStatus *ControlConnStatus
-	TargetStatus *ControlConnStatus
-	Status       Status
+	OriginStatus   *ControlConnStatus
+	TargetStatus   *ControlConnStatus
+	ListenerStatus *ListenerStatus
+	Status         Status
 }
 
 type ControlConnStatus struct {
 	Addr                  string
 	CurrentFailureCount   int
 	FailureCountThreshold int
-	Status                Status
+	UsableHosts           int
+
+	// RemoteDatacenter is empty unless no host of the local datacenter is reachable
+	RemoteDatacenter string `json:",omitempty"`
+
+	// LastTopologyRefresh is nil if the topology was never refreshed
+	LastTopologyRefresh             *time.Time
+	SecondsSinceLastTopologyRefresh float64
+
+	Status Status
+}
+
+type ListenerStatus struct {
+	Addr   string
+	Status Status
 }
 
 type Status string
@@ -34,6 +50,9 @@ const (
 	STARTUP = Status("STARTUP")
 )
 
+// ReadinessHandler reports whether the proxy is ready to serve requests, i.e. it is listening for client connections
+// and both control connections are healthy. The response is a JSON StatusReport, the status code is 503 if
+// the proxy is not ready.
 func ReadinessHandler(proxy *zdmproxy.ZdmProxy) http.Handler {
 	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
 		if req.Method != http.MethodGet {
@@ -63,6 +82,8 @@ func ReadinessHandler(proxy *zdmproxy.ZdmProxy) http.Handler {
 	})
 }
 
+// LivenessHandler only reports that the process is able to serve HTTP requests. It does not depend on the state of
+// ORIGIN or TARGET so that an orchestrator doesn't restart the proxy while it is retrying to connect to them.
 func LivenessHandler() http.Handler {
 	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
 		rsp.WriteHeader(http.StatusOK)
@@ -84,14 +105,30 @@ func PerformHealthCheck(proxy *zdmproxy.ZdmProxy) *StatusReport {
 
 	originControlConnStatus := newControlConnStatus(originControlConn, proxy.Conf.HeartbeatFailureThreshold)
 	targetControlConnStatus := newControlConnStatus(targetControlConn, proxy.Conf.HeartbeatFailureThreshold)
+	listenerStatus := newListenerStatus(proxy)
 	status := UP
-	if originControlConnStatus.Status != UP || targetControlConnStatus.Status != UP {
+	if originControlConnStatus.Status != UP || targetControlConnStatus.Status != UP || listenerStatus.Status != UP {
 		status = DOWN
 	}
 	return &StatusReport{
-		OriginStatus: originControlConnStatus,
-		TargetStatus: targetControlConnStatus,
-		Status:       status,
+		OriginStatus:   originControlConnStatus,
+		TargetStatus:   targetControlConnStatus,
+		ListenerStatus: listenerStatus,
+		Status:         status,
+	}
+}
+
+func newListenerStatus(proxy *zdmproxy.ZdmProxy) *ListenerStatus {
+	addr := proxy.GetClientListenerAddress()
+	if addr == "" {
+		return &ListenerStatus{
+			Addr:   "NOT_LISTENING",
+			Status: DOWN,
+		}
+	}
+	return &ListenerStatus{
+		Addr:   addr,
+		Status: UP,
 	}
 }
 
@@ -104,14 +141,28 @@ func newControlConnStatus(controlConn *zdmproxy.ControlConn, failureThreshold in
 		addr = currentEndpoint.GetEndpointIdentifier()
 	}
 
+	usableHosts := 0
+	hosts, err := controlConn.GetHostsInLocalDatacenter()
+	if err == nil {
+		usableHosts = len(hosts)
+	}
+
 	controlConnReport := &ControlConnStatus{
 		Addr:                  addr,
 		CurrentFailureCount:   controlConn.ReadFailureCounter(),
 		FailureCountThreshold: failureThreshold,
+		UsableHosts:           usableHosts,
+		RemoteDatacenter:      controlConn.GetRemoteDatacenter(),
 		Status:                UP,
 	}
 
-	if controlConnReport.CurrentFailureCount >= controlConnReport.FailureCountThreshold {
+	lastTopologyRefresh := controlConn.GetLastTopologyRefreshTime()
+	if !lastTopologyRefresh.IsZero() {
+		controlConnReport.LastTopologyRefresh = &lastTopologyRefresh
+		controlConnReport.SecondsSinceLastTopologyRefresh = time.Since(lastTopologyRefresh).Seconds()
+	}
+
+	if controlConnReport.CurrentFailureCount >= controlConnReport.FailureCountThreshold || usableHosts == 0 {
 		controlConnReport.Status = DOWN
 	}
 
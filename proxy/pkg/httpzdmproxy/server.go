@@ -7,15 +7,23 @@ import (
 )
 
 func StartHttpServer(addr string, wg *sync.WaitGroup) *http.Server {
-	srv := &http.Server{Addr: addr}
+	return startServer(&http.Server{Addr: addr}, "metrics", wg)
+}
+
+// StartAdminHttpServer serves handler, the admin endpoints, on its own listener so that they are not exposed on the
+// metrics address.
+func StartAdminHttpServer(addr string, handler http.Handler, wg *sync.WaitGroup) *http.Server {
+	return startServer(&http.Server{Addr: addr, Handler: handler}, "admin", wg)
+}
 
+func startServer(srv *http.Server, name string, wg *sync.WaitGroup) *http.Server {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 
 		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
-			log.Errorf("Failed to listen on the metrics endpoint: %v. "+
-				"The proxy will stay up and listen for CQL requests.", err)
+			log.Errorf("Failed to listen on the %v endpoint: %v. "+
+				"The proxy will stay up and listen for CQL requests.", name, err)
 		}
 	}()
 
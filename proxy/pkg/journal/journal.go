@@ -0,0 +1,209 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ReasonTargetError        = "TARGET_ERROR"
+	ReasonTargetTimeout      = "TARGET_TIMEOUT"
+	ReasonCircuitBreakerOpen = "CIRCUIT_BREAKER_OPEN"
+)
+
+// Entry is a write that was applied to ORIGIN but not to TARGET. Entries are stored as one JSON object per line.
+type Entry struct {
+	// Timestamp is the time (in microseconds since the epoch) at which the proxy received the request, it can be used
+	// as the write timestamp when the mutation is replayed so that it doesn't overwrite more recent writes.
+	Timestamp int64  `json:"timestamp"`
+	Reason    string `json:"reason"`
+	Error     string `json:"error,omitempty"`
+	Keyspace  string `json:"keyspace,omitempty"`
+
+	// PreparedStatements contains the query of every prepared statement referenced by Frame,
+	// indexed by the hex encoded TARGET prepared id.
+	PreparedStatements map[string]string `json:"prepared_statements,omitempty"`
+
+	// Frame is the request (QUERY, EXECUTE or BATCH) as it would have been sent to TARGET,
+	// including the statements and bound values. It is base64 encoded in the journal.
+	Frame []byte `json:"frame"`
+}
+
+// FileJournal appends entries to a local file.
+//
+// Entries are queued and written by a single goroutine so that the client requests never wait for the disk.
+// Entries are dropped (and counted as such) if the queue is full or if the file reached its maximum size.
+type FileJournal struct {
+	config *common.FailedWritesJournalConfig
+
+	file   *os.File
+	writer *bufio.Writer
+	queue  chan *Entry
+	wg     *sync.WaitGroup
+
+	closeLock *sync.RWMutex
+	closed    bool
+
+	size  int64 // bytes, accessed atomically
+	lagNs int64 // accessed atomically
+
+	writtenEntries metrics.Counter
+	droppedEntries metrics.Counter
+
+	logger *log.Entry
+}
+
+// NewFileJournal opens (or creates) the journal file, creates its metrics and starts writing the entries that are
+// appended.
+func NewFileJournal(
+	config *common.FailedWritesJournalConfig, metricFactory metrics.MetricFactory, logger *log.Entry) (*FileJournal, error) {
+	writtenEntries, err := metricFactory.GetOrCreateCounter(metrics.FailedWritesJournalEntries)
+	if err != nil {
+		return nil, err
+	}
+	droppedEntries, err := metricFactory.GetOrCreateCounter(metrics.FailedWritesJournalDroppedEntries)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(config.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("could not open failed writes journal %v: %w", config.Path, err)
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("could not read size of failed writes journal %v: %w", config.Path, err)
+	}
+
+	j := &FileJournal{
+		config:         config,
+		file:           file,
+		writer:         bufio.NewWriter(file),
+		queue:          make(chan *Entry, config.QueueSize),
+		wg:             &sync.WaitGroup{},
+		closeLock:      &sync.RWMutex{},
+		size:           stat.Size(),
+		writtenEntries: writtenEntries,
+		droppedEntries: droppedEntries,
+		logger:         logger,
+	}
+
+	_, err = metricFactory.GetOrCreateGaugeFunc(metrics.FailedWritesJournalSize, func() float64 {
+		return float64(j.Size())
+	})
+	if err == nil {
+		_, err = metricFactory.GetOrCreateGaugeFunc(metrics.FailedWritesJournalPendingEntries, func() float64 {
+			return float64(len(j.queue))
+		})
+	}
+	if err == nil {
+		_, err = metricFactory.GetOrCreateGaugeFunc(metrics.FailedWritesJournalLag, func() float64 {
+			return j.Lag().Seconds()
+		})
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	j.wg.Add(1)
+	go j.run()
+	return j, nil
+}
+
+// Append queues the entry without blocking, it returns false if the entry was dropped.
+func (j *FileJournal) Append(entry *Entry) bool {
+	j.closeLock.RLock()
+	defer j.closeLock.RUnlock()
+	if j.closed {
+		j.droppedEntries.Add(1)
+		return false
+	}
+	select {
+	case j.queue <- entry:
+		return true
+	default:
+		j.droppedEntries.Add(1)
+		j.logger.Debugf("Failed writes journal queue is full, dropping entry with timestamp %v.", entry.Timestamp)
+		return false
+	}
+}
+
+// Size returns the size of the journal file in bytes.
+func (j *FileJournal) Size() int64 {
+	return atomic.LoadInt64(&j.size)
+}
+
+// Lag returns the time between the reception of the request and the write of its entry for the last entry
+// that was written.
+func (j *FileJournal) Lag() time.Duration {
+	return time.Duration(atomic.LoadInt64(&j.lagNs))
+}
+
+// Close writes the entries that are still queued and closes the file.
+func (j *FileJournal) Close() error {
+	j.closeLock.Lock()
+	if j.closed {
+		j.closeLock.Unlock()
+		return nil
+	}
+	j.closed = true
+	close(j.queue)
+	j.closeLock.Unlock()
+
+	j.wg.Wait()
+	return j.file.Close()
+}
+
+func (j *FileJournal) run() {
+	defer j.wg.Done()
+	maxSizeReported := false
+	for entry := range j.queue {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			j.logger.Errorf("Could not serialize failed writes journal entry: %v", err)
+			j.droppedEntries.Add(1)
+			continue
+		}
+		line = append(line, '\n')
+
+		size := atomic.LoadInt64(&j.size)
+		if j.config.MaxSizeBytes > 0 && size+int64(len(line)) > j.config.MaxSizeBytes {
+			if !maxSizeReported {
+				j.logger.Errorf("Failed writes journal %v reached its maximum size of %v bytes, "+
+					"writes that fail on TARGET are no longer journaled.", j.config.Path, j.config.MaxSizeBytes)
+				maxSizeReported = true
+			}
+			j.droppedEntries.Add(1)
+			continue
+		}
+
+		if _, err = j.writer.Write(line); err != nil {
+			j.logger.Errorf("Could not write to failed writes journal %v: %v", j.config.Path, err)
+			j.droppedEntries.Add(1)
+			continue
+		}
+		atomic.AddInt64(&j.size, int64(len(line)))
+		j.writtenEntries.Add(1)
+
+		if len(j.queue) == 0 {
+			if err = j.writer.Flush(); err != nil {
+				j.logger.Errorf("Could not flush failed writes journal %v: %v", j.config.Path, err)
+			}
+		}
+		lag := time.Since(time.Unix(0, entry.Timestamp*int64(time.Microsecond)))
+		atomic.StoreInt64(&j.lagNs, int64(lag))
+	}
+	if err := j.writer.Flush(); err != nil {
+		j.logger.Errorf("Could not flush failed writes journal %v: %v", j.config.Path, err)
+	}
+}
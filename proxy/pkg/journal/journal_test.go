@@ -0,0 +1,77 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics/noopmetrics"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileJournal_Append(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	j, err := NewFileJournal(&common.FailedWritesJournalConfig{Path: path, QueueSize: 10},
+		noopmetrics.NewNoopMetricFactory(), log.NewEntry(log.StandardLogger()))
+	require.Nil(t, err)
+
+	first := &Entry{
+		Timestamp: 1000,
+		Reason:    ReasonTargetError,
+		Error:     "Write timeout",
+		Keyspace:  "ks1",
+		Frame:     []byte{0x04, 0x00, 0x00, 0x01},
+	}
+	second := &Entry{
+		Timestamp:          2000,
+		Reason:             ReasonCircuitBreakerOpen,
+		PreparedStatements: map[string]string{"cafe": "INSERT INTO ks1.t1 (a) VALUES (?)"},
+		Frame:              []byte{0x04, 0x00, 0x00, 0x02},
+	}
+	require.True(t, j.Append(first))
+	require.True(t, j.Append(second))
+	require.Nil(t, j.Close())
+	require.False(t, j.Append(first))
+
+	entries := readEntries(t, path)
+	require.Equal(t, []*Entry{first, second}, entries)
+
+	stat, err := os.Stat(path)
+	require.Nil(t, err)
+	require.Equal(t, stat.Size(), j.Size())
+}
+
+func TestFileJournal_MaxSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	j, err := NewFileJournal(&common.FailedWritesJournalConfig{Path: path, MaxSizeBytes: 100, QueueSize: 10},
+		noopmetrics.NewNoopMetricFactory(), log.NewEntry(log.StandardLogger()))
+	require.Nil(t, err)
+
+	for i := 0; i < 5; i++ {
+		j.Append(&Entry{Timestamp: int64(i), Reason: ReasonTargetTimeout, Frame: []byte{0x04}})
+	}
+	require.Nil(t, j.Close())
+
+	entries := readEntries(t, path)
+	require.Equal(t, 1, len(entries))
+	require.LessOrEqual(t, j.Size(), int64(100))
+}
+
+func readEntries(t *testing.T, path string) []*Entry {
+	file, err := os.Open(path)
+	require.Nil(t, err)
+	defer file.Close()
+
+	entries := make([]*Entry, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		entry := &Entry{}
+		require.Nil(t, json.Unmarshal(scanner.Bytes(), entry))
+		entries = append(entries, entry)
+	}
+	require.Nil(t, scanner.Err())
+	return entries
+}
@@ -0,0 +1,204 @@
+package metrics
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	applicationLabel   = "application"
+	driverNameLabel    = "driver_name"
+	driverVersionLabel = "driver_version"
+
+	applicationConnectionsName        = "proxy_application_connections_total"
+	applicationConnectionsDescription = "Number of client connections currently open per client application"
+
+	applicationRequestsName        = "proxy_application_requests_total"
+	applicationRequestsDescription = "Running total of requests received by the proxy per client application"
+	applicationRequestsTypeLabel   = "type"
+
+	applicationFailedReadsName         = "proxy_application_failed_reads_total"
+	applicationFailedReadsDescription  = "Running total of failed reads per client application"
+	applicationFailedWritesName        = "proxy_application_failed_writes_total"
+	applicationFailedWritesDescription = "Running total of failed writes per client application"
+
+	// UnknownApplicationLabelValue is used when the client did not send the corresponding STARTUP option.
+	UnknownApplicationLabelValue = "unknown"
+)
+
+var (
+	ApplicationConnections = NewMetric(applicationConnectionsName, applicationConnectionsDescription)
+
+	ApplicationReads = NewMetricWithLabels(
+		applicationRequestsName,
+		applicationRequestsDescription,
+		map[string]string{
+			applicationRequestsTypeLabel: typeReads,
+		},
+	)
+	ApplicationWrites = NewMetricWithLabels(
+		applicationRequestsName,
+		applicationRequestsDescription,
+		map[string]string{
+			applicationRequestsTypeLabel: typeWrites,
+		},
+	)
+
+	ApplicationFailedReadsOrigin = NewMetricWithLabels(
+		applicationFailedReadsName,
+		applicationFailedReadsDescription,
+		map[string]string{
+			failedReadsClusterLabel: failedRequestsClusterOrigin,
+		},
+	)
+	ApplicationFailedReadsTarget = NewMetricWithLabels(
+		applicationFailedReadsName,
+		applicationFailedReadsDescription,
+		map[string]string{
+			failedReadsClusterLabel: failedRequestsClusterTarget,
+		},
+	)
+	ApplicationFailedWritesOnOrigin = NewMetricWithLabels(
+		applicationFailedWritesName,
+		applicationFailedWritesDescription,
+		map[string]string{
+			failedWritesFailedOnClusterTypeLabel: failedRequestsClusterOrigin,
+		},
+	)
+	ApplicationFailedWritesOnTarget = NewMetricWithLabels(
+		applicationFailedWritesName,
+		applicationFailedWritesDescription,
+		map[string]string{
+			failedWritesFailedOnClusterTypeLabel: failedRequestsClusterTarget,
+		},
+	)
+	ApplicationFailedWritesOnBoth = NewMetricWithLabels(
+		applicationFailedWritesName,
+		applicationFailedWritesDescription,
+		map[string]string{
+			failedWritesFailedOnClusterTypeLabel: failedRequestsClusterBoth,
+		},
+	)
+)
+
+// ApplicationInfo identifies a client application with the options that drivers send in the STARTUP request.
+type ApplicationInfo struct {
+	Name          string
+	DriverName    string
+	DriverVersion string
+}
+
+func (recv ApplicationInfo) labels() map[string]string {
+	return map[string]string{
+		applicationLabel:   labelValueOrUnknown(recv.Name),
+		driverNameLabel:    labelValueOrUnknown(recv.DriverName),
+		driverVersionLabel: labelValueOrUnknown(recv.DriverVersion),
+	}
+}
+
+func labelValueOrUnknown(value string) string {
+	if value == "" {
+		return UnknownApplicationLabelValue
+	}
+	return value
+}
+
+type ApplicationMetricsInstance struct {
+	OpenConnections Gauge
+
+	Reads  Counter
+	Writes Counter
+
+	FailedReadsOrigin    Counter
+	FailedReadsTarget    Counter
+	FailedWritesOnOrigin Counter
+	FailedWritesOnTarget Counter
+	FailedWritesOnBoth   Counter
+}
+
+// ApplicationMetrics creates the metrics of a client application when the first connection of that application
+// completes the STARTUP request.
+//
+// Up to maxApplications distinct applications are tracked, connections of any other application share the metrics
+// of the application with every label set to UnknownApplicationLabelValue.
+type ApplicationMetrics struct {
+	metricFactory   MetricFactory
+	maxApplications int
+
+	lock      *sync.RWMutex
+	instances map[ApplicationInfo]*ApplicationMetricsInstance
+}
+
+func NewApplicationMetrics(metricFactory MetricFactory, maxApplications int) *ApplicationMetrics {
+	return &ApplicationMetrics{
+		metricFactory:   metricFactory,
+		maxApplications: maxApplications,
+		lock:            &sync.RWMutex{},
+		instances:       make(map[ApplicationInfo]*ApplicationMetricsInstance),
+	}
+}
+
+func (recv *ApplicationMetrics) GetApplicationMetrics(info ApplicationInfo) (*ApplicationMetricsInstance, error) {
+	recv.lock.RLock()
+	instance, ok := recv.instances[info]
+	recv.lock.RUnlock()
+	if ok {
+		return instance, nil
+	}
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	instance, ok = recv.instances[info]
+	if ok {
+		return instance, nil
+	}
+
+	unknown := ApplicationInfo{}
+	if len(recv.instances) >= recv.maxApplications {
+		if instance, ok = recv.instances[unknown]; ok {
+			return instance, nil
+		}
+		info = unknown
+	}
+
+	instance, err := recv.createApplicationMetrics(info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metrics of application %v: %w", info.Name, err)
+	}
+	recv.instances[info] = instance
+	return instance, nil
+}
+
+func (recv *ApplicationMetrics) createApplicationMetrics(info ApplicationInfo) (*ApplicationMetricsInstance, error) {
+	labels := info.labels()
+	openConnections, err := recv.metricFactory.GetOrCreateGauge(ApplicationConnections.WithLabels(labels))
+	if err != nil {
+		return nil, err
+	}
+	counters := make([]Counter, 0, 7)
+	for _, mn := range []Metric{
+		ApplicationReads,
+		ApplicationWrites,
+		ApplicationFailedReadsOrigin,
+		ApplicationFailedReadsTarget,
+		ApplicationFailedWritesOnOrigin,
+		ApplicationFailedWritesOnTarget,
+		ApplicationFailedWritesOnBoth,
+	} {
+		counter, err := recv.metricFactory.GetOrCreateCounter(mn.WithLabels(labels))
+		if err != nil {
+			return nil, err
+		}
+		counters = append(counters, counter)
+	}
+	return &ApplicationMetricsInstance{
+		OpenConnections:      openConnections,
+		Reads:                counters[0],
+		Writes:               counters[1],
+		FailedReadsOrigin:    counters[2],
+		FailedReadsTarget:    counters[3],
+		FailedWritesOnOrigin: counters[4],
+		FailedWritesOnTarget: counters[5],
+		FailedWritesOnBoth:   counters[6],
+	}, nil
+}
@@ -0,0 +1,71 @@
+package metrics_test
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics/prommetrics"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestApplicationMetrics_MaxApplications(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	applicationMetrics := metrics.NewApplicationMetrics(prommetrics.NewPrometheusMetricFactory(registry, log.NewEntry(log.StandardLogger())), 2)
+
+	app1 := metrics.ApplicationInfo{Name: "app1", DriverName: "DataStax Java driver", DriverVersion: "4.14.0"}
+	app2 := metrics.ApplicationInfo{Name: "app2"}
+	app3 := metrics.ApplicationInfo{Name: "app3"}
+
+	app1Metrics, err := applicationMetrics.GetApplicationMetrics(app1)
+	require.Nil(t, err)
+	app1MetricsAgain, err := applicationMetrics.GetApplicationMetrics(app1)
+	require.Nil(t, err)
+	require.Same(t, app1Metrics, app1MetricsAgain)
+
+	app2Metrics, err := applicationMetrics.GetApplicationMetrics(app2)
+	require.Nil(t, err)
+	require.NotSame(t, app1Metrics, app2Metrics)
+
+	app3Metrics, err := applicationMetrics.GetApplicationMetrics(app3)
+	require.Nil(t, err)
+	unknownMetrics, err := applicationMetrics.GetApplicationMetrics(metrics.ApplicationInfo{})
+	require.Nil(t, err)
+	require.Same(t, unknownMetrics, app3Metrics)
+
+	app1Metrics.OpenConnections.Add(1)
+	app2Metrics.Writes.Add(1)
+	app3Metrics.FailedWritesOnTarget.Add(1)
+
+	families, err := registry.Gather()
+	require.Nil(t, err)
+	connections := make(map[string]float64)
+	failedWrites := make(map[string]float64)
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range m.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			key := labels["application"] + "/" + labels["driver_name"] + "/" + labels["driver_version"]
+			switch family.GetName() {
+			case "zdm_proxy_application_connections_total":
+				connections[key] = m.GetGauge().GetValue()
+			case "zdm_proxy_application_failed_writes_total":
+				if labels["failed_on"] == "target" {
+					failedWrites[key] = m.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	require.Equal(t, map[string]float64{
+		"app1/DataStax Java driver/4.14.0": 1,
+		"app2/unknown/unknown":             0,
+		"unknown/unknown/unknown":          0,
+	}, connections)
+	require.Equal(t, map[string]float64{
+		"app1/DataStax Java driver/4.14.0": 0,
+		"app2/unknown/unknown":             0,
+		"unknown/unknown/unknown":          1,
+	}, failedWrites)
+}
@@ -0,0 +1,21 @@
+package metrics
+
+// The metrics of the audit log are only created if the audit log is enabled.
+var (
+	AuditLogEvents = NewMetric(
+		"audit_log_events_total",
+		"Running total of audit events that were written to all the audit log sinks",
+	)
+	AuditLogDroppedEvents = NewMetric(
+		"audit_log_dropped_events_total",
+		"Running total of audit events that were dropped because the audit log queue was full",
+	)
+	AuditLogSinkErrors = NewMetric(
+		"audit_log_sink_errors_total",
+		"Running total of audit events that could not be written to one of the audit log sinks",
+	)
+	AuditLogPendingEvents = NewMetric(
+		"audit_log_pending_events",
+		"Number of audit events that are queued and not yet written to the sinks",
+	)
+)
@@ -0,0 +1,35 @@
+package metrics
+
+const (
+	InjectedFaultsClusterLabel = "cluster"
+
+	injectedFaultsName        = "proxy_injected_faults_total"
+	injectedFaultsDescription = "Running total of faults injected in the responses of a cluster, by type of fault"
+	injectedFaultsTypeLabel   = "fault"
+)
+
+// The metrics of fault injection are only created for the clusters that have faults configured, the cluster label is
+// set with WithLabels.
+var (
+	InjectedFaultsLatency = NewMetricWithLabels(
+		injectedFaultsName,
+		injectedFaultsDescription,
+		map[string]string{
+			injectedFaultsTypeLabel: "latency",
+		},
+	)
+	InjectedFaultsDrop = NewMetricWithLabels(
+		injectedFaultsName,
+		injectedFaultsDescription,
+		map[string]string{
+			injectedFaultsTypeLabel: "drop",
+		},
+	)
+	InjectedFaultsError = NewMetricWithLabels(
+		injectedFaultsName,
+		injectedFaultsDescription,
+		map[string]string{
+			injectedFaultsTypeLabel: "error",
+		},
+	)
+)
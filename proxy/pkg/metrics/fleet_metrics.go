@@ -0,0 +1,17 @@
+package metrics
+
+// The metrics of the fleet config are only created if ZDM_FLEET_CONFIG_BACKEND is set.
+var (
+	FleetConfigUpdates = NewMetric(
+		"fleet_config_updates_total",
+		"Running total of changes of the shared fleet state that were applied",
+	)
+	FleetConfigErrors = NewMetric(
+		"fleet_config_errors_total",
+		"Running total of fleet state fetches or changes that failed",
+	)
+	FleetConfigLastSync = NewMetric(
+		"fleet_config_last_sync_timestamp_seconds",
+		"Time at which the shared fleet state was last fetched successfully",
+	)
+)
@@ -15,5 +15,9 @@ type GaugeFunc interface {
 }
 
 type Histogram interface {
+	// Track observes the time elapsed since begin.
 	Track(begin time.Time)
+
+	// Observe observes a value that is not a duration (e.g. a number of rows).
+	Observe(value float64)
 }
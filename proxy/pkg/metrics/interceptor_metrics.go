@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	interceptorLabel = "interceptor"
+
+	interceptorRequestsName        = "proxy_interceptor_requests_total"
+	interceptorRequestsDescription = "Running total of requests processed by a request interceptor, by the outcome of the interceptor"
+	interceptorRequestsResultLabel = "result"
+
+	interceptorResultPassed    = "passed"
+	interceptorResultRewritten = "rewritten"
+	interceptorResultResponded = "responded"
+	interceptorResultFailed    = "failed"
+)
+
+var (
+	InterceptorRequestsPassed = NewMetricWithLabels(
+		interceptorRequestsName,
+		interceptorRequestsDescription,
+		map[string]string{
+			interceptorRequestsResultLabel: interceptorResultPassed,
+		},
+	)
+	InterceptorRequestsRewritten = NewMetricWithLabels(
+		interceptorRequestsName,
+		interceptorRequestsDescription,
+		map[string]string{
+			interceptorRequestsResultLabel: interceptorResultRewritten,
+		},
+	)
+	InterceptorRequestsResponded = NewMetricWithLabels(
+		interceptorRequestsName,
+		interceptorRequestsDescription,
+		map[string]string{
+			interceptorRequestsResultLabel: interceptorResultResponded,
+		},
+	)
+	InterceptorRequestsFailed = NewMetricWithLabels(
+		interceptorRequestsName,
+		interceptorRequestsDescription,
+		map[string]string{
+			interceptorRequestsResultLabel: interceptorResultFailed,
+		},
+	)
+)
+
+type InterceptorMetricsInstance struct {
+	// the interceptor did not modify the request
+	Passed Counter
+	// the interceptor returned a new request
+	Rewritten Counter
+	// the interceptor returned the response, the request was not forwarded
+	Responded Counter
+	// the interceptor returned an error, the client received a server error
+	Failed Counter
+}
+
+// InterceptorMetrics holds the metrics of the request interceptors registered on the proxy, the interceptor label
+// is the name of the interceptor.
+type InterceptorMetrics struct {
+	metricFactory MetricFactory
+
+	lock      *sync.Mutex
+	instances map[string]*InterceptorMetricsInstance
+}
+
+func NewInterceptorMetrics(metricFactory MetricFactory) *InterceptorMetrics {
+	return &InterceptorMetrics{
+		metricFactory: metricFactory,
+		lock:          &sync.Mutex{},
+		instances:     make(map[string]*InterceptorMetricsInstance),
+	}
+}
+
+func (recv *InterceptorMetrics) GetInterceptorMetrics(name string) (*InterceptorMetricsInstance, error) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if instance, ok := recv.instances[name]; ok {
+		return instance, nil
+	}
+
+	labels := map[string]string{interceptorLabel: name}
+	counters := make([]Counter, 0, 4)
+	for _, mn := range []Metric{
+		InterceptorRequestsPassed,
+		InterceptorRequestsRewritten,
+		InterceptorRequestsResponded,
+		InterceptorRequestsFailed,
+	} {
+		counter, err := recv.metricFactory.GetOrCreateCounter(mn.WithLabels(labels))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create metrics of interceptor %v: %w", name, err)
+		}
+		counters = append(counters, counter)
+	}
+	instance := &InterceptorMetricsInstance{
+		Passed:    counters[0],
+		Rewritten: counters[1],
+		Responded: counters[2],
+		Failed:    counters[3],
+	}
+	recv.instances[name] = instance
+	return instance, nil
+}
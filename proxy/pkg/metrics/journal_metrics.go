@@ -0,0 +1,25 @@
+package metrics
+
+// The metrics of the failed writes journal are only created if the journal is enabled.
+var (
+	FailedWritesJournalEntries = NewMetric(
+		"failed_writes_journal_entries_total",
+		"Running total of writes that were applied to ORIGIN but not to TARGET and were written to the journal",
+	)
+	FailedWritesJournalDroppedEntries = NewMetric(
+		"failed_writes_journal_dropped_entries_total",
+		"Running total of writes that could not be written to the journal (queue full, maximum size reached or I/O error)",
+	)
+	FailedWritesJournalSize = NewMetric(
+		"failed_writes_journal_size_bytes",
+		"Size of the failed writes journal file",
+	)
+	FailedWritesJournalPendingEntries = NewMetric(
+		"failed_writes_journal_pending_entries",
+		"Number of journal entries that are queued and not yet written to the file",
+	)
+	FailedWritesJournalLag = NewMetric(
+		"failed_writes_journal_lag_seconds",
+		"Time between the reception of the last journaled write and the write of its journal entry",
+	)
+)
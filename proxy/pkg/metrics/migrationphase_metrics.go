@@ -0,0 +1,20 @@
+package metrics
+
+const MigrationPhaseLabel = "phase"
+
+// The metrics of the migration phase are only created if ZDM_MIGRATION_PHASE is set, the phase label is set with
+// WithLabels.
+var (
+	MigrationPhase = NewMetric(
+		"migration_phase",
+		"1 for the current migration phase and 0 for the other phases",
+	)
+	MigrationPhaseClientConnections = NewMetric(
+		"migration_phase_client_connections",
+		"Number of open client connections by the migration phase that was current when they were opened",
+	)
+	MigrationPhaseTransitions = NewMetric(
+		"migration_phase_transitions_total",
+		"Running total of migration phase transitions",
+	)
+)
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+)
+
+// multiMetricFactory creates every metric in each of the underlying factories so that the metrics are exported to
+// several monitoring systems at the same time. The HTTP handler of the first factory is used.
+type multiMetricFactory struct {
+	factories []MetricFactory
+}
+
+func NewMultiMetricFactory(factories ...MetricFactory) MetricFactory {
+	return &multiMetricFactory{factories: factories}
+}
+
+func (recv *multiMetricFactory) GetOrCreateCounter(mn Metric) (Counter, error) {
+	counters := make(multiCounter, 0, len(recv.factories))
+	for _, factory := range recv.factories {
+		counter, err := factory.GetOrCreateCounter(mn)
+		if err != nil {
+			return nil, err
+		}
+		counters = append(counters, counter)
+	}
+	return counters, nil
+}
+
+func (recv *multiMetricFactory) GetOrCreateGauge(mn Metric) (Gauge, error) {
+	gauges := make(multiGauge, 0, len(recv.factories))
+	for _, factory := range recv.factories {
+		gauge, err := factory.GetOrCreateGauge(mn)
+		if err != nil {
+			return nil, err
+		}
+		gauges = append(gauges, gauge)
+	}
+	return gauges, nil
+}
+
+func (recv *multiMetricFactory) GetOrCreateGaugeFunc(mn Metric, mf func() float64) (GaugeFunc, error) {
+	gaugeFuncs := make([]GaugeFunc, 0, len(recv.factories))
+	for _, factory := range recv.factories {
+		gaugeFunc, err := factory.GetOrCreateGaugeFunc(mn, mf)
+		if err != nil {
+			return nil, err
+		}
+		gaugeFuncs = append(gaugeFuncs, gaugeFunc)
+	}
+	return gaugeFuncs, nil
+}
+
+func (recv *multiMetricFactory) GetOrCreateHistogram(mn Metric, buckets []float64) (Histogram, error) {
+	histograms := make(multiHistogram, 0, len(recv.factories))
+	for _, factory := range recv.factories {
+		histogram, err := factory.GetOrCreateHistogram(mn, buckets)
+		if err != nil {
+			return nil, err
+		}
+		histograms = append(histograms, histogram)
+	}
+	return histograms, nil
+}
+
+func (recv *multiMetricFactory) UnregisterAllMetrics() error {
+	var firstErr error
+	for _, factory := range recv.factories {
+		if err := factory.UnregisterAllMetrics(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (recv *multiMetricFactory) HttpHandler() http.Handler {
+	if len(recv.factories) == 0 {
+		return DefaultHttpHandler()
+	}
+	return recv.factories[0].HttpHandler()
+}
+
+type multiCounter []Counter
+
+func (recv multiCounter) Add(valueToAdd int) {
+	for _, counter := range recv {
+		counter.Add(valueToAdd)
+	}
+}
+
+type multiGauge []Gauge
+
+func (recv multiGauge) Add(valueToAdd int) {
+	for _, gauge := range recv {
+		gauge.Add(valueToAdd)
+	}
+}
+
+func (recv multiGauge) Subtract(valueToSubtract int) {
+	for _, gauge := range recv {
+		gauge.Subtract(valueToSubtract)
+	}
+}
+
+type multiHistogram []Histogram
+
+func (recv multiHistogram) Track(begin time.Time) {
+	for _, histogram := range recv {
+		histogram.Track(begin)
+	}
+}
+
+func (recv multiHistogram) Observe(value float64) {
+	for _, histogram := range recv {
+		histogram.Observe(value)
+	}
+}
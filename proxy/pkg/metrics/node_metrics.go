@@ -245,10 +245,60 @@ var (
 		"Number of connections currently open for async requests",
 	)
 
+	OriginBytesReceived = NewMetric(
+		"origin_bytes_received_total",
+		"Running total of bytes received on connections to Origin Cassandra",
+	)
+	OriginBytesSent = NewMetric(
+		"origin_bytes_sent_total",
+		"Running total of bytes sent on connections to Origin Cassandra",
+	)
+	TargetBytesReceived = NewMetric(
+		"target_bytes_received_total",
+		"Running total of bytes received on connections to Target Cassandra",
+	)
+	TargetBytesSent = NewMetric(
+		"target_bytes_sent_total",
+		"Running total of bytes sent on connections to Target Cassandra",
+	)
+	AsyncBytesReceived = NewMetric(
+		"async_bytes_received_total",
+		"Running total of bytes received on connections used for async requests",
+	)
+	AsyncBytesSent = NewMetric(
+		"async_bytes_sent_total",
+		"Running total of bytes sent on connections used for async requests",
+	)
+
+	OriginHeartbeatFailures = NewMetric(
+		"origin_heartbeat_failures_total",
+		"Running total of heartbeats that failed on request connections to Origin Cassandra",
+	)
+	TargetHeartbeatFailures = NewMetric(
+		"target_heartbeat_failures_total",
+		"Running total of heartbeats that failed on request connections to Target Cassandra",
+	)
+	AsyncHeartbeatFailures = NewMetric(
+		"async_heartbeat_failures_total",
+		"Running total of heartbeats that failed on connections used for async requests",
+	)
+
 	InFlightRequestsAsync = NewMetric(
 		"async_inflight_requests_total",
 		"Number of async requests currently in flight",
 	)
+	OriginStreamIdsExhausted = NewMetric(
+		"origin_stream_ids_exhausted_total",
+		"Running total of requests that could not use the request connection to Origin Cassandra because all its stream ids were in use",
+	)
+	TargetStreamIdsExhausted = NewMetric(
+		"target_stream_ids_exhausted_total",
+		"Running total of requests that could not use the request connection to Target Cassandra because all its stream ids were in use",
+	)
+	AsyncStreamIdsExhausted = NewMetric(
+		"async_stream_ids_exhausted_total",
+		"Running total of async requests that were not sent because all the stream ids of the async connection were in use",
+	)
 )
 
 type NodeMetrics struct {
@@ -272,7 +322,16 @@ type NodeMetricsInstance struct {
 
 	OpenConnections Gauge
 
+	// bytes of the CQL frames, the TLS overhead is not included
+	BytesReceived Counter
+	BytesSent     Counter
+
+	HeartbeatFailures Counter
+
 	InFlightRequests Gauge
+
+	// see ZDM_ASYNC_CONNECTOR_MAX_STREAM_IDS and ZDM_STREAM_ID_VIRTUALIZATION_ENABLED
+	StreamIdsExhausted Counter
 }
 
 func CreateCounterNodeMetric(metricFactory MetricFactory, nodeDescription string, mn Metric) (Counter, error) {
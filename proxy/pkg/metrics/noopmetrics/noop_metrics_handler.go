@@ -46,3 +46,5 @@ func (recv *NoopMetric) Add(val int) {}
 func (recv *NoopMetric) Subtract(val int) {}
 
 func (recv *NoopMetric) Track(begin time.Time) {}
+
+func (recv *NoopMetric) Observe(value float64) {}
@@ -18,17 +18,19 @@ type PrometheusMetricFactory struct {
 	registerer           prometheus.Registerer
 	lock                 *sync.Mutex
 	registeredCollectors []*collectorEntry
+	logger               *log.Entry
 }
 
 /***
 	Instantiation and initialization
  ***/
 
-func NewPrometheusMetricFactory(registerer prometheus.Registerer) *PrometheusMetricFactory {
+func NewPrometheusMetricFactory(registerer prometheus.Registerer, logger *log.Entry) *PrometheusMetricFactory {
 	m := &PrometheusMetricFactory{
 		registerer:           registerer,
 		lock:                 &sync.Mutex{},
 		registeredCollectors: make([]*collectorEntry, 0),
+		logger:               logger,
 	}
 	return m
 }
@@ -205,7 +207,7 @@ func (pm *PrometheusMetricFactory) UnregisterAllMetrics() error {
 		if !ok {
 			failedUnregistrations = append(failedUnregistrations, c.name)
 		} else {
-			log.Debugf("Collector %v successfully unregistered.", c.name)
+			pm.logger.Debugf("Collector %v successfully unregistered.", c.name)
 		}
 	}
 
@@ -220,7 +222,12 @@ func (pm *PrometheusMetricFactory) UnregisterAllMetrics() error {
 	return nil
 }
 
+// HttpHandler serves the metrics of the registerer if it is not prometheus.DefaultRegisterer and is also a
+// prometheus.Gatherer, the metrics of prometheus.DefaultGatherer otherwise.
 func (pm *PrometheusMetricFactory) HttpHandler() http.Handler {
+	if gatherer, ok := pm.registerer.(prometheus.Gatherer); ok && pm.registerer != prometheus.DefaultRegisterer {
+		return promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})
+	}
 	return promhttp.Handler()
 }
 
@@ -236,7 +243,7 @@ func (pm *PrometheusMetricFactory) registerCollector(mn metrics.Metric, c promet
 		}
 		return nil, fmt.Errorf("collector %v could not be registered due to %w", mn.String(), err)
 	} else {
-		log.Debugf("Collector %v registered", mn.GetName())
+		pm.logger.Debugf("Collector %v registered", mn.GetName())
 	}
 
 	pm.lock.Lock()
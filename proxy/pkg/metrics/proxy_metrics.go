@@ -24,6 +24,65 @@ const (
 	inFlightRequestsName        = "proxy_inflight_requests_total"
 	inFlightRequestsTypeLabel   = "type"
 	inFlightRequestsDescription = "Number of requests currently in flight in the proxy"
+
+	psCacheRePrepareName              = "pscache_reprepare_total"
+	psCacheRePrepareDescription       = "Running total of statements that were re-prepared by the proxy after an UNPREPARED response"
+	psCacheRePrepareFailedName        = "pscache_reprepare_failed_total"
+	psCacheRePrepareFailedDescription = "Running total of statements that the proxy failed to re-prepare after an UNPREPARED response"
+	psCacheRePrepareClusterLabel      = "cluster"
+
+	retriesName         = "proxy_retries_total"
+	retriesDescription  = "Running total of requests that the proxy retried after a transient error"
+	retriesClusterLabel = "cluster"
+
+	failoversName              = "proxy_request_connection_failovers_total"
+	failoversDescription       = "Running total of request connections that were reconnected to another host after the connection to their host was lost"
+	failoversFailedName        = "proxy_request_connection_failovers_failed_total"
+	failoversFailedDescription = "Running total of request connections that could not be reconnected to another host after the connection to their host was lost"
+	failoversClusterLabel      = "cluster"
+
+	bufferPoolGetsName        = "proxy_buffer_pool_gets_total"
+	bufferPoolGetsDescription = "Running total of write buffers taken from the buffer pool, by whether a pooled buffer was reused (hit) or a new one was allocated (miss)"
+	bufferPoolResultLabel     = "result"
+	bufferPoolResultHit       = "hit"
+	bufferPoolResultMiss      = "miss"
+
+	oversizedFramesName           = "proxy_oversized_frames_total"
+	oversizedFramesDescription    = "Running total of frames that were rejected because their body exceeded the maximum frame size, by direction"
+	oversizedFramesDirectionLabel = "direction"
+	oversizedFramesRequest        = "request"
+	oversizedFramesResponse       = "response"
+
+	dnsResolutionFailuresName         = "proxy_dns_resolution_failures_total"
+	dnsResolutionFailuresDescription  = "Running total of failed DNS resolutions of the host names of a cluster (e.g. the Astra SNI proxy)"
+	dnsResolutionFailuresClusterLabel = "cluster"
+
+	consistencyOverridesName         = "proxy_consistency_level_overrides_total"
+	consistencyOverridesDescription  = "Running total of requests whose consistency level was overridden by the proxy"
+	consistencyOverridesClusterLabel = "cluster"
+
+	responseWarningsName         = "proxy_response_warnings_total"
+	responseWarningsDescription  = "Running total of the warnings that the clusters returned in their responses, by cluster"
+	responseWarningsClusterLabel = "cluster"
+
+	speculativeReadsName        = "proxy_speculative_reads_total"
+	speculativeReadsDescription = "Running total of speculative reads sent to the secondary cluster, by whether their response was returned to the client"
+	speculativeReadsResultLabel = "result"
+	speculativeReadsResultWin   = "win"
+	speculativeReadsResultLoss  = "loss"
+
+	readResponseRowsName         = "proxy_read_response_rows"
+	readResponseRowsDescription  = "Histogram that tracks the number of rows returned per read, by the cluster that returned them"
+	readResponseBytesName        = "proxy_read_response_bytes"
+	readResponseBytesDescription = "Histogram that tracks the size in bytes of the responses of reads, by the cluster that returned them"
+	readResponseClusterLabel     = "cluster"
+	readResponseClusterAsync     = "async"
+)
+
+// ReadResponseRowsBuckets and ReadResponseBytesBuckets are the buckets of the read response histograms.
+var (
+	ReadResponseRowsBuckets  = []float64{0, 1, 10, 100, 1000, 5000, 10000, 50000, 100000}
+	ReadResponseBytesBuckets = []float64{1024, 4096, 16384, 65536, 262144, 1048576, 4194304, 16777216, 67108864}
 )
 
 var (
@@ -71,6 +130,177 @@ var (
 		"pscache_miss_total",
 		"Running total of prepared statement cache misses in the proxy",
 	)
+	PSCacheHitCount = NewMetric(
+		"pscache_hit_total",
+		"Running total of prepared statement cache hits in the proxy",
+	)
+	PSCacheEvictionCount = NewMetric(
+		"pscache_eviction_total",
+		"Running total of prepared statement cache entries evicted because the cache reached its maximum size",
+	)
+	PSCacheRePrepareOrigin = NewMetricWithLabels(
+		psCacheRePrepareName,
+		psCacheRePrepareDescription,
+		map[string]string{
+			psCacheRePrepareClusterLabel: failedRequestsClusterOrigin,
+		},
+	)
+	PSCacheRePrepareTarget = NewMetricWithLabels(
+		psCacheRePrepareName,
+		psCacheRePrepareDescription,
+		map[string]string{
+			psCacheRePrepareClusterLabel: failedRequestsClusterTarget,
+		},
+	)
+
+	RetriesOrigin = NewMetricWithLabels(
+		retriesName,
+		retriesDescription,
+		map[string]string{
+			retriesClusterLabel: failedRequestsClusterOrigin,
+		},
+	)
+	RetriesTarget = NewMetricWithLabels(
+		retriesName,
+		retriesDescription,
+		map[string]string{
+			retriesClusterLabel: failedRequestsClusterTarget,
+		},
+	)
+	RequestConnectionFailoversOrigin = NewMetricWithLabels(
+		failoversName,
+		failoversDescription,
+		map[string]string{
+			failoversClusterLabel: failedRequestsClusterOrigin,
+		},
+	)
+	RequestConnectionFailoversTarget = NewMetricWithLabels(
+		failoversName,
+		failoversDescription,
+		map[string]string{
+			failoversClusterLabel: failedRequestsClusterTarget,
+		},
+	)
+	RequestConnectionFailoversFailedOrigin = NewMetricWithLabels(
+		failoversFailedName,
+		failoversFailedDescription,
+		map[string]string{
+			failoversClusterLabel: failedRequestsClusterOrigin,
+		},
+	)
+	RequestConnectionFailoversFailedTarget = NewMetricWithLabels(
+		failoversFailedName,
+		failoversFailedDescription,
+		map[string]string{
+			failoversClusterLabel: failedRequestsClusterTarget,
+		},
+	)
+	BufferPoolHits = NewMetricWithLabels(
+		bufferPoolGetsName,
+		bufferPoolGetsDescription,
+		map[string]string{
+			bufferPoolResultLabel: bufferPoolResultHit,
+		},
+	)
+	BufferPoolMisses = NewMetricWithLabels(
+		bufferPoolGetsName,
+		bufferPoolGetsDescription,
+		map[string]string{
+			bufferPoolResultLabel: bufferPoolResultMiss,
+		},
+	)
+	OversizedRequestFrames = NewMetricWithLabels(
+		oversizedFramesName,
+		oversizedFramesDescription,
+		map[string]string{
+			oversizedFramesDirectionLabel: oversizedFramesRequest,
+		},
+	)
+	OversizedResponseFrames = NewMetricWithLabels(
+		oversizedFramesName,
+		oversizedFramesDescription,
+		map[string]string{
+			oversizedFramesDirectionLabel: oversizedFramesResponse,
+		},
+	)
+	DnsResolutionFailuresOrigin = NewMetricWithLabels(
+		dnsResolutionFailuresName,
+		dnsResolutionFailuresDescription,
+		map[string]string{
+			dnsResolutionFailuresClusterLabel: failedRequestsClusterOrigin,
+		},
+	)
+	DnsResolutionFailuresTarget = NewMetricWithLabels(
+		dnsResolutionFailuresName,
+		dnsResolutionFailuresDescription,
+		map[string]string{
+			dnsResolutionFailuresClusterLabel: failedRequestsClusterTarget,
+		},
+	)
+	DatacenterFailoverActiveOrigin = NewMetric(
+		"origin_datacenter_failover_active",
+		"Whether the ORIGIN control connection is connected to a remote datacenter because no host of the local datacenter is reachable (1) or not (0)",
+	)
+	DatacenterFailoverActiveTarget = NewMetric(
+		"target_datacenter_failover_active",
+		"Whether the TARGET control connection is connected to a remote datacenter because no host of the local datacenter is reachable (1) or not (0)",
+	)
+	ConsistencyLevelOverridesOrigin = NewMetricWithLabels(
+		consistencyOverridesName,
+		consistencyOverridesDescription,
+		map[string]string{
+			consistencyOverridesClusterLabel: failedRequestsClusterOrigin,
+		},
+	)
+	ConsistencyLevelOverridesTarget = NewMetricWithLabels(
+		consistencyOverridesName,
+		consistencyOverridesDescription,
+		map[string]string{
+			consistencyOverridesClusterLabel: failedRequestsClusterTarget,
+		},
+	)
+	ResponseWarningsOrigin = NewMetricWithLabels(
+		responseWarningsName,
+		responseWarningsDescription,
+		map[string]string{
+			responseWarningsClusterLabel: failedRequestsClusterOrigin,
+		},
+	)
+	ResponseWarningsTarget = NewMetricWithLabels(
+		responseWarningsName,
+		responseWarningsDescription,
+		map[string]string{
+			responseWarningsClusterLabel: failedRequestsClusterTarget,
+		},
+	)
+	SpeculativeReadWins = NewMetricWithLabels(
+		speculativeReadsName,
+		speculativeReadsDescription,
+		map[string]string{
+			speculativeReadsResultLabel: speculativeReadsResultWin,
+		},
+	)
+	SpeculativeReadLosses = NewMetricWithLabels(
+		speculativeReadsName,
+		speculativeReadsDescription,
+		map[string]string{
+			speculativeReadsResultLabel: speculativeReadsResultLoss,
+		},
+	)
+	PSCacheRePrepareFailedOrigin = NewMetricWithLabels(
+		psCacheRePrepareFailedName,
+		psCacheRePrepareFailedDescription,
+		map[string]string{
+			psCacheRePrepareClusterLabel: failedRequestsClusterOrigin,
+		},
+	)
+	PSCacheRePrepareFailedTarget = NewMetricWithLabels(
+		psCacheRePrepareFailedName,
+		psCacheRePrepareFailedDescription,
+		map[string]string{
+			psCacheRePrepareClusterLabel: failedRequestsClusterTarget,
+		},
+	)
 
 	ProxyReadsOriginDuration = NewMetricWithLabels(
 		requestDurationName,
@@ -120,6 +350,98 @@ var (
 		"client_connections_total",
 		"Number of client connections currently open",
 	)
+	ClientBytesReceived = NewMetric(
+		"client_bytes_received_total",
+		"Running total of bytes received on client connections",
+	)
+	ClientBytesSent = NewMetric(
+		"client_bytes_sent_total",
+		"Running total of bytes sent on client connections",
+	)
+	IdleClientConnectionsClosed = NewMetric(
+		"client_connections_idle_closed_total",
+		"Running total of client connections that were closed because they did not send any request for ZDM_PROXY_CLIENT_IDLE_TIMEOUT_MS",
+	)
+
+	LwtRequestCount = NewMetric(
+		"lwt_requests_total",
+		"Running total of lightweight transactions (conditional writes) received by the proxy",
+	)
+	LwtAppliedMismatchCount = NewMetric(
+		"lwt_applied_mismatch_total",
+		"Running total of lightweight transactions whose [applied] result differed between ORIGIN and TARGET",
+	)
+
+	CounterWriteCount = NewMetric(
+		"counter_writes_total",
+		"Running total of counter table updates received by the proxy",
+	)
+
+	TargetCircuitBreakerSkippedWrites = NewMetric(
+		"target_circuit_breaker_skipped_writes_total",
+		"Running total of writes that were only sent to ORIGIN because the TARGET circuit breaker was open",
+	)
+	TargetCircuitBreakerOpen = NewMetric(
+		"target_circuit_breaker_open",
+		"1 if the TARGET circuit breaker is open or half open, 0 otherwise",
+	)
+
+	ShadowWrites = NewMetric(
+		"shadow_writes_total",
+		"Running total of writes that were mirrored to TARGET in shadow mode",
+	)
+	ShadowWritesSkipped = NewMetric(
+		"shadow_writes_skipped_total",
+		"Running total of writes that could not be mirrored to TARGET in shadow mode because the async connection was not available",
+	)
+
+	RequestDeadlinesExceeded = NewMetric(
+		"request_deadlines_exceeded_total",
+		"Running total of requests whose client deadline elapsed before every cluster responded",
+	)
+
+	ReadResponseRowsOrigin = NewMetricWithLabels(
+		readResponseRowsName,
+		readResponseRowsDescription,
+		map[string]string{
+			readResponseClusterLabel: failedRequestsClusterOrigin,
+		},
+	)
+	ReadResponseRowsTarget = NewMetricWithLabels(
+		readResponseRowsName,
+		readResponseRowsDescription,
+		map[string]string{
+			readResponseClusterLabel: failedRequestsClusterTarget,
+		},
+	)
+	ReadResponseRowsAsync = NewMetricWithLabels(
+		readResponseRowsName,
+		readResponseRowsDescription,
+		map[string]string{
+			readResponseClusterLabel: readResponseClusterAsync,
+		},
+	)
+	ReadResponseBytesOrigin = NewMetricWithLabels(
+		readResponseBytesName,
+		readResponseBytesDescription,
+		map[string]string{
+			readResponseClusterLabel: failedRequestsClusterOrigin,
+		},
+	)
+	ReadResponseBytesTarget = NewMetricWithLabels(
+		readResponseBytesName,
+		readResponseBytesDescription,
+		map[string]string{
+			readResponseClusterLabel: failedRequestsClusterTarget,
+		},
+	)
+	ReadResponseBytesAsync = NewMetricWithLabels(
+		readResponseBytesName,
+		readResponseBytesDescription,
+		map[string]string{
+			readResponseClusterLabel: readResponseClusterAsync,
+		},
+	)
 )
 
 type ProxyMetrics struct {
@@ -129,16 +451,83 @@ type ProxyMetrics struct {
 	FailedWritesOnTarget Counter
 	FailedWritesOnBoth   Counter
 
-	PSCacheSize      GaugeFunc
-	PSCacheMissCount Counter
+	PSCacheSize                  GaugeFunc
+	PSCacheMissCount             Counter
+	PSCacheHitCount              Counter
+	PSCacheEvictionCount         Counter
+	PSCacheRePrepareOrigin       Counter
+	PSCacheRePrepareTarget       Counter
+	PSCacheRePrepareFailedOrigin Counter
+	PSCacheRePrepareFailedTarget Counter
+
+	RetriesOrigin Counter
+	RetriesTarget Counter
+
+	RequestConnectionFailoversOrigin       Counter
+	RequestConnectionFailoversTarget       Counter
+	RequestConnectionFailoversFailedOrigin Counter
+	RequestConnectionFailoversFailedTarget Counter
+
+	DatacenterFailoverActiveOrigin GaugeFunc
+	DatacenterFailoverActiveTarget GaugeFunc
+
+	BufferPoolHits   Counter
+	BufferPoolMisses Counter
+
+	OversizedRequestFrames  Counter
+	OversizedResponseFrames Counter
+
+	DnsResolutionFailuresOrigin Counter
+	DnsResolutionFailuresTarget Counter
+
+	ConsistencyLevelOverridesOrigin Counter
+	ConsistencyLevelOverridesTarget Counter
+
+	ResponseWarningsOrigin Counter
+	ResponseWarningsTarget Counter
+
+	SpeculativeReadWins   Counter
+	SpeculativeReadLosses Counter
 
 	ProxyReadsOriginDuration Histogram
 	ProxyReadsTargetDuration Histogram
 	ProxyWritesDuration      Histogram
 
+	ReadResponseRowsOrigin  Histogram
+	ReadResponseRowsTarget  Histogram
+	ReadResponseRowsAsync   Histogram
+	ReadResponseBytesOrigin Histogram
+	ReadResponseBytesTarget Histogram
+	ReadResponseBytesAsync  Histogram
+
 	InFlightReadsOrigin Gauge
 	InFlightReadsTarget Gauge
 	InFlightWrites      Gauge
 
-	OpenClientConnections GaugeFunc
+	OpenClientConnections       GaugeFunc
+	ClientBytesReceived         Counter
+	ClientBytesSent             Counter
+	IdleClientConnectionsClosed Counter
+
+	LwtRequestCount         Counter
+	LwtAppliedMismatchCount Counter
+
+	CounterWriteCount Counter
+
+	TargetCircuitBreakerSkippedWrites Counter
+	TargetCircuitBreakerOpen          GaugeFunc
+
+	ShadowWrites        Counter
+	ShadowWritesSkipped Counter
+
+	RequestDeadlinesExceeded Counter
+
+	// TableRequests is nil unless per-table request metrics are enabled.
+	TableRequests *TableMetrics
+
+	// Applications is nil unless per-application metrics are enabled.
+	Applications *ApplicationMetrics
+
+	// Interceptors is nil unless request interceptors are registered.
+	Interceptors *InterceptorMetrics
 }
@@ -0,0 +1,24 @@
+package metrics
+
+const SchemaDriftKindLabel = "kind"
+
+// The metrics of the schema drift detector are only created if ZDM_SCHEMA_DRIFT_CHECK_INTERVAL_MS is greater than 0,
+// the kind label is set with WithLabels.
+var (
+	SchemaDriftDifferences = NewMetric(
+		"schema_drift_differences",
+		"Number of differences between the schemas of ORIGIN and TARGET found by the last schema comparison",
+	)
+	SchemaDriftChecks = NewMetric(
+		"schema_drift_checks_total",
+		"Running total of schema comparisons between ORIGIN and TARGET",
+	)
+	SchemaDriftCheckErrors = NewMetric(
+		"schema_drift_check_errors_total",
+		"Running total of schema comparisons that failed because the schema of a cluster could not be fetched",
+	)
+	SchemaDriftLastCheck = NewMetric(
+		"schema_drift_last_check_timestamp_seconds",
+		"Time at which the schemas of ORIGIN and TARGET were last compared successfully",
+	)
+)
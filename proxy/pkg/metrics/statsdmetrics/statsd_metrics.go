@@ -0,0 +1,96 @@
+package statsdmetrics
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxHistogramSamples is the maximum number of timings that a histogram keeps between two flushes, the timings that
+// are sent afterwards are sampled and the sample rate is reported to the StatsD server.
+const maxHistogramSamples = 1000
+
+type StatsdCounter struct {
+	value int64
+}
+
+func (recv *StatsdCounter) Add(valueToAdd int) {
+	atomic.AddInt64(&recv.value, int64(valueToAdd))
+}
+
+// flush returns the increment since the previous flush.
+func (recv *StatsdCounter) flush() int64 {
+	return atomic.SwapInt64(&recv.value, 0)
+}
+
+type StatsdGauge struct {
+	value int64
+}
+
+func (recv *StatsdGauge) Add(valueToAdd int) {
+	atomic.AddInt64(&recv.value, int64(valueToAdd))
+}
+
+func (recv *StatsdGauge) Subtract(valueToSubtract int) {
+	atomic.AddInt64(&recv.value, -int64(valueToSubtract))
+}
+
+func (recv *StatsdGauge) get() int64 {
+	return atomic.LoadInt64(&recv.value)
+}
+
+type StatsdGaugeFunc struct {
+	mf func() float64
+}
+
+type StatsdHistogram struct {
+	lock    *sync.Mutex
+	samples []float64
+	count   int
+
+	// metricType is "ms" for timings and "h" for the histograms of values that are not durations, a histogram is
+	// expected to only be used for one of them.
+	metricType string
+}
+
+func newStatsdHistogram() *StatsdHistogram {
+	return &StatsdHistogram{
+		lock:       &sync.Mutex{},
+		samples:    make([]float64, 0),
+		metricType: "ms",
+	}
+}
+
+func (recv *StatsdHistogram) Track(begin time.Time) {
+	elapsedTimeInMs := float64(time.Since(begin)) / float64(time.Millisecond)
+	recv.observe(elapsedTimeInMs, "ms")
+}
+
+func (recv *StatsdHistogram) Observe(value float64) {
+	recv.observe(value, "h")
+}
+
+func (recv *StatsdHistogram) observe(value float64, metricType string) {
+	recv.lock.Lock()
+	recv.metricType = metricType
+	recv.count++
+	if len(recv.samples) < maxHistogramSamples {
+		recv.samples = append(recv.samples, value)
+	}
+	recv.lock.Unlock()
+}
+
+// flush returns the samples tracked since the previous flush, the rate at which they were sampled and the StatsD type
+// of the samples.
+func (recv *StatsdHistogram) flush() ([]float64, float64, string) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	samples := recv.samples
+	count := recv.count
+	recv.samples = make([]float64, 0, len(samples))
+	recv.count = 0
+	if count == 0 {
+		return nil, 1, recv.metricType
+	}
+	return samples, float64(len(samples)) / float64(count), recv.metricType
+}
@@ -0,0 +1,290 @@
+package statsdmetrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxPacketSize keeps the datagrams below the usual MTU of 1500 bytes minus the IP and UDP headers.
+const maxPacketSize = 1432
+
+// StatsdMetricFactory is a metrics.MetricFactory that aggregates the metrics in memory and sends them to a StatsD
+// (or DogStatsD) server over UDP every flush interval.
+//
+// Counters are sent as the increment since the previous flush, gauges with their current value and histograms
+// as the timings (in milliseconds) that were tracked since the previous flush.
+type StatsdMetricFactory struct {
+	config *common.StatsdConfig
+	writer io.WriteCloser
+
+	lock       *sync.Mutex
+	counters   map[string]*statsdEntry
+	gauges     map[string]*statsdEntry
+	gaugeFuncs map[string]*statsdEntry
+	histograms map[string]*statsdEntry
+
+	cancelFn context.CancelFunc
+	wg       *sync.WaitGroup
+
+	logger *log.Entry
+}
+
+type statsdEntry struct {
+	name   string
+	tags   string
+	metric interface{}
+}
+
+func NewStatsdMetricFactory(config *common.StatsdConfig, logger *log.Entry) (*StatsdMetricFactory, error) {
+	conn, err := net.Dial("udp", config.Address)
+	if err != nil {
+		return nil, fmt.Errorf("could not create statsd client for %v: %w", config.Address, err)
+	}
+	factory := newStatsdMetricFactory(config, conn, logger)
+	factory.start()
+	return factory, nil
+}
+
+func newStatsdMetricFactory(config *common.StatsdConfig, writer io.WriteCloser, logger *log.Entry) *StatsdMetricFactory {
+	return &StatsdMetricFactory{
+		config:     config,
+		writer:     writer,
+		lock:       &sync.Mutex{},
+		counters:   make(map[string]*statsdEntry),
+		gauges:     make(map[string]*statsdEntry),
+		gaugeFuncs: make(map[string]*statsdEntry),
+		histograms: make(map[string]*statsdEntry),
+		cancelFn:   func() {},
+		wg:         &sync.WaitGroup{},
+		logger:     logger,
+	}
+}
+
+func (sm *StatsdMetricFactory) start() {
+	ctx, cancelFn := context.WithCancel(context.Background())
+	sm.cancelFn = cancelFn
+	sm.wg.Add(1)
+	go func() {
+		defer sm.wg.Done()
+		ticker := time.NewTicker(sm.config.FlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				sm.flush()
+			}
+		}
+	}()
+}
+
+func (sm *StatsdMetricFactory) GetOrCreateCounter(mn metrics.Metric) (metrics.Counter, error) {
+	entry := sm.getOrCreate(sm.counters, mn, func() interface{} { return &StatsdCounter{} })
+	counter, ok := entry.metric.(*StatsdCounter)
+	if !ok {
+		return nil, fmt.Errorf("metric %v is not a counter", mn)
+	}
+	return counter, nil
+}
+
+func (sm *StatsdMetricFactory) GetOrCreateGauge(mn metrics.Metric) (metrics.Gauge, error) {
+	entry := sm.getOrCreate(sm.gauges, mn, func() interface{} { return &StatsdGauge{} })
+	gauge, ok := entry.metric.(*StatsdGauge)
+	if !ok {
+		return nil, fmt.Errorf("metric %v is not a gauge", mn)
+	}
+	return gauge, nil
+}
+
+func (sm *StatsdMetricFactory) GetOrCreateGaugeFunc(mn metrics.Metric, mf func() float64) (metrics.GaugeFunc, error) {
+	entry := sm.getOrCreate(sm.gaugeFuncs, mn, func() interface{} { return &StatsdGaugeFunc{mf: mf} })
+	gaugeFunc, ok := entry.metric.(*StatsdGaugeFunc)
+	if !ok {
+		return nil, fmt.Errorf("metric %v is not a gauge function", mn)
+	}
+	return gaugeFunc, nil
+}
+
+func (sm *StatsdMetricFactory) GetOrCreateHistogram(mn metrics.Metric, buckets []float64) (metrics.Histogram, error) {
+	entry := sm.getOrCreate(sm.histograms, mn, func() interface{} { return newStatsdHistogram() })
+	histogram, ok := entry.metric.(*StatsdHistogram)
+	if !ok {
+		return nil, fmt.Errorf("metric %v is not a histogram", mn)
+	}
+	return histogram, nil
+}
+
+// UnregisterAllMetrics sends the pending values, stops the periodic flush and closes the UDP socket.
+func (sm *StatsdMetricFactory) UnregisterAllMetrics() error {
+	sm.cancelFn()
+	sm.wg.Wait()
+	sm.flush()
+
+	sm.lock.Lock()
+	sm.counters = make(map[string]*statsdEntry)
+	sm.gauges = make(map[string]*statsdEntry)
+	sm.gaugeFuncs = make(map[string]*statsdEntry)
+	sm.histograms = make(map[string]*statsdEntry)
+	sm.lock.Unlock()
+
+	return sm.writer.Close()
+}
+
+func (sm *StatsdMetricFactory) HttpHandler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		http.Error(writer, "Metrics are sent to a StatsD server by this proxy instance.", http.StatusNotFound)
+	})
+}
+
+func (sm *StatsdMetricFactory) getOrCreate(
+	entries map[string]*statsdEntry, mn metrics.Metric, newMetric func() interface{}) *statsdEntry {
+	sm.lock.Lock()
+	defer sm.lock.Unlock()
+	key := mn.String()
+	if entry, ok := entries[key]; ok {
+		return entry
+	}
+	entry := &statsdEntry{
+		name:   sm.metricName(mn),
+		tags:   sm.metricTags(mn),
+		metric: newMetric(),
+	}
+	entries[key] = entry
+	return entry
+}
+
+// metricName returns <prefix>.<name>, followed by the sorted label values with the plain StatsD flavor.
+func (sm *StatsdMetricFactory) metricName(mn metrics.Metric) string {
+	parts := make([]string, 0)
+	if sm.config.Prefix != "" {
+		parts = append(parts, sm.config.Prefix)
+	}
+	parts = append(parts, mn.GetName())
+	if !sm.config.DogStatsd {
+		for _, labelName := range sortedLabelNames(mn) {
+			parts = append(parts, sanitize(mn.GetLabels()[labelName]))
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// metricTags returns the DogStatsD tags section of the metric lines, including the leading |#.
+func (sm *StatsdMetricFactory) metricTags(mn metrics.Metric) string {
+	if !sm.config.DogStatsd {
+		return ""
+	}
+	tags := make([]string, 0, len(sm.config.Tags)+len(mn.GetLabels()))
+	tags = append(tags, sm.config.Tags...)
+	for _, labelName := range sortedLabelNames(mn) {
+		tags = append(tags, labelName+":"+sanitizeTagValue(mn.GetLabels()[labelName]))
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+	return "|#" + strings.Join(tags, ",")
+}
+
+func (sm *StatsdMetricFactory) flush() {
+	sm.lock.Lock()
+	lines := make([]string, 0, len(sm.counters)+len(sm.gauges)+len(sm.gaugeFuncs)+len(sm.histograms))
+	for _, entry := range sm.counters {
+		if delta := entry.metric.(*StatsdCounter).flush(); delta != 0 {
+			lines = append(lines, fmt.Sprintf("%v:%d|c%v", entry.name, delta, entry.tags))
+		}
+	}
+	for _, entry := range sm.gauges {
+		lines = append(lines, fmt.Sprintf("%v:%d|g%v", entry.name, entry.metric.(*StatsdGauge).get(), entry.tags))
+	}
+	gaugeFuncs := make([]*statsdEntry, 0, len(sm.gaugeFuncs))
+	for _, entry := range sm.gaugeFuncs {
+		gaugeFuncs = append(gaugeFuncs, entry)
+	}
+	for _, entry := range sm.histograms {
+		samples, sampleRate, metricType := entry.metric.(*StatsdHistogram).flush()
+		rate := ""
+		if sampleRate < 1 {
+			rate = "|@" + strconv.FormatFloat(sampleRate, 'f', 4, 64)
+		}
+		for _, sample := range samples {
+			lines = append(lines, fmt.Sprintf("%v:%v|%v%v%v",
+				entry.name, strconv.FormatFloat(sample, 'f', 3, 64), metricType, rate, entry.tags))
+		}
+	}
+	sm.lock.Unlock()
+
+	// gauge functions can take locks of other components so they are evaluated without holding the factory lock
+	for _, entry := range gaugeFuncs {
+		value := entry.metric.(*StatsdGaugeFunc).mf()
+		lines = append(lines, fmt.Sprintf("%v:%v|g%v", entry.name, strconv.FormatFloat(value, 'f', -1, 64), entry.tags))
+	}
+
+	if err := sm.send(lines); err != nil {
+		sm.logger.Debugf("Could not send metrics to the statsd server %v: %v", sm.config.Address, err)
+	}
+}
+
+// send writes the metric lines in as few datagrams as possible.
+func (sm *StatsdMetricFactory) send(lines []string) error {
+	var buf bytes.Buffer
+	for _, line := range lines {
+		if buf.Len() > 0 && buf.Len()+1+len(line) > maxPacketSize {
+			if _, err := sm.writer.Write(buf.Bytes()); err != nil {
+				return err
+			}
+			buf.Reset()
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(line)
+	}
+	if buf.Len() > 0 {
+		if _, err := sm.writer.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortedLabelNames(mn metrics.Metric) []string {
+	labelNames := make([]string, 0, len(mn.GetLabels()))
+	for labelName := range mn.GetLabels() {
+		labelNames = append(labelNames, labelName)
+	}
+	sort.Strings(labelNames)
+	return labelNames
+}
+
+// sanitize replaces the characters that have a meaning in the StatsD line protocol or in metric paths.
+func sanitize(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '#', ',', '.', ' ', '\n':
+			return '_'
+		}
+		return r
+	}, value)
+}
+
+func sanitizeTagValue(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '|', '@', '#', ',', '\n':
+			return '_'
+		}
+		return r
+	}, value)
+}
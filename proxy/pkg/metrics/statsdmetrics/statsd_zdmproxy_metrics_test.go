@@ -0,0 +1,128 @@
+package statsdmetrics
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeWriter struct {
+	packets []string
+	closed  bool
+}
+
+func (recv *fakeWriter) Write(p []byte) (int, error) {
+	recv.packets = append(recv.packets, string(p))
+	return len(p), nil
+}
+
+func (recv *fakeWriter) Close() error {
+	recv.closed = true
+	return nil
+}
+
+func (recv *fakeWriter) lines() []string {
+	lines := make([]string, 0)
+	for _, packet := range recv.packets {
+		lines = append(lines, strings.Split(packet, "\n")...)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestStatsdMetricFactory_DogStatsd(t *testing.T) {
+	writer := &fakeWriter{}
+	factory := newStatsdMetricFactory(&common.StatsdConfig{
+		Prefix:    "zdm",
+		Tags:      []string{"env:test"},
+		DogStatsd: true,
+	}, writer, log.NewEntry(log.StandardLogger()))
+
+	counter, err := factory.GetOrCreateCounter(
+		metrics.NewMetricWithLabels("failed_writes", "", map[string]string{"failed_on": "target"}))
+	require.Nil(t, err)
+	sameCounter, err := factory.GetOrCreateCounter(
+		metrics.NewMetricWithLabels("failed_writes", "", map[string]string{"failed_on": "target"}))
+	require.Nil(t, err)
+	require.Same(t, counter, sameCounter)
+	gauge, err := factory.GetOrCreateGauge(metrics.NewMetric("connections", ""))
+	require.Nil(t, err)
+	_, err = factory.GetOrCreateGaugeFunc(metrics.NewMetric("cache_size", ""), func() float64 { return 12 })
+	require.Nil(t, err)
+	histogram, err := factory.GetOrCreateHistogram(metrics.NewMetric("latency", ""), nil)
+	require.Nil(t, err)
+
+	counter.Add(2)
+	counter.Add(3)
+	gauge.Add(5)
+	gauge.Subtract(1)
+	histogram.Track(time.Now())
+
+	factory.flush()
+	lines := writer.lines()
+	require.Len(t, lines, 4)
+	require.Equal(t, "zdm.cache_size:12|g|#env:test", lines[0])
+	require.Equal(t, "zdm.connections:4|g|#env:test", lines[1])
+	require.Equal(t, "zdm.failed_writes:5|c|#env:test,failed_on:target", lines[2])
+	require.True(t, strings.HasPrefix(lines[3], "zdm.latency:"), lines[3])
+	require.True(t, strings.HasSuffix(lines[3], "|ms|#env:test"), lines[3])
+
+	// counters only send the increment since the previous flush
+	writer.packets = nil
+	factory.flush()
+	require.Equal(t, []string{"zdm.cache_size:12|g|#env:test", "zdm.connections:4|g|#env:test"}, writer.lines())
+
+	require.Nil(t, factory.UnregisterAllMetrics())
+	require.True(t, writer.closed)
+}
+
+func TestStatsdMetricFactory_PlainStatsd(t *testing.T) {
+	writer := &fakeWriter{}
+	factory := newStatsdMetricFactory(&common.StatsdConfig{Prefix: "zdm"}, writer, log.NewEntry(log.StandardLogger()))
+
+	counter, err := factory.GetOrCreateCounter(
+		metrics.NewMetricWithLabels("requests", "", map[string]string{"node": "10.0.0.1:9042", "error": "read_timeout"}))
+	require.Nil(t, err)
+	counter.Add(1)
+
+	factory.flush()
+	require.Equal(t, []string{"zdm.requests.read_timeout.10_0_0_1_9042:1|c"}, writer.lines())
+}
+
+func TestStatsdMetricFactory_ObservedValues(t *testing.T) {
+	writer := &fakeWriter{}
+	factory := newStatsdMetricFactory(&common.StatsdConfig{Prefix: "zdm"}, writer, log.NewEntry(log.StandardLogger()))
+
+	histogram, err := factory.GetOrCreateHistogram(metrics.NewMetric("response_rows", ""), nil)
+	require.Nil(t, err)
+	histogram.Observe(10)
+	histogram.Observe(2.5)
+
+	factory.flush()
+	require.Equal(t, []string{"zdm.response_rows:10.000|h", "zdm.response_rows:2.500|h"}, writer.lines())
+}
+
+func TestStatsdMetricFactory_PacketSize(t *testing.T) {
+	writer := &fakeWriter{}
+	factory := newStatsdMetricFactory(&common.StatsdConfig{Prefix: "zdm"}, writer, log.NewEntry(log.StandardLogger()))
+
+	histogram, err := factory.GetOrCreateHistogram(metrics.NewMetric("latency", ""), nil)
+	require.Nil(t, err)
+	for i := 0; i < maxHistogramSamples*2; i++ {
+		histogram.Track(time.Now())
+	}
+
+	factory.flush()
+	require.Greater(t, len(writer.packets), 1)
+	for _, packet := range writer.packets {
+		require.LessOrEqual(t, len(packet), maxPacketSize)
+	}
+	lines := writer.lines()
+	require.Len(t, lines, maxHistogramSamples)
+	require.True(t, strings.HasSuffix(lines[0], "|ms|@0.5000"), lines[0])
+}
@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	tableRequestsName          = "proxy_table_requests_total"
+	tableRequestsDescription   = "Running total of requests received by the proxy per keyspace and table"
+	tableRequestsKeyspaceLabel = "keyspace"
+	tableRequestsTableLabel    = "table"
+	tableRequestsTypeLabel     = "type"
+
+	typeReads = "reads"
+
+	// OtherTablesLabelValue is used as keyspace and table label of the requests to tables that are not tracked
+	// individually because they are not in the allow list or the maximum number of tables was reached.
+	OtherTablesLabelValue = "_other"
+)
+
+var TableRequests = NewMetric(tableRequestsName, tableRequestsDescription)
+
+type tableRequestsKey struct {
+	keyspace string
+	table    string
+	write    bool
+}
+
+// TableMetrics creates request counters labeled by keyspace and table when a table receives its first request.
+//
+// The number of label combinations is bounded: if an allow list is provided only those tables are tracked
+// individually, otherwise the first maxTables tables that receive requests are. Requests to any other table are
+// counted under OtherTablesLabelValue.
+type TableMetrics struct {
+	metricFactory MetricFactory
+	allowList     map[string]bool
+	maxTables     int
+
+	lock     *sync.RWMutex
+	counters map[tableRequestsKey]Counter
+	tables   map[string]bool
+}
+
+// NewTableMetrics creates a TableMetrics instance. Entries of allowList have the format <keyspace>.<table>.
+func NewTableMetrics(metricFactory MetricFactory, allowList []string, maxTables int) *TableMetrics {
+	var allowListMap map[string]bool
+	if len(allowList) > 0 {
+		allowListMap = make(map[string]bool, len(allowList))
+		for _, qualifiedTable := range allowList {
+			allowListMap[qualifiedTable] = true
+		}
+	}
+	return &TableMetrics{
+		metricFactory: metricFactory,
+		allowList:     allowListMap,
+		maxTables:     maxTables,
+		lock:          &sync.RWMutex{},
+		counters:      make(map[tableRequestsKey]Counter),
+		tables:        make(map[string]bool),
+	}
+}
+
+// TrackRequest increments the read or write counter of the given table.
+func (recv *TableMetrics) TrackRequest(keyspace string, table string, write bool) error {
+	counter, err := recv.getOrCreateCounter(tableRequestsKey{keyspace: keyspace, table: table, write: write})
+	if err != nil {
+		return err
+	}
+	counter.Add(1)
+	return nil
+}
+
+// getOrCreateCounter only stores the counters of the tracked tables and the shared counters of the other tables, the
+// keys of the tables that are not tracked are not stored so that the map doesn't grow with every table that receives
+// requests.
+func (recv *TableMetrics) getOrCreateCounter(key tableRequestsKey) (Counter, error) {
+	qualifiedTable := key.keyspace + "." + key.table
+
+	recv.lock.RLock()
+	counter, ok := recv.counters[key]
+	if !ok && !recv.isTracked(qualifiedTable) {
+		key = otherTablesKey(key.write)
+		counter, ok = recv.counters[key]
+	}
+	recv.lock.RUnlock()
+	if ok {
+		return counter, nil
+	}
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	counter, ok = recv.counters[key]
+	if !ok && key != otherTablesKey(key.write) && !recv.isTracked(qualifiedTable) {
+		key = otherTablesKey(key.write)
+		counter, ok = recv.counters[key]
+	}
+	if ok {
+		return counter, nil
+	}
+
+	requestType := typeReads
+	if key.write {
+		requestType = typeWrites
+	}
+	counter, err := recv.metricFactory.GetOrCreateCounter(TableRequests.WithLabels(map[string]string{
+		tableRequestsKeyspaceLabel: key.keyspace,
+		tableRequestsTableLabel:    key.table,
+		tableRequestsTypeLabel:     requestType,
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create table metrics for %v: %w", qualifiedTable, err)
+	}
+
+	if key != otherTablesKey(key.write) {
+		recv.tables[qualifiedTable] = true
+	}
+	recv.counters[key] = counter
+	return counter, nil
+}
+
+func otherTablesKey(write bool) tableRequestsKey {
+	return tableRequestsKey{keyspace: OtherTablesLabelValue, table: OtherTablesLabelValue, write: write}
+}
+
+// isTracked must be called with the lock held.
+func (recv *TableMetrics) isTracked(qualifiedTable string) bool {
+	if recv.allowList != nil {
+		return recv.allowList[qualifiedTable] || recv.allowList[strings.ToLower(qualifiedTable)]
+	}
+	return recv.tables[qualifiedTable] || len(recv.tables) < recv.maxTables
+}
@@ -0,0 +1,67 @@
+package metrics_test
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics/prommetrics"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTableMetrics_MaxTables(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	tableMetrics := metrics.NewTableMetrics(prommetrics.NewPrometheusMetricFactory(registry, log.NewEntry(log.StandardLogger())), nil, 2)
+
+	require.Nil(t, tableMetrics.TrackRequest("ks1", "t1", false))
+	require.Nil(t, tableMetrics.TrackRequest("ks1", "t1", true))
+	require.Nil(t, tableMetrics.TrackRequest("ks1", "t2", true))
+	require.Nil(t, tableMetrics.TrackRequest("ks1", "t3", true))
+	require.Nil(t, tableMetrics.TrackRequest("ks2", "t1", true))
+	require.Nil(t, tableMetrics.TrackRequest("ks1", "t1", true))
+
+	require.Equal(t, map[string]float64{
+		"ks1.t1.reads":         1,
+		"ks1.t1.writes":        2,
+		"ks1.t2.writes":        1,
+		"_other._other.writes": 2,
+	}, gatherTableRequests(t, registry))
+}
+
+func TestTableMetrics_AllowList(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	tableMetrics := metrics.NewTableMetrics(
+		prommetrics.NewPrometheusMetricFactory(registry, log.NewEntry(log.StandardLogger())), []string{"ks1.t2", "ks2.t1"}, 100)
+
+	require.Nil(t, tableMetrics.TrackRequest("ks1", "t1", false))
+	require.Nil(t, tableMetrics.TrackRequest("ks1", "t2", false))
+	require.Nil(t, tableMetrics.TrackRequest("ks1", "t2", true))
+	require.Nil(t, tableMetrics.TrackRequest("ks2", "t1", true))
+	require.Nil(t, tableMetrics.TrackRequest("ks2", "t2", false))
+
+	require.Equal(t, map[string]float64{
+		"ks1.t2.reads":        1,
+		"ks1.t2.writes":       1,
+		"ks2.t1.writes":       1,
+		"_other._other.reads": 2,
+	}, gatherTableRequests(t, registry))
+}
+
+func gatherTableRequests(t *testing.T, registry *prometheus.Registry) map[string]float64 {
+	families, err := registry.Gather()
+	require.Nil(t, err)
+	values := make(map[string]float64)
+	for _, family := range families {
+		if family.GetName() != "zdm_proxy_table_requests_total" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range m.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			values[labels["keyspace"]+"."+labels["table"]+"."+labels["type"]] = m.GetCounter().GetValue()
+		}
+	}
+	return values
+}
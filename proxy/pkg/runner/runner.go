@@ -3,8 +3,9 @@ package runner
 import (
 	"context"
 	"errors"
-	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/admin"
 	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/embedded"
 	"github.com/datastax/zdm-proxy/proxy/pkg/health"
 	"github.com/datastax/zdm-proxy/proxy/pkg/httpzdmproxy"
 	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
@@ -16,6 +17,16 @@ import (
 	"time"
 )
 
+// the admin handlers are not returned by SetupHandlers because only RunMain needs them, they are served on the admin
+// listener which is only started if ZDM_ADMIN_ENDPOINT_ENABLED is true.
+var (
+	targetCircuitBreakerHandler = httpzdmproxy.NewHandlerWithFallback(admin.DefaultTargetCircuitBreakerHandler())
+	migrationPhaseHandler       = httpzdmproxy.NewHandlerWithFallback(admin.DefaultMigrationPhaseHandler())
+	fleetStateHandler           = httpzdmproxy.NewHandlerWithFallback(admin.DefaultFleetStateHandler())
+	schemaDriftHandler          = httpzdmproxy.NewHandlerWithFallback(admin.DefaultSchemaDriftHandler())
+	debugHandler                = httpzdmproxy.NewHandlerWithFallback(admin.DefaultDebugHandler())
+)
+
 func SetupHandlers() (metricsHandler *httpzdmproxy.HandlerWithFallback, readinessHandler *httpzdmproxy.HandlerWithFallback) {
 	metricsHandler = httpzdmproxy.NewHandlerWithFallback(metrics.DefaultHttpHandler())
 	readinessHandler = httpzdmproxy.NewHandlerWithFallback(health.DefaultReadinessHandler())
@@ -26,6 +37,16 @@ func SetupHandlers() (metricsHandler *httpzdmproxy.HandlerWithFallback, readines
 	return metricsHandler, readinessHandler
 }
 
+func newAdminServeMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/admin/target-circuit-breaker", targetCircuitBreakerHandler.Handler())
+	mux.Handle("/admin/migration-phase", migrationPhaseHandler.Handler())
+	mux.Handle("/admin/fleet-state", fleetStateHandler.Handler())
+	mux.Handle("/admin/schema-drift", schemaDriftHandler.Handler())
+	mux.Handle("/debug/", debugHandler.Handler())
+	return mux
+}
+
 func RunMain(
 	conf *config.Config,
 	ctx context.Context,
@@ -34,7 +55,20 @@ func RunMain(
 
 	log.Infof("Starting http server (metrics and health checks) on %v:%d", conf.MetricsAddress, conf.MetricsPort)
 	wg := &sync.WaitGroup{}
-	srv := httpzdmproxy.StartHttpServer(fmt.Sprintf("%s:%d", conf.MetricsAddress, conf.MetricsPort), wg)
+	srv := httpzdmproxy.StartHttpServer(zdmproxy.JoinHostPort(conf.MetricsAddress, conf.MetricsPort), wg)
+
+	var adminSrv *http.Server
+	if conf.AdminEndpointEnabled {
+		log.Warnf("Admin endpoints enabled on %v:%d/admin/, they have no authentication", conf.AdminAddress, conf.AdminPort)
+		adminSrv = httpzdmproxy.StartAdminHttpServer(
+			zdmproxy.JoinHostPort(conf.AdminAddress, conf.AdminPort), newAdminServeMux(), wg)
+	}
+
+	// the profiles are available while the proxy is starting up, e.g. if it is stuck connecting to the clusters
+	if conf.DebugEndpointEnabled {
+		log.Warnf("Debug endpoint enabled on %v:%d/debug/", conf.AdminAddress, conf.AdminPort)
+		debugHandler.SetHandler(admin.DebugHandler(conf, nil))
+	}
 
 	b := &backoff.Backoff{
 		Min:    100 * time.Millisecond,
@@ -43,27 +77,51 @@ func RunMain(
 		Jitter: true,
 	}
 
-	zdmProxy, err := zdmproxy.RunWithRetries(conf, ctx, b)
+	proxy, err := embedded.NewProxy(embedded.WithConfig(conf), embedded.WithStartupBackoff(b))
+	if err == nil {
+		err = proxy.Start(ctx)
+	}
 
 	if err == nil {
+		zdmProxy := proxy.ZdmProxy()
 		metricsHandler.SetHandler(zdmProxy.GetMetricHandler().GetHttpHandler())
 		readinessHandler.SetHandler(health.ReadinessHandler(zdmProxy))
+		targetCircuitBreakerHandler.SetHandler(admin.TargetCircuitBreakerHandler(zdmProxy))
+		migrationPhaseHandler.SetHandler(admin.MigrationPhaseHandler(zdmProxy))
+		fleetStateHandler.SetHandler(admin.FleetStateHandler(zdmProxy))
+		schemaDriftHandler.SetHandler(admin.SchemaDriftHandler(zdmProxy))
+		if conf.DebugEndpointEnabled {
+			debugHandler.SetHandler(admin.DebugHandler(conf, zdmProxy))
+		}
 
 		log.Info("Proxy started. Waiting for SIGINT/SIGTERM to shutdown.")
 		<-ctx.Done()
 
-		zdmProxy.Shutdown()
+		if err := proxy.Shutdown(context.Background()); err != nil {
+			log.Errorf("Failed to gracefully shutdown proxy: %v", err)
+		}
 		metricsHandler.ClearHandler()
 		readinessHandler.ClearHandler()
+		targetCircuitBreakerHandler.ClearHandler()
+		migrationPhaseHandler.ClearHandler()
+		fleetStateHandler.ClearHandler()
+		schemaDriftHandler.ClearHandler()
 	} else if !errors.Is(err, zdmproxy.ShutdownErr) {
 		log.Errorf("Error launching proxy: %v", err)
 	}
 
+	debugHandler.ClearHandler()
+
 	log.Info("Shutting down httpzdmproxy server, waiting up to 5 seconds.")
 	srvShutdownCtx, _ := context.WithTimeout(context.Background(), 5*time.Second)
 	if err := srv.Shutdown(srvShutdownCtx); err != nil {
 		log.Errorf("Failed to gracefully shutdown httpzdmproxy server: %v", err)
 	}
+	if adminSrv != nil {
+		if err := adminSrv.Shutdown(srvShutdownCtx); err != nil {
+			log.Errorf("Failed to gracefully shutdown admin http server: %v", err)
+		}
+	}
 
 	wg.Wait()
 	log.Info("Http server shutdown.")
@@ -0,0 +1,172 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const awsSecretsManagerService = "secretsmanager"
+
+// AwsSecretsManagerProvider reads secrets with the GetSecretValue action of AWS Secrets Manager. The location is
+// the secret name or ARN. Requests are signed with AWS Signature Version 4 using static credentials.
+type AwsSecretsManagerProvider struct {
+	region          string
+	accessKeyId     string
+	secretAccessKey string
+	sessionToken    string
+	endpoint        string
+	httpClient      *http.Client
+	now             func() time.Time
+}
+
+func NewAwsSecretsManagerProvider(
+	region string, accessKeyId string, secretAccessKey string, sessionToken string, endpoint string,
+	httpClient *http.Client) *AwsSecretsManagerProvider {
+	if endpoint == "" && region != "" {
+		endpoint = fmt.Sprintf("https://%v.%v.amazonaws.com", awsSecretsManagerService, region)
+	}
+	return &AwsSecretsManagerProvider{
+		region:          region,
+		accessKeyId:     accessKeyId,
+		secretAccessKey: secretAccessKey,
+		sessionToken:    sessionToken,
+		endpoint:        strings.TrimSuffix(endpoint, "/"),
+		httpClient:      httpClient,
+		now:             time.Now,
+	}
+}
+
+// NewAwsSecretsManagerProviderFromEnv uses AWS_REGION (or AWS_DEFAULT_REGION), AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+func NewAwsSecretsManagerProviderFromEnv(httpClient *http.Client) *AwsSecretsManagerProvider {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	return NewAwsSecretsManagerProvider(
+		region, os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("AWS_SESSION_TOKEN"),
+		"", httpClient)
+}
+
+func (recv *AwsSecretsManagerProvider) Fetch(ctx context.Context, location string) ([]byte, error) {
+	if recv.region == "" {
+		return nil, fmt.Errorf("AWS_REGION is not set")
+	}
+	if recv.accessKeyId == "" || recv.secretAccessKey == "" {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+
+	payload, err := json.Marshal(map[string]string{"SecretId": location})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, recv.endpoint+"/", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	recv.sign(req, payload)
+
+	resp, err := recv.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("aws secrets manager request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read aws secrets manager response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("aws secrets manager returned status %v for %v: %v", resp.StatusCode, location, string(body))
+	}
+
+	var parsed struct {
+		SecretString *string `json:"SecretString"`
+		SecretBinary *string `json:"SecretBinary"`
+	}
+	if err = json.Unmarshal(body, &parsed); err != nil {
+		return nil, fmt.Errorf("could not parse aws secrets manager response: %w", err)
+	}
+	if parsed.SecretString != nil {
+		return []byte(*parsed.SecretString), nil
+	}
+	if parsed.SecretBinary != nil {
+		return base64.StdEncoding.DecodeString(*parsed.SecretBinary)
+	}
+	return nil, fmt.Errorf("aws secrets manager response for %v does not contain a secret value", location)
+}
+
+// sign adds the AWS Signature Version 4 headers to req.
+func (recv *AwsSecretsManagerProvider) sign(req *http.Request, payload []byte) {
+	now := recv.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if recv.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", recv.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	headerNames := make([]string, 0, len(headers))
+	for name := range headers {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+
+	canonicalHeaders := &strings.Builder{}
+	for _, name := range headerNames {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+
+	canonicalUri := req.URL.EscapedPath()
+	if canonicalUri == "" {
+		canonicalUri = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method, canonicalUri, req.URL.RawQuery, canonicalHeaders.String(), signedHeaders, sha256Hex(payload),
+	}, "\n")
+
+	scope := strings.Join([]string{date, recv.region, awsSecretsManagerService, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSha256([]byte("AWS4"+recv.secretAccessKey), date)
+	signingKey = hmacSha256(signingKey, recv.region)
+	signingKey = hmacSha256(signingKey, awsSecretsManagerService)
+	signingKey = hmacSha256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSha256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%v/%v, SignedHeaders=%v, Signature=%v",
+		recv.accessKeyId, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,107 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const (
+	gcpSecretManagerEndpoint = "https://secretmanager.googleapis.com"
+	gcpMetadataTokenUrl      = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// GcpSecretManagerProvider reads secrets from GCP Secret Manager. The location is the resource name of the secret
+// (projects/<project>/secrets/<secret>), optionally followed by /versions/<version>; the latest version is used
+// otherwise. The access token comes from GOOGLE_OAUTH_ACCESS_TOKEN or from the metadata server of the instance.
+type GcpSecretManagerProvider struct {
+	accessToken      string
+	endpoint         string
+	metadataTokenUrl string
+	httpClient       *http.Client
+}
+
+func NewGcpSecretManagerProvider(
+	accessToken string, endpoint string, metadataTokenUrl string, httpClient *http.Client) *GcpSecretManagerProvider {
+	return &GcpSecretManagerProvider{
+		accessToken:      accessToken,
+		endpoint:         strings.TrimSuffix(endpoint, "/"),
+		metadataTokenUrl: metadataTokenUrl,
+		httpClient:       httpClient,
+	}
+}
+
+func NewGcpSecretManagerProviderFromEnv(httpClient *http.Client) *GcpSecretManagerProvider {
+	return NewGcpSecretManagerProvider(
+		os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"), gcpSecretManagerEndpoint, gcpMetadataTokenUrl, httpClient)
+}
+
+func (recv *GcpSecretManagerProvider) Fetch(ctx context.Context, location string) ([]byte, error) {
+	location = strings.TrimPrefix(location, "/")
+	if !strings.Contains(location, "/versions/") {
+		location += "/versions/latest"
+	}
+
+	token, err := recv.getAccessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodGet, fmt.Sprintf("%v/v1/%v:access", recv.endpoint, location), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	var parsed struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err = recv.doJsonRequest(req, &parsed); err != nil {
+		return nil, fmt.Errorf("could not access gcp secret %v: %w", location, err)
+	}
+	return base64.StdEncoding.DecodeString(parsed.Payload.Data)
+}
+
+func (recv *GcpSecretManagerProvider) getAccessToken(ctx context.Context) (string, error) {
+	if recv.accessToken != "" {
+		return recv.accessToken, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, recv.metadataTokenUrl, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	var parsed struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err = recv.doJsonRequest(req, &parsed); err != nil {
+		return "", fmt.Errorf("could not obtain access token from the gcp metadata server: %w", err)
+	}
+	return parsed.AccessToken, nil
+}
+
+func (recv *GcpSecretManagerProvider) doJsonRequest(req *http.Request, result interface{}) error {
+	resp, err := recv.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %v", resp.StatusCode)
+	}
+	return json.Unmarshal(body, result)
+}
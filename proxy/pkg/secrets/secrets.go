@@ -0,0 +1,241 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A setting refers to a secret instead of containing its value when it has the format
+// secret://<provider>:<location>[#<field>], e.g. secret://vault:secret/data/zdm#origin_password. The explicit prefix
+// makes sure that literal values are never mistaken for secret references.
+//
+// When a field is specified, the secret must be a JSON object and only the value of that field is used.
+const (
+	ReferencePrefix = "secret://"
+
+	ProviderVault             = "vault"
+	ProviderAwsSecretsManager = "aws-sm"
+	ProviderGcpSecretManager  = "gcp-sm"
+)
+
+// Provider fetches secrets from an external secret store.
+type Provider interface {
+	// Fetch returns the current value of the secret at the given location.
+	Fetch(ctx context.Context, location string) ([]byte, error)
+}
+
+type reference struct {
+	provider string
+	location string
+	field    string
+}
+
+func parseReference(value string) (*reference, error) {
+	value = strings.TrimPrefix(value, ReferencePrefix)
+	idx := strings.Index(value, ":")
+	if idx <= 0 {
+		return nil, fmt.Errorf("expected %v<provider>:<location>[#<field>]", ReferencePrefix)
+	}
+	provider := value[:idx]
+	switch provider {
+	case ProviderVault, ProviderAwsSecretsManager, ProviderGcpSecretManager:
+	default:
+		return nil, fmt.Errorf("unknown provider %v, possible values are: %v, %v and %v",
+			provider, ProviderVault, ProviderAwsSecretsManager, ProviderGcpSecretManager)
+	}
+	location := value[idx+1:]
+	field := ""
+	if fieldIdx := strings.LastIndex(location, "#"); fieldIdx >= 0 {
+		field = location[fieldIdx+1:]
+		location = location[:fieldIdx]
+	}
+	if location == "" {
+		return nil, fmt.Errorf("the location of the secret is empty")
+	}
+	return &reference{provider: provider, location: location, field: field}, nil
+}
+
+// IsReference returns true if the setting value has the secret:// prefix.
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, ReferencePrefix)
+}
+
+// Store resolves setting values that refer to secrets and keeps the latest value of each secret.
+//
+// Values that are not secret references are returned as they are.
+type Store struct {
+	providers map[string]Provider
+	lock      *sync.RWMutex
+	values    map[string][]byte
+	logger    *log.Entry
+}
+
+func NewStore(providers map[string]Provider, logger *log.Entry) *Store {
+	return &Store{
+		providers: providers,
+		lock:      &sync.RWMutex{},
+		values:    make(map[string][]byte),
+		logger:    logger,
+	}
+}
+
+// NewDefaultStore creates a store with the Vault, AWS Secrets Manager and GCP Secret Manager providers. Each
+// provider is configured with the environment variables that the official tools of that platform use.
+func NewDefaultStore(logger *log.Entry) *Store {
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	return NewStore(map[string]Provider{
+		ProviderVault:             NewVaultProviderFromEnv(httpClient),
+		ProviderAwsSecretsManager: NewAwsSecretsManagerProviderFromEnv(httpClient),
+		ProviderGcpSecretManager:  NewGcpSecretManagerProviderFromEnv(httpClient),
+	}, logger)
+}
+
+// Resolve fetches the secret that value refers to and caches it so that Get can return it.
+func (recv *Store) Resolve(ctx context.Context, value string) ([]byte, error) {
+	if !IsReference(value) {
+		return []byte(value), nil
+	}
+	secret, err := recv.fetchReference(ctx, value)
+	if err != nil {
+		return nil, err
+	}
+	recv.lock.Lock()
+	recv.values[value] = secret
+	recv.lock.Unlock()
+	return secret, nil
+}
+
+// Get returns the latest value of the secret that value refers to, nil if it wasn't resolved yet.
+func (recv *Store) Get(value string) []byte {
+	return recv.GetAll(value)[0]
+}
+
+// GetAll is like Get but the values of all the secrets come from the same refresh, e.g. a TLS certificate and its
+// key.
+func (recv *Store) GetAll(values ...string) [][]byte {
+	result := make([][]byte, len(values))
+	recv.lock.RLock()
+	defer recv.lock.RUnlock()
+	for i, value := range values {
+		if IsReference(value) {
+			result[i] = recv.values[value]
+		} else {
+			result[i] = []byte(value)
+		}
+	}
+	return result
+}
+
+func (recv *Store) GetString(value string) string {
+	return string(recv.Get(value))
+}
+
+// HasSecrets returns true if at least one secret reference was resolved.
+func (recv *Store) HasSecrets() bool {
+	recv.lock.RLock()
+	defer recv.lock.RUnlock()
+	return len(recv.values) > 0
+}
+
+// Refresh fetches every resolved secret again and replaces all of them at once, so that secrets that belong
+// together (e.g. a TLS certificate, its key and the CA) are never seen half rotated. Secrets that can not be fetched
+// keep their previous value.
+func (recv *Store) Refresh(ctx context.Context) error {
+	recv.lock.RLock()
+	references := make([]string, 0, len(recv.values))
+	for value := range recv.values {
+		references = append(references, value)
+	}
+	recv.lock.RUnlock()
+
+	failures := 0
+	refreshed := make(map[string][]byte, len(references))
+	for _, value := range references {
+		secret, err := recv.fetchReference(ctx, value)
+		if err != nil {
+			recv.logger.Warnf("Could not refresh secret, the previous value will be used: %v", err)
+			failures++
+			continue
+		}
+		refreshed[value] = secret
+	}
+
+	recv.lock.Lock()
+	for value, secret := range refreshed {
+		recv.values[value] = secret
+	}
+	recv.lock.Unlock()
+
+	if failures > 0 {
+		return fmt.Errorf("could not refresh %d out of %d secrets", failures, len(references))
+	}
+	return nil
+}
+
+// RefreshPeriodically refreshes the resolved secrets every interval until ctx is canceled.
+func (recv *Store) RefreshPeriodically(ctx context.Context, interval time.Duration, wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := recv.Refresh(ctx); err == nil {
+					recv.logger.Debugf("Refreshed secrets.")
+				}
+			}
+		}
+	}()
+}
+
+func (recv *Store) fetchReference(ctx context.Context, value string) ([]byte, error) {
+	ref, err := parseReference(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret reference %v: %w", value, err)
+	}
+	secret, err := recv.fetch(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve secret %v: %w", value, err)
+	}
+	return secret, nil
+}
+
+func (recv *Store) fetch(ctx context.Context, ref *reference) ([]byte, error) {
+	provider, ok := recv.providers[ref.provider]
+	if !ok || provider == nil {
+		return nil, fmt.Errorf("secret provider %v is not available", ref.provider)
+	}
+	secret, err := provider.Fetch(ctx, ref.location)
+	if err != nil {
+		return nil, err
+	}
+	if ref.field == "" {
+		return secret, nil
+	}
+	return extractField(secret, ref.field)
+}
+
+func extractField(secret []byte, field string) ([]byte, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(secret, &fields); err != nil {
+		return nil, fmt.Errorf("secret is not a JSON object, can not extract field %v: %w", field, err)
+	}
+	value, ok := fields[field]
+	if !ok {
+		return nil, fmt.Errorf("secret does not contain field %v", field)
+	}
+	if str, ok := value.(string); ok {
+		return []byte(str), nil
+	}
+	return json.Marshal(value)
+}
@@ -0,0 +1,161 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeProvider struct {
+	secrets map[string]string
+}
+
+func (recv *fakeProvider) Fetch(_ context.Context, location string) ([]byte, error) {
+	secret, ok := recv.secrets[location]
+	if !ok {
+		return nil, fmt.Errorf("secret %v not found", location)
+	}
+	return []byte(secret), nil
+}
+
+func TestIsReference(t *testing.T) {
+	require.True(t, IsReference("secret://vault:secret/data/zdm#password"))
+	require.True(t, IsReference("secret://aws-sm:prod/zdm/origin"))
+	require.True(t, IsReference("secret://gcp-sm:projects/p/secrets/s#key"))
+	require.False(t, IsReference("cassandra"))
+	require.False(t, IsReference("vault:secret/data/zdm#password"))
+	require.False(t, IsReference("/etc/zdm/ca.pem"))
+	require.False(t, IsReference("pass:word"))
+}
+
+func TestStore_ResolveGetAndRefresh(t *testing.T) {
+	provider := &fakeProvider{secrets: map[string]string{
+		"secret/data/zdm": `{"username":"origin_user","password":"origin_pwd"}`,
+		"ca":              "-----BEGIN CERTIFICATE-----",
+	}}
+	store := NewStore(map[string]Provider{ProviderVault: provider}, log.NewEntry(log.StandardLogger()))
+
+	require.Equal(t, "plain", store.GetString("plain"))
+	value, err := store.Resolve(context.Background(), "vault:not-a-reference")
+	require.Nil(t, err)
+	require.Equal(t, "vault:not-a-reference", string(value))
+	_, err = store.Resolve(context.Background(), "secret://unknown:location")
+	require.NotNil(t, err)
+	_, err = store.Resolve(context.Background(), "secret://vault:")
+	require.NotNil(t, err)
+	require.False(t, store.HasSecrets())
+
+	value, err = store.Resolve(context.Background(), "secret://vault:secret/data/zdm#password")
+	require.Nil(t, err)
+	require.Equal(t, "origin_pwd", string(value))
+	_, err = store.Resolve(context.Background(), "secret://vault:ca")
+	require.Nil(t, err)
+	require.True(t, store.HasSecrets())
+	require.Equal(t, "-----BEGIN CERTIFICATE-----", store.GetString("secret://vault:ca"))
+
+	_, err = store.Resolve(context.Background(), "secret://vault:secret/data/zdm#missing")
+	require.NotNil(t, err)
+	_, err = store.Resolve(context.Background(), "secret://aws-sm:secret")
+	require.NotNil(t, err)
+	require.Nil(t, store.Get("secret://aws-sm:secret"))
+
+	provider.secrets["secret/data/zdm"] = `{"username":"origin_user","password":"rotated_pwd"}`
+	require.Nil(t, store.Refresh(context.Background()))
+	require.Equal(t, "rotated_pwd", store.GetString("secret://vault:secret/data/zdm#password"))
+	require.Equal(t, [][]byte{[]byte("rotated_pwd"), []byte("-----BEGIN CERTIFICATE-----"), []byte("plain")},
+		store.GetAll("secret://vault:secret/data/zdm#password", "secret://vault:ca", "plain"))
+
+	delete(provider.secrets, "ca")
+	require.NotNil(t, store.Refresh(context.Background()))
+	require.Equal(t, "-----BEGIN CERTIFICATE-----", store.GetString("secret://vault:ca"))
+}
+
+func TestVaultProvider_Fetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/zdm":
+			w.Write([]byte(`{"data":{"data":{"password":"kv2"},"metadata":{"version":3}}}`))
+		case "/v1/kv/zdm":
+			w.Write([]byte(`{"data":{"password":"kv1"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	store := NewStore(map[string]Provider{
+		ProviderVault: NewVaultProvider(server.URL, "root", "", server.Client()),
+	}, log.NewEntry(log.StandardLogger()))
+	value, err := store.Resolve(context.Background(), "secret://vault:secret/data/zdm#password")
+	require.Nil(t, err)
+	require.Equal(t, "kv2", string(value))
+	value, err = store.Resolve(context.Background(), "secret://vault:kv/zdm#password")
+	require.Nil(t, err)
+	require.Equal(t, "kv1", string(value))
+	_, err = store.Resolve(context.Background(), "secret://vault:kv/missing#password")
+	require.NotNil(t, err)
+}
+
+func TestAwsSecretsManagerProvider_Fetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/20230102/us-east-1/secretsmanager/aws4_request, "+
+			"SignedHeaders=content-type;host;x-amz-date;x-amz-security-token;x-amz-target, Signature=") ||
+			r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" ||
+			r.Header.Get("X-Amz-Security-Token") != "session" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"Name":"zdm","SecretString":"{\"password\":\"aws_pwd\"}"}`))
+	}))
+	defer server.Close()
+
+	provider := NewAwsSecretsManagerProvider("us-east-1", "AKID", "SECRET", "session", server.URL, server.Client())
+	provider.now = func() time.Time { return time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC) }
+	store := NewStore(map[string]Provider{ProviderAwsSecretsManager: provider}, log.NewEntry(log.StandardLogger()))
+
+	value, err := store.Resolve(context.Background(), "secret://aws-sm:zdm#password")
+	require.Nil(t, err)
+	require.Equal(t, "aws_pwd", string(value))
+}
+
+func TestGcpSecretManagerProvider_Fetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if r.Header.Get("Metadata-Flavor") != "Google" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"access_token":"metadata-token","expires_in":3599}`))
+		case "/v1/projects/p/secrets/zdm/versions/latest:access":
+			if r.Header.Get("Authorization") != "Bearer metadata-token" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			payload := base64.StdEncoding.EncodeToString([]byte("gcp_pwd"))
+			w.Write([]byte(fmt.Sprintf(`{"payload":{"data":"%v"}}`, payload)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	store := NewStore(map[string]Provider{
+		ProviderGcpSecretManager: NewGcpSecretManagerProvider("", server.URL, server.URL+"/token", server.Client()),
+	}, log.NewEntry(log.StandardLogger()))
+	value, err := store.Resolve(context.Background(), "secret://gcp-sm:projects/p/secrets/zdm")
+	require.Nil(t, err)
+	require.Equal(t, "gcp_pwd", string(value))
+}
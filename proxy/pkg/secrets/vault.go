@@ -0,0 +1,78 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// VaultProvider reads secrets from the HashiCorp Vault HTTP API. Both KV version 1 and version 2 secret engines
+// are supported, the location is the API path of the secret (e.g. secret/data/zdm for KV version 2).
+type VaultProvider struct {
+	address    string
+	token      string
+	namespace  string
+	httpClient *http.Client
+}
+
+func NewVaultProvider(address string, token string, namespace string, httpClient *http.Client) *VaultProvider {
+	return &VaultProvider{
+		address:    strings.TrimSuffix(address, "/"),
+		token:      token,
+		namespace:  namespace,
+		httpClient: httpClient,
+	}
+}
+
+// NewVaultProviderFromEnv uses VAULT_ADDR, VAULT_TOKEN and VAULT_NAMESPACE.
+func NewVaultProviderFromEnv(httpClient *http.Client) *VaultProvider {
+	return NewVaultProvider(os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN"), os.Getenv("VAULT_NAMESPACE"), httpClient)
+}
+
+func (recv *VaultProvider) Fetch(ctx context.Context, location string) ([]byte, error) {
+	if recv.address == "" {
+		return nil, fmt.Errorf("VAULT_ADDR is not set")
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodGet, fmt.Sprintf("%v/v1/%v", recv.address, strings.TrimPrefix(location, "/")), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", recv.token)
+	if recv.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", recv.namespace)
+	}
+
+	resp, err := recv.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read vault response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault returned status %v for %v", resp.StatusCode, location)
+	}
+
+	var parsed struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err = json.Unmarshal(body, &parsed); err != nil {
+		return nil, fmt.Errorf("could not parse vault response: %w", err)
+	}
+
+	// KV version 2 wraps the secret in another data object alongside its metadata
+	if kvData, ok := parsed.Data["data"]; ok {
+		if _, hasMetadata := parsed.Data["metadata"]; hasMetadata {
+			return kvData, nil
+		}
+	}
+	return json.Marshal(parsed.Data)
+}
@@ -0,0 +1,53 @@
+package testkit
+
+import "strings"
+
+// TestingT is the subset of testing.T used by the assertions, so that they can be used with other test frameworks.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// AssertExecuted checks that the cluster executed the statement with the given query at least once.
+func (recv *Cluster) AssertExecuted(t TestingT, query string) bool {
+	t.Helper()
+	if len(recv.Executions(query)) == 0 {
+		t.Errorf("expected cluster %v to execute %q but it executed:\n%v", recv.Name, query, recv.executedQueries())
+		return false
+	}
+	return true
+}
+
+// AssertNotExecuted checks that the cluster never executed the statement with the given query.
+func (recv *Cluster) AssertNotExecuted(t TestingT, query string) bool {
+	t.Helper()
+	if executions := recv.Executions(query); len(executions) > 0 {
+		t.Errorf("expected cluster %v to not execute %q but it was executed %d time(s)",
+			recv.Name, query, len(executions))
+		return false
+	}
+	return true
+}
+
+// AssertExecutedTimes checks that the cluster executed the statement with the given query exactly n times.
+func (recv *Cluster) AssertExecutedTimes(t TestingT, query string, n int) bool {
+	t.Helper()
+	if executions := recv.Executions(query); len(executions) != n {
+		t.Errorf("expected cluster %v to execute %q %d time(s) but it was executed %d time(s)",
+			recv.Name, query, n, len(executions))
+		return false
+	}
+	return true
+}
+
+func (recv *Cluster) executedQueries() string {
+	sb := &strings.Builder{}
+	for _, statement := range recv.Statements() {
+		sb.WriteString("  ")
+		sb.WriteString(statement.OpCode.String())
+		sb.WriteString(" ")
+		sb.WriteString(statement.Query)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
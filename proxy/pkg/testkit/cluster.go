@@ -0,0 +1,349 @@
+// Package testkit provides in-memory CQL clusters so that programs that embed the proxy, or that automate its
+// deployment, can be tested without Docker or ccm.
+package testkit
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	DefaultUsername   = "cassandra"
+	DefaultPassword   = "cassandra"
+	DefaultDatacenter = "dc1"
+)
+
+// Prime is the canned response of a cluster to the statements with the given query, whether they are executed with a
+// QUERY, prepared and executed with an EXECUTE or executed in a BATCH.
+type Prime struct {
+	Query string
+
+	// Response is the result (e.g. RowsResult or VoidResult) or the error that is returned when the statement is
+	// executed. The result of a BATCH is always a VoidResult unless one of its statements is primed with an error.
+	Response message.Message
+
+	// Variables are the bound variables returned in the response of a PREPARE, optional.
+	Variables []*message.ColumnMetadata
+
+	// Warnings are returned with the response of QUERY and EXECUTE requests of protocol v4 or higher, optional.
+	Warnings []string
+}
+
+// Statement is a statement that a cluster received.
+type Statement struct {
+	// OpCode is QUERY, PREPARE, EXECUTE or BATCH (the statements of a batch are recorded separately).
+	OpCode primitive.OpCode
+
+	// Query is the query of the statement, for EXECUTE and prepared statements of a BATCH it is the query that was
+	// prepared.
+	Query       string
+	Values      []*primitive.Value
+	NamedValues map[string]*primitive.Value
+}
+
+// Cluster is an in-memory CQL server that implements the handshake (with authentication if credentials are provided),
+// the system tables, USE, PREPARE and the execution of primed statements. Statements that are not primed return an
+// empty RowsResult if they are SELECT statements and a VoidResult otherwise.
+//
+// The requests to the system tables, the handshake and the heartbeats are not recorded.
+type Cluster struct {
+	Name string
+
+	// CqlServer is the underlying server, it can be customized (e.g. with more request handlers) before Start.
+	CqlServer *client.CqlServer
+
+	// RecordStatements can be disabled to avoid accumulating the statements of long running tests (e.g. benchmarks).
+	RecordStatements bool
+
+	host string
+	port int
+
+	lock       *sync.Mutex
+	primes     map[string]*Prime
+	prepared   map[string]string
+	statements []*Statement
+}
+
+// NewCluster creates a cluster that listens on the given host and port. If port is 0, a free port is chosen. The
+// credentials are optional, nil disables authentication.
+func NewCluster(name string, host string, port int, credentials *client.AuthCredentials) (*Cluster, error) {
+	if port == 0 {
+		ports, err := FreePorts(host, 1)
+		if err != nil {
+			return nil, fmt.Errorf("could not find a free port for cluster %v: %w", name, err)
+		}
+		port = ports[0]
+	}
+	cluster := &Cluster{
+		Name:             name,
+		CqlServer:        client.NewCqlServer(net.JoinHostPort(host, strconv.Itoa(port)), credentials),
+		RecordStatements: true,
+		host:             host,
+		port:             port,
+		lock:             &sync.Mutex{},
+		primes:           make(map[string]*Prime),
+		prepared:         make(map[string]string),
+	}
+	cluster.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.RegisterHandler,
+		client.HeartbeatHandler,
+		client.HandshakeHandler,
+		client.NewSystemTablesHandler(name, DefaultDatacenter),
+		client.NewSetKeyspaceHandler(func(string) {}),
+		cluster.handleRequest,
+	}
+	return cluster, nil
+}
+
+// MaxProtocolVersionHandler is a request handler that makes a cluster behave like a cluster that doesn't support the
+// protocol versions greater than the given version: it answers their requests with a protocol error encoded with the
+// given version. It has to be the first request handler of the CqlServer.
+func MaxProtocolVersionHandler(version primitive.ProtocolVersion) client.RequestHandler {
+	return func(
+		request *frame.Frame, _ *client.CqlServerConnection, _ client.RequestHandlerContext) *frame.Frame {
+		if request.Header.Version <= version {
+			return nil
+		}
+		return frame.NewFrame(version, request.Header.StreamId, &message.ProtocolError{
+			ErrorMessage: fmt.Sprintf("Invalid or unsupported protocol version (%d)", request.Header.Version),
+		})
+	}
+}
+
+// Start starts the server, it is closed when ctx is done or when Close is called.
+func (recv *Cluster) Start(ctx context.Context) error {
+	return recv.CqlServer.Start(ctx)
+}
+
+func (recv *Cluster) Close() error {
+	return recv.CqlServer.Close()
+}
+
+func (recv *Cluster) Host() string {
+	return recv.host
+}
+
+func (recv *Cluster) Port() int {
+	return recv.port
+}
+
+func (recv *Cluster) Address() string {
+	return recv.CqlServer.ListenAddress
+}
+
+// Prime sets the response of the statements with prime.Query, it replaces the previous prime of the same query.
+func (recv *Cluster) Prime(prime *Prime) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.primes[prime.Query] = prime
+}
+
+// PrimeRows primes query with a RowsResult of the given columns and rows.
+func (recv *Cluster) PrimeRows(query string, columns []*message.ColumnMetadata, rows message.RowSet) {
+	recv.Prime(&Prime{
+		Query: query,
+		Response: &message.RowsResult{
+			Metadata: &message.RowsMetadata{ColumnCount: int32(len(columns)), Columns: columns},
+			Data:     rows,
+		},
+	})
+}
+
+// PrimeError primes query with an error response.
+func (recv *Cluster) PrimeError(query string, err message.Error) {
+	recv.Prime(&Prime{Query: query, Response: err})
+}
+
+// ClearPrimes removes all primes, the prepared statements are kept.
+func (recv *Cluster) ClearPrimes() {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.primes = make(map[string]*Prime)
+}
+
+// Statements returns the statements received so far, in the order in which they were received.
+func (recv *Cluster) Statements() []*Statement {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	statements := make([]*Statement, len(recv.statements))
+	copy(statements, recv.statements)
+	return statements
+}
+
+// Executions returns the executions (QUERY, EXECUTE or BATCH) of the statements with the given query.
+func (recv *Cluster) Executions(query string) []*Statement {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	var executions []*Statement
+	for _, statement := range recv.statements {
+		if statement.Query == query && statement.OpCode != primitive.OpCodePrepare {
+			executions = append(executions, statement)
+		}
+	}
+	return executions
+}
+
+// ClearStatements forgets the statements received so far.
+func (recv *Cluster) ClearStatements() {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.statements = nil
+}
+
+func (recv *Cluster) handleRequest(
+	request *frame.Frame, _ *client.CqlServerConnection, _ client.RequestHandlerContext) *frame.Frame {
+
+	var response message.Message
+	var warnings []string
+	switch msg := request.Body.Message.(type) {
+	case *message.Query:
+		recv.record(newStatement(primitive.OpCodeQuery, msg.Query, msg.Options))
+		response = recv.executionResponse(msg.Query)
+		warnings = recv.executionWarnings(msg.Query)
+	case *message.Prepare:
+		recv.record(newStatement(primitive.OpCodePrepare, msg.Query, nil))
+		response = recv.prepare(request.Header.Version, msg.Query)
+	case *message.Execute:
+		query, ok := recv.preparedQuery(msg.QueryId)
+		if !ok {
+			response = newUnprepared(msg.QueryId)
+			break
+		}
+		recv.record(newStatement(primitive.OpCodeExecute, query, msg.Options))
+		response = recv.executionResponse(query)
+		warnings = recv.executionWarnings(query)
+	case *message.Batch:
+		response = recv.batch(msg)
+	default:
+		return nil
+	}
+	responseFrame := frame.NewFrame(request.Header.Version, request.Header.StreamId, response)
+	if request.Header.Version >= primitive.ProtocolVersion4 {
+		responseFrame.SetWarnings(warnings)
+	}
+	return responseFrame
+}
+
+func newStatement(opCode primitive.OpCode, query string, options *message.QueryOptions) *Statement {
+	statement := &Statement{OpCode: opCode, Query: query}
+	if options != nil {
+		statement.Values = options.PositionalValues
+		statement.NamedValues = options.NamedValues
+	}
+	return statement
+}
+
+func newUnprepared(id []byte) *message.Unprepared {
+	return &message.Unprepared{ErrorMessage: fmt.Sprintf("Prepared query with ID %x not found", id), Id: id}
+}
+
+func (recv *Cluster) record(statement *Statement) {
+	if !recv.RecordStatements {
+		return
+	}
+	recv.lock.Lock()
+	recv.statements = append(recv.statements, statement)
+	recv.lock.Unlock()
+}
+
+func (recv *Cluster) executionResponse(query string) message.Message {
+	recv.lock.Lock()
+	prime, ok := recv.primes[query]
+	recv.lock.Unlock()
+	if ok && prime.Response != nil {
+		return prime.Response
+	}
+	if isSelect(query) {
+		return &message.RowsResult{Metadata: &message.RowsMetadata{}, Data: message.RowSet{}}
+	}
+	return &message.VoidResult{}
+}
+
+func (recv *Cluster) executionWarnings(query string) []string {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if prime, ok := recv.primes[query]; ok {
+		return prime.Warnings
+	}
+	return nil
+}
+
+func (recv *Cluster) prepare(version primitive.ProtocolVersion, query string) message.Message {
+	id := md5.Sum([]byte(query))
+	recv.lock.Lock()
+	recv.prepared[string(id[:])] = query
+	prime := recv.primes[query]
+	recv.lock.Unlock()
+
+	result := &message.PreparedResult{
+		PreparedQueryId:   id[:],
+		VariablesMetadata: &message.VariablesMetadata{},
+		ResultMetadata:    &message.RowsMetadata{},
+	}
+	if version >= primitive.ProtocolVersion5 {
+		result.ResultMetadataId = id[:]
+	}
+	if prime != nil {
+		result.VariablesMetadata.Columns = prime.Variables
+		if rows, ok := prime.Response.(*message.RowsResult); ok && rows.Metadata != nil {
+			result.ResultMetadata = rows.Metadata
+		}
+	}
+	return result
+}
+
+func (recv *Cluster) preparedQuery(id []byte) (string, bool) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	query, ok := recv.prepared[string(id)]
+	return query, ok
+}
+
+func (recv *Cluster) batch(batch *message.Batch) message.Message {
+	var response message.Message = &message.VoidResult{}
+	for _, child := range batch.Children {
+		var query string
+		switch queryOrId := child.QueryOrId.(type) {
+		case string:
+			query = queryOrId
+		case []byte:
+			var ok bool
+			if query, ok = recv.preparedQuery(queryOrId); !ok {
+				return newUnprepared(queryOrId)
+			}
+		}
+		recv.record(&Statement{OpCode: primitive.OpCodeBatch, Query: query, Values: child.Values})
+		if childResponse, ok := recv.executionResponse(query).(message.Error); ok {
+			response = childResponse
+		}
+	}
+	return response
+}
+
+func isSelect(query string) bool {
+	trimmed := strings.TrimSpace(query)
+	return len(trimmed) >= 6 && strings.EqualFold(trimmed[:6], "SELECT")
+}
+
+// FreePorts returns n distinct ports that are free on the given host.
+func FreePorts(host string, n int) ([]int, error) {
+	ports := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		listener, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+		if err != nil {
+			return nil, err
+		}
+		defer listener.Close()
+		ports = append(ports, listener.Addr().(*net.TCPAddr).Port)
+	}
+	return ports, nil
+}
@@ -0,0 +1,118 @@
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"strconv"
+)
+
+const localhost = "127.0.0.1"
+
+// Setup is an ORIGIN and a TARGET cluster with a proxy in front of them, all of them listening on free ports of the
+// loopback interface.
+type Setup struct {
+	Origin *Cluster
+	Target *Cluster
+	Proxy  *zdmproxy.ZdmProxy
+	Config *config.Config
+}
+
+// NewSetup starts the clusters and the proxy. The settings of the proxy use the keys of the config file (e.g.
+// read_mode) and override the contact points, ports and credentials that point the proxy to the clusters.
+func NewSetup(ctx context.Context, settings map[string]string) (*Setup, error) {
+	credentials := &client.AuthCredentials{Username: DefaultUsername, Password: DefaultPassword}
+	origin, err := NewCluster("origin", localhost, 0, credentials)
+	if err != nil {
+		return nil, err
+	}
+	target, err := NewCluster("target", localhost, 0, credentials)
+	if err != nil {
+		return nil, err
+	}
+	setup := &Setup{Origin: origin, Target: target}
+	if err = setup.Start(ctx, settings); err != nil {
+		setup.Close()
+		return nil, err
+	}
+	return setup, nil
+}
+
+// NewSetupWithClusters creates a setup with clusters that are not started yet (e.g. because their CqlServer needs to
+// be customized), Start starts them.
+func NewSetupWithClusters(origin *Cluster, target *Cluster) *Setup {
+	return &Setup{Origin: origin, Target: target}
+}
+
+// Start starts the clusters and the proxy.
+func (recv *Setup) Start(ctx context.Context, settings map[string]string) error {
+	if err := recv.Origin.Start(ctx); err != nil {
+		return fmt.Errorf("could not start cluster %v: %w", recv.Origin.Name, err)
+	}
+	if err := recv.Target.Start(ctx); err != nil {
+		return fmt.Errorf("could not start cluster %v: %w", recv.Target.Name, err)
+	}
+
+	proxySettings := map[string]string{
+		"origin_contact_points": recv.Origin.Host(),
+		"origin_port":           strconv.Itoa(recv.Origin.Port()),
+		"origin_username":       DefaultUsername,
+		"origin_password":       DefaultPassword,
+		"target_contact_points": recv.Target.Host(),
+		"target_port":           strconv.Itoa(recv.Target.Port()),
+		"target_username":       DefaultUsername,
+		"target_password":       DefaultPassword,
+		"proxy_listen_address":  localhost,
+	}
+	for key, value := range settings {
+		proxySettings[key] = value
+	}
+	if _, ok := proxySettings["proxy_listen_port"]; !ok {
+		ports, err := FreePorts(proxySettings["proxy_listen_address"], 1)
+		if err != nil {
+			return fmt.Errorf("could not find a free port for the proxy: %w", err)
+		}
+		proxySettings["proxy_listen_port"] = strconv.Itoa(ports[0])
+	}
+	conf, err := config.New().ParseSettings(proxySettings)
+	if err != nil {
+		return err
+	}
+	recv.Config = conf
+	recv.Proxy, err = zdmproxy.Run(conf, ctx)
+	if err != nil {
+		return fmt.Errorf("could not start proxy: %w", err)
+	}
+	return nil
+}
+
+// ProxyAddress is the address that the clients connect to.
+func (recv *Setup) ProxyAddress() string {
+	return net.JoinHostPort(recv.Config.ProxyListenAddress, strconv.Itoa(recv.Config.ProxyListenPort))
+}
+
+// Connect opens a client connection to the proxy and performs the handshake.
+func (recv *Setup) Connect(ctx context.Context, version primitive.ProtocolVersion) (*client.CqlClientConnection, error) {
+	cqlClient := client.NewCqlClient(recv.ProxyAddress(), &client.AuthCredentials{
+		Username: DefaultUsername,
+		Password: DefaultPassword,
+	})
+	return cqlClient.ConnectAndInit(ctx, version, client.ManagedStreamId)
+}
+
+// Close shuts down the proxy and the clusters.
+func (recv *Setup) Close() {
+	if recv.Proxy != nil {
+		recv.Proxy.Shutdown()
+	}
+	for _, cluster := range []*Cluster{recv.Target, recv.Origin} {
+		if err := cluster.Close(); err != nil {
+			log.Warnf("Error closing cluster %v: %v", cluster.Name, err)
+		}
+	}
+}
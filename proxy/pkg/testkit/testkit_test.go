@@ -0,0 +1,106 @@
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type recordingT struct {
+	errors []string
+}
+
+func (recv *recordingT) Helper() {}
+
+func (recv *recordingT) Errorf(format string, args ...interface{}) {
+	recv.errors = append(recv.errors, fmt.Sprintf(format, args...))
+}
+
+func TestSetup(t *testing.T) {
+	setup, err := NewSetup(context.Background(), map[string]string{"metrics_enabled": "false"})
+	require.Nil(t, err)
+	defer setup.Close()
+
+	columns := []*message.ColumnMetadata{{Keyspace: "ks", Table: "tb", Name: "name", Type: datatype.Varchar}}
+	setup.Origin.PrimeRows("SELECT name FROM ks.tb", columns, message.RowSet{{[]byte("origin")}})
+	setup.Target.PrimeRows("SELECT name FROM ks.tb", columns, message.RowSet{{[]byte("target")}})
+	setup.Target.PrimeError("INSERT INTO ks.tb (name) VALUES (?)", &message.Overloaded{ErrorMessage: "overloaded"})
+
+	conn, err := setup.Connect(context.Background(), primitive.ProtocolVersion4)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	response, err := conn.SendAndReceive(frame.NewFrame(
+		primitive.ProtocolVersion4, 0, &message.Query{Query: "SELECT name FROM ks.tb"}))
+	require.Nil(t, err)
+	require.Equal(t, message.RowSet{{[]byte("origin")}}, response.Body.Message.(*message.RowsResult).Data)
+	setup.Origin.AssertExecuted(t, "SELECT name FROM ks.tb")
+	setup.Target.AssertNotExecuted(t, "SELECT name FROM ks.tb")
+
+	response, err = conn.SendAndReceive(frame.NewFrame(
+		primitive.ProtocolVersion4, 0, &message.Prepare{Query: "INSERT INTO ks.tb (name) VALUES (?)"}))
+	require.Nil(t, err)
+	prepared, ok := response.Body.Message.(*message.PreparedResult)
+	require.True(t, ok, response.Body.Message)
+
+	value := primitive.NewValue([]byte("john"))
+	response, err = conn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Execute{
+		QueryId: prepared.PreparedQueryId,
+		Options: &message.QueryOptions{PositionalValues: []*primitive.Value{value}},
+	}))
+	require.Nil(t, err)
+	require.Equal(t, &message.Overloaded{ErrorMessage: "overloaded"}, response.Body.Message)
+	for _, cluster := range []*Cluster{setup.Origin, setup.Target} {
+		executions := cluster.Executions("INSERT INTO ks.tb (name) VALUES (?)")
+		require.Equal(t, 1, len(executions), cluster.Name)
+		require.Equal(t, primitive.OpCodeExecute, executions[0].OpCode)
+		require.Equal(t, []*primitive.Value{value}, executions[0].Values)
+	}
+
+	response, err = conn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Batch{
+		Children: []*message.BatchChild{
+			{QueryOrId: "INSERT INTO ks.tb2 (name) VALUES ('a')"},
+			{QueryOrId: prepared.PreparedQueryId, Values: []*primitive.Value{value}},
+		},
+	}))
+	require.Nil(t, err)
+	require.IsType(t, &message.Overloaded{}, response.Body.Message)
+	setup.Origin.AssertExecutedTimes(t, "INSERT INTO ks.tb (name) VALUES (?)", 2)
+	setup.Origin.AssertExecuted(t, "INSERT INTO ks.tb2 (name) VALUES ('a')")
+
+	setup.Origin.ClearStatements()
+	require.Empty(t, setup.Origin.Statements())
+	require.NotEmpty(t, setup.Target.Statements())
+}
+
+func TestCluster_Assertions(t *testing.T) {
+	cluster, err := NewCluster("test", localhost, 0, nil)
+	require.Nil(t, err)
+	cluster.record(&Statement{OpCode: primitive.OpCodePrepare, Query: "SELECT * FROM t"})
+	cluster.record(&Statement{OpCode: primitive.OpCodeQuery, Query: "INSERT INTO t (k) VALUES (1)"})
+
+	recorder := &recordingT{}
+	require.False(t, cluster.AssertExecuted(recorder, "SELECT * FROM t"), "a prepare is not an execution")
+	require.True(t, cluster.AssertNotExecuted(recorder, "SELECT * FROM t"))
+	require.True(t, cluster.AssertExecuted(recorder, "INSERT INTO t (k) VALUES (1)"))
+	require.False(t, cluster.AssertExecutedTimes(recorder, "INSERT INTO t (k) VALUES (1)", 2))
+	require.Equal(t, 2, len(recorder.errors))
+	require.Contains(t, recorder.errors[0], "INSERT INTO t (k) VALUES (1)")
+}
+
+func TestCluster_UnknownPreparedId(t *testing.T) {
+	cluster, err := NewCluster("test", localhost, 0, nil)
+	require.Nil(t, err)
+	response := cluster.handleRequest(frame.NewFrame(primitive.ProtocolVersion4, 1, &message.Execute{
+		QueryId: []byte{1, 2},
+		Options: &message.QueryOptions{},
+	}), nil, nil)
+	require.Equal(t, &message.Unprepared{ErrorMessage: "Prepared query with ID 0102 not found", Id: []byte{1, 2}},
+		response.Body.Message)
+	require.Empty(t, cluster.Statements())
+}
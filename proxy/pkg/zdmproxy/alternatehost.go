@@ -0,0 +1,197 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"net"
+	"sync"
+	"time"
+)
+
+// alternateHostMaxAttempts is the number of hosts that are tried each time an alternate host connection is opened
+const alternateHostMaxAttempts = 3
+
+// alternateHostReopenDelay is how long a connector waits before trying to open its alternate host connection again
+// when none of the hosts could be reached, the requests are sent on the connection of the connector in the meantime
+const alternateHostReopenDelay = 5 * time.Second
+
+// alternateHost tracks the additional request connection that an ORIGIN or TARGET connector opens to another host of
+// the local datacenter of its cluster, the reads that are retried and the speculative reads of the cluster are sent on
+// it (see ClientHandler.handleRetry and ClientHandler.scheduleSpeculativeRead).
+//
+// The connection is opened the first time it is needed and it is opened again if it is lost. Like the stream id
+// overflow connections, it is retired when the client changes the current keyspace and a retired connection is
+// closed once the responses of its requests are received.
+type alternateHost struct {
+	lock *sync.Mutex
+
+	// set by enableAlternateHost once the client handshake is done
+	controlConn *ControlConn
+	handshake   connectionHandshake
+
+	// closed once the connection that is being opened is ready or could not be opened, nil if none is being opened
+	opening  chan struct{}
+	failedAt time.Time
+	closed   bool
+	current  *ClusterConnector
+
+	// all the alternate host connectors that were started, including the retired ones
+	connectors []*ClusterConnector
+}
+
+func newAlternateHost() *alternateHost {
+	return &alternateHost{
+		lock: &sync.Mutex{},
+	}
+}
+
+// getAlternateHostConnector returns the connector of the alternate host, the connection is opened if needed. It
+// returns nil if the connection could not be opened, e.g. because the cluster doesn't have another host in the local
+// datacenter.
+func (cc *ClusterConnector) getAlternateHostConnector() *ClusterConnector {
+	if cc.alternateHost == nil {
+		return nil
+	}
+	alternate := cc.alternateHost
+	alternate.lock.Lock()
+	if alternate.current != nil && !alternate.current.IsShutdown() {
+		defer alternate.lock.Unlock()
+		return alternate.current
+	}
+	alternate.current = nil
+	if alternate.handshake == nil || alternate.closed || cc.IsShutdown() ||
+		time.Since(alternate.failedAt) < alternateHostReopenDelay {
+		alternate.lock.Unlock()
+		return nil
+	}
+
+	opening := alternate.opening
+	if opening == nil {
+		opening = make(chan struct{})
+		alternate.opening = opening
+		controlConn, handshake := alternate.controlConn, alternate.handshake
+		cc.clientHandlerRequestWg.Add(1)
+		go func() {
+			defer cc.clientHandlerRequestWg.Done()
+			defer close(opening)
+			cc.openAlternateHostConnector(controlConn, handshake)
+		}()
+	}
+	alternate.lock.Unlock()
+
+	select {
+	case <-opening:
+	case <-cc.clusterConnContext.Done():
+		return nil
+	}
+	alternate.lock.Lock()
+	defer alternate.lock.Unlock()
+	return alternate.current
+}
+
+func (cc *ClusterConnector) openAlternateHostConnector(controlConn *ControlConn, handshake connectionHandshake) {
+	cc.logger.Infof("[%s] Opening a request connection to another host of %v.", cc.connectorType, cc.clusterType)
+	conn, endpoint, err := openConnectionToOtherHost(cc.clusterConnContext, cc.connInfo.connConfig, controlConn,
+		cc.connInfo.endpoint, alternateHostMaxAttempts, handshake, cc.connectorType, cc.logger)
+	var connector *ClusterConnector
+	if err == nil {
+		nodeMetricsInstance, metricsErr := GetNodeMetricsByClusterConnector(cc.nodeMetrics, cc.connectorType)
+		if metricsErr == nil {
+			nodeMetricsInstance.OpenConnections.Add(1)
+		}
+		connector, err = cc.newAdditionalConnector(conn, maxStreamIdsV3)
+	}
+
+	alternate := cc.alternateHost
+	alternate.lock.Lock()
+	defer alternate.lock.Unlock()
+	alternate.opening = nil
+	if err != nil {
+		if cc.clusterConnContext.Err() == nil {
+			cc.logger.Warnf("[%s] Could not open a request connection to another host of %v, "+
+				"the requests are sent to %v instead: %v.",
+				cc.connectorType, cc.clusterType, cc.connection.RemoteAddr(), err)
+		}
+		alternate.failedAt = time.Now()
+		return
+	}
+	if alternate.closed || cc.IsShutdown() {
+		connector.Shutdown()
+		return
+	}
+	connector.run()
+	alternate.current = connector
+	alternate.connectors = append(alternate.connectors, connector)
+	cc.logger.Infof("[%s] Request connection to another host of %v (%v) has been opened.",
+		cc.connectorType, cc.clusterType, endpoint.GetEndpointIdentifier())
+}
+
+// retireAlternateHostConnector stops sending requests on the current alternate host connection, see alternateHost.
+func (cc *ClusterConnector) retireAlternateHostConnector() {
+	if cc.alternateHost == nil {
+		return
+	}
+	alternate := cc.alternateHost
+	alternate.lock.Lock()
+	defer alternate.lock.Unlock()
+	if alternate.current == nil {
+		return
+	}
+	if alternate.current.streamIds.retire() {
+		alternate.current.Shutdown()
+	}
+	alternate.current = nil
+}
+
+// closeAlternateHostConnectors closes the write queues of the alternate host connectors, it is called by the client
+// handler once no more requests are sent.
+func (cc *ClusterConnector) closeAlternateHostConnectors() {
+	if cc.alternateHost == nil {
+		return
+	}
+	alternate := cc.alternateHost
+	alternate.lock.Lock()
+	alternate.closed = true
+	connectors := alternate.connectors
+	alternate.lock.Unlock()
+	for _, connector := range connectors {
+		connector.writeCoalescer.Close()
+	}
+}
+
+// waitForAlternateHostConnectors waits until the alternate host connectors stop sending responses to the client
+// handler, see waitForStreamIdOverflowConnectors.
+func (cc *ClusterConnector) waitForAlternateHostConnectors() {
+	if cc.alternateHost == nil {
+		return
+	}
+	alternate := cc.alternateHost
+	alternate.lock.Lock()
+	alternate.closed = true
+	connectors := alternate.connectors
+	alternate.lock.Unlock()
+	for _, connector := range connectors {
+		connector.Shutdown()
+		<-connector.doneChan
+	}
+}
+
+// enableAlternateHost is called when the client handshake is done, i.e., when the STARTUP request, the credentials
+// and the prepared statements that have to be replayed on the alternate host connections are known.
+func (ch *ClientHandler) enableAlternateHost() {
+	for _, connector := range []*ClusterConnector{ch.originCassandraConnector, ch.targetCassandraConnector} {
+		if connector.alternateHost == nil {
+			continue
+		}
+		clusterType := connector.clusterType
+		controlConn := ch.originControlConn
+		if clusterType == common.ClusterTypeTarget {
+			controlConn = ch.targetControlConn
+		}
+		connector.alternateHost.lock.Lock()
+		connector.alternateHost.controlConn = controlConn
+		connector.alternateHost.handshake = func(conn net.Conn, timeout time.Duration) error {
+			return ch.replayHandshake(conn, clusterType, timeout)
+		}
+		connector.alternateHost.lock.Unlock()
+	}
+}
@@ -27,7 +27,7 @@ type AstraMetadata struct {
 const AstraMetadataHttpTimeout = 30 * time.Second
 
 func retrieveAstraMetadata(astraMetadataServiceHostName string, astraMetadataServicePort string,
-	astraTlsConfig *tls.Config, ctx context.Context) (*AstraMetadata, error) {
+	astraTlsConfig *tls.Config, dialer Dialer, logger *log.Entry, ctx context.Context) (*AstraMetadata, error) {
 	var metadata *AstraMetadata
 	// create an HTTP Client using TLS to point to the metadata service
 	//targetMetadataServiceUrl := "https://" + astraMetadataServiceHostName + ":" + astraMetadataServicePort + "/metadata"
@@ -35,6 +35,7 @@ func retrieveAstraMetadata(astraMetadataServiceHostName string, astraMetadataSer
 	httpsClient := &http.Client{
 		Transport: &http.Transport{
 			TLSClientConfig: astraTlsConfig,
+			DialContext:     dialer.DialContext,
 		},
 		Timeout: AstraMetadataHttpTimeout,
 	}
@@ -42,18 +43,18 @@ func retrieveAstraMetadata(astraMetadataServiceHostName string, astraMetadataSer
 	// Issue HTTPS request (client.Get("/metadata")) to MetadataService to discover contact points (Stargates).
 	req, err := http.NewRequestWithContext(ctx, "GET", targetMetadataServiceUrl, nil)
 	if err != nil {
-		log.Errorf("Failed to create metadata HTTP request to %v due to %v", targetMetadataServiceUrl, err)
+		logger.Errorf("Failed to create metadata HTTP request to %v due to %v", targetMetadataServiceUrl, err)
 		return nil, err
 	}
 
 	metadataResponse, err := httpsClient.Do(req)
 	if err != nil {
-		log.Errorf("Failed to retrieve the target metadata information from %s due to %v", targetMetadataServiceUrl, err)
+		logger.Errorf("Failed to retrieve the target metadata information from %s due to %v", targetMetadataServiceUrl, err)
 		return nil, err
 	}
 
 	metadataBody, err := ioutil.ReadAll(metadataResponse.Body)
-	log.Debugf("Metadata JSON: %s", string(metadataBody))
+	logger.Debugf("Metadata JSON: %s", string(metadataBody))
 
 	if metadataResponse.StatusCode < 200 || metadataResponse.StatusCode >= 300 {
 		return nil, fmt.Errorf("metadata service (Astra) returned not successful status code %d, body: %v",
@@ -0,0 +1,166 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/audit"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"strings"
+)
+
+// getAuditCategory returns the category of a CQL statement based on its first keywords, or AuditCategoryUndefined
+// for statements that are not audited (e.g. USE).
+func getAuditCategory(query string) common.AuditCategory {
+	keywords := leadingKeywords(query, 2)
+	if len(keywords) == 0 {
+		return common.AuditCategoryUndefined
+	}
+	switch keywords[0] {
+	case "SELECT":
+		return common.AuditCategorySelect
+	case "INSERT", "UPDATE", "DELETE", "BEGIN":
+		return common.AuditCategoryDml
+	case "GRANT", "REVOKE", "LIST":
+		return common.AuditCategoryDcl
+	case "CREATE", "ALTER", "DROP":
+		if len(keywords) == 2 && (keywords[1] == "ROLE" || keywords[1] == "USER") {
+			return common.AuditCategoryDcl
+		}
+		return common.AuditCategoryDdl
+	case "TRUNCATE":
+		return common.AuditCategoryDdl
+	default:
+		return common.AuditCategoryUndefined
+	}
+}
+
+// auditRequest appends an event to the audit log if the request is a QUERY, EXECUTE or BATCH of an audited category
+// that was forwarded to at least one cluster.
+func (ch *ClientHandler) auditRequest(reqCtx *requestContextImpl) {
+	var clusters []common.ClusterType
+	switch reqCtx.requestInfo.GetForwardDecision() {
+	case forwardToBoth:
+		clusters = []common.ClusterType{common.ClusterTypeOrigin, common.ClusterTypeTarget}
+	case forwardToOrigin:
+		clusters = []common.ClusterType{common.ClusterTypeOrigin}
+	case forwardToTarget:
+		clusters = []common.ClusterType{common.ClusterTypeTarget}
+	default:
+		return
+	}
+
+	operation, category, statement, err := ch.getAuditStatement(reqCtx)
+	if err != nil {
+		reqCtx.logger.Warnf("Could not decode %v request for the audit log: %v", reqCtx.request.Header.OpCode, err)
+		return
+	}
+	if category == common.AuditCategoryUndefined || !ch.auditLog.IsAudited(category) {
+		return
+	}
+
+	event := &audit.Event{
+		Timestamp:     reqCtx.startTime,
+		ClientAddress: ch.clientConnector.connection.RemoteAddr().String(),
+		Username:      ch.clientUsername,
+		Category:      category.String(),
+		Operation:     operation,
+		Keyspace:      reqCtx.keyspace,
+		Clusters:      make([]*audit.ClusterOutcome, 0, 2),
+	}
+	if ch.auditLog.IncludeStatements() {
+		event.Statement = getLogRedactor().redactStatement(statement)
+	}
+	for _, cluster := range clusters {
+		response := reqCtx.originResponse
+		if cluster == common.ClusterTypeTarget {
+			response = reqCtx.targetResponse
+		}
+		event.Clusters = append(event.Clusters, getAuditClusterOutcome(cluster, response))
+	}
+	if _, skipped := reqCtx.requestInfo.(*targetSkippedRequestInfo); skipped {
+		event.Clusters = append(event.Clusters, &audit.ClusterOutcome{
+			Cluster: string(common.ClusterTypeTarget),
+			Outcome: audit.OutcomeSkipped,
+		})
+	}
+
+	if !ch.auditLog.Append(event) {
+		reqCtx.logger.Debugf("Could not append %v to the audit log.", reqCtx.request.Header)
+	}
+}
+
+// getAuditStatement returns the operation, category and statement of a QUERY, EXECUTE or BATCH request, the
+// statements of a BATCH are separated by semicolons.
+func (ch *ClientHandler) getAuditStatement(reqCtx *requestContextImpl) (string, common.AuditCategory, string, error) {
+	requestInfo := unwrapRequestInfo(reqCtx.requestInfo)
+	switch reqCtx.request.Header.OpCode {
+	case primitive.OpCodeQuery:
+		decodedRequest, err := defaultCodec.ConvertFromRawFrame(reqCtx.request)
+		if err != nil {
+			return "", common.AuditCategoryUndefined, "", err
+		}
+		query, ok := decodedRequest.Body.Message.(*message.Query)
+		if !ok {
+			return "", common.AuditCategoryUndefined, "", nil
+		}
+		return "QUERY", getAuditCategory(query.Query), query.Query, nil
+	case primitive.OpCodeExecute:
+		executeRequestInfo, ok := requestInfo.(*ExecuteRequestInfo)
+		if !ok {
+			return "", common.AuditCategoryUndefined, "", nil
+		}
+		query := executeRequestInfo.GetPreparedData().GetPrepareRequestInfo().GetQuery()
+		return "EXECUTE", getAuditCategory(query), query, nil
+	case primitive.OpCodeBatch:
+		if !ch.auditLog.IncludeStatements() {
+			return "BATCH", common.AuditCategoryDml, "", nil
+		}
+		statement, err := getAuditBatchStatement(reqCtx.request, requestInfo)
+		return "BATCH", common.AuditCategoryDml, statement, err
+	default:
+		return "", common.AuditCategoryUndefined, "", nil
+	}
+}
+
+func getAuditBatchStatement(request *frame.RawFrame, requestInfo RequestInfo) (string, error) {
+	decodedRequest, err := defaultCodec.ConvertFromRawFrame(request)
+	if err != nil {
+		return "", err
+	}
+	batch, ok := decodedRequest.Body.Message.(*message.Batch)
+	if !ok {
+		return "", nil
+	}
+	var preparedDataByStmtIdx map[int]PreparedData
+	if batchRequestInfo, ok := requestInfo.(*BatchRequestInfo); ok {
+		preparedDataByStmtIdx = batchRequestInfo.GetPreparedDataByStmtIdx()
+	}
+	statements := make([]string, 0, len(batch.Children))
+	for idx, child := range batch.Children {
+		if query, ok := child.QueryOrId.(string); ok {
+			statements = append(statements, query)
+		} else if preparedData, ok := preparedDataByStmtIdx[idx]; ok {
+			statements = append(statements, preparedData.GetPrepareRequestInfo().GetQuery())
+		}
+	}
+	return strings.Join(statements, "; "), nil
+}
+
+func getAuditClusterOutcome(cluster common.ClusterType, response *frame.RawFrame) *audit.ClusterOutcome {
+	outcome := &audit.ClusterOutcome{Cluster: string(cluster)}
+	switch {
+	case response == nil:
+		outcome.Outcome = audit.OutcomeTimeout
+	case isResponseSuccessful(response):
+		outcome.Outcome = audit.OutcomeSuccess
+	default:
+		outcome.Outcome = audit.OutcomeError
+		if decodedResponse, err := defaultCodec.ConvertFromRawFrame(response); err == nil {
+			if errorResponse, ok := decodedResponse.Body.Message.(message.Error); ok {
+				outcome.Error = errorResponse.GetErrorMessage()
+			}
+		}
+	}
+	return outcome
+}
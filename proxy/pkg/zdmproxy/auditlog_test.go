@@ -0,0 +1,34 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestGetAuditCategory(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected common.AuditCategory
+	}{
+		{"SELECT * FROM ks.tb", common.AuditCategorySelect},
+		{"insert into ks.tb (a) values (1)", common.AuditCategoryDml},
+		{"/* app */ UPDATE ks.tb SET b = 1 WHERE a = 1", common.AuditCategoryDml},
+		{"DELETE FROM ks.tb WHERE a = 1", common.AuditCategoryDml},
+		{"BEGIN BATCH INSERT INTO ks.tb (a) VALUES (1); APPLY BATCH", common.AuditCategoryDml},
+		{"CREATE TABLE ks.tb (a int PRIMARY KEY)", common.AuditCategoryDdl},
+		{"-- drop\nDROP KEYSPACE ks", common.AuditCategoryDdl},
+		{"TRUNCATE ks.tb", common.AuditCategoryDdl},
+		{"CREATE ROLE app WITH PASSWORD = 'secret'", common.AuditCategoryDcl},
+		{"alter user app with password 'secret'", common.AuditCategoryDcl},
+		{"GRANT SELECT ON KEYSPACE ks TO app", common.AuditCategoryDcl},
+		{"LIST ROLES", common.AuditCategoryDcl},
+		{"USE ks", common.AuditCategoryUndefined},
+		{"", common.AuditCategoryUndefined},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			require.Equal(t, tt.expected, getAuditCategory(tt.query))
+		})
+	}
+}
@@ -2,6 +2,7 @@ package zdmproxy
 
 import (
 	"bytes"
+	"crypto/subtle"
 	"fmt"
 	"github.com/datastax/go-cassandra-native-protocol/frame"
 	"github.com/datastax/go-cassandra-native-protocol/message"
@@ -45,6 +46,10 @@ type AuthCredentials struct {
 	Password string
 }
 
+// CredentialsSupplier returns the credentials to use for a new connection to a cluster. They can change over time
+// when the credentials are read from a secrets backend.
+type CredentialsSupplier func() *AuthCredentials
+
 func (c *AuthCredentials) String() string {
 	return fmt.Sprintf("AuthCredentials{username: %v}", c.Username)
 }
@@ -90,6 +95,11 @@ type DsePlainTextAuthenticator struct {
 	Credentials *AuthCredentials
 }
 
+const (
+	dseAuthenticatorClass      = "com.datastax.bdp.cassandra.auth.DseAuthenticator"
+	passwordAuthenticatorClass = "org.apache.cassandra.auth.PasswordAuthenticator"
+)
+
 var (
 	expectedChallenge = []byte("PLAIN-START")
 	mechanism         = []byte("PLAIN")
@@ -97,9 +107,9 @@ var (
 
 func (a *DsePlainTextAuthenticator) InitialResponse(authenticator string) ([]byte, error) {
 	switch authenticator {
-	case "com.datastax.bdp.cassandra.auth.DseAuthenticator":
+	case dseAuthenticatorClass:
 		return mechanism, nil
-	case "org.apache.cassandra.auth.PasswordAuthenticator":
+	case passwordAuthenticatorClass:
 		return a.Credentials.Marshal(), nil
 	}
 	return nil, fmt.Errorf("unknown authenticator: %v", authenticator)
@@ -112,6 +122,20 @@ func (a *DsePlainTextAuthenticator) EvaluateChallenge(challenge []byte) ([]byte,
 	return a.Credentials.Marshal(), nil
 }
 
+// isOpaqueSaslExchange returns true if the tokens that the client sends to the given authenticator don't contain
+// plain-text credentials, e.g. Kerberos (GSSAPI) through DseAuthenticator. Tokens sent to custom authenticators
+// are only considered plain-text credentials if they have the authzid\0username\0password layout.
+func isOpaqueSaslExchange(authenticator string, saslMechanism string, token []byte) bool {
+	switch authenticator {
+	case "", passwordAuthenticatorClass:
+		return false
+	case dseAuthenticatorClass:
+		return saslMechanism != "" && saslMechanism != string(mechanism)
+	default:
+		return bytes.Count(token, []byte{0}) != 2
+	}
+}
+
 // ParseCredentialsFromRequest can return nil in both credsInToken and err in case the request does not contain credentials
 func ParseCredentialsFromRequest(token []byte) (credsInToken *AuthCredentials, err error) {
 	if token == nil || bytes.Compare(token, mechanism) == 0 {
@@ -126,3 +150,14 @@ func ParseCredentialsFromRequest(token []byte) (credsInToken *AuthCredentials, e
 
 	return authCreds, nil
 }
+
+// Matches returns true if the provided credentials have the same username and password as the current ones.
+// The comparison takes constant time so that it doesn't leak how much of the password was correct.
+func (c *AuthCredentials) Matches(provided *AuthCredentials) bool {
+	if provided == nil {
+		return false
+	}
+	usernameMatches := subtle.ConstantTimeCompare([]byte(c.Username), []byte(provided.Username)) == 1
+	passwordMatches := subtle.ConstantTimeCompare([]byte(c.Password), []byte(provided.Password)) == 1
+	return usernameMatches && passwordMatches
+}
@@ -0,0 +1,32 @@
+package zdmproxy
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestIsOpaqueSaslExchange(t *testing.T) {
+	plainTextToken := (&AuthCredentials{Username: "cassandra", Password: "cassandra"}).Marshal()
+	gssapiToken := []byte{0x60, 0x82, 0x02, 0x00, 0x06, 0x09, 0x00, 0x2a, 0x86, 0x00}
+
+	tests := []struct {
+		name          string
+		authenticator string
+		mechanism     string
+		token         []byte
+		expected      bool
+	}{
+		{"password authenticator", passwordAuthenticatorClass, "", plainTextToken, false},
+		{"dse authenticator mechanism selection", dseAuthenticatorClass, "", []byte("GSSAPI"), false},
+		{"dse authenticator plain", dseAuthenticatorClass, "PLAIN", plainTextToken, false},
+		{"dse authenticator gssapi", dseAuthenticatorClass, "GSSAPI", gssapiToken, true},
+		{"custom authenticator plain-text token", "com.example.auth.TokenAuthenticator", "", plainTextToken, false},
+		{"custom authenticator binary token", "com.example.auth.KerberosAuthenticator", "", gssapiToken, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, isOpaqueSaslExchange(tt.authenticator, tt.mechanism, tt.token))
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"sync"
+)
+
+// bufferPool reuses the buffers in which frames are encoded before they are written on a connection and the buffers
+// of compressed bodies.
+//
+// Only buffers that are not referenced after the write can be pooled: the bodies of the frames read from a connection
+// are kept by the request contexts (retries, speculative executions, aggregated responses...) so they are not pooled.
+type bufferPool struct {
+	pool          sync.Pool
+	maxBufferSize int
+	hits          metrics.Counter
+	misses        metrics.Counter
+}
+
+// newBufferPool returns a pool that keeps the buffers up to maxBufferSize bytes, larger buffers are left to the GC
+// so that a single large result set doesn't pin its memory. Buffers are never pooled if maxBufferSize is 0.
+func newBufferPool(maxBufferSize int, hits metrics.Counter, misses metrics.Counter) *bufferPool {
+	return &bufferPool{
+		maxBufferSize: maxBufferSize,
+		hits:          hits,
+		misses:        misses,
+	}
+}
+
+func (recv *bufferPool) Get() *bytes.Buffer {
+	if buf, ok := recv.pool.Get().(*bytes.Buffer); ok {
+		recv.hits.Add(1)
+		return buf
+	}
+	recv.misses.Add(1)
+	return bytes.NewBuffer(make([]byte, 0, initialBufferSize))
+}
+
+func (recv *bufferPool) Put(buf *bytes.Buffer) {
+	if buf == nil || buf.Cap() > recv.maxBufferSize {
+		return
+	}
+	buf.Reset()
+	recv.pool.Put(buf)
+}
@@ -0,0 +1,75 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+)
+
+type countingCounter struct {
+	value int32
+}
+
+func (recv *countingCounter) Add(valueToAdd int) {
+	atomic.AddInt32(&recv.value, int32(valueToAdd))
+}
+
+func TestBufferPool(t *testing.T) {
+	hits := &countingCounter{}
+	misses := &countingCounter{}
+	pool := newBufferPool(4096, hits, misses)
+
+	buf := pool.Get()
+	require.Equal(t, 0, buf.Len())
+	require.Equal(t, int32(1), misses.value)
+	buf.WriteString("CQL")
+	pool.Put(buf)
+
+	// the buffer is reset before it is reused, sync.Pool can also drop it so a miss is valid too
+	reused := pool.Get()
+	require.Equal(t, 0, reused.Len())
+	require.Equal(t, int32(2), hits.value+misses.value)
+	pool.Put(reused)
+
+	// buffers larger than the max size are not pooled
+	large := bytes.NewBuffer(make([]byte, 0, 8192))
+	pool.Put(large)
+	for i := 0; i < 10; i++ {
+		require.NotSame(t, large, pool.Get())
+	}
+
+	disabled := newBufferPool(0, &countingCounter{}, &countingCounter{})
+	buf = disabled.Get()
+	disabled.Put(buf)
+	require.NotSame(t, buf, disabled.Get())
+}
+
+// BenchmarkWriteRawFrame encodes a large response in a pooled write buffer like the write coalescer does.
+func BenchmarkWriteRawFrame(b *testing.B) {
+	rows := message.RowSet{}
+	for i := 0; i < 1000; i++ {
+		rows = append(rows, message.Row{make([]byte, 100)})
+	}
+	response, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 1, &message.RowsResult{
+		Metadata: &message.RowsMetadata{ColumnCount: 1},
+		Data:     rows,
+	}))
+	require.Nil(b, err)
+	pool := newBufferPool(1024*1024, newFakeCounter(), newFakeCounter())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf := pool.Get()
+		err = writeRawFrame(buf, "", context.Background(), response)
+		if err != nil {
+			b.Fatal(err)
+		}
+		pool.Put(buf)
+	}
+}
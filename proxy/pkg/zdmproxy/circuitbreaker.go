@@ -0,0 +1,231 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"sync"
+	"time"
+)
+
+type CircuitBreakerState string
+
+const (
+	CircuitBreakerClosed   = CircuitBreakerState("CLOSED")
+	CircuitBreakerOpen     = CircuitBreakerState("OPEN")
+	CircuitBreakerHalfOpen = CircuitBreakerState("HALF_OPEN")
+)
+
+type CircuitBreakerOverride string
+
+const (
+	CircuitBreakerOverrideAuto        = CircuitBreakerOverride("AUTO")
+	CircuitBreakerOverrideForceOpen   = CircuitBreakerOverride("FORCE_OPEN")
+	CircuitBreakerOverrideForceClosed = CircuitBreakerOverride("FORCE_CLOSED")
+)
+
+func ParseCircuitBreakerOverride(value string) (CircuitBreakerOverride, error) {
+	switch override := CircuitBreakerOverride(strings.ToUpper(strings.TrimSpace(value))); override {
+	case CircuitBreakerOverrideAuto, CircuitBreakerOverrideForceOpen, CircuitBreakerOverrideForceClosed:
+		return override, nil
+	default:
+		return "", fmt.Errorf("invalid circuit breaker override %v; possible values are: %v, %v and %v",
+			value, CircuitBreakerOverrideAuto, CircuitBreakerOverrideForceOpen, CircuitBreakerOverrideForceClosed)
+	}
+}
+
+type CircuitBreakerStatus struct {
+	State    CircuitBreakerState    `json:"state"`
+	Override CircuitBreakerOverride `json:"override"`
+	Requests int                    `json:"window_requests"`
+	Failures int                    `json:"window_failures"`
+}
+
+// CircuitBreaker decides whether writes should still be duplicated to the secondary cluster.
+//
+// The results of the requests are counted in fixed windows. The circuit opens when, within a window, at least
+// MinRequests requests were tracked and the percentage of failures reaches ErrorRatePercent. While the circuit is open
+// every request is skipped until the cooldown expires, then the circuit becomes half open and lets HalfOpenProbes
+// requests through: a failure opens the circuit again and HalfOpenProbes successes close it.
+//
+// The override set by operators takes precedence over the state computed from the request results.
+type CircuitBreaker struct {
+	config *common.CircuitBreakerConfig
+	now    func() time.Time
+	logger *log.Entry
+
+	lock           *sync.Mutex
+	state          CircuitBreakerState
+	override       CircuitBreakerOverride
+	windowStart    time.Time
+	requests       int
+	failures       int
+	stateChangedAt time.Time
+	probesAllowed  int
+	probeSuccesses int
+}
+
+func NewCircuitBreaker(config *common.CircuitBreakerConfig, logger *log.Entry) *CircuitBreaker {
+	return newCircuitBreaker(config, time.Now, logger)
+}
+
+func newCircuitBreaker(config *common.CircuitBreakerConfig, now func() time.Time, logger *log.Entry) *CircuitBreaker {
+	return &CircuitBreaker{
+		config:      config,
+		now:         now,
+		logger:      logger,
+		lock:        &sync.Mutex{},
+		state:       CircuitBreakerClosed,
+		override:    CircuitBreakerOverrideAuto,
+		windowStart: now(),
+	}
+}
+
+// Allow returns false if the request should not be sent to the secondary cluster.
+func (recv *CircuitBreaker) Allow() bool {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	switch recv.override {
+	case CircuitBreakerOverrideForceOpen:
+		return false
+	case CircuitBreakerOverrideForceClosed:
+		return true
+	}
+
+	switch recv.state {
+	case CircuitBreakerOpen:
+		if recv.now().Sub(recv.stateChangedAt) < recv.config.Cooldown {
+			return false
+		}
+		recv.transition(CircuitBreakerHalfOpen)
+		fallthrough
+	case CircuitBreakerHalfOpen:
+		if recv.probesAllowed >= recv.config.HalfOpenProbes {
+			// probes that fail on both clusters are not recorded so don't wait forever for their results
+			if recv.now().Sub(recv.stateChangedAt) >= recv.config.Cooldown {
+				recv.transition(CircuitBreakerOpen)
+			}
+			return false
+		}
+		recv.probesAllowed++
+		return true
+	default:
+		return true
+	}
+}
+
+// RecordResult tracks the result of a request that was sent to the secondary cluster.
+func (recv *CircuitBreaker) RecordResult(success bool) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	if recv.override != CircuitBreakerOverrideAuto {
+		return
+	}
+
+	switch recv.state {
+	case CircuitBreakerHalfOpen:
+		if !success {
+			recv.transition(CircuitBreakerOpen)
+			return
+		}
+		recv.probeSuccesses++
+		if recv.probeSuccesses >= recv.config.HalfOpenProbes {
+			recv.transition(CircuitBreakerClosed)
+		}
+	case CircuitBreakerClosed:
+		now := recv.now()
+		if now.Sub(recv.windowStart) >= recv.config.Window {
+			recv.resetWindow(now)
+		}
+		recv.requests++
+		if !success {
+			recv.failures++
+		}
+		if recv.requests >= recv.config.MinRequests &&
+			recv.failures*100 >= recv.config.ErrorRatePercent*recv.requests {
+			recv.transition(CircuitBreakerOpen)
+		}
+	}
+}
+
+func (recv *CircuitBreaker) GetStatus() CircuitBreakerStatus {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return CircuitBreakerStatus{
+		State:    recv.effectiveState(),
+		Override: recv.override,
+		Requests: recv.requests,
+		Failures: recv.failures,
+	}
+}
+
+// IsOpen returns true if requests are currently being skipped, i.e. the circuit is open or half open.
+func (recv *CircuitBreaker) IsOpen() bool {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.effectiveState() != CircuitBreakerClosed
+}
+
+// SetOverride forces the circuit open or closed regardless of the request results. Setting
+// CircuitBreakerOverrideAuto restores the automatic behavior starting with a closed circuit.
+func (recv *CircuitBreaker) SetOverride(override CircuitBreakerOverride) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if recv.override == override {
+		return
+	}
+	recv.logger.Warnf("TARGET circuit breaker override changed from %v to %v.", recv.override, override)
+	recv.override = override
+	if override == CircuitBreakerOverrideAuto {
+		recv.transition(CircuitBreakerClosed)
+	}
+}
+
+func (recv *CircuitBreaker) effectiveState() CircuitBreakerState {
+	switch recv.override {
+	case CircuitBreakerOverrideForceOpen:
+		return CircuitBreakerOpen
+	case CircuitBreakerOverrideForceClosed:
+		return CircuitBreakerClosed
+	default:
+		return recv.state
+	}
+}
+
+func (recv *CircuitBreaker) resetWindow(now time.Time) {
+	recv.windowStart = now
+	recv.requests = 0
+	recv.failures = 0
+}
+
+// transition must be called while holding the lock.
+func (recv *CircuitBreaker) transition(newState CircuitBreakerState) {
+	oldState := recv.state
+	recv.state = newState
+	recv.probesAllowed = 0
+	recv.probeSuccesses = 0
+	now := recv.now()
+	recv.stateChangedAt = now
+
+	switch newState {
+	case CircuitBreakerOpen:
+		if oldState == CircuitBreakerHalfOpen {
+			recv.logger.Warnf("TARGET circuit breaker probes did not succeed, writes will not be sent to TARGET for another %v.",
+				recv.config.Cooldown)
+		} else {
+			recv.logger.Warnf("TARGET circuit breaker opened after %v failures out of %v requests, "+
+				"writes will not be sent to TARGET for %v.", recv.failures, recv.requests, recv.config.Cooldown)
+		}
+	case CircuitBreakerHalfOpen:
+		recv.logger.Infof("TARGET circuit breaker is half open, sending up to %v writes to TARGET as probes.",
+			recv.config.HalfOpenProbes)
+	case CircuitBreakerClosed:
+		if oldState != CircuitBreakerClosed {
+			recv.logger.Infof("TARGET circuit breaker closed, writes are sent to TARGET again.")
+		}
+	}
+	recv.resetWindow(now)
+}
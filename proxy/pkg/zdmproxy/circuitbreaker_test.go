@@ -0,0 +1,132 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (recv *fakeClock) Now() time.Time {
+	return recv.now
+}
+
+func (recv *fakeClock) Advance(d time.Duration) {
+	recv.now = recv.now.Add(d)
+}
+
+func newTestCircuitBreaker() (*CircuitBreaker, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	return newCircuitBreaker(&common.CircuitBreakerConfig{
+		ErrorRatePercent: 50,
+		MinRequests:      4,
+		Window:           10 * time.Second,
+		Cooldown:         30 * time.Second,
+		HalfOpenProbes:   2,
+	}, clock.Now, log.NewEntry(log.StandardLogger())), clock
+}
+
+func TestCircuitBreaker_OpensAfterErrorRate(t *testing.T) {
+	circuitBreaker, _ := newTestCircuitBreaker()
+
+	circuitBreaker.RecordResult(false)
+	circuitBreaker.RecordResult(false)
+	circuitBreaker.RecordResult(false)
+	require.True(t, circuitBreaker.Allow(), "minimum number of requests not reached yet")
+
+	circuitBreaker.RecordResult(true)
+	require.False(t, circuitBreaker.Allow())
+	require.Equal(t, CircuitBreakerOpen, circuitBreaker.GetStatus().State)
+}
+
+func TestCircuitBreaker_WindowReset(t *testing.T) {
+	circuitBreaker, clock := newTestCircuitBreaker()
+
+	circuitBreaker.RecordResult(false)
+	circuitBreaker.RecordResult(false)
+	circuitBreaker.RecordResult(true)
+	clock.Advance(10 * time.Second)
+	circuitBreaker.RecordResult(false)
+	circuitBreaker.RecordResult(true)
+	circuitBreaker.RecordResult(true)
+	circuitBreaker.RecordResult(true)
+
+	require.True(t, circuitBreaker.Allow())
+	status := circuitBreaker.GetStatus()
+	require.Equal(t, CircuitBreakerClosed, status.State)
+	require.Equal(t, 4, status.Requests)
+	require.Equal(t, 1, status.Failures)
+}
+
+func TestCircuitBreaker_HalfOpen(t *testing.T) {
+	circuitBreaker, clock := newTestCircuitBreaker()
+	for i := 0; i < 4; i++ {
+		circuitBreaker.RecordResult(false)
+	}
+	require.False(t, circuitBreaker.Allow())
+
+	clock.Advance(30 * time.Second)
+	require.True(t, circuitBreaker.Allow())
+	require.True(t, circuitBreaker.Allow())
+	require.False(t, circuitBreaker.Allow(), "only 2 probes are allowed")
+	require.Equal(t, CircuitBreakerHalfOpen, circuitBreaker.GetStatus().State)
+
+	circuitBreaker.RecordResult(true)
+	circuitBreaker.RecordResult(false)
+	require.Equal(t, CircuitBreakerOpen, circuitBreaker.GetStatus().State)
+	require.False(t, circuitBreaker.Allow())
+
+	clock.Advance(30 * time.Second)
+	require.True(t, circuitBreaker.Allow())
+	require.True(t, circuitBreaker.Allow())
+	circuitBreaker.RecordResult(true)
+	circuitBreaker.RecordResult(true)
+	require.Equal(t, CircuitBreakerClosed, circuitBreaker.GetStatus().State)
+	require.True(t, circuitBreaker.Allow())
+}
+
+func TestCircuitBreaker_HalfOpenWithoutProbeResults(t *testing.T) {
+	circuitBreaker, clock := newTestCircuitBreaker()
+	for i := 0; i < 4; i++ {
+		circuitBreaker.RecordResult(false)
+	}
+
+	clock.Advance(30 * time.Second)
+	require.True(t, circuitBreaker.Allow())
+	require.True(t, circuitBreaker.Allow())
+
+	clock.Advance(30 * time.Second)
+	require.False(t, circuitBreaker.Allow())
+	require.Equal(t, CircuitBreakerOpen, circuitBreaker.GetStatus().State)
+}
+
+func TestCircuitBreaker_Override(t *testing.T) {
+	circuitBreaker, _ := newTestCircuitBreaker()
+
+	circuitBreaker.SetOverride(CircuitBreakerOverrideForceOpen)
+	require.False(t, circuitBreaker.Allow())
+	require.True(t, circuitBreaker.IsOpen())
+
+	circuitBreaker.SetOverride(CircuitBreakerOverrideForceClosed)
+	for i := 0; i < 10; i++ {
+		circuitBreaker.RecordResult(false)
+	}
+	require.True(t, circuitBreaker.Allow())
+	require.False(t, circuitBreaker.IsOpen())
+
+	circuitBreaker.SetOverride(CircuitBreakerOverrideAuto)
+	require.Equal(t, CircuitBreakerStatus{
+		State:    CircuitBreakerClosed,
+		Override: CircuitBreakerOverrideAuto,
+	}, circuitBreaker.GetStatus())
+
+	_, err := ParseCircuitBreakerOverride("force_open")
+	require.Nil(t, err)
+	_, err = ParseCircuitBreakerOverride("sometimes")
+	require.NotNil(t, err)
+}
@@ -8,10 +8,12 @@ import (
 	"github.com/datastax/go-cassandra-native-protocol/message"
 	"github.com/datastax/go-cassandra-native-protocol/primitive"
 	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
 	log "github.com/sirupsen/logrus"
 	"net"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 const ClientConnectorLogPrefix = "CLIENT-CONNECTOR"
@@ -48,8 +50,20 @@ type ClientConnector struct {
 	clientConnectorRequestsDoneChan chan bool
 
 	readScheduler *Scheduler
+	readShard     int
 
 	shutdownRequestCtx context.Context
+
+	// *frameCompressor, set when the client negotiates compression in its STARTUP request
+	compressor *atomic.Value
+
+	// see closeWhenIdle, the connection is never closed for being idle if idleTimeout is 0
+	idleTimeout      time.Duration
+	lastRequestNanos *int64
+	proxyMetrics     *metrics.ProxyMetrics
+
+	// logger with the fields of the client connection
+	logger *log.Entry
 }
 
 func NewClientConnector(
@@ -64,8 +78,12 @@ func NewClientConnector(
 	eventsDoneChan <-chan bool,
 	readScheduler *Scheduler,
 	writeScheduler *Scheduler,
+	writeBufferPool *bufferPool,
 	shutdownRequestCtx context.Context,
-	clientHandlerShutdownRequestCancelFn context.CancelFunc) *ClientConnector {
+	clientHandlerShutdownRequestCancelFn context.CancelFunc,
+	proxyMetrics *metrics.ProxyMetrics,
+	logger *log.Entry) *ClientConnector {
+	lastRequestNanos := time.Now().UnixNano()
 	return &ClientConnector{
 		connection:              connection,
 		conf:                    conf,
@@ -82,14 +100,22 @@ func NewClientConnector(
 			ClientConnectorLogPrefix,
 			false,
 			false,
-			writeScheduler),
+			writeScheduler,
+			writeBufferPool,
+			logger),
 		responsesDoneChan:                    responsesDoneChan,
 		requestsDoneCtx:                      requestsDoneCtx,
 		eventsDoneChan:                       eventsDoneChan,
 		clientConnectorRequestsDoneChan:      make(chan bool, 1),
 		readScheduler:                        readScheduler,
+		readShard:                            readScheduler.NextShard(),
 		shutdownRequestCtx:                   shutdownRequestCtx,
 		clientHandlerShutdownRequestCancelFn: clientHandlerShutdownRequestCancelFn,
+		compressor:                           &atomic.Value{},
+		idleTimeout:                          time.Duration(conf.ProxyClientIdleTimeoutMs) * time.Millisecond,
+		lastRequestNanos:                     &lastRequestNanos,
+		proxyMetrics:                         proxyMetrics,
+		logger:                               logger,
 	}
 }
 
@@ -99,6 +125,7 @@ func NewClientConnector(
 func (cc *ClientConnector) run(activeClients *int32) {
 	cc.listenForRequests()
 	cc.writeCoalescer.RunWriteQueueLoop()
+	cc.closeWhenIdle()
 	cc.clientHandlerWg.Add(1)
 	go func() {
 		defer cc.clientHandlerWg.Done()
@@ -106,19 +133,19 @@ func (cc *ClientConnector) run(activeClients *int32) {
 		<-cc.requestsDoneCtx.Done()
 		<-cc.eventsDoneChan
 
-		log.Debugf("[%s] All in flight requests are done, requesting cluster connections of client handler %v "+
+		cc.logger.Debugf("[%s] All in flight requests are done, requesting cluster connections of client handler %v "+
 			"to be terminated.", ClientConnectorLogPrefix, cc.connection.RemoteAddr())
 		cc.clientHandlerCancelFunc()
 
-		log.Infof("[%s] Shutting down client connection to %v", ClientConnectorLogPrefix, cc.connection.RemoteAddr())
+		cc.logger.Infof("[%s] Shutting down client connection to %v", ClientConnectorLogPrefix, cc.connection.RemoteAddr())
 		err := cc.connection.Close()
 		if err != nil {
-			log.Warnf("[%s] Error received while closing connection to %v: %v", ClientConnectorLogPrefix, cc.connection.RemoteAddr(), err)
+			cc.logger.Warnf("[%s] Error received while closing connection to %v: %v", ClientConnectorLogPrefix, cc.connection.RemoteAddr(), err)
 		}
 
-		log.Debugf("[%s] Waiting until request listener is done.", ClientConnectorLogPrefix)
+		cc.logger.Debugf("[%s] Waiting until request listener is done.", ClientConnectorLogPrefix)
 		<-cc.clientConnectorRequestsDoneChan
-		log.Debugf("[%s] Shutting down write coalescer.", ClientConnectorLogPrefix)
+		cc.logger.Debugf("[%s] Shutting down write coalescer.", ClientConnectorLogPrefix)
 		cc.writeCoalescer.Close()
 
 		atomic.AddInt32(activeClients, -1)
@@ -127,7 +154,7 @@ func (cc *ClientConnector) run(activeClients *int32) {
 
 func (cc *ClientConnector) listenForRequests() {
 
-	log.Tracef("[%s] listenForRequests for client %v", ClientConnectorLogPrefix, cc.connection.RemoteAddr())
+	cc.logger.Tracef("[%s] listenForRequests for client %v", ClientConnectorLogPrefix, cc.connection.RemoteAddr())
 
 	cc.clientHandlerWg.Add(1)
 	go func() {
@@ -136,7 +163,7 @@ func (cc *ClientConnector) listenForRequests() {
 
 		wg := &sync.WaitGroup{}
 		defer wg.Wait()
-		defer log.Debugf("[%s] Shutting down request listener, waiting until request listener tasks are done...", ClientConnectorLogPrefix)
+		defer cc.logger.Debugf("[%s] Shutting down request listener, waiting until request listener tasks are done...", ClientConnectorLogPrefix)
 
 		lock := &sync.RWMutex{}
 		closed := false
@@ -156,7 +183,7 @@ func (cc *ClientConnector) listenForRequests() {
 			select {
 			case <-cc.clientHandlerContext.Done():
 			case <-cc.shutdownRequestCtx.Done():
-				log.Debugf("[%s] Entering \"draining\" mode of request listener %v", ClientConnectorLogPrefix, cc.connection.RemoteAddr())
+				cc.logger.Debugf("[%s] Entering \"draining\" mode of request listener %v", ClientConnectorLogPrefix, cc.connection.RemoteAddr())
 			}
 
 			setDrainModeNowFunc()
@@ -166,12 +193,17 @@ func (cc *ClientConnector) listenForRequests() {
 		connectionAddr := cc.connection.RemoteAddr().String()
 		protocolErrOccurred := false
 		for cc.clientHandlerContext.Err() == nil {
-			f, err := readRawFrame(bufferedReader, connectionAddr, cc.clientHandlerContext)
+			f, err := readRawFrame(bufferedReader, connectionAddr, cc.clientHandlerContext, cc.conf.ProxyMaxRequestFrameSizeBytes)
+			if tooLargeErr, ok := err.(*frameTooLargeError); ok {
+				cc.rejectOversizedRequest(tooLargeErr)
+				continue
+			}
 
-			protocolErrResponseFrame, err := checkProtocolError(f, err, protocolErrOccurred, ClientConnectorLogPrefix)
+			protocolErrResponseFrame, err := checkProtocolError(f, err, protocolErrOccurred, ClientConnectorLogPrefix, cc.logger)
 			if err != nil {
 				handleConnectionError(
-					err, cc.clientHandlerContext, cc.clientHandlerCancelFunc, ClientConnectorLogPrefix, "reading", connectionAddr)
+					err, cc.clientHandlerContext, cc.clientHandlerCancelFunc, ClientConnectorLogPrefix, "reading", connectionAddr,
+					cc.logger)
 				break
 			} else if protocolErrResponseFrame != nil {
 				f = protocolErrResponseFrame
@@ -184,10 +216,23 @@ func (cc *ClientConnector) listenForRequests() {
 				}
 			}
 
+			if cc.idleTimeout > 0 {
+				atomic.StoreInt64(cc.lastRequestNanos, time.Now().UnixNano())
+			}
+
 			wg.Add(1)
-			cc.readScheduler.Schedule(func() {
+			cc.readScheduler.ScheduleOnShard(cc.readShard, func() {
 				defer wg.Done()
-				log.Tracef("[%s] Received request on client connector: %v", ClientConnectorLogPrefix, f.Header)
+				cc.logger.Tracef("[%s] Received request on client connector: %v", ClientConnectorLogPrefix, f.Header)
+				if compressor := cc.getCompressor(); compressor != nil {
+					decompressed, err := compressor.decompress(f)
+					if err != nil {
+						cc.logger.Errorf("[%s] Could not decompress request %v: %v", ClientConnectorLogPrefix, f.Header, err)
+						cc.sendProtocolErrorToClient(f, err.Error())
+						return
+					}
+					f = decompressed
+				}
 				lock.RLock()
 				if closed {
 					lock.RUnlock()
@@ -196,12 +241,72 @@ func (cc *ClientConnector) listenForRequests() {
 				}
 				cc.requestChannel <- f
 				lock.RUnlock()
-				log.Tracef("[%s] Request sent to client connector's request channel: %v", ClientConnectorLogPrefix, f.Header)
+				cc.logger.Tracef("[%s] Request sent to client connector's request channel: %v", ClientConnectorLogPrefix, f.Header)
 			})
 		}
 	}()
 }
 
+// closeWhenIdle closes the client connection if the client doesn't send any request for
+// ZDM_PROXY_CLIENT_IDLE_TIMEOUT_MS. Drivers send heartbeats on idle connections so this only closes connections that
+// were leaked or abandoned by the client. The connection is closed like it is when the proxy shuts down, i.e. the
+// requests that are still in flight are completed first.
+func (cc *ClientConnector) closeWhenIdle() {
+	if cc.idleTimeout <= 0 {
+		return
+	}
+
+	cc.clientHandlerWg.Add(1)
+	go func() {
+		defer cc.clientHandlerWg.Done()
+		for {
+			idle := time.Since(time.Unix(0, atomic.LoadInt64(cc.lastRequestNanos)))
+			if idle >= cc.idleTimeout {
+				cc.logger.Infof("[%s] Closing client connection %v because no request was received for %v.",
+					ClientConnectorLogPrefix, cc.connection.RemoteAddr(), cc.idleTimeout)
+				cc.proxyMetrics.IdleClientConnectionsClosed.Add(1)
+				cc.clientHandlerShutdownRequestCancelFn()
+				return
+			}
+
+			timer := time.NewTimer(cc.idleTimeout - idle)
+			select {
+			case <-timer.C:
+			case <-cc.clientHandlerContext.Done():
+				timer.Stop()
+				return
+			case <-cc.shutdownRequestCtx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// setCompressor enables compression on the client connection, requests are decompressed as soon as they are read
+// and responses are compressed when they are written.
+func (cc *ClientConnector) setCompressor(compressor *frameCompressor) {
+	cc.compressor.Store(compressor)
+	cc.writeCoalescer.setCompressor(compressor)
+}
+
+func (cc *ClientConnector) getCompressor() *frameCompressor {
+	compressor, _ := cc.compressor.Load().(*frameCompressor)
+	return compressor
+}
+
+func (cc *ClientConnector) sendProtocolErrorToClient(request *frame.RawFrame, errorMessage string) {
+	response := frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.ProtocolError{
+		ErrorMessage: errorMessage,
+	})
+	rawResponse, err := defaultCodec.ConvertToRawFrame(response)
+	if err != nil {
+		cc.logger.Errorf("[%s] Could not convert frame (%v) to raw frame: %v", ClientConnectorLogPrefix, response, err)
+	} else {
+		cc.sendResponseToClient(rawResponse)
+	}
+}
+
 func (cc *ClientConnector) sendOverloadedToClient(request *frame.RawFrame) {
 	msg := &message.Overloaded{
 		ErrorMessage: "Shutting down, please retry on next host.",
@@ -209,13 +314,37 @@ func (cc *ClientConnector) sendOverloadedToClient(request *frame.RawFrame) {
 	response := frame.NewFrame(request.Header.Version, request.Header.StreamId, msg)
 	rawResponse, err := defaultCodec.ConvertToRawFrame(response)
 	if err != nil {
-		log.Errorf("[%s] Could not convert frame (%v) to raw frame: %v", ClientConnectorLogPrefix, response, err)
+		cc.logger.Errorf("[%s] Could not convert frame (%v) to raw frame: %v", ClientConnectorLogPrefix, response, err)
+	} else {
+		cc.sendResponseToClient(rawResponse)
+	}
+}
+
+// rejectOversizedRequest returns a PROTOCOL_ERROR on the stream of a request that was discarded because its body
+// exceeded ZDM_PROXY_MAX_REQUEST_FRAME_SIZE_BYTES, the client connection is kept open.
+func (cc *ClientConnector) rejectOversizedRequest(tooLargeErr *frameTooLargeError) {
+	cc.proxyMetrics.OversizedRequestFrames.Add(1)
+	cc.logger.Warnf("[%s] Rejecting request from %v: %v.", ClientConnectorLogPrefix, cc.connection.RemoteAddr(), tooLargeErr)
+
+	version := tooLargeErr.header.Version
+	if checkProtocolVersion(version) != nil {
+		version = primitive.ProtocolVersion4
+	}
+	msg := &message.ProtocolError{
+		ErrorMessage: fmt.Sprintf("Request body of %d bytes exceeds the maximum of %d bytes allowed by the proxy",
+			tooLargeErr.header.BodyLength, tooLargeErr.maxBodySize),
+	}
+	response := frame.NewFrame(version, tooLargeErr.header.StreamId, msg)
+	rawResponse, err := defaultCodec.ConvertToRawFrame(response)
+	if err != nil {
+		cc.logger.Errorf("[%s] Could not convert frame (%v) to raw frame: %v", ClientConnectorLogPrefix, response, err)
 	} else {
 		cc.sendResponseToClient(rawResponse)
 	}
 }
 
-func checkProtocolError(f *frame.RawFrame, connErr error, protocolErrorOccurred bool, prefix string) (protocolErrResponse *frame.RawFrame, fatalErr error) {
+func checkProtocolError(f *frame.RawFrame, connErr error, protocolErrorOccurred bool, prefix string,
+	logger *log.Entry) (protocolErrResponse *frame.RawFrame, fatalErr error) {
 	var protocolErrMsg *message.ProtocolError
 	var streamId int16
 	var logMsg string
@@ -231,7 +360,7 @@ func checkProtocolError(f *frame.RawFrame, connErr error, protocolErrorOccurred
 
 	if protocolErrMsg != nil {
 		if !protocolErrorOccurred {
-			log.Debugf("[%v] %v Returning a protocol error to the client to force a downgrade: %v.", prefix, logMsg, protocolErrMsg)
+			logger.Debugf("[%v] %v Returning a protocol error to the client to force a downgrade: %v.", prefix, logMsg, protocolErrMsg)
 		}
 		rawProtocolErrResponse, err := generateProtocolErrorResponseFrame(streamId, protocolErrMsg)
 		if err != nil {
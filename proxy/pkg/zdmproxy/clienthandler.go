@@ -9,8 +9,10 @@ import (
 	"github.com/datastax/go-cassandra-native-protocol/frame"
 	"github.com/datastax/go-cassandra-native-protocol/message"
 	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/audit"
 	"github.com/datastax/zdm-proxy/proxy/pkg/common"
 	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/journal"
 	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
@@ -41,6 +43,18 @@ type ClientHandler struct {
 	targetCassandraConnector *ClusterConnector
 	asyncConnector           *ClusterConnector
 
+	// asyncConnector is also created when only shadow mode is enabled, reads are mirrored to it only when
+	// asyncReadsEnabled is true (ZDM_READ_MODE=DUAL_ASYNC_ON_SECONDARY)
+	asyncReadsEnabled bool
+	shadowModeEnabled bool
+
+	// false in the ORIGIN_DECOMMISSIONED migration phase, writes are then only sent to TARGET
+	originWritesEnabled bool
+
+	// the migration phase of the connection, nil tracker unless ZDM_MIGRATION_PHASE is set
+	migrationPhase        common.MigrationPhase
+	migrationPhaseTracker *migrationPhaseTracker
+
 	originControlConn *ControlConn
 	targetControlConn *ControlConn
 
@@ -49,6 +63,9 @@ type ClientHandler struct {
 	metricHandler *metrics.MetricHandler
 	nodeMetrics   *metrics.NodeMetrics
 
+	// applicationMetrics is set when the STARTUP request is received, nil if per-application metrics are disabled
+	applicationMetrics *metrics.ApplicationMetricsInstance
+
 	clientHandlerContext    context.Context
 	clientHandlerCancelFunc context.CancelFunc
 
@@ -59,6 +76,7 @@ type ClientHandler struct {
 
 	startupRequest           *frame.RawFrame
 	secondaryStartupResponse *frame.RawFrame
+	primaryHandshakeCreds    *AuthCredentials
 	secondaryHandshakeCreds  *AuthCredentials
 	asyncHandshakeCreds      *AuthCredentials
 
@@ -68,6 +86,69 @@ type ClientHandler struct {
 	originUsername string
 	originPassword string
 
+	// validates client credentials in the proxy, nil when client credentials are forwarded to the clusters
+	credentialMapper *CredentialMapper
+
+	// mapping of the client that was authenticated by credentialMapper
+	mappedCredentials *common.CredentialMapping
+
+	// username of the credentials provided by the client, empty if the client didn't authenticate
+	clientUsername string
+
+	// nil unless the TARGET circuit breaker is enabled, shared by all client connections
+	targetCircuitBreaker *CircuitBreaker
+
+	// nil unless the failed writes journal is enabled, shared by all client connections
+	failedWritesJournal *journal.FileJournal
+
+	// nil unless the audit log is enabled, shared by all client connections
+	auditLog *audit.Log
+
+	retryPolicies *RetryPolicies
+
+	requestTimeouts *common.RequestTimeouts
+
+	// nil unless ZDM_PROXY_REQUEST_DEADLINES_ENABLED is true
+	abandonedRequests *abandonedRequests
+
+	// nil unless ZDM_PROXY_INTROSPECTION_ENABLED is true, shared by all client connections
+	introspectionTables *IntrospectionTables
+
+	// nil unless ZDM_DEBUG_ENDPOINT_ENABLED is true, shared by all client connections
+	diagnostics *diagnostics
+
+	// nil unless ZDM_QUERY_REWRITE_RULES_FILE is set, shared by all client connections
+	queryRewriter *queryRewriter
+
+	// nil unless ZDM_TARGET_NAME_MAPPING is set, shared by all client connections
+	targetNameMapper *targetNameMapper
+
+	// nil unless ZDM_ORIGIN_CONSISTENCY_OVERRIDE or ZDM_TARGET_CONSISTENCY_OVERRIDE is set
+	consistencyOverrides *consistencyOverrides
+
+	// nil unless ZDM_PROXY_ANSWER_OPTIONS is true
+	optionsResponder *optionsResponder
+
+	// nil unless request interceptors are registered, shared by all client connections
+	interceptors *interceptorChain
+
+	// only used by the event listener goroutine
+	eventForwarder *eventForwarder
+
+	// 0 unless speculative reads are enabled, in which case asyncConnector is not nil
+	speculativeReadThreshold time.Duration
+
+	originReadResponses *readResponseTracker
+	targetReadResponses *readResponseTracker
+
+	// true when the proxy sent its own AUTHENTICATE to the client because neither cluster asked for credentials
+	proxyAuthPending bool
+
+	// authenticator class advertised by the cluster that handles the client's handshake and, for DseAuthenticator,
+	// the SASL mechanism selected by the client
+	primaryAuthenticator string
+	clientSaslMechanism  string
+
 	// map of request context holders that store the contexts for the active requests, keyed on streamID
 	requestContextHolders *sync.Map
 
@@ -91,6 +172,7 @@ type ClientHandler struct {
 	requestsDoneCancelFn context.CancelFunc
 
 	requestResponseScheduler  *Scheduler
+	requestResponseShard      int
 	clientConnectorScheduler  *Scheduler
 	clusterConnectorScheduler *Scheduler
 
@@ -107,8 +189,18 @@ type ClientHandler struct {
 
 	primaryCluster               common.ClusterType
 	forwardSystemQueriesToTarget bool
-	forwardAuthToTarget          bool
-	targetCredsOnClientRequest   bool
+
+	// false if the proxy topology isn't virtualized or if the client matches ZDM_SYSTEM_QUERIES_BYPASS_CLIENTS or
+	// ZDM_SYSTEM_QUERIES_BYPASS_APPLICATIONS, the system.local and system.peers queries are then sent to the cluster
+	interceptSystemQueries     bool
+	systemQueriesBypass        *common.SystemQueriesBypass
+	lwtPolicy                  common.LwtPolicy
+	counterWritePolicy         common.CounterWritePolicy
+	ddlPolicy                  common.DdlPolicy
+	tableRouting               *common.TableRouting
+	responsePropagation        *responsePropagation
+	forwardAuthToTarget        bool
+	targetCredsOnClientRequest bool
 
 	queryModifier     *QueryModifier
 	parameterModifier *ParameterModifier
@@ -116,6 +208,14 @@ type ClientHandler struct {
 
 	clientHandlerShutdownRequestCancelFn context.CancelFunc
 	clientHandlerShutdownRequestContext  context.Context
+
+	// identifies the client connection in the logs, every log line emitted for this connection has the
+	// connection_id field and, with the JSON log format or the DEBUG log level, the log lines of each request also
+	// have a request_id field
+	connectionId     string
+	logger           *log.Entry
+	jsonLogFormat    bool
+	requestIdCounter uint64
 }
 
 func NewClientHandler(
@@ -136,19 +236,47 @@ func NewClientHandler(
 	requestResponseScheduler *Scheduler,
 	readScheduler *Scheduler,
 	writeScheduler *Scheduler,
+	writeBufferPool *bufferPool,
 	numWorkers int,
 	globalShutdownRequestCtx context.Context,
 	originHost *Host,
 	targetHost *Host,
 	timeUuidGenerator TimeUuidGenerator,
-	readMode common.ReadMode,
-	primaryCluster common.ClusterType,
-	systemQueriesMode common.SystemQueriesMode) (*ClientHandler, error) {
+	connectionSettings *clientConnectionSettings,
+	migrationPhaseTracker *migrationPhaseTracker,
+	systemQueriesMode common.SystemQueriesMode,
+	systemQueriesBypass *common.SystemQueriesBypass,
+	lwtPolicy common.LwtPolicy,
+	counterWritePolicy common.CounterWritePolicy,
+	ddlPolicy common.DdlPolicy,
+	tableRouting *common.TableRouting,
+	eventSourcePolicy common.EventSourcePolicy,
+	warningsPolicy common.PropagationPolicy,
+	customPayloadPolicy common.PropagationPolicy,
+	credentialMapper *CredentialMapper,
+	targetCircuitBreaker *CircuitBreaker,
+	failedWritesJournal *journal.FileJournal,
+	auditLog *audit.Log,
+	retryPolicies *RetryPolicies,
+	requestTimeouts *common.RequestTimeouts,
+	introspectionTables *IntrospectionTables,
+	diagnostics *diagnostics,
+	queryRewriter *queryRewriter,
+	targetNameMapper *targetNameMapper,
+	consistencyOverrides *consistencyOverrides,
+	optionsResponder *optionsResponder,
+	originFaultInjector *faultInjector,
+	targetFaultInjector *faultInjector,
+	interceptors *interceptorChain,
+	logger *log.Entry) (*ClientHandler, error) {
+
+	readMode := connectionSettings.readMode
+	primaryCluster := connectionSettings.primaryCluster
 
 	originEndpointId := originCassandraConnInfo.endpoint.GetEndpointIdentifier()
 	targetEndpointId := targetCassandraConnInfo.endpoint.GetEndpointIdentifier()
 	asyncEndpointId := ""
-	if readMode == common.ReadModeDualAsyncOnSecondary {
+	if readMode == common.ReadModeDualAsyncOnSecondary || connectionSettings.shadowModeEnabled {
 		if primaryCluster == common.ClusterTypeTarget {
 			asyncEndpointId = originEndpointId
 		} else {
@@ -161,6 +289,12 @@ func NewClientHandler(
 		return nil, fmt.Errorf("failed to create node metrics: %w", err)
 	}
 
+	connectionId := uuid.New().String()
+	logger = logger.WithFields(log.Fields{
+		"connection_id": connectionId,
+		"client":        clientTcpConn.RemoteAddr().String(),
+	})
+
 	clientHandlerContext, clientHandlerCancelFunc := context.WithCancel(context.Background())
 	clientHandlerShutdownRequestContext, clientHandlerShutdownRequestCancelFn := context.WithCancel(globalShutdownRequestCtx)
 	requestsDoneCtx, requestsDoneCancelFn := context.WithCancel(context.Background())
@@ -173,46 +307,73 @@ func NewClientHandler(
 		clientHandlerShutdownRequestCancelFn()
 		localClientHandlerWg.Wait()
 		requestsDoneCancelFn() // make sure this ctx is not leaked but it should be canceled before this
-		log.Debugf("Client Handler is shutdown.")
+		logger.Debugf("Client Handler is shutdown.")
 	}()
 
+	interceptSystemQueries := topologyConfig.VirtualizationEnabled
+	if interceptSystemQueries && systemQueriesBypass != nil {
+		if tcpAddr, ok := clientTcpConn.RemoteAddr().(*net.TCPAddr); ok && systemQueriesBypass.MatchesClientAddress(tcpAddr.IP) {
+			logger.Infof("System queries of client %v are not intercepted (ZDM_SYSTEM_QUERIES_BYPASS_CLIENTS).",
+				tcpAddr.IP)
+			interceptSystemQueries = false
+		}
+	}
+
 	respChannel := make(chan *Response, numWorkers)
 	clientHandlerRequestWg := &sync.WaitGroup{}
 	handshakeDone := &atomic.Value{}
 
+	proxyMetrics := metricHandler.GetProxyMetrics()
+	originReadResponses := newReadResponseTracker(
+		string(common.ClusterTypeOrigin), proxyMetrics.ReadResponseRowsOrigin, proxyMetrics.ReadResponseBytesOrigin,
+		conf.ProxyLargeResponseWarningThresholdBytes)
+	targetReadResponses := newReadResponseTracker(
+		string(common.ClusterTypeTarget), proxyMetrics.ReadResponseRowsTarget, proxyMetrics.ReadResponseBytesTarget,
+		conf.ProxyLargeResponseWarningThresholdBytes)
+
 	originConnector, err := NewClusterConnector(
-		originCassandraConnInfo, conf, psCache, nodeMetrics, localClientHandlerWg, clientHandlerRequestWg,
-		clientHandlerContext, clientHandlerCancelFunc, respChannel, readScheduler, writeScheduler, requestsDoneCtx,
-		false, nil, handshakeDone)
+		originCassandraConnInfo, conf, psCache, nodeMetrics, proxyMetrics.OversizedResponseFrames, nil, proxyMetrics.ResponseWarningsOrigin, localClientHandlerWg, clientHandlerRequestWg,
+		clientHandlerContext, clientHandlerCancelFunc, respChannel, readScheduler, writeScheduler, writeBufferPool, requestsDoneCtx,
+		false, nil, handshakeDone, originFaultInjector, logger)
 	if err != nil {
 		clientHandlerCancelFunc()
 		return nil, err
 	}
 
 	targetConnector, err := NewClusterConnector(
-		targetCassandraConnInfo, conf, psCache, nodeMetrics, localClientHandlerWg, clientHandlerRequestWg,
-		clientHandlerContext, clientHandlerCancelFunc, respChannel, readScheduler, writeScheduler, requestsDoneCtx,
-		false, nil, handshakeDone)
+		targetCassandraConnInfo, conf, psCache, nodeMetrics, proxyMetrics.OversizedResponseFrames, nil, proxyMetrics.ResponseWarningsTarget, localClientHandlerWg, clientHandlerRequestWg,
+		clientHandlerContext, clientHandlerCancelFunc, respChannel, readScheduler, writeScheduler, writeBufferPool, requestsDoneCtx,
+		false, nil, handshakeDone, targetFaultInjector, logger)
 	if err != nil {
 		clientHandlerCancelFunc()
 		return nil, err
 	}
 
-	asyncPendingRequests := newPendingRequests(MaxStreams, nodeMetrics)
+	asyncPendingRequests := newPendingRequests(conf.AsyncConnectorMaxStreamIds, nodeMetrics, logger)
 	var asyncConnector *ClusterConnector
-	if readMode == common.ReadModeDualAsyncOnSecondary {
+	if readMode == common.ReadModeDualAsyncOnSecondary || connectionSettings.shadowModeEnabled {
 		var asyncConnInfo *ClusterConnectionInfo
+		var asyncFaultInjector *faultInjector
 		if primaryCluster == common.ClusterTypeTarget {
 			asyncConnInfo = originCassandraConnInfo
+			asyncFaultInjector = originFaultInjector
 		} else {
 			asyncConnInfo = targetCassandraConnInfo
+			asyncFaultInjector = targetFaultInjector
+		}
+		asyncReadResponses := newReadResponseTracker(
+			fmt.Sprintf("%v (async)", asyncConnInfo.connConfig.GetClusterType()), proxyMetrics.ReadResponseRowsAsync,
+			proxyMetrics.ReadResponseBytesAsync, conf.ProxyLargeResponseWarningThresholdBytes)
+		asyncResponseWarnings := proxyMetrics.ResponseWarningsTarget
+		if asyncConnInfo.isOriginCassandra {
+			asyncResponseWarnings = proxyMetrics.ResponseWarningsOrigin
 		}
 		asyncConnector, err = NewClusterConnector(
-			asyncConnInfo, conf, psCache, nodeMetrics, localClientHandlerWg, clientHandlerRequestWg,
-			clientHandlerContext, clientHandlerCancelFunc, respChannel, readScheduler, writeScheduler, requestsDoneCtx,
-			true, asyncPendingRequests, handshakeDone)
+			asyncConnInfo, conf, psCache, nodeMetrics, proxyMetrics.OversizedResponseFrames, asyncReadResponses, asyncResponseWarnings, localClientHandlerWg, clientHandlerRequestWg,
+			clientHandlerContext, clientHandlerCancelFunc, respChannel, readScheduler, writeScheduler, writeBufferPool, requestsDoneCtx,
+			true, asyncPendingRequests, handshakeDone, asyncFaultInjector, logger)
 		if err != nil {
-			log.Errorf("Could not create async cluster connector to %s, async requests will not be forwarded: %s", asyncConnInfo.connConfig.GetClusterType(), err.Error())
+			logger.Errorf("Could not create async cluster connector to %s, async requests will not be forwarded: %s", asyncConnInfo.connConfig.GetClusterType(), err.Error())
 			asyncConnector = nil
 		}
 	}
@@ -223,14 +384,31 @@ func NewClientHandler(
 
 	var originObserver, targetObserver *protocolEventObserverImpl
 	if originHost != nil {
-		originObserver = NewProtocolEventObserver(clientHandlerShutdownRequestCancelFn, originHost)
+		originObserver = NewProtocolEventObserver(clientHandlerShutdownRequestCancelFn, originHost, logger)
 	}
 	if targetHost != nil {
-		targetObserver = NewProtocolEventObserver(clientHandlerShutdownRequestCancelFn, targetHost)
+		targetObserver = NewProtocolEventObserver(clientHandlerShutdownRequestCancelFn, targetHost, logger)
+	}
+
+	speculativeReadThreshold := time.Duration(conf.SpeculativeReadThresholdMs) * time.Millisecond
+
+	var virtualizationControlConn *ControlConn
+	if topologyConfig.VirtualizationEnabled {
+		virtualizationControlConn = originControlConn
+		if systemQueriesMode == common.SystemQueriesModeTarget {
+			virtualizationControlConn = targetControlConn
+		}
 	}
+	eventForwarder := newEventForwarder(eventSourcePolicy, primaryCluster,
+		time.Duration(conf.EventDedupWindowMs)*time.Millisecond, virtualizationControlConn, conf.ProxyListenPort, logger)
 
 	forwardAuthToTarget, targetCredsOnClientRequest := forwardAuthToTarget(
-		originControlConn, targetControlConn, conf.ForwardClientCredentialsToOrigin)
+		originControlConn, targetControlConn, conf.ForwardClientCredentialsToOrigin, logger)
+
+	var abandoned *abandonedRequests
+	if conf.ProxyRequestDeadlinesEnabled {
+		abandoned = newAbandonedRequests(clientHandlerRequestWg)
+	}
 
 	return &ClientHandler{
 		clientConnector: NewClientConnector(
@@ -245,10 +423,18 @@ func NewClientHandler(
 			eventsDoneChan,
 			readScheduler,
 			writeScheduler,
+			writeBufferPool,
 			clientHandlerShutdownRequestContext,
-			clientHandlerShutdownRequestCancelFn),
+			clientHandlerShutdownRequestCancelFn,
+			metricHandler.GetProxyMetrics(),
+			logger),
 
 		asyncConnector:                       asyncConnector,
+		asyncReadsEnabled:                    readMode == common.ReadModeDualAsyncOnSecondary,
+		shadowModeEnabled:                    connectionSettings.shadowModeEnabled,
+		originWritesEnabled:                  connectionSettings.originWritesEnabled,
+		migrationPhase:                       connectionSettings.migrationPhase,
+		migrationPhaseTracker:                migrationPhaseTracker,
 		originCassandraConnector:             originConnector,
 		targetCassandraConnector:             targetConnector,
 		originControlConn:                    originControlConn,
@@ -266,6 +452,26 @@ func NewClientHandler(
 		targetPassword:                       targetPassword,
 		originUsername:                       originUsername,
 		originPassword:                       originPassword,
+		credentialMapper:                     credentialMapper,
+		mappedCredentials:                    nil,
+		targetCircuitBreaker:                 targetCircuitBreaker,
+		failedWritesJournal:                  failedWritesJournal,
+		auditLog:                             auditLog,
+		retryPolicies:                        retryPolicies,
+		requestTimeouts:                      requestTimeouts,
+		abandonedRequests:                    abandoned,
+		introspectionTables:                  introspectionTables,
+		diagnostics:                          diagnostics,
+		queryRewriter:                        queryRewriter,
+		targetNameMapper:                     targetNameMapper,
+		consistencyOverrides:                 consistencyOverrides,
+		optionsResponder:                     optionsResponder,
+		interceptors:                         interceptors,
+		speculativeReadThreshold:             speculativeReadThreshold,
+		originReadResponses:                  originReadResponses,
+		targetReadResponses:                  targetReadResponses,
+		eventForwarder:                       eventForwarder,
+		proxyAuthPending:                     false,
 		requestContextHolders:                &sync.Map{},
 		asyncRequestContextHolders:           &sync.Map{},
 		asyncPendingRequests:                 asyncPendingRequests,
@@ -278,6 +484,7 @@ func NewClientHandler(
 		eventsDoneChan:                       eventsDoneChan,
 		requestsDoneCancelFn:                 requestsDoneCancelFn,
 		requestResponseScheduler:             requestResponseScheduler,
+		requestResponseShard:                 requestResponseScheduler.NextShard(),
 		conf:                                 conf,
 		localClientHandlerWg:                 localClientHandlerWg,
 		topologyConfig:                       topologyConfig,
@@ -287,6 +494,13 @@ func NewClientHandler(
 		targetObserver:                       targetObserver,
 		primaryCluster:                       primaryCluster,
 		forwardSystemQueriesToTarget:         systemQueriesMode == common.SystemQueriesModeTarget,
+		interceptSystemQueries:               interceptSystemQueries,
+		systemQueriesBypass:                  systemQueriesBypass,
+		lwtPolicy:                            lwtPolicy,
+		counterWritePolicy:                   counterWritePolicy,
+		ddlPolicy:                            ddlPolicy,
+		tableRouting:                         tableRouting,
+		responsePropagation:                  newResponsePropagation(warningsPolicy, customPayloadPolicy, primaryCluster),
 		forwardAuthToTarget:                  forwardAuthToTarget,
 		targetCredsOnClientRequest:           targetCredsOnClientRequest,
 		queryModifier:                        NewQueryModifier(timeUuidGenerator),
@@ -294,6 +508,10 @@ func NewClientHandler(
 		timeUuidGenerator:                    timeUuidGenerator,
 		clientHandlerShutdownRequestCancelFn: clientHandlerShutdownRequestCancelFn,
 		clientHandlerShutdownRequestContext:  clientHandlerShutdownRequestContext,
+		connectionId:                         connectionId,
+		logger:                               logger,
+		jsonLogFormat:                        isJsonLogFormat(logger),
+		requestIdCounter:                     0,
 	}, nil
 }
 
@@ -302,6 +520,8 @@ func NewClientHandler(
  */
 func (ch *ClientHandler) run(activeClients *int32) {
 	ch.clientConnector.run(activeClients)
+	ch.originCassandraConnector.rejectedResponses = ch.sendRejectedResponse
+	ch.targetCassandraConnector.rejectedResponses = ch.sendRejectedResponse
 	ch.originCassandraConnector.run()
 	ch.targetCassandraConnector.run()
 	if ch.asyncConnector != nil {
@@ -313,6 +533,12 @@ func (ch *ClientHandler) run(activeClients *int32) {
 
 	addObserver(ch.originObserver, ch.originControlConn)
 	addObserver(ch.targetObserver, ch.targetControlConn)
+	if ch.introspectionTables != nil {
+		ch.introspectionTables.addClient(ch)
+	}
+	if ch.diagnostics != nil {
+		ch.diagnostics.addClient(ch)
+	}
 
 	go func() {
 		<-ch.originCassandraConnector.doneChan
@@ -327,6 +553,21 @@ func (ch *ClientHandler) run(activeClients *int32) {
 
 		removeObserver(ch.originObserver, ch.originControlConn)
 		removeObserver(ch.targetObserver, ch.targetControlConn)
+		if ch.introspectionTables != nil {
+			ch.introspectionTables.removeClient(ch)
+		}
+		if ch.diagnostics != nil {
+			ch.diagnostics.removeClient(ch)
+		}
+		if ch.migrationPhaseTracker != nil {
+			ch.migrationPhaseTracker.closeClientConnection(ch.migrationPhase)
+		}
+		if ch.originHost != nil {
+			ch.originControlConn.ReleaseAssignedHost(ch.originHost)
+		}
+		if ch.targetHost != nil {
+			ch.targetControlConn.ReleaseAssignedHost(ch.targetHost)
+		}
 	}()
 }
 
@@ -355,19 +596,24 @@ func (ch *ClientHandler) requestLoop() {
 	ready := false
 	var err error
 	ch.localClientHandlerWg.Add(1)
-	log.Debugf("requestLoop starting now")
+	ch.logger.Debugf("requestLoop starting now")
 	go func() {
 		defer ch.localClientHandlerWg.Done()
 		connectionAddr := ch.clientConnector.connection.RemoteAddr().String()
-		defer log.Debugf("Client Handler request loop %v shutdown.", connectionAddr)
+		defer ch.logger.Debugf("Client Handler request loop %v shutdown.", connectionAddr)
 		defer ch.requestsDoneCancelFn()
+		defer ch.releaseApplicationMetrics()
 		defer ch.originCassandraConnector.writeCoalescer.Close()
-		defer log.Debugf("Waiting for origin write coalescer to finish...")
+		defer ch.logger.Debugf("Waiting for origin write coalescer to finish...")
 		defer ch.targetCassandraConnector.writeCoalescer.Close()
-		defer log.Debugf("Waiting for target write coalescer to finish...")
+		defer ch.logger.Debugf("Waiting for target write coalescer to finish...")
+		defer ch.originCassandraConnector.closeStreamIdOverflowConnectors()
+		defer ch.targetCassandraConnector.closeStreamIdOverflowConnectors()
+		defer ch.originCassandraConnector.closeAlternateHostConnectors()
+		defer ch.targetCassandraConnector.closeAlternateHostConnectors()
 		if ch.asyncConnector != nil {
 			defer ch.asyncConnector.writeCoalescer.Close()
-			defer log.Debugf("Waiting for async %s write coalescer to finish...", ch.asyncConnector.clusterType)
+			defer ch.logger.Debugf("Waiting for async %s write coalescer to finish...", ch.asyncConnector.clusterType)
 		}
 
 		wg := &sync.WaitGroup{}
@@ -382,30 +628,41 @@ func (ch *ClientHandler) requestLoop() {
 				continue
 			}
 
-			log.Tracef("Request received on client handler: %v", f.Header)
+			ch.logger.Tracef("Request received on client handler: %v", f.Header)
+			if !ready && ch.conf.ProtocolVersionTranslationEnabled {
+				ch.negotiateProtocolVersion(f.Header.Version)
+			}
+			if f.Header.OpCode == primitive.OpCodeOptions && ch.answerOptions(f) {
+				continue
+			}
+
 			if !ready {
-				log.Tracef("not ready")
+				ch.logger.Tracef("not ready")
 				// Handle client authentication
 				ready, err = ch.handleHandshakeRequest(f, wg)
 				if err != nil && !errors.Is(err, ShutdownErr) {
-					log.Error(err)
+					ch.logger.Error(err)
 				}
 				if ready {
 					ch.handshakeDone.Store(true)
-					log.Infof(
+					ch.enableRequestConnectionFailover()
+					ch.startHeartbeats(f.Header.Version)
+					ch.enableStreamIdOverflow()
+					ch.enableAlternateHost()
+					ch.logger.Infof(
 						"Handshake successful with client %s", connectionAddr)
 				}
-				log.Tracef("ready? %t", ready)
+				ch.logger.Tracef("ready? %t", ready)
 			} else {
 				wg.Add(1)
-				ch.requestResponseScheduler.Schedule(func() {
+				ch.requestResponseScheduler.ScheduleOnShard(ch.requestResponseShard, func() {
 					defer wg.Done()
 					ch.handleRequest(f)
 				})
 			}
 		}
 
-		log.Debugf("Shutting down client handler request listener %v.", connectionAddr)
+		ch.logger.Debugf("Shutting down client handler request listener %v.", connectionAddr)
 
 		wg.Wait()
 
@@ -413,11 +670,14 @@ func (ch *ClientHandler) requestLoop() {
 			<-ch.clientHandlerContext.Done()
 			ch.clearRequestContexts(ch.requestContextHolders)
 			ch.clearRequestContexts(ch.asyncRequestContextHolders)
+			if ch.abandonedRequests != nil {
+				ch.abandonedRequests.clear()
+			}
 			if ch.asyncPendingRequests != nil {
 				ch.asyncPendingRequests.clear(func(ctx RequestContext) {
 					typedReqCtx, ok := ctx.(*asyncRequestContextImpl)
 					if !ok {
-						log.Errorf("Failed to cancel async request because request context conversion failed. "+
+						ch.logger.Errorf("Failed to cancel async request because request context conversion failed. "+
 							"This is most likely a bug, please report. AsyncRequestContext: %v", ctx)
 					} else {
 						if !typedReqCtx.expectedResponse {
@@ -428,11 +688,44 @@ func (ch *ClientHandler) requestLoop() {
 			}
 		}()
 
-		log.Debugf("Waiting for all in flight requests from %v to finish.", connectionAddr)
+		ch.logger.Debugf("Waiting for all in flight requests from %v to finish.", connectionAddr)
 		ch.clientHandlerRequestWaitGroup.Wait()
 	}()
 }
 
+// negotiateProtocolVersion sets the protocol version translators of the cluster connectors for the protocol version
+// of the client, see newProtocolVersionTranslator.
+func (ch *ClientHandler) negotiateProtocolVersion(version primitive.ProtocolVersion) {
+	connectors := []*ClusterConnector{ch.originCassandraConnector, ch.targetCassandraConnector}
+	if ch.asyncConnector != nil {
+		connectors = append(connectors, ch.asyncConnector)
+	}
+	for _, connector := range connectors {
+		controlConn := ch.originControlConn
+		if connector.clusterType == common.ClusterTypeTarget {
+			controlConn = ch.targetControlConn
+		}
+		translator := newProtocolVersionTranslator(version, controlConn.GetProtocolVersion())
+		if translator != nil && connector.getProtocolVersionTranslator() == nil {
+			ch.logger.Infof("Translating the frames of %v from protocol version %v to %v.",
+				connector.connectorType, version, translator.clusterVersion)
+		}
+		connector.setProtocolVersionTranslator(translator)
+	}
+}
+
+// startHeartbeats starts the heartbeats of the cluster connectors, see ClusterConnector.startHeartbeats.
+func (ch *ClientHandler) startHeartbeats(version primitive.ProtocolVersion) {
+	ch.originCassandraConnector.startHeartbeats(
+		version, ch.clientHandlerShutdownRequestContext, ch.clientHandlerRequestWaitGroup)
+	ch.targetCassandraConnector.startHeartbeats(
+		version, ch.clientHandlerShutdownRequestContext, ch.clientHandlerRequestWaitGroup)
+	if ch.asyncConnector != nil {
+		ch.asyncConnector.startHeartbeats(
+			version, ch.clientHandlerShutdownRequestContext, ch.clientHandlerRequestWaitGroup)
+	}
+}
+
 func (ch *ClientHandler) clearRequestContexts(contextHoldersMap *sync.Map) {
 	contextHoldersMap.Range(func(key, value interface{}) bool {
 		reqCtxHolder := value.(*requestContextHolder)
@@ -444,7 +737,7 @@ func (ch *ClientHandler) clearRequestContexts(contextHoldersMap *sync.Map) {
 		if canceled {
 			typedReqCtx, ok := reqCtx.(*requestContextImpl)
 			if !ok {
-				log.Errorf("Failed to cancel request because request context conversion failed. "+
+				ch.logger.Errorf("Failed to cancel request because request context conversion failed. "+
 					"This is most likely a bug, please report. RequestContext: %v", reqCtx)
 			} else {
 				ch.cancelRequest(reqCtxHolder, typedReqCtx)
@@ -460,7 +753,7 @@ func (ch *ClientHandler) clearRequestContexts(contextHoldersMap *sync.Map) {
 //   - it's a schema change from origin
 func (ch *ClientHandler) listenForEventMessages() {
 	ch.localClientHandlerWg.Add(1)
-	log.Debugf("listenForEventMessages loop starting now")
+	ch.logger.Debugf("listenForEventMessages loop starting now")
 	go func() {
 		defer ch.localClientHandlerWg.Done()
 		defer close(ch.eventsDoneChan)
@@ -474,71 +767,43 @@ func (ch *ClientHandler) listenForEventMessages() {
 
 			var event *frame.RawFrame
 			var ok bool
-			var fromTarget bool
+			var clusterType common.ClusterType
 
 			//goland:noinspection ALL
 			select {
 			case event, ok = <-targetChannel:
 				if !ok {
-					log.Debugf("Target event channel closed")
+					ch.logger.Debugf("Target event channel closed")
 					shutDownChannels++
 					targetChannel = nil
 					continue
 				}
-				fromTarget = true
+				clusterType = common.ClusterTypeTarget
 			case event, ok = <-originChannel:
 				if !ok {
-					log.Debugf("Origin event channel closed")
+					ch.logger.Debugf("Origin event channel closed")
 					shutDownChannels++
 					originChannel = nil
 					continue
 				}
-				fromTarget = false
+				clusterType = common.ClusterTypeOrigin
 			}
 
-			log.Debugf("Message received (fromTarget: %v) on event listener of the client handler: %v", fromTarget, event.Header)
+			ch.logger.Debugf("Message received from %v on event listener of the client handler: %v", clusterType, event.Header)
 
-			body, err := defaultCodec.DecodeBody(event.Header, bytes.NewReader(event.Body))
+			event, err := ch.eventForwarder.getClientEvent(event, clusterType, time.Now())
 			if err != nil {
-				log.Warnf("Error decoding event response: %v", err)
+				ch.logger.Warnf("Error handling event from %v: %v", clusterType, err)
 				continue
 			}
-
-			switch msgType := body.Message.(type) {
-			case *message.ProtocolError:
-				log.Debug("Received protocol error on event body listener, forwarding to client: ", body.Message)
-			case *message.SchemaChangeEvent:
-				if fromTarget {
-					log.Infof("Received schema change event from target, skipping: %v", msgType)
-					continue
-				}
-			case *message.StatusChangeEvent:
-				if ch.topologyConfig.VirtualizationEnabled {
-					log.Infof("Received status change event (fromTarget=%v) but virtualization is enabled, skipping: %v", fromTarget, msgType)
-					continue
-				}
-				if !fromTarget {
-					log.Infof("Received status change event from origin, skipping: %v", msgType)
-					continue
-				}
-			case *message.TopologyChangeEvent:
-				if ch.topologyConfig.VirtualizationEnabled {
-					log.Infof("Received topology change event (fromTarget=%v) but virtualization is enabled, skipping: %v", fromTarget, msgType)
-					continue
-				}
-				if !fromTarget {
-					log.Infof("Received topology change event from origin, skipping: %v", msgType)
-					continue
-				}
-			default:
-				log.Infof("Expected event body (fromTarget: %v) but got: %v", fromTarget, msgType)
+			if event == nil {
 				continue
 			}
 
 			ch.clientConnector.sendResponseToClient(event)
 		}
 
-		log.Debugf("Shutting down client event messages listener.")
+		ch.logger.Debugf("Shutting down client event messages listener.")
 	}()
 }
 
@@ -546,7 +811,7 @@ func (ch *ClientHandler) listenForEventMessages() {
 // (which is written by both cluster connectors).
 func (ch *ClientHandler) responseLoop() {
 	ch.localClientHandlerWg.Add(1)
-	log.Debugf("responseLoop starting now")
+	ch.logger.Debugf("responseLoop starting now")
 	go func() {
 		defer ch.localClientHandlerWg.Done()
 		defer close(ch.responsesDoneChan)
@@ -563,7 +828,7 @@ func (ch *ClientHandler) responseLoop() {
 			}
 
 			wg.Add(1)
-			ch.requestResponseScheduler.Schedule(func() {
+			ch.requestResponseScheduler.ScheduleOnShard(ch.requestResponseShard, func() {
 				defer wg.Done()
 
 				var responseClusterType common.ClusterType
@@ -582,6 +847,19 @@ func (ch *ClientHandler) responseLoop() {
 					}
 				}
 
+				if response.speculativeReqCtx != nil {
+					ch.handleSpeculativeReadResponse(response, responseClusterType)
+					return
+				}
+
+				if ch.abandonedRequests != nil && response.responseFrame != nil &&
+					response.connectorType != ClusterConnectorTypeAsync &&
+					ch.abandonedRequests.releaseResponse(response.connectorType, response.responseFrame) {
+					ch.logger.Tracef("Discarding late response from %v for stream id %d, its request exceeded "+
+						"its deadline.", response.connectorType, response.responseFrame.Header.StreamId)
+					return
+				}
+
 				streamId := response.GetStreamId()
 				var contextHoldersMap *sync.Map
 				if response.connectorType == ClusterConnectorTypeAsync {
@@ -593,7 +871,7 @@ func (ch *ClientHandler) responseLoop() {
 				reqCtx := holder.Get()
 				if reqCtx == nil {
 					if ch.clientHandlerContext.Err() == nil {
-						log.Warnf("Could not find request context for stream id %d received from %v. "+
+						ch.logger.Warnf("Could not find request context for stream id %d received from %v. "+
 							"It either timed out or a protocol error occurred.", streamId, response.connectorType)
 					}
 					return
@@ -603,16 +881,36 @@ func (ch *ClientHandler) responseLoop() {
 				if response.responseFrame == nil {
 					finished = reqCtx.SetTimeout(ch.nodeMetrics, response.requestFrame)
 				} else {
-					finished = reqCtx.SetResponse(ch.nodeMetrics, response.responseFrame, responseClusterType, response.connectorType)
+					responseFrame := response.responseFrame
+					if typedReqCtx, ok := reqCtx.(*requestContextImpl); ok && response.connectorType != ClusterConnectorTypeAsync {
+						if typedReqCtx.continuousPaging != nil {
+							responseFrame = ch.handleContinuousPage(typedReqCtx, responseFrame)
+							if responseFrame == nil {
+								// the page was buffered or sent to the client, the last page finishes the request
+								return
+							}
+						}
+						responseFrame = ch.handleRePrepare(typedReqCtx, responseFrame, responseClusterType)
+						if responseFrame == nil {
+							// statement is being re-prepared, the request will be finished when the retry completes
+							return
+						}
+						responseFrame = ch.handleRetry(typedReqCtx, responseFrame, responseClusterType)
+						if responseFrame == nil {
+							// the request will be finished when the response of the retry is received
+							return
+						}
+					}
+					finished = reqCtx.SetResponse(ch.nodeMetrics, responseFrame, responseClusterType, response.connectorType)
 					if reqCtx.GetRequestInfo().ShouldBeTrackedInMetrics() {
-						trackClusterErrorMetrics(response.responseFrame, response.connectorType, ch.nodeMetrics)
+						trackClusterErrorMetrics(responseFrame, response.connectorType, ch.nodeMetrics, ch.logger)
 					}
 				}
 
 				if finished {
 					typedReqCtx, ok := reqCtx.(*requestContextImpl)
 					if !ok {
-						log.Errorf("Failed to finish request because request context conversion failed. "+
+						ch.logger.Errorf("Failed to finish request because request context conversion failed. "+
 							"This is most likely a bug, please report. RequestContext: %v", reqCtx)
 					} else {
 						ch.finishRequest(holder, typedReqCtx)
@@ -621,7 +919,7 @@ func (ch *ClientHandler) responseLoop() {
 			})
 		}
 
-		log.Debugf("Shutting down responseLoop.")
+		ch.logger.Debugf("Shutting down responseLoop.")
 	}()
 }
 
@@ -630,16 +928,16 @@ func (ch *ClientHandler) responseLoop() {
 func (ch *ClientHandler) tryProcessProtocolError(response *Response, protocolErrOccurred *int32) bool {
 	errMsg, err := decodeError(response.responseFrame)
 	if err != nil {
-		log.Errorf("Could not check if error from %v was protocol error: %v, skipping it.",
+		ch.logger.Errorf("Could not check if error from %v was protocol error: %v, skipping it.",
 			response.connectorType, response.responseFrame.Header)
 		return false
 	} else if errMsg != nil && errMsg.GetErrorCode() == primitive.ErrorCodeProtocolError {
 		if atomic.CompareAndSwapInt32(protocolErrOccurred, 0, 1) {
 			if ch.handshakeDone.Load() != nil {
-				log.Errorf("[ClientHandler] Protocol error detected (%v) on %v, forwarding it to the client.",
+				ch.logger.Errorf("[ClientHandler] Protocol error detected (%v) on %v, forwarding it to the client.",
 					errMsg, response.connectorType)
 			} else {
-				log.Debugf("[ClientHandler] Protocol version downgrade detected (%v) on %v, forwarding it to the client.",
+				ch.logger.Debugf("[ClientHandler] Protocol version downgrade detected (%v) on %v, forwarding it to the client.",
 					errMsg, response.connectorType)
 			}
 			ch.clientConnector.sendResponseToClient(response.responseFrame)
@@ -677,12 +975,12 @@ func (ch *ClientHandler) finishRequest(holder *requestContextHolder, reqCtx *req
 
 	err := holder.Clear(reqCtx)
 	if err != nil {
-		log.Debugf("Could not free stream id: %v", err)
+		reqCtx.logger.Debugf("Could not free stream id: %v", err)
 	}
 
 	if reqCtx.requestInfo.ShouldBeTrackedInMetrics() {
 		proxyMetrics := ch.metricHandler.GetProxyMetrics()
-		switch reqCtx.requestInfo.GetForwardDecision() {
+		switch getMetricsForwardDecision(reqCtx.requestInfo) {
 		case forwardToBoth:
 			proxyMetrics.ProxyWritesDuration.Track(reqCtx.startTime)
 			proxyMetrics.InFlightWrites.Subtract(1)
@@ -694,11 +992,28 @@ func (ch *ClientHandler) finishRequest(holder *requestContextHolder, reqCtx *req
 			proxyMetrics.InFlightReadsTarget.Subtract(1)
 		case forwardToAsyncOnly, forwardToNone:
 		default:
-			log.Errorf("unexpected forwardDecision %v, unable to track proxy level metrics", reqCtx.requestInfo.GetForwardDecision())
+			reqCtx.logger.Errorf("unexpected forwardDecision %v, unable to track proxy level metrics", reqCtx.requestInfo.GetForwardDecision())
 		}
 	}
 
+	if ch.targetCircuitBreaker != nil && reqCtx.requestInfo.ShouldBeTrackedInMetrics() &&
+		reqCtx.requestInfo.GetForwardDecision() == forwardToBoth {
+		ch.recordTargetCircuitBreakerResult(reqCtx)
+	}
+
+	if ch.failedWritesJournal != nil {
+		ch.journalFailedTargetWrite(reqCtx)
+	}
+
+	if ch.auditLog != nil {
+		ch.auditRequest(reqCtx)
+	}
+
 	aggregatedResponse, responseClusterType, err := ch.computeClientResponse(reqCtx)
+	if err == nil {
+		aggregatedResponse, err = ch.responsePropagation.apply(
+			aggregatedResponse, reqCtx.originResponse, reqCtx.targetResponse)
+	}
 	finalResponse := aggregatedResponse
 	if err == nil && reqCtx.requestInfo.GetForwardDecision() != forwardToAsyncOnly {
 		// async only requests can't have "PREPARED", "SETKEYSPACE" or "UNPREPARED" responses so skip this
@@ -709,7 +1024,7 @@ func (ch *ClientHandler) finishRequest(holder *requestContextHolder, reqCtx *req
 		if reqCtx.customResponseChannel != nil {
 			close(reqCtx.customResponseChannel)
 		}
-		log.Errorf("Error handling request (%v): %v", reqCtx.request.Header, err)
+		reqCtx.logger.Errorf("Error handling request (%v): %v", reqCtx.request.Header, err)
 		return
 	}
 
@@ -719,6 +1034,17 @@ func (ch *ClientHandler) finishRequest(holder *requestContextHolder, reqCtx *req
 	targetResponse := reqCtx.targetResponse
 	reqCtx.targetResponse = nil
 
+	if reqCtx.customResponseChannel == nil && ch.shouldWaitForSchemaAgreement(reqCtx, finalResponse) {
+		// don't block the response workers while polling the schema versions
+		ch.clientHandlerRequestWaitGroup.Add(1)
+		go func() {
+			defer ch.clientHandlerRequestWaitGroup.Done()
+			ch.waitForSchemaAgreement(reqCtx)
+			ch.clientConnector.sendResponseToClient(finalResponse)
+		}()
+		return
+	}
+
 	if reqCtx.customResponseChannel != nil {
 		reqCtx.customResponseChannel <- &customResponse{
 			originResponse:     originResponse,
@@ -730,18 +1056,98 @@ func (ch *ClientHandler) finishRequest(holder *requestContextHolder, reqCtx *req
 	}
 }
 
+// recordTargetCircuitBreakerResult reports a write that was sent to both clusters to the TARGET circuit breaker.
+// A TARGET failure (or timeout) only counts if ORIGIN did not fail as well because in that case the request itself
+// is most likely the problem.
+func (ch *ClientHandler) recordTargetCircuitBreakerResult(reqCtx *requestContextImpl) {
+	originFailed := reqCtx.originResponse == nil || !isResponseSuccessful(reqCtx.originResponse)
+	targetFailed := reqCtx.targetResponse == nil || !isResponseSuccessful(reqCtx.targetResponse)
+	if originFailed && targetFailed {
+		return
+	}
+	ch.targetCircuitBreaker.RecordResult(!targetFailed)
+}
+
+// journalFailedTargetWrite appends the request to the failed writes journal if it is a write that succeeded on ORIGIN
+// but failed, timed out or was skipped by the circuit breaker on TARGET.
+func (ch *ClientHandler) journalFailedTargetWrite(reqCtx *requestContextImpl) {
+	if reqCtx.originResponse == nil || !isResponseSuccessful(reqCtx.originResponse) || reqCtx.targetRequest == nil {
+		return
+	}
+
+	var reason string
+	var errorMsg string
+	requestInfo := reqCtx.requestInfo
+	if _, skipped := requestInfo.(*targetSkippedRequestInfo); skipped {
+		reason = journal.ReasonCircuitBreakerOpen
+	} else if requestInfo.GetForwardDecision() != forwardToBoth || !requestInfo.ShouldBeTrackedInMetrics() {
+		return
+	} else if reqCtx.targetResponse == nil {
+		reason = journal.ReasonTargetTimeout
+	} else if !isResponseSuccessful(reqCtx.targetResponse) {
+		reason = journal.ReasonTargetError
+		if decodedResponse, err := defaultCodec.ConvertFromRawFrame(reqCtx.targetResponse); err == nil {
+			if errorResponse, ok := decodedResponse.Body.Message.(message.Error); ok {
+				errorMsg = errorResponse.GetErrorMessage()
+			}
+		}
+	} else {
+		return
+	}
+
+	encodedRequest := &bytes.Buffer{}
+	err := defaultCodec.EncodeRawFrame(reqCtx.targetRequest, encodedRequest)
+	if err != nil {
+		reqCtx.logger.Errorf("Could not encode request to write it to the failed writes journal: %v", err)
+		return
+	}
+
+	entry := &journal.Entry{
+		Timestamp:          reqCtx.startTime.UnixNano() / int64(time.Microsecond),
+		Reason:             reason,
+		Error:              errorMsg,
+		Keyspace:           reqCtx.keyspace,
+		PreparedStatements: getJournalPreparedStatements(unwrapRequestInfo(requestInfo)),
+		Frame:              encodedRequest.Bytes(),
+	}
+	if !ch.failedWritesJournal.Append(entry) {
+		reqCtx.logger.Debugf("Could not append %v to the failed writes journal.", reqCtx.request.Header)
+	}
+}
+
+func getJournalPreparedStatements(requestInfo RequestInfo) map[string]string {
+	var preparedDataList []PreparedData
+	switch castedRequestInfo := requestInfo.(type) {
+	case *ExecuteRequestInfo:
+		preparedDataList = append(preparedDataList, castedRequestInfo.GetPreparedData())
+	case *BatchRequestInfo:
+		for _, preparedData := range castedRequestInfo.GetPreparedDataByStmtIdx() {
+			preparedDataList = append(preparedDataList, preparedData)
+		}
+	}
+	if len(preparedDataList) == 0 {
+		return nil
+	}
+	statements := make(map[string]string, len(preparedDataList))
+	for _, preparedData := range preparedDataList {
+		statements[hex.EncodeToString(preparedData.GetTargetPreparedId())] =
+			preparedData.GetPrepareRequestInfo().GetQuery()
+	}
+	return statements
+}
+
 // should only be called after Cancel returns true
 func (ch *ClientHandler) cancelRequest(holder *requestContextHolder, reqCtx *requestContextImpl) {
 	defer ch.clientHandlerRequestWaitGroup.Done()
 
 	err := holder.Clear(reqCtx)
 	if err != nil {
-		log.Debugf("Could not free stream id: %v", err)
+		reqCtx.logger.Debugf("Could not free stream id: %v", err)
 	}
 
 	if reqCtx.requestInfo.ShouldBeTrackedInMetrics() {
 		proxyMetrics := ch.metricHandler.GetProxyMetrics()
-		switch reqCtx.requestInfo.GetForwardDecision() {
+		switch getMetricsForwardDecision(reqCtx.requestInfo) {
 		case forwardToBoth:
 			proxyMetrics.InFlightWrites.Subtract(1)
 		case forwardToOrigin:
@@ -750,7 +1156,7 @@ func (ch *ClientHandler) cancelRequest(holder *requestContextHolder, reqCtx *req
 			proxyMetrics.InFlightReadsTarget.Subtract(1)
 		case forwardToAsyncOnly, forwardToNone:
 		default:
-			log.Errorf("unexpected forwardDecision %v, unable to track proxy level metrics", reqCtx.requestInfo.GetForwardDecision())
+			reqCtx.logger.Errorf("unexpected forwardDecision %v, unable to track proxy level metrics", reqCtx.requestInfo.GetForwardDecision())
 		}
 	}
 
@@ -758,11 +1164,18 @@ func (ch *ClientHandler) cancelRequest(holder *requestContextHolder, reqCtx *req
 		close(reqCtx.customResponseChannel)
 	}
 
-	log.Tracef("Canceled request %v.", reqCtx.request.Header)
+	reqCtx.logger.Tracef("Canceled request %v.", reqCtx.request.Header)
 }
 
 // Computes the response to be sent to the client based on the forward decision of the request.
 func (ch *ClientHandler) computeClientResponse(requestContext *requestContextImpl) (*frame.RawFrame, common.ClusterType, error) {
+	if response, clusterType, ok := ch.getSpeculativeReadResponse(requestContext); ok {
+		return response, clusterType, nil
+	}
+	if response, clusterType, ok := ch.getDeadlineResponse(requestContext); ok {
+		return response, clusterType, nil
+	}
+
 	fwdDecision := requestContext.requestInfo.GetForwardDecision()
 	switch fwdDecision {
 	case forwardToOrigin:
@@ -771,11 +1184,21 @@ func (ch *ClientHandler) computeClientResponse(requestContext *requestContextImp
 				"did not receive response from origin cassandra channel, stream: %d",
 				requestContext.request.Header.StreamId)
 		}
-		log.Tracef("Forward to origin: just returning the response received from %v: %d",
+		requestContext.logger.Tracef("Forward to origin: just returning the response received from %v: %d",
 			common.ClusterTypeOrigin, requestContext.originResponse.Header.OpCode)
 
 		if requestContext.requestInfo.ShouldBeTrackedInMetrics() && !isResponseSuccessful(requestContext.originResponse) {
-			ch.metricHandler.GetProxyMetrics().FailedReadsOrigin.Add(1)
+			if _, shadowed := requestContext.requestInfo.(*shadowedRequestInfo); shadowed {
+				ch.metricHandler.GetProxyMetrics().FailedWritesOnOrigin.Add(1)
+				if ch.applicationMetrics != nil {
+					ch.applicationMetrics.FailedWritesOnOrigin.Add(1)
+				}
+			} else {
+				ch.metricHandler.GetProxyMetrics().FailedReadsOrigin.Add(1)
+				if ch.applicationMetrics != nil {
+					ch.applicationMetrics.FailedReadsOrigin.Add(1)
+				}
+			}
 		}
 		return requestContext.originResponse, common.ClusterTypeOrigin, nil
 	case forwardToTarget:
@@ -784,11 +1207,21 @@ func (ch *ClientHandler) computeClientResponse(requestContext *requestContextImp
 				"did not receive response from target cassandra channel, stream: %d",
 				requestContext.request.Header.StreamId)
 		}
-		log.Tracef("Forward to target: just returning the response received from %v: %d",
+		requestContext.logger.Tracef("Forward to target: just returning the response received from %v: %d",
 			common.ClusterTypeTarget, requestContext.targetResponse.Header.OpCode)
 
 		if requestContext.requestInfo.ShouldBeTrackedInMetrics() && !isResponseSuccessful(requestContext.targetResponse) {
-			ch.metricHandler.GetProxyMetrics().FailedReadsTarget.Add(1)
+			if _, decommissioned := requestContext.requestInfo.(*originDecommissionedRequestInfo); decommissioned {
+				ch.metricHandler.GetProxyMetrics().FailedWritesOnTarget.Add(1)
+				if ch.applicationMetrics != nil {
+					ch.applicationMetrics.FailedWritesOnTarget.Add(1)
+				}
+			} else {
+				ch.metricHandler.GetProxyMetrics().FailedReadsTarget.Add(1)
+				if ch.applicationMetrics != nil {
+					ch.applicationMetrics.FailedReadsTarget.Add(1)
+				}
+			}
 		}
 		return requestContext.targetResponse, common.ClusterTypeTarget, nil
 	case forwardToBoth:
@@ -802,6 +1235,9 @@ func (ch *ClientHandler) computeClientResponse(requestContext *requestContextImp
 				"did not receive response from target cassandra channel, stream: %d",
 				requestContext.request.Header.StreamId)
 		}
+		if requestContext.requestInfo.IsConditional() {
+			ch.compareConditionalResults(requestContext.request, requestContext.originResponse, requestContext.targetResponse)
+		}
 		aggregatedResponse, responseClusterType := ch.aggregateAndTrackResponses(
 			requestContext.requestInfo, requestContext.request, requestContext.originResponse, requestContext.targetResponse)
 		return aggregatedResponse, responseClusterType, nil
@@ -813,7 +1249,7 @@ func (ch *ClientHandler) computeClientResponse(requestContext *requestContextImp
 					"did not receive response from async target cassandra channel, stream: %d",
 					requestContext.request.Header.StreamId)
 			}
-			log.Tracef("Forward to async: just returning the response received from %v: %d",
+			requestContext.logger.Tracef("Forward to async: just returning the response received from %v: %d",
 				common.ClusterTypeTarget, requestContext.targetResponse.Header.OpCode)
 			return requestContext.targetResponse, common.ClusterTypeTarget, nil
 		case common.ClusterTypeOrigin:
@@ -822,11 +1258,11 @@ func (ch *ClientHandler) computeClientResponse(requestContext *requestContextImp
 					"did not receive response from async origin cassandra channel, stream: %d",
 					requestContext.request.Header.StreamId)
 			}
-			log.Tracef("Forward to async: just returning the response received from %v: %d",
+			requestContext.logger.Tracef("Forward to async: just returning the response received from %v: %d",
 				common.ClusterTypeOrigin, requestContext.originResponse.Header.OpCode)
 			return requestContext.originResponse, common.ClusterTypeOrigin, nil
 		default:
-			log.Errorf("Unknown cluster type: %v. This is a bug, please report.", ch.asyncConnector.clusterType)
+			requestContext.logger.Errorf("Unknown cluster type: %v. This is a bug, please report.", ch.asyncConnector.clusterType)
 			return nil, common.ClusterTypeNone, fmt.Errorf("unknown cluster type: %v; this is a bug, please report", ch.asyncConnector.clusterType)
 		}
 	case forwardToNone:
@@ -859,10 +1295,30 @@ func (ch *ClientHandler) processClientResponse(
 			}
 		case *message.SetKeyspaceResult:
 			if bodyMsg.Keyspace == "" {
-				log.Warnf("unexpected set keyspace empty")
+				reqCtx.logger.Warnf("unexpected set keyspace empty")
+			} else if responseClusterType == common.ClusterTypeTarget && ch.targetNameMapper != nil {
+				// the current keyspace is tracked with the ORIGIN name that the client uses
+				ch.StoreCurrentKeyspace(ch.targetNameMapper.mapping.OriginKeyspace(bodyMsg.Keyspace))
 			} else {
 				ch.StoreCurrentKeyspace(bodyMsg.Keyspace)
 			}
+			ch.originCassandraConnector.retireStreamIdOverflowConnector()
+			ch.targetCassandraConnector.retireStreamIdOverflowConnector()
+			ch.originCassandraConnector.retireAlternateHostConnector()
+			ch.targetCassandraConnector.retireAlternateHostConnector()
+		case *message.RowsResult:
+			if isTrackedRead(reqCtx.requestInfo) {
+				switch responseClusterType {
+				case common.ClusterTypeOrigin:
+					ch.originReadResponses.track(response, bodyMsg, reqCtx.requestInfo, reqCtx.logger)
+				case common.ClusterTypeTarget:
+					ch.targetReadResponses.track(response, bodyMsg, reqCtx.requestInfo, reqCtx.logger)
+				}
+			}
+			if ch.conf.TagPagingStates && bodyMsg.Metadata != nil && bodyMsg.Metadata.PagingState != nil {
+				bodyMsg.Metadata.PagingState = tagPagingState(bodyMsg.Metadata.PagingState, responseClusterType)
+				newFrame = decodedFrame
+			}
 		case *message.Unprepared:
 			var unpreparedId []byte
 			switch responseClusterType {
@@ -887,7 +1343,7 @@ func (ch *ClientHandler) processClientResponse(
 			}
 			newFrame.Body.Message = newUnprepared
 
-			log.Infof("Received UNPREPARED from %v, generating UNPREPARED response with prepared ID %s. "+
+			reqCtx.logger.Infof("Received UNPREPARED from %v, generating UNPREPARED response with prepared ID %s. "+
 				"Prepared ID in response from %v: %v. Original error: %v",
 				responseClusterType, hex.EncodeToString(unpreparedId),
 				responseClusterType, hex.EncodeToString(bodyMsg.Id), bodyMsg.ErrorMessage)
@@ -1015,7 +1471,9 @@ func (ch *ClientHandler) processPreparedResponse(
 			}
 		}
 
-		ch.preparedStatementCache.Store(bodyMsg, targetPreparedResult, prepareRequestInfo)
+		if evicted := ch.preparedStatementCache.Store(bodyMsg, targetPreparedResult, prepareRequestInfo); evicted > 0 {
+			ch.metricHandler.GetProxyMetrics().PSCacheEvictionCount.Add(evicted)
+		}
 		return newResponse, nil
 	}
 }
@@ -1035,7 +1493,7 @@ type handshakeRequestResult struct {
 func (ch *ClientHandler) handleHandshakeRequest(request *frame.RawFrame, wg *sync.WaitGroup) (bool, error) {
 	scheduledTaskChannel := make(chan *handshakeRequestResult, 1)
 	wg.Add(1)
-	ch.requestResponseScheduler.Schedule(func() {
+	ch.requestResponseScheduler.ScheduleOnShard(ch.requestResponseShard, func() {
 		defer wg.Done()
 		defer close(scheduledTaskChannel)
 		if ch.authErrorMessage != nil {
@@ -1050,8 +1508,39 @@ func (ch *ClientHandler) handleHandshakeRequest(request *frame.RawFrame, wg *syn
 			return
 		}
 
+		if request.Header.OpCode == primitive.OpCodeStartup {
+			newRequest, compressor, err := removeStartupCompression(request)
+			if err != nil {
+				ch.clientConnector.sendProtocolErrorToClient(request, err.Error())
+				scheduledTaskChannel <- &handshakeRequestResult{
+					authSuccess: false,
+					err:         fmt.Errorf("could not handle STARTUP request: %w", err),
+				}
+				return
+			}
+			if compressor != nil {
+				ch.logger.Debugf("Client negotiated %v compression, frames are compressed only on the client connection.",
+					compressor.algorithm)
+				ch.clientConnector.setCompressor(compressor)
+			}
+			request = newRequest
+		}
+
 		if request.Header.OpCode == primitive.OpCodeAuthResponse {
+			if ch.proxyAuthPending {
+				scheduledTaskChannel <- ch.handleProxyAuthResponse(request)
+				return
+			}
+
 			newAuthFrame, err := ch.handleClientCredentials(request)
+			var authError *AuthError
+			if errors.As(err, &authError) {
+				scheduledTaskChannel <- &handshakeRequestResult{
+					authSuccess: false,
+					err:         ch.sendProxyAuthErrorToClient(request, authError.errMsg),
+				}
+				return
+			}
 			if err != nil {
 				scheduledTaskChannel <- &handshakeRequestResult{
 					authSuccess: false,
@@ -1134,11 +1623,34 @@ func (ch *ClientHandler) handleHandshakeRequest(request *frame.RawFrame, wg *syn
 		if err != nil {
 			return false, fmt.Errorf("unsuccessful startup on %v: %w", secondaryCluster, err)
 		}
+
+		ch.trackApplicationConnection(request)
+		ch.checkSystemQueriesBypass(request)
+
+		if aggregatedResponse.Header.OpCode == primitive.OpCodeAuthenticate {
+			parsedResponse, err := defaultCodec.ConvertFromRawFrame(aggregatedResponse)
+			if err != nil {
+				return false, fmt.Errorf("could not decode authenticate response: %w", err)
+			}
+			if authenticate, ok := parsedResponse.Body.Message.(*message.Authenticate); ok {
+				ch.primaryAuthenticator = authenticate.Authenticator
+			}
+		}
+
+		if ch.credentialMapper != nil && aggregatedResponse.Header.OpCode == primitive.OpCodeReady {
+			// neither cluster asked for credentials so the proxy has to ask for them itself
+			aggregatedResponse, err = ch.buildLocalResponse(
+				request, &message.Authenticate{Authenticator: passwordAuthenticatorClass})
+			if err != nil {
+				return false, fmt.Errorf("could not build authenticate response: %w", err)
+			}
+			ch.proxyAuthPending = true
+		}
 	}
 
 	scheduledTaskChannel = make(chan *handshakeRequestResult, 1)
 	wg.Add(1)
-	ch.requestResponseScheduler.Schedule(func() {
+	ch.requestResponseScheduler.ScheduleOnShard(ch.requestResponseShard, func() {
 		defer wg.Done()
 		defer close(scheduledTaskChannel)
 		tempResult := &handshakeRequestResult{
@@ -1168,7 +1680,7 @@ func (ch *ClientHandler) handleHandshakeRequest(request *frame.RawFrame, wg *syn
 			if ch.asyncConnector != nil {
 				asyncConnectorHandshakeChannel, err = ch.startSecondaryHandshake(true)
 				if err != nil {
-					log.Errorf("Error occured in async connector (%v) handshake: %v. "+
+					ch.logger.Errorf("Error occured in async connector (%v) handshake: %v. "+
 						"Async requests will not be forwarded.", ch.asyncConnector.clusterType, err.Error())
 					ch.asyncConnector.Shutdown()
 					asyncConnectorHandshakeChannel = nil
@@ -1185,7 +1697,7 @@ func (ch *ClientHandler) handleHandshakeRequest(request *frame.RawFrame, wg *syn
 			}
 
 			if errAsync != nil {
-				log.Errorf("Async connector (%v) handshake failed, async requests will not be forwarded: %s",
+				ch.logger.Errorf("Async connector (%v) handshake failed, async requests will not be forwarded: %s",
 					ch.asyncConnector.clusterType, errAsync.Error())
 				ch.asyncConnector.Shutdown()
 			}
@@ -1199,7 +1711,7 @@ func (ch *ClientHandler) handleHandshakeRequest(request *frame.RawFrame, wg *syn
 					return
 				}
 
-				log.Errorf("secondary (%v) handshake failed, shutting down the client handler and connectors: %s", secondaryClusterType, err.Error())
+				ch.logger.Errorf("secondary (%v) handshake failed, shutting down the client handler and connectors: %s", secondaryClusterType, err.Error())
 				ch.clientHandlerCancelFunc()
 				tempResult.err = fmt.Errorf("handshake failed: %w", ShutdownErr)
 				scheduledTaskChannel <- tempResult
@@ -1228,7 +1740,7 @@ func (ch *ClientHandler) handleHandshakeRequest(request *frame.RawFrame, wg *syn
 func (ch *ClientHandler) sendAuthErrorToClient(requestFrame *frame.RawFrame, secondaryClusterType common.ClusterType) error {
 	authErrorResponse, err := ch.buildAuthErrorResponse(requestFrame, ch.authErrorMessage)
 	if err == nil {
-		log.Warnf("Secondary (%v) handshake failed with an auth error, returning %v to client.", secondaryClusterType, ch.authErrorMessage)
+		ch.logger.Warnf("Secondary (%v) handshake failed with an auth error, returning %v to client.", secondaryClusterType, ch.authErrorMessage)
 		ch.clientConnector.sendResponseToClient(authErrorResponse)
 		return nil
 	} else {
@@ -1239,11 +1751,44 @@ func (ch *ClientHandler) sendAuthErrorToClient(requestFrame *frame.RawFrame, sec
 // Build authentication error response to return to client
 func (ch *ClientHandler) buildAuthErrorResponse(
 	requestFrame *frame.RawFrame, authenticationError *message.AuthenticationError) (*frame.RawFrame, error) {
-	f := frame.NewFrame(requestFrame.Header.Version, requestFrame.Header.StreamId, authenticationError)
-	if requestFrame.Header.Flags.Contains(primitive.HeaderFlagCompressed) {
-		f.SetCompress(true)
+	return ch.buildLocalResponse(requestFrame, authenticationError)
+}
+
+// Sends an authentication error to the client when the credentials it provided were rejected by the proxy itself.
+func (ch *ClientHandler) sendProxyAuthErrorToClient(
+	requestFrame *frame.RawFrame, authenticationError *message.AuthenticationError) error {
+	authErrorResponse, err := ch.buildAuthErrorResponse(requestFrame, authenticationError)
+	if err != nil {
+		return fmt.Errorf("client credentials were rejected but could not create response frame: %w", err)
 	}
+	ch.logger.Warnf("Client %v provided invalid credentials, returning %v to client.",
+		ch.clientConnector.connection.RemoteAddr(), authenticationError)
+	ch.clientConnector.sendResponseToClient(authErrorResponse)
+	return nil
+}
 
+// answerOptions sends a SUPPORTED response built by the proxy to the client, it returns false if the OPTIONS request
+// has to be forwarded to the clusters instead.
+func (ch *ClientHandler) answerOptions(f *frame.RawFrame) bool {
+	if ch.optionsResponder == nil {
+		return false
+	}
+	options := ch.optionsResponder.GetSupportedOptions()
+	if options == nil {
+		return false
+	}
+	response, err := ch.buildLocalResponse(f, &message.Supported{Options: options})
+	if err != nil {
+		ch.logger.Errorf("Could not build SUPPORTED response, the OPTIONS request will be forwarded: %v", err)
+		return false
+	}
+	ch.clientConnector.sendResponseToClient(response)
+	return true
+}
+
+// Build a response to a handshake request that is generated by the proxy instead of one of the clusters
+func (ch *ClientHandler) buildLocalResponse(requestFrame *frame.RawFrame, msg message.Message) (*frame.RawFrame, error) {
+	f := frame.NewFrame(requestFrame.Header.Version, requestFrame.Header.StreamId, msg)
 	return defaultCodec.ConvertToRawFrame(f)
 }
 
@@ -1281,24 +1826,111 @@ func (ch *ClientHandler) startSecondaryHandshake(asyncConnector bool) (chan erro
 // Handles a request, see the docs for the forwardRequest() function, as handleRequest is pretty much a wrapper
 // around forwardRequest.
 func (ch *ClientHandler) handleRequest(f *frame.RawFrame) {
+	if ch.interceptors != nil {
+		request, response, err := ch.interceptors.intercept(
+			f, ch.LoadCurrentKeyspace(), ch.clientConnector.connection.RemoteAddr().String())
+		if err != nil {
+			ch.logger.Warnf("error intercepting request with opcode %02x and streamid %d: %s", f.Header.OpCode, f.Header.StreamId, err.Error())
+			return
+		}
+		if response != nil {
+			ch.clientConnector.sendResponseToClient(response)
+			return
+		}
+		f = request
+	}
+
 	err := ch.forwardRequest(f, nil)
 
 	if err != nil {
-		log.Warnf("error sending request with opcode %02x and streamid %d: %s", f.Header.OpCode, f.Header.StreamId, err.Error())
+		ch.logger.Warnf("error sending request with opcode %02x and streamid %d: %s", f.Header.OpCode, f.Header.StreamId, err.Error())
 		return
 	}
 }
 
+// newRequestLogger returns a logger for a new request of this client connection, request ids are unique within
+// the connection and are prefixed with the connection id.
+//
+// The request_id and stream_id fields are only added with the JSON log format or when DEBUG is enabled, the
+// connection logger is returned as it is otherwise so that requests don't pay for fields that are never logged.
+func (ch *ClientHandler) newRequestLogger(request *frame.RawFrame) *log.Entry {
+	if !ch.jsonLogFormat && !ch.logger.Logger.IsLevelEnabled(log.DebugLevel) {
+		return ch.logger
+	}
+	requestId := atomic.AddUint64(&ch.requestIdCounter, 1)
+	return ch.logger.WithFields(log.Fields{
+		"request_id": fmt.Sprintf("%v-%d", ch.connectionId, requestId),
+		"stream_id":  request.Header.StreamId,
+	})
+}
+
+func isJsonLogFormat(logger *log.Entry) bool {
+	_, ok := logger.Logger.Formatter.(*log.JSONFormatter)
+	return ok
+}
+
+// getPrimaryControlConn returns the control connection of the primary cluster, its schema metadata is used to
+// detect counter updates.
+func (ch *ClientHandler) getPrimaryControlConn() *ControlConn {
+	if ch.primaryCluster == common.ClusterTypeTarget {
+		return ch.targetControlConn
+	}
+	return ch.originControlConn
+}
+
+// shouldInjectWriteTimestamp returns true if ZDM_INJECT_WRITE_TIMESTAMP is enabled and the request is an INSERT, UPDATE,
+// DELETE or BATCH sent to both clusters. Lightweight transactions, whose timestamps come from Paxos, and counter updates
+// are left unchanged, as well as the BATCH requests that contain any of them.
+func (ch *ClientHandler) shouldInjectWriteTimestamp(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string) (bool, error) {
+	if !ch.conf.InjectWriteTimestamp || requestInfo.GetForwardDecision() != forwardToBoth || !requestInfo.IsWrite() ||
+		requestInfo.IsConditional() || requestInfo.IsCounterWrite() {
+		return false, nil
+	}
+	if _, ok := unwrapRequestInfo(requestInfo).(*BatchRequestInfo); !ok {
+		return true, nil
+	}
+	conditionalOrCounterWrite, err := ch.isConditionalOrCounterBatch(frameContext, currentKeyspace)
+	return !conditionalOrCounterWrite, err
+}
+
+// isConditionalOrCounterBatch returns true if a query string of the BATCH is a lightweight transaction or a counter
+// update. The query strings of a BATCH are not inspected when it is parsed with the default LWT and counter write
+// policies so BatchRequestInfo.IsConditional and BatchRequestInfo.IsCounterWrite only cover its prepared statements.
+func (ch *ClientHandler) isConditionalOrCounterBatch(
+	frameContext *frameDecodeContext, currentKeyspace string) (bool, error) {
+	stmtsQueryData, err := frameContext.GetOrInspectAllStatements(currentKeyspace, ch.timeUuidGenerator)
+	if err != nil {
+		return false, fmt.Errorf("could not inspect BATCH frame: %w", err)
+	}
+	counterTables := ch.getPrimaryControlConn()
+	for _, stmtQueryData := range stmtsQueryData {
+		if stmtQueryData.queryData.isConditional() || isCounterWrite(stmtQueryData.queryData, counterTables) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // Forwards the request, parsing it and enqueuing it to the appropriate cluster connector(s)' write queue(s).
 func (ch *ClientHandler) forwardRequest(request *frame.RawFrame, customResponseChannel chan *customResponse) error {
 	overallRequestStartTime := time.Now()
+	logger := ch.newRequestLogger(request)
 
-	log.Tracef("Request frame: %v", request)
+	logger.Tracef("Request frame: %v", redactedRawFrame{request})
 
 	currentKeyspace := ch.LoadCurrentKeyspace()
-	context := NewFrameDecodeContext(request)
+	context := NewFrameDecodeContext(request, logger)
 	var replacedTerms []*statementReplacedTerms
 	var err error
+	if ch.conf.QualifyUnqualifiedStatements {
+		// qualify table names first because replacing function calls invalidates the parsed table name positions
+		context, err = ch.queryModifier.qualifyQueryString(currentKeyspace, context)
+		if err != nil {
+			return err
+		}
+	}
+
 	if ch.conf.ReplaceCqlFunctions {
 		context, replacedTerms, err = ch.queryModifier.replaceQueryString(currentKeyspace, context)
 	}
@@ -1308,40 +1940,82 @@ func (ch *ClientHandler) forwardRequest(request *frame.RawFrame, customResponseC
 	}
 	requestInfo, err := buildRequestInfo(
 		context, replacedTerms, ch.preparedStatementCache, ch.metricHandler, currentKeyspace, ch.primaryCluster,
-		ch.forwardSystemQueriesToTarget, ch.topologyConfig.VirtualizationEnabled, ch.forwardAuthToTarget, ch.timeUuidGenerator)
+		ch.forwardSystemQueriesToTarget, ch.interceptSystemQueries, ch.introspectionTables != nil,
+		ch.forwardAuthToTarget, ch.lwtPolicy, ch.counterWritePolicy, ch.ddlPolicy, ch.tableRouting,
+		ch.getPrimaryControlConn(), ch.timeUuidGenerator)
+	if err == nil {
+		context, requestInfo, err = ch.getPagedRequestInfo(context, requestInfo)
+	}
 	if err != nil {
 		if errVal, ok := err.(*UnpreparedExecuteError); ok {
 			unpreparedFrame, err := createUnpreparedFrame(errVal)
 			if err != nil {
 				return err
 			}
-			log.Debugf(
+			logger.Debugf(
 				"PS Cache miss, created unprepared response with version %v, streamId %v and preparedId %s",
 				errVal.Header.Version, errVal.Header.StreamId, errVal.preparedId)
 
 			// send it back to client
 			ch.clientConnector.sendResponseToClient(unpreparedFrame)
-			log.Debugf("Unprepared Response sent, exiting handleRequest now")
+			logger.Debugf("Unprepared Response sent, exiting handleRequest now")
 			return nil
 		}
+		if errVal, ok := err.(*RejectedRequestError); ok {
+			return ch.sendRejectedResponseToClient(errVal, logger)
+		}
 		return err
 	}
 
-	requestTimeout := time.Duration(ch.conf.ProxyRequestTimeoutMs) * time.Millisecond
-	err = ch.executeRequest(context, requestInfo, currentKeyspace, overallRequestStartTime, customResponseChannel, requestTimeout)
+	injectWriteTimestamp, err := ch.shouldInjectWriteTimestamp(context, requestInfo, currentKeyspace)
+	if err != nil {
+		return err
+	}
+	if injectWriteTimestamp {
+		context, err = ch.queryModifier.injectWriteTimestamp(context)
+		if err != nil {
+			return err
+		}
+	}
+
+	requestTimeout := getRequestTimeout(ch.requestTimeouts, context, requestInfo)
+
+	if request.Header.Version.IsDse() {
+		requestInfo, err = ch.getDseRequestInfo(context, requestInfo, logger)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = ch.executeRequest(
+		context, requestInfo, currentKeyspace, overallRequestStartTime, customResponseChannel, requestTimeout, logger)
+	if errVal, ok := err.(*RejectedRequestError); ok {
+		return ch.sendRejectedResponseToClient(errVal, logger)
+	}
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+func (ch *ClientHandler) sendRejectedResponseToClient(errVal *RejectedRequestError, logger *log.Entry) error {
+	rejectedFrame, err := createRejectedFrame(errVal)
+	if err != nil {
+		return err
+	}
+	logger.Debugf("Rejected request with streamId %v: %v", errVal.Header.StreamId, errVal.Reason)
+	ch.clientConnector.sendResponseToClient(rejectedFrame)
+	return nil
+}
+
 // executeRequest executes the forward decision and waits for one or two responses, then returns the response
 // that should be sent back to the client.
 func (ch *ClientHandler) executeRequest(
 	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string,
-	overallRequestStartTime time.Time, customResponseChannel chan *customResponse, requestTimeout time.Duration) error {
+	overallRequestStartTime time.Time, customResponseChannel chan *customResponse, requestTimeout time.Duration,
+	logger *log.Entry) error {
 	fwdDecision := requestInfo.GetForwardDecision()
-	log.Tracef("Opcode: %v, Forward decision: %v", frameContext.GetRawFrame().Header.OpCode, fwdDecision)
+	logger.Tracef("Opcode: %v, Forward decision: %v", frameContext.GetRawFrame().Header.OpCode, fwdDecision)
 
 	f := frameContext.GetRawFrame()
 	originRequest := f
@@ -1349,7 +2023,7 @@ func (ch *ClientHandler) executeRequest(
 	var clientResponse *frame.RawFrame
 	var err error
 
-	switch castedRequestInfo := requestInfo.(type) {
+	switch castedRequestInfo := unwrapRequestInfo(requestInfo).(type) {
 	case *InterceptedRequestInfo:
 		clientResponse, err = ch.handleInterceptedRequest(castedRequestInfo, frameContext, currentKeyspace)
 	case *PrepareRequestInfo:
@@ -1364,6 +2038,56 @@ func (ch *ClientHandler) executeRequest(
 		return err
 	}
 
+	if ch.queryRewriter != nil && fwdDecision != forwardToNone {
+		originRequest, err = ch.queryRewriter.rewriteRequest(originRequest, common.ClusterTypeOrigin, currentKeyspace, logger)
+		if err != nil {
+			return err
+		}
+		targetRequest, err = ch.queryRewriter.rewriteRequest(targetRequest, common.ClusterTypeTarget, currentKeyspace, logger)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ch.targetNameMapper != nil && fwdDecision != forwardToNone {
+		targetRequest, err = ch.targetNameMapper.mapRequest(targetRequest, currentKeyspace, logger)
+		if err != nil {
+			return err
+		}
+	}
+
+	if fwdDecision == forwardToBoth && requestInfo.ShouldBeTrackedInMetrics() && ch.shadowModeEnabled {
+		logger.Tracef("Shadow mode is enabled, forwarding write to %v and mirroring it to %v",
+			common.ClusterTypeOrigin, common.ClusterTypeTarget)
+		ch.metricHandler.GetProxyMetrics().ShadowWrites.Add(1)
+		requestInfo = &shadowedRequestInfo{RequestInfo: requestInfo}
+		fwdDecision = forwardToOrigin
+	}
+
+	if fwdDecision == forwardToBoth && requestInfo.ShouldBeTrackedInMetrics() &&
+		ch.targetCircuitBreaker != nil && !ch.targetCircuitBreaker.Allow() {
+		logger.Tracef("%v circuit breaker is open, forwarding write only to %v", common.ClusterTypeTarget,
+			common.ClusterTypeOrigin)
+		ch.metricHandler.GetProxyMetrics().TargetCircuitBreakerSkippedWrites.Add(1)
+		requestInfo = &targetSkippedRequestInfo{RequestInfo: requestInfo}
+		fwdDecision = forwardToOrigin
+	}
+
+	if fwdDecision == forwardToBoth && requestInfo.ShouldBeTrackedInMetrics() && !ch.originWritesEnabled {
+		logger.Tracef("%v is decommissioned, forwarding write only to %v", common.ClusterTypeOrigin,
+			common.ClusterTypeTarget)
+		requestInfo = &originDecommissionedRequestInfo{RequestInfo: requestInfo}
+		fwdDecision = forwardToTarget
+	}
+
+	if ch.consistencyOverrides != nil && fwdDecision != forwardToNone {
+		originRequest, targetRequest, err = ch.overrideRequestsConsistency(
+			requestInfo, fwdDecision, originRequest, targetRequest, logger)
+		if err != nil {
+			return err
+		}
+	}
+
 	if fwdDecision == forwardToNone {
 		if clientResponse == nil {
 			return fmt.Errorf("forwardDecision is NONE but client response is nil")
@@ -1378,7 +2102,31 @@ func (ch *ClientHandler) executeRequest(
 		return nil
 	}
 
-	reqCtx := NewRequestContext(f, requestInfo, overallRequestStartTime, customResponseChannel)
+	// requests that can't be translated are rejected here instead of being discarded by the connectors
+	if fwdDecision == forwardToBoth || fwdDecision == forwardToOrigin {
+		originRequest, err = ch.originCassandraConnector.translateRequest(originRequest)
+		if err != nil {
+			return &RejectedRequestError{Header: f.Header, Reason: fmt.Sprintf(
+				"the request can not be translated to the protocol version of %v: %v", common.ClusterTypeOrigin, err)}
+		}
+	}
+	if fwdDecision == forwardToBoth || fwdDecision == forwardToTarget {
+		targetRequest, err = ch.targetCassandraConnector.translateRequest(targetRequest)
+		if err != nil {
+			return &RejectedRequestError{Header: f.Header, Reason: fmt.Sprintf(
+				"the request can not be translated to the protocol version of %v: %v", common.ClusterTypeTarget, err)}
+		}
+	}
+
+	reqCtx := NewRequestContext(
+		f, originRequest, targetRequest, requestInfo, currentKeyspace, overallRequestStartTime, customResponseChannel,
+		logger)
+	if _, ok := requestInfo.(*continuousPagingRequestInfo); ok {
+		reqCtx.continuousPaging = newContinuousPagingState(requestTimeout)
+	}
+	if fwdDecision != forwardToAsyncOnly {
+		requestTimeout = ch.setRequestDeadline(reqCtx, frameContext, requestTimeout)
+	}
 	var contextHoldersMap *sync.Map
 	if fwdDecision == forwardToAsyncOnly {
 		contextHoldersMap = ch.asyncRequestContextHolders // different map because of stream id collision
@@ -1392,7 +2140,8 @@ func (ch *ClientHandler) executeRequest(
 
 	if requestInfo.ShouldBeTrackedInMetrics() {
 		proxyMetrics := ch.metricHandler.GetProxyMetrics()
-		switch fwdDecision {
+		metricsFwdDecision := getMetricsForwardDecision(requestInfo)
+		switch metricsFwdDecision {
 		case forwardToBoth:
 			proxyMetrics.InFlightWrites.Add(1)
 		case forwardToOrigin:
@@ -1401,7 +2150,16 @@ func (ch *ClientHandler) executeRequest(
 			proxyMetrics.InFlightReadsTarget.Add(1)
 		case forwardToAsyncOnly:
 		default:
-			log.Errorf("unexpected forwardDecision %v, unable to track proxy level metrics", fwdDecision)
+			logger.Errorf("unexpected forwardDecision %v, unable to track proxy level metrics", metricsFwdDecision)
+		}
+		if ch.applicationMetrics != nil {
+			switch metricsFwdDecision {
+			case forwardToBoth:
+				ch.applicationMetrics.Writes.Add(1)
+			case forwardToOrigin, forwardToTarget:
+				ch.applicationMetrics.Reads.Add(1)
+			default:
+			}
 		}
 	}
 
@@ -1422,26 +2180,34 @@ func (ch *ClientHandler) executeRequest(
 		reqCtx.SetTimer(timer)
 	}
 
-	sendAlsoToAsync := requestInfo.ShouldAlsoBeSentAsync() && ch.asyncConnector != nil
+	_, shadowed := requestInfo.(*shadowedRequestInfo)
+	sendAlsoToAsync := ch.shouldAlsoSendToAsync(requestInfo, fwdDecision)
+	if shadowed && ch.asyncConnector == nil {
+		ch.metricHandler.GetProxyMetrics().ShadowWritesSkipped.Add(1)
+	}
 	switch fwdDecision {
 	case forwardToBoth:
-		log.Tracef("Forwarding request with opcode %v for stream %v to %v and %v",
+		logger.Tracef("Forwarding request with opcode %v for stream %v to %v and %v",
 			f.Header.OpCode, f.Header.StreamId, common.ClusterTypeOrigin, common.ClusterTypeTarget)
-		ch.originCassandraConnector.sendRequestToCluster(originRequest)
-		ch.targetCassandraConnector.sendRequestToCluster(targetRequest)
+		ch.sendRequestToCluster(ch.originCassandraConnector, originRequest)
+		ch.sendRequestToCluster(ch.targetCassandraConnector, targetRequest)
 	case forwardToOrigin:
-		log.Tracef("Forwarding request with opcode %v for stream %v to %v",
+		logger.Tracef("Forwarding request with opcode %v for stream %v to %v",
 			f.Header.OpCode, f.Header.StreamId, common.ClusterTypeOrigin)
-		ch.originCassandraConnector.sendRequestToCluster(originRequest)
+		ch.sendRequestToCluster(ch.originCassandraConnector, originRequest)
 	case forwardToTarget:
-		log.Tracef("Forwarding request with opcode %v for stream %v to %v",
+		logger.Tracef("Forwarding request with opcode %v for stream %v to %v",
 			f.Header.OpCode, f.Header.StreamId, common.ClusterTypeTarget)
-		ch.targetCassandraConnector.sendRequestToCluster(targetRequest)
+		ch.sendRequestToCluster(ch.targetCassandraConnector, targetRequest)
 	case forwardToAsyncOnly:
 	default:
 		return fmt.Errorf("unknown forward decision %v, stream: %d", fwdDecision, f.Header.StreamId)
 	}
 
+	if ch.speculativeReadThreshold > 0 && isSpeculativeReadCandidate(requestInfo) {
+		ch.scheduleSpeculativeRead(reqCtx, originRequest, targetRequest, requestTimeout)
+	}
+
 	if !sendAlsoToAsync && fwdDecision != forwardToAsyncOnly {
 		return nil
 	}
@@ -1484,14 +2250,27 @@ func (ch *ClientHandler) handleInterceptedRequest(
 	} else {
 		controlConn = ch.originControlConn
 	}
-	virtualHosts, err := controlConn.GetVirtualHosts()
-	if err != nil {
-		return nil, err
+	if (interceptedQueryType == peersV1 || interceptedQueryType == local) && ch.conf.SystemQueriesCacheTtlMs > 0 {
+		ttl := time.Duration(ch.conf.SystemQueriesCacheTtlMs) * time.Millisecond
+		if err := controlConn.RefreshTopologyIfOlderThan(ttl, ch.clientHandlerContext); err != nil {
+			ch.logger.Warnf("Could not refresh the topology of %v for an intercepted %v query, "+
+				"the last known topology is returned instead: %v", controlConn.connConfig.GetClusterType(),
+				interceptedQueryType, err)
+		}
 	}
 
 	typeCodec := GetDefaultGenericTypeCodec()
 
+	var err error
 	switch interceptedQueryType {
+	case introspectionClients, introspectionPreparedStatements, introspectionConfig:
+		parsedSelectClause := interceptedRequestInfo.GetParsedSelectClause()
+		if parsedSelectClause == nil {
+			return nil, fmt.Errorf("unable to intercept %v query (prepared=%v) because parsed select clause is nil",
+				introspectionKeyspaceName, prepared)
+		}
+		interceptedQueryResponse, err = ch.introspectionTables.NewResult(prepareRequestInfo, currentKeyspace,
+			typeCodec, f.Header.Version, interceptedQueryType, parsedSelectClause)
 	case peersV2:
 		interceptedQueryResponse = &message.Invalid{
 			ErrorMessage: "unconfigured table peers_v2",
@@ -1501,6 +2280,11 @@ func (ch *ClientHandler) handleInterceptedRequest(
 		if parsedSelectClause == nil {
 			return nil, fmt.Errorf("unable to intercept system.peers query (prepared=%v) because parsed select clause is nil", prepared)
 		}
+		var virtualHosts []*VirtualHost
+		virtualHosts, err = controlConn.GetVirtualHosts()
+		if err != nil {
+			return nil, err
+		}
 		interceptedQueryResponse, err = NewSystemPeersResult(prepareRequestInfo, currentKeyspace,
 			typeCodec, f.Header.Version, controlConn.GetSystemPeersColumnNames(), controlConn.GetSystemLocalColumnData(),
 			parsedSelectClause, virtualHosts, controlConn.GetLocalVirtualHostIndex(), ch.conf.ProxyListenPort)
@@ -1509,6 +2293,11 @@ func (ch *ClientHandler) handleInterceptedRequest(
 		if parsedSelectClause == nil {
 			return nil, fmt.Errorf("unable to intercept system.local query (prepared=%v) because parsed select clause is nil", prepared)
 		}
+		var virtualHosts []*VirtualHost
+		virtualHosts, err = controlConn.GetVirtualHosts()
+		if err != nil {
+			return nil, err
+		}
 		localVirtualHost := virtualHosts[controlConn.GetLocalVirtualHostIndex()]
 		interceptedQueryResponse, err = NewSystemLocalResult(prepareRequestInfo, currentKeyspace,
 			typeCodec, f.Header.Version, controlConn.GetSystemLocalColumnData(), parsedSelectClause,
@@ -1535,7 +2324,9 @@ func (ch *ClientHandler) handleInterceptedRequest(
 
 	preparedMsg, ok := interceptedQueryResponse.(*message.PreparedResult)
 	if ok {
-		ch.preparedStatementCache.StoreIntercepted(preparedMsg, prepareRequestInfo)
+		if evicted := ch.preparedStatementCache.StoreIntercepted(preparedMsg, prepareRequestInfo); evicted > 0 {
+			ch.metricHandler.GetProxyMetrics().PSCacheEvictionCount.Add(evicted)
+		}
 	}
 
 	return interceptedResponseRawFrame, nil
@@ -1641,7 +2432,7 @@ func (ch *ClientHandler) handleExecuteRequest(
 
 		originalQueryId := newTargetExecuteMsg.QueryId
 		newTargetExecuteMsg.QueryId = preparedData.GetTargetPreparedId()
-		log.Tracef("Replacing prepared ID %s with %s for target cluster.",
+		ch.logger.Tracef("Replacing prepared ID %s with %s for target cluster.",
 			hex.EncodeToString(originalQueryId), hex.EncodeToString(newTargetExecuteMsg.QueryId))
 
 		newTargetRequestRaw, err := defaultCodec.ConvertToRawFrame(newTargetRequest)
@@ -1699,10 +2490,26 @@ func (ch *ClientHandler) handleBatchRequest(
 
 		originalQueryId := newTargetBatchMsg.Children[stmtIdx].QueryOrId.([]byte)
 		newTargetBatchMsg.Children[stmtIdx].QueryOrId = preparedData.GetTargetPreparedId()
-		log.Tracef("Replacing prepared ID %s within a BATCH with %s for target cluster.",
+		ch.logger.Tracef("Replacing prepared ID %s within a BATCH with %s for target cluster.",
 			hex.EncodeToString(originalQueryId), hex.EncodeToString(preparedData.GetTargetPreparedId()))
 	}
 
+	if castedRequestInfo.IsSplit() {
+		if newOriginRequest == nil {
+			newOriginRequest = decodedFrame.Clone()
+			newOriginBatchMsg, ok = newOriginRequest.Body.Message.(*message.Batch)
+			if !ok {
+				return nil, nil, fmt.Errorf("expected Batch but got %v instead", newOriginRequest.Body.Message.GetOpCode())
+			}
+		}
+		newOriginBatchMsg.Children = filterBatchChildren(
+			newOriginBatchMsg.Children, castedRequestInfo.GetForwardDecisionByStmtIdx(), forwardToOrigin)
+		newTargetBatchMsg.Children = filterBatchChildren(
+			newTargetBatchMsg.Children, castedRequestInfo.GetForwardDecisionByStmtIdx(), forwardToTarget)
+		ch.logger.Debugf("Splitting BATCH into an ORIGIN sub-batch with %v statements and a TARGET sub-batch with %v statements.",
+			len(newOriginBatchMsg.Children), len(newTargetBatchMsg.Children))
+	}
+
 	if newOriginRequest != nil {
 		originBatchRequest, err := defaultCodec.ConvertToRawFrame(newOriginRequest)
 		if err != nil {
@@ -1722,6 +2529,35 @@ func (ch *ClientHandler) handleBatchRequest(
 	return originRequest, targetRequest, nil
 }
 
+// filterBatchChildren returns the batch child statements that should be sent to the cluster
+// identified by clusterForwardDecision (forwardToOrigin or forwardToTarget).
+func filterBatchChildren(
+	children []*message.BatchChild, forwardDecisionByStmtIdx map[int]forwardDecision,
+	clusterForwardDecision forwardDecision) []*message.BatchChild {
+	filtered := make([]*message.BatchChild, 0, len(children))
+	for stmtIdx, child := range children {
+		stmtForwardDecision, ok := forwardDecisionByStmtIdx[stmtIdx]
+		if !ok || stmtForwardDecision == forwardToBoth || stmtForwardDecision == clusterForwardDecision {
+			filtered = append(filtered, child)
+		}
+	}
+	return filtered
+}
+
+// shouldAlsoSendToAsync returns whether a request forwarded to the primary cluster (or to both) is also sent to the
+// async connector as fire and forget.
+func (ch *ClientHandler) shouldAlsoSendToAsync(requestInfo RequestInfo, fwdDecision forwardDecision) bool {
+	if !requestInfo.ShouldAlsoBeSentAsync() || ch.asyncConnector == nil {
+		return false
+	}
+	_, shadowed := requestInfo.(*shadowedRequestInfo)
+	if !ch.asyncReadsEnabled && !shadowed && fwdDecision != forwardToBoth {
+		// only shadow mode is enabled, reads are not mirrored
+		return false
+	}
+	return true
+}
+
 func (ch *ClientHandler) sendToAsyncConnector(
 	frameContext *frameDecodeContext, originRequest *frame.RawFrame, targetRequest *frame.RawFrame,
 	fwdDecision forwardDecision, reqCtx *requestContextImpl, holder *requestContextHolder, sendAlsoToAsync bool,
@@ -1736,7 +2572,12 @@ func (ch *ClientHandler) sendToAsyncConnector(
 	// forwardToAsyncOnly requests are not fire and forget, i.e., client handler waits for the response
 	isFireAndForget := fwdDecision != forwardToAsyncOnly
 
+	_, shadowed := reqCtx.GetRequestInfo().(*shadowedRequestInfo)
+
 	if !ch.asyncConnector.validateAsyncStateForRequest(asyncRequest) {
+		if shadowed {
+			ch.metricHandler.GetProxyMetrics().ShadowWritesSkipped.Add(1)
+		}
 		if !isFireAndForget {
 			if reqCtx.Cancel(ch.nodeMetrics) {
 				ch.cancelRequest(holder, reqCtx)
@@ -1774,6 +2615,9 @@ func (ch *ClientHandler) sendToAsyncConnector(
 		})
 
 	if !sent {
+		if shadowed {
+			ch.metricHandler.GetProxyMetrics().ShadowWritesSkipped.Add(1)
+		}
 		if !isFireAndForget {
 			if reqCtx.Cancel(ch.nodeMetrics) {
 				ch.cancelRequest(holder, reqCtx)
@@ -1798,13 +2642,13 @@ func (ch *ClientHandler) aggregateAndTrackResponses(
 	responseFromTargetCassandra *frame.RawFrame) (*frame.RawFrame, common.ClusterType) {
 
 	originOpCode := responseFromOriginCassandra.Header.OpCode
-	log.Tracef("Aggregating responses. %v opcode %d, %v opcode %d",
+	ch.logger.Tracef("Aggregating responses. %v opcode %d, %v opcode %d",
 		common.ClusterTypeOrigin, originOpCode, common.ClusterTypeTarget, responseFromTargetCassandra.Header.OpCode)
 
 	// aggregate responses and update relevant aggregate metrics for general failed or successful responses
 	if isResponseSuccessful(responseFromOriginCassandra) && isResponseSuccessful(responseFromTargetCassandra) {
 		if originOpCode == primitive.OpCodeSupported {
-			log.Tracef("Aggregated response: both successes, sending back %v response with opcode %d",
+			ch.logger.Tracef("Aggregated response: both successes, sending back %v response with opcode %d",
 				common.ClusterTypeTarget, originOpCode)
 			return responseFromTargetCassandra, common.ClusterTypeTarget
 		} else if request.Header.OpCode == primitive.OpCodePrepare {
@@ -1812,11 +2656,11 @@ func (ch *ClientHandler) aggregateAndTrackResponses(
 			return responseFromOriginCassandra, common.ClusterTypeOrigin
 		} else {
 			if ch.primaryCluster == common.ClusterTypeTarget {
-				log.Tracef("Aggregated response: both successes, sending back %v response with opcode %d",
+				ch.logger.Tracef("Aggregated response: both successes, sending back %v response with opcode %d",
 					common.ClusterTypeTarget, responseFromTargetCassandra.Header.OpCode)
 				return responseFromTargetCassandra, common.ClusterTypeTarget
 			} else {
-				log.Tracef("Aggregated response: both successes, sending back %v response with opcode %d",
+				ch.logger.Tracef("Aggregated response: both successes, sending back %v response with opcode %d",
 					common.ClusterTypeOrigin, originOpCode)
 				return responseFromOriginCassandra, common.ClusterTypeOrigin
 			}
@@ -1825,32 +2669,98 @@ func (ch *ClientHandler) aggregateAndTrackResponses(
 
 	proxyMetrics := ch.metricHandler.GetProxyMetrics()
 	if !isResponseSuccessful(responseFromOriginCassandra) && !isResponseSuccessful(responseFromTargetCassandra) {
-		log.Debugf("Aggregated response: both failures, sending back %v response with opcode %d",
+		ch.logger.Debugf("Aggregated response: both failures, sending back %v response with opcode %d",
 			common.ClusterTypeOrigin, originOpCode)
 		if requestInfo.ShouldBeTrackedInMetrics() {
 			proxyMetrics.FailedWritesOnBoth.Add(1)
+			if ch.applicationMetrics != nil {
+				ch.applicationMetrics.FailedWritesOnBoth.Add(1)
+			}
 		}
 		return responseFromOriginCassandra, common.ClusterTypeOrigin
 	}
 
 	// if either response is a failure, the failure "wins" --> return the failed response
 	if !isResponseSuccessful(responseFromOriginCassandra) {
-		log.Debugf("Aggregated response: failure only on %v, sending back %v response with opcode %d",
+		ch.logger.Debugf("Aggregated response: failure only on %v, sending back %v response with opcode %d",
 			common.ClusterTypeOrigin, common.ClusterTypeOrigin, originOpCode)
 		if requestInfo.ShouldBeTrackedInMetrics() {
 			proxyMetrics.FailedWritesOnOrigin.Add(1)
+			if ch.applicationMetrics != nil {
+				ch.applicationMetrics.FailedWritesOnOrigin.Add(1)
+			}
 		}
 		return responseFromOriginCassandra, common.ClusterTypeOrigin
 	} else {
-		log.Debugf("Aggregated response: failure only on %v, sending back %v response with opcode %d",
+		ch.logger.Debugf("Aggregated response: failure only on %v, sending back %v response with opcode %d",
 			common.ClusterTypeTarget, common.ClusterTypeTarget, originOpCode)
 		if requestInfo.ShouldBeTrackedInMetrics() {
 			proxyMetrics.FailedWritesOnTarget.Add(1)
+			if ch.applicationMetrics != nil {
+				ch.applicationMetrics.FailedWritesOnTarget.Add(1)
+			}
 		}
 		return responseFromTargetCassandra, common.ClusterTypeTarget
 	}
 }
 
+// trackApplicationConnection finds the metrics of the client application from the options of the STARTUP request,
+// if per-application metrics are enabled.
+func (ch *ClientHandler) trackApplicationConnection(startupRequest *frame.RawFrame) {
+	applicationMetrics := ch.metricHandler.GetProxyMetrics().Applications
+	if applicationMetrics == nil || ch.applicationMetrics != nil {
+		return
+	}
+	decodedFrame, err := defaultCodec.ConvertFromRawFrame(startupRequest)
+	if err != nil {
+		ch.logger.Warnf("Could not decode STARTUP request to update application metrics: %v", err)
+		return
+	}
+	startup, ok := decodedFrame.Body.Message.(*message.Startup)
+	if !ok {
+		return
+	}
+	instance, err := applicationMetrics.GetApplicationMetrics(metrics.ApplicationInfo{
+		Name:          startup.GetApplicationName(),
+		DriverName:    startup.GetDriverName(),
+		DriverVersion: startup.GetDriverVersion(),
+	})
+	if err != nil {
+		ch.logger.Warnf("Could not create application metrics: %v", err)
+		return
+	}
+	instance.OpenConnections.Add(1)
+	ch.applicationMetrics = instance
+}
+
+// checkSystemQueriesBypass stops intercepting the system queries of the connection if the application name of the
+// STARTUP request is one of ZDM_SYSTEM_QUERIES_BYPASS_APPLICATIONS.
+func (ch *ClientHandler) checkSystemQueriesBypass(startupRequest *frame.RawFrame) {
+	if !ch.interceptSystemQueries || ch.systemQueriesBypass == nil || len(ch.systemQueriesBypass.ApplicationNames) == 0 {
+		return
+	}
+	decodedFrame, err := defaultCodec.ConvertFromRawFrame(startupRequest)
+	if err != nil {
+		ch.logger.Warnf("Could not decode STARTUP request to check the application name: %v", err)
+		return
+	}
+	startup, ok := decodedFrame.Body.Message.(*message.Startup)
+	if !ok {
+		return
+	}
+	if applicationName := startup.GetApplicationName(); ch.systemQueriesBypass.MatchesApplicationName(applicationName) {
+		ch.logger.Infof("System queries of application %v are not intercepted (ZDM_SYSTEM_QUERIES_BYPASS_APPLICATIONS).",
+			applicationName)
+		ch.interceptSystemQueries = false
+	}
+}
+
+func (ch *ClientHandler) releaseApplicationMetrics() {
+	if ch.applicationMetrics != nil {
+		ch.applicationMetrics.OpenConnections.Subtract(1)
+	}
+}
+
 // Replaces the credentials in the provided auth frame (which are the Target credentials) with
 // the Origin credentials that are provided to the proxy in the configuration.
 func (ch *ClientHandler) handleClientCredentials(f *frame.RawFrame) (*frame.RawFrame, error) {
@@ -1866,20 +2776,45 @@ func (ch *ClientHandler) handleClientCredentials(f *frame.RawFrame) (*frame.RawF
 			parsedAuthFrame.Body.Message)
 	}
 
+	if ch.primaryAuthenticator == dseAuthenticatorClass && ch.clientSaslMechanism == "" {
+		// the first AUTH_RESPONSE of a DseAuthenticator exchange only contains the mechanism name
+		ch.clientSaslMechanism = string(authResponse.Token)
+	}
+
+	if isOpaqueSaslExchange(ch.primaryAuthenticator, ch.clientSaslMechanism, authResponse.Token) {
+		return ch.handleOpaqueSaslResponse(f)
+	}
+
 	clientCreds, err := ParseCredentialsFromRequest(authResponse.Token)
 	if err != nil {
 		return nil, err
 	}
 
 	if clientCreds == nil {
-		log.Debugf("Found auth response frame without creds: %v", authResponse)
+		ch.logger.Debugf("Found auth response frame without creds: %v", authResponse)
 		return f, nil
 	}
 
-	log.Debugf("Successfully extracted credentials from client auth frame: %v", clientCreds)
+	ch.logger.Debugf("Successfully extracted credentials from client auth frame: %v", clientCreds)
+	ch.clientUsername = clientCreds.Username
 
 	var primaryHandshakeCreds *AuthCredentials
-	if ch.forwardAuthToTarget {
+	if ch.credentialMapper != nil {
+		// client credentials are validated by the proxy and never reach the clusters,
+		// each cluster is authenticated with the credentials mapped to the client
+		if err = ch.validateProxyClientCredentials(clientCreds); err != nil {
+			return nil, err
+		}
+
+		primaryHandshakeCreds = ch.getClusterCredentials(common.ClusterTypeOrigin)
+		ch.secondaryHandshakeCreds = ch.getClusterCredentials(common.ClusterTypeTarget)
+		if ch.forwardAuthToTarget {
+			primaryHandshakeCreds, ch.secondaryHandshakeCreds = ch.secondaryHandshakeCreds, primaryHandshakeCreds
+		}
+		if ch.asyncConnector != nil {
+			ch.asyncHandshakeCreds = ch.getClusterCredentials(ch.asyncConnector.clusterType)
+		}
+	} else if ch.forwardAuthToTarget {
 		// primary handshake is TARGET, secondary is ORIGIN
 
 		if ch.targetCredsOnClientRequest {
@@ -1912,8 +2847,10 @@ func (ch *ClientHandler) handleClientCredentials(f *frame.RawFrame) (*frame.RawF
 		}
 	}
 
-	ch.asyncHandshakeCreds = clientCreds
-	if ch.asyncConnector != nil {
+	if ch.credentialMapper == nil {
+		ch.asyncHandshakeCreds = clientCreds
+	}
+	if ch.asyncConnector != nil && ch.credentialMapper == nil {
 		if ch.targetCredsOnClientRequest && ch.asyncConnector.clusterType == common.ClusterTypeOrigin {
 			ch.asyncHandshakeCreds = &AuthCredentials{
 				Username: ch.originUsername,
@@ -1930,8 +2867,10 @@ func (ch *ClientHandler) handleClientCredentials(f *frame.RawFrame) (*frame.RawF
 
 	if primaryHandshakeCreds == nil {
 		// client credentials don't need to be replaced
+		ch.primaryHandshakeCreds = clientCreds
 		return f, nil
 	}
+	ch.primaryHandshakeCreds = primaryHandshakeCreds
 
 	authResponse.Token = primaryHandshakeCreds.Marshal()
 
@@ -1943,6 +2882,110 @@ func (ch *ClientHandler) handleClientCredentials(f *frame.RawFrame) (*frame.RawF
 	return f, nil
 }
 
+// Handles an AUTH_RESPONSE that belongs to a SASL exchange the proxy can't interpret (e.g. GSSAPI).
+//
+// The tokens are forwarded untouched so the client can complete as many rounds as the mechanism needs with the
+// cluster that handles its handshake. The other connections can't reuse the client's credentials so they are
+// authenticated with the configured credentials of their cluster.
+func (ch *ClientHandler) handleOpaqueSaslResponse(f *frame.RawFrame) (*frame.RawFrame, error) {
+	if ch.credentialMapper != nil {
+		return nil, &AuthError{errMsg: &message.AuthenticationError{
+			ErrorMessage: fmt.Sprintf(
+				"SASL exchange with %v (mechanism %v) can not be validated by the proxy, "+
+					"only plain-text credentials are supported", ch.primaryAuthenticator, ch.clientSaslMechanism),
+		}}
+	}
+
+	if ch.secondaryHandshakeCreds == nil {
+		ch.logger.Debugf("Forwarding opaque SASL exchange (authenticator %v, mechanism %v) to the primary handshake cluster, "+
+			"secondary handshakes will use the configured credentials.", ch.primaryAuthenticator, ch.clientSaslMechanism)
+		secondaryClusterType := common.ClusterTypeTarget
+		if ch.forwardAuthToTarget {
+			secondaryClusterType = common.ClusterTypeOrigin
+		}
+		ch.secondaryHandshakeCreds = ch.getClusterCredentials(secondaryClusterType)
+		if ch.asyncConnector != nil {
+			ch.asyncHandshakeCreds = ch.getClusterCredentials(ch.asyncConnector.clusterType)
+		}
+	}
+
+	return f, nil
+}
+
+// Handles the client's AUTH_RESPONSE when the proxy requested authentication on its own, i.e., when neither
+// cluster requires credentials. The credentials are validated locally and nothing is forwarded to the clusters.
+func (ch *ClientHandler) handleProxyAuthResponse(f *frame.RawFrame) *handshakeRequestResult {
+	parsedAuthFrame, err := defaultCodec.ConvertFromRawFrame(f)
+	if err != nil {
+		return &handshakeRequestResult{err: fmt.Errorf("could not decode client auth response: %w", err)}
+	}
+
+	authResponse, ok := parsedAuthFrame.Body.Message.(*message.AuthResponse)
+	if !ok {
+		return &handshakeRequestResult{
+			err: fmt.Errorf("expected AuthResponse but got %v", parsedAuthFrame.Body.Message),
+		}
+	}
+
+	clientCreds, err := ParseCredentialsFromRequest(authResponse.Token)
+	if err != nil {
+		return &handshakeRequestResult{err: err}
+	}
+
+	err = ch.validateProxyClientCredentials(clientCreds)
+	var authError *AuthError
+	if errors.As(err, &authError) {
+		return &handshakeRequestResult{err: ch.sendProxyAuthErrorToClient(f, authError.errMsg)}
+	}
+
+	authSuccess, err := ch.buildLocalResponse(f, &message.AuthSuccess{})
+	if err != nil {
+		return &handshakeRequestResult{err: fmt.Errorf("could not build auth success response: %w", err)}
+	}
+
+	ch.proxyAuthPending = false
+	responseChan := make(chan *customResponse, 1)
+	responseChan <- &customResponse{aggregatedResponse: authSuccess}
+	return &handshakeRequestResult{customResponseChan: responseChan}
+}
+
+// Returns an AuthError if the credentials provided by the client don't match any of the credential mappings.
+func (ch *ClientHandler) validateProxyClientCredentials(clientCreds *AuthCredentials) error {
+	if mapping := ch.credentialMapper.Authenticate(clientCreds); mapping != nil {
+		ch.logger.Debugf("Client %v authenticated by the proxy as %v.",
+			ch.clientConnector.connection.RemoteAddr(), mapping.ClientUsername)
+		ch.mappedCredentials = mapping
+		ch.clientUsername = clientCreds.Username
+		return nil
+	}
+
+	username := ""
+	if clientCreds != nil {
+		username = clientCreds.Username
+	}
+	return &AuthError{errMsg: &message.AuthenticationError{
+		ErrorMessage: fmt.Sprintf("Provided username %v and/or password are incorrect", username),
+	}}
+}
+
+// Returns the credentials mapped to the client if it was authenticated by the proxy
+// or the configured credentials of the cluster otherwise.
+func (ch *ClientHandler) getClusterCredentials(clusterType common.ClusterType) *AuthCredentials {
+	if ch.mappedCredentials != nil {
+		return ch.credentialMapper.GetClusterCredentials(ch.mappedCredentials, clusterType)
+	}
+	if clusterType == common.ClusterTypeTarget {
+		return &AuthCredentials{
+			Username: ch.targetUsername,
+			Password: ch.targetPassword,
+		}
+	}
+	return &AuthCredentials{
+		Username: ch.originUsername,
+		Password: ch.originPassword,
+	}
+}
+
 func (ch *ClientHandler) LoadCurrentKeyspace() string {
 	ks := ch.currentKeyspaceName.Load()
 	if ks != nil {
@@ -1974,6 +3017,16 @@ func isResponseSuccessful(response *frame.RawFrame) bool {
 	return response.Header.OpCode != primitive.OpCodeError
 }
 
+func createRejectedFrame(errVal *RejectedRequestError) (*frame.RawFrame, error) {
+	f := frame.NewFrame(errVal.Header.Version, errVal.Header.StreamId, &message.Invalid{ErrorMessage: errVal.Reason})
+	rawFrame, err := defaultCodec.ConvertToRawFrame(f)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert rejected response frame to rawframe: %w", err)
+	}
+
+	return rawFrame, nil
+}
+
 func createUnpreparedFrame(errVal *UnpreparedExecuteError) (*frame.RawFrame, error) {
 	unpreparedMsg := &message.Unprepared{
 		ErrorMessage: fmt.Sprintf("Prepared query with ID %s not found (either the query was not prepared "+
@@ -2024,26 +3077,28 @@ func storeRequestContext(contextHoldersMap *sync.Map, reqCtx *requestContextImpl
 func trackClusterErrorMetrics(
 	response *frame.RawFrame,
 	connectorType ClusterConnectorType,
-	nodeMetrics *metrics.NodeMetrics) {
+	nodeMetrics *metrics.NodeMetrics,
+	logger *log.Entry) {
 	if !isResponseSuccessful(response) {
 		errorMsg, err := decodeErrorResult(response)
 		if err != nil {
-			log.Errorf("could not track read response: %v", err)
+			logger.Errorf("could not track read response: %v", err)
 			return
 		}
 
-		trackClusterErrorMetricsFromErrorMessage(errorMsg, connectorType, nodeMetrics)
+		trackClusterErrorMetricsFromErrorMessage(errorMsg, connectorType, nodeMetrics, logger)
 	}
 }
 
 func trackClusterErrorMetricsFromErrorMessage(
 	errorMsg message.Error,
 	connectorType ClusterConnectorType,
-	nodeMetrics *metrics.NodeMetrics) {
+	nodeMetrics *metrics.NodeMetrics,
+	logger *log.Entry) {
 
 	nodeMetricsInstance, err := GetNodeMetricsByClusterConnector(nodeMetrics, connectorType)
 	if err != nil {
-		log.Errorf("Failed to track cluster error metrics: %v.", err)
+		logger.Errorf("Failed to track cluster error metrics: %v.", err)
 		return
 	}
 
@@ -2063,7 +3118,7 @@ func trackClusterErrorMetricsFromErrorMessage(
 	case primitive.ErrorCodeUnavailable:
 		nodeMetricsInstance.UnavailableErrors.Add(1)
 	default:
-		log.Debugf("Recording %v other error: %v", connectorType, errorMsg)
+		logger.Debugf("Recording %v other error: %v", connectorType, errorMsg)
 		nodeMetricsInstance.OtherErrors.Add(1)
 	}
 }
@@ -2071,7 +3126,8 @@ func trackClusterErrorMetricsFromErrorMessage(
 func forwardAuthToTarget(
 	originControlConn *ControlConn,
 	targetControlConn *ControlConn,
-	forwardClientCredsToOrigin bool) (forwardAuthToTarget bool, targetCredsOnClientRequest bool) {
+	forwardClientCredsToOrigin bool,
+	logger *log.Entry) (forwardAuthToTarget bool, targetCredsOnClientRequest bool) {
 	authEnabledOnOrigin, err := originControlConn.IsAuthEnabled()
 	clusterType := common.ClusterTypeOrigin
 	var authEnabledOnTarget bool
@@ -2081,7 +3137,7 @@ func forwardAuthToTarget(
 	}
 
 	if err != nil {
-		log.Errorf("Error detected while checking if auth is enabled on %v to figure out which cluster should "+
+		logger.Errorf("Error detected while checking if auth is enabled on %v to figure out which cluster should "+
 			"receive the auth credentials from the client. Falling back to sending auth to %v and assuming "+
 			"that client credentials are meant for %v. "+
 			"This is a bug, please report: %v", clusterType, common.ClusterTypeOrigin, common.ClusterTypeTarget, err)
@@ -2140,18 +3196,20 @@ type customResponse struct {
 type protocolEventObserverImpl struct {
 	cancelFn       context.CancelFunc
 	connectionHost *Host
+	logger         *log.Entry
 }
 
-func NewProtocolEventObserver(cancelFunc context.CancelFunc, host *Host) *protocolEventObserverImpl {
+func NewProtocolEventObserver(cancelFunc context.CancelFunc, host *Host, logger *log.Entry) *protocolEventObserverImpl {
 	return &protocolEventObserverImpl{
 		cancelFn:       cancelFunc,
 		connectionHost: host,
+		logger:         logger,
 	}
 }
 
 func (recv *protocolEventObserverImpl) OnHostRemoved(host *Host) {
 	if recv.connectionHost.HostId == host.HostId {
-		log.Infof("Host used in connection was removed, closing connection: %v", host)
+		recv.logger.Infof("Host used in connection was removed, closing connection: %v", host)
 		recv.cancelFn()
 	}
 }
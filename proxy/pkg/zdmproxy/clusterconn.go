@@ -2,6 +2,7 @@ package zdmproxy
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/hex"
 	"errors"
@@ -54,6 +55,9 @@ type ClusterConnector struct {
 
 	clusterConnEventsChan  chan *frame.RawFrame
 	nodeMetrics            *metrics.NodeMetrics
+	oversizedResponses     metrics.Counter
+	readResponses          *readResponseTracker // only set for async connectors
+	responseWarnings       metrics.Counter
 	clientHandlerWg        *sync.WaitGroup
 	clientHandlerRequestWg *sync.WaitGroup
 	clusterConnContext     context.Context
@@ -71,6 +75,43 @@ type ClusterConnector struct {
 	asyncPendingRequests *pendingRequests
 
 	readScheduler *Scheduler
+	readShard     int
+
+	// see startHeartbeats, heartbeats are disabled if heartbeatInterval is 0
+	heartbeatInterval  time.Duration
+	heartbeatTimeout   time.Duration
+	heartbeatResponses chan *frame.RawFrame
+	lastReadNanos      *int64
+
+	// nil unless ZDM_REQUEST_CONNECTION_FAILOVER_ENABLED is true, connection is a *failoverConn in that case
+	failover *requestConnectionFailover
+
+	// nil unless faults are injected in the responses of this cluster, see ZDM_FAULT_INJECTION_ENABLED
+	faultInjector *faultInjector
+
+	// nil unless the protocol version of the client is translated for this cluster, see
+	// ZDM_PROTOCOL_VERSION_TRANSLATION_ENABLED
+	versionTranslator *atomic.Value
+
+	// used to open the stream id overflow and alternate host connections, see newAdditionalConnector
+	connInfo        *ClusterConnectionInfo
+	writeScheduler  *Scheduler
+	writeBufferPool *bufferPool
+
+	// nil unless ZDM_STREAM_ID_VIRTUALIZATION_ENABLED is true (never set for the async connector), see streamIdMapper
+	streamIds *streamIdMapper
+
+	// nil unless ZDM_STREAM_ID_OVERFLOW_CONNECTION_ENABLED is true, always nil for the overflow connectors themselves
+	streamIdOverflow *streamIdOverflow
+
+	// nil for the async connector and for the additional connectors, see newAdditionalConnector
+	alternateHost *alternateHost
+
+	// set by the client handler when stream ids are virtualized, see rejectRequest
+	rejectedResponses func(response *Response)
+
+	// logger with the fields of the client connection and the connector type
+	logger *log.Entry
 }
 
 func NewClusterConnectionInfo(connConfig ConnectionConfig, endpointConfig Endpoint, isOriginCassandra bool) *ClusterConnectionInfo {
@@ -86,6 +127,9 @@ func NewClusterConnector(
 	conf *config.Config,
 	psCache *PreparedStatementCache,
 	nodeMetrics *metrics.NodeMetrics,
+	oversizedResponses metrics.Counter,
+	readResponses *readResponseTracker,
+	responseWarnings metrics.Counter,
 	clientHandlerWg *sync.WaitGroup,
 	clientHandlerRequestWg *sync.WaitGroup,
 	clientHandlerContext context.Context,
@@ -93,10 +137,13 @@ func NewClusterConnector(
 	responseChan chan<- *Response,
 	readScheduler *Scheduler,
 	writeScheduler *Scheduler,
+	writeBufferPool *bufferPool,
 	requestsDoneCtx context.Context,
 	asyncConnector bool,
 	asyncPendingRequests *pendingRequests,
-	handshakeDone *atomic.Value) (*ClusterConnector, error) {
+	handshakeDone *atomic.Value,
+	faultInjector *faultInjector,
+	logger *log.Entry) (*ClusterConnector, error) {
 
 	var connectorType ClusterConnectorType
 	var clusterType common.ClusterType
@@ -112,7 +159,7 @@ func NewClusterConnector(
 		connectorType = ClusterConnectorTypeAsync
 	}
 
-	conn, timeoutCtx, err := openConnectionToCluster(connInfo, clientHandlerContext, connectorType, nodeMetrics)
+	conn, timeoutCtx, err := openConnectionToCluster(connInfo, clientHandlerContext, connectorType, nodeMetrics, logger)
 	if err != nil {
 		if errors.Is(err, ShutdownErr) {
 			if timeoutCtx.Err() != nil {
@@ -122,6 +169,21 @@ func NewClusterConnector(
 		return nil, fmt.Errorf("%s could not open connection to %v: %w", connectorType, clusterType, err)
 	}
 
+	var failover *requestConnectionFailover
+	if conf.RequestConnectionFailoverEnabled && !asyncConnector {
+		fConn := newFailoverConn(conn)
+		failover = newRequestConnectionFailover(fConn, connInfo, conf.RequestConnectionFailoverMaxAttempts)
+		conn = fConn
+	}
+
+	// wrapped after the failover connection so that the bytes of a connection that failed over are still counted
+	nodeMetricsInstance, err := GetNodeMetricsByClusterConnector(nodeMetrics, connectorType)
+	if err != nil {
+		closeConnectionToCluster(conn, clusterType, connectorType, nodeMetrics, logger)
+		return nil, err
+	}
+	conn = newMeteredConn(conn, nodeMetricsInstance.BytesReceived, nodeMetricsInstance.BytesSent)
+
 	clusterConnCtx, clusterConnCancelFn := context.WithCancel(clientHandlerContext)
 
 	go func() {
@@ -130,14 +192,27 @@ func NewClusterConnector(
 			clusterConnCancelFn()
 		case <-clusterConnCtx.Done():
 		}
-		closeConnectionToCluster(conn, clusterType, connectorType, nodeMetrics)
+		closeConnectionToCluster(conn, clusterType, connectorType, nodeMetrics, logger)
 	}()
 
+	lastReadNanos := time.Now().UnixNano()
+	versionTranslator := &atomic.Value{}
+	versionTranslator.Store((*protocolVersionTranslator)(nil))
 	cancelFn := clusterConnCancelFn
 	var clusterConnEventsChan chan *frame.RawFrame
+	var streamIds *streamIdMapper
+	var overflow *streamIdOverflow
+	var alternate *alternateHost
 	if !asyncConnector {
+		alternate = newAlternateHost()
 		cancelFn = clientHandlerCancelFunc
 		clusterConnEventsChan = make(chan *frame.RawFrame, conf.EventQueueSizeFrames)
+		if conf.StreamIdVirtualizationEnabled {
+			streamIds = newStreamIdMapper(conf.RequestConnectionMaxStreamIds)
+		}
+		if conf.StreamIdOverflowConnectionEnabled {
+			overflow = newStreamIdOverflow()
+		}
 	}
 
 	return &ClusterConnector{
@@ -148,6 +223,9 @@ func NewClusterConnector(
 		clusterConnEventsChan:  clusterConnEventsChan,
 		psCache:                psCache,
 		nodeMetrics:            nodeMetrics,
+		oversizedResponses:     oversizedResponses,
+		readResponses:          readResponses,
+		responseWarnings:       responseWarnings,
 		clientHandlerWg:        clientHandlerWg,
 		clientHandlerRequestWg: clientHandlerRequestWg,
 		clusterConnContext:     clusterConnCtx,
@@ -161,15 +239,32 @@ func NewClusterConnector(
 			string(connectorType),
 			true,
 			asyncConnector,
-			writeScheduler),
+			writeScheduler,
+			writeBufferPool,
+			logger),
 		responseChan:                responseChan,
 		responseReadBufferSizeBytes: conf.ResponseReadBufferSizeBytes,
 		doneChan:                    make(chan bool),
 		readScheduler:               readScheduler,
+		readShard:                   readScheduler.NextShard(),
 		asyncConnector:              asyncConnector,
 		asyncConnectorState:         ConnectorStateHandshake,
 		asyncPendingRequests:        asyncPendingRequests,
 		handshakeDone:               handshakeDone,
+		heartbeatInterval:           time.Duration(conf.RequestConnectionHeartbeatIntervalMs) * time.Millisecond,
+		heartbeatTimeout:            time.Duration(conf.RequestConnectionHeartbeatTimeoutMs) * time.Millisecond,
+		heartbeatResponses:          make(chan *frame.RawFrame, 1),
+		lastReadNanos:               &lastReadNanos,
+		failover:                    failover,
+		faultInjector:               faultInjector,
+		versionTranslator:           versionTranslator,
+		connInfo:                    connInfo,
+		writeScheduler:              writeScheduler,
+		writeBufferPool:             writeBufferPool,
+		streamIds:                   streamIds,
+		streamIdOverflow:            overflow,
+		alternateHost:               alternate,
+		logger:                      logger.WithField("connector", connectorType),
 	}, nil
 }
 
@@ -178,9 +273,9 @@ func (cc *ClusterConnector) run() {
 	cc.writeCoalescer.RunWriteQueueLoop()
 }
 
-func openConnectionToCluster(connInfo *ClusterConnectionInfo, context context.Context, connectorType ClusterConnectorType, nodeMetrics *metrics.NodeMetrics) (net.Conn, context.Context, error) {
+func openConnectionToCluster(connInfo *ClusterConnectionInfo, context context.Context, connectorType ClusterConnectorType, nodeMetrics *metrics.NodeMetrics, logger *log.Entry) (net.Conn, context.Context, error) {
 	clusterType := connInfo.connConfig.GetClusterType()
-	log.Infof("[%s] Opening request connection to %v (%v).", connectorType, clusterType, connInfo.endpoint.GetEndpointIdentifier())
+	logger.Infof("[%s] Opening request connection to %v (%v).", connectorType, clusterType, connInfo.endpoint.GetEndpointIdentifier())
 	conn, timeoutCtx, err := openConnection(connInfo.connConfig, connInfo.endpoint, context, true)
 	if err != nil {
 		return nil, timeoutCtx, err
@@ -188,30 +283,30 @@ func openConnectionToCluster(connInfo *ClusterConnectionInfo, context context.Co
 
 	nodeMetricsInstance, err := GetNodeMetricsByClusterConnector(nodeMetrics, connectorType)
 	if err != nil {
-		log.Errorf("Failed to track open connection metrics for conn %v: %v.", conn.RemoteAddr().String(), err)
+		logger.Errorf("Failed to track open connection metrics for conn %v: %v.", conn.RemoteAddr().String(), err)
 	} else {
 		nodeMetricsInstance.OpenConnections.Add(1)
 	}
 
-	log.Infof("[%s] Request connection to %v (%v) has been opened.", connectorType, clusterType, conn.RemoteAddr())
+	logger.Infof("[%s] Request connection to %v (%v) has been opened.", connectorType, clusterType, conn.RemoteAddr())
 	return conn, timeoutCtx, nil
 }
 
-func closeConnectionToCluster(conn net.Conn, clusterType common.ClusterType, connectorClusterType ClusterConnectorType, nodeMetrics *metrics.NodeMetrics) {
-	log.Infof("[%s] Closing request connection to %v (%v)", connectorClusterType, clusterType, conn.RemoteAddr())
+func closeConnectionToCluster(conn net.Conn, clusterType common.ClusterType, connectorClusterType ClusterConnectorType, nodeMetrics *metrics.NodeMetrics, logger *log.Entry) {
+	logger.Infof("[%s] Closing request connection to %v (%v)", connectorClusterType, clusterType, conn.RemoteAddr())
 	err := conn.Close()
 	if err != nil {
-		log.Warnf("[%s] Error closing connection to %v (%v)", connectorClusterType, clusterType, conn.RemoteAddr())
+		logger.Warnf("[%s] Error closing connection to %v (%v)", connectorClusterType, clusterType, conn.RemoteAddr())
 	}
 
 	nodeMetricsInstance, err := GetNodeMetricsByClusterConnector(nodeMetrics, connectorClusterType)
 	if err != nil {
-		log.Errorf("Failed to subtract open connection metrics for conn %v: %v.", conn.RemoteAddr().String(), err)
+		logger.Errorf("Failed to subtract open connection metrics for conn %v: %v.", conn.RemoteAddr().String(), err)
 	} else {
 		nodeMetricsInstance.OpenConnections.Subtract(1)
 	}
 
-	log.Infof("[%s] Request connection to %v (%v) has been closed", connectorClusterType, clusterType, conn.RemoteAddr())
+	logger.Infof("[%s] Request connection to %v (%v) has been closed", connectorClusterType, clusterType, conn.RemoteAddr())
 }
 
 /**
@@ -220,13 +315,15 @@ func closeConnectionToCluster(conn net.Conn, clusterType common.ClusterType, con
 func (cc *ClusterConnector) runResponseListeningLoop() {
 
 	cc.clientHandlerWg.Add(1)
-	log.Debugf("[%s] Listening to replies sent by node %v", cc.connectorType, cc.connection.RemoteAddr())
+	cc.logger.Debugf("[%s] Listening to replies sent by node %v", cc.connectorType, cc.connection.RemoteAddr())
 	go func() {
 		defer cc.clientHandlerWg.Done()
 		if cc.clusterConnEventsChan != nil {
 			defer close(cc.clusterConnEventsChan)
 		}
 		defer close(cc.doneChan)
+		defer cc.waitForStreamIdOverflowConnectors()
+		defer cc.waitForAlternateHostConnectors()
 		defer atomic.StoreInt32(&cc.asyncConnectorState, ConnectorStateShutdown)
 
 		bufferedReader := bufio.NewReaderSize(cc.connection, cc.responseReadBufferSizeBytes)
@@ -235,16 +332,32 @@ func (cc *ClusterConnector) runResponseListeningLoop() {
 		defer wg.Wait()
 		protocolErrOccurred := false
 		for {
-			response, err := readRawFrame(bufferedReader, connectionAddr, cc.clusterConnContext)
+			response, err := readRawFrame(bufferedReader, connectionAddr, cc.clusterConnContext, cc.conf.ProxyMaxResponseFrameSizeBytes)
+			if tooLargeErr, ok := err.(*frameTooLargeError); ok {
+				response, err = cc.replaceOversizedResponse(tooLargeErr)
+				if response == nil && err == nil {
+					continue
+				}
+			}
+			if err != nil && cc.failover != nil && cc.clusterConnContext.Err() == nil &&
+				cc.failover.run(cc.clusterConnContext, cc.connectorType, cc.logger) {
+				bufferedReader = bufio.NewReaderSize(cc.connection, cc.responseReadBufferSizeBytes)
+				connectionAddr = cc.connection.RemoteAddr().String()
+				atomic.StoreInt64(cc.lastReadNanos, time.Now().UnixNano())
+				if cc.streamIds != nil {
+					cc.releaseLostStreamIds(cc.failover.requestTimeout)
+				}
+				continue
+			}
 
-			protocolErrResponseFrame, err := checkProtocolError(response, err, protocolErrOccurred, string(cc.connectorType))
+			protocolErrResponseFrame, err := checkProtocolError(response, err, protocolErrOccurred, string(cc.connectorType), cc.logger)
 			if err != nil {
 				handleConnectionError(
-					err, cc.clusterConnContext, cc.cancelFunc, string(cc.connectorType), "reading", connectionAddr)
+					err, cc.clusterConnContext, cc.cancelFunc, string(cc.connectorType), "reading", connectionAddr, cc.logger)
 				break
 			} else {
 				if protocolErrOccurred {
-					log.Debugf("[%v] Data received after protocol error occured, ignoring it.", string(cc.connectorType))
+					cc.logger.Debugf("[%v] Data received after protocol error occured, ignoring it.", string(cc.connectorType))
 					continue
 				} else if protocolErrResponseFrame != nil {
 					response = protocolErrResponseFrame
@@ -252,12 +365,46 @@ func (cc *ClusterConnector) runResponseListeningLoop() {
 				}
 			}
 
+			if cc.heartbeatInterval > 0 {
+				atomic.StoreInt64(cc.lastReadNanos, time.Now().UnixNano())
+				if response.Header.StreamId == heartbeatStreamId && response.Header.OpCode != primitive.OpCodeEvent {
+					select {
+					case cc.heartbeatResponses <- response:
+					default:
+					}
+					continue
+				}
+			}
+
+			var speculativeReqCtx *requestContextImpl
+			if cc.streamIds != nil && response.Header.OpCode != primitive.OpCodeEvent {
+				var ok bool
+				if speculativeReqCtx, ok = cc.restoreClientStreamId(response); !ok {
+					continue
+				}
+			}
+
 			wg.Add(1)
-			cc.readScheduler.Schedule(func() {
+			cc.readScheduler.ScheduleOnShard(cc.readShard, func() {
 				defer wg.Done()
-				log.Tracef("[%s] Received response from %v (%v): %v",
+				cc.logger.Tracef("[%s] Received response from %v (%v): %v",
 					cc.connectorType, cc.clusterType, connectionAddr, response.Header)
 
+				if translator := cc.getProtocolVersionTranslator(); translator != nil {
+					translatedResponse, err := translator.translateResponse(response)
+					if err != nil {
+						cc.logger.Warnf("[%s] Could not translate response from %v: %v.", cc.connectorType, cc.clusterType, err)
+					}
+					if translatedResponse == nil {
+						return
+					}
+					response = translatedResponse
+				}
+
+				if response.Header.Flags.Contains(primitive.HeaderFlagWarning) {
+					cc.trackResponseWarnings(response)
+				}
+
 				if cc.asyncConnector {
 					response = cc.handleAsyncResponse(response)
 					if response == nil {
@@ -266,30 +413,68 @@ func (cc *ClusterConnector) runResponseListeningLoop() {
 				}
 
 				if response.Header.OpCode == primitive.OpCodeEvent {
-					cc.clusterConnEventsChan <- response
+					// the stream id overflow connectors don't have an events channel, their connections are not registered
+					if cc.clusterConnEventsChan != nil {
+						cc.clusterConnEventsChan <- response
+					}
+				} else if speculativeReqCtx != nil {
+					cc.responseChan <- NewSpeculativeResponse(response, cc.connectorType, speculativeReqCtx)
+				} else if cc.faultInjector != nil && cc.handshakeDone.Load() != nil {
+					cc.injectFault(response, wg)
 				} else {
 					cc.responseChan <- NewResponse(response, cc.connectorType)
 				}
-				log.Tracef("[%s] Response sent to response channel: %v", cc.connectorType, response.Header)
+				cc.logger.Tracef("[%s] Response sent to response channel: %v", cc.connectorType, response.Header)
 			})
 		}
-		log.Debugf("[%s] Shutting down response listening loop from %v", cc.connectorType, connectionAddr)
+		cc.logger.Debugf("[%s] Shutting down response listening loop from %v", cc.connectorType, connectionAddr)
+	}()
+}
+
+// injectFault forwards a response to the client handler with the faults of ZDM_FAULT_INJECTION_ENABLED. Delayed
+// responses are forwarded by a separate goroutine so that they don't delay the responses received after them.
+func (cc *ClusterConnector) injectFault(response *frame.RawFrame, wg *sync.WaitGroup) {
+	injectedResponse, delay, err := cc.faultInjector.inject(response)
+	if err != nil {
+		cc.logger.Errorf("[%s] Could not inject fault in response %v: %v.", cc.connectorType, response.Header, err)
+		injectedResponse, delay = response, 0
+	}
+	if injectedResponse == nil {
+		cc.logger.Debugf("[%s] Dropping response %v (fault injection).", cc.connectorType, response.Header)
+		return
+	}
+	if delay <= 0 {
+		cc.responseChan <- NewResponse(injectedResponse, cc.connectorType)
+		return
+	}
+
+	cc.logger.Debugf("[%s] Delaying response %v by %v (fault injection).", cc.connectorType, response.Header, delay)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			cc.responseChan <- NewResponse(injectedResponse, cc.connectorType)
+		case <-cc.clusterConnContext.Done():
+		}
 	}()
 }
 
 func (cc *ClusterConnector) handleAsyncResponse(response *frame.RawFrame) *frame.RawFrame {
 	errMsg, err := decodeError(response)
 	if err != nil {
-		log.Errorf("[%s] Error occured while checking if error is a protocol error: %v.", cc.connectorType, err)
+		cc.logger.Errorf("[%s] Error occured while checking if error is a protocol error: %v.", cc.connectorType, err)
 		cc.Shutdown()
 		return nil
 	}
 
 	if errMsg != nil && errMsg.GetErrorCode() == primitive.ErrorCodeProtocolError {
 		if cc.handshakeDone.Load() != nil {
-			log.Errorf("[%s] Protocol error occured in async connector, async requests will not be forwarded: %v.", cc.connectorType, errMsg)
+			cc.logger.Errorf("[%s] Protocol error occured in async connector, async requests will not be forwarded: %v.", cc.connectorType, errMsg)
 		} else {
-			log.Debugf("[%s] Protocol version downgrade detected in async connector, async requests will not be forwarded: %v.", cc.connectorType, errMsg)
+			cc.logger.Debugf("[%s] Protocol version downgrade detected in async connector, async requests will not be forwarded: %v.", cc.connectorType, errMsg)
 		}
 		cc.Shutdown()
 		return nil
@@ -303,16 +488,22 @@ func (cc *ClusterConnector) handleAsyncResponse(response *frame.RawFrame) *frame
 	if done {
 		typedReqCtx, ok := reqCtx.(*asyncRequestContextImpl)
 		if !ok {
-			log.Errorf("Failed to finish async request because request context conversion failed. "+
+			cc.logger.Errorf("Failed to finish async request because request context conversion failed. "+
 				"This is most likely a bug, please report. AsyncRequestContext: %v", reqCtx)
 		} else if typedReqCtx.expectedResponse {
 			response.Header.StreamId = typedReqCtx.requestStreamId
 			return response
+		} else if typedReqCtx.speculativeReqCtx != nil {
+			response.Header.StreamId = typedReqCtx.requestStreamId
+			cc.responseChan <- NewSpeculativeResponse(response, cc.connectorType, typedReqCtx.speculativeReqCtx)
+			cc.clientHandlerRequestWg.Done()
 		} else {
 			callDone := true
-			if errMsg != nil {
+			if errMsg == nil {
+				cc.trackAsyncReadResponse(response, reqCtx.GetRequestInfo())
+			} else {
 				if reqCtx.GetRequestInfo().ShouldBeTrackedInMetrics() {
-					trackClusterErrorMetricsFromErrorMessage(errMsg, cc.connectorType, cc.nodeMetrics)
+					trackClusterErrorMetricsFromErrorMessage(errMsg, cc.connectorType, cc.nodeMetrics, cc.logger)
 				}
 				switch msg := errMsg.(type) {
 				case *message.Unprepared:
@@ -324,7 +515,7 @@ func (cc *ClusterConnector) handleAsyncResponse(response *frame.RawFrame) *frame
 						preparedData, ok = cc.psCache.Get(msg.Id)
 					}
 					if !ok {
-						log.Warnf("Received UNPREPARED for async request with prepare ID %v "+
+						cc.logger.Warnf("Received UNPREPARED for async request with prepare ID %v "+
 							"but could not find prepared data.", hex.EncodeToString(msg.Id))
 					} else {
 						prepare := &message.Prepare{
@@ -334,7 +525,7 @@ func (cc *ClusterConnector) handleAsyncResponse(response *frame.RawFrame) *frame
 						prepareFrame := frame.NewFrame(response.Header.Version, response.Header.StreamId, prepare)
 						prepareRawFrame, err := defaultCodec.ConvertToRawFrame(prepareFrame)
 						if err != nil {
-							log.Errorf("Could not send async PREPARE because convert raw frame failed: %v.", err.Error())
+							cc.logger.Errorf("Could not send async PREPARE because convert raw frame failed: %v.", err.Error())
 						} else {
 							sent := cc.sendAsyncRequest(
 								preparedData.GetPrepareRequestInfo(), prepareRawFrame, false, time.Now(),
@@ -348,7 +539,7 @@ func (cc *ClusterConnector) handleAsyncResponse(response *frame.RawFrame) *frame
 						}
 					}
 				default:
-					log.Warnf("Async Request failed with error code %v. Error message: %v", errMsg.GetErrorCode(), errMsg.GetErrorMessage())
+					cc.logger.Warnf("Async Request failed with error code %v. Error message: %v", errMsg.GetErrorCode(), errMsg.GetErrorMessage())
 				}
 			}
 
@@ -360,15 +551,73 @@ func (cc *ClusterConnector) handleAsyncResponse(response *frame.RawFrame) *frame
 	return nil
 }
 
+// trackAsyncReadResponse decodes the response of an async read to track the rows and bytes that the secondary cluster
+// returned, the responses of the other requests are not decoded.
+func (cc *ClusterConnector) trackAsyncReadResponse(response *frame.RawFrame, requestInfo RequestInfo) {
+	if cc.readResponses == nil || response.Header.OpCode != primitive.OpCodeResult || !isTrackedRead(requestInfo) {
+		return
+	}
+	body, err := defaultCodec.DecodeBody(response.Header, bytes.NewReader(response.Body))
+	if err != nil {
+		cc.logger.Warnf("[%s] Could not decode async read response: %v.", cc.connectorType, err)
+		return
+	}
+	if result, ok := body.Message.(*message.RowsResult); ok {
+		cc.readResponses.track(response, result, requestInfo, cc.logger)
+	}
+}
+
+// trackResponseWarnings counts the warnings of the responses, including the responses of async requests, so that the
+// warnings that only one cluster returns can be noticed.
+func (cc *ClusterConnector) trackResponseWarnings(response *frame.RawFrame) {
+	warnings, err := countWarnings(response)
+	if err != nil {
+		cc.logger.Debugf("[%s] Could not count the warnings of a response from %v: %v.", cc.connectorType, cc.clusterType, err)
+		return
+	}
+	cc.responseWarnings.Add(warnings)
+}
+
+// replaceOversizedResponse returns a SERVER_ERROR in place of a response that was discarded because its body exceeded
+// ZDM_PROXY_MAX_RESPONSE_FRAME_SIZE_BYTES so that the request doesn't time out. Oversized events are skipped.
+func (cc *ClusterConnector) replaceOversizedResponse(tooLargeErr *frameTooLargeError) (*frame.RawFrame, error) {
+	cc.oversizedResponses.Add(1)
+	cc.logger.Warnf("[%s] Discarding response from %v: %v.", cc.connectorType, cc.clusterType, tooLargeErr)
+	if tooLargeErr.header.StreamId < 0 {
+		return nil, nil
+	}
+
+	msg := &message.ServerError{
+		ErrorMessage: fmt.Sprintf("Response body of %d bytes from %v exceeds the maximum of %d bytes allowed by the proxy",
+			tooLargeErr.header.BodyLength, cc.clusterType, tooLargeErr.maxBodySize),
+	}
+	response := frame.NewFrame(tooLargeErr.header.Version, tooLargeErr.header.StreamId, msg)
+	rawResponse, err := defaultCodec.ConvertToRawFrame(response)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert frame (%v) to raw frame: %w", response, err)
+	}
+	return rawResponse, nil
+}
+
 func (cc *ClusterConnector) sendRequestToCluster(frame *frame.RawFrame) {
-	cc.writeCoalescer.Enqueue(frame)
+	request, err := cc.translateRequest(frame)
+	if err != nil {
+		cc.logger.Errorf("[%s] Discarding %v request because it could not be translated: %v.",
+			cc.connectorType, frame.Header.OpCode, err)
+		return
+	}
+	if cc.streamIds != nil {
+		cc.sendRequestWithStreamId(frame, request)
+		return
+	}
+	cc.writeCoalescer.Enqueue(request)
 }
 
 func (cc *ClusterConnector) validateAsyncStateForRequest(frame *frame.RawFrame) bool {
 	state := atomic.LoadInt32(&cc.asyncConnectorState)
 	switch state {
 	case ConnectorStateShutdown:
-		log.Tracef("[%s] Discarding async %v request because async connector is shut down.",
+		cc.logger.Tracef("[%s] Discarding async %v request because async connector is shut down.",
 			cc.connectorType, frame.Header.OpCode.String())
 		return false
 	case ConnectorStateHandshake:
@@ -376,20 +625,26 @@ func (cc *ClusterConnector) validateAsyncStateForRequest(frame *frame.RawFrame)
 		case primitive.OpCodeStartup, primitive.OpCodeAuthResponse, primitive.OpCodeOptions:
 			return true
 		default:
-			log.Debugf("[%s] Discarding async %v request because async connector is not ready.",
+			cc.logger.Debugf("[%s] Discarding async %v request because async connector is not ready.",
 				cc.connectorType, frame.Header.OpCode.String())
 			return false
 		}
 	case ConnectorStateReady:
 		return true
 	default:
-		log.Errorf("Unknown cluster connector state: %v. This is a bug, please report.", state)
+		cc.logger.Errorf("Unknown cluster connector state: %v. This is a bug, please report.", state)
 		return false
 	}
 }
 
 func (cc *ClusterConnector) sendAsyncRequestToCluster(frame *frame.RawFrame) bool {
-	return cc.writeCoalescer.EnqueueAsync(frame)
+	request, err := cc.translateRequest(frame)
+	if err != nil {
+		cc.logger.Debugf("[%s] Discarding async %v request because it could not be translated: %v.",
+			cc.connectorType, frame.Header.OpCode, err)
+		return false
+	}
+	return cc.writeCoalescer.EnqueueAsync(request)
 }
 
 func (cc *ClusterConnector) SetReady() bool {
@@ -406,14 +661,14 @@ func (cc *ClusterConnector) Shutdown() {
 
 // Checks if the error was due to a shutdown request, triggering the cancellation function if it was not.
 // Also logs the error appropriately.
-func handleConnectionError(err error, ctx context.Context, cancelFn context.CancelFunc, logPrefix string, operation string, connectionAddr string) {
+func handleConnectionError(err error, ctx context.Context, cancelFn context.CancelFunc, logPrefix string, operation string, connectionAddr string, logger *log.Entry) {
 	if errors.Is(err, ShutdownErr) {
 		return
 	}
 	if errors.Is(err, io.EOF) || IsPeerDisconnect(err) || IsClosingErr(err) {
-		log.Infof("[%v] %v disconnected", logPrefix, connectionAddr)
+		logger.Infof("[%v] %v disconnected", logPrefix, connectionAddr)
 	} else {
-		log.Errorf("[%v] error %v: %v", logPrefix, operation, err)
+		logger.Errorf("[%v] error %v: %v", logPrefix, operation, err)
 	}
 
 	if ctx.Err() == nil {
@@ -434,11 +689,39 @@ func (cc *ClusterConnector) sendAsyncRequest(
 	}
 
 	asyncReqCtx := NewAsyncRequestContext(requestInfo, asyncRequest.Header.StreamId, expectedResponse, overallRequestStartTime)
+	return cc.sendAsyncRequestWithContext(asyncReqCtx, asyncRequest, requestTimeout, onTimeout)
+}
+
+// sendSpeculativeRequest sends a duplicate of a read that is still pending on the primary cluster. The response is
+// routed back to reqCtx (and not to the request that currently uses the same client stream id) once it is received.
+func (cc *ClusterConnector) sendSpeculativeRequest(
+	reqCtx *requestContextImpl,
+	speculativeRequest *frame.RawFrame,
+	requestTimeout time.Duration,
+	onTimeout func()) bool {
+
+	if !cc.validateAsyncStateForRequest(speculativeRequest) {
+		return false
+	}
+
+	asyncReqCtx := NewAsyncRequestContext(
+		reqCtx.GetRequestInfo(), speculativeRequest.Header.StreamId, false, time.Now())
+	asyncReqCtx.speculativeReqCtx = reqCtx
+	return cc.sendAsyncRequestWithContext(asyncReqCtx, speculativeRequest, requestTimeout, onTimeout)
+}
+
+func (cc *ClusterConnector) sendAsyncRequestWithContext(
+	asyncReqCtx *asyncRequestContextImpl,
+	asyncRequest *frame.RawFrame,
+	requestTimeout time.Duration,
+	onTimeout func()) bool {
+
+	requestInfo := asyncReqCtx.GetRequestInfo()
 	var newStreamId int16
 	newStreamId, err := cc.asyncPendingRequests.store(asyncReqCtx)
 	storedAsync := err == nil
 	if err != nil {
-		log.Warnf("Could not send async request due to an error while storing the request state: %v.", err.Error())
+		cc.logger.Warnf("Could not send async request due to an error while storing the request state: %v.", err.Error())
 	} else {
 		if requestInfo.ShouldBeTrackedInMetrics() {
 			cc.nodeMetrics.AsyncMetrics.InFlightRequests.Add(1)
@@ -446,7 +729,7 @@ func (cc *ClusterConnector) sendAsyncRequest(
 		asyncRequest.Header.StreamId = newStreamId
 		timer := time.AfterFunc(requestTimeout, func() {
 			if cc.asyncPendingRequests.timeOut(newStreamId, asyncReqCtx, asyncRequest) {
-				log.Warnf(
+				cc.logger.Warnf(
 					"Async Request (%v) timed out after %v ms.",
 					asyncRequest.Header.OpCode.String(), requestTimeout.Milliseconds())
 				onTimeout()
@@ -457,7 +740,7 @@ func (cc *ClusterConnector) sendAsyncRequest(
 	}
 
 	if err == nil {
-		log.Tracef("Forwarding ASYNC request with opcode %v for stream %v to %v",
+		cc.logger.Tracef("Forwarding ASYNC request with opcode %v for stream %v to %v",
 			asyncRequest.Header.OpCode, asyncRequest.Header.StreamId, cc.clusterType)
 		if !cc.sendAsyncRequestToCluster(asyncRequest) {
 			err = errors.New("async request was not sent")
@@ -8,6 +8,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"net"
 	"sync"
+	"sync/atomic"
 )
 
 const (
@@ -32,6 +33,14 @@ type writeCoalescer struct {
 	writeBufferSizeBytes int
 
 	scheduler *Scheduler
+	shard     int
+
+	bufferPool *bufferPool
+
+	// *frameCompressor, only set on client connections that negotiated compression
+	compressor *atomic.Value
+
+	logger *log.Entry
 }
 
 func NewWriteCoalescer(
@@ -43,7 +52,9 @@ func NewWriteCoalescer(
 	logPrefix string,
 	isRequest bool,
 	isAsync bool,
-	scheduler *Scheduler) *writeCoalescer {
+	scheduler *Scheduler,
+	bufferPool *bufferPool,
+	logger *log.Entry) *writeCoalescer {
 
 	writeQueueSizeFrames := conf.RequestWriteQueueSizeFrames
 	if !isRequest {
@@ -71,12 +82,20 @@ func NewWriteCoalescer(
 		waitGroup:              &sync.WaitGroup{},
 		writeBufferSizeBytes:   writeBufferSizeBytes,
 		scheduler:              scheduler,
+		shard:                  scheduler.NextShard(),
+		bufferPool:             bufferPool,
+		compressor:             &atomic.Value{},
+		logger:                 logger,
 	}
 }
 
+func (recv *writeCoalescer) setCompressor(compressor *frameCompressor) {
+	recv.compressor.Store(compressor)
+}
+
 func (recv *writeCoalescer) RunWriteQueueLoop() {
 	connectionAddr := recv.connection.RemoteAddr().String()
-	log.Tracef("[%v] WriteQueueLoop starting for %v", recv.logPrefix, connectionAddr)
+	recv.logger.Tracef("[%v] WriteQueueLoop starting for %v", recv.logPrefix, connectionAddr)
 
 	recv.clientHandlerWaitGroup.Add(1)
 	recv.waitGroup.Add(1)
@@ -85,7 +104,6 @@ func (recv *writeCoalescer) RunWriteQueueLoop() {
 		defer recv.waitGroup.Done()
 
 		draining := false
-		bufferedWriter := bytes.NewBuffer(make([]byte, 0, initialBufferSize))
 
 		for {
 			var resultOk bool
@@ -98,8 +116,8 @@ func (recv *writeCoalescer) RunWriteQueueLoop() {
 
 			resultChannel := make(chan *coalescerIterationResult, 1)
 			tempDraining := draining
-			tempBuffer := bufferedWriter
-			recv.scheduler.Schedule(func() {
+			tempBuffer := recv.bufferPool.Get()
+			recv.scheduler.ScheduleOnShard(recv.shard, func() {
 				firstFrameRead := false
 				for {
 					var f *frame.RawFrame
@@ -123,7 +141,7 @@ func (recv *writeCoalescer) RunWriteQueueLoop() {
 
 						if tempDraining {
 							// continue draining the write queue without writing on connection until it is closed
-							log.Tracef("[%v] Discarding frame from write queue because shutdown was requested: %v", recv.logPrefix, f.Header)
+							recv.logger.Tracef("[%v] Discarding frame from write queue because shutdown was requested: %v", recv.logPrefix, f.Header)
 							continue
 						}
 					} else {
@@ -132,11 +150,25 @@ func (recv *writeCoalescer) RunWriteQueueLoop() {
 						ok = true
 					}
 
-					log.Tracef("[%v] Writing %v on %v", recv.logPrefix, f.Header, connectionAddr)
+					var compressedBody *bytes.Buffer
+					if compressor, _ := recv.compressor.Load().(*frameCompressor); compressor != nil {
+						compressedBody = recv.bufferPool.Get()
+						compressed, err := compressor.compress(f, compressedBody)
+						if err != nil {
+							// compression is optional, the frame is sent uncompressed
+							recv.logger.Warnf("[%v] Could not compress %v: %v", recv.logPrefix, f.Header, err)
+						} else {
+							f = compressed
+						}
+					}
+
+					recv.logger.Tracef("[%v] Writing %v on %v", recv.logPrefix, f.Header, connectionAddr)
 					err := writeRawFrame(tempBuffer, connectionAddr, recv.shutdownContext, f)
+					// the compressed body was copied to the write buffer
+					recv.bufferPool.Put(compressedBody)
 					if err != nil {
 						tempDraining = true
-						handleConnectionError(err, recv.shutdownContext, recv.cancelFunc, recv.logPrefix, "writing", connectionAddr)
+						handleConnectionError(err, recv.shutdownContext, recv.cancelFunc, recv.logPrefix, "writing", connectionAddr, recv.logger)
 					} else {
 						if tempBuffer.Len() >= recv.writeBufferSizeBytes {
 							t := &coalescerIterationResult{
@@ -157,33 +189,33 @@ func (recv *writeCoalescer) RunWriteQueueLoop() {
 			}
 
 			draining = result.draining
-			bufferedWriter = result.buffer
+			bufferedWriter := result.buffer
 			if bufferedWriter.Len() > 0 && !draining {
 				_, err := recv.connection.Write(bufferedWriter.Bytes())
-				bufferedWriter.Reset()
 				if err != nil {
-					handleConnectionError(err, recv.shutdownContext, recv.cancelFunc, recv.logPrefix, "writing", connectionAddr)
+					handleConnectionError(err, recv.shutdownContext, recv.cancelFunc, recv.logPrefix, "writing", connectionAddr, recv.logger)
 					draining = true
 				}
 			}
+			recv.bufferPool.Put(bufferedWriter)
 		}
 	}()
 }
 
 func (recv *writeCoalescer) Enqueue(frame *frame.RawFrame) {
-	log.Tracef("[%v] Sending %v to write queue on %v", recv.logPrefix, frame.Header, recv.connection.RemoteAddr())
+	recv.logger.Tracef("[%v] Sending %v to write queue on %v", recv.logPrefix, frame.Header, recv.connection.RemoteAddr())
 	recv.writeQueue <- frame
-	log.Tracef("[%v] Sent %v to write queue on %v", recv.logPrefix, frame.Header, recv.connection.RemoteAddr())
+	recv.logger.Tracef("[%v] Sent %v to write queue on %v", recv.logPrefix, frame.Header, recv.connection.RemoteAddr())
 }
 
 func (recv *writeCoalescer) EnqueueAsync(frame *frame.RawFrame) bool {
-	log.Tracef("[%v] Sending %v to write queue on %v", recv.logPrefix, frame.Header, recv.connection.RemoteAddr())
+	recv.logger.Tracef("[%v] Sending %v to write queue on %v", recv.logPrefix, frame.Header, recv.connection.RemoteAddr())
 	select {
 	case recv.writeQueue <- frame:
-		log.Tracef("[%v] Sent %v to write queue on %v", recv.logPrefix, frame.Header, recv.connection.RemoteAddr())
+		recv.logger.Tracef("[%v] Sent %v to write queue on %v", recv.logPrefix, frame.Header, recv.connection.RemoteAddr())
 		return true
 	default:
-		log.Debugf("[%v] Discarded %v because write queue is full on %v", recv.logPrefix, frame.Header, recv.connection.RemoteAddr())
+		recv.logger.Debugf("[%v] Discarded %v because write queue is full on %v", recv.logPrefix, frame.Header, recv.connection.RemoteAddr())
 		return false
 	}
 }
@@ -0,0 +1,114 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/compression/lz4"
+	"github.com/datastax/go-cassandra-native-protocol/compression/snappy"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"strings"
+)
+
+const (
+	compressionLz4    = "lz4"
+	compressionSnappy = "snappy"
+)
+
+// frameCompressor compresses and decompresses the bodies of the frames exchanged with a client that negotiated
+// compression in its STARTUP request.
+//
+// Compression is only applied on the client connection: the proxy removes the COMPRESSION option from the STARTUP
+// request that it forwards to the clusters so every frame that flows through the proxy is uncompressed and can be
+// parsed and modified without a compressor.
+type frameCompressor struct {
+	algorithm  string
+	compressor frame.BodyCompressor
+}
+
+func newFrameCompressor(algorithm string) (*frameCompressor, error) {
+	algorithm = strings.ToLower(algorithm)
+	var compressor frame.BodyCompressor
+	switch algorithm {
+	case compressionLz4:
+		compressor = lz4.Compressor{}
+	case compressionSnappy:
+		compressor = snappy.Compressor{}
+	default:
+		return nil, fmt.Errorf("unknown compression algorithm: %v", algorithm)
+	}
+	return &frameCompressor{
+		algorithm:  algorithm,
+		compressor: compressor,
+	}, nil
+}
+
+// decompress returns the frame with an uncompressed body, which is the same frame if it was not compressed.
+func (recv *frameCompressor) decompress(f *frame.RawFrame) (*frame.RawFrame, error) {
+	if !f.Header.Flags.Contains(primitive.HeaderFlagCompressed) {
+		return f, nil
+	}
+	body := &bytes.Buffer{}
+	err := recv.compressor.DecompressWithLength(bytes.NewReader(f.Body), body)
+	if err != nil {
+		return nil, fmt.Errorf("could not decompress %v body: %w", recv.algorithm, err)
+	}
+	return newRawFrameWithBody(f, f.Header.Flags.Remove(primitive.HeaderFlagCompressed), body.Bytes()), nil
+}
+
+// compress returns the frame with a body compressed in the provided buffer, which is the same frame if it is already
+// compressed or if its opcode should not be compressed. The returned frame can't be used once the buffer is reused.
+func (recv *frameCompressor) compress(f *frame.RawFrame, body *bytes.Buffer) (*frame.RawFrame, error) {
+	if f.Header.Flags.Contains(primitive.HeaderFlagCompressed) || len(f.Body) == 0 {
+		return f, nil
+	}
+	switch f.Header.OpCode {
+	case primitive.OpCodeStartup, primitive.OpCodeOptions, primitive.OpCodeReady:
+		return f, nil
+	}
+	err := recv.compressor.CompressWithLength(bytes.NewReader(f.Body), body)
+	if err != nil {
+		return nil, fmt.Errorf("could not compress body with %v: %w", recv.algorithm, err)
+	}
+	return newRawFrameWithBody(f, f.Header.Flags.Add(primitive.HeaderFlagCompressed), body.Bytes()), nil
+}
+
+func newRawFrameWithBody(f *frame.RawFrame, flags primitive.HeaderFlag, body []byte) *frame.RawFrame {
+	header := f.Header.Clone()
+	header.Flags = flags
+	header.BodyLength = int32(len(body))
+	return &frame.RawFrame{
+		Header: header,
+		Body:   body,
+	}
+}
+
+// removeStartupCompression removes the COMPRESSION option from a STARTUP request. It returns the new request and
+// the compressor of the algorithm requested by the client, or nil if the client didn't request compression.
+func removeStartupCompression(request *frame.RawFrame) (*frame.RawFrame, *frameCompressor, error) {
+	decodedFrame, err := defaultCodec.ConvertFromRawFrame(request)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not decode STARTUP request: %w", err)
+	}
+	startup, ok := decodedFrame.Body.Message.(*message.Startup)
+	if !ok {
+		return nil, nil, fmt.Errorf("expected STARTUP request but got %v", decodedFrame.Body.Message)
+	}
+
+	algorithm, ok := startup.Options[message.StartupOptionCompression]
+	if !ok {
+		return request, nil, nil
+	}
+	compressor, err := newFrameCompressor(algorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	delete(startup.Options, message.StartupOptionCompression)
+	newRequest, err := defaultCodec.ConvertToRawFrame(decodedFrame)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not convert STARTUP request without compression to raw frame: %w", err)
+	}
+	return newRequest, compressor, nil
+}
@@ -0,0 +1,88 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"github.com/datastax/go-cassandra-native-protocol/compression/lz4"
+	"github.com/datastax/go-cassandra-native-protocol/compression/snappy"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestFrameCompressor(t *testing.T) {
+	tests := []struct {
+		algorithm  string
+		compressor frame.BodyCompressor
+	}{
+		{"lz4", lz4.Compressor{}},
+		{"SNAPPY", snappy.Compressor{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			compressor, err := newFrameCompressor(tt.algorithm)
+			require.Nil(t, err)
+			driverCodec := frame.NewRawCodecWithCompression(tt.compressor)
+
+			request := frame.NewFrame(primitive.ProtocolVersion4, 5, &message.Query{Query: "SELECT * FROM ks.tb"})
+			uncompressedRequest, err := defaultCodec.ConvertToRawFrame(request)
+			require.Nil(t, err)
+			compressedFrame := request.Clone()
+			compressedFrame.SetCompress(true)
+			compressedRequest, err := driverCodec.ConvertToRawFrame(compressedFrame)
+			require.Nil(t, err)
+
+			// requests compressed by the client are decompressed by the proxy
+			decompressed, err := compressor.decompress(compressedRequest)
+			require.Nil(t, err)
+			require.Equal(t, uncompressedRequest, decompressed)
+			same, err := compressor.decompress(uncompressedRequest)
+			require.Nil(t, err)
+			require.Same(t, uncompressedRequest, same)
+
+			// responses compressed by the proxy are decompressed by the client
+			response, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(
+				primitive.ProtocolVersion4, 5, &message.RowsResult{Metadata: &message.RowsMetadata{ColumnCount: 1}}))
+			require.Nil(t, err)
+			compressedResponse, err := compressor.compress(response, &bytes.Buffer{})
+			require.Nil(t, err)
+			require.True(t, compressedResponse.Header.Flags.Contains(primitive.HeaderFlagCompressed))
+			decodedResponse, err := driverCodec.ConvertFromRawFrame(compressedResponse)
+			require.Nil(t, err)
+			require.Equal(t, int32(1), decodedResponse.Body.Message.(*message.RowsResult).Metadata.ColumnCount)
+
+			ready, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Ready{}))
+			require.Nil(t, err)
+			same, err = compressor.compress(ready, &bytes.Buffer{})
+			require.Nil(t, err)
+			require.Same(t, ready, same)
+		})
+	}
+
+	_, err := newFrameCompressor("deflate")
+	require.NotNil(t, err)
+	require.Equal(t, "unknown compression algorithm: deflate", err.Error())
+}
+
+func TestRemoveStartupCompression(t *testing.T) {
+	startup := message.NewStartup()
+	request := mockFrame(t, startup, primitive.ProtocolVersion4)
+	newRequest, compressor, err := removeStartupCompression(request)
+	require.Nil(t, err)
+	require.Nil(t, compressor)
+	require.Same(t, request, newRequest)
+
+	startup = message.NewStartup()
+	startup.SetCompression(primitive.CompressionLz4)
+	newRequest, compressor, err = removeStartupCompression(mockFrame(t, startup, primitive.ProtocolVersion4))
+	require.Nil(t, err)
+	require.Equal(t, "lz4", compressor.algorithm)
+	decodedRequest, err := defaultCodec.ConvertFromRawFrame(newRequest)
+	require.Nil(t, err)
+	require.Equal(t, message.NewStartup(), decodedRequest.Body.Message)
+
+	startup.Options[message.StartupOptionCompression] = "deflate"
+	_, _, err = removeStartupCompression(mockFrame(t, startup, primitive.ProtocolVersion4))
+	require.NotNil(t, err)
+}
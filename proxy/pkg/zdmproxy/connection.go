@@ -4,9 +4,11 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
 	"github.com/jpillora/backoff"
 	log "github.com/sirupsen/logrus"
 	"net"
+	"sync/atomic"
 	"time"
 )
 
@@ -19,7 +21,7 @@ func openConnection(cc ConnectionConfig, ec Endpoint, ctx context.Context, useBa
 
 	if cc.GetTlsConfig() != nil {
 		// open connection using TLS
-		connection, err = openTLSConnection(ec, openConnectionTimeoutCtx, useBackoff)
+		connection, err = openTLSConnection(cc.GetDialer(), ec, openConnectionTimeoutCtx, useBackoff, cc.GetLogger())
 		if err != nil {
 			return nil, openConnectionTimeoutCtx, err
 		}
@@ -28,15 +30,15 @@ func openConnection(cc ConnectionConfig, ec Endpoint, ctx context.Context, useBa
 
 	// open plain TCP connection using contact points
 	if useBackoff {
-		connection, err = openTCPConnectionWithBackoff(ec.GetSocketEndpoint(), openConnectionTimeoutCtx)
+		connection, err = openTCPConnectionWithBackoff(cc.GetDialer(), ec.GetSocketEndpoint(), openConnectionTimeoutCtx, cc.GetLogger())
 	} else {
-		connection, err = openTCPConnection(ec.GetSocketEndpoint(), openConnectionTimeoutCtx)
+		connection, err = openTCPConnection(cc.GetDialer(), ec.GetSocketEndpoint(), openConnectionTimeoutCtx, cc.GetLogger())
 	}
 
 	return connection, openConnectionTimeoutCtx, err
 }
 
-func openTCPConnectionWithBackoff(addr string, ctx context.Context) (net.Conn, error) {
+func openTCPConnectionWithBackoff(dialer Dialer, addr string, ctx context.Context, logger *log.Entry) (net.Conn, error) {
 	b := &backoff.Backoff{
 		Min:    100 * time.Millisecond,
 		Max:    10 * time.Second,
@@ -44,8 +46,7 @@ func openTCPConnectionWithBackoff(addr string, ctx context.Context) (net.Conn, e
 		Jitter: false,
 	}
 
-	log.Debugf("[openTCPConnectionWithBackoff] Attempting to connect to %v...", addr)
-	dialer := net.Dialer{}
+	logger.Debugf("[openTCPConnectionWithBackoff] Attempting to connect to %v...", addr)
 	for {
 		conn, err := dialer.DialContext(ctx, "tcp", addr)
 		if err != nil {
@@ -53,20 +54,19 @@ func openTCPConnectionWithBackoff(addr string, ctx context.Context) (net.Conn, e
 				return nil, ShutdownErr
 			}
 			nextDuration := b.Duration()
-			log.Errorf("[openTCPConnectionWithBackoff] Couldn't connect to %v, retrying in %v...", addr, nextDuration)
+			logger.Errorf("[openTCPConnectionWithBackoff] Couldn't connect to %v, retrying in %v...", addr, nextDuration)
 			time.Sleep(nextDuration)
 			continue
 		}
-		log.Debugf("[openTCPConnectionWithBackoff] Successfully established connection with %v", conn.RemoteAddr())
+		logger.Debugf("[openTCPConnectionWithBackoff] Successfully established connection with %v", conn.RemoteAddr())
 		return conn, nil
 	}
 }
 
-func openTCPConnection(addr string, ctx context.Context) (net.Conn, error) {
-	log.Infof("[openTCPConnection] Opening connection to %v", addr)
+func openTCPConnection(dialer Dialer, addr string, ctx context.Context, logger *log.Entry) (net.Conn, error) {
+	logger.Infof("[openTCPConnection] Opening connection to %v", addr)
 
 	// Wait until the source database is up and ready to accept TCP connections.
-	dialer := net.Dialer{}
 	conn, err := dialer.DialContext(ctx, "tcp", addr)
 	if err != nil {
 		if ctx.Err() == context.Canceled {
@@ -74,31 +74,71 @@ func openTCPConnection(addr string, ctx context.Context) (net.Conn, error) {
 		}
 		return nil, err
 	}
-	log.Infof("[openTCPConnection] Successfully established connection with %v", conn.RemoteAddr())
+	logger.Infof("[openTCPConnection] Successfully established connection with %v", conn.RemoteAddr())
 
 	return conn, nil
 }
 
-func openTLSConnection(endpoint Endpoint, ctx context.Context, useBackoff bool) (*tls.Conn, error) {
+func openTLSConnection(
+	dialer Dialer, endpoint Endpoint, ctx context.Context, useBackoff bool, logger *log.Entry) (*tls.Conn, error) {
 
 	var tcpConn net.Conn
 	var err error
 	if useBackoff {
-		tcpConn, err = openTCPConnectionWithBackoff(endpoint.GetSocketEndpoint(), ctx)
+		tcpConn, err = openTCPConnectionWithBackoff(dialer, endpoint.GetSocketEndpoint(), ctx, logger)
 	} else {
-		tcpConn, err = openTCPConnection(endpoint.GetSocketEndpoint(), ctx)
+		tcpConn, err = openTCPConnection(dialer, endpoint.GetSocketEndpoint(), ctx, logger)
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	log.Infof("[openTLSConnection] Opening TLS connection to %v using underlying TCP connection", endpoint.GetEndpointIdentifier())
+	logger.Infof("[openTLSConnection] Opening TLS connection to %v using underlying TCP connection", endpoint.GetEndpointIdentifier())
 	tlsConn := tls.Client(tcpConn, endpoint.GetTlsConfig())
 	if err := tlsConn.Handshake(); err != nil {
 		tlsConn.Close()
 		return nil, err
 	}
-	log.Infof("[openTLSConnection] Successfully established connection with %v", endpoint.GetEndpointIdentifier())
+	logger.Infof("[openTLSConnection] Successfully established connection with %v", endpoint.GetEndpointIdentifier())
 
 	return tlsConn, nil
 }
+
+// meteredConn counts the bytes that are read from and written to a connection, in the metrics and per connection.
+type meteredConn struct {
+	net.Conn
+	bytesReceivedMetric metrics.Counter
+	bytesSentMetric     metrics.Counter
+	bytesReceived       int64 // accessed atomically
+	bytesSent           int64 // accessed atomically
+}
+
+func newMeteredConn(conn net.Conn, bytesReceived metrics.Counter, bytesSent metrics.Counter) *meteredConn {
+	return &meteredConn{Conn: conn, bytesReceivedMetric: bytesReceived, bytesSentMetric: bytesSent}
+}
+
+func (recv *meteredConn) Read(b []byte) (int, error) {
+	n, err := recv.Conn.Read(b)
+	if n > 0 {
+		recv.bytesReceivedMetric.Add(n)
+		atomic.AddInt64(&recv.bytesReceived, int64(n))
+	}
+	return n, err
+}
+
+func (recv *meteredConn) Write(b []byte) (int, error) {
+	n, err := recv.Conn.Write(b)
+	if n > 0 {
+		recv.bytesSentMetric.Add(n)
+		atomic.AddInt64(&recv.bytesSent, int64(n))
+	}
+	return n, err
+}
+
+func (recv *meteredConn) BytesReceived() int64 {
+	return atomic.LoadInt64(&recv.bytesReceived)
+}
+
+func (recv *meteredConn) BytesSent() int64 {
+	return atomic.LoadInt64(&recv.bytesSent)
+}
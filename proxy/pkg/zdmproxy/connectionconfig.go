@@ -5,8 +5,10 @@ import (
 	"crypto/tls"
 	"fmt"
 	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/secrets"
 	log "github.com/sirupsen/logrus"
 	"net"
+	"strings"
 	"sync"
 )
 
@@ -16,47 +18,63 @@ type ConnectionConfig interface {
 	GetTlsConfig() *tls.Config
 	UsesSNI() bool
 	GetConnectionTimeoutMs() int
+	GetDialer() Dialer
+	GetLogger() *log.Entry
 	GetContactPoints() []Endpoint
 	RefreshContactPoints(ctx context.Context) ([]Endpoint, error)
 	CreateEndpoint(h *Host) Endpoint
 }
 
 func InitializeConnectionConfig(clusterTlsConfig *common.ClusterTlsConfig, contactPointsFromConfig []string, port int,
-	connTimeoutInMs int, clusterType common.ClusterType, datacenterFromConfig string, ctx context.Context) (ConnectionConfig, error) {
+	connTimeoutInMs int, clusterType common.ClusterType, datacenterFromConfig string, secretStore *secrets.Store,
+	ipFamilyPreference common.IpFamilyPreference, dialer Dialer, logger *log.Entry,
+	ctx context.Context) (ConnectionConfig, error) {
 
 	var tlsConfig *tls.Config
 	var err error
 	if clusterTlsConfig.TlsEnabled {
 		if clusterTlsConfig.SecureConnectBundlePath != "" {
-			return initializeAstraConnectionConfig(connTimeoutInMs, clusterType, clusterTlsConfig.SecureConnectBundlePath, ctx)
+			return initializeAstraConnectionConfig(
+				connTimeoutInMs, clusterType, clusterTlsConfig.SecureConnectBundlePath, dialer, logger, ctx)
 		} else {
-			tlsConfig, err = getClientSideTlsConfigFromProxyClusterTlsConfig(clusterTlsConfig, clusterType)
+			tlsConfig, err = getClientSideTlsConfigFromProxyClusterTlsConfig(
+				clusterTlsConfig, clusterType, secretStore, logger)
 			if err != nil {
 				return nil, err
 			}
 		}
 	}
 
-	contactPoints := make([]Endpoint, 0)
-	for _, contactPoint := range contactPointsFromConfig {
-		contactPoints = append(contactPoints, NewDefaultEndpoint(contactPoint, port, tlsConfig))
+	connConfig := newGenericConnectionConfig(
+		tlsConfig, connTimeoutInMs, clusterType, datacenterFromConfig, contactPointsFromConfig, port, ipFamilyPreference,
+		dialer, logger)
+	_, err = connConfig.RefreshContactPoints(ctx)
+	if err != nil {
+		return nil, err
 	}
-	return newGenericConnectionConfig(tlsConfig, connTimeoutInMs, clusterType, datacenterFromConfig, contactPoints), nil
-
+	return connConfig, nil
 }
 
 type baseConnectionConfig struct {
 	tlsConfig           *tls.Config
 	connectionTimeoutMs int
 	clusterType         common.ClusterType
+	dialer              Dialer
+	logger              *log.Entry
 }
 
 func newBaseConnectionConfig(
-	tlsConfig *tls.Config, connectionTimeoutMs int, clusterType common.ClusterType) *baseConnectionConfig {
+	tlsConfig *tls.Config, connectionTimeoutMs int, clusterType common.ClusterType, dialer Dialer,
+	logger *log.Entry) *baseConnectionConfig {
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
 	return &baseConnectionConfig{
 		tlsConfig:           tlsConfig,
 		connectionTimeoutMs: connectionTimeoutMs,
 		clusterType:         clusterType,
+		dialer:              dialer,
+		logger:              logger,
 	}
 }
 
@@ -72,18 +90,54 @@ func (cc *baseConnectionConfig) GetClusterType() common.ClusterType {
 	return cc.clusterType
 }
 
+func (cc *baseConnectionConfig) GetDialer() Dialer {
+	return cc.dialer
+}
+
+func (cc *baseConnectionConfig) GetLogger() *log.Entry {
+	return cc.logger
+}
+
+const (
+	// Contact points with this prefix are DNS SRV records, e.g. srv:_cql._tcp.cassandra.default.svc.cluster.local
+	srvContactPointPrefix = "srv:"
+
+	// Contact points with this prefix are host names that resolve to one address per node (e.g. Kubernetes headless
+	// services), e.g. dns:cassandra.default.svc.cluster.local
+	dnsContactPointPrefix = "dns:"
+)
+
 type genericConnectionConfig struct {
 	*baseConnectionConfig
-	datacenter    string
-	contactPoints []Endpoint
+	datacenter              string
+	configuredContactPoints []string
+	port                    int
+	ipFamilyPreference      common.IpFamilyPreference
+
+	contactPoints     []Endpoint
+	contactPointsLock *sync.RWMutex
+
+	lookupSRV  func(ctx context.Context, name string) ([]*net.SRV, error)
+	lookupHost func(ctx context.Context, host string) ([]string, error)
 }
 
 func newGenericConnectionConfig(
-	tlsConfig *tls.Config, connectionTimeoutMs int, clusterType common.ClusterType, datacenter string, contactPoints []Endpoint) *genericConnectionConfig {
+	tlsConfig *tls.Config, connectionTimeoutMs int, clusterType common.ClusterType, datacenter string,
+	configuredContactPoints []string, port int, ipFamilyPreference common.IpFamilyPreference,
+	dialer Dialer, logger *log.Entry) *genericConnectionConfig {
 	return &genericConnectionConfig{
-		baseConnectionConfig: newBaseConnectionConfig(tlsConfig, connectionTimeoutMs, clusterType),
-		datacenter:           datacenter,
-		contactPoints:        contactPoints,
+		baseConnectionConfig:    newBaseConnectionConfig(tlsConfig, connectionTimeoutMs, clusterType, dialer, logger),
+		datacenter:              datacenter,
+		configuredContactPoints: configuredContactPoints,
+		port:                    port,
+		ipFamilyPreference:      ipFamilyPreference,
+		contactPoints:           nil,
+		contactPointsLock:       &sync.RWMutex{},
+		lookupSRV: func(ctx context.Context, name string) ([]*net.SRV, error) {
+			_, records, err := net.DefaultResolver.LookupSRV(ctx, "", "", name)
+			return records, err
+		},
+		lookupHost: net.DefaultResolver.LookupHost,
 	}
 }
 
@@ -97,11 +151,61 @@ func (cc *genericConnectionConfig) UsesSNI() bool {
 }
 
 func (cc *genericConnectionConfig) GetContactPoints() []Endpoint {
+	cc.contactPointsLock.RLock()
+	defer cc.contactPointsLock.RUnlock()
 	return cc.contactPoints
 }
 
+// RefreshContactPoints resolves the SRV records and host names of the contact points that use the srv: or dns:
+// prefixes. Other contact points are used as they are, i.e. they are resolved every time a connection is opened.
+//
+// Host names with the dns: prefix resolve to the addresses of both families, those of the family configured with
+// ZDM_IP_FAMILY_PREFERENCE come first.
 func (cc *genericConnectionConfig) RefreshContactPoints(ctx context.Context) ([]Endpoint, error) {
-	return cc.contactPoints, nil
+	endpoints := make([]Endpoint, 0, len(cc.configuredContactPoints))
+	for _, contactPoint := range cc.configuredContactPoints {
+		if strings.HasPrefix(contactPoint, srvContactPointPrefix) {
+			name := strings.TrimPrefix(contactPoint, srvContactPointPrefix)
+			records, err := cc.lookupSRV(ctx, name)
+			if err != nil {
+				return nil, fmt.Errorf("could not resolve SRV record %v of %v contact points: %w", name, cc.clusterType, err)
+			}
+			for _, record := range records {
+				endpoints = append(endpoints, NewDefaultEndpoint(strings.TrimSuffix(record.Target, "."), int(record.Port), cc.tlsConfig))
+			}
+		} else if strings.HasPrefix(contactPoint, dnsContactPointPrefix) {
+			host := strings.TrimPrefix(contactPoint, dnsContactPointPrefix)
+			addresses, err := cc.lookupHost(ctx, host)
+			if err != nil {
+				return nil, fmt.Errorf("could not resolve host name %v of %v contact points: %w", host, cc.clusterType, err)
+			}
+			ips := make([]net.IP, 0, len(addresses))
+			for _, address := range addresses {
+				if ip := net.ParseIP(address); ip != nil {
+					ips = append(ips, ip)
+				}
+			}
+			for _, ip := range common.SortIpsByFamily(ips, cc.ipFamilyPreference) {
+				endpoints = append(endpoints, NewDefaultEndpoint(ip.String(), cc.port, cc.tlsConfig))
+			}
+		} else {
+			endpoints = append(endpoints, NewDefaultEndpoint(contactPoint, cc.port, cc.tlsConfig))
+		}
+	}
+
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("no %v contact points were found after resolving %v", cc.clusterType, cc.configuredContactPoints)
+	}
+
+	cc.contactPointsLock.Lock()
+	oldEndpoints := cc.contactPoints
+	cc.contactPoints = endpoints
+	cc.contactPointsLock.Unlock()
+
+	if oldEndpoints != nil && fmt.Sprint(oldEndpoints) != fmt.Sprint(endpoints) {
+		cc.logger.Infof("%v contact points changed from %v to %v.", cc.clusterType, oldEndpoints, endpoints)
+	}
+	return endpoints, nil
 }
 
 func (cc *genericConnectionConfig) CreateEndpoint(h *Host) Endpoint {
@@ -127,13 +231,14 @@ type astraConnectionConfigImpl struct {
 }
 
 func initializeAstraConnectionConfig(
-	connectionTimeoutMs int, clusterType common.ClusterType, secureConnectBundlePath string, ctx context.Context) (*astraConnectionConfigImpl, error) {
-	fileMap, err := extractFilesFromZipArchive(secureConnectBundlePath)
+	connectionTimeoutMs int, clusterType common.ClusterType, secureConnectBundlePath string, dialer Dialer,
+	logger *log.Entry, ctx context.Context) (*astraConnectionConfigImpl, error) {
+	fileMap, err := extractFilesFromZipArchive(secureConnectBundlePath, logger)
 	if err != nil {
 		return nil, err
 	}
 
-	metadataServiceHostName, metadataServicePort, err := parseHostAndPortFromSCBConfig(fileMap["config.json"])
+	metadataServiceHostName, metadataServicePort, err := parseHostAndPortFromSCBConfig(fileMap["config.json"], logger)
 	if err != nil {
 		return nil, err
 	}
@@ -142,13 +247,14 @@ func initializeAstraConnectionConfig(
 		return nil, fmt.Errorf("incomplete metadata service contact information. hostname: %v, port: %v", metadataServiceHostName, metadataServicePort)
 	}
 
-	tlsConfig, err := initializeTlsConfigurationFromSecureConnectBundle(fileMap, metadataServiceHostName, clusterType)
+	tlsConfig, err := initializeTlsConfigurationFromSecureConnectBundle(
+		fileMap, metadataServiceHostName, clusterType, logger)
 	if err != nil {
 		return nil, err
 	}
 
 	connConfig := &astraConnectionConfigImpl{
-		baseConnectionConfig: newBaseConnectionConfig(tlsConfig, connectionTimeoutMs, clusterType),
+		baseConnectionConfig: newBaseConnectionConfig(tlsConfig, connectionTimeoutMs, clusterType, dialer, logger),
 		datacenter:           "",
 		metadataServiceName:  metadataServiceHostName,
 		metadataServicePort:  metadataServicePort,
@@ -211,11 +317,12 @@ func (cc *astraConnectionConfigImpl) createEndpointFromString(hostId string) End
 }
 
 func (cc *astraConnectionConfigImpl) refreshMetadata(ctx context.Context) (*AstraMetadata, []Endpoint, error) {
-	metadata, err := retrieveAstraMetadata(cc.metadataServiceName, cc.metadataServicePort, cc.GetTlsConfig(), ctx)
+	metadata, err := retrieveAstraMetadata(
+		cc.metadataServiceName, cc.metadataServicePort, cc.GetTlsConfig(), cc.GetDialer(), cc.logger, ctx)
 	if err != nil {
 		return nil, nil, err
 	}
-	log.Debugf("Astra metadata parsed to: %v", metadata)
+	cc.logger.Debugf("Astra metadata parsed to: %v", metadata)
 
 	sniProxyHostname, _, err := net.SplitHostPort(metadata.ContactInfo.SniProxyAddress)
 	if err != nil {
@@ -0,0 +1,84 @@
+package zdmproxy
+
+import (
+	"context"
+	"errors"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+)
+
+func TestGenericConnectionConfigRefreshContactPoints(t *testing.T) {
+	connConfig := newGenericConnectionConfig(nil, 1000, common.ClusterTypeOrigin, "",
+		[]string{"10.0.0.1", "srv:_cql._tcp.cassandra", "dns:cassandra-headless", "cassandra.example.com"}, 9042,
+		common.IpFamilyPreferenceV4, nil, log.NewEntry(log.StandardLogger()))
+	srvRecords := []*net.SRV{{Target: "cassandra-0.cassandra.", Port: 9043}, {Target: "cassandra-1.cassandra.", Port: 9043}}
+	hostAddresses := []string{"10.0.1.1", "10.0.1.2", "fe80::1"}
+	connConfig.lookupSRV = func(ctx context.Context, name string) ([]*net.SRV, error) {
+		require.Equal(t, "_cql._tcp.cassandra", name)
+		return srvRecords, nil
+	}
+	connConfig.lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		require.Equal(t, "cassandra-headless", host)
+		return hostAddresses, nil
+	}
+
+	endpoints, err := connConfig.RefreshContactPoints(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, []string{
+		"10.0.0.1:9042", "cassandra-0.cassandra:9043", "cassandra-1.cassandra:9043",
+		"10.0.1.1:9042", "10.0.1.2:9042", "[fe80::1]:9042", "cassandra.example.com:9042"}, endpointIdentifiers(endpoints))
+	require.Equal(t, endpoints, connConfig.GetContactPoints())
+
+	// pod addresses changed
+	hostAddresses = []string{"10.0.2.1"}
+	srvRecords = []*net.SRV{{Target: "cassandra-2.cassandra.", Port: 9043}}
+	endpoints, err = connConfig.RefreshContactPoints(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, []string{
+		"10.0.0.1:9042", "cassandra-2.cassandra:9043", "10.0.2.1:9042", "cassandra.example.com:9042"},
+		endpointIdentifiers(connConfig.GetContactPoints()))
+
+	// failed lookups keep the previous contact points
+	connConfig.lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		return nil, errors.New("no such host")
+	}
+	_, err = connConfig.RefreshContactPoints(context.Background())
+	require.NotNil(t, err)
+	require.Equal(t, endpoints, connConfig.GetContactPoints())
+}
+
+func TestGenericConnectionConfigIpv6(t *testing.T) {
+	connConfig := newGenericConnectionConfig(nil, 1000, common.ClusterTypeOrigin, "",
+		[]string{"fd00::1", "[fd00::2]", "dns:cassandra-headless"}, 9042, common.IpFamilyPreferenceV6, nil, log.NewEntry(log.StandardLogger()))
+	connConfig.lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		return []string{"10.0.1.1", "fd00::3", "10.0.1.2", "fd00::4"}, nil
+	}
+
+	endpoints, err := connConfig.RefreshContactPoints(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, []string{
+		"[fd00::1]:9042", "[fd00::2]:9042", "[fd00::3]:9042", "[fd00::4]:9042", "10.0.1.1:9042", "10.0.1.2:9042"},
+		endpointIdentifiers(endpoints))
+
+	// IPv4 is used when the host name has no IPv6 address
+	connConfig.lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		return []string{"10.0.1.1"}, nil
+	}
+	endpoints, err = connConfig.RefreshContactPoints(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, []string{"[fd00::1]:9042", "[fd00::2]:9042", "10.0.1.1:9042"}, endpointIdentifiers(endpoints))
+
+	host := &Host{Address: net.ParseIP("fd00::5"), Port: 9043}
+	require.Equal(t, "[fd00::5]:9043", connConfig.CreateEndpoint(host).GetSocketEndpoint())
+}
+
+func endpointIdentifiers(endpoints []Endpoint) []string {
+	identifiers := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		identifiers = append(identifiers, endpoint.GetEndpointIdentifier())
+	}
+	return identifiers
+}
@@ -0,0 +1,116 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+)
+
+// consistencyOverrides holds the consistency levels set with ZDM_ORIGIN_CONSISTENCY_OVERRIDE and
+// ZDM_TARGET_CONSISTENCY_OVERRIDE, a nil level means that the requests sent to that cluster keep the consistency
+// level chosen by the client.
+type consistencyOverrides struct {
+	origin *primitive.ConsistencyLevel
+	target *primitive.ConsistencyLevel
+}
+
+func newConsistencyOverrides(origin *primitive.ConsistencyLevel, target *primitive.ConsistencyLevel) *consistencyOverrides {
+	if origin == nil && target == nil {
+		return nil
+	}
+	return &consistencyOverrides{
+		origin: origin,
+		target: target,
+	}
+}
+
+// overrideConsistency returns a QUERY, EXECUTE or BATCH request with the provided consistency level and whether it
+// was changed. Requests with a serial consistency level (i.e. SERIAL reads) are not changed.
+func overrideConsistency(
+	request *frame.RawFrame, consistency primitive.ConsistencyLevel) (*frame.RawFrame, bool, error) {
+
+	switch request.Header.OpCode {
+	case primitive.OpCodeQuery, primitive.OpCodeExecute, primitive.OpCodeBatch:
+	default:
+		return request, false, nil
+	}
+
+	decodedFrame, err := defaultCodec.ConvertFromRawFrame(request)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not decode '%v' request to override consistency level: %w",
+			request.Header.OpCode.String(), err)
+	}
+
+	var options *message.QueryOptions
+	switch msg := decodedFrame.Body.Message.(type) {
+	case *message.Query:
+		if msg.Options == nil {
+			msg.Options = &message.QueryOptions{}
+		}
+		options = msg.Options
+	case *message.Execute:
+		if msg.Options == nil {
+			msg.Options = &message.QueryOptions{}
+		}
+		options = msg.Options
+	case *message.Batch:
+		if msg.Consistency == consistency || msg.Consistency.IsSerial() {
+			return request, false, nil
+		}
+		msg.Consistency = consistency
+	}
+	if options != nil {
+		if options.Consistency == consistency || options.Consistency.IsSerial() {
+			return request, false, nil
+		}
+		options.Consistency = consistency
+	}
+
+	newRequest, err := defaultCodec.ConvertToRawFrame(decodedFrame)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not convert frame with overridden consistency level to raw frame: %w", err)
+	}
+	return newRequest, true, nil
+}
+
+// overrideRequestsConsistency applies the consistency overrides to the requests that will be sent to each cluster,
+// including the async connector, and counts the overridden requests.
+func (ch *ClientHandler) overrideRequestsConsistency(
+	requestInfo RequestInfo, fwdDecision forwardDecision, originRequest *frame.RawFrame, targetRequest *frame.RawFrame,
+	logger *log.Entry) (*frame.RawFrame, *frame.RawFrame, error) {
+
+	sentToAsync := fwdDecision == forwardToAsyncOnly || ch.shouldAlsoSendToAsync(requestInfo, fwdDecision)
+	sentToOrigin := fwdDecision == forwardToOrigin || fwdDecision == forwardToBoth ||
+		(sentToAsync && ch.asyncConnector.clusterType == common.ClusterTypeOrigin)
+	sentToTarget := fwdDecision == forwardToTarget || fwdDecision == forwardToBoth ||
+		(sentToAsync && ch.asyncConnector.clusterType == common.ClusterTypeTarget)
+
+	proxyMetrics := ch.metricHandler.GetProxyMetrics()
+	var err error
+	if consistency := ch.consistencyOverrides.origin; consistency != nil && sentToOrigin {
+		var overridden bool
+		originRequest, overridden, err = overrideConsistency(originRequest, *consistency)
+		if err != nil {
+			return nil, nil, err
+		}
+		if overridden {
+			logger.Tracef("Overrode consistency level of request for %v with %v", common.ClusterTypeOrigin, consistency)
+			proxyMetrics.ConsistencyLevelOverridesOrigin.Add(1)
+		}
+	}
+	if consistency := ch.consistencyOverrides.target; consistency != nil && sentToTarget {
+		var overridden bool
+		targetRequest, overridden, err = overrideConsistency(targetRequest, *consistency)
+		if err != nil {
+			return nil, nil, err
+		}
+		if overridden {
+			logger.Tracef("Overrode consistency level of request for %v with %v", common.ClusterTypeTarget, consistency)
+			proxyMetrics.ConsistencyLevelOverridesTarget.Add(1)
+		}
+	}
+	return originRequest, targetRequest, nil
+}
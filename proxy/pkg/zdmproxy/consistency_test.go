@@ -0,0 +1,70 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestOverrideConsistency(t *testing.T) {
+	getConsistency := func(request *frame.RawFrame) primitive.ConsistencyLevel {
+		decodedFrame, err := defaultCodec.ConvertFromRawFrame(request)
+		require.Nil(t, err)
+		switch msg := decodedFrame.Body.Message.(type) {
+		case *message.Query:
+			return msg.Options.Consistency
+		case *message.Execute:
+			return msg.Options.Consistency
+		case *message.Batch:
+			return msg.Consistency
+		default:
+			require.Fail(t, "unexpected message %v", msg)
+			return 0
+		}
+	}
+
+	tests := []struct {
+		name       string
+		request    *frame.RawFrame
+		overridden bool
+	}{
+		{"query", mockFrame(t, &message.Query{
+			Query:   "SELECT * FROM ks.tb",
+			Options: &message.QueryOptions{Consistency: primitive.ConsistencyLevelLocalQuorum},
+		}, primitive.ProtocolVersion4), true},
+		{"query without options", mockQueryFrame(t, "SELECT * FROM ks.tb"), true},
+		{"execute", mockExecuteFrame(t, "prepared_id"), true},
+		{"batch", mockBatchWithChildren(t, []*message.BatchChild{
+			{QueryOrId: "INSERT INTO ks.tb (a) VALUES (1)"},
+		}), true},
+		{"same consistency", mockFrame(t, &message.Query{
+			Query:   "SELECT * FROM ks.tb",
+			Options: &message.QueryOptions{Consistency: primitive.ConsistencyLevelLocalOne},
+		}, primitive.ProtocolVersion4), false},
+		{"serial read", mockFrame(t, &message.Query{
+			Query:   "SELECT * FROM ks.tb WHERE a = 1",
+			Options: &message.QueryOptions{Consistency: primitive.ConsistencyLevelSerial},
+		}, primitive.ProtocolVersion4), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newRequest, overridden, err := overrideConsistency(tt.request, primitive.ConsistencyLevelLocalOne)
+			require.Nil(t, err)
+			require.Equal(t, tt.overridden, overridden)
+			if tt.overridden {
+				require.Equal(t, primitive.ConsistencyLevelLocalOne, getConsistency(newRequest))
+				require.Equal(t, tt.request.Header.StreamId, newRequest.Header.StreamId)
+			} else {
+				require.Same(t, tt.request, newRequest)
+			}
+		})
+	}
+
+	prepareRequest := mockFrame(t, &message.Prepare{Query: "SELECT * FROM ks.tb"}, primitive.ProtocolVersion4)
+	newRequest, overridden, err := overrideConsistency(prepareRequest, primitive.ConsistencyLevelLocalOne)
+	require.Nil(t, err)
+	require.False(t, overridden)
+	require.Same(t, prepareRequest, newRequest)
+}
@@ -0,0 +1,206 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// continuousPagingRequestInfo is a read that uses DSE continuous paging. The cluster streams the pages of the result
+// on the stream id of the request until the last page so the request is not sent to the async connector, which
+// expects a single response for each request.
+type continuousPagingRequestInfo struct {
+	RequestInfo
+}
+
+func (recv *continuousPagingRequestInfo) String() string {
+	return fmt.Sprintf("continuousPagingRequestInfo{%v}", recv.RequestInfo)
+}
+
+func (recv *continuousPagingRequestInfo) ShouldAlsoBeSentAsync() bool {
+	return false
+}
+
+// continuousPagingState keeps track of the pages of a continuous paging request that were received from the cluster.
+// The responses can be processed out of order by the response workers so the pages are buffered until they can be
+// sent to the client in order.
+type continuousPagingState struct {
+	lock           *sync.Mutex
+	nextPage       int32
+	pending        map[int32]*continuousPage
+	forwardedPages bool
+
+	// the request timeout is restarted every time a page is received
+	pageTimeout time.Duration
+}
+
+type continuousPage struct {
+	response *frame.RawFrame
+	last     bool
+}
+
+func newContinuousPagingState(pageTimeout time.Duration) *continuousPagingState {
+	return &continuousPagingState{
+		lock:        &sync.Mutex{},
+		nextPage:    1,
+		pending:     map[int32]*continuousPage{},
+		pageTimeout: pageTimeout,
+	}
+}
+
+// addPage stores a page and calls send for every page that can be sent to the client in order, except the last page
+// which is returned so that the request can be finished like any other request.
+func (recv *continuousPagingState) addPage(
+	response *frame.RawFrame, pageNumber int32, last bool, send func(*frame.RawFrame)) *frame.RawFrame {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	recv.pending[pageNumber] = &continuousPage{response: response, last: last}
+	for {
+		page, ok := recv.pending[recv.nextPage]
+		if !ok {
+			return nil
+		}
+		delete(recv.pending, recv.nextPage)
+		recv.nextPage++
+		if page.last {
+			return page.response
+		}
+		send(page.response)
+		recv.forwardedPages = true
+	}
+}
+
+// hasForwardedPages returns whether at least one page was already sent to the client.
+func (recv *continuousPagingState) hasForwardedPages() bool {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.forwardedPages
+}
+
+// isContinuousPagingRequest returns whether the request is a QUERY or EXECUTE with DSE continuous paging options.
+func isContinuousPagingRequest(frameContext *frameDecodeContext) (bool, error) {
+	f := frameContext.GetRawFrame()
+	if !f.Header.Version.IsDse() {
+		return false, nil
+	}
+	switch f.Header.OpCode {
+	case primitive.OpCodeQuery, primitive.OpCodeExecute:
+	default:
+		return false, nil
+	}
+
+	decodedFrame, err := frameContext.GetOrDecodeFrame()
+	if err != nil {
+		return false, fmt.Errorf("could not decode frame: %w", err)
+	}
+	var options *message.QueryOptions
+	switch msg := decodedFrame.Body.Message.(type) {
+	case *message.Query:
+		options = msg.Options
+	case *message.Execute:
+		options = msg.Options
+	}
+	return options != nil && options.ContinuousPagingOptions != nil, nil
+}
+
+// decodeContinuousPage returns the page number of a RESULT of a continuous paging request and whether it is the
+// last page. It returns false if the response is not a continuous page, e.g. an error.
+func decodeContinuousPage(response *frame.RawFrame) (int32, bool, bool, error) {
+	if response.Header.OpCode != primitive.OpCodeResult {
+		return 0, false, false, nil
+	}
+	decodedFrame, err := defaultCodec.ConvertFromRawFrame(response)
+	if err != nil {
+		return 0, false, false, fmt.Errorf("could not decode continuous paging response: %w", err)
+	}
+	rows, ok := decodedFrame.Body.Message.(*message.RowsResult)
+	if !ok || rows.Metadata == nil || rows.Metadata.ContinuousPageNumber <= 0 {
+		return 0, false, false, nil
+	}
+	return rows.Metadata.ContinuousPageNumber, rows.Metadata.LastContinuousPage, true, nil
+}
+
+// getDseRequestInfo handles the DSE protocol extensions: continuous paging reads are only sent to the cluster that
+// handles the read and REVISE_REQUEST messages are sent to the cluster that streams the pages of the revised request.
+func (ch *ClientHandler) getDseRequestInfo(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, logger *log.Entry) (RequestInfo, error) {
+
+	if frameContext.GetRawFrame().Header.OpCode == primitive.OpCodeDseRevise {
+		return ch.getReviseRequestInfo(frameContext, logger)
+	}
+
+	switch requestInfo.GetForwardDecision() {
+	case forwardToOrigin, forwardToTarget:
+	default:
+		return requestInfo, nil
+	}
+	continuousPaging, err := isContinuousPagingRequest(frameContext)
+	if err != nil {
+		return nil, err
+	}
+	if !continuousPaging {
+		return requestInfo, nil
+	}
+	return &continuousPagingRequestInfo{RequestInfo: requestInfo}, nil
+}
+
+func (ch *ClientHandler) getReviseRequestInfo(frameContext *frameDecodeContext, logger *log.Entry) (RequestInfo, error) {
+	decodedFrame, err := frameContext.GetOrDecodeFrame()
+	if err != nil {
+		return nil, fmt.Errorf("could not decode REVISE_REQUEST frame: %w", err)
+	}
+	revise, ok := decodedFrame.Body.Message.(*message.Revise)
+	if !ok {
+		return nil, fmt.Errorf("expected REVISE_REQUEST but got %v", decodedFrame.Body.Message)
+	}
+
+	fwdDecision := forwardToOrigin
+	if ch.primaryCluster == common.ClusterTypeTarget {
+		fwdDecision = forwardToTarget
+	}
+
+	holder := getOrCreateRequestContextHolder(ch.requestContextHolders, int16(revise.TargetStreamId))
+	reqCtx, ok := holder.Get().(*requestContextImpl)
+	if !ok || reqCtx.continuousPaging == nil {
+		logger.Debugf("No continuous paging request with stream id %d, forwarding %v to the primary cluster.",
+			revise.TargetStreamId, revise)
+		return NewGenericRequestInfo(fwdDecision, false, false), nil
+	}
+
+	fwdDecision = reqCtx.requestInfo.GetForwardDecision()
+	switch revise.RevisionType {
+	case primitive.DseRevisionTypeCancelContinuousPaging:
+		// the cluster stops sending pages so the request would otherwise time out
+		if reqCtx.Cancel(ch.nodeMetrics) {
+			ch.cancelRequest(holder, reqCtx)
+		}
+	case primitive.DseRevisionTypeMoreContinuousPages:
+		reqCtx.ResetTimer(reqCtx.continuousPaging.pageTimeout)
+	}
+	return NewGenericRequestInfo(fwdDecision, false, false), nil
+}
+
+// handleContinuousPage sends the pages of a continuous paging request to the client as they are received and
+// returns the last page, which finishes the request. It returns nil if the request is still streaming pages.
+func (ch *ClientHandler) handleContinuousPage(reqCtx *requestContextImpl, response *frame.RawFrame) *frame.RawFrame {
+	pageNumber, last, ok, err := decodeContinuousPage(response)
+	if err != nil {
+		reqCtx.logger.Warnf("Could not check if response is a continuous page, finishing the request: %v", err)
+		return response
+	}
+	if !ok {
+		return response
+	}
+
+	reqCtx.ResetTimer(reqCtx.continuousPaging.pageTimeout)
+	return reqCtx.continuousPaging.addPage(response, pageNumber, last, func(page *frame.RawFrame) {
+		reqCtx.logger.Tracef("Forwarding continuous page to the client: %v", page.Header)
+		ch.clientConnector.sendResponseToClient(page)
+	})
+}
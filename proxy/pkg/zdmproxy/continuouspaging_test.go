@@ -0,0 +1,93 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestContinuousPagingState_AddPage(t *testing.T) {
+	state := newContinuousPagingState(time.Second)
+	pages := make([]*frame.RawFrame, 5)
+	for i := range pages {
+		pages[i] = &frame.RawFrame{Header: &frame.Header{StreamId: int16(i + 1)}}
+	}
+
+	var sent []*frame.RawFrame
+	send := func(f *frame.RawFrame) {
+		sent = append(sent, f)
+	}
+
+	require.Nil(t, state.addPage(pages[1], 2, false, send))
+	require.Empty(t, sent)
+	require.False(t, state.hasForwardedPages())
+
+	require.Nil(t, state.addPage(pages[0], 1, false, send))
+	require.Equal(t, pages[:2], sent)
+	require.True(t, state.hasForwardedPages())
+
+	require.Nil(t, state.addPage(pages[4], 5, true, send))
+	require.Nil(t, state.addPage(pages[3], 4, false, send))
+	require.Equal(t, pages[:2], sent)
+
+	require.Same(t, pages[4], state.addPage(pages[2], 3, false, send))
+	require.Equal(t, pages[:4], sent)
+}
+
+func TestIsContinuousPagingRequest(t *testing.T) {
+	continuousPagingOptions := &message.QueryOptions{
+		ContinuousPagingOptions: &message.ContinuousPagingOptions{MaxPages: 10, PagesPerSecond: 100},
+	}
+	tests := []struct {
+		name     string
+		request  *frame.RawFrame
+		expected bool
+	}{
+		{"query", mockFrame(t, &message.Query{Query: "SELECT * FROM ks.tb", Options: continuousPagingOptions},
+			primitive.ProtocolVersionDse2), true},
+		{"execute", mockFrame(t, &message.Execute{QueryId: []byte("prepared_id"), ResultMetadataId: []byte("metadata_id"),
+			Options: continuousPagingOptions}, primitive.ProtocolVersionDse2), true},
+		{"dse v1", mockFrame(t, &message.Query{Query: "SELECT * FROM ks.tb", Options: continuousPagingOptions},
+			primitive.ProtocolVersionDse1), true},
+		{"without continuous paging", mockFrame(t, &message.Query{Query: "SELECT * FROM ks.tb"},
+			primitive.ProtocolVersionDse2), false},
+		{"oss protocol version", mockQueryFrame(t, "SELECT * FROM ks.tb"), false},
+		{"prepare", mockFrame(t, &message.Prepare{Query: "SELECT * FROM ks.tb"}, primitive.ProtocolVersionDse2), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			continuousPaging, err := isContinuousPagingRequest(NewFrameDecodeContext(tt.request, log.NewEntry(log.StandardLogger())))
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, continuousPaging)
+		})
+	}
+}
+
+func TestDecodeContinuousPage(t *testing.T) {
+	page := mockFrame(t, &message.RowsResult{
+		Metadata: &message.RowsMetadata{ColumnCount: 1, ContinuousPageNumber: 3, LastContinuousPage: true},
+		Data:     message.RowSet{},
+	}, primitive.ProtocolVersionDse2)
+	pageNumber, last, ok, err := decodeContinuousPage(page)
+	require.Nil(t, err)
+	require.True(t, ok)
+	require.True(t, last)
+	require.Equal(t, int32(3), pageNumber)
+
+	rows := mockFrame(t, &message.RowsResult{
+		Metadata: &message.RowsMetadata{ColumnCount: 1},
+		Data:     message.RowSet{},
+	}, primitive.ProtocolVersionDse2)
+	_, _, ok, err = decodeContinuousPage(rows)
+	require.Nil(t, err)
+	require.False(t, ok)
+
+	serverError := mockFrame(t, &message.ServerError{ErrorMessage: "error"}, primitive.ProtocolVersionDse2)
+	_, _, ok, err = decodeContinuousPage(serverError)
+	require.Nil(t, err)
+	require.False(t, ok)
+}
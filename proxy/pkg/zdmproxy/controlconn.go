@@ -32,26 +32,37 @@ type ControlConn struct {
 	defaultPort              int
 	connConfig               ConnectionConfig
 	currentContactPoint      Endpoint
-	username                 string
-	password                 string
+	credentials              CredentialsSupplier
 	counterLock              *sync.RWMutex
 	consecutiveFailures      int
 	OpenConnectionTimeout    time.Duration
 	cqlConnLock              *sync.Mutex
 	topologyLock             *sync.RWMutex
+	staleRefreshLock         *sync.Mutex
 	datacenter               string
+	remoteDatacenters        []string
+	remoteDatacenter         string
 	orderedHostsInLocalDc    []*Host
 	hostsInLocalDcById       map[uuid.UUID]*Host
 	assignedHosts            []*Host
-	currentAssignment        int64
+	hostAssignments          *hostAssignments
 	refreshHostsDebouncer    chan CqlConnection
+	refreshSchemaDebouncer   chan CqlConnection
 	systemLocalColumnData    map[string]*optionalColumn
 	systemPeersColumnNames   map[string]bool
 	virtualHosts             []*VirtualHost
+	lastTopologyRefresh      time.Time
 	proxyRand                *rand.Rand
 	reconnectCh              chan bool
 	protocolEventSubscribers map[ProtocolEventObserver]interface{}
+	topologyListeners        map[int]TopologyEventListener
+	nextTopologyListenerId   int
+	clusterDownListener      ClusterDownListener
 	authEnabled              *atomic.Value
+	counterTables            *atomic.Value
+	supportedOptions         *atomic.Value
+	protocolVersion          *atomic.Value
+	logger                   *log.Entry
 }
 
 const ProxyVirtualRack = "rack0"
@@ -60,10 +71,17 @@ const ccProtocolVersion = primitive.ProtocolVersion3
 const ccWriteTimeout = 5 * time.Second
 const ccReadTimeout = 10 * time.Second
 
-func NewControlConn(ctx context.Context, defaultPort int, connConfig ConnectionConfig,
-	username string, password string, conf *config.Config, topologyConfig *common.TopologyConfig, proxyRand *rand.Rand) *ControlConn {
+func NewControlConn(ctx context.Context, defaultPort int, connConfig ConnectionConfig, remoteDatacenters []string,
+	credentials CredentialsSupplier, conf *config.Config, topologyConfig *common.TopologyConfig, proxyRand *rand.Rand,
+	hostSelectionPolicy HostSelectionPolicy, logger *log.Entry) *ControlConn {
 	authEnabled := &atomic.Value{}
 	authEnabled.Store(true)
+	counterTables := &atomic.Value{}
+	counterTables.Store(map[string]bool{})
+	supportedOptions := &atomic.Value{}
+	supportedOptions.Store(map[string][]string(nil))
+	protocolVersion := &atomic.Value{}
+	protocolVersion.Store(primitive.ProtocolVersion(0))
 	return &ControlConn{
 		conf:           conf,
 		topologyConfig: topologyConfig,
@@ -79,25 +97,32 @@ func NewControlConn(ctx context.Context, defaultPort int, connConfig ConnectionC
 		defaultPort:              defaultPort,
 		connConfig:               connConfig,
 		currentContactPoint:      nil,
-		username:                 username,
-		password:                 password,
+		credentials:              credentials,
 		counterLock:              &sync.RWMutex{},
 		consecutiveFailures:      0,
 		OpenConnectionTimeout:    time.Duration(connConfig.GetConnectionTimeoutMs()) * time.Millisecond,
 		cqlConnLock:              &sync.Mutex{},
 		topologyLock:             &sync.RWMutex{},
+		staleRefreshLock:         &sync.Mutex{},
+		remoteDatacenters:        remoteDatacenters,
 		orderedHostsInLocalDc:    nil,
 		hostsInLocalDcById:       map[uuid.UUID]*Host{},
 		assignedHosts:            nil,
-		currentAssignment:        0,
+		hostAssignments:          newHostAssignments(hostSelectionPolicy),
 		refreshHostsDebouncer:    make(chan CqlConnection, 1),
+		refreshSchemaDebouncer:   make(chan CqlConnection, 1),
 		systemLocalColumnData:    nil,
 		systemPeersColumnNames:   nil,
 		virtualHosts:             nil,
 		proxyRand:                proxyRand,
 		reconnectCh:              make(chan bool, 1),
 		protocolEventSubscribers: map[ProtocolEventObserver]interface{}{},
+		topologyListeners:        map[int]TopologyEventListener{},
 		authEnabled:              authEnabled,
+		counterTables:            counterTables,
+		supportedOptions:         supportedOptions,
+		protocolVersion:          protocolVersion,
+		logger:                   logger,
 	}
 }
 
@@ -110,7 +135,7 @@ func (cc *ControlConn) Start(wg *sync.WaitGroup, ctx context.Context) error {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		defer log.Infof("Shutting down refresh topology debouncer of control connection %v.", cc.connConfig.GetClusterType())
+		defer cc.logger.Infof("Shutting down refresh topology debouncer of control connection %v.", cc.connConfig.GetClusterType())
 		for cc.context.Err() == nil {
 			var eventConnection CqlConnection
 			select {
@@ -119,18 +144,18 @@ func (cc *ControlConn) Start(wg *sync.WaitGroup, ctx context.Context) error {
 			case eventConnection = <-cc.refreshHostsDebouncer:
 			}
 
-			log.Infof("Received topology event from %v, refreshing topology.", cc.connConfig.GetClusterType())
+			cc.logger.Infof("Received topology event from %v, refreshing topology.", cc.connConfig.GetClusterType())
 
 			conn, _ := cc.getConnAndContactPoint()
 			if conn == nil {
-				log.Debugf("Topology refresh scheduled but the control connection isn't open. " +
+				cc.logger.Debugf("Topology refresh scheduled but the control connection isn't open. " +
 					"Falling back to the connection where the event was received.")
 				conn = eventConnection
 			}
 
-			_, err = cc.RefreshHosts(conn, cc.context)
+			_, err := cc.RefreshHosts(conn, cc.context)
 			if err != nil && cc.context.Err() == nil {
-				log.Errorf("Error refreshing topology (triggered by event), triggering reconnection: %v", err)
+				cc.logger.Errorf("Error refreshing topology (triggered by event), triggering reconnection: %v", err)
 				select {
 				case cc.reconnectCh <- true:
 				default:
@@ -139,11 +164,57 @@ func (cc *ControlConn) Start(wg *sync.WaitGroup, ctx context.Context) error {
 		}
 	}()
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer cc.logger.Infof("Shutting down refresh schema debouncer of control connection %v.", cc.connConfig.GetClusterType())
+		for cc.context.Err() == nil {
+			var eventConnection CqlConnection
+			select {
+			case <-cc.context.Done():
+				return
+			case eventConnection = <-cc.refreshSchemaDebouncer:
+			}
+
+			cc.logger.Debugf("Received schema event from %v, refreshing counter tables.", cc.connConfig.GetClusterType())
+
+			conn, _ := cc.getConnAndContactPoint()
+			if conn == nil {
+				conn = eventConnection
+			}
+
+			err := cc.RefreshCounterTables(conn, cc.context)
+			if err != nil && cc.context.Err() == nil {
+				cc.logger.Warnf("Error refreshing counter tables of %v (triggered by event): %v", cc.connConfig.GetClusterType(), err)
+			}
+		}
+	}()
+
+	refreshInterval := time.Duration(cc.conf.ContactPointsRefreshIntervalMs) * time.Millisecond
+	if refreshInterval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer cc.logger.Infof("Shutting down contact points refresh of control connection %v.", cc.connConfig.GetClusterType())
+			for {
+				timedOut, _ := sleepWithContext(refreshInterval, cc.context, nil)
+				if !timedOut {
+					return
+				}
+				_, err := cc.connConfig.RefreshContactPoints(cc.context)
+				if err != nil && cc.context.Err() == nil {
+					cc.logger.Warnf("Failed to refresh contact points of %v, keeping the previous contact points: %v",
+						cc.connConfig.GetClusterType(), err)
+				}
+			}
+		}()
+	}
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		defer cc.Close()
-		defer log.Infof("Shutting down control connection to %v,", cc.connConfig.GetClusterType())
+		defer cc.logger.Infof("Shutting down control connection to %v,", cc.connConfig.GetClusterType())
 		lastOpenSuccessful := true
 		reconnect := false
 		for cc.context.Err() == nil {
@@ -160,17 +231,18 @@ func (cc *ControlConn) Start(wg *sync.WaitGroup, ctx context.Context) error {
 
 			conn, _ := cc.getConnAndContactPoint()
 			if conn == nil {
+				// without ZDM_HEARTBEAT_REFRESH_CONTACT_POINTS every known host and contact point is tried on each attempt
 				useContactPointsOnly := false
-				if !lastOpenSuccessful {
+				if !lastOpenSuccessful && cc.conf.HeartbeatRefreshContactPoints {
 					useContactPointsOnly = true
-					log.Infof("Refreshing contact points and reopening control connection to %v.", cc.connConfig.GetClusterType())
+					cc.logger.Infof("Refreshing contact points and reopening control connection to %v.", cc.connConfig.GetClusterType())
 					_, err = cc.connConfig.RefreshContactPoints(cc.context)
 					if err != nil {
-						log.Warnf("Failed to refresh contact points, reopening control connection to %v with old contact points.", cc.connConfig.GetClusterType())
+						cc.logger.Warnf("Failed to refresh contact points, reopening control connection to %v with old contact points.", cc.connConfig.GetClusterType())
 						useContactPointsOnly = false
 					}
 				} else {
-					log.Infof("Reopening control connection to %v.", cc.connConfig.GetClusterType())
+					cc.logger.Infof("Reopening control connection to %v.", cc.connConfig.GetClusterType())
 				}
 				newConn, err := cc.Open(useContactPointsOnly, cc.context)
 				if cc.context.Err() != nil {
@@ -179,9 +251,9 @@ func (cc *ControlConn) Start(wg *sync.WaitGroup, ctx context.Context) error {
 				if err != nil {
 					lastOpenSuccessful = false
 					timeUntilRetry := cc.retryBackoffPolicy.Duration()
-					log.Errorf("Failed to open control connection to %v, retrying in %v: %v",
+					cc.logger.Errorf("Failed to open control connection to %v, retrying in %v: %v",
 						cc.connConfig.GetClusterType(), timeUntilRetry, err)
-					cc.IncrementFailureCounter()
+					cc.recordFailure(err)
 					sleepWithContext(timeUntilRetry, cc.context, nil)
 					continue
 				} else {
@@ -198,16 +270,20 @@ func (cc *ControlConn) Start(wg *sync.WaitGroup, ctx context.Context) error {
 			}
 
 			if err != nil {
-				log.Warnf("Heartbeat failed on %v. Closing and opening a new connection: %v.", conn, err)
-				cc.IncrementFailureCounter()
+				cc.logger.Warnf("Heartbeat failed on %v. Closing and opening a new connection: %v.", conn, err)
+				cc.recordFailure(err)
 				cc.Close()
 			} else {
 				logMsg := "Heartbeat successful on %v, waiting %v until next heartbeat."
 				if cc.ReadFailureCounter() != 0 {
-					log.Infof(logMsg, conn, cc.heartbeatPeriod)
+					cc.logger.Infof(logMsg, conn, cc.heartbeatPeriod)
 					cc.ResetFailureCounter()
 				} else {
-					log.Debugf(logMsg, conn, cc.heartbeatPeriod)
+					cc.logger.Debugf(logMsg, conn, cc.heartbeatPeriod)
+				}
+				if cc.isLocalDatacenterReachableAgain() {
+					cc.Close()
+					continue
 				}
 				_, reconnect = sleepWithContext(cc.heartbeatPeriod, cc.context, cc.reconnectCh)
 			}
@@ -257,6 +333,11 @@ func (cc *ControlConn) Open(contactPointsOnly bool, ctx context.Context) (CqlCon
 	var endpoint Endpoint
 	var triedEndpoints []Endpoint
 
+	cc.topologyLock.Lock()
+	localDc := cc.datacenter
+	cc.remoteDatacenter = ""
+	cc.topologyLock.Unlock()
+
 	if contactPointsOnly {
 		contactPoints := cc.connConfig.GetContactPoints()
 		conn, endpoint = cc.openInternal(contactPoints, ctx)
@@ -267,6 +348,10 @@ func (cc *ControlConn) Open(contactPointsOnly bool, ctx context.Context) (CqlCon
 		hosts, err := cc.GetHostsInLocalDatacenter()
 		if err == nil {
 			for _, h := range hosts {
+				if len(cc.remoteDatacenters) > 0 && localDc != "" && h.Datacenter != localDc {
+					// hosts of the remote datacenters are only used when no host of the local datacenter is reachable
+					continue
+				}
 				endpt := cc.connConfig.CreateEndpoint(h)
 				allEndpointsById[endpt.GetEndpointIdentifier()] = endpt
 				hostEndpoints = append(hostEndpoints, endpt)
@@ -291,6 +376,12 @@ func (cc *ControlConn) Open(contactPointsOnly bool, ctx context.Context) (CqlCon
 		}
 	}
 
+	if conn == nil && localDc != "" && len(cc.remoteDatacenters) > 0 {
+		var remoteEndpoints []Endpoint
+		conn, endpoint, remoteEndpoints = cc.openRemoteDatacenters(localDc, ctx)
+		triedEndpoints = append(triedEndpoints, remoteEndpoints...)
+	}
+
 	if conn == nil {
 		return nil, fmt.Errorf("could not open control connection to %v, tried endpoints: %v",
 			cc.connConfig.GetClusterType(), triedEndpoints)
@@ -300,6 +391,88 @@ func (cc *ControlConn) Open(contactPointsOnly bool, ctx context.Context) (CqlCon
 	return conn, nil
 }
 
+// openRemoteDatacenters opens the control connection to a host of the first datacenter of ZDM_*_REMOTE_DATACENTERS
+// that has a reachable host. The request connections are then opened to the hosts of that datacenter until
+// isLocalDatacenterReachableAgain detects that the local datacenter can be used again.
+func (cc *ControlConn) openRemoteDatacenters(localDc string, ctx context.Context) (CqlConnection, Endpoint, []Endpoint) {
+	hosts, err := cc.GetHostsInLocalDatacenter()
+	if err != nil {
+		return nil, nil, nil
+	}
+
+	var triedEndpoints []Endpoint
+	for _, dc := range cc.remoteDatacenters {
+		endpoints := make([]Endpoint, 0)
+		for _, h := range hosts {
+			if h.Datacenter == dc {
+				endpoints = append(endpoints, cc.connConfig.CreateEndpoint(h))
+			}
+		}
+		if len(endpoints) == 0 {
+			continue
+		}
+
+		cc.logger.Warnf("No host of the local datacenter %v of %v is reachable, trying remote datacenter %v.",
+			localDc, cc.connConfig.GetClusterType(), dc)
+		cc.topologyLock.Lock()
+		cc.remoteDatacenter = dc
+		cc.topologyLock.Unlock()
+
+		conn, endpoint := cc.openInternal(endpoints, ctx)
+		triedEndpoints = append(triedEndpoints, endpoints...)
+		if conn != nil {
+			cc.logger.Warnf("Running degraded: new %v request connections are opened to remote datacenter %v "+
+				"until a host of the local datacenter %v is reachable again.", cc.connConfig.GetClusterType(), dc, localDc)
+			return conn, endpoint, triedEndpoints
+		}
+	}
+
+	cc.topologyLock.Lock()
+	cc.remoteDatacenter = ""
+	cc.topologyLock.Unlock()
+	return nil, nil, triedEndpoints
+}
+
+// isLocalDatacenterReachableAgain returns true if the control connection is connected to a remote datacenter and
+// a connection to a random host of the local datacenter could be opened.
+func (cc *ControlConn) isLocalDatacenterReachableAgain() bool {
+	cc.topologyLock.RLock()
+	localDc := cc.datacenter
+	remoteDc := cc.remoteDatacenter
+	localHosts := make([]*Host, 0)
+	for _, h := range cc.hostsInLocalDcById {
+		if h.Datacenter == localDc {
+			localHosts = append(localHosts, h)
+		}
+	}
+	cc.topologyLock.RUnlock()
+
+	if remoteDc == "" || len(localHosts) == 0 {
+		return false
+	}
+
+	endpoint := cc.connConfig.CreateEndpoint(localHosts[cc.proxyRand.Intn(len(localHosts))])
+	conn, _, err := openConnection(cc.connConfig, endpoint, cc.context, false)
+	if err != nil {
+		cc.logger.Warnf("Running degraded in remote datacenter %v of %v, local datacenter %v is still unreachable: %v",
+			remoteDc, cc.connConfig.GetClusterType(), localDc, err)
+		return false
+	}
+	_ = conn.Close()
+
+	cc.logger.Infof("Local datacenter %v of %v is reachable again (%v), reopening the control connection.",
+		localDc, cc.connConfig.GetClusterType(), endpoint.GetEndpointIdentifier())
+	return true
+}
+
+// GetRemoteDatacenter returns the remote datacenter that is used because no host of the local datacenter is reachable,
+// it returns an empty string if the local datacenter is used.
+func (cc *ControlConn) GetRemoteDatacenter() string {
+	cc.topologyLock.RLock()
+	defer cc.topologyLock.RUnlock()
+	return cc.remoteDatacenter
+}
+
 func (cc *ControlConn) openInternal(endpoints []Endpoint, ctx context.Context) (CqlConnection, Endpoint) {
 	if ctx == nil {
 		ctx = cc.context
@@ -318,21 +491,31 @@ func (cc *ControlConn) openInternal(endpoints []Endpoint, ctx context.Context) (
 		endpoint = endpoints[currentIndex]
 		tcpConn, _, err := openConnection(cc.connConfig, endpoint, ctx, false)
 		if err != nil {
-			log.Warnf("Failed to open control connection to %v using endpoint %v: %v",
+			cc.logger.Warnf("Failed to open control connection to %v using endpoint %v: %v",
 				cc.connConfig.GetClusterType(), endpoint.GetEndpointIdentifier(), err)
 			continue
 		}
 
-		newConn := NewCqlConnection(tcpConn, cc.username, cc.password, ccReadTimeout, ccWriteTimeout)
+		creds := cc.credentials()
+		newConn := NewCqlConnection(tcpConn, creds.Username, creds.Password, ccReadTimeout, ccWriteTimeout, cc.logger)
 		err = newConn.InitializeContext(ccProtocolVersion, ctx)
 		if err == nil {
 			newConn.SetEventHandler(func(f *frame.Frame, c CqlConnection) {
-				switch f.Body.Message.(type) {
+				switch msg := f.Body.Message.(type) {
+				case *message.StatusChangeEvent:
+					cc.handleStatusChangeEvent(msg)
 				case *message.TopologyChangeEvent:
 					select {
 					case cc.refreshHostsDebouncer <- c:
 					default:
-						log.Debugf("Discarding event %v in %v because a topology refresh is already scheduled.",
+						cc.logger.Debugf("Discarding event %v in %v because a topology refresh is already scheduled.",
+							cc.connConfig.GetClusterType(), f.Body.Message)
+					}
+				case *message.SchemaChangeEvent:
+					select {
+					case cc.refreshSchemaDebouncer <- c:
+					default:
+						cc.logger.Debugf("Discarding event %v in %v because a schema refresh is already scheduled.",
 							cc.connConfig.GetClusterType(), f.Body.Message)
 					}
 				default:
@@ -340,27 +523,43 @@ func (cc *ControlConn) openInternal(endpoints []Endpoint, ctx context.Context) (
 				}
 			})
 
-			err = newConn.SubscribeToProtocolEvents(ctx, []primitive.EventType{primitive.EventTypeTopologyChange})
+			err = newConn.SubscribeToProtocolEvents(
+				ctx, []primitive.EventType{
+					primitive.EventTypeTopologyChange, primitive.EventTypeStatusChange, primitive.EventTypeSchemaChange})
 			if err == nil {
 				_, err = cc.RefreshHosts(newConn, ctx)
 			}
+			if err == nil && cc.conf.ProxyAnswerOptions {
+				cc.RefreshSupportedOptions(newConn, ctx)
+			}
+			if err == nil && cc.conf.ProtocolVersionTranslationEnabled {
+				cc.RefreshProtocolVersion(endpoint, ctx)
+			}
+			if err == nil {
+				// counter table detection is best effort so it is done by the schema refresh goroutine instead of
+				// delaying the control connection initialization
+				select {
+				case cc.refreshSchemaDebouncer <- newConn:
+				default:
+				}
+			}
 		}
 
 		if err != nil {
 			if ctx.Err() == nil {
-				log.Warnf("Error while initializing a new cql connection for the control connection of %v: %v",
+				cc.logger.Warnf("Error while initializing a new cql connection for the control connection of %v: %v",
 					cc.connConfig.GetClusterType(), err)
 			}
 			err2 := newConn.Close()
 			if err2 != nil {
-				log.Errorf("Failed to close cql connection: %v", err2)
+				cc.logger.Errorf("Failed to close cql connection: %v", err2)
 			}
 
 			continue
 		}
 
 		conn = newConn
-		log.Infof("Successfully opened control connection to %v using endpoint %v.",
+		cc.logger.Infof("Successfully opened control connection to %v using endpoint %v.",
 			cc.connConfig.GetClusterType(), endpoint.String())
 		break
 	}
@@ -377,7 +576,7 @@ func (cc *ControlConn) Close() {
 	if conn != nil {
 		err := conn.Close()
 		if err != nil {
-			log.Warnf("Failed to close connection (possible leaked connection): %v", err)
+			cc.logger.Warnf("Failed to close connection (possible leaked connection): %v", err)
 		}
 	}
 }
@@ -388,7 +587,7 @@ func (cc *ControlConn) RefreshHosts(conn CqlConnection, ctx context.Context) ([]
 		return nil, fmt.Errorf("could not fetch information from system.local table: %w", err)
 	}
 
-	localInfo, localHost, err := ParseSystemLocalResult(localQueryResult, cc.defaultPort)
+	localInfo, localHost, err := ParseSystemLocalResult(localQueryResult, cc.defaultPort, cc.logger)
 	if err != nil {
 		return nil, err
 	}
@@ -400,7 +599,7 @@ func (cc *ControlConn) RefreshHosts(conn CqlConnection, ctx context.Context) ([]
 	}
 	if partitioner != nil && !strings.Contains(*partitioner, "Murmur3Partitioner") && cc.topologyConfig.VirtualizationEnabled {
 		if strings.Contains(*partitioner, "RandomPartitioner") {
-			log.Debugf("Cluster %v uses the Random partitioner, but the proxy will return Murmur3 to the client instead. This is the expected behaviour.", cc.connConfig.GetClusterType())
+			cc.logger.Debugf("Cluster %v uses the Random partitioner, but the proxy will return Murmur3 to the client instead. This is the expected behaviour.", cc.connConfig.GetClusterType())
 		} else {
 			return nil, fmt.Errorf("virtualization is enabled and partitioner is not Murmur3 or Random but instead %v", *partitioner)
 		}
@@ -411,7 +610,7 @@ func (cc *ControlConn) RefreshHosts(conn CqlConnection, ctx context.Context) ([]
 		return nil, fmt.Errorf("could not fetch information from system.peers table: %w", err)
 	}
 
-	hostsById := ParseSystemPeersResult(peersQuery, cc.defaultPort, false)
+	hostsById := ParseSystemPeersResult(peersQuery, cc.defaultPort, false, cc.logger)
 
 	var peersColumns map[string]bool // nil if no peers
 	if len(hostsById) > 0 {
@@ -430,7 +629,7 @@ func (cc *ControlConn) RefreshHosts(conn CqlConnection, ctx context.Context) ([]
 
 	oldLocalhost, localHostExists := hostsById[localHost.HostId]
 	if localHostExists {
-		log.Warnf("Local host is also on the peers list: %v vs %v, ignoring the former one.", oldLocalhost, localHost)
+		cc.logger.Warnf("Local host is also on the peers list: %v vs %v, ignoring the former one.", oldLocalhost, localHost)
 	}
 	hostsById[localHost.HostId] = localHost
 	orderedLocalHosts := make([]*Host, 0, len(hostsById))
@@ -440,9 +639,12 @@ func (cc *ControlConn) RefreshHosts(conn CqlConnection, ctx context.Context) ([]
 
 	cc.topologyLock.RLock()
 	currentDc := cc.datacenter
+	if cc.remoteDatacenter != "" {
+		currentDc = cc.remoteDatacenter
+	}
 	cc.topologyLock.RUnlock()
 
-	orderedLocalHosts, currentDc, err = filterHosts(orderedLocalHosts, currentDc, cc.connConfig, localHost)
+	orderedLocalHosts, currentDc, err = filterHosts(orderedLocalHosts, currentDc, cc.connConfig, localHost, cc.logger)
 	if err != nil {
 		return nil, err
 	}
@@ -468,11 +670,11 @@ func (cc *ControlConn) RefreshHosts(conn CqlConnection, ctx context.Context) ([]
 		virtualHosts = make([]*VirtualHost, 0)
 	}
 
-	log.Infof("Refreshed %v orderedHostsInLocalDc. Assigned Hosts: %v, VirtualHosts: %v, ProxyTopologyIndex: %v",
+	cc.logger.Infof("Refreshed %v orderedHostsInLocalDc. Assigned Hosts: %v, VirtualHosts: %v, ProxyTopologyIndex: %v",
 		cc.connConfig.GetClusterType(), assignedHosts, virtualHosts, cc.topologyConfig.Index)
 
 	cc.topologyLock.Lock()
-	if cc.datacenter == "" {
+	if cc.datacenter == "" && cc.remoteDatacenter == "" {
 		cc.datacenter = currentDc
 	}
 	oldHosts := cc.hostsInLocalDcById
@@ -482,6 +684,8 @@ func (cc *ControlConn) RefreshHosts(conn CqlConnection, ctx context.Context) ([]
 	cc.systemLocalColumnData = localInfo
 	cc.systemPeersColumnNames = peersColumns
 	cc.virtualHosts = virtualHosts
+	cc.lastTopologyRefresh = time.Now()
+	hostChanges := computeHostChanges(cc.connConfig.GetClusterType(), oldHosts, hostsById)
 
 	if oldHosts != nil && len(oldHosts) > 0 {
 		removedHosts := make([]*Host, 0)
@@ -499,9 +703,139 @@ func (cc *ControlConn) RefreshHosts(conn CqlConnection, ctx context.Context) ([]
 	}
 	cc.topologyLock.Unlock()
 
+	cc.notifyTopologyListeners(hostChanges)
 	return orderedLocalHosts, nil
 }
 
+// RefreshCounterTables fetches the tables that contain counter columns from system_schema.columns
+// (or system.schema_columns for clusters that don't have the system_schema keyspace).
+func (cc *ControlConn) RefreshCounterTables(conn CqlConnection, ctx context.Context) error {
+	var counterTables map[string]bool
+	rs, err := conn.Query(
+		"SELECT keyspace_name, table_name, type FROM system_schema.columns", GetDefaultGenericTypeCodec(), ccProtocolVersion, ctx)
+	if err == nil {
+		counterTables = parseCounterTables(rs, "table_name", "type", func(columnType string) bool {
+			return columnType == "counter"
+		})
+	} else {
+		cc.logger.Debugf("Could not fetch columns of %v from system_schema.columns, falling back to system.schema_columns: %v",
+			cc.connConfig.GetClusterType(), err)
+		rs, err = conn.Query(
+			"SELECT keyspace_name, columnfamily_name, validator FROM system.schema_columns", GetDefaultGenericTypeCodec(), ccProtocolVersion, ctx)
+		if err != nil {
+			return fmt.Errorf("could not fetch information from system.schema_columns table: %w", err)
+		}
+		counterTables = parseCounterTables(rs, "columnfamily_name", "validator", func(validator string) bool {
+			return strings.Contains(validator, "CounterColumnType")
+		})
+	}
+
+	cc.logger.Debugf("Refreshed counter tables of %v: %v", cc.connConfig.GetClusterType(), counterTables)
+	cc.counterTables.Store(counterTables)
+	return nil
+}
+
+// IsCounterTable returns true if the table contains counter columns.
+// The keyspace and table names are expected to be in the same format as the query inspector provides them,
+// i.e. lower case unless they were quoted.
+func (cc *ControlConn) IsCounterTable(keyspaceName string, tableName string) bool {
+	counterTables := cc.counterTables.Load().(map[string]bool)
+	return counterTables[keyspaceName+"."+tableName]
+}
+
+// RefreshSupportedOptions sends an OPTIONS request to the cluster and keeps the options of its SUPPORTED response, the
+// previous options are kept if the request fails.
+func (cc *ControlConn) RefreshSupportedOptions(conn CqlConnection, ctx context.Context) {
+	response, err := conn.Execute(&message.Options{}, ctx)
+	if err != nil {
+		cc.logger.Warnf("Could not fetch the supported options of %v: %v", cc.connConfig.GetClusterType(), err)
+		return
+	}
+	supported, ok := response.(*message.Supported)
+	if !ok {
+		cc.logger.Warnf("Expected SUPPORTED response from %v but got %v.", cc.connConfig.GetClusterType(), response)
+		return
+	}
+	cc.logger.Debugf("Supported options of %v: %v", cc.connConfig.GetClusterType(), supported.Options)
+	cc.supportedOptions.Store(supported.Options)
+}
+
+// GetSupportedOptions returns the options of the SUPPORTED response of the cluster, or nil if they were not fetched
+// (they are only fetched when ZDM_PROXY_ANSWER_OPTIONS is enabled).
+func (cc *ControlConn) GetSupportedOptions() map[string][]string {
+	return cc.supportedOptions.Load().(map[string][]string)
+}
+
+// RefreshProtocolVersion probes the highest protocol version that the cluster supports with a new connection to the
+// given endpoint, the previous version is kept if the probe fails.
+func (cc *ControlConn) RefreshProtocolVersion(endpoint Endpoint, ctx context.Context) {
+	version, err := probeProtocolVersion(cc.connConfig, endpoint, ctx)
+	if err != nil {
+		cc.logger.Warnf("Could not probe the protocol version of %v: %v", cc.connConfig.GetClusterType(), err)
+		return
+	}
+	cc.logger.Infof("Highest protocol version supported by %v: %v", cc.connConfig.GetClusterType(), version)
+	cc.protocolVersion.Store(version)
+}
+
+// GetProtocolVersion returns the highest protocol version that the cluster supports, or 0 if it is unknown (it is
+// only probed when ZDM_PROTOCOL_VERSION_TRANSLATION_ENABLED is enabled).
+func (cc *ControlConn) GetProtocolVersion() primitive.ProtocolVersion {
+	return cc.protocolVersion.Load().(primitive.ProtocolVersion)
+}
+
+// CheckSchemaAgreement returns true if every node of the cluster that reported a schema version in system.local and
+// system.peers reported the same one.
+func (cc *ControlConn) CheckSchemaAgreement(ctx context.Context) (bool, error) {
+	conn, _ := cc.getConnAndContactPoint()
+	if conn == nil {
+		return false, fmt.Errorf("control connection is not open")
+	}
+
+	versions := make(map[string]bool)
+	for _, query := range []string{
+		"SELECT schema_version FROM system.local",
+		"SELECT schema_version FROM system.peers"} {
+		rs, err := conn.Query(query, GetDefaultGenericTypeCodec(), ccProtocolVersion, ctx)
+		if err != nil {
+			return false, fmt.Errorf("could not fetch schema versions: %w", err)
+		}
+		for _, row := range rs.Rows {
+			version, _, err := parseNillableUuid(row, "schema_version")
+			if err != nil {
+				return false, err
+			}
+			if version != nil {
+				versions[version.String()] = true
+			}
+		}
+	}
+	return len(versions) == 1, nil
+}
+
+func parseCounterTables(
+	rs *ParsedRowSet, tableNameColumn string, typeColumn string, isCounterType func(string) bool) map[string]bool {
+	counterTables := map[string]bool{}
+	for _, row := range rs.Rows {
+		keyspaceName, ok := parseNillableString(row, "keyspace_name")
+		if !ok || keyspaceName == nil {
+			continue
+		}
+		tableName, ok := parseNillableString(row, tableNameColumn)
+		if !ok || tableName == nil {
+			continue
+		}
+		columnType, ok := parseNillableString(row, typeColumn)
+		if !ok || columnType == nil {
+			continue
+		}
+		if isCounterType(*columnType) {
+			counterTables[*keyspaceName+"."+*tableName] = true
+		}
+	}
+	return counterTables
+}
+
 func (cc *ControlConn) GetHostsInLocalDatacenter() (map[uuid.UUID]*Host, error) {
 	cc.topologyLock.RLock()
 	defer cc.topologyLock.RUnlock()
@@ -513,6 +847,31 @@ func (cc *ControlConn) GetHostsInLocalDatacenter() (map[uuid.UUID]*Host, error)
 	return cc.hostsInLocalDcById, nil
 }
 
+// GetLastTopologyRefreshTime returns the time of the last successful topology refresh,
+// the zero value is returned if the topology was never refreshed.
+func (cc *ControlConn) GetLastTopologyRefreshTime() time.Time {
+	cc.topologyLock.RLock()
+	defer cc.topologyLock.RUnlock()
+	return cc.lastTopologyRefresh
+}
+
+// RefreshTopologyIfOlderThan refreshes the topology if the last refresh is older than maxAge. Concurrent callers wait
+// for a single refresh.
+func (cc *ControlConn) RefreshTopologyIfOlderThan(maxAge time.Duration, ctx context.Context) error {
+	cc.staleRefreshLock.Lock()
+	defer cc.staleRefreshLock.Unlock()
+
+	if time.Since(cc.GetLastTopologyRefreshTime()) < maxAge {
+		return nil
+	}
+	conn, _ := cc.getConnAndContactPoint()
+	if conn == nil {
+		return fmt.Errorf("the control connection to %v is not open", cc.connConfig.GetClusterType())
+	}
+	_, err := cc.RefreshHosts(conn, ctx)
+	return err
+}
+
 func (cc *ControlConn) GetOrderedHostsInLocalDatacenter() ([]*Host, error) {
 	cc.topologyLock.RLock()
 	defer cc.topologyLock.RUnlock()
@@ -554,6 +913,8 @@ func (cc *ControlConn) GetAssignedHosts() ([]*Host, error) {
 	return cc.assignedHosts, nil
 }
 
+// NextAssignedHost returns the host that a new client connection is assigned to according to the host selection
+// policy, ReleaseAssignedHost must be called when the client connection is closed.
 func (cc *ControlConn) NextAssignedHost() (*Host, error) {
 	cc.topologyLock.RLock()
 	defer cc.topologyLock.RUnlock()
@@ -561,10 +922,17 @@ func (cc *ControlConn) NextAssignedHost() (*Host, error) {
 	if cc.assignedHosts == nil {
 		return nil, fmt.Errorf("could not get assigned hosts because topology information has not been retrieved yet")
 	}
+	if len(cc.assignedHosts) == 0 {
+		return nil, fmt.Errorf("no %v hosts are assigned to this proxy instance", cc.connConfig.GetClusterType())
+	}
 
-	assignment := cc.incCurrentAssignmentCounter(len(cc.assignedHosts))
+	return cc.hostAssignments.assign(cc.assignedHosts)
+}
 
-	return cc.assignedHosts[assignment], nil
+// ReleaseAssignedHost is called when a client connection that was assigned to the host with NextAssignedHost is
+// closed.
+func (cc *ControlConn) ReleaseAssignedHost(host *Host) {
+	cc.hostAssignments.release(host)
 }
 
 func (cc *ControlConn) GetClusterName() string {
@@ -612,7 +980,7 @@ func (cc *ControlConn) setConn(oldConn CqlConnection, newConn CqlConnection, new
 		cc.currentContactPoint = newContactPoint
 		authEnabled, err := newConn.IsAuthEnabled()
 		if err != nil {
-			log.Errorf("Error detected when trying to set whether auth is enabled or not in control connection, "+
+			cc.logger.Errorf("Error detected when trying to set whether auth is enabled or not in control connection, "+
 				"this is a bug, please report: %v", err)
 		} else {
 			cc.authEnabled.Store(authEnabled)
@@ -621,11 +989,11 @@ func (cc *ControlConn) setConn(oldConn CqlConnection, newConn CqlConnection, new
 	}
 
 	if newConn != nil {
-		log.Infof("Another control connection attempt to %v was successful in parallel, closing this connection (%v).",
+		cc.logger.Infof("Another control connection attempt to %v was successful in parallel, closing this connection (%v).",
 			cc.connConfig.GetClusterType(), newContactPoint.String())
 		err := newConn.Close()
 		if err != nil {
-			log.Errorf("Failed to close cql connection: %v", err)
+			cc.logger.Errorf("Failed to close cql connection: %v", err)
 		}
 	}
 
@@ -640,26 +1008,12 @@ func (cc *ControlConn) getConnAndContactPoint() (CqlConnection, Endpoint) {
 	return conn, contactPoint
 }
 
-// should be called with a read or write lock on topologyLock
-func (cc *ControlConn) getCurrentAssignmentCounter(assignedHostsLength int) int64 {
-	return atomic.LoadInt64(&cc.currentAssignment) % int64(assignedHostsLength)
-}
-
-// should be called with a read or write lock on topologyLock
-func (cc *ControlConn) incCurrentAssignmentCounter(assignedHostsLength int) int64 {
-	value := atomic.AddInt64(&cc.currentAssignment, 1) % int64(assignedHostsLength)
-	if value == 0 {
-		atomic.AddInt64(&cc.currentAssignment, int64(-assignedHostsLength))
-	}
-	return value
-}
-
 func (cc *ControlConn) RegisterObserver(observer ProtocolEventObserver) {
 	cc.topologyLock.Lock()
 	defer cc.topologyLock.Unlock()
 	_, ok := cc.protocolEventSubscribers[observer]
 	if ok {
-		log.Warnf("Duplicate observer found while registering protocol event observer.")
+		cc.logger.Warnf("Duplicate observer found while registering protocol event observer.")
 	}
 	cc.protocolEventSubscribers[observer] = nil
 }
@@ -746,7 +1100,8 @@ func filterHostsByDatacenter(datacenter string, hosts []*Host) []*Host {
 	return filteredHosts
 }
 
-func filterHosts(hosts []*Host, currentDc string, connConfig ConnectionConfig, localHost *Host) ([]*Host, string, error) {
+func filterHosts(
+	hosts []*Host, currentDc string, connConfig ConnectionConfig, localHost *Host, logger *log.Entry) ([]*Host, string, error) {
 	if currentDc != "" {
 		filteredOrderedHosts := filterHostsByDatacenter(currentDc, hosts)
 		if len(filteredOrderedHosts) == 0 {
@@ -760,7 +1115,7 @@ func filterHosts(hosts []*Host, currentDc string, connConfig ConnectionConfig, l
 	if datacenter != "" {
 		filteredOrderedHosts := filterHostsByDatacenter(datacenter, hosts)
 		if len(filteredOrderedHosts) == 0 {
-			log.Warnf("datacenter was set to '%v' but no hosts were found with that DC "+
+			logger.Warnf("datacenter was set to '%v' but no hosts were found with that DC "+
 				"so falling back to local host's DC '%v' (hosts=%v)",
 				datacenter, localHost.Datacenter, hosts)
 		} else {
@@ -59,6 +59,7 @@ type cqlConn struct {
 	eventHandler          func(f *frame.Frame, conn CqlConnection)
 	eventHandlerLock      *sync.Mutex
 	authEnabled           bool
+	logger                *log.Entry
 }
 
 var (
@@ -72,7 +73,7 @@ func (c *cqlConn) String() string {
 func NewCqlConnection(
 	conn net.Conn,
 	username string, password string,
-	readTimeout time.Duration, writeTimeout time.Duration) CqlConnection {
+	readTimeout time.Duration, writeTimeout time.Duration, logger *log.Entry) CqlConnection {
 	ctx, cFn := context.WithCancel(context.Background())
 	streamIdsQueue := make(chan int16, numberOfStreamIds)
 	for i := int16(0); i < numberOfStreamIds; i++ {
@@ -99,6 +100,7 @@ func NewCqlConnection(
 		closed:                false,
 		eventHandlerLock:      &sync.Mutex{},
 		authEnabled:           true,
+		logger:                logger,
 	}
 	cqlConn.StartRequestLoop()
 	cqlConn.StartResponseLoop()
@@ -131,12 +133,12 @@ func (c *cqlConn) StartResponseLoop() {
 	go func() {
 		defer c.wg.Done()
 		defer close(c.eventsQueue)
-		defer log.Debugf("Shutting down response loop on %v.", c)
+		defer c.logger.Debugf("Shutting down response loop on %v.", c)
 		for c.ctx.Err() == nil {
 			f, err := defaultCodec.DecodeFrame(c.conn)
 			if err != nil {
 				if (!errors.Is(err, io.EOF) && !IsClosingErr(err)) || c.ctx.Err() == nil {
-					log.Errorf("Failed to read/decode frame on cql connection %v: %v", c, err)
+					c.logger.Errorf("Failed to read/decode frame on cql connection %v: %v", c, err)
 				}
 				c.cancelFn()
 				break
@@ -146,7 +148,7 @@ func (c *cqlConn) StartResponseLoop() {
 				select {
 				case c.eventsQueue <- f:
 				default:
-					log.Warnf("[CqlConnection] events queue is full, blocking response loop until event queue is not full...")
+					c.logger.Warnf("[CqlConnection] events queue is full, blocking response loop until event queue is not full...")
 					select {
 					case c.eventsQueue <- f:
 					case <-c.ctx.Done():
@@ -158,7 +160,7 @@ func (c *cqlConn) StartResponseLoop() {
 			c.pendingOperationsLock.Lock()
 			respChan, ok := c.pendingOperations[f.Header.StreamId]
 			if !ok {
-				log.Warnf("[CqlConnection] could not find response channel for streamid %d, skipping", f.Header.StreamId)
+				c.logger.Warnf("[CqlConnection] could not find response channel for streamid %d, skipping", f.Header.StreamId)
 				c.pendingOperationsLock.Unlock()
 				continue
 			}
@@ -184,14 +186,14 @@ func (c *cqlConn) StartRequestLoop() {
 	c.wg.Add(1)
 	go func() {
 		defer c.wg.Done()
-		defer log.Debug("Shutting down request loop on ", c)
+		defer c.logger.Debug("Shutting down request loop on ", c)
 		for c.ctx.Err() == nil {
 			select {
 			case f := <-c.outgoingCh:
 				err := defaultCodec.EncodeFrame(f, c.conn)
 				if err != nil {
 					if (!errors.Is(err, io.EOF) && !IsClosingErr(err)) || c.ctx.Err() == nil {
-						log.Errorf("Failed to write/encode frame on cql connection %v: %v", c, err)
+						c.logger.Errorf("Failed to write/encode frame on cql connection %v: %v", c, err)
 					}
 					c.cancelFn()
 					return
@@ -207,7 +209,7 @@ func (c *cqlConn) StartEventLoop() {
 	c.wg.Add(1)
 	go func() {
 		defer c.wg.Done()
-		defer log.Debugf("Shutting down event loop on %v.", c)
+		defer c.logger.Debugf("Shutting down event loop on %v.", c)
 
 		event, ok := <-c.eventsQueue
 		for ; ok; event, ok = <-c.eventsQueue {
@@ -330,7 +332,7 @@ func (c *cqlConn) SendAndReceive(request *frame.Frame, ctx context.Context) (*fr
 }
 
 func (c *cqlConn) PerformHandshake(version primitive.ProtocolVersion, ctx context.Context) (auth bool, err error) {
-	log.Debug("performing handshake")
+	c.logger.Debug("performing handshake")
 	startup := frame.NewFrame(version, -1, message.NewStartup())
 	var response *frame.Frame
 	authenticator := &DsePlainTextAuthenticator{c.credentials}
@@ -338,7 +340,7 @@ func (c *cqlConn) PerformHandshake(version primitive.ProtocolVersion, ctx contex
 	if response, err = c.SendAndReceive(startup, ctx); err == nil {
 		switch response.Body.Message.(type) {
 		case *message.Ready:
-			log.Warnf("%v: expected AUTHENTICATE, got READY – is authentication required?", c)
+			c.logger.Warnf("%v: expected AUTHENTICATE, got READY – is authentication required?", c)
 			break
 		case *message.Authenticate:
 			authEnabled = true
@@ -363,10 +365,10 @@ func (c *cqlConn) PerformHandshake(version primitive.ProtocolVersion, ctx contex
 		}
 	}
 	if err == nil {
-		log.Debugf("%v: handshake successful", c)
+		c.logger.Debugf("%v: handshake successful", c)
 		c.initialized = true
 	} else {
-		log.Errorf("%v: handshake failed: %v", c, err)
+		c.logger.Errorf("%v: handshake failed: %v", c, err)
 	}
 	return authEnabled, err
 }
@@ -454,7 +456,7 @@ func (c *cqlConn) SendHeartbeat(ctx context.Context) error {
 
 	_, ok := response.Body.Message.(*message.Supported)
 	if !ok {
-		log.Warnf("Expected SUPPORTED but got %v. Considering this a successful heartbeat regardless.", response.Body.Message)
+		c.logger.Warnf("Expected SUPPORTED but got %v. Considering this a successful heartbeat regardless.", response.Body.Message)
 	}
 
 	return nil
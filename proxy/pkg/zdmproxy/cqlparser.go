@@ -29,6 +29,10 @@ const (
 	peersV2 = interceptedQueryType("peersV2")
 	peersV1 = interceptedQueryType("peersV1")
 	local   = interceptedQueryType("local")
+
+	introspectionClients            = interceptedQueryType("introspectionClients")
+	introspectionPreparedStatements = interceptedQueryType("introspectionPreparedStatements")
+	introspectionConfig             = interceptedQueryType("introspectionConfig")
 )
 
 const (
@@ -59,6 +63,17 @@ func (uee *UnpreparedExecuteError) Error() string {
 	return fmt.Sprintf("The preparedID of the statement to be executed (%s) does not exist in the proxy cache", hex.EncodeToString(uee.preparedId))
 }
 
+// RejectedRequestError is returned when the proxy refuses to forward a request because of a configured policy,
+// the client receives an Invalid error with the provided message.
+type RejectedRequestError struct {
+	Header *frame.Header
+	Reason string
+}
+
+func (rre *RejectedRequestError) Error() string {
+	return fmt.Sprintf("Request rejected by the proxy: %v", rre.Reason)
+}
+
 func buildRequestInfo(
 	frameContext *frameDecodeContext,
 	stmtsReplacedTerms []*statementReplacedTerms,
@@ -68,7 +83,13 @@ func buildRequestInfo(
 	primaryCluster common.ClusterType,
 	forwardSystemQueriesToTarget bool,
 	virtualizationEnabled bool,
+	introspectionEnabled bool,
 	forwardAuthToTarget bool,
+	lwtPolicy common.LwtPolicy,
+	counterWritePolicy common.CounterWritePolicy,
+	ddlPolicy common.DdlPolicy,
+	tableRouting *common.TableRouting,
+	counterTables CounterTableChecker,
 	timeUuidGenerator TimeUuidGenerator) (RequestInfo, error) {
 
 	f := frameContext.GetRawFrame()
@@ -78,9 +99,17 @@ func buildRequestInfo(
 		if err != nil {
 			return nil, fmt.Errorf("could not inspect QUERY frame: %w", err)
 		}
+		if stmtQueryData.queryData.isConditional() {
+			mh.GetProxyMetrics().LwtRequestCount.Add(1)
+		}
+		if isCounterWrite(stmtQueryData.queryData, counterTables) {
+			mh.GetProxyMetrics().CounterWriteCount.Add(1)
+		}
+		trackStatementTableRequest(mh, stmtQueryData.queryData, frameContext.logger)
 		return getRequestInfoFromQueryInfo(
-			frameContext.GetRawFrame(), primaryCluster,
-			forwardSystemQueriesToTarget, virtualizationEnabled, stmtQueryData.queryData), nil
+			frameContext.GetRawFrame(), primaryCluster, forwardSystemQueriesToTarget, virtualizationEnabled,
+			introspectionEnabled, lwtPolicy, counterWritePolicy, ddlPolicy, tableRouting, counterTables,
+			stmtQueryData.queryData, frameContext.logger)
 	case primitive.OpCodePrepare:
 		stmtQueryData, err := frameContext.GetOrInspectStatement(currentKeyspaceName, timeUuidGenerator)
 		if err != nil {
@@ -94,9 +123,13 @@ func buildRequestInfo(
 		if !ok {
 			return nil, fmt.Errorf("unexpected message type when decoding PREPARE message: %v", decodedFrame.Body.Message)
 		}
-		baseRequestInfo := getRequestInfoFromQueryInfo(
-			frameContext.GetRawFrame(), primaryCluster,
-			forwardSystemQueriesToTarget, virtualizationEnabled, stmtQueryData.queryData)
+		baseRequestInfo, err := getRequestInfoFromQueryInfo(
+			frameContext.GetRawFrame(), primaryCluster, forwardSystemQueriesToTarget, virtualizationEnabled,
+			introspectionEnabled, lwtPolicy, counterWritePolicy, ddlPolicy, tableRouting, counterTables,
+			stmtQueryData.queryData, frameContext.logger)
+		if err != nil {
+			return nil, err
+		}
 		replacedTerms := make([]*term, 0)
 		if len(stmtsReplacedTerms) > 1 {
 			return nil, fmt.Errorf("expected single list of replaced terms for prepare message but got %v", len(stmtsReplacedTerms))
@@ -114,19 +147,78 @@ func buildRequestInfo(
 			return nil, fmt.Errorf("could not convert message with batch op code to batch type, got %v instead", decodedFrame.Body.Message)
 		}
 		preparedDataByStmtIdxMap := make(map[int]PreparedData)
+		conditional := false
+		counterWrite := false
 		for childIdx, child := range batchMsg.Children {
 			switch queryOrId := child.QueryOrId.(type) {
 			case []byte:
-				preparedData, err := getPreparedData(psCache, mh, queryOrId, primitive.OpCodeBatch, decodedFrame)
+				preparedData, err := getPreparedData(psCache, mh, queryOrId, primitive.OpCodeBatch, decodedFrame, frameContext.logger)
 				if err != nil {
 					return nil, err
 				} else {
 					preparedDataByStmtIdxMap[childIdx] = preparedData
+					if preparedData.GetPrepareRequestInfo().GetBaseRequestInfo().IsConditional() {
+						conditional = true
+					}
+					if preparedData.GetPrepareRequestInfo().GetBaseRequestInfo().IsCounterWrite() {
+						counterWrite = true
+					}
 				}
 			default:
 			}
 		}
-		return NewBatchRequestInfo(preparedDataByStmtIdxMap), nil
+		if mh.GetProxyMetrics().TableRequests != nil {
+			err = trackBatchTableRequests(frameContext, mh, preparedDataByStmtIdxMap, currentKeyspaceName, timeUuidGenerator)
+			if err != nil {
+				return nil, err
+			}
+		}
+		// the query strings of the batch are only parsed if their forward decision can differ from the default one,
+		// i.e. lightweight transactions and counter updates are sent to both clusters and no table is routed
+		inspectQueryStmts := lwtPolicy != common.LwtPolicyBoth || counterWritePolicy != common.CounterWritePolicyBoth ||
+			tableRouting != nil
+		forwardDecisionByQueryStmtIdx := make(map[int]forwardDecision)
+		if inspectQueryStmts && !conditional && !counterWrite {
+			stmtsQueryData, err := frameContext.GetOrInspectAllStatements(currentKeyspaceName, timeUuidGenerator)
+			if err != nil {
+				return nil, fmt.Errorf("could not inspect BATCH frame: %w", err)
+			}
+			for _, stmtQueryData := range stmtsQueryData {
+				if stmtQueryData.queryData.isConditional() {
+					conditional = true
+					break
+				}
+				if isCounterWrite(stmtQueryData.queryData, counterTables) {
+					counterWrite = true
+					break
+				}
+				stmtForwardDecision, routed, err := getTableRoutingForwardDecision(
+					decodedFrame.Header, tableRouting, stmtQueryData.queryData)
+				if err != nil {
+					return nil, err
+				}
+				if routed {
+					forwardDecisionByQueryStmtIdx[stmtQueryData.statementIndex] = stmtForwardDecision
+				}
+			}
+		}
+		if counterWrite {
+			mh.GetProxyMetrics().CounterWriteCount.Add(1)
+			fwdDecision, err := getCounterWriteForwardDecision(decodedFrame.Header, primaryCluster, counterWritePolicy)
+			if err != nil {
+				return nil, err
+			}
+			return NewCounterBatchRequestInfo(preparedDataByStmtIdxMap, len(batchMsg.Children), fwdDecision), nil
+		}
+		if conditional {
+			mh.GetProxyMetrics().LwtRequestCount.Add(1)
+			fwdDecision, err := getConditionalForwardDecision(decodedFrame.Header, primaryCluster, lwtPolicy)
+			if err != nil {
+				return nil, err
+			}
+			return NewConditionalBatchRequestInfo(preparedDataByStmtIdxMap, len(batchMsg.Children), fwdDecision), nil
+		}
+		return NewBatchRequestInfo(preparedDataByStmtIdxMap, forwardDecisionByQueryStmtIdx, len(batchMsg.Children)), nil
 	case primitive.OpCodeExecute:
 		decodedFrame, err := frameContext.GetOrDecodeFrame()
 		if err != nil {
@@ -136,10 +228,19 @@ func buildRequestInfo(
 		if !ok {
 			return nil, fmt.Errorf("expected Execute but got %v instead", decodedFrame.Body.Message.GetOpCode())
 		}
-		preparedData, err := getPreparedData(psCache, mh, executeMsg.QueryId, primitive.OpCodeExecute, decodedFrame)
+		preparedData, err := getExecutePreparedData(psCache, mh, executeMsg.QueryId, decodedFrame, virtualizationEnabled, frameContext.logger)
 		if err != nil {
 			return nil, err
 		} else {
+			if preparedData.GetPrepareRequestInfo().GetBaseRequestInfo().IsConditional() {
+				mh.GetProxyMetrics().LwtRequestCount.Add(1)
+			}
+			if preparedData.GetPrepareRequestInfo().GetBaseRequestInfo().IsCounterWrite() {
+				mh.GetProxyMetrics().CounterWriteCount.Add(1)
+			}
+			if keyspace, table, ok := getPreparedStatementTable(preparedData); ok {
+				trackTableRequest(mh, keyspace, table, isPreparedWrite(preparedData), frameContext.logger)
+			}
 			return NewExecuteRequestInfo(preparedData), nil
 		}
 	case primitive.OpCodeAuthResponse:
@@ -155,56 +256,183 @@ func buildRequestInfo(
 	}
 }
 
+// trackTableRequest updates the per-table request metrics, if they are enabled.
+func trackTableRequest(mh *metrics.MetricHandler, keyspace string, table string, write bool, logger *log.Entry) {
+	tableMetrics := mh.GetProxyMetrics().TableRequests
+	if tableMetrics == nil || table == "" {
+		return
+	}
+	if err := tableMetrics.TrackRequest(keyspace, table, write); err != nil {
+		logger.Warnf("Could not update request metrics of table %v.%v: %v", keyspace, table, err)
+	}
+}
+
+func trackStatementTableRequest(mh *metrics.MetricHandler, queryInfo QueryInfo, logger *log.Entry) {
+	switch queryInfo.getStatementType() {
+	case statementTypeSelect:
+		trackTableRequest(mh, queryInfo.getApplicableKeyspace(), queryInfo.getTableName(), false, logger)
+	case statementTypeInsert, statementTypeUpdate, statementTypeDelete, statementTypeBatch:
+		trackTableRequest(mh, queryInfo.getApplicableKeyspace(), queryInfo.getTableName(), true, logger)
+	default:
+	}
+}
+
+// trackBatchTableRequests counts a BATCH request once for every table that its statements write to.
+func trackBatchTableRequests(
+	frameContext *frameDecodeContext,
+	mh *metrics.MetricHandler,
+	preparedDataByStmtIdx map[int]PreparedData,
+	currentKeyspaceName string,
+	timeUuidGenerator TimeUuidGenerator) error {
+	tables := make(map[[2]string]bool)
+	for _, preparedData := range preparedDataByStmtIdx {
+		if keyspace, table, ok := getPreparedStatementTable(preparedData); ok {
+			tables[[2]string{keyspace, table}] = true
+		}
+	}
+	stmtsQueryData, err := frameContext.GetOrInspectAllStatements(currentKeyspaceName, timeUuidGenerator)
+	if err != nil {
+		return fmt.Errorf("could not inspect BATCH frame: %w", err)
+	}
+	for _, stmtQueryData := range stmtsQueryData {
+		tables[[2]string{stmtQueryData.queryData.getApplicableKeyspace(), stmtQueryData.queryData.getTableName()}] = true
+	}
+	for table := range tables {
+		trackTableRequest(mh, table[0], table[1], true, frameContext.logger)
+	}
+	return nil
+}
+
+// getPreparedStatementTable returns the table of a prepared statement from the metadata of its bound variables,
+// which the cluster fills in. It returns false for statements without bound variables.
+func getPreparedStatementTable(preparedData PreparedData) (string, string, bool) {
+	variablesMetadata := preparedData.GetOriginVariablesMetadata()
+	if variablesMetadata == nil || len(variablesMetadata.Columns) == 0 {
+		return "", "", false
+	}
+	column := variablesMetadata.Columns[0]
+	return column.Keyspace, column.Table, column.Table != ""
+}
+
+func isPreparedWrite(preparedData PreparedData) bool {
+	baseRequestInfo := preparedData.GetPrepareRequestInfo().GetBaseRequestInfo()
+	return baseRequestInfo.IsWrite() || isSchemaChangeRequest(baseRequestInfo)
+}
+
 func getPreparedData(
 	psCache *PreparedStatementCache,
 	mh *metrics.MetricHandler,
 	preparedId []byte,
 	code primitive.OpCode,
-	decodedFrame *frame.Frame) (PreparedData, error) {
+	decodedFrame *frame.Frame,
+	logger *log.Entry) (PreparedData, error) {
 	if preparedData, ok := psCache.Get(preparedId); ok {
-		log.Tracef("%v with prepared-id = '%s' has prepared-data = %v", code.String(), hex.EncodeToString(preparedId), preparedData)
+		logger.Tracef("%v with prepared-id = '%s' has prepared-data = %v", code.String(), hex.EncodeToString(preparedId), preparedData)
+		mh.GetProxyMetrics().PSCacheHitCount.Add(1)
 		// The forward decision was set in the cache when handling the corresponding PREPARE request
 		return preparedData, nil
 	} else {
-		log.Warnf("No cached entry for prepared-id = '%s' for %v.", hex.EncodeToString(preparedId), code.String())
+		logger.Warnf("No cached entry for prepared-id = '%s' for %v.", hex.EncodeToString(preparedId), code.String())
 		mh.GetProxyMetrics().PSCacheMissCount.Add(1)
 		// return meaningful error to caller so it can generate an unprepared response
 		return nil, &UnpreparedExecuteError{Header: decodedFrame.Header, Body: decodedFrame.Body, preparedId: preparedId}
 	}
 }
 
+// getExecutePreparedData returns the prepared data of an EXECUTE request. The system.local and system.peers statements
+// that the proxy prepared itself are only executed by the proxy for the connections whose system queries are
+// intercepted, the other connections get an UNPREPARED response so that they prepare the statement on the cluster.
+func getExecutePreparedData(
+	psCache *PreparedStatementCache,
+	mh *metrics.MetricHandler,
+	preparedId []byte,
+	decodedFrame *frame.Frame,
+	virtualizationEnabled bool,
+	logger *log.Entry) (PreparedData, error) {
+	if virtualizationEnabled {
+		if preparedData, ok := psCache.GetIntercepted(preparedId); ok {
+			mh.GetProxyMetrics().PSCacheHitCount.Add(1)
+			return preparedData, nil
+		}
+	} else if preparedData, ok := psCache.Get(preparedId); ok && isInterceptedSystemQuery(preparedData) {
+		logger.Debugf("Prepared-id = '%s' of an intercepted system query was executed by a connection whose system "+
+			"queries are not intercepted, returning UNPREPARED.", hex.EncodeToString(preparedId))
+		mh.GetProxyMetrics().PSCacheMissCount.Add(1)
+		return nil, &UnpreparedExecuteError{Header: decodedFrame.Header, Body: decodedFrame.Body, preparedId: preparedId}
+	}
+	return getPreparedData(psCache, mh, preparedId, primitive.OpCodeExecute, decodedFrame, logger)
+}
+
+func isInterceptedSystemQuery(preparedData PreparedData) bool {
+	interceptedRequestInfo, ok := preparedData.GetPrepareRequestInfo().GetBaseRequestInfo().(*InterceptedRequestInfo)
+	if !ok {
+		return false
+	}
+	switch interceptedRequestInfo.GetQueryType() {
+	case peersV1, peersV2, local:
+		return true
+	default:
+		return false
+	}
+}
+
 func getRequestInfoFromQueryInfo(
 	f *frame.RawFrame,
 	primaryCluster common.ClusterType,
 	forwardSystemQueriesToTarget bool,
 	virtualizationEnabled bool,
-	queryInfo QueryInfo) RequestInfo {
+	introspectionEnabled bool,
+	lwtPolicy common.LwtPolicy,
+	counterWritePolicy common.CounterWritePolicy,
+	ddlPolicy common.DdlPolicy,
+	tableRouting *common.TableRouting,
+	counterTables CounterTableChecker,
+	queryInfo QueryInfo,
+	logger *log.Entry) (RequestInfo, error) {
 
 	var sendAlsoToAsync bool
 	forwardDecision := forwardToBoth
 	if queryInfo.getStatementType() == statementTypeSelect {
+		if introspectionEnabled && isIntrospectionKeyspace(queryInfo.getApplicableKeyspace()) {
+			if queryType, ok := introspectionQueryTypes[queryInfo.getTableName()]; ok {
+				logger.Debugf("Detected introspection query: %v with stream id: %v",
+					redactedStatement(queryInfo.getQuery()), f.Header.StreamId)
+				return NewInterceptedRequestInfo(queryType, queryInfo.getParsedSelectClause()), nil
+			}
+		}
+
 		if virtualizationEnabled {
 			parsedSelectClause := queryInfo.getParsedSelectClause()
 			if isSystemLocal(queryInfo) {
-				log.Debugf("Detected system local query: %v with stream id: %v", queryInfo.getQuery(), f.Header.StreamId)
-				return NewInterceptedRequestInfo(local, parsedSelectClause)
+				logger.Debugf("Detected system local query: %v with stream id: %v",
+					redactedStatement(queryInfo.getQuery()), f.Header.StreamId)
+				return NewInterceptedRequestInfo(local, parsedSelectClause), nil
 			} else if isSystemPeersV1(queryInfo) {
-				log.Debugf("Detected system peers query: %v with stream id: %v", queryInfo.getQuery(), f.Header.StreamId)
-				return NewInterceptedRequestInfo(peersV1, parsedSelectClause)
+				logger.Debugf("Detected system peers query: %v with stream id: %v",
+					redactedStatement(queryInfo.getQuery()), f.Header.StreamId)
+				return NewInterceptedRequestInfo(peersV1, parsedSelectClause), nil
 			} else if isSystemPeersV2(queryInfo) {
-				log.Debugf("Detected system peers_v2 query: %v with stream id: %v", queryInfo.getQuery(), f.Header.StreamId)
-				return NewInterceptedRequestInfo(peersV2, parsedSelectClause)
+				logger.Debugf("Detected system peers_v2 query: %v with stream id: %v",
+					redactedStatement(queryInfo.getQuery()), f.Header.StreamId)
+				return NewInterceptedRequestInfo(peersV2, parsedSelectClause), nil
 			}
 		}
 
 		if isSystemQuery(queryInfo) {
 			sendAlsoToAsync = false
-			log.Debugf("Detected system query: %v with stream id: %v", queryInfo.getQuery(), f.Header.StreamId)
+			logger.Debugf("Detected system query: %v with stream id: %v",
+				redactedStatement(queryInfo.getQuery()), f.Header.StreamId)
 			if forwardSystemQueriesToTarget {
 				forwardDecision = forwardToTarget
 			} else {
 				forwardDecision = forwardToOrigin
 			}
+		} else if routedForwardDecision, routed, _ := getTableRoutingForwardDecision(
+			f.Header, tableRouting, queryInfo); routed {
+			sendAlsoToAsync = false
+			logger.Debugf("Detected read of a routed table: %v with stream id: %v",
+				redactedStatement(queryInfo.getQuery()), f.Header.StreamId)
+			forwardDecision = routedForwardDecision
 		} else {
 			sendAlsoToAsync = true
 			if primaryCluster == common.ClusterTypeTarget {
@@ -215,13 +443,153 @@ func getRequestInfoFromQueryInfo(
 		}
 	} else if queryInfo.getStatementType() == statementTypeUse {
 		sendAlsoToAsync = true
+	} else if queryInfo.isConditional() {
+		logger.Debugf("Detected lightweight transaction: %v with stream id: %v",
+			redactedStatement(queryInfo.getQuery()), f.Header.StreamId)
+		conditionalForwardDecision, err := getConditionalForwardDecision(f.Header, primaryCluster, lwtPolicy)
+		if err != nil {
+			return nil, err
+		}
+		return NewConditionalRequestInfo(conditionalForwardDecision), nil
+	} else if isCounterWrite(queryInfo, counterTables) {
+		counterWriteForwardDecision, err := getCounterWriteForwardDecision(f.Header, primaryCluster, counterWritePolicy)
+		if err != nil {
+			return nil, err
+		}
+		if counterWriteForwardDecision == forwardToBoth {
+			logger.Warnf("Counter update is being written to both clusters, counter values may diverge: %v",
+				redactedStatement(queryInfo.getQuery()))
+		} else {
+			logger.Debugf("Detected counter update: %v with stream id: %v",
+				redactedStatement(queryInfo.getQuery()), f.Header.StreamId)
+		}
+		return NewCounterWriteRequestInfo(counterWriteForwardDecision), nil
+	} else if queryInfo.getStatementType() == statementTypeOther && isDdlQuery(queryInfo.getQuery()) {
+		logger.Debugf("Detected schema change: %v with stream id: %v",
+			redactedStatement(queryInfo.getQuery()), f.Header.StreamId)
+		return getSchemaChangeRequestInfo(f.Header, ddlPolicy)
 	} else {
 		sendAlsoToAsync = false
+		routedForwardDecision, routed, err := getTableRoutingForwardDecision(f.Header, tableRouting, queryInfo)
+		if err != nil {
+			return nil, err
+		}
+		if routed {
+			logger.Debugf("Detected write to a routed table: %v with stream id: %v",
+				redactedStatement(queryInfo.getQuery()), f.Header.StreamId)
+			forwardDecision = routedForwardDecision
+		}
 	}
 
-	log.Tracef("Forward decision: %s", forwardDecision)
+	logger.Tracef("Forward decision: %s", forwardDecision)
 
-	return NewGenericRequestInfo(forwardDecision, sendAlsoToAsync, true)
+	switch queryInfo.getStatementType() {
+	case statementTypeInsert, statementTypeUpdate, statementTypeDelete, statementTypeBatch:
+		return NewWriteRequestInfo(forwardDecision), nil
+	default:
+		return NewGenericRequestInfo(forwardDecision, sendAlsoToAsync, true), nil
+	}
+}
+
+// getTableRoutingForwardDecision returns the forward decision of ZDM_TABLE_ROUTING_RULES for the table that a SELECT
+// reads or for the tables that an INSERT, UPDATE, DELETE or BATCH query writes to, or false if none of them is routed.
+// A BATCH query string can not be split like a BATCH request, so it is rejected if its statements write to tables that
+// are not all routed to the same cluster.
+func getTableRoutingForwardDecision(
+	header *frame.Header, tableRouting *common.TableRouting, queryInfo QueryInfo) (forwardDecision, bool, error) {
+	if tableRouting == nil {
+		return forwardToBoth, false, nil
+	}
+
+	var clusters []common.ClusterType
+	if queryInfo.getStatementType() == statementTypeSelect {
+		if cluster, ok := tableRouting.GetCluster(queryInfo.getApplicableKeyspace(), queryInfo.getTableName()); ok {
+			clusters = append(clusters, cluster)
+		}
+	} else {
+		stmts := queryInfo.getParsedStatements()
+		for _, stmt := range stmts {
+			if cluster, ok := tableRouting.GetCluster(stmt.keyspaceName, stmt.tableName); ok {
+				clusters = append(clusters, cluster)
+			}
+		}
+		if len(clusters) > 0 && len(clusters) != len(stmts) {
+			return forwardToNone, false, &RejectedRequestError{
+				Header: header,
+				Reason: "the statements of this BATCH write to routed and non routed tables, " +
+					"send them as separate statements or in a BATCH request"}
+		}
+	}
+	if len(clusters) == 0 {
+		return forwardToBoth, false, nil
+	}
+	for _, cluster := range clusters[1:] {
+		if cluster != clusters[0] {
+			return forwardToNone, false, &RejectedRequestError{
+				Header: header,
+				Reason: fmt.Sprintf("the statements of this BATCH write to tables routed to %v and %v, "+
+					"send them as separate statements or in a BATCH request", common.ClusterTypeOrigin,
+					common.ClusterTypeTarget)}
+		}
+	}
+	if clusters[0] == common.ClusterTypeTarget {
+		return forwardToTarget, true, nil
+	}
+	return forwardToOrigin, true, nil
+}
+
+// getConditionalForwardDecision applies the configured LWT policy to a lightweight transaction.
+func getConditionalForwardDecision(
+	header *frame.Header, primaryCluster common.ClusterType, lwtPolicy common.LwtPolicy) (forwardDecision, error) {
+	switch lwtPolicy {
+	case common.LwtPolicyReject:
+		return forwardToNone, &RejectedRequestError{
+			Header: header,
+			Reason: "lightweight transactions (conditional writes) are not allowed during the migration"}
+	case common.LwtPolicyPrimaryOnly:
+		if primaryCluster == common.ClusterTypeTarget {
+			return forwardToTarget, nil
+		}
+		return forwardToOrigin, nil
+	default:
+		return forwardToBoth, nil
+	}
+}
+
+// CounterTableChecker tells whether a table has counter columns.
+type CounterTableChecker interface {
+	IsCounterTable(keyspaceName string, tableName string) bool
+}
+
+// isCounterWrite returns true if at least one of the statements is an UPDATE on a counter table.
+func isCounterWrite(queryInfo QueryInfo, counterTables CounterTableChecker) bool {
+	if counterTables == nil {
+		return false
+	}
+	for _, stmt := range queryInfo.getParsedStatements() {
+		if stmt.statementType == statementTypeUpdate && counterTables.IsCounterTable(stmt.keyspaceName, stmt.tableName) {
+			return true
+		}
+	}
+	return false
+}
+
+// getCounterWriteForwardDecision applies the configured counter write policy to an UPDATE on a counter table.
+func getCounterWriteForwardDecision(
+	header *frame.Header, primaryCluster common.ClusterType, counterWritePolicy common.CounterWritePolicy) (forwardDecision, error) {
+	switch counterWritePolicy {
+	case common.CounterWritePolicyReject:
+		return forwardToNone, &RejectedRequestError{
+			Header: header,
+			Reason: "counter updates are not allowed during the migration"}
+	case common.CounterWritePolicyPrimaryOnly:
+		if primaryCluster == common.ClusterTypeTarget {
+			return forwardToTarget, nil
+		}
+		return forwardToOrigin, nil
+	default:
+		return forwardToBoth, nil
+	}
 }
 
 func isSystemQuery(info QueryInfo) bool {
@@ -263,19 +631,22 @@ type frameDecodeContext struct {
 	frame               *frame.RawFrame       // always non nil
 	decodedFrame        *frame.Frame          // nil until first decode
 	statementsQueryData []*statementQueryData // nil until first query inspection
+	logger              *log.Entry
 }
 
 var NotInspectableErr = errors.New("only Query and Prepare messages can be inspected")
 
-func NewFrameDecodeContext(f *frame.RawFrame) *frameDecodeContext {
-	return &frameDecodeContext{frame: f}
+func NewFrameDecodeContext(f *frame.RawFrame, logger *log.Entry) *frameDecodeContext {
+	return &frameDecodeContext{frame: f, logger: logger}
 }
 
-func NewInitializedFrameDecodeContext(f *frame.RawFrame, decodedFrame *frame.Frame, statementsQueryData []*statementQueryData) *frameDecodeContext {
+func NewInitializedFrameDecodeContext(
+	f *frame.RawFrame, decodedFrame *frame.Frame, statementsQueryData []*statementQueryData, logger *log.Entry) *frameDecodeContext {
 	return &frameDecodeContext{
 		frame:               f,
 		decodedFrame:        decodedFrame,
-		statementsQueryData: statementsQueryData}
+		statementsQueryData: statementsQueryData,
+		logger:              logger}
 }
 
 func (recv *frameDecodeContext) GetRawFrame() *frame.RawFrame {
@@ -331,21 +702,21 @@ func (recv *frameDecodeContext) inspectStatements(currentKeyspace string, timeUu
 	var statementsQueryData []*statementQueryData
 	switch typedMsg := decodedFrame.Body.Message.(type) {
 	case *message.Query:
-		log.Tracef("Decoded frame %v", decodedFrame)
+		recv.logger.Tracef("Decoded frame %v", redactedFrame{decodedFrame})
 		if protocolSupportsKeyspaceInRequest(decodedFrame.Header.Version) &&
 			typedMsg.Options != nil &&
 			typedMsg.Options.Flags().Contains(primitive.QueryFlagWithKeyspace) {
 			currentKeyspace = typedMsg.Options.Keyspace
 		}
 		statementsQueryData = []*statementQueryData{
-			{statementIndex: 0, queryData: inspectCqlQuery(typedMsg.Query, currentKeyspace, timeUuidGenerator)}}
+			{statementIndex: 0, queryData: inspectCqlQuery(typedMsg.Query, currentKeyspace, timeUuidGenerator, recv.logger)}}
 	case *message.Prepare:
 		if protocolSupportsKeyspaceInRequest(decodedFrame.Header.Version) &&
 			typedMsg.Flags().Contains(primitive.PrepareFlagWithKeyspace) {
 			currentKeyspace = typedMsg.Keyspace
 		}
 		statementsQueryData = []*statementQueryData{
-			{statementIndex: 0, queryData: inspectCqlQuery(typedMsg.Query, currentKeyspace, timeUuidGenerator)}}
+			{statementIndex: 0, queryData: inspectCqlQuery(typedMsg.Query, currentKeyspace, timeUuidGenerator, recv.logger)}}
 	case *message.Batch:
 		if protocolSupportsKeyspaceInRequest(decodedFrame.Header.Version) &&
 			typedMsg.Flags().Contains(primitive.QueryFlagWithKeyspace) {
@@ -356,7 +727,7 @@ func (recv *frameDecodeContext) inspectStatements(currentKeyspace string, timeUu
 			case string:
 				statementsQueryData = append(
 					statementsQueryData, &statementQueryData{
-						statementIndex: idx, queryData: inspectCqlQuery(typedQueryOrId, currentKeyspace, timeUuidGenerator)})
+						statementIndex: idx, queryData: inspectCqlQuery(typedQueryOrId, currentKeyspace, timeUuidGenerator, recv.logger)})
 			}
 		}
 	default:
@@ -6,6 +6,7 @@ import (
 	"github.com/datastax/go-cassandra-native-protocol/primitive"
 	"github.com/datastax/zdm-proxy/proxy/pkg/common"
 	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 	"reflect"
 	"testing"
@@ -27,7 +28,7 @@ func getGeneralParamsForTests(t *testing.T) params {
 	require.Nil(t, err)
 
 	return params{
-		psCache:                      NewPreparedStatementCache(),
+		psCache:                      NewPreparedStatementCache(5000, log.NewEntry(log.StandardLogger())),
 		mh:                           newFakeMetricHandler(),
 		kn:                           "",
 		primaryCluster:               common.ClusterTypeOrigin,
@@ -72,7 +73,7 @@ func convertEncodedRequestToRawFrameForTests(queryFrame *frame.Frame, t *testing
 func parseEncodedRequestForTests(queryRawFrame *frame.RawFrame, t *testing.T) (RequestInfo, error) {
 	generalParams := getGeneralParamsForTests(t)
 
-	return buildRequestInfo(&frameDecodeContext{frame: queryRawFrame},
+	return buildRequestInfo(&frameDecodeContext{frame: queryRawFrame, logger: log.NewEntry(log.StandardLogger())},
 		[]*statementReplacedTerms{},
 		generalParams.psCache,
 		generalParams.mh,
@@ -80,7 +81,13 @@ func parseEncodedRequestForTests(queryRawFrame *frame.RawFrame, t *testing.T) (R
 		generalParams.primaryCluster,
 		generalParams.forwardSystemQueriesToTarget,
 		generalParams.virtualizationEnabled,
+		false,
 		generalParams.forwardAuthToTarget,
+		common.LwtPolicyBoth,
+		common.CounterWritePolicyBoth,
+		common.DdlPolicyBoth,
+		nil,
+		nil,
 		generalParams.timeUuidGenerator)
 }
 
@@ -9,6 +9,7 @@ import (
 	"github.com/datastax/zdm-proxy/proxy/pkg/common"
 	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
 	"github.com/datastax/zdm-proxy/proxy/pkg/metrics/noopmetrics"
+	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 	"reflect"
 	"testing"
@@ -57,7 +58,7 @@ func TestInspectFrame(t *testing.T) {
 		targetPreparedId:   []byte("LOCAL"),
 		prepareRequestInfo: NewPrepareRequestInfo(NewInterceptedRequestInfo(local, newStarSelectClause()), nil, false, "SELECT * FROM system.local", ""),
 	}
-	psCache := NewPreparedStatementCache()
+	psCache := NewPreparedStatementCache(5000, log.NewEntry(log.StandardLogger()))
 	psCache.cache["BOTH"] = bothCacheEntry
 	psCache.cache["ORIGIN"] = originCacheEntry
 	psCache.cache["TARGET"] = targetCacheEntry
@@ -90,8 +91,8 @@ func TestInspectFrame(t *testing.T) {
 		{"OpCodeQuery SELECT system.peers_v2", args{mockQueryFrame(t, "SELECT * FROM system.peers_v2"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToTarget, forwardAuthToOrigin}, NewInterceptedRequestInfo(peersV2, newStarSelectClause())},
 		{"OpCodeQuery SELECT system_auth.roles", args{mockQueryFrame(t, "SELECT * FROM system_auth.roles"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewGenericRequestInfo(forwardToOrigin, false, true)},
 		{"OpCodeQuery SELECT dse_insights.tokens", args{mockQueryFrame(t, "SELECT * FROM dse_insights.tokens"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewGenericRequestInfo(forwardToOrigin, false, true)},
-		{"OpCodeQuery INSERT INTO asd (a, b) VALUES (1, 2)", args{mockQueryFrame(t, "INSERT INTO asd (a, b) VALUES (1, 2)"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewGenericRequestInfo(forwardToBoth, false, true)},
-		{"OpCodeQuery UPDATE asd SET b = 2 WHERE a = 1", args{mockQueryFrame(t, "UPDATE asd SET b = 2 WHERE a = 1"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewGenericRequestInfo(forwardToBoth, false, true)},
+		{"OpCodeQuery INSERT INTO asd (a, b) VALUES (1, 2)", args{mockQueryFrame(t, "INSERT INTO asd (a, b) VALUES (1, 2)"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewWriteRequestInfo(forwardToBoth)},
+		{"OpCodeQuery UPDATE asd SET b = 2 WHERE a = 1", args{mockQueryFrame(t, "UPDATE asd SET b = 2 WHERE a = 1"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewWriteRequestInfo(forwardToBoth)},
 		{"OpCodeQuery UNKNOWN", args{mockQueryFrame(t, "UNKNOWN"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewGenericRequestInfo(forwardToBoth, false, true)},
 
 		// PREPARE
@@ -106,8 +107,8 @@ func TestInspectFrame(t *testing.T) {
 		{"OpCodePrepare SELECT system.peers_v2 forwardSystemQueriesToOrigin", args{mockPrepareFrame(t, "SELECT * FROM system.peers_v2"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewPrepareRequestInfo(NewInterceptedRequestInfo(peersV2, newStarSelectClause()), []*term{}, false, "SELECT * FROM system.peers_v2", "")},
 		{"OpCodePrepare SELECT system_auth.roles", args{mockPrepareFrame(t, "SELECT * FROM system_auth.roles"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToTarget, forwardAuthToOrigin}, NewPrepareRequestInfo(NewGenericRequestInfo(forwardToTarget, false, true), []*term{}, false, "SELECT * FROM system_auth.roles", "")},
 		{"OpCodePrepare SELECT dse_insights.tokens", args{mockPrepareFrame(t, "SELECT * FROM dse_insights.tokens"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToTarget, forwardAuthToOrigin}, NewPrepareRequestInfo(NewGenericRequestInfo(forwardToTarget, false, true), []*term{}, false, "SELECT * FROM dse_insights.tokens", "")},
-		{"OpCodePrepare INSERT INTO asd (a, b) VALUES (1, 2)", args{mockPrepareFrame(t, "INSERT INTO asd (a, b) VALUES (1, 2)"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), []*term{}, false, "INSERT INTO asd (a, b) VALUES (1, 2)", "")},
-		{"OpCodePrepare UPDATE asd SET b = 2 WHERE a = 1", args{mockPrepareFrame(t, "UPDATE asd SET b = 2 WHERE a = 1"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), []*term{}, false, "UPDATE asd SET b = 2 WHERE a = 1", "")},
+		{"OpCodePrepare INSERT INTO asd (a, b) VALUES (1, 2)", args{mockPrepareFrame(t, "INSERT INTO asd (a, b) VALUES (1, 2)"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewPrepareRequestInfo(NewWriteRequestInfo(forwardToBoth), []*term{}, false, "INSERT INTO asd (a, b) VALUES (1, 2)", "")},
+		{"OpCodePrepare UPDATE asd SET b = 2 WHERE a = 1", args{mockPrepareFrame(t, "UPDATE asd SET b = 2 WHERE a = 1"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewPrepareRequestInfo(NewWriteRequestInfo(forwardToBoth), []*term{}, false, "UPDATE asd SET b = 2 WHERE a = 1", "")},
 		{"OpCodePrepare UNKNOWN", args{mockPrepareFrame(t, "UNKNOWN"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), []*term{}, false, "UNKNOWN", "")},
 
 		// EXECUTE
@@ -122,8 +123,10 @@ func TestInspectFrame(t *testing.T) {
 		// REGISTER
 		{"OpCodeRegister", args{mockFrame(t, &message.Register{EventTypes: []primitive.EventType{primitive.EventTypeSchemaChange}}, primitive.ProtocolVersion4), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewGenericRequestInfo(forwardToBoth, false, false)},
 		// BATCH
-		{"OpCodeBatch simple", args{mockBatch(t, "simple query"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewBatchRequestInfo(map[int]PreparedData{})},
-		{"OpCodeBatch prepared", args{mockBatch(t, []byte("BOTH")), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewBatchRequestInfo(map[int]PreparedData{0: bothCacheEntry})},
+		{"OpCodeBatch simple", args{mockBatch(t, "simple query"), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewBatchRequestInfo(map[int]PreparedData{}, nil, 1)},
+		{"OpCodeBatch prepared", args{mockBatch(t, []byte("BOTH")), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewBatchRequestInfo(map[int]PreparedData{0: bothCacheEntry}, nil, 1)},
+		{"OpCodeBatch prepared origin only", args{mockBatchWithChildren(t, []*message.BatchChild{{QueryOrId: []byte("ORIGIN")}, {QueryOrId: []byte("ORIGIN")}}), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewBatchRequestInfo(map[int]PreparedData{0: originCacheEntry, 1: originCacheEntry}, nil, 2)},
+		{"OpCodeBatch prepared split", args{mockBatchWithChildren(t, []*message.BatchChild{{QueryOrId: []byte("ORIGIN")}, {QueryOrId: "simple query"}, {QueryOrId: []byte("TARGET")}}), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewBatchRequestInfo(map[int]PreparedData{0: originCacheEntry, 2: targetCacheEntry}, nil, 3)},
 		// AUTH_RESPONSE
 		{"OpCodeAuthResponse ForwardAuthToTarget", args{mockAuthResponse(t), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToTarget}, NewGenericRequestInfo(forwardToTarget, false, false)},
 		{"OpCodeAuthResponse ForwardAuthToOrigin", args{mockAuthResponse(t), []*term{}, primaryClusterOrigin, forwardSystemQueriesToOrigin, forwardAuthToOrigin}, NewGenericRequestInfo(forwardToOrigin, false, false)},
@@ -135,10 +138,11 @@ func TestInspectFrame(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			timeUuidGenerator, err := GetDefaultTimeUuidGenerator()
 			require.Nil(t, err)
-			actual, err := buildRequestInfo(&frameDecodeContext{frame: tt.args.f}, []*statementReplacedTerms{{
+			actual, err := buildRequestInfo(&frameDecodeContext{frame: tt.args.f, logger: log.NewEntry(log.StandardLogger())}, []*statementReplacedTerms{{
 				statementIndex: 0,
 				replacedTerms:  tt.args.replacedTerms,
-			}}, psCache, mh, km, tt.args.primaryCluster, tt.args.forwardSystemQueriesToTarget, true, tt.args.forwardAuthToTarget, timeUuidGenerator)
+			}}, psCache, mh, km, tt.args.primaryCluster, tt.args.forwardSystemQueriesToTarget, true, false, tt.args.forwardAuthToTarget,
+				common.LwtPolicyBoth, common.CounterWritePolicyBoth, common.DdlPolicyBoth, nil, nil, timeUuidGenerator)
 			if err != nil {
 				if !reflect.DeepEqual(err.Error(), tt.expected) {
 					t.Errorf("buildRequestInfo() actual = %v, expected %v", err, tt.expected)
@@ -150,6 +154,231 @@ func TestInspectFrame(t *testing.T) {
 	}
 }
 
+func TestInspectFrameLwtPolicy(t *testing.T) {
+	conditionalCacheEntry := &preparedDataImpl{
+		originPreparedId:   []byte("LWT"),
+		targetPreparedId:   []byte("LWT_TARGET"),
+		prepareRequestInfo: NewPrepareRequestInfo(NewConditionalRequestInfo(forwardToBoth), nil, false, "", ""),
+	}
+	psCache := NewPreparedStatementCache(5000, log.NewEntry(log.StandardLogger()))
+	psCache.cache["LWT"] = conditionalCacheEntry
+	mh := newFakeMetricHandler()
+
+	lwtInsert := "INSERT INTO ks.tb (a, b) VALUES (1, 2) IF NOT EXISTS"
+	lwtUpdate := "UPDATE ks.tb SET b = 2 WHERE a = 1 IF b = 1"
+	lwtDelete := "DELETE FROM ks.tb WHERE a = 1 IF EXISTS"
+	rejectedErr := "Request rejected by the proxy: lightweight transactions (conditional writes) are not allowed during the migration"
+	tests := []struct {
+		name           string
+		f              *frame.RawFrame
+		primaryCluster common.ClusterType
+		lwtPolicy      common.LwtPolicy
+		expected       interface{}
+	}{
+		{"QUERY INSERT both", mockQueryFrame(t, lwtInsert), common.ClusterTypeOrigin, common.LwtPolicyBoth, NewConditionalRequestInfo(forwardToBoth)},
+		{"QUERY UPDATE both", mockQueryFrame(t, lwtUpdate), common.ClusterTypeOrigin, common.LwtPolicyBoth, NewConditionalRequestInfo(forwardToBoth)},
+		{"QUERY DELETE both", mockQueryFrame(t, lwtDelete), common.ClusterTypeOrigin, common.LwtPolicyBoth, NewConditionalRequestInfo(forwardToBoth)},
+		{"QUERY INSERT primary only", mockQueryFrame(t, lwtInsert), common.ClusterTypeOrigin, common.LwtPolicyPrimaryOnly, NewConditionalRequestInfo(forwardToOrigin)},
+		{"QUERY UPDATE primary only target", mockQueryFrame(t, lwtUpdate), common.ClusterTypeTarget, common.LwtPolicyPrimaryOnly, NewConditionalRequestInfo(forwardToTarget)},
+		{"QUERY DELETE reject", mockQueryFrame(t, lwtDelete), common.ClusterTypeOrigin, common.LwtPolicyReject, rejectedErr},
+		{"QUERY non conditional reject", mockQueryFrame(t, "UPDATE ks.tb SET b = 2 WHERE a = 1"), common.ClusterTypeOrigin, common.LwtPolicyReject, NewWriteRequestInfo(forwardToBoth)},
+		{"PREPARE primary only", mockPrepareFrame(t, lwtInsert), common.ClusterTypeOrigin, common.LwtPolicyPrimaryOnly, NewPrepareRequestInfo(NewConditionalRequestInfo(forwardToOrigin), []*term{}, false, lwtInsert, "")},
+		{"PREPARE reject", mockPrepareFrame(t, lwtInsert), common.ClusterTypeOrigin, common.LwtPolicyReject, rejectedErr},
+		{"EXECUTE", mockExecuteFrame(t, "LWT"), common.ClusterTypeOrigin, common.LwtPolicyPrimaryOnly, NewExecuteRequestInfo(conditionalCacheEntry)},
+		{"BATCH simple both not inspected", mockBatchWithChildren(t, []*message.BatchChild{{QueryOrId: "INSERT INTO ks.tb (a, b) VALUES (1, 2)"}, {QueryOrId: lwtUpdate}}), common.ClusterTypeOrigin, common.LwtPolicyBoth, NewBatchRequestInfo(map[int]PreparedData{}, nil, 2)},
+		{"BATCH simple primary only", mockBatchWithChildren(t, []*message.BatchChild{{QueryOrId: "INSERT INTO ks.tb (a, b) VALUES (1, 2)"}, {QueryOrId: lwtUpdate}}), common.ClusterTypeOrigin, common.LwtPolicyPrimaryOnly, NewConditionalBatchRequestInfo(map[int]PreparedData{}, 2, forwardToOrigin)},
+		{"BATCH prepared primary only", mockBatchWithChildren(t, []*message.BatchChild{{QueryOrId: "INSERT INTO ks.tb (a, b) VALUES (1, 2)"}, {QueryOrId: []byte("LWT")}}), common.ClusterTypeTarget, common.LwtPolicyPrimaryOnly, NewConditionalBatchRequestInfo(map[int]PreparedData{1: conditionalCacheEntry}, 2, forwardToTarget)},
+		{"BATCH reject", mockBatch(t, lwtInsert), common.ClusterTypeOrigin, common.LwtPolicyReject, rejectedErr},
+		{"BATCH non conditional reject", mockBatch(t, "INSERT INTO ks.tb (a, b) VALUES (1, 2)"), common.ClusterTypeOrigin, common.LwtPolicyReject, NewBatchRequestInfo(map[int]PreparedData{}, nil, 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeUuidGenerator, err := GetDefaultTimeUuidGenerator()
+			require.Nil(t, err)
+			actual, err := buildRequestInfo(&frameDecodeContext{frame: tt.f, logger: log.NewEntry(log.StandardLogger())}, []*statementReplacedTerms{}, psCache, mh, "",
+				tt.primaryCluster, false, true, false, false, tt.lwtPolicy, common.CounterWritePolicyBoth, common.DdlPolicyBoth, nil, nil, timeUuidGenerator)
+			if err != nil {
+				require.Equal(t, tt.expected, err.Error())
+			} else {
+				require.Equal(t, tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestInspectFrame_BatchQueryStringsInspectedOnlyWhenNeeded(t *testing.T) {
+	psCache := NewPreparedStatementCache(5000, log.NewEntry(log.StandardLogger()))
+	mh := newFakeMetricHandler()
+	batch := mockBatchWithChildren(t, []*message.BatchChild{
+		{QueryOrId: "INSERT INTO ks.tb (a, b) VALUES (1, 2)"}, {QueryOrId: "UPDATE ks.tb SET b = 3 WHERE a = 1 IF b = 2"}})
+	tests := []struct {
+		name               string
+		lwtPolicy          common.LwtPolicy
+		counterWritePolicy common.CounterWritePolicy
+		tableRouting       *common.TableRouting
+		inspected          bool
+	}{
+		{"default policies", common.LwtPolicyBoth, common.CounterWritePolicyBoth, nil, false},
+		{"lwt policy", common.LwtPolicyPrimaryOnly, common.CounterWritePolicyBoth, nil, true},
+		{"counter write policy", common.LwtPolicyBoth, common.CounterWritePolicyReject, nil, true},
+		{"table routing", common.LwtPolicyBoth, common.CounterWritePolicyBoth, &common.TableRouting{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeUuidGenerator, err := GetDefaultTimeUuidGenerator()
+			require.Nil(t, err)
+			frameContext := &frameDecodeContext{frame: batch, logger: log.NewEntry(log.StandardLogger())}
+			_, err = buildRequestInfo(frameContext, []*statementReplacedTerms{}, psCache, mh, "",
+				common.ClusterTypeOrigin, false, true, false, false, tt.lwtPolicy, tt.counterWritePolicy,
+				common.DdlPolicyBoth, tt.tableRouting, nil, timeUuidGenerator)
+			require.Nil(t, err)
+			require.Equal(t, tt.inspected, frameContext.statementsQueryData != nil)
+		})
+	}
+}
+
+type fakeCounterTableChecker map[string]bool
+
+func (recv fakeCounterTableChecker) IsCounterTable(keyspaceName string, tableName string) bool {
+	return recv[keyspaceName+"."+tableName]
+}
+
+func TestInspectFrameCounterWritePolicy(t *testing.T) {
+	counterCacheEntry := &preparedDataImpl{
+		originPreparedId:   []byte("COUNTER"),
+		targetPreparedId:   []byte("COUNTER_TARGET"),
+		prepareRequestInfo: NewPrepareRequestInfo(NewCounterWriteRequestInfo(forwardToOrigin), nil, false, "", ""),
+	}
+	psCache := NewPreparedStatementCache(5000, log.NewEntry(log.StandardLogger()))
+	psCache.cache["COUNTER"] = counterCacheEntry
+	mh := newFakeMetricHandler()
+	counterTables := fakeCounterTableChecker{"ks.counters": true}
+
+	counterUpdate := "UPDATE ks.counters SET c = c + 1 WHERE a = 1"
+	unqualifiedCounterUpdate := "UPDATE counters SET c = c + 1 WHERE a = 1"
+	rejectedErr := "Request rejected by the proxy: counter updates are not allowed during the migration"
+	tests := []struct {
+		name               string
+		f                  *frame.RawFrame
+		keyspace           string
+		primaryCluster     common.ClusterType
+		counterWritePolicy common.CounterWritePolicy
+		expected           interface{}
+	}{
+		{"QUERY both", mockQueryFrame(t, counterUpdate), "", common.ClusterTypeOrigin, common.CounterWritePolicyBoth, NewCounterWriteRequestInfo(forwardToBoth)},
+		{"QUERY primary only", mockQueryFrame(t, counterUpdate), "", common.ClusterTypeOrigin, common.CounterWritePolicyPrimaryOnly, NewCounterWriteRequestInfo(forwardToOrigin)},
+		{"QUERY primary only target", mockQueryFrame(t, counterUpdate), "", common.ClusterTypeTarget, common.CounterWritePolicyPrimaryOnly, NewCounterWriteRequestInfo(forwardToTarget)},
+		{"QUERY unqualified primary only", mockQueryFrame(t, unqualifiedCounterUpdate), "ks", common.ClusterTypeOrigin, common.CounterWritePolicyPrimaryOnly, NewCounterWriteRequestInfo(forwardToOrigin)},
+		{"QUERY unqualified other keyspace", mockQueryFrame(t, unqualifiedCounterUpdate), "ks2", common.ClusterTypeOrigin, common.CounterWritePolicyReject, NewWriteRequestInfo(forwardToBoth)},
+		{"QUERY reject", mockQueryFrame(t, counterUpdate), "", common.ClusterTypeOrigin, common.CounterWritePolicyReject, rejectedErr},
+		{"QUERY non counter table reject", mockQueryFrame(t, "UPDATE ks.tb SET b = 2 WHERE a = 1"), "", common.ClusterTypeOrigin, common.CounterWritePolicyReject, NewWriteRequestInfo(forwardToBoth)},
+		{"PREPARE primary only", mockPrepareFrame(t, counterUpdate), "", common.ClusterTypeOrigin, common.CounterWritePolicyPrimaryOnly, NewPrepareRequestInfo(NewCounterWriteRequestInfo(forwardToOrigin), []*term{}, false, counterUpdate, "")},
+		{"PREPARE reject", mockPrepareFrame(t, counterUpdate), "", common.ClusterTypeOrigin, common.CounterWritePolicyReject, rejectedErr},
+		{"EXECUTE", mockExecuteFrame(t, "COUNTER"), "", common.ClusterTypeOrigin, common.CounterWritePolicyPrimaryOnly, NewExecuteRequestInfo(counterCacheEntry)},
+		{"BATCH simple primary only", mockBatchWithChildren(t, []*message.BatchChild{{QueryOrId: counterUpdate}, {QueryOrId: counterUpdate}}), "", common.ClusterTypeTarget, common.CounterWritePolicyPrimaryOnly, NewCounterBatchRequestInfo(map[int]PreparedData{}, 2, forwardToTarget)},
+		{"BATCH prepared both", mockBatchWithChildren(t, []*message.BatchChild{{QueryOrId: counterUpdate}, {QueryOrId: []byte("COUNTER")}}), "", common.ClusterTypeOrigin, common.CounterWritePolicyBoth, NewCounterBatchRequestInfo(map[int]PreparedData{1: counterCacheEntry}, 2, forwardToBoth)},
+		{"BATCH reject", mockBatch(t, counterUpdate), "", common.ClusterTypeOrigin, common.CounterWritePolicyReject, rejectedErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeUuidGenerator, err := GetDefaultTimeUuidGenerator()
+			require.Nil(t, err)
+			actual, err := buildRequestInfo(&frameDecodeContext{frame: tt.f, logger: log.NewEntry(log.StandardLogger())}, []*statementReplacedTerms{}, psCache, mh, tt.keyspace,
+				tt.primaryCluster, false, true, false, false, common.LwtPolicyBoth, tt.counterWritePolicy, common.DdlPolicyBoth, nil, counterTables, timeUuidGenerator)
+			if err != nil {
+				require.Equal(t, tt.expected, err.Error())
+			} else {
+				require.Equal(t, tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestInspectFrameDdlPolicy(t *testing.T) {
+	psCache := NewPreparedStatementCache(5000, log.NewEntry(log.StandardLogger()))
+	mh := newFakeMetricHandler()
+	createTable := "CREATE TABLE ks.tb (a int PRIMARY KEY, b int)"
+	rejectedErr := "Request rejected by the proxy: schema changes are not allowed during the migration"
+	tests := []struct {
+		name      string
+		f         *frame.RawFrame
+		ddlPolicy common.DdlPolicy
+		expected  interface{}
+	}{
+		{"QUERY both", mockQueryFrame(t, createTable), common.DdlPolicyBoth, NewSchemaChangeRequestInfo(forwardToBoth, true)},
+		{"QUERY origin only", mockQueryFrame(t, "drop keyspace ks"), common.DdlPolicyOriginOnly, NewSchemaChangeRequestInfo(forwardToOrigin, false)},
+		{"QUERY reject", mockQueryFrame(t, "ALTER TABLE ks.tb ADD c int"), common.DdlPolicyReject, rejectedErr},
+		{"QUERY non DDL reject", mockQueryFrame(t, "UPDATE ks.tb SET b = 2 WHERE a = 1"), common.DdlPolicyReject, NewWriteRequestInfo(forwardToBoth)},
+		{"PREPARE origin only", mockPrepareFrame(t, createTable), common.DdlPolicyOriginOnly, NewPrepareRequestInfo(NewSchemaChangeRequestInfo(forwardToOrigin, false), []*term{}, false, createTable, "")},
+		{"PREPARE reject", mockPrepareFrame(t, createTable), common.DdlPolicyReject, rejectedErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeUuidGenerator, err := GetDefaultTimeUuidGenerator()
+			require.Nil(t, err)
+			actual, err := buildRequestInfo(&frameDecodeContext{frame: tt.f, logger: log.NewEntry(log.StandardLogger())}, []*statementReplacedTerms{}, psCache, mh, "",
+				common.ClusterTypeOrigin, false, true, false, false, common.LwtPolicyBoth, common.CounterWritePolicyBoth, tt.ddlPolicy, nil, nil, timeUuidGenerator)
+			if err != nil {
+				require.Equal(t, tt.expected, err.Error())
+			} else {
+				require.Equal(t, tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestInspectFrame_TableRouting(t *testing.T) {
+	psCache := NewPreparedStatementCache(5000, log.NewEntry(log.StandardLogger()))
+	mh := newFakeMetricHandler()
+	tableRouting := &common.TableRouting{
+		Keyspaces: map[string]common.ClusterType{"ks_legacy": common.ClusterTypeOrigin},
+		Tables:    map[common.QualifiedTableName]common.ClusterType{{Keyspace: "ks", Table: "events"}: common.ClusterTypeTarget},
+	}
+	insertEvent := "INSERT INTO ks.events (a, b) VALUES (1, 2)"
+	insertUser := "INSERT INTO ks.users (a, b) VALUES (1, 2)"
+	insertLegacy := "INSERT INTO tb (a, b) VALUES (1, 2)"
+	tests := []struct {
+		name     string
+		f        *frame.RawFrame
+		keyspace string
+		expected interface{}
+		split    bool
+	}{
+		{"SELECT routed table", mockQueryFrame(t, "SELECT * FROM ks.events"), "", NewGenericRequestInfo(forwardToTarget, false, true), false},
+		{"SELECT routed keyspace", mockQueryFrame(t, "SELECT * FROM tb"), "ks_legacy", NewGenericRequestInfo(forwardToOrigin, false, true), false},
+		{"SELECT not routed", mockQueryFrame(t, "SELECT * FROM ks.users"), "", NewGenericRequestInfo(forwardToOrigin, true, true), false},
+		{"INSERT routed table", mockQueryFrame(t, insertEvent), "", NewWriteRequestInfo(forwardToTarget), false},
+		{"INSERT not routed", mockQueryFrame(t, insertUser), "", NewWriteRequestInfo(forwardToBoth), false},
+		{"PREPARE routed table", mockPrepareFrame(t, insertEvent), "", NewPrepareRequestInfo(NewWriteRequestInfo(forwardToTarget), []*term{}, false, insertEvent, ""), false},
+		{"BATCH query string routed", mockQueryFrame(t, "BEGIN BATCH "+insertEvent+"; UPDATE ks.events SET b = 3 WHERE a = 2; APPLY BATCH"), "", NewWriteRequestInfo(forwardToTarget), false},
+		{"BATCH query string routed and not routed", mockQueryFrame(t, "BEGIN BATCH "+insertEvent+"; "+insertUser+"; APPLY BATCH"), "",
+			"Request rejected by the proxy: the statements of this BATCH write to routed and non routed tables, send them as separate statements or in a BATCH request", false},
+		{"BATCH query string routed to both clusters", mockQueryFrame(t, "BEGIN BATCH "+insertEvent+"; "+insertLegacy+"; APPLY BATCH"), "ks_legacy",
+			"Request rejected by the proxy: the statements of this BATCH write to tables routed to ORIGIN and TARGET, send them as separate statements or in a BATCH request", false},
+		{"BATCH routed table", mockBatchWithChildren(t, []*message.BatchChild{{QueryOrId: insertEvent}, {QueryOrId: insertEvent}}), "",
+			NewBatchRequestInfo(map[int]PreparedData{}, map[int]forwardDecision{0: forwardToTarget, 1: forwardToTarget}, 2), false},
+		{"BATCH split", mockBatchWithChildren(t, []*message.BatchChild{{QueryOrId: insertEvent}, {QueryOrId: insertUser}, {QueryOrId: insertLegacy}}), "ks_legacy",
+			NewBatchRequestInfo(map[int]PreparedData{}, map[int]forwardDecision{0: forwardToTarget, 2: forwardToOrigin}, 3), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeUuidGenerator, err := GetDefaultTimeUuidGenerator()
+			require.Nil(t, err)
+			actual, err := buildRequestInfo(&frameDecodeContext{frame: tt.f, logger: log.NewEntry(log.StandardLogger())}, []*statementReplacedTerms{}, psCache, mh, tt.keyspace,
+				common.ClusterTypeOrigin, false, true, false, false, common.LwtPolicyBoth, common.CounterWritePolicyBoth,
+				common.DdlPolicyBoth, tableRouting, nil, timeUuidGenerator)
+			if err != nil {
+				require.Equal(t, tt.expected, err.Error())
+				return
+			}
+			require.Equal(t, tt.expected, actual)
+			if batchRequestInfo, ok := actual.(*BatchRequestInfo); ok {
+				require.Equal(t, tt.split, batchRequestInfo.IsSplit())
+			}
+		})
+	}
+}
+
 func mockPrepareFrame(t *testing.T, query string) *frame.RawFrame {
 	prepareMsg := &message.Prepare{
 		Query:    query,
@@ -181,6 +410,61 @@ func mockExecuteFrame(t *testing.T, preparedId string) *frame.RawFrame {
 	return mockFrame(t, executeMsg, primitive.ProtocolVersion4)
 }
 
+func TestBatchRequestInfoSplit(t *testing.T) {
+	originCacheEntry := &preparedDataImpl{
+		prepareRequestInfo: NewPrepareRequestInfo(NewGenericRequestInfo(forwardToOrigin, false, false), nil, false, "", ""),
+	}
+	targetCacheEntry := &preparedDataImpl{
+		prepareRequestInfo: NewPrepareRequestInfo(NewGenericRequestInfo(forwardToTarget, false, false), nil, false, "", ""),
+	}
+	bothCacheEntry := &preparedDataImpl{
+		prepareRequestInfo: NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, false), nil, false, "", ""),
+	}
+
+	tests := []struct {
+		name                  string
+		preparedDataByStmtIdx map[int]PreparedData
+		numberOfStatements    int
+		forwardDecision       forwardDecision
+		split                 bool
+		originStmts           []int
+		targetStmts           []int
+	}{
+		{"no prepared statements", map[int]PreparedData{}, 2, forwardToBoth, false, []int{0, 1}, []int{0, 1}},
+		{"prepared both", map[int]PreparedData{0: bothCacheEntry}, 2, forwardToBoth, false, []int{0, 1}, []int{0, 1}},
+		{"prepared origin only", map[int]PreparedData{0: originCacheEntry, 1: originCacheEntry}, 2, forwardToOrigin, false, []int{0, 1}, []int{0, 1}},
+		{"prepared target only", map[int]PreparedData{0: targetCacheEntry}, 1, forwardToTarget, false, []int{0}, []int{0}},
+		{"origin and both", map[int]PreparedData{0: originCacheEntry, 1: bothCacheEntry}, 2, forwardToBoth, true, []int{0, 1}, []int{1}},
+		{"origin, target and simple", map[int]PreparedData{0: originCacheEntry, 2: targetCacheEntry}, 3, forwardToBoth, true, []int{0, 1}, []int{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestInfo := NewBatchRequestInfo(tt.preparedDataByStmtIdx, nil, tt.numberOfStatements)
+			require.Equal(t, tt.forwardDecision, requestInfo.GetForwardDecision())
+			require.Equal(t, tt.split, requestInfo.IsSplit())
+
+			children := make([]*message.BatchChild, 0, tt.numberOfStatements)
+			for i := 0; i < tt.numberOfStatements; i++ {
+				children = append(children, &message.BatchChild{QueryOrId: fmt.Sprintf("%v", i)})
+			}
+			if !tt.split {
+				return
+			}
+
+			originChildren := filterBatchChildren(children, requestInfo.GetForwardDecisionByStmtIdx(), forwardToOrigin)
+			require.Equal(t, len(tt.originStmts), len(originChildren))
+			for i, stmtIdx := range tt.originStmts {
+				require.Same(t, children[stmtIdx], originChildren[i])
+			}
+			targetChildren := filterBatchChildren(children, requestInfo.GetForwardDecisionByStmtIdx(), forwardToTarget)
+			require.Equal(t, len(tt.targetStmts), len(targetChildren))
+			for i, stmtIdx := range tt.targetStmts {
+				require.Same(t, children[stmtIdx], targetChildren[i])
+			}
+		})
+	}
+}
+
 func mockBatch(t *testing.T, query interface{}) *frame.RawFrame {
 	batchMsg := &message.Batch{Children: []*message.BatchChild{{QueryOrId: query}}}
 	return mockFrame(t, batchMsg, primitive.ProtocolVersion4)
@@ -214,20 +498,64 @@ func newFakeMetricHandler() *metrics.MetricHandler {
 
 func newFakeProxyMetrics() *metrics.ProxyMetrics {
 	return &metrics.ProxyMetrics{
-		FailedReadsOrigin:        newFakeCounter(),
-		FailedReadsTarget:        newFakeCounter(),
-		FailedWritesOnOrigin:     newFakeCounter(),
-		FailedWritesOnTarget:     newFakeCounter(),
-		FailedWritesOnBoth:       newFakeCounter(),
-		PSCacheSize:              newFakeGaugeFunc(),
-		PSCacheMissCount:         newFakeCounter(),
-		ProxyReadsOriginDuration: newFakeHistogram(),
-		ProxyReadsTargetDuration: newFakeHistogram(),
-		ProxyWritesDuration:      newFakeHistogram(),
-		InFlightReadsOrigin:      newFakeGauge(),
-		InFlightReadsTarget:      newFakeGauge(),
-		InFlightWrites:           newFakeGauge(),
-		OpenClientConnections:    newFakeGaugeFunc(),
+		FailedReadsOrigin:            newFakeCounter(),
+		FailedReadsTarget:            newFakeCounter(),
+		FailedWritesOnOrigin:         newFakeCounter(),
+		FailedWritesOnTarget:         newFakeCounter(),
+		FailedWritesOnBoth:           newFakeCounter(),
+		PSCacheSize:                  newFakeGaugeFunc(),
+		PSCacheMissCount:             newFakeCounter(),
+		PSCacheHitCount:              newFakeCounter(),
+		PSCacheEvictionCount:         newFakeCounter(),
+		PSCacheRePrepareOrigin:       newFakeCounter(),
+		PSCacheRePrepareTarget:       newFakeCounter(),
+		PSCacheRePrepareFailedOrigin: newFakeCounter(),
+		PSCacheRePrepareFailedTarget: newFakeCounter(),
+		RetriesOrigin:                newFakeCounter(),
+		RetriesTarget:                newFakeCounter(),
+		SpeculativeReadWins:          newFakeCounter(),
+		SpeculativeReadLosses:        newFakeCounter(),
+		ProxyReadsOriginDuration:     newFakeHistogram(),
+		ProxyReadsTargetDuration:     newFakeHistogram(),
+		ProxyWritesDuration:          newFakeHistogram(),
+		InFlightReadsOrigin:          newFakeGauge(),
+		InFlightReadsTarget:          newFakeGauge(),
+		InFlightWrites:               newFakeGauge(),
+		OpenClientConnections:        newFakeGaugeFunc(),
+		ClientBytesReceived:          newFakeCounter(),
+		ClientBytesSent:              newFakeCounter(),
+		IdleClientConnectionsClosed:  newFakeCounter(),
+		LwtRequestCount:              newFakeCounter(),
+		LwtAppliedMismatchCount:      newFakeCounter(),
+		CounterWriteCount:            newFakeCounter(),
+
+		RequestConnectionFailoversOrigin:       newFakeCounter(),
+		RequestConnectionFailoversTarget:       newFakeCounter(),
+		RequestConnectionFailoversFailedOrigin: newFakeCounter(),
+		RequestConnectionFailoversFailedTarget: newFakeCounter(),
+
+		DatacenterFailoverActiveOrigin: newFakeGaugeFunc(),
+		DatacenterFailoverActiveTarget: newFakeGaugeFunc(),
+
+		BufferPoolHits:   newFakeCounter(),
+		BufferPoolMisses: newFakeCounter(),
+
+		OversizedRequestFrames:  newFakeCounter(),
+		OversizedResponseFrames: newFakeCounter(),
+
+		DnsResolutionFailuresOrigin: newFakeCounter(),
+		DnsResolutionFailuresTarget: newFakeCounter(),
+
+		TargetCircuitBreakerSkippedWrites: newFakeCounter(),
+		TargetCircuitBreakerOpen:          newFakeGaugeFunc(),
+
+		ShadowWrites:        newFakeCounter(),
+		ShadowWritesSkipped: newFakeCounter(),
+
+		RequestDeadlinesExceeded: newFakeCounter(),
+
+		ConsistencyLevelOverridesOrigin: newFakeCounter(),
+		ConsistencyLevelOverridesTarget: newFakeCounter(),
 	}
 }
 
@@ -278,3 +606,47 @@ func (recv *fakeMetric) WithLabels(map[string]string) metrics.Metric {
 func newFakeMetric() metrics.Metric {
 	return &fakeMetric{}
 }
+
+func TestInspectFrameExecuteInterceptedSystemQuery(t *testing.T) {
+	// the proxy and the cluster computed the same prepared id for "SELECT * FROM system.local"
+	interceptedCacheEntry := &preparedDataImpl{
+		originPreparedId: []byte("SYSTEM_LOCAL"),
+		targetPreparedId: []byte("SYSTEM_LOCAL"),
+		prepareRequestInfo: NewPrepareRequestInfo(
+			NewInterceptedRequestInfo(local, newStarSelectClause()), nil, false, "SELECT * FROM system.local", ""),
+	}
+	clusterCacheEntry := &preparedDataImpl{
+		originPreparedId:   []byte("SYSTEM_LOCAL"),
+		targetPreparedId:   []byte("SYSTEM_LOCAL"),
+		prepareRequestInfo: NewPrepareRequestInfo(NewGenericRequestInfo(forwardToOrigin, false, false), nil, false, "SELECT * FROM system.local", ""),
+	}
+	mh := newFakeMetricHandler()
+	timeUuidGenerator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+	inspect := func(psCache *PreparedStatementCache, virtualizationEnabled bool) (RequestInfo, error) {
+		return buildRequestInfo(&frameDecodeContext{frame: mockExecuteFrame(t, "SYSTEM_LOCAL"), logger: log.NewEntry(log.StandardLogger())}, []*statementReplacedTerms{},
+			psCache, mh, "", common.ClusterTypeOrigin, false, virtualizationEnabled, false, false, common.LwtPolicyBoth,
+			common.CounterWritePolicyBoth, common.DdlPolicyBoth, nil, nil, timeUuidGenerator)
+	}
+
+	psCache := NewPreparedStatementCache(5000, log.NewEntry(log.StandardLogger()))
+	psCache.interceptedCache["SYSTEM_LOCAL"] = interceptedCacheEntry
+
+	actual, err := inspect(psCache, true)
+	require.Nil(t, err)
+	require.Equal(t, NewExecuteRequestInfo(interceptedCacheEntry), actual)
+
+	// a connection whose system queries are not intercepted has to prepare the statement on the cluster
+	_, err = inspect(psCache, false)
+	require.IsType(t, &UnpreparedExecuteError{}, err)
+
+	psCache.cache["SYSTEM_LOCAL"] = clusterCacheEntry
+
+	actual, err = inspect(psCache, true)
+	require.Nil(t, err)
+	require.Equal(t, NewExecuteRequestInfo(interceptedCacheEntry), actual)
+
+	actual, err = inspect(psCache, false)
+	require.Nil(t, err)
+	require.Equal(t, NewExecuteRequestInfo(clusterCacheEntry), actual)
+}
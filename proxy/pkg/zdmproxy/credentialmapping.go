@@ -0,0 +1,57 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/secrets"
+)
+
+// CredentialMapper authenticates clients against the configured credential mappings and returns the credentials
+// that the proxy must use to connect to ORIGIN and TARGET on behalf of each client.
+//
+// Mapping values can refer to secrets, they are resolved with secretStore every time they are used.
+type CredentialMapper struct {
+	mappings    map[string]*common.CredentialMapping
+	secretStore *secrets.Store
+}
+
+func NewCredentialMapper(mappings []*common.CredentialMapping, secretStore *secrets.Store) *CredentialMapper {
+	mappingsByUsername := make(map[string]*common.CredentialMapping, len(mappings))
+	for _, mapping := range mappings {
+		mappingsByUsername[mapping.ClientUsername] = mapping
+	}
+	return &CredentialMapper{mappings: mappingsByUsername, secretStore: secretStore}
+}
+
+// Authenticate returns the mapping that matches the provided client credentials or nil if the credentials are invalid.
+func (recv *CredentialMapper) Authenticate(clientCreds *AuthCredentials) *common.CredentialMapping {
+	if clientCreds == nil {
+		return nil
+	}
+	mapping, ok := recv.mappings[clientCreds.Username]
+	if !ok {
+		return nil
+	}
+	expected := &AuthCredentials{
+		Username: mapping.ClientUsername,
+		Password: recv.secretStore.GetString(mapping.ClientPassword),
+	}
+	if !expected.Matches(clientCreds) {
+		return nil
+	}
+	return mapping
+}
+
+// GetClusterCredentials returns the credentials of the given cluster for a client that was authenticated with mapping.
+func (recv *CredentialMapper) GetClusterCredentials(
+	mapping *common.CredentialMapping, clusterType common.ClusterType) *AuthCredentials {
+	if clusterType == common.ClusterTypeTarget {
+		return &AuthCredentials{
+			Username: recv.secretStore.GetString(mapping.TargetUsername),
+			Password: recv.secretStore.GetString(mapping.TargetPassword),
+		}
+	}
+	return &AuthCredentials{
+		Username: recv.secretStore.GetString(mapping.OriginUsername),
+		Password: recv.secretStore.GetString(mapping.OriginPassword),
+	}
+}
@@ -0,0 +1,35 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/secrets"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCredentialMapper(t *testing.T) {
+	mapper := NewCredentialMapper([]*common.CredentialMapping{
+		{
+			ClientUsername: "app1", ClientPassword: "secret1",
+			OriginUsername: "origin_app1", OriginPassword: "origin_secret1",
+			TargetUsername: "token", TargetPassword: "AstraCS:app1",
+		},
+		{
+			ClientUsername: "app2", ClientPassword: "secret2",
+			OriginUsername: "origin_app2", OriginPassword: "origin_secret2",
+			TargetUsername: "token", TargetPassword: "AstraCS:app2",
+		},
+	}, secrets.NewStore(nil, log.NewEntry(log.StandardLogger())))
+
+	require.Nil(t, mapper.Authenticate(nil))
+	require.Nil(t, mapper.Authenticate(&AuthCredentials{Username: "app1", Password: "secret2"}))
+	require.Nil(t, mapper.Authenticate(&AuthCredentials{Username: "app3", Password: "secret1"}))
+
+	mapping := mapper.Authenticate(&AuthCredentials{Username: "app2", Password: "secret2"})
+	require.NotNil(t, mapping)
+	require.Equal(t, &AuthCredentials{Username: "origin_app2", Password: "origin_secret2"},
+		mapper.GetClusterCredentials(mapping, common.ClusterTypeOrigin))
+	require.Equal(t, &AuthCredentials{Username: "token", Password: "AstraCS:app2"},
+		mapper.GetClusterCredentials(mapping, common.ClusterTypeTarget))
+}
@@ -0,0 +1,140 @@
+package zdmproxy
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const schemaAgreementPollInterval = 200 * time.Millisecond
+
+var ddlKeywords = map[string]bool{
+	"CREATE":   true,
+	"ALTER":    true,
+	"DROP":     true,
+	"TRUNCATE": true,
+}
+
+// isDdlQuery returns true if the first keyword of the query, after whitespace and comments, is a schema change.
+func isDdlQuery(query string) bool {
+	keywords := leadingKeywords(query, 1)
+	return len(keywords) == 1 && ddlKeywords[keywords[0]]
+}
+
+// leadingKeywords returns (in upper case) up to n keywords at the start of the query, skipping whitespace and
+// comments.
+func leadingKeywords(query string, n int) []string {
+	keywords := make([]string, 0, n)
+	for len(keywords) < n {
+		query = strings.TrimLeftFunc(query, unicode.IsSpace)
+		switch {
+		case strings.HasPrefix(query, "--"), strings.HasPrefix(query, "//"):
+			end := strings.IndexByte(query, '\n')
+			if end < 0 {
+				return keywords
+			}
+			query = query[end+1:]
+		case strings.HasPrefix(query, "/*"):
+			end := strings.Index(query, "*/")
+			if end < 0 {
+				return keywords
+			}
+			query = query[end+2:]
+		default:
+			end := strings.IndexFunc(query, func(r rune) bool {
+				return !unicode.IsLetter(r)
+			})
+			if end < 0 {
+				end = len(query)
+			}
+			if end == 0 {
+				return keywords
+			}
+			keywords = append(keywords, strings.ToUpper(query[:end]))
+			query = query[end:]
+		}
+	}
+	return keywords
+}
+
+// getSchemaChangeRequestInfo applies the configured DDL policy to a schema change.
+//
+// With ORIGIN_ONLY, the schema change is not tracked in the metrics because it would be counted as a read.
+func getSchemaChangeRequestInfo(header *frame.Header, ddlPolicy common.DdlPolicy) (RequestInfo, error) {
+	switch ddlPolicy {
+	case common.DdlPolicyReject:
+		return nil, &RejectedRequestError{
+			Header: header,
+			Reason: "schema changes are not allowed during the migration"}
+	case common.DdlPolicyOriginOnly:
+		return NewSchemaChangeRequestInfo(forwardToOrigin, false), nil
+	default:
+		return NewSchemaChangeRequestInfo(forwardToBoth, true), nil
+	}
+}
+
+// isSchemaChangeRequest returns true if the request (QUERY or EXECUTE) is a DDL statement.
+func isSchemaChangeRequest(requestInfo RequestInfo) bool {
+	requestInfo = unwrapRequestInfo(requestInfo)
+	if executeRequestInfo, ok := requestInfo.(*ExecuteRequestInfo); ok {
+		requestInfo = executeRequestInfo.GetPreparedData().GetPrepareRequestInfo().GetBaseRequestInfo()
+	}
+	genericRequestInfo, ok := requestInfo.(*GenericRequestInfo)
+	return ok && genericRequestInfo.schemaChange
+}
+
+func isSchemaChangeResult(response *frame.RawFrame) bool {
+	if response == nil || response.Header.OpCode != primitive.OpCodeResult {
+		return false
+	}
+	decodedFrame, err := defaultCodec.ConvertFromRawFrame(response)
+	if err != nil {
+		return false
+	}
+	_, ok := decodedFrame.Body.Message.(*message.SchemaChangeResult)
+	return ok
+}
+
+// shouldWaitForSchemaAgreement returns true if the response of the request is a SCHEMA_CHANGE result that should only
+// be returned to the client once the clusters agree on the schema version (ZDM_DDL_SCHEMA_AGREEMENT_TIMEOUT_MS).
+func (ch *ClientHandler) shouldWaitForSchemaAgreement(reqCtx *requestContextImpl, response *frame.RawFrame) bool {
+	return ch.conf.DdlSchemaAgreementTimeoutMs > 0 && isSchemaChangeRequest(reqCtx.requestInfo) &&
+		isSchemaChangeResult(response)
+}
+
+// waitForSchemaAgreement polls the schema versions of the clusters to which the schema change was sent until all nodes
+// of each cluster agree or until ZDM_DDL_SCHEMA_AGREEMENT_TIMEOUT_MS. Like the drivers, the proxy returns the
+// response anyway if the clusters don't agree in time.
+func (ch *ClientHandler) waitForSchemaAgreement(reqCtx *requestContextImpl) {
+	controlConns := []*ControlConn{ch.originControlConn}
+	if reqCtx.requestInfo.GetForwardDecision() == forwardToBoth {
+		controlConns = append(controlConns, ch.targetControlConn)
+	}
+
+	timeout := time.Duration(ch.conf.DdlSchemaAgreementTimeoutMs) * time.Millisecond
+	ctx, cancelFn := context.WithTimeout(ch.clientHandlerContext, timeout)
+	defer cancelFn()
+
+	for _, controlConn := range controlConns {
+		clusterType := controlConn.connConfig.GetClusterType()
+		for {
+			agreement, err := controlConn.CheckSchemaAgreement(ctx)
+			if err == nil && agreement {
+				break
+			}
+			if err != nil {
+				reqCtx.logger.Debugf("Could not check schema agreement on %v: %v", clusterType, err)
+			}
+			if ok, _ := sleepWithContext(schemaAgreementPollInterval, ctx, nil); !ok {
+				reqCtx.logger.Warnf("Schema agreement was not reached on %v after %v, returning the response of the "+
+					"schema change anyway.", clusterType, timeout)
+				return
+			}
+		}
+	}
+}
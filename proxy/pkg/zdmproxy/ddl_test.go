@@ -0,0 +1,42 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestIsDdlQuery(t *testing.T) {
+	require.True(t, isDdlQuery("CREATE TABLE ks.t (a int PRIMARY KEY)"))
+	require.True(t, isDdlQuery("  drop keyspace ks"))
+	require.True(t, isDdlQuery("-- comment\nALTER TABLE ks.t ADD b int"))
+	require.True(t, isDdlQuery("/* a */ // b\n TRUNCATE ks.t"))
+	require.False(t, isDdlQuery("SELECT * FROM ks.create"))
+	require.False(t, isDdlQuery("/* CREATE */ INSERT INTO ks.t (a) VALUES (1)"))
+	require.False(t, isDdlQuery("/* unterminated"))
+	require.False(t, isDdlQuery(""))
+}
+
+func TestIsSchemaChangeResult(t *testing.T) {
+	newRawFrame := func(msg message.Message) *frame.RawFrame {
+		rawFrame, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 1, msg))
+		require.Nil(t, err)
+		return rawFrame
+	}
+
+	require.True(t, isSchemaChangeResult(newRawFrame(&message.SchemaChangeResult{
+		ChangeType: primitive.SchemaChangeTypeCreated,
+		Target:     primitive.SchemaChangeTargetTable,
+		Keyspace:   "ks",
+		Object:     "tb",
+	})))
+	require.False(t, isSchemaChangeResult(newRawFrame(&message.VoidResult{})))
+	require.False(t, isSchemaChangeResult(newRawFrame(&message.Ready{})))
+
+	require.True(t, isSchemaChangeRequest(NewSchemaChangeRequestInfo(forwardToBoth, true)))
+	require.True(t, isSchemaChangeRequest(&targetSkippedRequestInfo{NewSchemaChangeRequestInfo(forwardToBoth, true)}))
+	require.True(t, isSchemaChangeRequest(&shadowedRequestInfo{NewSchemaChangeRequestInfo(forwardToBoth, true)}))
+	require.False(t, isSchemaChangeRequest(NewGenericRequestInfo(forwardToBoth, false, true)))
+}
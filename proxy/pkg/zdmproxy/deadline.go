@@ -0,0 +1,284 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"sync"
+	"time"
+)
+
+// setRequestDeadline lowers the timeout of the request to the budget that the client set with
+// DeadlineCustomPayloadKey and returns the new timeout. Continuous paging requests are not supported because their
+// timeout applies to each page.
+func (ch *ClientHandler) setRequestDeadline(
+	reqCtx *requestContextImpl, frameContext *frameDecodeContext, requestTimeout time.Duration) time.Duration {
+
+	if ch.abandonedRequests == nil || reqCtx.continuousPaging != nil {
+		return requestTimeout
+	}
+	budget := getRequestDeadlineBudget(frameContext)
+	if budget <= 0 {
+		return requestTimeout
+	}
+
+	reqCtx.deadline = reqCtx.startTime.Add(budget)
+	reqCtx.requestTimeout = requestTimeout
+	remaining := time.Until(reqCtx.deadline)
+	if remaining < requestTimeout {
+		return remaining
+	}
+	return requestTimeout
+}
+
+// getDeadlineResponse returns the best available response of a request whose deadline elapsed: the response of the
+// cluster that did respond if the request was sent to both clusters, a READ_TIMEOUT or WRITE_TIMEOUT error if no
+// cluster responded. The stream ids of the calls that are still in flight are kept by abandonedRequests.
+//
+// Returns false if the request didn't time out or doesn't have a deadline, the timeout is then handled as usual
+// (i.e. the client doesn't receive a response).
+func (ch *ClientHandler) getDeadlineResponse(reqCtx *requestContextImpl) (*frame.RawFrame, common.ClusterType, bool) {
+	if reqCtx.deadline.IsZero() || reqCtx.state != RequestTimedOut {
+		return nil, common.ClusterTypeNone, false
+	}
+
+	ch.metricHandler.GetProxyMetrics().RequestDeadlinesExceeded.Add(1)
+	abandonedTimeout := time.Until(reqCtx.startTime.Add(reqCtx.requestTimeout))
+	streamId := reqCtx.request.Header.StreamId
+	fwdDecision := reqCtx.requestInfo.GetForwardDecision()
+	if (fwdDecision == forwardToBoth || fwdDecision == forwardToOrigin) && reqCtx.originResponse == nil {
+		ch.abandonedRequests.add(ch.originCassandraConnector, streamId, abandonedTimeout)
+	}
+	if (fwdDecision == forwardToBoth || fwdDecision == forwardToTarget) && reqCtx.targetResponse == nil {
+		ch.abandonedRequests.add(ch.targetCassandraConnector, streamId, abandonedTimeout)
+	}
+
+	budget := reqCtx.deadline.Sub(reqCtx.startTime)
+	if reqCtx.originResponse != nil {
+		reqCtx.logger.Debugf("Deadline of %v exceeded, returning the response of %v without waiting for %v.",
+			budget, common.ClusterTypeOrigin, common.ClusterTypeTarget)
+		return reqCtx.originResponse, common.ClusterTypeOrigin, true
+	}
+	if reqCtx.targetResponse != nil {
+		reqCtx.logger.Debugf("Deadline of %v exceeded, returning the response of %v without waiting for %v.",
+			budget, common.ClusterTypeTarget, common.ClusterTypeOrigin)
+		return reqCtx.targetResponse, common.ClusterTypeTarget, true
+	}
+
+	response, err := newDeadlineExceededResponse(reqCtx.request, reqCtx.requestInfo, budget)
+	if err != nil {
+		reqCtx.logger.Errorf("Could not create the response of a request whose deadline was exceeded: %v", err)
+		return nil, common.ClusterTypeNone, false
+	}
+	reqCtx.logger.Debugf("Deadline of %v exceeded before any cluster responded.", budget)
+	return response, common.ClusterTypeNone, true
+}
+
+// newDeadlineExceededResponse returns a READ_TIMEOUT error for reads and a WRITE_TIMEOUT error for writes. The
+// received and block for values make sure that the default retry policies of the drivers don't retry the request.
+func newDeadlineExceededResponse(
+	request *frame.RawFrame, requestInfo RequestInfo, budget time.Duration) (*frame.RawFrame, error) {
+
+	decodedRequest, err := defaultCodec.ConvertFromRawFrame(request)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode request: %w", err)
+	}
+
+	consistency := primitive.ConsistencyLevelOne
+	writeType := primitive.WriteTypeSimple
+	switch msg := decodedRequest.Body.Message.(type) {
+	case *message.Query:
+		if msg.Options != nil {
+			consistency = msg.Options.Consistency
+		}
+	case *message.Execute:
+		if msg.Options != nil {
+			consistency = msg.Options.Consistency
+		}
+	case *message.Batch:
+		consistency = msg.Consistency
+		writeType = primitive.WriteTypeBatch
+	}
+
+	errorMessage := fmt.Sprintf("Operation timed out - the deadline of %v set by the client was exceeded", budget)
+	var msg message.Error
+	if isRead(requestInfo) {
+		msg = &message.ReadTimeout{
+			ErrorMessage: errorMessage,
+			Consistency:  consistency,
+			Received:     0,
+			BlockFor:     1,
+		}
+	} else {
+		msg = &message.WriteTimeout{
+			ErrorMessage: errorMessage,
+			Consistency:  consistency,
+			Received:     0,
+			BlockFor:     1,
+			WriteType:    writeType,
+		}
+	}
+	response, err := defaultCodec.ConvertToRawFrame(
+		frame.NewFrame(request.Header.Version, request.Header.StreamId, msg))
+	if err != nil {
+		return nil, fmt.Errorf("could not convert %v to raw frame: %w", msg, err)
+	}
+	return response, nil
+}
+
+// sendRequestToCluster sends the request unless it has to be held because the connector still has an abandoned call
+// with the same stream id.
+func (ch *ClientHandler) sendRequestToCluster(connector *ClusterConnector, request *frame.RawFrame) {
+	if ch.abandonedRequests != nil && ch.abandonedRequests.hold(connector, request) {
+		ch.logger.Tracef("Holding request for stream id %d until %v responds to the abandoned request with the same "+
+			"stream id.", request.Header.StreamId, connector.connectorType)
+		return
+	}
+	connector.sendRequestToCluster(request)
+}
+
+// abandonedRequests keeps the stream ids of the calls that were still in flight on a cluster connection when the
+// deadline of their request elapsed. The proxy forwards the requests with the stream ids of the client, which can
+// reuse them as soon as it receives the response of the request. A request of the client that reuses the stream id
+// of an abandoned call is held until the late response is received (and discarded) or until the abandoned call
+// times out, otherwise the late response would be returned to the client as the response of the new request.
+type abandonedRequests struct {
+	entries map[abandonedRequestKey]*abandonedRequest
+	lock    *sync.Mutex
+
+	// in flight requests of the client handler, the cluster connectors are closed once it is done
+	requestWaitGroup *sync.WaitGroup
+}
+
+type abandonedRequestKey struct {
+	connectorType ClusterConnectorType
+	streamId      int16
+}
+
+type abandonedRequest struct {
+	connector  *ClusterConnector
+	held       []*heldRequest
+	timer      *time.Timer
+	generation int
+}
+
+type heldRequest struct {
+	request *frame.RawFrame
+
+	// set if the deadline of the request elapsed while it was held, its response must then be discarded as well
+	abandoned bool
+	timeout   time.Duration
+}
+
+func newAbandonedRequests(requestWaitGroup *sync.WaitGroup) *abandonedRequests {
+	return &abandonedRequests{
+		entries:          make(map[abandonedRequestKey]*abandonedRequest),
+		lock:             &sync.Mutex{},
+		requestWaitGroup: requestWaitGroup,
+	}
+}
+
+// add records that the response of the call with the provided stream id is not awaited anymore. The stream id is
+// released when the response is received or after the timeout.
+func (recv *abandonedRequests) add(connector *ClusterConnector, streamId int16, timeout time.Duration) {
+	key := abandonedRequestKey{connectorType: connector.connectorType, streamId: streamId}
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	if entry, ok := recv.entries[key]; ok {
+		// the request is the last held request, it reused the stream id of an earlier abandoned call
+		if len(entry.held) > 0 {
+			last := entry.held[len(entry.held)-1]
+			last.abandoned = true
+			last.timeout = timeout
+		}
+		return
+	}
+
+	entry := &abandonedRequest{connector: connector}
+	recv.requestWaitGroup.Add(1)
+	recv.startTimer(key, entry, timeout)
+	recv.entries[key] = entry
+}
+
+func (recv *abandonedRequests) startTimer(key abandonedRequestKey, entry *abandonedRequest, timeout time.Duration) {
+	entry.generation++
+	generation := entry.generation
+	entry.timer = time.AfterFunc(timeout, func() {
+		recv.lock.Lock()
+		if recv.entries[key] != entry || entry.generation != generation {
+			// already released
+			recv.lock.Unlock()
+			return
+		}
+		recv.release(key, entry)
+	})
+}
+
+// hold returns true if the request reuses the stream id of an abandoned call of the connector, the request is then
+// sent when the stream id is released.
+func (recv *abandonedRequests) hold(connector *ClusterConnector, request *frame.RawFrame) bool {
+	key := abandonedRequestKey{connectorType: connector.connectorType, streamId: request.Header.StreamId}
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	entry, ok := recv.entries[key]
+	if !ok {
+		return false
+	}
+	entry.held = append(entry.held, &heldRequest{request: request})
+	return true
+}
+
+// releaseResponse returns true if the response is the late response of an abandoned call, it must then be discarded.
+func (recv *abandonedRequests) releaseResponse(connectorType ClusterConnectorType, response *frame.RawFrame) bool {
+	key := abandonedRequestKey{connectorType: connectorType, streamId: response.Header.StreamId}
+	recv.lock.Lock()
+	entry, ok := recv.entries[key]
+	if !ok {
+		recv.lock.Unlock()
+		return false
+	}
+	recv.release(key, entry)
+	return true
+}
+
+// release sends the first held request, the stream id stays reserved if that request was abandoned as well. The
+// client can't send another request with the same stream id until it receives the response of the held request so
+// only the last held request can still be awaited. Must be called with the lock, which is released.
+func (recv *abandonedRequests) release(key abandonedRequestKey, entry *abandonedRequest) {
+	entry.timer.Stop()
+	var next *heldRequest
+	if len(entry.held) > 0 {
+		next = entry.held[0]
+		entry.held = entry.held[1:]
+	}
+	done := next == nil || !next.abandoned
+	if done {
+		delete(recv.entries, key)
+	} else {
+		recv.startTimer(key, entry, next.timeout)
+	}
+	recv.lock.Unlock()
+
+	if next != nil {
+		entry.connector.sendRequestToCluster(next.request)
+	}
+	if done {
+		recv.requestWaitGroup.Done()
+	}
+}
+
+// clear discards the held requests without sending them, it is called when the client connection is closed.
+func (recv *abandonedRequests) clear() {
+	recv.lock.Lock()
+	entries := recv.entries
+	recv.entries = make(map[abandonedRequestKey]*abandonedRequest)
+	recv.lock.Unlock()
+
+	for _, entry := range entries {
+		entry.timer.Stop()
+		recv.requestWaitGroup.Done()
+	}
+}
@@ -0,0 +1,129 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewDeadlineExceededResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		msg         message.Message
+		requestInfo RequestInfo
+		expected    message.Error
+	}{
+		{"read", &message.Query{Query: "SELECT * FROM ks.t", Options: &message.QueryOptions{
+			Consistency: primitive.ConsistencyLevelLocalQuorum}}, NewGenericRequestInfo(forwardToOrigin, true, true),
+			&message.ReadTimeout{Consistency: primitive.ConsistencyLevelLocalQuorum, BlockFor: 1}},
+		{"write", &message.Query{Query: "INSERT INTO ks.t (a) VALUES (1)", Options: &message.QueryOptions{
+			Consistency: primitive.ConsistencyLevelLocalOne}}, NewGenericRequestInfo(forwardToBoth, false, true),
+			&message.WriteTimeout{Consistency: primitive.ConsistencyLevelLocalOne, BlockFor: 1,
+				WriteType: primitive.WriteTypeSimple}},
+		{"batch", &message.Batch{Consistency: primitive.ConsistencyLevelQuorum, Children: []*message.BatchChild{
+			{QueryOrId: "INSERT INTO ks.t (a) VALUES (1)"}}}, NewGenericRequestInfo(forwardToBoth, false, true),
+			&message.WriteTimeout{Consistency: primitive.ConsistencyLevelQuorum, BlockFor: 1,
+				WriteType: primitive.WriteTypeBatch}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 7, test.msg))
+			require.Nil(t, err)
+
+			response, err := newDeadlineExceededResponse(request, test.requestInfo, 50*time.Millisecond)
+			require.Nil(t, err)
+			require.Equal(t, int16(7), response.Header.StreamId)
+			decoded, err := defaultCodec.ConvertFromRawFrame(response)
+			require.Nil(t, err)
+
+			errorMessage := "Operation timed out - the deadline of 50ms set by the client was exceeded"
+			switch expected := test.expected.(type) {
+			case *message.ReadTimeout:
+				expected.ErrorMessage = errorMessage
+			case *message.WriteTimeout:
+				expected.ErrorMessage = errorMessage
+			}
+			require.Equal(t, test.expected, decoded.Body.Message)
+		})
+	}
+}
+
+func TestAbandonedRequests(t *testing.T) {
+	newConnector := func(connectorType ClusterConnectorType) *ClusterConnector {
+		clientConn, serverConn := net.Pipe()
+		t.Cleanup(func() {
+			clientConn.Close()
+			serverConn.Close()
+		})
+		versionTranslator := &atomic.Value{}
+		versionTranslator.Store((*protocolVersionTranslator)(nil))
+		return &ClusterConnector{
+			connectorType:     connectorType,
+			versionTranslator: versionTranslator,
+			writeCoalescer: &writeCoalescer{
+				connection: clientConn,
+				writeQueue: make(chan *frame.RawFrame, 10),
+				logger:     log.NewEntry(log.StandardLogger()),
+			},
+		}
+	}
+	newRequest := func(streamId int16) *frame.RawFrame {
+		request, err := defaultCodec.ConvertToRawFrame(
+			frame.NewFrame(primitive.ProtocolVersion4, streamId, &message.Query{Query: "SELECT * FROM ks.t"}))
+		require.Nil(t, err)
+		return request
+	}
+
+	origin := newConnector(ClusterConnectorTypeOrigin)
+	target := newConnector(ClusterConnectorTypeTarget)
+	wg := &sync.WaitGroup{}
+	abandoned := newAbandonedRequests(wg)
+
+	abandoned.add(origin, 1, time.Minute)
+	require.False(t, abandoned.hold(target, newRequest(1)))
+	require.False(t, abandoned.hold(origin, newRequest(2)))
+	require.True(t, abandoned.hold(origin, newRequest(1)))
+	require.Len(t, origin.writeCoalescer.writeQueue, 0)
+
+	// the late response is discarded and the held request is sent
+	require.False(t, abandoned.releaseResponse(ClusterConnectorTypeTarget, newRequest(1)))
+	require.True(t, abandoned.releaseResponse(ClusterConnectorTypeOrigin, newRequest(1)))
+	require.Len(t, origin.writeCoalescer.writeQueue, 1)
+	require.False(t, abandoned.releaseResponse(ClusterConnectorTypeOrigin, newRequest(1)))
+	require.False(t, abandoned.hold(origin, newRequest(1)))
+
+	// the stream id is released after the timeout if the cluster doesn't respond
+	abandoned.add(target, 3, 20*time.Millisecond)
+	require.True(t, abandoned.hold(target, newRequest(3)))
+	require.Eventually(t, func() bool {
+		return len(target.writeCoalescer.writeQueue) == 1
+	}, time.Second, 10*time.Millisecond)
+	require.False(t, abandoned.releaseResponse(ClusterConnectorTypeTarget, newRequest(3)))
+
+	// the response of a held request whose deadline elapsed as well is discarded after it is sent
+	abandoned.add(origin, 5, time.Minute)
+	require.True(t, abandoned.hold(origin, newRequest(5)))
+	abandoned.add(origin, 5, time.Minute)
+	require.True(t, abandoned.hold(origin, newRequest(5)))
+	require.True(t, abandoned.releaseResponse(ClusterConnectorTypeOrigin, newRequest(5)))
+	require.Len(t, origin.writeCoalescer.writeQueue, 2)
+	require.True(t, abandoned.releaseResponse(ClusterConnectorTypeOrigin, newRequest(5)))
+	require.Len(t, origin.writeCoalescer.writeQueue, 3)
+	require.False(t, abandoned.releaseResponse(ClusterConnectorTypeOrigin, newRequest(5)))
+
+	// the held requests are discarded when the client connection is closed
+	abandoned.add(target, 4, time.Minute)
+	require.True(t, abandoned.hold(target, newRequest(4)))
+	abandoned.clear()
+	require.False(t, abandoned.releaseResponse(ClusterConnectorTypeTarget, newRequest(4)))
+	require.Len(t, target.writeCoalescer.writeQueue, 1)
+	wg.Wait()
+}
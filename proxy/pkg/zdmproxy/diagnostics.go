@@ -0,0 +1,131 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// diagnostics keeps track of the open client connections so that their in-flight requests can be written to the
+// dumps of the debug endpoint (ZDM_DEBUG_ENDPOINT_ENABLED).
+type diagnostics struct {
+	clients     map[*ClientHandler]time.Time // client handler -> time at which the client connected
+	clientsLock *sync.Mutex
+}
+
+func newDiagnostics() *diagnostics {
+	return &diagnostics{
+		clients:     make(map[*ClientHandler]time.Time),
+		clientsLock: &sync.Mutex{},
+	}
+}
+
+func (recv *diagnostics) addClient(clientHandler *ClientHandler) {
+	recv.clientsLock.Lock()
+	defer recv.clientsLock.Unlock()
+	recv.clients[clientHandler] = time.Now()
+}
+
+func (recv *diagnostics) removeClient(clientHandler *ClientHandler) {
+	recv.clientsLock.Lock()
+	defer recv.clientsLock.Unlock()
+	delete(recv.clients, clientHandler)
+}
+
+// writeActiveRequests writes a line for each open client connection followed by a line for each of its in-flight
+// requests, the oldest connections first.
+func (recv *diagnostics) writeActiveRequests(w io.Writer) error {
+	type client struct {
+		handler     *ClientHandler
+		connectedAt time.Time
+	}
+
+	recv.clientsLock.Lock()
+	clients := make([]client, 0, len(recv.clients))
+	for clientHandler, connectedAt := range recv.clients {
+		clients = append(clients, client{handler: clientHandler, connectedAt: connectedAt})
+	}
+	recv.clientsLock.Unlock()
+
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].connectedAt.Before(clients[j].connectedAt)
+	})
+
+	now := time.Now()
+	if _, err := fmt.Fprintf(w, "Client connections: %d\n", len(clients)); err != nil {
+		return err
+	}
+	for _, c := range clients {
+		requests := c.handler.getInFlightRequests()
+		_, err := fmt.Fprintf(w, "client %v connection_id=%v connected_at=%v keyspace=%q origin=%v target=%v "+
+			"in_flight_requests=%d\n",
+			c.handler.clientConnector.connection.RemoteAddr(), c.handler.connectionId,
+			c.connectedAt.UTC().Format(time.RFC3339), c.handler.LoadCurrentKeyspace(),
+			c.handler.originCassandraConnector.connection.RemoteAddr(),
+			c.handler.targetCassandraConnector.connection.RemoteAddr(), len(requests))
+		if err != nil {
+			return err
+		}
+		for _, reqCtx := range requests {
+			if _, err = fmt.Fprintf(w, "  %v\n", reqCtx.describe(now)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// getInFlightRequests returns the requests that are waiting for the responses of the clusters, the oldest first.
+func (ch *ClientHandler) getInFlightRequests() []*requestContextImpl {
+	requests := make([]*requestContextImpl, 0)
+	ch.requestContextHolders.Range(func(_, value interface{}) bool {
+		if reqCtx, ok := value.(*requestContextHolder).Get().(*requestContextImpl); ok && reqCtx.IsPending() {
+			requests = append(requests, reqCtx)
+		}
+		return true
+	})
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].startTime.Before(requests[j].startTime)
+	})
+	return requests
+}
+
+// describe returns a line that describes the request for the diagnostics dumps. The statement isn't included
+// because it may contain sensitive values.
+func (recv *requestContextImpl) describe(now time.Time) string {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	waitingFor := make([]string, 0, 2)
+	decision := recv.requestInfo.GetForwardDecision()
+	if (decision == forwardToOrigin || decision == forwardToBoth ||
+		recv.speculativeCluster == common.ClusterTypeOrigin) && recv.originResponse == nil {
+		waitingFor = append(waitingFor, string(common.ClusterTypeOrigin))
+	}
+	if (decision == forwardToTarget || decision == forwardToBoth ||
+		recv.speculativeCluster == common.ClusterTypeTarget) && recv.targetResponse == nil {
+		waitingFor = append(waitingFor, string(common.ClusterTypeTarget))
+	}
+	var requestId interface{}
+	if recv.logger != nil {
+		requestId = recv.logger.Data["request_id"]
+	}
+	return fmt.Sprintf("request_id=%v stream_id=%d opcode=%v forward_decision=%v elapsed=%v waiting_for=%v "+
+		"origin_retries=%d target_retries=%d",
+		requestId, recv.request.Header.StreamId, recv.request.Header.OpCode, decision,
+		now.Sub(recv.startTime).Round(time.Millisecond), strings.Join(waitingFor, ","),
+		recv.originRetries, recv.targetRetries)
+}
+
+// WriteDiagnostics writes the open client connections and their in-flight requests, it returns an error if
+// ZDM_DEBUG_ENDPOINT_ENABLED is false.
+func (p *ZdmProxy) WriteDiagnostics(w io.Writer) error {
+	if p.diagnostics == nil {
+		return fmt.Errorf("the client connections are not tracked, see ZDM_DEBUG_ENDPOINT_ENABLED")
+	}
+	return p.diagnostics.writeActiveRequests(w)
+}
@@ -0,0 +1,35 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestRequestContext_Describe(t *testing.T) {
+	request := &frame.RawFrame{Header: &frame.Header{StreamId: 12, OpCode: primitive.OpCodeExecute}}
+	logger := log.WithField("request_id", "conn-3")
+	startTime := time.Now()
+	reqCtx := NewRequestContext(request, request, request, NewGenericRequestInfo(forwardToBoth, true, false), "ks",
+		startTime, nil, logger)
+
+	require.Equal(t, "request_id=conn-3 stream_id=12 opcode=OpCode EXECUTE [0x0A] forward_decision=both elapsed=1.5s "+
+		"waiting_for=ORIGIN,TARGET origin_retries=0 target_retries=0",
+		reqCtx.describe(startTime.Add(1500*time.Millisecond)))
+
+	reqCtx.updateInternalState(&frame.RawFrame{Header: &frame.Header{}}, common.ClusterTypeOrigin)
+	require.Contains(t, reqCtx.describe(startTime), "waiting_for=TARGET ")
+}
+
+func TestDiagnostics_NoClients(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	require.Nil(t, newDiagnostics().writeActiveRequests(buffer))
+	require.Equal(t, "Client connections: 0\n", buffer.String())
+
+	require.NotNil(t, (&ZdmProxy{}).WriteDiagnostics(buffer))
+}
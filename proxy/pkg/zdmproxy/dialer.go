@@ -0,0 +1,258 @@
+package zdmproxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Dialer opens the TCP connections to the nodes of a cluster and to the Astra metadata service. *net.Dialer is used
+// unless a dialer is registered with ZdmProxy.SetDialer (e.g. to reach a cluster through a tunnel) and it is wrapped
+// by an egress proxy dialer when ZDM_ORIGIN_EGRESS_PROXY_URL or ZDM_TARGET_EGRESS_PROXY_URL is set.
+type Dialer interface {
+	DialContext(ctx context.Context, network string, address string) (net.Conn, error)
+}
+
+// newEgressProxyDialer returns a dialer that opens the connections through the SOCKS5 or HTTP CONNECT proxy at
+// proxyUrl (as returned by config.ParseOriginEgressProxyUrl), the connections to the proxy itself are opened with
+// forward.
+func newEgressProxyDialer(proxyUrl *url.URL, username string, password string, forward Dialer) (Dialer, error) {
+	switch proxyUrl.Scheme {
+	case config.EgressProxySchemeSocks5:
+		if len(username) > 255 || len(password) > 255 {
+			return nil, errors.New("SOCKS5 username and password must not be longer than 255 bytes")
+		}
+		return &socks5Dialer{proxyAddr: proxyUrl.Host, username: username, password: password, forward: forward}, nil
+	case config.EgressProxySchemeHttp:
+		return &httpConnectDialer{proxyAddr: proxyUrl.Host, username: username, password: password, forward: forward}, nil
+	default:
+		return nil, fmt.Errorf("unsupported egress proxy scheme: %v", proxyUrl.Scheme)
+	}
+}
+
+// dialThroughProxy opens a connection to the egress proxy and runs the handshake that asks it to connect to address.
+// The handshake is bound by the deadline of the context.
+func dialThroughProxy(
+	ctx context.Context, forward Dialer, proxyAddr string, network string, address string,
+	handshake func(conn net.Conn, address string) (net.Conn, error)) (net.Conn, error) {
+
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("network %v is not supported by the egress proxy", network)
+	}
+
+	conn, err := forward.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to egress proxy %v: %w", proxyAddr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	// unblock the handshake if the context is canceled
+	handshakeDone := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.SetDeadline(time.Unix(1, 0))
+		case <-handshakeDone:
+		}
+	}()
+
+	proxiedConn, err := handshake(conn, address)
+	close(handshakeDone)
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("egress proxy %v could not connect to %v: %w", proxyAddr, address, err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return proxiedConn, nil
+}
+
+const (
+	socks5Version              = 0x05
+	socks5AuthNone             = 0x00
+	socks5AuthUsernamePassword = 0x02
+	socks5AuthNoAcceptable     = 0xFF
+	socks5AuthVersion          = 0x01
+	socks5CmdConnect           = 0x01
+	socks5AddrTypeIpv4         = 0x01
+	socks5AddrTypeDomain       = 0x03
+	socks5AddrTypeIpv6         = 0x04
+)
+
+// socks5Dialer implements the CONNECT command of SOCKS5 (RFC 1928) with optional username/password authentication
+// (RFC 1929). Host names are resolved by the proxy.
+type socks5Dialer struct {
+	proxyAddr string
+	username  string
+	password  string
+	forward   Dialer
+}
+
+func (d *socks5Dialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	return dialThroughProxy(ctx, d.forward, d.proxyAddr, network, address, d.handshake)
+}
+
+func (d *socks5Dialer) handshake(conn net.Conn, address string) (net.Conn, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %v", portStr)
+	}
+
+	authMethod := byte(socks5AuthNone)
+	if d.username != "" {
+		authMethod = socks5AuthUsernamePassword
+	}
+	if _, err = conn.Write([]byte{socks5Version, 1, authMethod}); err != nil {
+		return nil, err
+	}
+	reply := make([]byte, 2)
+	if _, err = io.ReadFull(conn, reply); err != nil {
+		return nil, err
+	}
+	if reply[0] != socks5Version {
+		return nil, fmt.Errorf("unexpected SOCKS version %d", reply[0])
+	}
+	switch reply[1] {
+	case authMethod:
+	case socks5AuthNoAcceptable:
+		return nil, errors.New("no acceptable SOCKS5 authentication method")
+	default:
+		return nil, fmt.Errorf("unexpected SOCKS5 authentication method %d", reply[1])
+	}
+
+	if authMethod == socks5AuthUsernamePassword {
+		auth := []byte{socks5AuthVersion, byte(len(d.username))}
+		auth = append(auth, d.username...)
+		auth = append(auth, byte(len(d.password)))
+		auth = append(auth, d.password...)
+		if _, err = conn.Write(auth); err != nil {
+			return nil, err
+		}
+		if _, err = io.ReadFull(conn, reply); err != nil {
+			return nil, err
+		}
+		if reply[1] != 0 {
+			return nil, errors.New("SOCKS5 authentication failed")
+		}
+	}
+
+	request := []byte{socks5Version, socks5CmdConnect, 0}
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return nil, fmt.Errorf("host name %v is too long", host)
+		}
+		request = append(request, socks5AddrTypeDomain, byte(len(host)))
+		request = append(request, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		request = append(request, socks5AddrTypeIpv4)
+		request = append(request, ip4...)
+	} else {
+		request = append(request, socks5AddrTypeIpv6)
+		request = append(request, ip.To16()...)
+	}
+	request = append(request, 0, 0)
+	binary.BigEndian.PutUint16(request[len(request)-2:], uint16(port))
+	if _, err = conn.Write(request); err != nil {
+		return nil, err
+	}
+
+	// VER, REP, RSV, ATYP followed by the bound address and port which are not used
+	header := make([]byte, 4)
+	if _, err = io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	if header[1] != 0 {
+		return nil, fmt.Errorf("SOCKS5 connect failed with reply code %d", header[1])
+	}
+	var boundAddrLen int
+	switch header[3] {
+	case socks5AddrTypeIpv4:
+		boundAddrLen = net.IPv4len
+	case socks5AddrTypeIpv6:
+		boundAddrLen = net.IPv6len
+	case socks5AddrTypeDomain:
+		if _, err = io.ReadFull(conn, header[:1]); err != nil {
+			return nil, err
+		}
+		boundAddrLen = int(header[0])
+	default:
+		return nil, fmt.Errorf("unexpected SOCKS5 address type %d", header[3])
+	}
+	if _, err = io.ReadFull(conn, make([]byte, boundAddrLen+2)); err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// httpConnectDialer opens tunnels with the CONNECT method of an HTTP proxy, with optional basic authentication.
+type httpConnectDialer struct {
+	proxyAddr string
+	username  string
+	password  string
+	forward   Dialer
+}
+
+func (d *httpConnectDialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	return dialThroughProxy(ctx, d.forward, d.proxyAddr, network, address, d.handshake)
+}
+
+func (d *httpConnectDialer) handshake(conn net.Conn, address string) (net.Conn, error) {
+	request := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if d.username != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(d.username + ":" + d.password))
+		request.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := request.Write(conn); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, request)
+	if err != nil {
+		return nil, err
+	}
+	// the body is not closed, it would be read until the end of the tunnel
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CONNECT request failed: %v", response.Status)
+	}
+
+	if reader.Buffered() > 0 {
+		// the cluster already sent data that was read along with the response
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
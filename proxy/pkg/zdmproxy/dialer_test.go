@@ -0,0 +1,271 @@
+package zdmproxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSocks5Dialer(t *testing.T) {
+	backendAddr := startEchoServer(t)
+	_, backendPort, _ := net.SplitHostPort(backendAddr)
+
+	tests := []struct {
+		name          string
+		proxyUsername string
+		proxyPassword string
+		username      string
+		password      string
+		address       string
+		expectErr     bool
+	}{
+		{"no authentication", "", "", "", "", backendAddr, false},
+		{"host name", "", "", "", "", net.JoinHostPort("localhost", backendPort), false},
+		{"authentication", "user", "pass", "user", "pass", backendAddr, false},
+		{"wrong password", "user", "pass", "user", "wrong", backendAddr, true},
+		{"missing credentials", "user", "pass", "", "", backendAddr, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxyAddr, requestedAddrs := startFakeSocks5Proxy(t, tt.proxyUsername, tt.proxyPassword)
+			dialer, err := newEgressProxyDialer(
+				&url.URL{Scheme: "socks5", Host: proxyAddr}, tt.username, tt.password, &net.Dialer{})
+			require.Nil(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			conn, err := dialer.DialContext(ctx, "tcp", tt.address)
+			if tt.expectErr {
+				require.NotNil(t, err)
+				return
+			}
+			require.Nil(t, err)
+			defer conn.Close()
+			require.Equal(t, tt.address, <-requestedAddrs)
+			requireEcho(t, conn)
+		})
+	}
+}
+
+func TestHttpConnectDialer(t *testing.T) {
+	backendAddr := startEchoServer(t)
+
+	tests := []struct {
+		name          string
+		proxyUsername string
+		proxyPassword string
+		username      string
+		password      string
+		expectErr     bool
+	}{
+		{"no authentication", "", "", "", "", false},
+		{"authentication", "user", "pass", "user", "pass", false},
+		{"wrong password", "user", "pass", "user", "wrong", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxyAddr, requestedAddrs := startFakeHttpConnectProxy(t, tt.proxyUsername, tt.proxyPassword)
+			dialer, err := newEgressProxyDialer(
+				&url.URL{Scheme: "http", Host: proxyAddr}, tt.username, tt.password, &net.Dialer{})
+			require.Nil(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			conn, err := dialer.DialContext(ctx, "tcp", backendAddr)
+			if tt.expectErr {
+				require.NotNil(t, err)
+				return
+			}
+			require.Nil(t, err)
+			defer conn.Close()
+			require.Equal(t, backendAddr, <-requestedAddrs)
+			requireEcho(t, conn)
+		})
+	}
+}
+
+func TestEgressProxyDialer_HandshakeTimeout(t *testing.T) {
+	// the proxy accepts the connection but never replies
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(io.Discard, conn)
+			}()
+		}
+	}()
+
+	for _, scheme := range []string{"socks5", "http"} {
+		dialer, err := newEgressProxyDialer(&url.URL{Scheme: scheme, Host: listener.Addr().String()}, "", "", &net.Dialer{})
+		require.Nil(t, err)
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		_, err = dialer.DialContext(ctx, "tcp", "127.0.0.1:9042")
+		cancel()
+		require.NotNil(t, err, scheme)
+	}
+}
+
+func requireEcho(t *testing.T, conn net.Conn) {
+	_, err := conn.Write([]byte("ping"))
+	require.Nil(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(conn, buf)
+	require.Nil(t, err)
+	require.Equal(t, "ping", string(buf))
+}
+
+func startEchoServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// startFakeProxy accepts connections and runs handshake on each of them, the connection is then piped to the address
+// returned by the handshake, which is also sent to the returned channel.
+func startFakeProxy(t *testing.T, handshake func(conn net.Conn, reader *bufio.Reader) (string, error)) (string, chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() { listener.Close() })
+	requestedAddrs := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				addr, err := handshake(conn, reader)
+				if err != nil {
+					return
+				}
+				requestedAddrs <- addr
+				backendConn, err := net.Dial("tcp", addr)
+				if err != nil {
+					return
+				}
+				defer backendConn.Close()
+				go func() { _, _ = io.Copy(backendConn, reader) }()
+				_, _ = io.Copy(conn, backendConn)
+			}()
+		}
+	}()
+	return listener.Addr().String(), requestedAddrs
+}
+
+func startFakeSocks5Proxy(t *testing.T, username string, password string) (string, chan string) {
+	return startFakeProxy(t, func(conn net.Conn, reader *bufio.Reader) (string, error) {
+		header := make([]byte, 2)
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return "", err
+		}
+		methods := make([]byte, header[1])
+		if _, err := io.ReadFull(reader, methods); err != nil {
+			return "", err
+		}
+		expectedMethod := byte(socks5AuthNone)
+		if username != "" {
+			expectedMethod = socks5AuthUsernamePassword
+		}
+		if len(methods) != 1 || methods[0] != expectedMethod {
+			_, _ = conn.Write([]byte{socks5Version, socks5AuthNoAcceptable})
+			return "", fmt.Errorf("unexpected methods %v", methods)
+		}
+		_, _ = conn.Write([]byte{socks5Version, expectedMethod})
+
+		if username != "" {
+			readString := func() string {
+				length, _ := reader.ReadByte()
+				value := make([]byte, length)
+				_, _ = io.ReadFull(reader, value)
+				return string(value)
+			}
+			_, _ = reader.ReadByte()
+			if readString() != username || readString() != password {
+				_, _ = conn.Write([]byte{socks5AuthVersion, 1})
+				return "", fmt.Errorf("invalid credentials")
+			}
+			_, _ = conn.Write([]byte{socks5AuthVersion, 0})
+		}
+
+		request := make([]byte, 4)
+		if _, err := io.ReadFull(reader, request); err != nil {
+			return "", err
+		}
+		var host string
+		switch request[3] {
+		case socks5AddrTypeIpv4:
+			ip := make([]byte, net.IPv4len)
+			_, _ = io.ReadFull(reader, ip)
+			host = net.IP(ip).String()
+		case socks5AddrTypeIpv6:
+			ip := make([]byte, net.IPv6len)
+			_, _ = io.ReadFull(reader, ip)
+			host = net.IP(ip).String()
+		case socks5AddrTypeDomain:
+			length, _ := reader.ReadByte()
+			name := make([]byte, length)
+			_, _ = io.ReadFull(reader, name)
+			host = string(name)
+		}
+		port := make([]byte, 2)
+		if _, err := io.ReadFull(reader, port); err != nil {
+			return "", err
+		}
+		_, _ = conn.Write([]byte{socks5Version, 0, 0, socks5AddrTypeIpv4, 127, 0, 0, 1, 0, 0})
+		return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), nil
+	})
+}
+
+func startFakeHttpConnectProxy(t *testing.T, username string, password string) (string, chan string) {
+	return startFakeProxy(t, func(conn net.Conn, reader *bufio.Reader) (string, error) {
+		request, err := http.ReadRequest(reader)
+		if err != nil {
+			return "", err
+		}
+		if request.Method != http.MethodConnect {
+			_, _ = conn.Write([]byte("HTTP/1.1 405 Method Not Allowed\r\n\r\n"))
+			return "", fmt.Errorf("unexpected method %v", request.Method)
+		}
+		if username != "" {
+			request.Header.Set("Authorization", request.Header.Get("Proxy-Authorization"))
+			user, pass, ok := request.BasicAuth()
+			if !ok || user != username || pass != password {
+				_, _ = conn.Write([]byte("HTTP/1.1 407 Proxy Authentication Required\r\n\r\n"))
+				return "", fmt.Errorf("invalid credentials")
+			}
+		}
+		_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		return request.Host, nil
+	})
+}
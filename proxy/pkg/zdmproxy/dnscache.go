@@ -0,0 +1,164 @@
+package zdmproxy
+
+import (
+	"context"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"sync"
+	"time"
+)
+
+// dnsCache caches the addresses of host names (e.g. the Astra SNI proxy) for the TTL of their records, bounded by
+// ZDM_DNS_CACHE_MIN_TTL_MS and ZDM_DNS_CACHE_MAX_TTL_MS. When a host name can't be resolved anymore the addresses
+// that are already cached keep being used until the next attempt, ZDM_DNS_CACHE_MIN_TTL_MS later.
+type dnsCache struct {
+	lookup  func(ctx context.Context, host string) ([]string, time.Duration, error)
+	minTtl  time.Duration
+	maxTtl  time.Duration
+	now     func() time.Time
+	entries map[string]*dnsCacheEntry
+
+	// set once the metric handler is initialized, the failures that happen before are not counted
+	failures metrics.Counter
+
+	lock   *sync.Mutex
+	logger *log.Entry
+}
+
+type dnsCacheEntry struct {
+	addrs     []string
+	expiresAt time.Time
+}
+
+func newDnsCache(
+	lookup func(ctx context.Context, host string) ([]string, time.Duration, error),
+	minTtl time.Duration, maxTtl time.Duration, logger *log.Entry) *dnsCache {
+	return &dnsCache{
+		lookup:  lookup,
+		minTtl:  minTtl,
+		maxTtl:  maxTtl,
+		now:     time.Now,
+		entries: make(map[string]*dnsCacheEntry),
+		lock:    &sync.Mutex{},
+		logger:  logger,
+	}
+}
+
+func (recv *dnsCache) SetFailureCounter(counter metrics.Counter) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.failures = counter
+}
+
+// Resolve returns the cached addresses of host or resolves it if they expired.
+func (recv *dnsCache) Resolve(ctx context.Context, host string) ([]string, error) {
+	recv.lock.Lock()
+	entry, ok := recv.entries[host]
+	recv.lock.Unlock()
+	if ok && recv.now().Before(entry.expiresAt) {
+		return entry.addrs, nil
+	}
+
+	addrs, ttl, err := recv.lookup(ctx, host)
+	if err == nil && len(addrs) == 0 {
+		err = fmt.Errorf("no addresses found for %v", host)
+	}
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if err != nil {
+		if recv.failures != nil {
+			recv.failures.Add(1)
+		}
+		entry, ok = recv.entries[host]
+		if !ok {
+			return nil, err
+		}
+		recv.logger.Warnf("Could not resolve %v (%v), the previous addresses %v will be used.", host, err, entry.addrs)
+		entry.expiresAt = recv.now().Add(recv.minTtl)
+		return entry.addrs, nil
+	}
+
+	if ttl < recv.minTtl {
+		ttl = recv.minTtl
+	}
+	if ttl > recv.maxTtl {
+		ttl = recv.maxTtl
+	}
+	if previous, ok := recv.entries[host]; !ok || !stringSlicesEqual(previous.addrs, addrs) {
+		recv.logger.Debugf("Resolved %v to %v, the addresses will be cached for %v.", host, addrs, ttl)
+	}
+	recv.entries[host] = &dnsCacheEntry{addrs: addrs, expiresAt: recv.now().Add(ttl)}
+	return addrs, nil
+}
+
+// Invalidate removes the addresses of host from the cache, unless they were already replaced by other addresses.
+func (recv *dnsCache) Invalidate(host string, addrs []string) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if entry, ok := recv.entries[host]; ok && stringSlicesEqual(entry.addrs, addrs) {
+		delete(recv.entries, host)
+	}
+}
+
+func stringSlicesEqual(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// dnsCachingDialer resolves host names with a dnsCache before opening the connections with the forward dialer. When
+// the connections to all the cached addresses of a host fail, the host is resolved again and, if its addresses
+// changed, the connection is attempted again with the new ones.
+type dnsCachingDialer struct {
+	cache   *dnsCache
+	forward Dialer
+}
+
+func (recv *dnsCachingDialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || net.ParseIP(host) != nil {
+		return recv.forward.DialContext(ctx, network, address)
+	}
+
+	addrs, err := recv.cache.Resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := recv.dialAny(ctx, network, addrs, port)
+	if err == nil || ctx.Err() != nil {
+		return conn, err
+	}
+
+	recv.cache.Invalidate(host, addrs)
+	newAddrs, resolveErr := recv.cache.Resolve(ctx, host)
+	if resolveErr != nil || stringSlicesEqual(addrs, newAddrs) {
+		return nil, err
+	}
+	recv.cache.logger.Infof("Could not connect to the previous addresses of %v (%v), retrying with the new addresses %v.",
+		host, err, newAddrs)
+	return recv.dialAny(ctx, network, newAddrs, port)
+}
+
+func (recv *dnsCachingDialer) dialAny(ctx context.Context, network string, addrs []string, port string) (net.Conn, error) {
+	var err error
+	for _, addr := range addrs {
+		var conn net.Conn
+		conn, err = recv.forward.DialContext(ctx, network, net.JoinHostPort(addr, port))
+		if err == nil {
+			return conn, nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,154 @@
+package zdmproxy
+
+import (
+	"context"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+	"time"
+)
+
+type fakeDnsLookup struct {
+	addrs   []string
+	ttl     time.Duration
+	err     error
+	lookups int
+}
+
+func (recv *fakeDnsLookup) LookupHost(ctx context.Context, host string) ([]string, time.Duration, error) {
+	recv.lookups++
+	return recv.addrs, recv.ttl, recv.err
+}
+
+func TestDnsCache(t *testing.T) {
+	lookup := &fakeDnsLookup{addrs: []string{"10.0.0.1"}, ttl: 30 * time.Second}
+	cache := newDnsCache(lookup.LookupHost, time.Second, time.Minute, log.NewEntry(log.StandardLogger()))
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	failures := &countingCounter{}
+	cache.SetFailureCounter(failures)
+
+	addrs, err := cache.Resolve(context.Background(), "sni.example.com")
+	require.Nil(t, err)
+	require.Equal(t, []string{"10.0.0.1"}, addrs)
+
+	// cached for the TTL of the record
+	lookup.addrs = []string{"10.0.0.2"}
+	now = now.Add(29 * time.Second)
+	addrs, _ = cache.Resolve(context.Background(), "sni.example.com")
+	require.Equal(t, []string{"10.0.0.1"}, addrs)
+	require.Equal(t, 1, lookup.lookups)
+
+	now = now.Add(time.Second)
+	addrs, _ = cache.Resolve(context.Background(), "sni.example.com")
+	require.Equal(t, []string{"10.0.0.2"}, addrs)
+	require.Equal(t, 2, lookup.lookups)
+
+	// the previous addresses are used when the host can't be resolved anymore, until the next attempt
+	lookup.err = errors.New("no such host")
+	now = now.Add(time.Minute)
+	addrs, err = cache.Resolve(context.Background(), "sni.example.com")
+	require.Nil(t, err)
+	require.Equal(t, []string{"10.0.0.2"}, addrs)
+	require.Equal(t, int32(1), failures.value)
+	_, _ = cache.Resolve(context.Background(), "sni.example.com")
+	require.Equal(t, 3, lookup.lookups)
+	now = now.Add(time.Second)
+	_, _ = cache.Resolve(context.Background(), "sni.example.com")
+	require.Equal(t, 4, lookup.lookups)
+	require.Equal(t, int32(2), failures.value)
+
+	_, err = cache.Resolve(context.Background(), "other.example.com")
+	require.NotNil(t, err)
+	require.Equal(t, int32(3), failures.value)
+
+	// invalidated only if the addresses were not replaced in the meantime
+	lookup.err = nil
+	cache.Invalidate("sni.example.com", []string{"10.0.0.1"})
+	_, _ = cache.Resolve(context.Background(), "sni.example.com")
+	require.Equal(t, 5, lookup.lookups)
+	cache.Invalidate("sni.example.com", []string{"10.0.0.2"})
+	_, _ = cache.Resolve(context.Background(), "sni.example.com")
+	require.Equal(t, 6, lookup.lookups)
+}
+
+func TestDnsCache_TtlBounds(t *testing.T) {
+	tests := []struct {
+		name        string
+		ttl         time.Duration
+		expectedTtl time.Duration
+	}{
+		{"below min", 0, time.Second},
+		{"between min and max", 10 * time.Second, 10 * time.Second},
+		{"above max", time.Hour, time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookup := &fakeDnsLookup{addrs: []string{"10.0.0.1"}, ttl: tt.ttl}
+			cache := newDnsCache(lookup.LookupHost, time.Second, time.Minute, log.NewEntry(log.StandardLogger()))
+			now := time.Now()
+			cache.now = func() time.Time { return now }
+
+			_, _ = cache.Resolve(context.Background(), "sni.example.com")
+			now = now.Add(tt.expectedTtl - time.Millisecond)
+			_, _ = cache.Resolve(context.Background(), "sni.example.com")
+			require.Equal(t, 1, lookup.lookups)
+			now = now.Add(time.Millisecond)
+			_, _ = cache.Resolve(context.Background(), "sni.example.com")
+			require.Equal(t, 2, lookup.lookups)
+		})
+	}
+}
+
+type fakeDialer struct {
+	reachable map[string]bool
+	dialed    []string
+}
+
+func (recv *fakeDialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	recv.dialed = append(recv.dialed, address)
+	if !recv.reachable[address] {
+		return nil, errors.New("connection refused")
+	}
+	client, server := net.Pipe()
+	_ = server.Close()
+	return client, nil
+}
+
+func TestDnsCachingDialer(t *testing.T) {
+	lookup := &fakeDnsLookup{addrs: []string{"10.0.0.1", "10.0.0.2"}, ttl: time.Minute}
+	forward := &fakeDialer{reachable: map[string]bool{"10.0.0.2:29042": true, "10.0.0.3:29042": true}}
+	dialer := &dnsCachingDialer{cache: newDnsCache(lookup.LookupHost, time.Second, time.Minute, log.NewEntry(log.StandardLogger())), forward: forward}
+
+	conn, err := dialer.DialContext(context.Background(), "tcp", "sni.example.com:29042")
+	require.Nil(t, err)
+	_ = conn.Close()
+	require.Equal(t, []string{"10.0.0.1:29042", "10.0.0.2:29042"}, forward.dialed)
+
+	// IP addresses are not resolved
+	forward.dialed = nil
+	conn, err = dialer.DialContext(context.Background(), "tcp", "10.0.0.3:29042")
+	require.Nil(t, err)
+	_ = conn.Close()
+	require.Equal(t, []string{"10.0.0.3:29042"}, forward.dialed)
+
+	// the host is resolved again when the cached addresses can't be reached anymore
+	forward.dialed = nil
+	forward.reachable["10.0.0.2:29042"] = false
+	lookup.addrs = []string{"10.0.0.3"}
+	conn, err = dialer.DialContext(context.Background(), "tcp", "sni.example.com:29042")
+	require.Nil(t, err)
+	_ = conn.Close()
+	require.Equal(t, []string{"10.0.0.1:29042", "10.0.0.2:29042", "10.0.0.3:29042"}, forward.dialed)
+	require.Equal(t, 2, lookup.lookups)
+
+	// the addresses didn't change so the connection is not attempted again
+	forward.dialed = nil
+	forward.reachable["10.0.0.3:29042"] = false
+	_, err = dialer.DialContext(context.Background(), "tcp", "sni.example.com:29042")
+	require.NotNil(t, err)
+	require.Equal(t, []string{"10.0.0.3:29042"}, forward.dialed)
+	require.Equal(t, 3, lookup.lookups)
+}
@@ -0,0 +1,240 @@
+package zdmproxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	dnsTypeA     = 1
+	dnsTypeCname = 5
+	dnsTypeAAAA  = 28
+	dnsClassIn   = 1
+
+	dnsRcodeNameError = 3
+
+	dnsQueryTimeout    = 5 * time.Second
+	dnsMaxUdpResponse  = 4096
+	resolvConfFilePath = "/etc/resolv.conf"
+)
+
+// dnsClient resolves host names with the name servers of /etc/resolv.conf to get the TTL of the records, which the
+// net package doesn't expose. The names it can't resolve (e.g. names of /etc/hosts, names that need the search
+// domains or truncated responses) are resolved by the system resolver with a TTL of 0.
+type dnsClient struct {
+	nameservers []string
+	fallback    func(ctx context.Context, host string) ([]string, error)
+}
+
+var (
+	defaultDnsClient     *dnsClient
+	defaultDnsClientOnce = &sync.Once{}
+)
+
+func getDefaultDnsClient() *dnsClient {
+	defaultDnsClientOnce.Do(func() {
+		defaultDnsClient = &dnsClient{
+			nameservers: readNameservers(resolvConfFilePath),
+			fallback:    net.DefaultResolver.LookupHost,
+		}
+	})
+	return defaultDnsClient
+}
+
+func readNameservers(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var nameservers []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" && net.ParseIP(fields[1]) != nil {
+			nameservers = append(nameservers, net.JoinHostPort(fields[1], "53"))
+		}
+	}
+	return nameservers
+}
+
+// LookupHost returns the addresses of host and the smallest TTL of the records.
+func (recv *dnsClient) LookupHost(ctx context.Context, host string) ([]string, time.Duration, error) {
+	if len(recv.nameservers) > 0 && strings.Contains(strings.TrimSuffix(host, "."), ".") {
+		addrs, ttl, err := recv.query(ctx, host)
+		if err == nil && len(addrs) > 0 {
+			return addrs, ttl, nil
+		}
+	}
+
+	addrs, err := recv.fallback(ctx, host)
+	return addrs, 0, err
+}
+
+func (recv *dnsClient) query(ctx context.Context, host string) ([]string, time.Duration, error) {
+	var lastErr error
+	for _, nameserver := range recv.nameservers {
+		addrs, ttl := make([]string, 0), time.Duration(-1)
+		var err error
+		for _, qtype := range []uint16{dnsTypeA, dnsTypeAAAA} {
+			var qtypeAddrs []string
+			var qtypeTtl time.Duration
+			qtypeAddrs, qtypeTtl, err = exchangeDnsQuery(ctx, nameserver, host, qtype)
+			if err != nil {
+				break
+			}
+			addrs = append(addrs, qtypeAddrs...)
+			if len(qtypeAddrs) > 0 && (ttl < 0 || qtypeTtl < ttl) {
+				ttl = qtypeTtl
+			}
+		}
+		if err == nil {
+			return addrs, ttl, nil
+		}
+		lastErr = err
+	}
+	return nil, 0, lastErr
+}
+
+func exchangeDnsQuery(ctx context.Context, nameserver string, host string, qtype uint16) ([]string, time.Duration, error) {
+	query, id, err := newDnsQuery(host, qtype)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "udp", nameserver)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(dnsQueryTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	_ = conn.SetDeadline(deadline)
+
+	if _, err = conn.Write(query); err != nil {
+		return nil, 0, err
+	}
+	response := make([]byte, dnsMaxUdpResponse)
+	for {
+		n, err := conn.Read(response)
+		if err != nil {
+			return nil, 0, err
+		}
+		if n >= 2 && binary.BigEndian.Uint16(response) != id {
+			continue // response to a previous query
+		}
+		return parseDnsResponse(response[:n], qtype)
+	}
+}
+
+func newDnsQuery(host string, qtype uint16) ([]byte, uint16, error) {
+	id := uint16(rand.Intn(1 << 16))
+	// header: id, flags (recursion desired), 1 question, 0 answer, authority and additional records
+	query := []byte{byte(id >> 8), byte(id), 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0}
+	for _, label := range strings.Split(strings.TrimSuffix(host, "."), ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, 0, fmt.Errorf("invalid host name %v", host)
+		}
+		query = append(query, byte(len(label)))
+		query = append(query, label...)
+	}
+	query = append(query, 0, byte(qtype>>8), byte(qtype), 0, dnsClassIn)
+	return query, id, nil
+}
+
+// parseDnsResponse returns the addresses of the records of type qtype and the smallest TTL of the answers, CNAME
+// records included.
+func parseDnsResponse(response []byte, qtype uint16) ([]string, time.Duration, error) {
+	if len(response) < 12 {
+		return nil, 0, errors.New("DNS response is too short")
+	}
+	flags := binary.BigEndian.Uint16(response[2:])
+	if flags&0x8000 == 0 {
+		return nil, 0, errors.New("DNS message is not a response")
+	}
+	if flags&0x0200 != 0 {
+		return nil, 0, errors.New("DNS response is truncated")
+	}
+	rcode := flags & 0x000F
+	if rcode == dnsRcodeNameError {
+		return nil, 0, nil
+	}
+	if rcode != 0 {
+		return nil, 0, fmt.Errorf("DNS query failed with rcode %d", rcode)
+	}
+
+	questions := int(binary.BigEndian.Uint16(response[4:]))
+	answers := int(binary.BigEndian.Uint16(response[6:]))
+	offset := 12
+	var err error
+	for i := 0; i < questions; i++ {
+		if offset, err = skipDnsName(response, offset); err != nil {
+			return nil, 0, err
+		}
+		offset += 4 // type and class
+	}
+
+	addrs := make([]string, 0, answers)
+	ttl := time.Duration(-1)
+	for i := 0; i < answers; i++ {
+		if offset, err = skipDnsName(response, offset); err != nil {
+			return nil, 0, err
+		}
+		if offset+10 > len(response) {
+			return nil, 0, errors.New("DNS record is truncated")
+		}
+		recordType := binary.BigEndian.Uint16(response[offset:])
+		recordTtl := time.Duration(binary.BigEndian.Uint32(response[offset+4:])) * time.Second
+		dataLength := int(binary.BigEndian.Uint16(response[offset+8:]))
+		offset += 10
+		if offset+dataLength > len(response) {
+			return nil, 0, errors.New("DNS record is truncated")
+		}
+		data := response[offset : offset+dataLength]
+		offset += dataLength
+
+		if recordType == qtype && (dataLength == net.IPv4len || dataLength == net.IPv6len) {
+			addrs = append(addrs, net.IP(data).String())
+		} else if recordType != dnsTypeCname {
+			continue
+		}
+		if ttl < 0 || recordTtl < ttl {
+			ttl = recordTtl
+		}
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+	return addrs, ttl, nil
+}
+
+func skipDnsName(message []byte, offset int) (int, error) {
+	for {
+		if offset >= len(message) {
+			return 0, errors.New("DNS name is truncated")
+		}
+		length := int(message[offset])
+		switch {
+		case length == 0:
+			return offset + 1, nil
+		case length&0xC0 == 0xC0:
+			// compression pointer, the name ends here
+			return offset + 2, nil
+		default:
+			offset += 1 + length
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package zdmproxy
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+	"time"
+)
+
+// newDnsResponse answers query with a CNAME record followed by one record of the queried type per address, the names
+// of the records are compression pointers to the name of the question.
+func newDnsResponse(query []byte, cnameTtl uint32, ttl uint32, addrs ...net.IP) []byte {
+	response := append([]byte{}, query...)
+	response[2] |= 0x80 // response
+	qtype := binary.BigEndian.Uint16(query[len(query)-4:])
+	binary.BigEndian.PutUint16(response[6:], uint16(len(addrs)+1))
+
+	record := func(recordType uint16, ttl uint32, data []byte) {
+		response = append(response, 0xC0, 12, 0, 0, 0, dnsClassIn, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint16(response[len(response)-10:], recordType)
+		binary.BigEndian.PutUint32(response[len(response)-6:], ttl)
+		binary.BigEndian.PutUint16(response[len(response)-2:], uint16(len(data)))
+		response = append(response, data...)
+	}
+	record(dnsTypeCname, cnameTtl, []byte{0xC0, 12})
+	for _, addr := range addrs {
+		if qtype == dnsTypeA {
+			record(dnsTypeA, ttl, addr.To4())
+		} else {
+			record(dnsTypeAAAA, ttl, addr.To16())
+		}
+	}
+	return response
+}
+
+func startFakeDnsServer(t *testing.T, records map[uint16][]net.IP) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			query := buf[:n]
+			qtype := binary.BigEndian.Uint16(query[n-4:])
+			_, _ = conn.WriteTo(newDnsResponse(query, 600, 30, records[qtype]...), addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestDnsClient_LookupHost(t *testing.T) {
+	nameserver := startFakeDnsServer(t, map[uint16][]net.IP{
+		dnsTypeA:    {net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")},
+		dnsTypeAAAA: {net.ParseIP("fd00::1")},
+	})
+	fallbackLookups := 0
+	client := &dnsClient{
+		nameservers: []string{nameserver},
+		fallback: func(ctx context.Context, host string) ([]string, error) {
+			fallbackLookups++
+			return []string{"127.0.0.1"}, nil
+		},
+	}
+
+	addrs, ttl, err := client.LookupHost(context.Background(), "sni.example.com")
+	require.Nil(t, err)
+	require.Equal(t, []string{"10.0.0.1", "10.0.0.2", "fd00::1"}, addrs)
+	require.Equal(t, 30*time.Second, ttl)
+	require.Equal(t, 0, fallbackLookups)
+
+	// names without a dot are resolved by the system resolver (e.g. localhost or names that need the search domains)
+	addrs, ttl, err = client.LookupHost(context.Background(), "localhost")
+	require.Nil(t, err)
+	require.Equal(t, []string{"127.0.0.1"}, addrs)
+	require.Equal(t, time.Duration(0), ttl)
+	require.Equal(t, 1, fallbackLookups)
+
+	client.fallback = func(ctx context.Context, host string) ([]string, error) {
+		return nil, errors.New("no such host")
+	}
+	client.nameservers = []string{startFakeDnsServer(t, map[uint16][]net.IP{})}
+	_, _, err = client.LookupHost(context.Background(), "unknown.example.com")
+	require.NotNil(t, err)
+}
+
+func TestParseDnsResponse(t *testing.T) {
+	query, _, err := newDnsQuery("sni.example.com", dnsTypeA)
+	require.Nil(t, err)
+
+	addrs, ttl, err := parseDnsResponse(newDnsResponse(query, 10, 300, net.ParseIP("10.0.0.1")), dnsTypeA)
+	require.Nil(t, err)
+	require.Equal(t, []string{"10.0.0.1"}, addrs)
+	require.Equal(t, 10*time.Second, ttl, "the TTL of the CNAME record is smaller")
+
+	truncated := newDnsResponse(query, 10, 300, net.ParseIP("10.0.0.1"))
+	truncated[2] |= 0x02
+	_, _, err = parseDnsResponse(truncated, dnsTypeA)
+	require.NotNil(t, err)
+
+	_, _, err = parseDnsResponse(query, dnsTypeA)
+	require.NotNil(t, err, "not a response")
+
+	response := newDnsResponse(query, 10, 300, net.ParseIP("10.0.0.1"))
+	_, _, err = parseDnsResponse(response[:len(response)-2], dnsTypeA)
+	require.NotNil(t, err)
+}
@@ -3,6 +3,9 @@ package zdmproxy
 import (
 	"crypto/tls"
 	"fmt"
+	"net"
+	"strconv"
+	"strings"
 )
 
 type Endpoint interface {
@@ -17,13 +20,21 @@ type DefaultEndpoint struct {
 	tlsConfig      *tls.Config
 }
 
+// NewDefaultEndpoint creates an endpoint from a host name or IP address, IPv6 literals can be provided with or without
+// brackets (e.g. "::1" or "[::1]").
 func NewDefaultEndpoint(addr string, port int, tlsConfig *tls.Config) *DefaultEndpoint {
 	return &DefaultEndpoint{
-		socketEndpoint: fmt.Sprintf("%s:%d", addr, port),
+		socketEndpoint: JoinHostPort(addr, port),
 		tlsConfig:      tlsConfig,
 	}
 }
 
+// JoinHostPort is like net.JoinHostPort but it also accepts IPv6 literals that are already enclosed in brackets.
+func JoinHostPort(host string, port int) string {
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
 func (recv *DefaultEndpoint) GetSocketEndpoint() string {
 	return recv.socketEndpoint
 }
@@ -0,0 +1,156 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// eventForwarder decides which protocol events received from ORIGIN and TARGET are forwarded to a client according
+// to ZDM_EVENT_SOURCE_POLICY. It is only used by the event listener goroutine of the client handler.
+type eventForwarder struct {
+	policy         common.EventSourcePolicy
+	primaryCluster common.ClusterType
+	dedupWindow    time.Duration
+
+	// nil unless virtualization is enabled, the control connection of the cluster whose topology is
+	// returned to the client in system.peers and system.local
+	virtualizationControlConn *ControlConn
+	proxyPort                 int
+
+	// events forwarded during the last dedupWindow, keyed on their description
+	recentEvents map[string]time.Time
+
+	logger *log.Entry
+}
+
+func newEventForwarder(
+	policy common.EventSourcePolicy, primaryCluster common.ClusterType, dedupWindow time.Duration,
+	virtualizationControlConn *ControlConn, proxyPort int, logger *log.Entry) *eventForwarder {
+	return &eventForwarder{
+		policy:                    policy,
+		primaryCluster:            primaryCluster,
+		dedupWindow:               dedupWindow,
+		virtualizationControlConn: virtualizationControlConn,
+		proxyPort:                 proxyPort,
+		recentEvents:              make(map[string]time.Time),
+		logger:                    logger,
+	}
+}
+
+// getClientEvent returns the event that should be sent to the client or nil if the event should be skipped.
+//
+// With virtualization, the address of STATUS_CHANGE events is translated to the address of the proxy instance that
+// is assigned to the node and TOPOLOGY_CHANGE events are skipped because the topology that clients see is made of
+// proxy instances (ZDM_PROXY_TOPOLOGY_ADDRESSES), it doesn't change with the topology of the clusters.
+func (recv *eventForwarder) getClientEvent(
+	event *frame.RawFrame, clusterType common.ClusterType, now time.Time) (*frame.RawFrame, error) {
+
+	decodedFrame, err := defaultCodec.ConvertFromRawFrame(event)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode event: %w", err)
+	}
+
+	if _, ok := decodedFrame.Body.Message.(*message.ProtocolError); ok {
+		recv.logger.Debugf("Received protocol error on event listener from %v, forwarding to client: %v",
+			clusterType, decodedFrame.Body.Message)
+		return event, nil
+	}
+
+	switch recv.policy {
+	case common.EventSourcePolicyNone:
+		recv.logger.Debugf("Received event from %v but ZDM_EVENT_SOURCE_POLICY is %v, skipping: %v",
+			clusterType, recv.policy, decodedFrame.Body.Message)
+		return nil, nil
+	case common.EventSourcePolicyPrimaryOnly:
+		if clusterType != recv.primaryCluster {
+			recv.logger.Debugf("Received event from %v which is not the primary cluster, skipping: %v",
+				clusterType, decodedFrame.Body.Message)
+			return nil, nil
+		}
+	}
+
+	translated := false
+	switch msg := decodedFrame.Body.Message.(type) {
+	case *message.SchemaChangeEvent:
+	case *message.StatusChangeEvent:
+		if recv.virtualizationControlConn != nil {
+			address, ok := recv.translateAddress(msg.Address)
+			if !ok {
+				recv.logger.Infof("Received status change event from %v for a node that isn't assigned to a proxy "+
+					"instance, skipping: %v", clusterType, msg)
+				return nil, nil
+			}
+			msg.Address = address
+			translated = true
+		}
+	case *message.TopologyChangeEvent:
+		if recv.virtualizationControlConn != nil {
+			recv.logger.Infof("Received topology change event from %v but virtualization is enabled, skipping: %v",
+				clusterType, msg)
+			return nil, nil
+		}
+	default:
+		recv.logger.Infof("Expected event body from %v but got: %v", clusterType, msg)
+		return nil, nil
+	}
+
+	if recv.isDuplicate(decodedFrame.Body.Message, now) {
+		recv.logger.Debugf("Received event from %v that was already forwarded from the other cluster, skipping: %v",
+			clusterType, decodedFrame.Body.Message)
+		return nil, nil
+	}
+
+	if !translated {
+		return event, nil
+	}
+	translatedEvent, err := defaultCodec.ConvertToRawFrame(decodedFrame)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode translated event: %w", err)
+	}
+	return translatedEvent, nil
+}
+
+// translateAddress returns the address (with the proxy port) of the proxy instance whose virtual host is
+// assigned to the node.
+func (recv *eventForwarder) translateAddress(address *primitive.Inet) (*primitive.Inet, bool) {
+	if address == nil {
+		return nil, false
+	}
+	virtualHosts, err := recv.virtualizationControlConn.GetVirtualHosts()
+	if err != nil {
+		recv.logger.Debugf("Could not translate event address %v: %v", address, err)
+		return nil, false
+	}
+	for _, virtualHost := range virtualHosts {
+		if virtualHost.Host != nil && virtualHost.Host.Address.Equal(address.Addr) {
+			return &primitive.Inet{Addr: virtualHost.Addr, Port: int32(recv.proxyPort)}, true
+		}
+	}
+	return nil, false
+}
+
+// isDuplicate returns true if the same event was forwarded during the last ZDM_EVENT_DEDUP_WINDOW_MS, which happens
+// when both clusters send it (e.g. a schema change that was applied to both clusters).
+func (recv *eventForwarder) isDuplicate(msg message.Message, now time.Time) bool {
+	if recv.policy != common.EventSourcePolicyBoth || recv.dedupWindow <= 0 {
+		return false
+	}
+
+	for key, forwardedAt := range recv.recentEvents {
+		if now.Sub(forwardedAt) > recv.dedupWindow {
+			delete(recv.recentEvents, key)
+		}
+	}
+
+	key := fmt.Sprintf("%v", msg)
+	if _, ok := recv.recentEvents[key]; ok {
+		return true
+	}
+	recv.recentEvents[key] = now
+	return false
+}
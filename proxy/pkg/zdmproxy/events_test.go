@@ -0,0 +1,104 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newEventFrame(t *testing.T, msg message.Message) *frame.RawFrame {
+	rawFrame, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, -1, msg))
+	require.Nil(t, err)
+	return rawFrame
+}
+
+func decodeEventFrame(t *testing.T, rawFrame *frame.RawFrame) message.Message {
+	decodedFrame, err := defaultCodec.ConvertFromRawFrame(rawFrame)
+	require.Nil(t, err)
+	return decodedFrame.Body.Message
+}
+
+func TestEventForwarder_SourcePolicy(t *testing.T) {
+	schemaChange := newEventFrame(t, &message.SchemaChangeEvent{
+		ChangeType: primitive.SchemaChangeTypeCreated,
+		Target:     primitive.SchemaChangeTargetTable,
+		Keyspace:   "ks",
+		Object:     "tb",
+	})
+	now := time.Now()
+	logger := log.WithFields(log.Fields{})
+
+	forwarder := newEventForwarder(common.EventSourcePolicyPrimaryOnly, common.ClusterTypeOrigin, time.Second, nil, 14002, logger)
+	event, err := forwarder.getClientEvent(schemaChange, common.ClusterTypeOrigin, now)
+	require.Nil(t, err)
+	require.Equal(t, schemaChange, event)
+	event, err = forwarder.getClientEvent(schemaChange, common.ClusterTypeTarget, now)
+	require.Nil(t, err)
+	require.Nil(t, event)
+
+	forwarder = newEventForwarder(common.EventSourcePolicyBoth, common.ClusterTypeOrigin, time.Second, nil, 14002, logger)
+	event, err = forwarder.getClientEvent(schemaChange, common.ClusterTypeTarget, now)
+	require.Nil(t, err)
+	require.Equal(t, schemaChange, event)
+	event, err = forwarder.getClientEvent(schemaChange, common.ClusterTypeOrigin, now.Add(500*time.Millisecond))
+	require.Nil(t, err)
+	require.Nil(t, event)
+	event, err = forwarder.getClientEvent(schemaChange, common.ClusterTypeOrigin, now.Add(2*time.Second))
+	require.Nil(t, err)
+	require.Equal(t, schemaChange, event)
+
+	forwarder = newEventForwarder(common.EventSourcePolicyNone, common.ClusterTypeOrigin, time.Second, nil, 14002, logger)
+	event, err = forwarder.getClientEvent(schemaChange, common.ClusterTypeOrigin, now)
+	require.Nil(t, err)
+	require.Nil(t, event)
+	protocolError := newEventFrame(t, &message.ProtocolError{ErrorMessage: "error"})
+	event, err = forwarder.getClientEvent(protocolError, common.ClusterTypeOrigin, now)
+	require.Nil(t, err)
+	require.Equal(t, protocolError, event)
+}
+
+func TestEventForwarder_Virtualization(t *testing.T) {
+	controlConn := &ControlConn{
+		topologyConfig: &common.TopologyConfig{VirtualizationEnabled: true},
+		topologyLock:   &sync.RWMutex{},
+		virtualHosts: []*VirtualHost{
+			{Addr: net.ParseIP("10.0.0.1"), Host: &Host{Address: net.ParseIP("192.168.1.1")}},
+			{Addr: net.ParseIP("10.0.0.2"), Host: &Host{Address: net.ParseIP("192.168.1.2")}},
+		},
+	}
+	forwarder := newEventForwarder(common.EventSourcePolicyPrimaryOnly, common.ClusterTypeOrigin, time.Second,
+		controlConn, 14002, log.WithFields(log.Fields{}))
+	now := time.Now()
+
+	event, err := forwarder.getClientEvent(newEventFrame(t, &message.StatusChangeEvent{
+		ChangeType: primitive.StatusChangeTypeDown,
+		Address:    &primitive.Inet{Addr: net.ParseIP("192.168.1.2"), Port: 9042},
+	}), common.ClusterTypeOrigin, now)
+	require.Nil(t, err)
+	statusChange, ok := decodeEventFrame(t, event).(*message.StatusChangeEvent)
+	require.True(t, ok)
+	require.Equal(t, primitive.StatusChangeTypeDown, statusChange.ChangeType)
+	require.True(t, net.ParseIP("10.0.0.2").Equal(statusChange.Address.Addr))
+	require.Equal(t, int32(14002), statusChange.Address.Port)
+
+	event, err = forwarder.getClientEvent(newEventFrame(t, &message.StatusChangeEvent{
+		ChangeType: primitive.StatusChangeTypeDown,
+		Address:    &primitive.Inet{Addr: net.ParseIP("192.168.1.3"), Port: 9042},
+	}), common.ClusterTypeOrigin, now)
+	require.Nil(t, err)
+	require.Nil(t, event)
+
+	event, err = forwarder.getClientEvent(newEventFrame(t, &message.TopologyChangeEvent{
+		ChangeType: primitive.TopologyChangeTypeNewNode,
+		Address:    &primitive.Inet{Addr: net.ParseIP("192.168.1.4"), Port: 9042},
+	}), common.ClusterTypeOrigin, now)
+	require.Nil(t, err)
+	require.Nil(t, event)
+}
@@ -0,0 +1,281 @@
+package zdmproxy
+
+import (
+	"context"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// failoverConn is the connection of an ORIGIN or TARGET connector when ZDM_REQUEST_CONNECTION_FAILOVER_ENABLED is true.
+// The connection to the cluster can be replaced while the write coalescer and the response loop keep using it.
+//
+// Once failover is enabled, write errors are not returned to the write coalescer. The connection is closed instead so
+// that the response loop starts the failover, the frames of that write are lost and their requests time out.
+// Writes wait while a failover is in progress.
+type failoverConn struct {
+	lock        *sync.Mutex
+	cond        *sync.Cond
+	conn        net.Conn
+	enabled     bool
+	failingOver bool
+	closed      bool
+}
+
+func newFailoverConn(conn net.Conn) *failoverConn {
+	lock := &sync.Mutex{}
+	return &failoverConn{
+		lock: lock,
+		cond: sync.NewCond(lock),
+		conn: conn,
+	}
+}
+
+func (recv *failoverConn) current() net.Conn {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.conn
+}
+
+func (recv *failoverConn) Read(b []byte) (int, error) {
+	return recv.current().Read(b)
+}
+
+func (recv *failoverConn) Write(b []byte) (int, error) {
+	recv.lock.Lock()
+	for recv.failingOver && !recv.closed {
+		recv.cond.Wait()
+	}
+	conn := recv.conn
+	swallowErrors := recv.enabled && !recv.closed
+	recv.lock.Unlock()
+
+	n, err := conn.Write(b)
+	if err != nil && swallowErrors {
+		_ = conn.Close()
+		return len(b), nil
+	}
+	return n, err
+}
+
+func (recv *failoverConn) Close() error {
+	recv.lock.Lock()
+	recv.closed = true
+	recv.cond.Broadcast()
+	conn := recv.conn
+	recv.lock.Unlock()
+	return conn.Close()
+}
+
+func (recv *failoverConn) LocalAddr() net.Addr {
+	return recv.current().LocalAddr()
+}
+
+func (recv *failoverConn) RemoteAddr() net.Addr {
+	return recv.current().RemoteAddr()
+}
+
+func (recv *failoverConn) SetDeadline(t time.Time) error {
+	return recv.current().SetDeadline(t)
+}
+
+func (recv *failoverConn) SetReadDeadline(t time.Time) error {
+	return recv.current().SetReadDeadline(t)
+}
+
+func (recv *failoverConn) SetWriteDeadline(t time.Time) error {
+	return recv.current().SetWriteDeadline(t)
+}
+
+func (recv *failoverConn) enable() {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.enabled = true
+}
+
+// begin blocks the writes until end is called, it returns false if failover is not enabled.
+func (recv *failoverConn) begin() bool {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if !recv.enabled || recv.closed {
+		return false
+	}
+	recv.failingOver = true
+	return true
+}
+
+// end replaces the connection with newConn and unblocks the writes. If newConn is nil the failover was not successful,
+// failover is disabled and write errors are returned again so that the connector is shut down.
+func (recv *failoverConn) end(newConn net.Conn) bool {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	defer recv.cond.Broadcast()
+	recv.failingOver = false
+	if newConn == nil || recv.closed {
+		recv.enabled = false
+		if newConn != nil {
+			_ = newConn.Close()
+		}
+		return false
+	}
+	_ = recv.conn.Close()
+	recv.conn = newConn
+	return true
+}
+
+// requestConnectionFailover reconnects an ORIGIN or TARGET connector to another host of the local datacenter when the
+// connection to its host is lost, see ClusterConnector.failover.
+type requestConnectionFailover struct {
+	conn        *failoverConn
+	connConfig  ConnectionConfig
+	endpoint    Endpoint
+	maxAttempts int
+
+	// set by enable once the client handshake is done
+	controlConn *ControlConn
+	handshake   connectionHandshake
+	successes   metrics.Counter
+	failures    metrics.Counter
+
+	// the longest timeout of a request, see releaseLostStreamIds
+	requestTimeout time.Duration
+}
+
+func newRequestConnectionFailover(
+	conn *failoverConn, connInfo *ClusterConnectionInfo, maxAttempts int) *requestConnectionFailover {
+	return &requestConnectionFailover{
+		conn:        conn,
+		connConfig:  connInfo.connConfig,
+		endpoint:    connInfo.endpoint,
+		maxAttempts: maxAttempts,
+	}
+}
+
+func (recv *requestConnectionFailover) enable(
+	controlConn *ControlConn, handshake connectionHandshake, successes metrics.Counter, failures metrics.Counter,
+	requestTimeout time.Duration) {
+	recv.controlConn = controlConn
+	recv.handshake = handshake
+	recv.successes = successes
+	recv.failures = failures
+	recv.requestTimeout = requestTimeout
+	recv.conn.enable()
+}
+
+// run replaces the connection with a new connection to another host of the local datacenter. It returns false if
+// failover is not enabled or if none of the attempts succeeded.
+func (recv *requestConnectionFailover) run(
+	ctx context.Context, connectorType ClusterConnectorType, logger *log.Entry) bool {
+	if !recv.conn.begin() {
+		return false
+	}
+
+	clusterType := recv.connConfig.GetClusterType()
+	logger.Warnf("[%s] Request connection to %v (%v) was lost, reconnecting to another host of the local datacenter.",
+		connectorType, clusterType, recv.endpoint.GetEndpointIdentifier())
+
+	newConn, newEndpoint := recv.reconnect(ctx, connectorType, logger)
+	if !recv.conn.end(newConn) {
+		recv.failures.Add(1)
+		logger.Errorf("[%s] Could not reconnect to another host of %v, closing the client connection.",
+			connectorType, clusterType)
+		return false
+	}
+
+	recv.successes.Add(1)
+	logger.Infof("[%s] Request connection to %v reconnected from %v to %v.",
+		connectorType, clusterType, recv.endpoint.GetEndpointIdentifier(), newEndpoint.GetEndpointIdentifier())
+	recv.endpoint = newEndpoint
+	return true
+}
+
+func (recv *requestConnectionFailover) reconnect(
+	ctx context.Context, connectorType ClusterConnectorType, logger *log.Entry) (net.Conn, Endpoint) {
+	conn, endpoint, err := openConnectionToOtherHost(
+		ctx, recv.connConfig, recv.controlConn, recv.endpoint, recv.maxAttempts, recv.handshake, connectorType, logger)
+	if err != nil && ctx.Err() == nil {
+		logger.Errorf("[%s] %v.", connectorType, err)
+	}
+	return conn, endpoint
+}
+
+// openConnectionToOtherHost opens a connection to a host of the local datacenter other than excludedEndpoint and
+// initializes it with the handshake. Up to maxAttempts hosts are tried, starting at a random host.
+func openConnectionToOtherHost(
+	ctx context.Context, connConfig ConnectionConfig, controlConn *ControlConn, excludedEndpoint Endpoint,
+	maxAttempts int, handshake connectionHandshake, connectorType ClusterConnectorType,
+	logger *log.Entry) (net.Conn, Endpoint, error) {
+	clusterType := connConfig.GetClusterType()
+	hosts, err := controlConn.GetOrderedHostsInLocalDatacenter()
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not get the hosts of %v: %w", clusterType, err)
+	}
+
+	endpoints := make([]Endpoint, 0, len(hosts))
+	for _, host := range hosts {
+		endpoint := connConfig.CreateEndpoint(host)
+		if endpoint.GetEndpointIdentifier() != excludedEndpoint.GetEndpointIdentifier() {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	if len(endpoints) == 0 {
+		return nil, nil, fmt.Errorf("there are no other hosts in the local datacenter of %v", clusterType)
+	}
+
+	timeout := time.Duration(connConfig.GetConnectionTimeoutMs()) * time.Millisecond
+	firstEndpointIndex := rand.Intn(len(endpoints))
+	for i := 0; i < len(endpoints) && i < maxAttempts; i++ {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+
+		endpoint := endpoints[(firstEndpointIndex+i)%len(endpoints)]
+		conn, _, err := openConnection(connConfig, endpoint, ctx, false)
+		if err != nil {
+			logger.Warnf("[%s] Failed to open request connection to %v using endpoint %v: %v.",
+				connectorType, clusterType, endpoint.GetEndpointIdentifier(), err)
+			continue
+		}
+
+		err = handshake(conn, timeout)
+		if err != nil {
+			logger.Warnf("[%s] Failed to initialize request connection to %v using endpoint %v: %v.",
+				connectorType, clusterType, endpoint.GetEndpointIdentifier(), err)
+			_ = conn.Close()
+			continue
+		}
+		return conn, endpoint, nil
+	}
+	return nil, nil, fmt.Errorf("could not open a request connection to another host of %v", clusterType)
+}
+
+// enableRequestConnectionFailover is called when the client handshake is done, i.e., when the STARTUP request,
+// the credentials and the prepared statements that have to be replayed on a new connection are known.
+func (ch *ClientHandler) enableRequestConnectionFailover() {
+	proxyMetrics := ch.metricHandler.GetProxyMetrics()
+	if ch.originCassandraConnector.failover != nil {
+		ch.originCassandraConnector.failover.enable(
+			ch.originControlConn,
+			func(conn net.Conn, timeout time.Duration) error {
+				return ch.replayHandshake(conn, common.ClusterTypeOrigin, timeout)
+			},
+			proxyMetrics.RequestConnectionFailoversOrigin,
+			proxyMetrics.RequestConnectionFailoversFailedOrigin,
+			ch.requestTimeouts.Max())
+	}
+	if ch.targetCassandraConnector.failover != nil {
+		ch.targetCassandraConnector.failover.enable(
+			ch.targetControlConn,
+			func(conn net.Conn, timeout time.Duration) error {
+				return ch.replayHandshake(conn, common.ClusterTypeTarget, timeout)
+			},
+			proxyMetrics.RequestConnectionFailoversTarget,
+			proxyMetrics.RequestConnectionFailoversFailedTarget,
+			ch.requestTimeouts.Max())
+	}
+}
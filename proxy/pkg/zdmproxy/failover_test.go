@@ -0,0 +1,81 @@
+package zdmproxy
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestFailoverConn_WriteErrors(t *testing.T) {
+	client, server := net.Pipe()
+	require.Nil(t, server.Close())
+	conn := newFailoverConn(client)
+
+	// write errors are returned until failover is enabled
+	_, err := conn.Write([]byte("CQL"))
+	require.NotNil(t, err)
+
+	client, server = net.Pipe()
+	require.Nil(t, server.Close())
+	conn = newFailoverConn(client)
+	conn.enable()
+	n, err := conn.Write([]byte("CQL"))
+	require.Nil(t, err)
+	require.Equal(t, 3, n)
+
+	require.Nil(t, conn.Close())
+	_, err = conn.Write([]byte("CQL"))
+	require.NotNil(t, err)
+}
+
+func TestFailoverConn_Replace(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	conn := newFailoverConn(client)
+	require.False(t, conn.begin())
+
+	conn.enable()
+	require.True(t, conn.begin())
+
+	newClient, newServer := net.Pipe()
+	defer newServer.Close()
+	written := make(chan error, 1)
+	go func() {
+		_, err := conn.Write([]byte("CQL"))
+		written <- err
+	}()
+
+	select {
+	case <-written:
+		require.Fail(t, "write should wait for the failover")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.True(t, conn.end(newClient))
+	data := make([]byte, 3)
+	_, err := newServer.Read(data)
+	require.Nil(t, err)
+	require.Equal(t, "CQL", string(data))
+	require.Nil(t, <-written)
+
+	// the replaced connection is closed
+	_, err = ioutil.ReadAll(server)
+	require.Nil(t, err)
+}
+
+func TestFailoverConn_Failed(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	conn := newFailoverConn(client)
+	conn.enable()
+	require.True(t, conn.begin())
+	require.False(t, conn.end(nil))
+
+	// failover is disabled after an unsuccessful failover
+	require.False(t, conn.begin())
+	require.Nil(t, client.Close())
+	_, err := conn.Write([]byte("CQL"))
+	require.NotNil(t, err)
+}
@@ -0,0 +1,116 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"math/rand"
+	"time"
+)
+
+const injectedErrorMessage = "Error injected by ZDM proxy"
+
+// faultInjector injects artificial latency, dropped responses and error responses in the responses that the request
+// connections of a cluster receive once the client handshake is done, so that teams can rehearse the failures of a
+// cluster (e.g. retries, timeouts and alerting) before the cutover.
+type faultInjector struct {
+	clusterType common.ClusterType
+	config      *common.FaultInjectionConfig
+	rand        *rand.Rand
+
+	latencyFaults metrics.Counter
+	dropFaults    metrics.Counter
+	errorFaults   metrics.Counter
+}
+
+func newFaultInjector(
+	clusterType common.ClusterType, config *common.FaultInjectionConfig,
+	metricFactory metrics.MetricFactory) (*faultInjector, error) {
+
+	labels := map[string]string{metrics.InjectedFaultsClusterLabel: string(clusterType)}
+	counters := make([]metrics.Counter, 0, 3)
+	for _, mn := range []metrics.Metric{
+		metrics.InjectedFaultsLatency,
+		metrics.InjectedFaultsDrop,
+		metrics.InjectedFaultsError,
+	} {
+		counter, err := metricFactory.GetOrCreateCounter(mn.WithLabels(labels))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create fault injection metrics of %v: %w", clusterType, err)
+		}
+		counters = append(counters, counter)
+	}
+	return &faultInjector{
+		clusterType:   clusterType,
+		config:        config,
+		rand:          NewThreadSafeRand(),
+		latencyFaults: counters[0],
+		dropFaults:    counters[1],
+		errorFaults:   counters[2],
+	}, nil
+}
+
+// inject returns the response that is forwarded to the client handler (nil if the response is dropped) and the delay
+// before it is forwarded. A dropped response is handled like a response that was never received, i.e. the request
+// times out.
+func (recv *faultInjector) inject(response *frame.RawFrame) (*frame.RawFrame, time.Duration, error) {
+	if recv.roll(recv.config.DropProbability) {
+		recv.dropFaults.Add(1)
+		return nil, 0, nil
+	}
+
+	if recv.roll(recv.config.ErrorProbability) {
+		errorResponse, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(
+			response.Header.Version, response.Header.StreamId, newInjectedError(recv.config.ErrorType)))
+		if err != nil {
+			return nil, 0, fmt.Errorf("could not encode injected %v error: %w", recv.config.ErrorType, err)
+		}
+		recv.errorFaults.Add(1)
+		response = errorResponse
+	}
+
+	var delay time.Duration
+	if recv.roll(recv.config.LatencyProbability) {
+		recv.latencyFaults.Add(1)
+		delay = recv.config.Latency
+	}
+	return response, delay, nil
+}
+
+func (recv *faultInjector) roll(probability float64) bool {
+	return probability > 0 && recv.rand.Float64() < probability
+}
+
+func newInjectedError(errorType common.FaultErrorType) message.Error {
+	switch errorType {
+	case common.FaultErrorTypeServerError:
+		return &message.ServerError{ErrorMessage: injectedErrorMessage}
+	case common.FaultErrorTypeUnavailable:
+		return &message.Unavailable{
+			ErrorMessage: injectedErrorMessage,
+			Consistency:  primitive.ConsistencyLevelLocalQuorum,
+			Required:     2,
+			Alive:        1,
+		}
+	case common.FaultErrorTypeReadTimeout:
+		return &message.ReadTimeout{
+			ErrorMessage: injectedErrorMessage,
+			Consistency:  primitive.ConsistencyLevelLocalQuorum,
+			Received:     1,
+			BlockFor:     2,
+		}
+	case common.FaultErrorTypeWriteTimeout:
+		return &message.WriteTimeout{
+			ErrorMessage: injectedErrorMessage,
+			Consistency:  primitive.ConsistencyLevelLocalQuorum,
+			Received:     1,
+			BlockFor:     2,
+			WriteType:    primitive.WriteTypeSimple,
+		}
+	default:
+		return &message.Overloaded{ErrorMessage: injectedErrorMessage}
+	}
+}
@@ -0,0 +1,82 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics/noopmetrics"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestFaultInjector(t *testing.T) {
+	response, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 12, &message.VoidResult{}))
+	require.Nil(t, err)
+
+	tests := []struct {
+		name          string
+		config        *common.FaultInjectionConfig
+		expectedDelay time.Duration
+		expectedMsg   message.Message
+		dropped       bool
+	}{
+		{
+			name:        "no fault",
+			config:      &common.FaultInjectionConfig{Latency: time.Second, ErrorType: common.FaultErrorTypeOverloaded},
+			expectedMsg: &message.VoidResult{},
+		},
+		{
+			name: "latency",
+			config: &common.FaultInjectionConfig{
+				LatencyProbability: 1, Latency: time.Second, ErrorType: common.FaultErrorTypeOverloaded},
+			expectedDelay: time.Second,
+			expectedMsg:   &message.VoidResult{},
+		},
+		{
+			name: "drop",
+			config: &common.FaultInjectionConfig{
+				LatencyProbability: 1, Latency: time.Second, DropProbability: 1, ErrorProbability: 1,
+				ErrorType: common.FaultErrorTypeOverloaded},
+			dropped: true,
+		},
+		{
+			name: "error",
+			config: &common.FaultInjectionConfig{
+				ErrorProbability: 1, ErrorType: common.FaultErrorTypeWriteTimeout},
+			expectedMsg: &message.WriteTimeout{
+				ErrorMessage: injectedErrorMessage,
+				Consistency:  primitive.ConsistencyLevelLocalQuorum,
+				Received:     1,
+				BlockFor:     2,
+				WriteType:    primitive.WriteTypeSimple,
+			},
+		},
+		{
+			name: "delayed error",
+			config: &common.FaultInjectionConfig{
+				LatencyProbability: 1, Latency: time.Second, ErrorProbability: 1,
+				ErrorType: common.FaultErrorTypeOverloaded},
+			expectedDelay: time.Second,
+			expectedMsg:   &message.Overloaded{ErrorMessage: injectedErrorMessage},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			injector, err := newFaultInjector(common.ClusterTypeTarget, tt.config, noopmetrics.NewNoopMetricFactory())
+			require.Nil(t, err)
+			injectedResponse, delay, err := injector.inject(response)
+			require.Nil(t, err)
+			if tt.dropped {
+				require.Nil(t, injectedResponse)
+				return
+			}
+			require.Equal(t, tt.expectedDelay, delay)
+			require.Equal(t, response.Header.StreamId, injectedResponse.Header.StreamId)
+			decodedResponse, err := defaultCodec.ConvertFromRawFrame(injectedResponse)
+			require.Nil(t, err)
+			require.Equal(t, tt.expectedMsg, decodedResponse.Body.Message)
+		})
+	}
+}
@@ -19,6 +19,18 @@ var defaultCodec = frame.NewRawCodec()
 
 var ShutdownErr = &shutdownError{err: "aborted due to shutdown request"}
 
+// frameTooLargeError is returned by readRawFrame when the body of a frame exceeds the maximum size, the body has been
+// discarded at that point so the next frame can still be read from the connection.
+type frameTooLargeError struct {
+	header      *frame.Header
+	maxBodySize int
+}
+
+func (e *frameTooLargeError) Error() string {
+	return fmt.Sprintf("frame body of %d bytes (stream id %d, opcode %v) exceeds the maximum of %d bytes",
+		e.header.BodyLength, e.header.StreamId, e.header.OpCode, e.maxBodySize)
+}
+
 func adaptConnErr(connectionAddr string, clientHandlerContext context.Context, err error) error {
 	if err != nil {
 		if clientHandlerContext.Err() != nil {
@@ -37,12 +49,28 @@ func writeRawFrame(writer io.Writer, connectionAddr string, clientHandlerContext
 	return adaptConnErr(connectionAddr, clientHandlerContext, err)
 }
 
-// Simple function that reads data from a connection and builds a frame
-func readRawFrame(reader io.Reader, connectionAddr string, clientHandlerContext context.Context) (*frame.RawFrame, error) {
-	rawFrame, err := defaultCodec.DecodeRawFrame(reader)
+// Simple function that reads data from a connection and builds a frame.
+// Bodies larger than maxBodySize are skipped without being buffered and a *frameTooLargeError is returned instead,
+// the size is not limited if maxBodySize is 0.
+func readRawFrame(
+	reader io.Reader, connectionAddr string, clientHandlerContext context.Context, maxBodySize int) (*frame.RawFrame, error) {
+	header, err := defaultCodec.DecodeHeader(reader)
+	if err != nil {
+		return nil, adaptConnErr(connectionAddr, clientHandlerContext, fmt.Errorf("cannot decode frame header: %w", err))
+	}
+
+	if maxBodySize > 0 && int(header.BodyLength) > maxBodySize {
+		err = defaultCodec.DiscardBody(header, reader)
+		if err != nil {
+			return nil, adaptConnErr(connectionAddr, clientHandlerContext, fmt.Errorf("cannot discard frame body: %w", err))
+		}
+		return nil, &frameTooLargeError{header: header, maxBodySize: maxBodySize}
+	}
+
+	body, err := defaultCodec.DecodeRawBody(header, reader)
 	if err != nil {
-		return nil, adaptConnErr(connectionAddr, clientHandlerContext, err)
+		return nil, adaptConnErr(connectionAddr, clientHandlerContext, fmt.Errorf("cannot read frame body: %w", err))
 	}
 
-	return rawFrame, nil
+	return &frame.RawFrame{Header: header, Body: body}, nil
 }
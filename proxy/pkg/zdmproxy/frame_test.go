@@ -0,0 +1,57 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestReadRawFrame_MaxBodySize(t *testing.T) {
+	largeQuery := frame.NewFrame(primitive.ProtocolVersion4, 1, &message.Query{Query: strings.Repeat("a", 1000)})
+	smallQuery := frame.NewFrame(primitive.ProtocolVersion4, 2, &message.Query{Query: "SELECT * FROM ks.tb"})
+
+	buf := &bytes.Buffer{}
+	require.Nil(t, defaultCodec.EncodeFrame(largeQuery, buf))
+	require.Nil(t, defaultCodec.EncodeFrame(smallQuery, buf))
+	encoded := buf.Bytes()
+
+	tests := []struct {
+		name           string
+		maxBodySize    int
+		expectTooLarge bool
+	}{
+		{"no limit", 0, false},
+		{"below limit", 2000, false},
+		{"above limit", 500, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := bytes.NewReader(encoded)
+
+			f, err := readRawFrame(reader, "127.0.0.1:9042", context.Background(), tt.maxBodySize)
+			if tt.expectTooLarge {
+				require.Nil(t, f)
+				tooLargeErr, ok := err.(*frameTooLargeError)
+				require.True(t, ok, "unexpected error: %v", err)
+				require.Equal(t, int16(1), tooLargeErr.header.StreamId)
+				require.Equal(t, tt.maxBodySize, tooLargeErr.maxBodySize)
+			} else {
+				require.Nil(t, err)
+				require.Equal(t, int16(1), f.Header.StreamId)
+			}
+
+			// the body of the oversized frame was skipped so the next frame can be read
+			f, err = readRawFrame(reader, "127.0.0.1:9042", context.Background(), tt.maxBodySize)
+			require.Nil(t, err)
+			require.Equal(t, int16(2), f.Header.StreamId)
+			decoded, err := defaultCodec.ConvertFromRawFrame(f)
+			require.Nil(t, err)
+			require.Equal(t, "SELECT * FROM ks.tb", decoded.Body.Message.(*message.Query).Query)
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"net"
+	"time"
+)
+
+// rePrepareBatchSize is the number of PREPARE requests that are sent before waiting for their responses when the
+// cached statements are prepared on a new connection.
+const rePrepareBatchSize = 128
+
+// connectionHandshake initializes a new connection to a cluster in the same state as the request connection of the
+// client handler to that cluster.
+type connectionHandshake func(conn net.Conn, timeout time.Duration) error
+
+// replayHandshake sends the STARTUP request of the client, authenticates with the credentials that were used for this
+// cluster during the client handshake, sets the current keyspace and prepares the cached statements on a new connection.
+func (ch *ClientHandler) replayHandshake(conn net.Conn, clusterType common.ClusterType, timeout time.Duration) error {
+	if ch.startupRequest == nil {
+		return fmt.Errorf("the STARTUP request of the client is not known")
+	}
+	version := ch.startupRequest.Header.Version
+	connector := ch.originCassandraConnector
+	if clusterType == common.ClusterTypeTarget {
+		connector = ch.targetCassandraConnector
+	}
+	if translator := connector.getProtocolVersionTranslator(); translator != nil {
+		version = translator.clusterVersion
+	}
+
+	err := conn.SetDeadline(time.Now().Add(timeout))
+	if err != nil {
+		return err
+	}
+
+	startup, err := defaultCodec.ConvertFromRawFrame(ch.startupRequest)
+	if err != nil {
+		return fmt.Errorf("could not decode the STARTUP request of the client: %w", err)
+	}
+	startup.Header = startup.Header.Clone()
+	startup.Header.Version = version
+
+	response, err := exchangeFrame(conn, startup)
+	authenticator := &DsePlainTextAuthenticator{Credentials: ch.getHandshakeCredentials(clusterType)}
+	for err == nil && (response.Header.OpCode == primitive.OpCodeAuthenticate ||
+		response.Header.OpCode == primitive.OpCodeAuthChallenge) {
+		var authResponse *frame.Frame
+		authResponse, err = performHandshakeStep(authenticator, version, 0, response)
+		if err == nil {
+			response, err = exchangeFrame(conn, authResponse)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	switch response.Body.Message.(type) {
+	case *message.Ready, *message.AuthSuccess:
+	default:
+		return fmt.Errorf("unexpected handshake response: %v", response.Body.Message)
+	}
+
+	if keyspace := ch.LoadCurrentKeyspace(); keyspace != "" {
+		if clusterType == common.ClusterTypeTarget && ch.targetNameMapper != nil {
+			keyspace = ch.targetNameMapper.mapKeyspace(keyspace)
+		}
+		response, err = exchangeFrame(conn, frame.NewFrame(
+			version, 0, &message.Query{Query: "USE " + formatIdentifier(keyspace)}))
+		if err != nil {
+			return err
+		}
+		if _, ok := response.Body.Message.(*message.SetKeyspaceResult); !ok {
+			return fmt.Errorf("unexpected response to USE %v: %v", keyspace, response.Body.Message)
+		}
+	}
+
+	err = ch.rePrepareCachedStatements(conn, clusterType, version, timeout)
+	if err != nil {
+		return err
+	}
+	return conn.SetDeadline(time.Time{})
+}
+
+// rePrepareCachedStatements prepares the statements of the prepared statement cache on a new connection so that the
+// EXECUTE requests of the client don't fail with UNPREPARED. Statements that can't be prepared on this cluster are
+// ignored, their EXECUTE requests get the UNPREPARED error of the cluster.
+func (ch *ClientHandler) rePrepareCachedStatements(
+	conn net.Conn, clusterType common.ClusterType, version primitive.ProtocolVersion, timeout time.Duration) error {
+	statements := ch.preparedStatementCache.GetAll()
+	for start := 0; start < len(statements); start += rePrepareBatchSize {
+		end := start + rePrepareBatchSize
+		if end > len(statements) {
+			end = len(statements)
+		}
+
+		err := conn.SetDeadline(time.Now().Add(timeout))
+		if err != nil {
+			return err
+		}
+
+		sent := 0
+		for i, preparedData := range statements[start:end] {
+			prepareRequestInfo := preparedData.GetPrepareRequestInfo()
+			prepareRawFrame, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(version, int16(i), &message.Prepare{
+				Query:    prepareRequestInfo.GetQuery(),
+				Keyspace: prepareRequestInfo.GetKeyspace(),
+			}))
+			if err == nil && ch.queryRewriter != nil {
+				prepareRawFrame, err = ch.queryRewriter.rewriteRequest(
+					prepareRawFrame, clusterType, prepareRequestInfo.GetKeyspace(), ch.logger)
+			}
+			if err == nil && clusterType == common.ClusterTypeTarget && ch.targetNameMapper != nil {
+				prepareRawFrame, err = ch.targetNameMapper.mapRequest(
+					prepareRawFrame, prepareRequestInfo.GetKeyspace(), ch.logger)
+			}
+			if err != nil {
+				ch.logger.Debugf("Could not re-prepare %v on %v on a new connection: %v",
+					redactedStatement(prepareRequestInfo.GetQuery()), clusterType, err)
+				continue
+			}
+			err = defaultCodec.EncodeRawFrame(prepareRawFrame, conn)
+			if err != nil {
+				return err
+			}
+			sent++
+		}
+
+		for ; sent > 0; sent-- {
+			response, err := defaultCodec.DecodeFrame(conn)
+			if err != nil {
+				return err
+			}
+			if errMsg, ok := response.Body.Message.(message.Error); ok {
+				ch.logger.Debugf("Could not re-prepare %v on %v on a new connection: %v",
+					statements[start+int(response.Header.StreamId)].GetPrepareRequestInfo().GetQuery(),
+					clusterType, errMsg)
+			}
+		}
+	}
+	return nil
+}
+
+// getHandshakeCredentials returns the credentials that were used to authenticate the connection to the given cluster
+// during the client handshake.
+func (ch *ClientHandler) getHandshakeCredentials(clusterType common.ClusterType) *AuthCredentials {
+	primaryCluster := common.ClusterTypeOrigin
+	if ch.forwardAuthToTarget {
+		primaryCluster = common.ClusterTypeTarget
+	}
+	if clusterType != primaryCluster && ch.secondaryHandshakeCreds != nil {
+		return ch.secondaryHandshakeCreds
+	}
+	if clusterType == primaryCluster && ch.primaryHandshakeCreds != nil {
+		return ch.primaryHandshakeCreds
+	}
+	return ch.getClusterCredentials(clusterType)
+}
+
+// exchangeFrame sends a request on a connection that is not used by a connector yet and reads its response.
+func exchangeFrame(conn net.Conn, request *frame.Frame) (*frame.Frame, error) {
+	err := defaultCodec.EncodeFrame(request, conn)
+	if err != nil {
+		return nil, err
+	}
+	return defaultCodec.DecodeFrame(conn)
+}
@@ -0,0 +1,120 @@
+package zdmproxy
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// heartbeatStreamId is the stream id of the OPTIONS requests that are sent on idle request connections. Clients only
+// use positive stream ids (negative ones are reserved for messages initiated by the server) so a heartbeat response
+// can not be mistaken for the response of a client request.
+const heartbeatStreamId = int16(-2)
+
+// startHeartbeats sends an OPTIONS request every time the connection has not received anything from the cluster for
+// ZDM_REQUEST_CONNECTION_HEARTBEAT_INTERVAL_MS so that NATs and load balancers don't close it while it is idle.
+//
+// If the cluster doesn't reply within ZDM_REQUEST_CONNECTION_HEARTBEAT_TIMEOUT_MS the connector is shut down. For the
+// Origin and Target connectors this closes the client connection so that the driver opens a new one (with new
+// connections to both clusters) while the async connector is shut down like it is when any other error occurs on it.
+// With ZDM_REQUEST_CONNECTION_FAILOVER_ENABLED the Origin and Target connectors reconnect to another host instead.
+//
+// The heartbeats are tracked by requestWg so that the write queue of the connector is not closed while a heartbeat
+// is being sent.
+func (cc *ClusterConnector) startHeartbeats(
+	version primitive.ProtocolVersion, shutdownRequestCtx context.Context, requestWg *sync.WaitGroup) {
+	if cc.heartbeatInterval <= 0 {
+		return
+	}
+
+	requestWg.Add(1)
+	go func() {
+		defer requestWg.Done()
+		for {
+			idle := time.Since(time.Unix(0, atomic.LoadInt64(cc.lastReadNanos)))
+			wait := cc.heartbeatInterval - idle
+			if wait <= 0 {
+				if !cc.sendHeartbeat(version, shutdownRequestCtx) {
+					return
+				}
+				wait = cc.heartbeatInterval
+			}
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-cc.clusterConnContext.Done():
+				timer.Stop()
+				return
+			case <-shutdownRequestCtx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// sendHeartbeat sends a heartbeat and waits for the response. It returns false if the heartbeats should stop.
+func (cc *ClusterConnector) sendHeartbeat(version primitive.ProtocolVersion, shutdownRequestCtx context.Context) bool {
+	if cc.IsShutdown() {
+		return false
+	}
+
+	heartbeat, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(version, heartbeatStreamId, &message.Options{}))
+	if err == nil {
+		heartbeat, err = cc.translateRequest(heartbeat)
+	}
+	if err != nil {
+		cc.logger.Errorf("[%s] Could not encode heartbeat, heartbeats will not be sent: %v.", cc.connectorType, err)
+		return false
+	}
+
+	// discard the response of a previous heartbeat that arrived after the connector stopped waiting for it
+	select {
+	case <-cc.heartbeatResponses:
+	default:
+	}
+
+	if !cc.writeCoalescer.EnqueueAsync(heartbeat) {
+		// the connection is not idle if the write queue is full
+		return true
+	}
+	cc.logger.Tracef("[%s] Heartbeat sent to %v.", cc.connectorType, cc.clusterType)
+
+	timer := time.NewTimer(cc.heartbeatTimeout)
+	defer timer.Stop()
+	select {
+	case response := <-cc.heartbeatResponses:
+		if response.Header.OpCode != primitive.OpCodeSupported {
+			cc.logger.Debugf("[%s] Expected SUPPORTED but got %v. Considering this a successful heartbeat regardless.",
+				cc.connectorType, response.Header.OpCode)
+		} else {
+			cc.logger.Tracef("[%s] Heartbeat successful on %v.", cc.connectorType, cc.clusterType)
+		}
+		return true
+	case <-timer.C:
+		nodeMetrics, err := GetNodeMetricsByClusterConnector(cc.nodeMetrics, cc.connectorType)
+		if err != nil {
+			cc.logger.Errorf("Failed to track heartbeat failure metrics: %v.", err)
+		} else {
+			nodeMetrics.HeartbeatFailures.Add(1)
+		}
+		cc.logger.Warnf("[%s] Heartbeat to %v (%v) timed out after %v, closing the connection.",
+			cc.connectorType, cc.clusterType, cc.connection.RemoteAddr(), cc.heartbeatTimeout)
+		if cc.failover != nil {
+			// the response loop reconnects to another host or shuts down the connector if it can't
+			_ = cc.failover.conn.current().Close()
+			return true
+		}
+		cc.Shutdown()
+		return false
+	case <-cc.clusterConnContext.Done():
+		return false
+	case <-shutdownRequestCtx.Done():
+		return false
+	}
+}
@@ -48,23 +48,24 @@ func (recv *Host) String() string {
 		hex.EncodeToString(recv.HostId[:]))
 }
 
-func ParseSystemLocalResult(rs *ParsedRowSet, defaultPort int) (map[string]*optionalColumn, *Host, error) {
+func ParseSystemLocalResult(
+	rs *ParsedRowSet, defaultPort int, logger *log.Entry) (map[string]*optionalColumn, *Host, error) {
 	if len(rs.Rows) < 1 {
 		return nil, nil, fmt.Errorf("could not parse system local query result: query returned %d rows", len(rs.Rows))
 	}
 
 	if len(rs.Rows) > 1 {
-		log.Warnf("system local query result returned %d rows", len(rs.Rows))
+		logger.Warnf("system local query result returned %d rows", len(rs.Rows))
 	}
 
 	row := rs.Rows[0]
 
-	addr, port, err := ParseRpcAddress(false, row, defaultPort)
+	addr, port, err := ParseRpcAddress(false, row, defaultPort, logger)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	host, err := parseHost(addr, port, row)
+	host, err := parseHost(addr, port, row, logger)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -82,30 +83,30 @@ func ParseSystemLocalResult(rs *ParsedRowSet, defaultPort int) (map[string]*opti
 	}
 
 	if clusterName, exists := sysLocalCols[clusterNameColumn.Name]; !exists || clusterName.column == nil {
-		log.Warnf("could not get %v using host %v", clusterNameColumn.Name, addr)
+		logger.Warnf("could not get %v using host %v", clusterNameColumn.Name, addr)
 	}
 
 	return sysLocalCols, host, nil
 }
 
-func ParseSystemPeersResult(rs *ParsedRowSet, defaultPort int, isPeersV2 bool) map[uuid.UUID]*Host {
+func ParseSystemPeersResult(rs *ParsedRowSet, defaultPort int, isPeersV2 bool, logger *log.Entry) map[uuid.UUID]*Host {
 	hosts := make(map[uuid.UUID]*Host)
 	for _, row := range rs.Rows {
-		addr, port, err := ParseRpcAddress(isPeersV2, row, defaultPort)
+		addr, port, err := ParseRpcAddress(isPeersV2, row, defaultPort, logger)
 		if err != nil {
-			log.Warnf("error parsing peer host address, skipping it: %v", err)
+			logger.Warnf("error parsing peer host address, skipping it: %v", err)
 			continue
 		}
 
-		host, err := parseHost(addr, port, row)
+		host, err := parseHost(addr, port, row, logger)
 		if err != nil {
-			log.Warnf("error parsing information of peer host %v:%d, skipping it: %v", addr, port, err)
+			logger.Warnf("error parsing information of peer host %v:%d, skipping it: %v", addr, port, err)
 			continue
 		}
 
 		oldHost, hostExists := hosts[host.HostId]
 		if hostExists {
-			log.Warnf("Duplicate host found: %v vs %v. Ignoring the former one.", oldHost, host)
+			logger.Warnf("Duplicate host found: %v vs %v. Ignoring the former one.", oldHost, host)
 		}
 		hosts[host.HostId] = host
 	}
@@ -113,7 +114,7 @@ func ParseSystemPeersResult(rs *ParsedRowSet, defaultPort int, isPeersV2 bool) m
 	return hosts
 }
 
-func parseHost(addr net.IP, port int, row *ParsedRow) (*Host, error) {
+func parseHost(addr net.IP, port int, row *ParsedRow, logger *log.Entry) (*Host, error) {
 	datacenter, err := parseString(row, "data_center")
 	if err != nil {
 		return nil, fmt.Errorf("could not parse data_center of host %v: %w", addr, err)
@@ -138,9 +139,9 @@ func parseHost(addr net.IP, port int, row *ParsedRow) (*Host, error) {
 	schemaId, _, err := parseNillableUuid(row, "schema_version")
 	if schemaId == nil {
 		if err != nil {
-			log.Warnf("could not parse schema_version for host %v: %v", addr, err)
+			logger.Warnf("could not parse schema_version for host %v: %v", addr, err)
 		} else {
-			log.Warnf("schema_version for host %v is nil", addr)
+			logger.Warnf("schema_version for host %v is nil", addr)
 		}
 	}
 
@@ -171,7 +172,7 @@ func parseHost(addr net.IP, port int, row *ParsedRow) (*Host, error) {
 		columnData), nil
 }
 
-func ParseRpcAddress(isPeersV2 bool, row *ParsedRow, defaultPort int) (net.IP, int, error) {
+func ParseRpcAddress(isPeersV2 bool, row *ParsedRow, defaultPort int, logger *log.Entry) (net.IP, int, error) {
 	var addr net.IP
 
 	if isPeersV2 {
@@ -197,7 +198,7 @@ func ParseRpcAddress(isPeersV2 bool, row *ParsedRow, defaultPort int) (net.IP, i
 					"because of this, the proxy can not connect to this node")
 		}
 
-		log.Infof("Found host with 0.0.0.0 as rpc_address, using listen_address (%v) to contact it instead. "+
+		logger.Infof("Found host with 0.0.0.0 as rpc_address, using listen_address (%v) to contact it instead. "+
 			"If this is incorrect you should avoid the use of 0.0.0.0 server side.", addr)
 	}
 
@@ -205,7 +206,7 @@ func ParseRpcAddress(isPeersV2 bool, row *ParsedRow, defaultPort int) (net.IP, i
 	if isPeersV2 {
 		val, ok := parseRpcPortPeersV2(row)
 		if !ok {
-			log.Warnf(
+			logger.Warnf(
 				"Found host with NULL native_port, using default port (%v) to contact it instead.", rpcPort)
 		} else {
 			rpcPort = val
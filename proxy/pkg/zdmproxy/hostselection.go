@@ -0,0 +1,131 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/google/uuid"
+	"sync"
+	"sync/atomic"
+)
+
+// HostSelectionPolicy selects the host of a cluster that a new client connection is assigned to when host assignment
+// is enabled for that cluster (ZDM_ORIGIN_ENABLE_HOST_ASSIGNMENT and ZDM_TARGET_ENABLE_HOST_ASSIGNMENT). The built-in
+// policies are selected with ZDM_ORIGIN_HOST_SELECTION_POLICY and ZDM_TARGET_HOST_SELECTION_POLICY, other policies
+// (e.g. one that uses the Tokens of the hosts) can be registered with ZdmProxy.SetHostSelectionPolicy.
+//
+// SelectHost is called concurrently by the client connections that are being opened.
+type HostSelectionPolicy interface {
+	// Name is the name of the policy that is used in the logs.
+	Name() string
+
+	// SelectHost returns one of the hosts that are assigned to this proxy instance, connections[i] is the number of
+	// open client connections of this proxy instance that are assigned to hosts[i]. The slices are never empty.
+	SelectHost(hosts []*Host, connections []int) *Host
+}
+
+// NewHostSelectionPolicy returns the built-in policy of ZDM_ORIGIN_HOST_SELECTION_POLICY or
+// ZDM_TARGET_HOST_SELECTION_POLICY.
+func NewHostSelectionPolicy(policy common.HostSelectionPolicy) (HostSelectionPolicy, error) {
+	switch policy {
+	case common.HostSelectionPolicyRoundRobin:
+		return NewRoundRobinHostSelectionPolicy(), nil
+	case common.HostSelectionPolicyLeastConnections:
+		return NewLeastConnectionsHostSelectionPolicy(), nil
+	default:
+		return nil, fmt.Errorf("unknown host selection policy: %v", policy)
+	}
+}
+
+type roundRobinHostSelectionPolicy struct {
+	counter int64
+}
+
+// NewRoundRobinHostSelectionPolicy returns a policy that assigns the client connections to each host in turn.
+func NewRoundRobinHostSelectionPolicy() HostSelectionPolicy {
+	return &roundRobinHostSelectionPolicy{}
+}
+
+func (recv *roundRobinHostSelectionPolicy) Name() string {
+	return common.HostSelectionPolicyRoundRobin.String()
+}
+
+func (recv *roundRobinHostSelectionPolicy) SelectHost(hosts []*Host, _ []int) *Host {
+	value := atomic.AddInt64(&recv.counter, 1) % int64(len(hosts))
+	if value == 0 {
+		atomic.AddInt64(&recv.counter, int64(-len(hosts)))
+	}
+	return hosts[value]
+}
+
+type leastConnectionsHostSelectionPolicy struct {
+	counter int64
+}
+
+// NewLeastConnectionsHostSelectionPolicy returns a policy that assigns each client connection to the host with the
+// fewest client connections, which balances the connections again after some hosts were restarted (round robin
+// keeps the connections that were moved to the other hosts while a host was down on those hosts). Ties are broken
+// in turn so that the connections of a proxy that was just started are spread over all the hosts.
+func NewLeastConnectionsHostSelectionPolicy() HostSelectionPolicy {
+	return &leastConnectionsHostSelectionPolicy{}
+}
+
+func (recv *leastConnectionsHostSelectionPolicy) Name() string {
+	return common.HostSelectionPolicyLeastConnections.String()
+}
+
+func (recv *leastConnectionsHostSelectionPolicy) SelectHost(hosts []*Host, connections []int) *Host {
+	start := int(uint64(atomic.AddInt64(&recv.counter, 1)) % uint64(len(hosts)))
+	selected := start
+	for i := 1; i < len(hosts); i++ {
+		current := (start + i) % len(hosts)
+		if connections[current] < connections[selected] {
+			selected = current
+		}
+	}
+	return hosts[selected]
+}
+
+// hostAssignments keeps track of the client connections that are assigned to each host of a cluster so that the
+// host selection policy can use them.
+type hostAssignments struct {
+	policy      HostSelectionPolicy
+	lock        *sync.Mutex
+	connections map[uuid.UUID]int
+}
+
+func newHostAssignments(policy HostSelectionPolicy) *hostAssignments {
+	return &hostAssignments{
+		policy:      policy,
+		lock:        &sync.Mutex{},
+		connections: map[uuid.UUID]int{},
+	}
+}
+
+// assign selects one of the hosts with the policy and counts a connection for it, the connection must be released
+// with release when it is closed.
+func (recv *hostAssignments) assign(hosts []*Host) (*Host, error) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	connections := make([]int, len(hosts))
+	for i, host := range hosts {
+		connections[i] = recv.connections[host.HostId]
+	}
+	selected := recv.policy.SelectHost(hosts, connections)
+	if selected == nil {
+		return nil, fmt.Errorf("host selection policy %v did not select a host out of %v", recv.policy.Name(), hosts)
+	}
+	recv.connections[selected.HostId]++
+	return selected, nil
+}
+
+func (recv *hostAssignments) release(host *Host) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	if recv.connections[host.HostId] <= 1 {
+		delete(recv.connections, host.HostId)
+	} else {
+		recv.connections[host.HostId]--
+	}
+}
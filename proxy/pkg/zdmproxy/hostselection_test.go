@@ -0,0 +1,112 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func newTestHosts(count int) []*Host {
+	hosts := make([]*Host, count)
+	for i := range hosts {
+		hosts[i] = &Host{HostId: uuid.New()}
+	}
+	return hosts
+}
+
+func TestRoundRobinHostSelectionPolicy(t *testing.T) {
+	hosts := newTestHosts(3)
+	assignments := newHostAssignments(NewRoundRobinHostSelectionPolicy())
+
+	counts := map[*Host]int{}
+	for i := 0; i < 9; i++ {
+		host, err := assignments.assign(hosts)
+		require.Nil(t, err)
+		counts[host]++
+	}
+	for _, host := range hosts {
+		require.Equal(t, 3, counts[host])
+	}
+}
+
+func TestLeastConnectionsHostSelectionPolicy(t *testing.T) {
+	hosts := newTestHosts(3)
+	assignments := newHostAssignments(NewLeastConnectionsHostSelectionPolicy())
+
+	// ties are broken in turn
+	assigned := make([]*Host, 0)
+	for i := 0; i < 3; i++ {
+		host, err := assignments.assign(hosts)
+		require.Nil(t, err)
+		assigned = append(assigned, host)
+	}
+	require.ElementsMatch(t, hosts, assigned)
+
+	// the connections of a host that was restarted were moved to the other hosts
+	assignments.release(hosts[0])
+	for i := 0; i < 4; i++ {
+		_, err := assignments.assign(hosts[1:])
+		require.Nil(t, err)
+	}
+	require.Equal(t, 0, assignments.connections[hosts[0].HostId])
+	require.Equal(t, 3, assignments.connections[hosts[1].HostId])
+	require.Equal(t, 3, assignments.connections[hosts[2].HostId])
+
+	// new connections are assigned to the restarted host until it has as many connections as the others
+	for i := 0; i < 3; i++ {
+		host, err := assignments.assign(hosts)
+		require.Nil(t, err)
+		require.Equal(t, hosts[0], host)
+	}
+	host, err := assignments.assign(hosts)
+	require.Nil(t, err)
+	require.Equal(t, 4, assignments.connections[host.HostId])
+
+	for _, h := range hosts {
+		for assignments.connections[h.HostId] > 0 {
+			assignments.release(h)
+		}
+	}
+	require.Empty(t, assignments.connections)
+}
+
+type firstHostSelectionPolicy struct{}
+
+func (recv *firstHostSelectionPolicy) Name() string {
+	return "FIRST"
+}
+
+func (recv *firstHostSelectionPolicy) SelectHost(hosts []*Host, _ []int) *Host {
+	return hosts[0]
+}
+
+type noHostSelectionPolicy struct{}
+
+func (recv *noHostSelectionPolicy) Name() string {
+	return "NONE"
+}
+
+func (recv *noHostSelectionPolicy) SelectHost([]*Host, []int) *Host {
+	return nil
+}
+
+func TestCustomHostSelectionPolicy(t *testing.T) {
+	hosts := newTestHosts(2)
+
+	host, err := newHostAssignments(&firstHostSelectionPolicy{}).assign(hosts)
+	require.Nil(t, err)
+	require.Equal(t, hosts[0], host)
+
+	_, err = newHostAssignments(&noHostSelectionPolicy{}).assign(hosts)
+	require.NotNil(t, err)
+}
+
+func TestNewHostSelectionPolicy(t *testing.T) {
+	policy, err := NewHostSelectionPolicy(common.HostSelectionPolicyLeastConnections)
+	require.Nil(t, err)
+	require.Equal(t, "LEAST_CONNECTIONS", policy.Name())
+
+	_, err = NewHostSelectionPolicy(common.HostSelectionPolicyUndefined)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,150 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+)
+
+// InterceptorRequest is a request received from a client that is passed to the interceptors.
+type InterceptorRequest struct {
+	// Frame is the decoded request, it is shared by the interceptors so it must not be modified. An interceptor that
+	// rewrites the request returns a modified Frame.Clone() instead.
+	Frame *frame.Frame
+
+	// Keyspace is the current keyspace of the client connection, empty until the client sends a USE request.
+	Keyspace string
+
+	ClientAddress string
+}
+
+// Interceptor is an extension point for programs that embed the proxy, interceptors are registered with
+// ZdmProxy.AddInterceptor before the proxy is started.
+//
+// Intercept is called for every request that a client sends after the handshake, before the proxy parses it. It
+// returns:
+//   - nil, nil, nil to let the request through unchanged;
+//   - a new request that replaces the original one for the next interceptors and for the proxy (e.g. to rewrite the
+//     query or to add a custom payload);
+//   - a response that is sent back to the client, the request is not forwarded and the next interceptors are skipped;
+//   - an error, the client receives a SERVER_ERROR response.
+//
+// The stream id and protocol version of the returned frames are set to those of the client request. Intercept is
+// called concurrently for requests of different clients and of the same client.
+type Interceptor interface {
+	// Name is used in logs and as the interceptor label of the interceptor metrics.
+	Name() string
+	Intercept(request *InterceptorRequest) (newRequest *frame.Frame, response *frame.Frame, err error)
+}
+
+type InterceptorFunc func(request *InterceptorRequest) (newRequest *frame.Frame, response *frame.Frame, err error)
+
+// NewInterceptor returns an Interceptor that calls the provided function.
+func NewInterceptor(name string, interceptFunc InterceptorFunc) Interceptor {
+	return &funcInterceptor{name: name, interceptFunc: interceptFunc}
+}
+
+type funcInterceptor struct {
+	name          string
+	interceptFunc InterceptorFunc
+}
+
+func (recv *funcInterceptor) Name() string {
+	return recv.name
+}
+
+func (recv *funcInterceptor) Intercept(request *InterceptorRequest) (*frame.Frame, *frame.Frame, error) {
+	return recv.interceptFunc(request)
+}
+
+// interceptorChain runs the interceptors in the order in which they were registered, it is shared by all client
+// connections.
+type interceptorChain struct {
+	interceptors []Interceptor
+	metrics      []*metrics.InterceptorMetricsInstance
+}
+
+func newInterceptorChain(
+	interceptors []Interceptor, interceptorMetrics *metrics.InterceptorMetrics) (*interceptorChain, error) {
+	chain := &interceptorChain{
+		interceptors: interceptors,
+		metrics:      make([]*metrics.InterceptorMetricsInstance, 0, len(interceptors)),
+	}
+	for _, interceptor := range interceptors {
+		instance, err := interceptorMetrics.GetInterceptorMetrics(interceptor.Name())
+		if err != nil {
+			return nil, err
+		}
+		chain.metrics = append(chain.metrics, instance)
+	}
+	return chain, nil
+}
+
+// intercept returns the request that should be forwarded to the clusters or, if an interceptor handled the request,
+// the response that should be sent to the client.
+func (recv *interceptorChain) intercept(
+	request *frame.RawFrame, keyspace string, clientAddress string) (*frame.RawFrame, *frame.RawFrame, error) {
+
+	decodedRequest, err := defaultCodec.ConvertFromRawFrame(request)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not decode request for interceptors: %w", err)
+	}
+
+	rewritten := false
+	for i, interceptor := range recv.interceptors {
+		interceptorMetrics := recv.metrics[i]
+		newRequest, response, err := interceptor.Intercept(&InterceptorRequest{
+			Frame:         decodedRequest,
+			Keyspace:      keyspace,
+			ClientAddress: clientAddress,
+		})
+		if err != nil {
+			interceptorMetrics.Failed.Add(1)
+			errorResponse := frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.ServerError{
+				ErrorMessage: fmt.Sprintf("Interceptor %v failed: %v", interceptor.Name(), err),
+			})
+			encodedResponse, err := encodeInterceptedFrame(errorResponse, request)
+			return nil, encodedResponse, err
+		}
+		if response != nil {
+			interceptorMetrics.Responded.Add(1)
+			if !response.Header.IsResponse {
+				return nil, nil, fmt.Errorf("interceptor %v returned a request as response: %v",
+					interceptor.Name(), response.Body.Message)
+			}
+			encodedResponse, err := encodeInterceptedFrame(response, request)
+			return nil, encodedResponse, err
+		}
+		if newRequest != nil {
+			interceptorMetrics.Rewritten.Add(1)
+			if newRequest.Header.IsResponse {
+				return nil, nil, fmt.Errorf("interceptor %v returned a response as request: %v",
+					interceptor.Name(), newRequest.Body.Message)
+			}
+			decodedRequest = newRequest
+			rewritten = true
+		} else {
+			interceptorMetrics.Passed.Add(1)
+		}
+	}
+
+	if !rewritten {
+		return request, nil, nil
+	}
+	encodedRequest, err := encodeInterceptedFrame(decodedRequest, request)
+	return encodedRequest, nil, err
+}
+
+// encodeInterceptedFrame encodes a frame returned by an interceptor with the stream id and protocol version of the
+// client request. Frames are never compressed inside the proxy, compression is applied by the client connection.
+func encodeInterceptedFrame(f *frame.Frame, request *frame.RawFrame) (*frame.RawFrame, error) {
+	f.Header.Version = request.Header.Version
+	f.Header.StreamId = request.Header.StreamId
+	f.SetCompress(false)
+	rawFrame, err := defaultCodec.ConvertToRawFrame(f)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode frame returned by interceptor: %w", err)
+	}
+	return rawFrame, nil
+}
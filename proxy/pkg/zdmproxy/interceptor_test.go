@@ -0,0 +1,109 @@
+package zdmproxy
+
+import (
+	"errors"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics/prommetrics"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestInterceptorChain_Intercept(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	addPayload := NewInterceptor("payload", func(request *InterceptorRequest) (*frame.Frame, *frame.Frame, error) {
+		newRequest := request.Frame.Clone()
+		newRequest.SetCustomPayload(map[string][]byte{"tenant": []byte(request.Keyspace)})
+		return newRequest, nil, nil
+	})
+	blockTruncate := NewInterceptor("block_truncate", func(request *InterceptorRequest) (*frame.Frame, *frame.Frame, error) {
+		query, ok := request.Frame.Body.Message.(*message.Query)
+		if !ok {
+			return nil, nil, nil
+		}
+		switch query.Query {
+		case "TRUNCATE ks.tb":
+			return nil, frame.NewFrame(request.Frame.Header.Version, 0, &message.Unauthorized{
+				ErrorMessage: "TRUNCATE is not allowed"}), nil
+		case "fail":
+			return nil, nil, errors.New("boom")
+		default:
+			return nil, nil, nil
+		}
+	})
+	chain, err := newInterceptorChain(
+		[]Interceptor{addPayload, blockTruncate},
+		metrics.NewInterceptorMetrics(prommetrics.NewPrometheusMetricFactory(registry, log.NewEntry(log.StandardLogger()))))
+	require.Nil(t, err)
+
+	request := mockQueryFrame(t, "SELECT * FROM ks.tb")
+	request.Header.StreamId = 10
+	newRequest, response, err := chain.intercept(request, "ks", "127.0.0.1:50000")
+	require.Nil(t, err)
+	require.Nil(t, response)
+	decodedRequest, err := defaultCodec.ConvertFromRawFrame(newRequest)
+	require.Nil(t, err)
+	require.Equal(t, int16(10), decodedRequest.Header.StreamId)
+	require.Equal(t, map[string][]byte{"tenant": []byte("ks")}, decodedRequest.Body.CustomPayload)
+	require.Equal(t, "SELECT * FROM ks.tb", decodedRequest.Body.Message.(*message.Query).Query)
+
+	request = mockQueryFrame(t, "TRUNCATE ks.tb")
+	request.Header.StreamId = 11
+	newRequest, response, err = chain.intercept(request, "ks", "127.0.0.1:50000")
+	require.Nil(t, err)
+	require.Nil(t, newRequest)
+	decodedResponse, err := defaultCodec.ConvertFromRawFrame(response)
+	require.Nil(t, err)
+	require.Equal(t, int16(11), decodedResponse.Header.StreamId)
+	require.Equal(t, primitive.ProtocolVersion4, decodedResponse.Header.Version)
+	require.Equal(t, &message.Unauthorized{ErrorMessage: "TRUNCATE is not allowed"}, decodedResponse.Body.Message)
+
+	newRequest, response, err = chain.intercept(mockQueryFrame(t, "fail"), "", "127.0.0.1:50000")
+	require.Nil(t, err)
+	require.Nil(t, newRequest)
+	decodedResponse, err = defaultCodec.ConvertFromRawFrame(response)
+	require.Nil(t, err)
+	require.Equal(t, &message.ServerError{ErrorMessage: "Interceptor block_truncate failed: boom"}, decodedResponse.Body.Message)
+
+	families, err := registry.Gather()
+	require.Nil(t, err)
+	results := make(map[string]float64)
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range m.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			results[labels["interceptor"]+"/"+labels["result"]] = m.GetCounter().GetValue()
+		}
+	}
+	require.Equal(t, map[string]float64{
+		"payload/passed":           0,
+		"payload/rewritten":        3,
+		"payload/responded":        0,
+		"payload/failed":           0,
+		"block_truncate/passed":    1,
+		"block_truncate/rewritten": 0,
+		"block_truncate/responded": 1,
+		"block_truncate/failed":    1,
+	}, results)
+}
+
+func TestInterceptorChain_NoChanges(t *testing.T) {
+	passThrough := NewInterceptor("pass_through", func(request *InterceptorRequest) (*frame.Frame, *frame.Frame, error) {
+		return nil, nil, nil
+	})
+	chain, err := newInterceptorChain([]Interceptor{passThrough},
+		metrics.NewInterceptorMetrics(prommetrics.NewPrometheusMetricFactory(prometheus.NewRegistry(), log.NewEntry(log.StandardLogger()))))
+	require.Nil(t, err)
+
+	request := mockQueryFrame(t, "SELECT * FROM ks.tb")
+	newRequest, response, err := chain.intercept(request, "", "127.0.0.1:50000")
+	require.Nil(t, err)
+	require.Nil(t, response)
+	require.Same(t, request, newRequest)
+}
@@ -0,0 +1,286 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	introspectionKeyspaceName                = "zdm"
+	introspectionClientsTableName            = "clients"
+	introspectionPreparedStatementsTableName = "prepared_statements"
+	introspectionConfigTableName             = "config"
+)
+
+var introspectionQueryTypes = map[string]interceptedQueryType{
+	introspectionClientsTableName:            introspectionClients,
+	introspectionPreparedStatementsTableName: introspectionPreparedStatements,
+	introspectionConfigTableName:             introspectionConfig,
+}
+
+/*
+CREATE TABLE zdm.clients (
+    client_address text,
+    origin_address text,
+    target_address text,
+    keyspace text,
+    connected_at timestamp,
+    bytes_received bigint,
+    bytes_sent bigint,
+    origin_bytes_received bigint,
+    origin_bytes_sent bigint,
+    target_bytes_received bigint,
+    target_bytes_sent bigint
+)
+*/
+
+var introspectionClientsColumns = []*message.ColumnMetadata{
+	{Keyspace: introspectionKeyspaceName, Table: introspectionClientsTableName, Name: "client_address", Type: datatype.Varchar},
+	{Keyspace: introspectionKeyspaceName, Table: introspectionClientsTableName, Name: "origin_address", Type: datatype.Varchar},
+	{Keyspace: introspectionKeyspaceName, Table: introspectionClientsTableName, Name: "target_address", Type: datatype.Varchar},
+	{Keyspace: introspectionKeyspaceName, Table: introspectionClientsTableName, Name: "keyspace", Type: datatype.Varchar},
+	{Keyspace: introspectionKeyspaceName, Table: introspectionClientsTableName, Name: "connected_at", Type: datatype.Timestamp},
+	{Keyspace: introspectionKeyspaceName, Table: introspectionClientsTableName, Name: "bytes_received", Type: datatype.Bigint},
+	{Keyspace: introspectionKeyspaceName, Table: introspectionClientsTableName, Name: "bytes_sent", Type: datatype.Bigint},
+	{Keyspace: introspectionKeyspaceName, Table: introspectionClientsTableName, Name: "origin_bytes_received", Type: datatype.Bigint},
+	{Keyspace: introspectionKeyspaceName, Table: introspectionClientsTableName, Name: "origin_bytes_sent", Type: datatype.Bigint},
+	{Keyspace: introspectionKeyspaceName, Table: introspectionClientsTableName, Name: "target_bytes_received", Type: datatype.Bigint},
+	{Keyspace: introspectionKeyspaceName, Table: introspectionClientsTableName, Name: "target_bytes_sent", Type: datatype.Bigint},
+}
+
+/*
+CREATE TABLE zdm.prepared_statements (
+    origin_id blob,
+    target_id blob,
+    keyspace text,
+    query text
+)
+*/
+
+var introspectionPreparedStatementsColumns = []*message.ColumnMetadata{
+	{Keyspace: introspectionKeyspaceName, Table: introspectionPreparedStatementsTableName, Name: "origin_id", Type: datatype.Blob},
+	{Keyspace: introspectionKeyspaceName, Table: introspectionPreparedStatementsTableName, Name: "target_id", Type: datatype.Blob},
+	{Keyspace: introspectionKeyspaceName, Table: introspectionPreparedStatementsTableName, Name: "keyspace", Type: datatype.Varchar},
+	{Keyspace: introspectionKeyspaceName, Table: introspectionPreparedStatementsTableName, Name: "query", Type: datatype.Varchar},
+}
+
+/*
+CREATE TABLE zdm.config (
+    name text,
+    value text
+)
+*/
+
+var introspectionConfigColumns = []*message.ColumnMetadata{
+	{Keyspace: introspectionKeyspaceName, Table: introspectionConfigTableName, Name: "name", Type: datatype.Varchar},
+	{Keyspace: introspectionKeyspaceName, Table: introspectionConfigTableName, Name: "value", Type: datatype.Varchar},
+}
+
+func isIntrospectionKeyspace(keyspace string) bool {
+	return keyspace == introspectionKeyspaceName
+}
+
+// IntrospectionTables answers the SELECT queries on the tables of the zdm keyspace with the state of this proxy
+// instance, without sending them to the clusters. WHERE clauses are ignored, every row is returned.
+type IntrospectionTables struct {
+	conf    *config.Config
+	psCache *PreparedStatementCache
+
+	clients     map[*ClientHandler]time.Time // client handler -> time at which the client connected
+	clientsLock *sync.Mutex
+}
+
+func NewIntrospectionTables(conf *config.Config, psCache *PreparedStatementCache) *IntrospectionTables {
+	return &IntrospectionTables{
+		conf:        conf,
+		psCache:     psCache,
+		clients:     make(map[*ClientHandler]time.Time),
+		clientsLock: &sync.Mutex{},
+	}
+}
+
+func (recv *IntrospectionTables) addClient(clientHandler *ClientHandler) {
+	recv.clientsLock.Lock()
+	defer recv.clientsLock.Unlock()
+	recv.clients[clientHandler] = time.Now()
+}
+
+func (recv *IntrospectionTables) removeClient(clientHandler *ClientHandler) {
+	recv.clientsLock.Lock()
+	defer recv.clientsLock.Unlock()
+	delete(recv.clients, clientHandler)
+}
+
+// NewResult returns a PreparedResult if the prepareRequestInfo parameter is not nil and it returns a
+// RowsResult if prepareRequestInfo is nil.
+func (recv *IntrospectionTables) NewResult(
+	prepareRequestInfo *PrepareRequestInfo, connectionKeyspace string, genericTypeCodec *GenericTypeCodec,
+	version primitive.ProtocolVersion, queryType interceptedQueryType,
+	parsedSelectClause *selectClause) (message.Result, error) {
+
+	var tableColumns []*message.ColumnMetadata
+	var rows [][]interface{}
+	switch queryType {
+	case introspectionClients:
+		tableColumns, rows = introspectionClientsColumns, recv.getClientRows()
+	case introspectionPreparedStatements:
+		tableColumns, rows = introspectionPreparedStatementsColumns, recv.getPreparedStatementRows()
+	case introspectionConfig:
+		tableColumns, rows = introspectionConfigColumns, recv.getConfigRows()
+	default:
+		return nil, fmt.Errorf("unexpected introspection query type: %v", queryType)
+	}
+
+	columns, rows, err := filterIntrospectionRows(parsedSelectClause, tableColumns, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if prepareRequestInfo != nil {
+		return EncodePreparedResult(prepareRequestInfo, connectionKeyspace, columns)
+	}
+	return EncodeRowsResult(genericTypeCodec, version, columns, rows)
+}
+
+func (recv *IntrospectionTables) getClientRows() [][]interface{} {
+	type client struct {
+		handler     *ClientHandler
+		connectedAt time.Time
+	}
+
+	recv.clientsLock.Lock()
+	clients := make([]client, 0, len(recv.clients))
+	for clientHandler, connectedAt := range recv.clients {
+		clients = append(clients, client{handler: clientHandler, connectedAt: connectedAt})
+	}
+	recv.clientsLock.Unlock()
+
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].connectedAt.Before(clients[j].connectedAt)
+	})
+
+	rows := make([][]interface{}, 0, len(clients))
+	for _, c := range clients {
+		var keyspace interface{}
+		if currentKeyspace := c.handler.LoadCurrentKeyspace(); currentKeyspace != "" {
+			keyspace = currentKeyspace
+		}
+		row := []interface{}{
+			c.handler.clientConnector.connection.RemoteAddr().String(),
+			c.handler.originCassandraConnector.connection.RemoteAddr().String(),
+			c.handler.targetCassandraConnector.connection.RemoteAddr().String(),
+			keyspace,
+			c.connectedAt,
+		}
+		for _, conn := range []net.Conn{
+			c.handler.clientConnector.connection,
+			c.handler.originCassandraConnector.connection,
+			c.handler.targetCassandraConnector.connection,
+		} {
+			if metered, ok := conn.(*meteredConn); ok {
+				row = append(row, metered.BytesReceived(), metered.BytesSent())
+			} else {
+				row = append(row, nil, nil)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func (recv *IntrospectionTables) getPreparedStatementRows() [][]interface{} {
+	preparedStatements := recv.psCache.GetAll()
+	rows := make([][]interface{}, 0, len(preparedStatements))
+	for _, preparedData := range preparedStatements {
+		prepareRequestInfo := preparedData.GetPrepareRequestInfo()
+		var keyspace interface{}
+		if prepareRequestInfo.GetKeyspace() != "" {
+			keyspace = prepareRequestInfo.GetKeyspace()
+		}
+		rows = append(rows, []interface{}{
+			preparedData.GetOriginPreparedId(),
+			preparedData.GetTargetPreparedId(),
+			keyspace,
+			prepareRequestInfo.GetQuery(),
+		})
+	}
+	return rows
+}
+
+func (recv *IntrospectionTables) getConfigRows() [][]interface{} {
+	settings := recv.conf.Settings()
+	rows := make([][]interface{}, 0, len(settings))
+	for _, setting := range settings {
+		rows = append(rows, []interface{}{setting[0], setting[1]})
+	}
+	return rows
+}
+
+// filterIntrospectionRows applies the select clause to the rows of an introspection table. Like with the system
+// tables, COUNT(*) returns a single row.
+func filterIntrospectionRows(
+	parsedSelectClause *selectClause, tableColumns []*message.ColumnMetadata, rows [][]interface{}) (
+	[]*message.ColumnMetadata, [][]interface{}, error) {
+
+	if parsedSelectClause.IsStarSelectClause() {
+		return tableColumns, rows, nil
+	}
+
+	selectors := parsedSelectClause.GetSelectors()
+	columns := make([]*message.ColumnMetadata, 0, len(selectors))
+	columnIndexes := make([]int, 0, len(selectors)) // -1 for COUNT
+	hasCountSelector := false
+	for _, parsedSelector := range selectors {
+		column, isCountSelector, err := columnFromSelector(
+			tableColumns, parsedSelector, introspectionKeyspaceName, tableColumns[0].Table)
+		if err != nil {
+			return nil, nil, err
+		}
+		columns = append(columns, column)
+		if isCountSelector {
+			hasCountSelector = true
+			columnIndexes = append(columnIndexes, -1)
+			continue
+		}
+		name, err := unaliasedColumnNameFromSelector(parsedSelector)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i, tableColumn := range tableColumns {
+			if tableColumn.Name == name {
+				columnIndexes = append(columnIndexes, i)
+				break
+			}
+		}
+	}
+
+	rowCount := len(rows)
+	if hasCountSelector {
+		if rowCount == 0 {
+			rows = [][]interface{}{make([]interface{}, len(tableColumns))}
+		} else {
+			rows = rows[:1]
+		}
+	}
+
+	filteredRows := make([][]interface{}, 0, len(rows))
+	for _, row := range rows {
+		filteredRow := make([]interface{}, 0, len(columnIndexes))
+		for _, idx := range columnIndexes {
+			if idx < 0 {
+				filteredRow = append(filteredRow, rowCount)
+			} else {
+				filteredRow = append(filteredRow, row[idx])
+			}
+		}
+		filteredRows = append(filteredRows, filteredRow)
+	}
+	return columns, filteredRows, nil
+}
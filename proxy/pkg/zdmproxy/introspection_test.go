@@ -0,0 +1,97 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestInspectFrameIntrospection(t *testing.T) {
+	psCache := NewPreparedStatementCache(5000, log.NewEntry(log.StandardLogger()))
+	mh := newFakeMetricHandler()
+	timeUuidGenerator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+
+	tests := []struct {
+		name                 string
+		query                string
+		keyspace             string
+		introspectionEnabled bool
+		expected             RequestInfo
+	}{
+		{"config", "SELECT * FROM zdm.config", "", true,
+			NewInterceptedRequestInfo(introspectionConfig, newStarSelectClause())},
+		{"clients with keyspace", "SELECT client_address FROM clients", "zdm", true,
+			NewInterceptedRequestInfo(introspectionClients, newSelectClauseWithSelectors(
+				[]selector{&idSelector{name: "client_address"}}))},
+		{"prepared statements", "SELECT count(*) FROM zdm.prepared_statements", "", true,
+			NewInterceptedRequestInfo(introspectionPreparedStatements, newSelectClauseWithSelectors(
+				[]selector{&countSelector{name: "count"}}))},
+		{"unknown table", "SELECT * FROM zdm.other", "", true, NewGenericRequestInfo(forwardToOrigin, true, true)},
+		{"disabled", "SELECT * FROM zdm.config", "", false, NewGenericRequestInfo(forwardToOrigin, true, true)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := buildRequestInfo(&frameDecodeContext{frame: mockQueryFrame(t, tt.query), logger: log.NewEntry(log.StandardLogger())},
+				[]*statementReplacedTerms{}, psCache, mh, tt.keyspace, common.ClusterTypeOrigin, false, true,
+				tt.introspectionEnabled, false, common.LwtPolicyBoth, common.CounterWritePolicyBoth,
+				common.DdlPolicyBoth, nil, nil, timeUuidGenerator)
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestIntrospectionTables_NewResult(t *testing.T) {
+	conf := config.New()
+	conf.OriginUsername = "cassandra"
+	conf.OriginPassword = "secret"
+	psCache := NewPreparedStatementCache(5000, log.NewEntry(log.StandardLogger()))
+	psCache.Store(
+		&message.PreparedResult{PreparedQueryId: []byte("origin1")},
+		&message.PreparedResult{PreparedQueryId: []byte("target1")},
+		NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), nil, false,
+			"INSERT INTO ks.tb (a) VALUES (?)", "ks"))
+	introspectionTables := NewIntrospectionTables(conf, psCache)
+	codec := GetDefaultGenericTypeCodec()
+
+	getRows := func(queryType interceptedQueryType, clause *selectClause) *ParsedRowSet {
+		result, err := introspectionTables.NewResult(nil, "", codec, primitive.ProtocolVersion4, queryType, clause)
+		require.Nil(t, err)
+		rowsResult, ok := result.(*message.RowsResult)
+		require.True(t, ok)
+		rowSet, err := ParseRowsResult(codec, primitive.ProtocolVersion4, rowsResult, rowsResult.Metadata.Columns,
+			map[string]int{})
+		require.Nil(t, err)
+		return rowSet
+	}
+
+	rowSet := getRows(introspectionConfig, newStarSelectClause())
+	require.Equal(t, 2, len(rowSet.Columns))
+	settings := make(map[string]interface{})
+	for _, row := range rowSet.Rows {
+		settings[row.Values[0].(string)] = row.Values[1]
+	}
+	require.Equal(t, "cassandra", settings["ZDM_ORIGIN_USERNAME"])
+	require.NotContains(t, settings, "ZDM_ORIGIN_PASSWORD")
+
+	rowSet = getRows(introspectionPreparedStatements, newSelectClauseWithSelectors([]selector{
+		&aliasedSelector{selector: &idSelector{name: "query"}, alias: "q"},
+		&idSelector{name: "target_id"},
+	}))
+	require.Equal(t, "q", rowSet.Columns[0].Name)
+	require.Equal(t, 1, len(rowSet.Rows))
+	require.Equal(t, []interface{}{"INSERT INTO ks.tb (a) VALUES (?)", []byte("target1")}, rowSet.Rows[0].Values)
+
+	rowSet = getRows(introspectionClients, newSelectClauseWithSelectors([]selector{&countSelector{name: "count"}}))
+	require.Equal(t, 1, len(rowSet.Rows))
+	require.Equal(t, []interface{}{int32(0)}, rowSet.Rows[0].Values)
+
+	_, err := introspectionTables.NewResult(nil, "", codec, primitive.ProtocolVersion4, introspectionConfig,
+		newSelectClauseWithSelectors([]selector{&idSelector{name: "other"}}))
+	require.Equal(t, &ColumnNotFoundErr{Name: "other"}, err)
+}
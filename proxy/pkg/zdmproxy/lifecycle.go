@@ -0,0 +1,60 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"net"
+)
+
+// ClientConnectListener is called when the proxy accepts a client connection, after the connections to ORIGIN and
+// TARGET of that client are opened. It is called from the goroutine that sets up the connection so it must not block.
+type ClientConnectListener func(clientAddress net.Addr)
+
+// ClusterDownListener is called when the control connection of a cluster fails ZDM_HEARTBEAT_FAILURE_THRESHOLD
+// consecutive times, i.e. when the readiness check starts reporting the cluster as DOWN. It is called again only
+// after the control connection recovers and reaches the threshold again. It must not block.
+type ClusterDownListener func(cluster common.ClusterType, err error)
+
+// AddClientConnectListener registers a listener of the client connections, it must be called before Start.
+func (p *ZdmProxy) AddClientConnectListener(listener ClientConnectListener) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.clientConnectListeners = append(p.clientConnectListeners, listener)
+}
+
+// AddClusterDownListener registers a listener that is notified when ORIGIN or TARGET is down, it must be called
+// before Start.
+func (p *ZdmProxy) AddClusterDownListener(listener ClusterDownListener) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.clusterDownListeners = append(p.clusterDownListeners, listener)
+}
+
+func (p *ZdmProxy) notifyClientConnect(clientAddress net.Addr) {
+	p.lock.RLock()
+	listeners := p.clientConnectListeners
+	p.lock.RUnlock()
+	for _, listener := range listeners {
+		listener(clientAddress)
+	}
+}
+
+func (p *ZdmProxy) notifyClusterDown(cluster common.ClusterType, err error) {
+	p.lock.RLock()
+	listeners := p.clusterDownListeners
+	p.lock.RUnlock()
+	for _, listener := range listeners {
+		listener(cluster, err)
+	}
+}
+
+// recordFailure increments the failure counter of the control connection and notifies the cluster down listener when
+// the counter reaches the threshold. The counter is only incremented by the heartbeat goroutine.
+func (cc *ControlConn) recordFailure(err error) {
+	cc.IncrementFailureCounter()
+	if cc.clusterDownListener == nil || cc.ReadFailureCounter() != cc.conf.HeartbeatFailureThreshold {
+		return
+	}
+	cc.logger.Warnf("Control connection to %v failed %d consecutive times, %v is down: %v",
+		cc.connConfig.GetClusterType(), cc.conf.HeartbeatFailureThreshold, cc.connConfig.GetClusterType(), err)
+	cc.clusterDownListener(cc.connConfig.GetClusterType(), err)
+}
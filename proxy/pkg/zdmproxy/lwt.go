@@ -0,0 +1,74 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+)
+
+const appliedColumnName = "[applied]"
+
+// compareConditionalResults compares the [applied] column of the ORIGIN and TARGET responses of a lightweight
+// transaction that was forwarded to both clusters. A mismatch means that the data of both clusters has diverged
+// (or is about to) so it is logged and tracked in the metrics. The client response is not affected.
+func (ch *ClientHandler) compareConditionalResults(request *frame.RawFrame, originResponse *frame.RawFrame, targetResponse *frame.RawFrame) {
+	if originResponse.Header.OpCode != primitive.OpCodeResult || targetResponse.Header.OpCode != primitive.OpCodeResult {
+		return
+	}
+
+	originApplied, err := getAppliedValue(originResponse)
+	if err != nil {
+		ch.logger.Warnf("Could not extract %v from ORIGIN response of conditional request with stream id %v: %v",
+			appliedColumnName, request.Header.StreamId, err)
+		return
+	}
+
+	targetApplied, err := getAppliedValue(targetResponse)
+	if err != nil {
+		ch.logger.Warnf("Could not extract %v from TARGET response of conditional request with stream id %v: %v",
+			appliedColumnName, request.Header.StreamId, err)
+		return
+	}
+
+	if originApplied == nil || targetApplied == nil {
+		return
+	}
+
+	if !bytes.Equal(originApplied, targetApplied) {
+		ch.metricHandler.GetProxyMetrics().LwtAppliedMismatchCount.Add(1)
+		ch.logger.Warnf("Conditional request with stream id %v returned different %v results: ORIGIN=%v, TARGET=%v.",
+			request.Header.StreamId, appliedColumnName, originApplied, targetApplied)
+	}
+}
+
+// getAppliedValue returns the encoded value of the [applied] column of the first row in a ROWS result
+// or nil if the response is not a ROWS result.
+func getAppliedValue(response *frame.RawFrame) ([]byte, error) {
+	body, err := defaultCodec.DecodeBody(response.Header, bytes.NewReader(response.Body))
+	if err != nil {
+		return nil, err
+	}
+
+	rowsResult, ok := body.Message.(*message.RowsResult)
+	if !ok || len(rowsResult.Data) == 0 {
+		return nil, nil
+	}
+
+	// [applied] is always the first column but use the metadata when it is available
+	appliedIdx := 0
+	if rowsResult.Metadata != nil {
+		for idx, column := range rowsResult.Metadata.Columns {
+			if column.Name == appliedColumnName {
+				appliedIdx = idx
+				break
+			}
+		}
+	}
+
+	row := rowsResult.Data[0]
+	if appliedIdx >= len(row) {
+		return nil, nil
+	}
+	return row[appliedIdx], nil
+}
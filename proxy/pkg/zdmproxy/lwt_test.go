@@ -0,0 +1,47 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestGetAppliedValue(t *testing.T) {
+	applied := message.Column{1}
+	notApplied := message.Column{0}
+	tests := []struct {
+		name     string
+		msg      message.Message
+		expected []byte
+	}{
+		{"void", &message.VoidResult{}, nil},
+		{"applied", &message.RowsResult{
+			Metadata: &message.RowsMetadata{ColumnCount: 1, Columns: []*message.ColumnMetadata{
+				{Keyspace: "ks", Table: "tb", Name: appliedColumnName, Type: datatype.Boolean}}},
+			Data: message.RowSet{{applied}},
+		}, applied},
+		{"not applied with current values", &message.RowsResult{
+			Metadata: &message.RowsMetadata{ColumnCount: 2, Columns: []*message.ColumnMetadata{
+				{Keyspace: "ks", Table: "tb", Name: "b", Type: datatype.Int},
+				{Keyspace: "ks", Table: "tb", Name: appliedColumnName, Type: datatype.Boolean}}},
+			Data: message.RowSet{{message.Column{0, 0, 0, 1}, notApplied}},
+		}, notApplied},
+		{"no rows", &message.RowsResult{
+			Metadata: &message.RowsMetadata{ColumnCount: 1, Columns: []*message.ColumnMetadata{
+				{Keyspace: "ks", Table: "tb", Name: appliedColumnName, Type: datatype.Boolean}}},
+			Data: message.RowSet{},
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rawFrame, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 1, tt.msg))
+			require.Nil(t, err)
+			actual, err := getAppliedValue(rawFrame)
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type MigrationPhaseStatus struct {
+	Phase         string    `json:"phase"`
+	Since         time.Time `json:"since"`
+	PreviousPhase string    `json:"previous_phase,omitempty"`
+
+	// AllowedTransitions are the phases that the proxy can move to from the current phase.
+	AllowedTransitions []string `json:"allowed_transitions"`
+
+	// ClientConnections are the open client connections by the phase that was current when they were opened, a
+	// client connection keeps the behavior of that phase until it is closed.
+	ClientConnections map[string]int32 `json:"client_connections"`
+}
+
+// clientConnectionSettings are the settings that a client connection uses for its whole life, they are the settings of
+// the migration phase that was current when the connection was opened (or the settings of the configuration if
+// ZDM_MIGRATION_PHASE is not set).
+type clientConnectionSettings struct {
+	migrationPhase      common.MigrationPhase
+	primaryCluster      common.ClusterType
+	readMode            common.ReadMode
+	shadowModeEnabled   bool
+	originWritesEnabled bool
+}
+
+// migrationPhaseTracker holds the current migration phase and validates the transitions between phases.
+type migrationPhaseTracker struct {
+	lock     *sync.RWMutex
+	phase    common.MigrationPhase
+	previous common.MigrationPhase
+	since    time.Time
+
+	clientConnections map[common.MigrationPhase]*int32
+	transitions       metrics.Counter
+}
+
+func newMigrationPhaseTracker(
+	phase common.MigrationPhase, metricFactory metrics.MetricFactory) (*migrationPhaseTracker, error) {
+
+	transitions, err := metricFactory.GetOrCreateCounter(metrics.MigrationPhaseTransitions)
+	if err != nil {
+		return nil, err
+	}
+	tracker := &migrationPhaseTracker{
+		lock:              &sync.RWMutex{},
+		phase:             phase,
+		previous:          common.MigrationPhaseUndefined,
+		since:             time.Now(),
+		clientConnections: make(map[common.MigrationPhase]*int32, len(common.MigrationPhases)),
+		transitions:       transitions,
+	}
+	for _, migrationPhase := range common.MigrationPhases {
+		migrationPhase := migrationPhase
+		connections := new(int32)
+		tracker.clientConnections[migrationPhase] = connections
+
+		labels := map[string]string{metrics.MigrationPhaseLabel: migrationPhase.String()}
+		_, err = metricFactory.GetOrCreateGaugeFunc(metrics.MigrationPhase.WithLabels(labels), func() float64 {
+			if tracker.current() == migrationPhase {
+				return 1
+			}
+			return 0
+		})
+		if err != nil {
+			return nil, err
+		}
+		_, err = metricFactory.GetOrCreateGaugeFunc(
+			metrics.MigrationPhaseClientConnections.WithLabels(labels), func() float64 {
+				return float64(atomic.LoadInt32(connections))
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tracker, nil
+}
+
+func (recv *migrationPhaseTracker) current() common.MigrationPhase {
+	recv.lock.RLock()
+	defer recv.lock.RUnlock()
+	return recv.phase
+}
+
+// transition moves to the given phase if it is one of the allowed transitions of the current phase.
+func (recv *migrationPhaseTracker) transition(phase common.MigrationPhase) (common.MigrationPhase, error) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if phase == recv.phase {
+		return recv.phase, nil
+	}
+	allowed := recv.phase.AllowedTransitions()
+	for _, allowedPhase := range allowed {
+		if allowedPhase == phase {
+			previous := recv.phase
+			recv.previous = previous
+			recv.phase = phase
+			recv.since = time.Now()
+			recv.transitions.Add(1)
+			return previous, nil
+		}
+	}
+	if len(allowed) == 0 {
+		return recv.phase, fmt.Errorf("illegal migration phase transition from %v to %v: %v is the last phase",
+			recv.phase, phase, recv.phase)
+	}
+	return recv.phase, fmt.Errorf("illegal migration phase transition from %v to %v; allowed transitions: %v",
+		recv.phase, phase, phaseNames(allowed))
+}
+
+// reset moves to the given phase without checking the allowed transitions, it must only be called while there are no
+// open client connections.
+func (recv *migrationPhaseTracker) reset(phase common.MigrationPhase) common.MigrationPhase {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	previous := recv.phase
+	if phase != previous {
+		recv.previous = previous
+		recv.phase = phase
+		recv.since = time.Now()
+		recv.transitions.Add(1)
+	}
+	return previous
+}
+
+func (recv *migrationPhaseTracker) status() *MigrationPhaseStatus {
+	recv.lock.RLock()
+	defer recv.lock.RUnlock()
+	status := &MigrationPhaseStatus{
+		Phase:              recv.phase.String(),
+		Since:              recv.since,
+		PreviousPhase:      recv.previous.String(),
+		AllowedTransitions: phaseNames(recv.phase.AllowedTransitions()),
+		ClientConnections:  make(map[string]int32, len(recv.clientConnections)),
+	}
+	for phase, connections := range recv.clientConnections {
+		status.ClientConnections[phase.String()] = atomic.LoadInt32(connections)
+	}
+	return status
+}
+
+// openClientConnection returns the settings of a new client connection, closeClientConnection must be called with
+// the phase of the settings when the connection is closed.
+func (recv *migrationPhaseTracker) openClientConnection() *clientConnectionSettings {
+	recv.lock.RLock()
+	phase := recv.phase
+	atomic.AddInt32(recv.clientConnections[phase], 1)
+	recv.lock.RUnlock()
+	return &clientConnectionSettings{
+		migrationPhase:      phase,
+		primaryCluster:      phase.PrimaryCluster(),
+		readMode:            phase.ReadMode(),
+		shadowModeEnabled:   phase.ShadowModeEnabled(),
+		originWritesEnabled: phase.OriginWritesEnabled(),
+	}
+}
+
+func (recv *migrationPhaseTracker) closeClientConnection(phase common.MigrationPhase) {
+	atomic.AddInt32(recv.clientConnections[phase], -1)
+}
+
+func phaseNames(phases []common.MigrationPhase) []string {
+	names := make([]string, 0, len(phases))
+	for _, phase := range phases {
+		names = append(names, phase.String())
+	}
+	return names
+}
@@ -0,0 +1,66 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics/noopmetrics"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMigrationPhaseTracker_Transitions(t *testing.T) {
+	tracker, err := newMigrationPhaseTracker(common.MigrationPhaseShadow, noopmetrics.NewNoopMetricFactory())
+	require.Nil(t, err)
+
+	_, err = tracker.transition(common.MigrationPhaseTargetPrimary)
+	require.Equal(t, "illegal migration phase transition from SHADOW to TARGET_PRIMARY; "+
+		"allowed transitions: [DUAL_WRITE]", err.Error())
+
+	for _, phase := range common.MigrationPhases[1:] {
+		previous := tracker.current()
+		returnedPrevious, err := tracker.transition(phase)
+		require.Nil(t, err)
+		require.Equal(t, previous, returnedPrevious)
+		require.Equal(t, phase, tracker.current())
+	}
+
+	_, err = tracker.transition(common.MigrationPhaseTargetPrimary)
+	require.Equal(t, "illegal migration phase transition from ORIGIN_DECOMMISSIONED to TARGET_PRIMARY: "+
+		"ORIGIN_DECOMMISSIONED is the last phase", err.Error())
+
+	previous, err := tracker.transition(common.MigrationPhaseOriginDecommissioned)
+	require.Nil(t, err, "moving to the current phase is a no-op")
+	require.Equal(t, common.MigrationPhaseOriginDecommissioned, previous)
+
+	status := tracker.status()
+	require.Equal(t, "ORIGIN_DECOMMISSIONED", status.Phase)
+	require.Equal(t, "TARGET_PRIMARY", status.PreviousPhase)
+	require.Empty(t, status.AllowedTransitions)
+}
+
+func TestMigrationPhaseTracker_ClientConnections(t *testing.T) {
+	tracker, err := newMigrationPhaseTracker(common.MigrationPhaseDualWriteAsyncRead, noopmetrics.NewNoopMetricFactory())
+	require.Nil(t, err)
+
+	settings := tracker.openClientConnection()
+	require.Equal(t, &clientConnectionSettings{
+		migrationPhase:      common.MigrationPhaseDualWriteAsyncRead,
+		primaryCluster:      common.ClusterTypeOrigin,
+		readMode:            common.ReadModeDualAsyncOnSecondary,
+		shadowModeEnabled:   false,
+		originWritesEnabled: true,
+	}, settings)
+
+	_, err = tracker.transition(common.MigrationPhaseTargetPrimary)
+	require.Nil(t, err)
+	targetPrimarySettings := tracker.openClientConnection()
+	require.Equal(t, common.ClusterTypeTarget, targetPrimarySettings.primaryCluster)
+	require.Equal(t, common.ReadModePrimaryOnly, targetPrimarySettings.readMode)
+
+	status := tracker.status()
+	require.Equal(t, int32(1), status.ClientConnections["DUAL_WRITE_ASYNC_READ"])
+	require.Equal(t, int32(1), status.ClientConnections["TARGET_PRIMARY"])
+	require.Equal(t, []string{"ORIGIN_DECOMMISSIONED", "DUAL_WRITE_ASYNC_READ"}, status.AllowedTransitions)
+
+	tracker.closeClientConnection(settings.migrationPhase)
+	require.Equal(t, int32(0), tracker.status().ClientConnections["DUAL_WRITE_ASYNC_READ"])
+}
@@ -0,0 +1,118 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+)
+
+// targetNameMapper translates the keyspace and table names of the QUERY, PREPARE and BATCH requests that are sent to
+// TARGET according to ZDM_TARGET_NAME_MAPPING. Clients keep using the ORIGIN names.
+//
+// Unqualified table names are resolved with the keyspace of the request (or the current keyspace of the connection)
+// and they are qualified with the TARGET keyspace when they are mapped.
+type targetNameMapper struct {
+	mapping           *common.NameMapping
+	timeUuidGenerator TimeUuidGenerator
+}
+
+func newTargetNameMapper(mapping *common.NameMapping, timeUuidGenerator TimeUuidGenerator) *targetNameMapper {
+	if mapping == nil {
+		return nil
+	}
+	return &targetNameMapper{
+		mapping:           mapping,
+		timeUuidGenerator: timeUuidGenerator,
+	}
+}
+
+// mapRequest returns the request that should be sent to TARGET, which is the same request if it doesn't reference
+// any mapped name.
+func (recv *targetNameMapper) mapRequest(
+	request *frame.RawFrame, currentKeyspace string, logger *log.Entry) (*frame.RawFrame, error) {
+
+	switch request.Header.OpCode {
+	case primitive.OpCodeQuery, primitive.OpCodePrepare, primitive.OpCodeBatch:
+	default:
+		return request, nil
+	}
+
+	decodedFrame, err := defaultCodec.ConvertFromRawFrame(request)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode '%v' request to map names for %v: %w",
+			request.Header.OpCode.String(), common.ClusterTypeTarget, err)
+	}
+
+	mapped := false
+	switch msg := decodedFrame.Body.Message.(type) {
+	case *message.Query:
+		keyspace := currentKeyspace
+		if msg.Options != nil && msg.Options.Keyspace != "" {
+			keyspace = msg.Options.Keyspace
+			msg.Options.Keyspace = recv.mapKeyspace(keyspace)
+			mapped = msg.Options.Keyspace != keyspace
+		}
+		var queryMapped bool
+		msg.Query, queryMapped = recv.mapQuery(msg.Query, keyspace, logger)
+		mapped = mapped || queryMapped
+	case *message.Prepare:
+		keyspace := currentKeyspace
+		if msg.Keyspace != "" {
+			keyspace = msg.Keyspace
+			msg.Keyspace = recv.mapKeyspace(keyspace)
+			mapped = msg.Keyspace != keyspace
+		}
+		var queryMapped bool
+		msg.Query, queryMapped = recv.mapQuery(msg.Query, keyspace, logger)
+		mapped = mapped || queryMapped
+	case *message.Batch:
+		keyspace := currentKeyspace
+		if msg.Keyspace != "" {
+			keyspace = msg.Keyspace
+			msg.Keyspace = recv.mapKeyspace(keyspace)
+			mapped = msg.Keyspace != keyspace
+		}
+		for _, child := range msg.Children {
+			query, ok := child.QueryOrId.(string)
+			if !ok {
+				continue
+			}
+			newQuery, childMapped := recv.mapQuery(query, keyspace, logger)
+			if childMapped {
+				child.QueryOrId = newQuery
+				mapped = true
+			}
+		}
+	}
+
+	if !mapped {
+		return request, nil
+	}
+
+	newRequest, err := defaultCodec.ConvertToRawFrame(decodedFrame)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert frame with mapped names to raw frame: %w", err)
+	}
+	return newRequest, nil
+}
+
+func (recv *targetNameMapper) mapKeyspace(keyspace string) string {
+	newKeyspace, _ := recv.mapping.MapName(keyspace, "")
+	return newKeyspace
+}
+
+// mapQuery maps the names of a single statement and returns the new query string and whether it changed.
+func (recv *targetNameMapper) mapQuery(query string, keyspace string, logger *log.Entry) (string, bool) {
+	queryInfo := inspectCqlQuery(query, keyspace, recv.timeUuidGenerator, logger)
+	newQueryInfo := queryInfo.mapTableNames(recv.mapping.MapName)
+	if newQueryInfo == queryInfo {
+		return query, false
+	}
+	newQuery := newQueryInfo.getQuery()
+	logger.Debugf("Mapped names of query for %v from '%v' to '%v'.", common.ClusterTypeTarget,
+		redactedStatement(query), redactedStatement(newQuery))
+	return newQuery, true
+}
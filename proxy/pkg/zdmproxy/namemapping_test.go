@@ -0,0 +1,77 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTargetNameMapper_MapRequest(t *testing.T) {
+	timeUuidGenerator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+	mapper := newTargetNameMapper(&common.NameMapping{
+		Keyspaces: map[string]string{"ks_old": "ks_new"},
+		Tables: map[common.QualifiedTableName]common.QualifiedTableName{
+			{Keyspace: "ks_old", Table: "tb_a"}: {Keyspace: "ks_other", Table: "TbB"},
+		},
+	}, timeUuidGenerator)
+	logger := log.WithFields(log.Fields{})
+
+	decode := func(request *frame.RawFrame) message.Message {
+		decodedFrame, err := defaultCodec.ConvertFromRawFrame(request)
+		require.Nil(t, err)
+		return decodedFrame.Body.Message
+	}
+
+	tests := []struct {
+		name            string
+		query           string
+		currentKeyspace string
+		expected        string
+	}{
+		{"qualified", "SELECT * FROM ks_old.tb WHERE a = 'ks_old.tb'", "", `SELECT * FROM "ks_new"."tb" WHERE a = 'ks_old.tb'`},
+		{"table mapping", "SELECT * FROM ks_old.tb_a", "", `SELECT * FROM "ks_other"."TbB"`},
+		{"unqualified", "INSERT INTO tb (a) VALUES (now())", "ks_old", `INSERT INTO "ks_new"."tb" (a) VALUES (now())`},
+		{"unqualified without keyspace", "SELECT * FROM tb", "", "SELECT * FROM tb"},
+		{"not mapped", "SELECT * FROM ks.tb", "ks_old", "SELECT * FROM ks.tb"},
+		{"use", "USE ks_old", "", `USE "ks_new"`},
+		{"multibyte", "UPDATE ks_old.tb SET a = 'ü' WHERE b = 1", "", `UPDATE "ks_new"."tb" SET a = 'ü' WHERE b = 1`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := mockQueryFrame(t, tt.query)
+			newRequest, err := mapper.mapRequest(request, tt.currentKeyspace, logger)
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, decode(newRequest).(*message.Query).Query)
+			if tt.expected == tt.query {
+				require.Same(t, request, newRequest)
+			}
+		})
+	}
+
+	prepareRequest := mockFrame(t, &message.Prepare{Query: "SELECT * FROM tb_a", Keyspace: "ks_old"}, primitive.ProtocolVersion5)
+	newRequest, err := mapper.mapRequest(prepareRequest, "", logger)
+	require.Nil(t, err)
+	require.Equal(t, &message.Prepare{Query: `SELECT * FROM "ks_other"."TbB"`, Keyspace: "ks_new"}, decode(newRequest))
+
+	batchRequest := mockBatchWithChildren(t, []*message.BatchChild{
+		{QueryOrId: "INSERT INTO ks_old.tb (a) VALUES (1)"},
+		{QueryOrId: []byte("prepared_id")},
+		{QueryOrId: "INSERT INTO ks.tb (a) VALUES (1)"},
+	})
+	newRequest, err = mapper.mapRequest(batchRequest, "", logger)
+	require.Nil(t, err)
+	children := decode(newRequest).(*message.Batch).Children
+	require.Equal(t, `INSERT INTO "ks_new"."tb" (a) VALUES (1)`, children[0].QueryOrId)
+	require.Equal(t, []byte("prepared_id"), children[1].QueryOrId)
+	require.Equal(t, "INSERT INTO ks.tb (a) VALUES (1)", children[2].QueryOrId)
+
+	executeRequest := mockExecuteFrame(t, "prepared_id")
+	newRequest, err = mapper.mapRequest(executeRequest, "ks_old", logger)
+	require.Nil(t, err)
+	require.Same(t, executeRequest, newRequest)
+}
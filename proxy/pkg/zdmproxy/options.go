@@ -0,0 +1,111 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"strconv"
+	"strings"
+)
+
+const (
+	supportedOptionCompression      = "COMPRESSION"
+	supportedOptionProtocolVersions = "PROTOCOL_VERSIONS"
+)
+
+// optionsResponder builds the SUPPORTED responses that the proxy returns to OPTIONS requests when
+// ZDM_PROXY_ANSWER_OPTIONS is enabled, so that the capabilities advertised to the drivers are the ones that the proxy
+// supports end-to-end instead of the capabilities of ORIGIN alone.
+type optionsResponder struct {
+	originControlConn *ControlConn
+	targetControlConn *ControlConn
+	overrides         map[string][]string
+}
+
+func newOptionsResponder(
+	originControlConn *ControlConn, targetControlConn *ControlConn, overrides map[string][]string) *optionsResponder {
+	return &optionsResponder{
+		originControlConn: originControlConn,
+		targetControlConn: targetControlConn,
+		overrides:         overrides,
+	}
+}
+
+// GetSupportedOptions returns the options of the SUPPORTED response or nil if the options of ORIGIN are not known
+// yet, in which case the OPTIONS request is forwarded to the clusters.
+func (recv *optionsResponder) GetSupportedOptions() map[string][]string {
+	originOptions := recv.originControlConn.GetSupportedOptions()
+	if originOptions == nil {
+		return nil
+	}
+	return newLocalSupportedOptions(originOptions, recv.targetControlConn.GetSupportedOptions(), recv.overrides)
+}
+
+// newLocalSupportedOptions starts from the options of ORIGIN, advertises the compression algorithms of the proxy,
+// removes the protocol versions that the proxy or TARGET don't support and finally applies the overrides of
+// ZDM_PROXY_SUPPORTED_OPTIONS (an override without values removes the option).
+func newLocalSupportedOptions(
+	originOptions map[string][]string, targetOptions map[string][]string,
+	overrides map[string][]string) map[string][]string {
+	options := make(map[string][]string, len(originOptions)+len(overrides))
+	for key, values := range originOptions {
+		options[key] = values
+	}
+
+	options[supportedOptionCompression] = []string{compressionLz4, compressionSnappy}
+
+	if versions, ok := options[supportedOptionProtocolVersions]; ok {
+		targetVersions, targetAdvertisesVersions := targetOptions[supportedOptionProtocolVersions]
+		filtered := make([]string, 0, len(versions))
+		for _, version := range versions {
+			if !isProtocolVersionSupportedByProxy(version) {
+				continue
+			}
+			if targetAdvertisesVersions && !containsProtocolVersion(targetVersions, version) {
+				continue
+			}
+			filtered = append(filtered, version)
+		}
+		options[supportedOptionProtocolVersions] = filtered
+	}
+
+	for key, values := range overrides {
+		if len(values) == 0 {
+			delete(options, key)
+		} else {
+			options[key] = values
+		}
+	}
+	return options
+}
+
+// parseProtocolVersionOption returns the number of an entry of PROTOCOL_VERSIONS, e.g. 4 for "4/v4" or 5 for
+// "5/v5-beta".
+func parseProtocolVersionOption(version string) (int, bool) {
+	number := version
+	if i := strings.Index(version, "/"); i >= 0 {
+		number = version[:i]
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(number))
+	return value, err == nil
+}
+
+func isProtocolVersionSupportedByProxy(version string) bool {
+	number, ok := parseProtocolVersionOption(version)
+	if !ok {
+		return false
+	}
+	// v1 and v2 are parsed by the protocol library but the proxy only supports v3, v4, DSE_V1 and DSE_V2
+	return number >= int(primitive.ProtocolVersion3) && checkProtocolVersion(primitive.ProtocolVersion(number)) == nil
+}
+
+func containsProtocolVersion(versions []string, version string) bool {
+	number, ok := parseProtocolVersionOption(version)
+	if !ok {
+		return false
+	}
+	for _, v := range versions {
+		if n, ok := parseProtocolVersionOption(v); ok && n == number {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package zdmproxy
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNewLocalSupportedOptions(t *testing.T) {
+	originOptions := map[string][]string{
+		"CQL_VERSION":       {"3.4.5"},
+		"COMPRESSION":       {"snappy", "lz4", "zstd"},
+		"PROTOCOL_VERSIONS": {"3/v3", "4/v4", "5/v5", "65/dse-v1", "66/dse-v2"},
+	}
+
+	tests := []struct {
+		name          string
+		targetOptions map[string][]string
+		overrides     map[string][]string
+		expected      map[string][]string
+	}{
+		{
+			name:          "versions supported by the proxy",
+			targetOptions: nil,
+			overrides:     map[string][]string{},
+			expected: map[string][]string{
+				"CQL_VERSION":       {"3.4.5"},
+				"COMPRESSION":       {"lz4", "snappy"},
+				"PROTOCOL_VERSIONS": {"3/v3", "4/v4", "65/dse-v1", "66/dse-v2"},
+			},
+		},
+		{
+			name:          "versions supported by target",
+			targetOptions: map[string][]string{"PROTOCOL_VERSIONS": {"3/v3", "4/v4", "5/v5-beta"}},
+			overrides:     map[string][]string{},
+			expected: map[string][]string{
+				"CQL_VERSION":       {"3.4.5"},
+				"COMPRESSION":       {"lz4", "snappy"},
+				"PROTOCOL_VERSIONS": {"3/v3", "4/v4"},
+			},
+		},
+		{
+			name:          "overrides",
+			targetOptions: map[string][]string{"PROTOCOL_VERSIONS": {"3/v3", "4/v4"}},
+			overrides: map[string][]string{
+				"COMPRESSION":       {"lz4"},
+				"CQL_VERSION":       {},
+				"PROTOCOL_VERSIONS": {"4/v4"},
+				"CUSTOM":            {"a", "b"},
+			},
+			expected: map[string][]string{
+				"COMPRESSION":       {"lz4"},
+				"PROTOCOL_VERSIONS": {"4/v4"},
+				"CUSTOM":            {"a", "b"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := newLocalSupportedOptions(originOptions, tt.targetOptions, tt.overrides)
+			require.Equal(t, tt.expected, options)
+		})
+	}
+	require.Equal(t, []string{"snappy", "lz4", "zstd"}, originOptions["COMPRESSION"], "origin options are not modified")
+}
@@ -0,0 +1,152 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+)
+
+// The paging states returned to the client are prefixed with a tag that identifies the cluster that issued them.
+// A paging state is meaningless to the other cluster, which would otherwise receive it when a speculative read of
+// the previous page won, when the read is mirrored to the secondary cluster or when ZDM_PRIMARY_CLUSTER changed while
+// the client was paging through a result.
+var pagingStateTagPrefix = []byte{'z', 'd', 'm', 1}
+
+const (
+	pagingStateTagOrigin = byte(1)
+	pagingStateTagTarget = byte(2)
+)
+
+// tagPagingState returns the paging state prefixed with the tag of the cluster that issued it.
+func tagPagingState(pagingState []byte, clusterType common.ClusterType) []byte {
+	var tag byte
+	switch clusterType {
+	case common.ClusterTypeOrigin:
+		tag = pagingStateTagOrigin
+	case common.ClusterTypeTarget:
+		tag = pagingStateTagTarget
+	default:
+		return pagingState
+	}
+
+	tagged := make([]byte, 0, len(pagingStateTagPrefix)+1+len(pagingState))
+	tagged = append(tagged, pagingStateTagPrefix...)
+	tagged = append(tagged, tag)
+	return append(tagged, pagingState...)
+}
+
+// untagPagingState returns the paging state issued by the cluster and that cluster, or ClusterTypeNone if the paging
+// state was not tagged by the proxy (e.g. it was returned before ZDM_TAG_PAGING_STATES was enabled).
+func untagPagingState(pagingState []byte) ([]byte, common.ClusterType, error) {
+	if len(pagingState) <= len(pagingStateTagPrefix) || !bytes.HasPrefix(pagingState, pagingStateTagPrefix) {
+		return pagingState, common.ClusterTypeNone, nil
+	}
+
+	tag := pagingState[len(pagingStateTagPrefix)]
+	untagged := pagingState[len(pagingStateTagPrefix)+1:]
+	switch tag {
+	case pagingStateTagOrigin:
+		return untagged, common.ClusterTypeOrigin, nil
+	case pagingStateTagTarget:
+		return untagged, common.ClusterTypeTarget, nil
+	default:
+		return nil, common.ClusterTypeNone, fmt.Errorf("invalid paging state, unknown cluster tag %d", tag)
+	}
+}
+
+// pagedRequestInfo is a read of the next page of a result. It is only forwarded to the cluster that issued the paging
+// state, it is never mirrored to the async connector nor sent speculatively.
+type pagedRequestInfo struct {
+	RequestInfo
+	clusterType common.ClusterType
+}
+
+func (recv *pagedRequestInfo) String() string {
+	return fmt.Sprintf("pagedRequestInfo{%v, %v}", recv.clusterType, recv.RequestInfo)
+}
+
+func (recv *pagedRequestInfo) GetForwardDecision() forwardDecision {
+	if recv.clusterType == common.ClusterTypeTarget {
+		return forwardToTarget
+	}
+	return forwardToOrigin
+}
+
+func (recv *pagedRequestInfo) ShouldAlsoBeSentAsync() bool {
+	return false
+}
+
+// untagRequestPagingState removes the tag from the paging state of a QUERY or EXECUTE request. It returns the frame
+// context of the request that can be sent to the cluster and the cluster that issued the paging state, or the same
+// frame context and ClusterTypeNone if the request doesn't have a tagged paging state.
+func untagRequestPagingState(frameContext *frameDecodeContext) (*frameDecodeContext, common.ClusterType, error) {
+	f := frameContext.GetRawFrame()
+	switch f.Header.OpCode {
+	case primitive.OpCodeQuery, primitive.OpCodeExecute:
+	default:
+		return frameContext, common.ClusterTypeNone, nil
+	}
+
+	decodedFrame, err := frameContext.GetOrDecodeFrame()
+	if err != nil {
+		return nil, common.ClusterTypeNone, fmt.Errorf("could not decode frame: %w", err)
+	}
+	var options *message.QueryOptions
+	switch msg := decodedFrame.Body.Message.(type) {
+	case *message.Query:
+		options = msg.Options
+	case *message.Execute:
+		options = msg.Options
+	}
+	if options == nil || options.PagingState == nil {
+		return frameContext, common.ClusterTypeNone, nil
+	}
+
+	pagingState, clusterType, err := untagPagingState(options.PagingState)
+	if err != nil || clusterType == common.ClusterTypeNone {
+		return frameContext, common.ClusterTypeNone, err
+	}
+
+	options.PagingState = pagingState
+	newRawFrame, err := defaultCodec.ConvertToRawFrame(decodedFrame)
+	if err != nil {
+		return nil, common.ClusterTypeNone, fmt.Errorf("could not convert frame with untagged paging state to raw frame: %w", err)
+	}
+	return NewInitializedFrameDecodeContext(newRawFrame, decodedFrame, frameContext.statementsQueryData, frameContext.logger), clusterType, nil
+}
+
+// getPagedRequestInfo forwards the reads with a tagged paging state to the cluster that issued it. The read is
+// rejected if the paging state is invalid or if the connection to that cluster was closed, the client has to restart
+// the query from the first page in that case.
+func (ch *ClientHandler) getPagedRequestInfo(
+	frameContext *frameDecodeContext, requestInfo RequestInfo) (*frameDecodeContext, RequestInfo, error) {
+
+	switch requestInfo.GetForwardDecision() {
+	case forwardToOrigin, forwardToTarget:
+	default:
+		return frameContext, requestInfo, nil
+	}
+
+	header := frameContext.GetRawFrame().Header
+	newFrameContext, clusterType, err := untagRequestPagingState(frameContext)
+	if err != nil {
+		return nil, nil, &RejectedRequestError{Header: header, Reason: err.Error()}
+	}
+	if clusterType == common.ClusterTypeNone {
+		return frameContext, requestInfo, nil
+	}
+
+	connector := ch.originCassandraConnector
+	if clusterType == common.ClusterTypeTarget {
+		connector = ch.targetCassandraConnector
+	}
+	if connector.IsShutdown() {
+		return nil, nil, &RejectedRequestError{
+			Header: header,
+			Reason: fmt.Sprintf("the paging state was issued by %v which is not available, "+
+				"the query has to be restarted from the first page", clusterType)}
+	}
+	return newFrameContext, &pagedRequestInfo{RequestInfo: requestInfo, clusterType: clusterType}, nil
+}
@@ -0,0 +1,79 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTagPagingState(t *testing.T) {
+	pagingState := []byte{0xca, 0xfe}
+	for _, clusterType := range []common.ClusterType{common.ClusterTypeOrigin, common.ClusterTypeTarget} {
+		tagged := tagPagingState(pagingState, clusterType)
+		require.NotEqual(t, pagingState, tagged)
+		untagged, taggedCluster, err := untagPagingState(tagged)
+		require.Nil(t, err)
+		require.Equal(t, clusterType, taggedCluster)
+		require.Equal(t, pagingState, untagged)
+	}
+
+	untagged, clusterType, err := untagPagingState(pagingState)
+	require.Nil(t, err)
+	require.Equal(t, common.ClusterTypeNone, clusterType, "paging state that was not tagged by the proxy")
+	require.Equal(t, pagingState, untagged)
+
+	_, _, err = untagPagingState(append(append([]byte{}, pagingStateTagPrefix...), 42, 0xca, 0xfe))
+	require.NotNil(t, err)
+}
+
+func TestUntagRequestPagingState(t *testing.T) {
+	pagingState := []byte{0xca, 0xfe}
+	newFrameContext := func(pagingState []byte) *frameDecodeContext {
+		f := frame.NewFrame(primitive.ProtocolVersion4, 1, &message.Query{
+			Query:   "SELECT * FROM ks.tb",
+			Options: &message.QueryOptions{PageSize: 100, PagingState: pagingState},
+		})
+		rawFrame, err := defaultCodec.ConvertToRawFrame(f)
+		require.Nil(t, err)
+		return NewFrameDecodeContext(rawFrame, log.NewEntry(log.StandardLogger()))
+	}
+
+	frameContext := newFrameContext(tagPagingState(pagingState, common.ClusterTypeTarget))
+	newContext, clusterType, err := untagRequestPagingState(frameContext)
+	require.Nil(t, err)
+	require.Equal(t, common.ClusterTypeTarget, clusterType)
+	require.NotSame(t, frameContext.GetRawFrame(), newContext.GetRawFrame())
+	decodedFrame, err := defaultCodec.ConvertFromRawFrame(newContext.GetRawFrame())
+	require.Nil(t, err)
+	require.Equal(t, pagingState, decodedFrame.Body.Message.(*message.Query).Options.PagingState)
+
+	frameContext = newFrameContext(pagingState)
+	newContext, clusterType, err = untagRequestPagingState(frameContext)
+	require.Nil(t, err)
+	require.Equal(t, common.ClusterTypeNone, clusterType)
+	require.Same(t, frameContext, newContext)
+
+	frameContext = newFrameContext(nil)
+	newContext, clusterType, err = untagRequestPagingState(frameContext)
+	require.Nil(t, err)
+	require.Equal(t, common.ClusterTypeNone, clusterType)
+	require.Same(t, frameContext, newContext)
+}
+
+func TestPagedRequestInfo(t *testing.T) {
+	requestInfo := &pagedRequestInfo{
+		RequestInfo: NewGenericRequestInfo(forwardToOrigin, true, true),
+		clusterType: common.ClusterTypeTarget,
+	}
+	require.Equal(t, forwardToTarget, requestInfo.GetForwardDecision())
+	require.False(t, requestInfo.ShouldAlsoBeSentAsync())
+	require.True(t, requestInfo.ShouldBeTrackedInMetrics())
+	require.False(t, isSpeculativeReadCandidate(requestInfo))
+
+	continuousPaging := &continuousPagingRequestInfo{RequestInfo: requestInfo}
+	require.Same(t, requestInfo.RequestInfo, unwrapRequestInfo(continuousPaging))
+}
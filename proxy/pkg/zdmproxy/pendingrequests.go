@@ -10,23 +10,23 @@ import (
 	"sync"
 )
 
-const MaxStreams = 2048
-
 type pendingRequests struct {
 	pending     *sync.Map
 	streams     chan int16
 	nodeMetrics *metrics.NodeMetrics
+	logger      *log.Entry
 }
 
-func newPendingRequests(maxStreams int16, nodeMetrics *metrics.NodeMetrics) *pendingRequests {
+func newPendingRequests(maxStreams int, nodeMetrics *metrics.NodeMetrics, logger *log.Entry) *pendingRequests {
 	streams := make(chan int16, maxStreams)
-	for i := int16(0); i < maxStreams; i++ {
-		streams <- i
+	for i := 0; i < maxStreams; i++ {
+		streams <- int16(i)
 	}
 	return &pendingRequests{
 		pending:     &sync.Map{},
 		streams:     streams,
 		nodeMetrics: nodeMetrics,
+		logger:      logger,
 	}
 }
 
@@ -45,6 +45,7 @@ func (p *pendingRequests) getOrCreateRequestContextHolder(streamId int16) *reque
 func (p *pendingRequests) store(reqCtx RequestContext) (int16, error) {
 	streamId, err := p.reserveStreamId()
 	if err != nil {
+		p.nodeMetrics.AsyncMetrics.StreamIdsExhausted.Add(1)
 		return -1, fmt.Errorf("stream id map ran out of stream ids: %w", err)
 	}
 	holder := getOrCreateRequestContextHolder(p.pending, streamId)
@@ -59,7 +60,7 @@ func (p *pendingRequests) store(reqCtx RequestContext) (int16, error) {
 func (p *pendingRequests) timeOut(streamId int16, reqCtx RequestContext, req *frame.RawFrame) bool {
 	holder := p.getOrCreateRequestContextHolder(streamId)
 	if reqCtx.SetTimeout(p.nodeMetrics, req) {
-		clearPendingRequestState(streamId, holder, reqCtx)
+		clearPendingRequestState(streamId, holder, reqCtx, p.logger)
 		return true
 	}
 	return false
@@ -68,7 +69,7 @@ func (p *pendingRequests) timeOut(streamId int16, reqCtx RequestContext, req *fr
 func (p *pendingRequests) cancel(streamId int16, reqCtx RequestContext) bool {
 	holder := p.getOrCreateRequestContextHolder(streamId)
 	if reqCtx.Cancel(p.nodeMetrics) {
-		clearPendingRequestState(streamId, holder, reqCtx)
+		clearPendingRequestState(streamId, holder, reqCtx, p.logger)
 		return true
 	}
 	return false
@@ -79,19 +80,19 @@ func (p *pendingRequests) markAsDone(
 	holder := p.getOrCreateRequestContextHolder(streamId)
 	reqCtx := holder.Get()
 	if reqCtx == nil {
-		log.Warnf("Could not find async request context for stream id %d received from async connector. "+
+		p.logger.Warnf("Could not find async request context for stream id %d received from async connector. "+
 			"It either timed out or a protocol error occurred.", streamId)
 		return nil, false
 	}
 	if reqCtx.SetResponse(p.nodeMetrics, f, cluster, connectorType) {
 		var err error
-		if clearPendingRequestState(streamId, holder, reqCtx) {
+		if clearPendingRequestState(streamId, holder, reqCtx, p.logger) {
 			err = p.releaseStreamId(streamId)
 		} else {
 			err = errors.New("could not clear pending request state")
 		}
 		if err != nil {
-			log.Errorf("Could not free stream id %v, this is most likely a bug, please report: %v", streamId, err.Error())
+			p.logger.Errorf("Could not free stream id %v, this is most likely a bug, please report: %v", streamId, err.Error())
 		}
 		return reqCtx, true
 	}
@@ -108,7 +109,7 @@ func (p *pendingRequests) clear(onCancelFunc func(ctx RequestContext)) {
 		canceled := reqCtx.Cancel(p.nodeMetrics)
 		if canceled {
 			onCancelFunc(reqCtx)
-			clearPendingRequestState(key.(int16), reqCtxHolder, reqCtx)
+			clearPendingRequestState(key.(int16), reqCtxHolder, reqCtx, p.logger)
 		}
 		return true
 	})
@@ -132,10 +133,11 @@ func (p *pendingRequests) reserveStreamId() (int16, error) {
 	}
 }
 
-func clearPendingRequestState(streamId int16, holder *requestContextHolder, reqCtx RequestContext) bool {
+func clearPendingRequestState(
+	streamId int16, holder *requestContextHolder, reqCtx RequestContext, logger *log.Entry) bool {
 	err := holder.Clear(reqCtx)
 	if err != nil {
-		log.Debugf("could not clean up pending request with streamid %v: %v", streamId, err.Error())
+		logger.Debugf("could not clean up pending request with streamid %v: %v", streamId, err.Error())
 		return false
 	}
 	return true
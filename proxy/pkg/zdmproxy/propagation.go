@@ -0,0 +1,171 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+)
+
+// responsePropagation applies ZDM_WARNINGS_POLICY and ZDM_CUSTOM_PAYLOAD_POLICY to the responses that are returned to
+// the client. The warnings and the custom payload of the response of the primary cluster are used with PRIMARY_ONLY,
+// those of both clusters with MERGE and none with STRIP. Requests that are only sent to one cluster have a single
+// response so PRIMARY_ONLY and MERGE return its warnings and custom payload even if it is the secondary cluster.
+type responsePropagation struct {
+	warnings       common.PropagationPolicy
+	customPayload  common.PropagationPolicy
+	primaryCluster common.ClusterType
+}
+
+func newResponsePropagation(
+	warnings common.PropagationPolicy, customPayload common.PropagationPolicy,
+	primaryCluster common.ClusterType) *responsePropagation {
+	return &responsePropagation{
+		warnings:       warnings,
+		customPayload:  customPayload,
+		primaryCluster: primaryCluster,
+	}
+}
+
+// apply returns the response with the warnings and the custom payload of the policies. The cluster responses are nil
+// for the clusters that the request wasn't sent to. The response is only decoded if it has to be modified, which is
+// why it must be applied before the response is modified by the proxy (the primary response is found by identity).
+func (recv *responsePropagation) apply(
+	response *frame.RawFrame, originResponse *frame.RawFrame, targetResponse *frame.RawFrame) (*frame.RawFrame, error) {
+	if response.Header.Version < primitive.ProtocolVersion4 {
+		return response, nil
+	}
+
+	primaryResponse, secondaryResponse := originResponse, targetResponse
+	if recv.primaryCluster == common.ClusterTypeTarget {
+		primaryResponse, secondaryResponse = targetResponse, originResponse
+	}
+	if primaryResponse == nil {
+		primaryResponse, secondaryResponse = secondaryResponse, nil
+	}
+	if primaryResponse == nil {
+		return response, nil
+	}
+
+	modifyWarnings := recv.mustModify(recv.warnings, primitive.HeaderFlagWarning, response, primaryResponse, secondaryResponse)
+	modifyCustomPayload := recv.mustModify(
+		recv.customPayload, primitive.HeaderFlagCustomPayload, response, primaryResponse, secondaryResponse)
+	if !modifyWarnings && !modifyCustomPayload {
+		return response, nil
+	}
+
+	primaryBody, err := decodeResponseExtras(primaryResponse)
+	if err != nil {
+		return nil, err
+	}
+	secondaryBody, err := decodeResponseExtras(secondaryResponse)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := defaultCodec.ConvertFromRawFrame(response)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode response: %w", err)
+	}
+	decoded.Header = decoded.Header.Clone()
+
+	if modifyWarnings {
+		switch recv.warnings {
+		case common.PropagationPolicyStrip:
+			decoded.SetWarnings(nil)
+		case common.PropagationPolicyPrimaryOnly:
+			decoded.SetWarnings(primaryBody.Warnings)
+		case common.PropagationPolicyMerge:
+			decoded.SetWarnings(mergeWarnings(primaryBody.Warnings, secondaryBody.Warnings))
+		}
+	}
+	if modifyCustomPayload {
+		switch recv.customPayload {
+		case common.PropagationPolicyStrip:
+			decoded.SetCustomPayload(nil)
+		case common.PropagationPolicyPrimaryOnly:
+			decoded.SetCustomPayload(primaryBody.CustomPayload)
+		case common.PropagationPolicyMerge:
+			decoded.SetCustomPayload(mergeCustomPayloads(primaryBody.CustomPayload, secondaryBody.CustomPayload))
+		}
+	}
+	return defaultCodec.ConvertToRawFrame(decoded)
+}
+
+// mustModify returns false if the response already has what the policy returns for the given header flag, e.g. if
+// the response is the response of the primary cluster and the policy is PRIMARY_ONLY.
+func (recv *responsePropagation) mustModify(
+	policy common.PropagationPolicy, flag primitive.HeaderFlag,
+	response *frame.RawFrame, primaryResponse *frame.RawFrame, secondaryResponse *frame.RawFrame) bool {
+	inResponse := response.Header.Flags.Contains(flag)
+	inPrimary := primaryResponse.Header.Flags.Contains(flag)
+	inSecondary := secondaryResponse != nil && secondaryResponse.Header.Flags.Contains(flag)
+	switch policy {
+	case common.PropagationPolicyStrip:
+		return inResponse
+	case common.PropagationPolicyPrimaryOnly:
+		return response != primaryResponse && (inResponse || inPrimary)
+	case common.PropagationPolicyMerge:
+		return inSecondary || (response != primaryResponse && (inResponse || inPrimary))
+	default:
+		return false
+	}
+}
+
+// decodeResponseExtras decodes the body of a response for its warnings and custom payload, it returns an empty body
+// if the response is nil or has neither of them.
+func decodeResponseExtras(response *frame.RawFrame) (*frame.Body, error) {
+	if response == nil || !(response.Header.Flags.Contains(primitive.HeaderFlagWarning) ||
+		response.Header.Flags.Contains(primitive.HeaderFlagCustomPayload)) {
+		return &frame.Body{}, nil
+	}
+	body, err := defaultCodec.DecodeBody(response.Header, bytes.NewReader(response.Body))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %v response: %w", response.Header.OpCode, err)
+	}
+	return body, nil
+}
+
+func mergeWarnings(primaryWarnings []string, secondaryWarnings []string) []string {
+	if len(secondaryWarnings) == 0 {
+		return primaryWarnings
+	}
+	merged := make([]string, 0, len(primaryWarnings)+len(secondaryWarnings))
+	seen := make(map[string]bool, len(primaryWarnings)+len(secondaryWarnings))
+	for _, warnings := range [][]string{primaryWarnings, secondaryWarnings} {
+		for _, warning := range warnings {
+			if !seen[warning] {
+				seen[warning] = true
+				merged = append(merged, warning)
+			}
+		}
+	}
+	return merged
+}
+
+// mergeCustomPayloads returns the entries of both custom payloads, the primary one wins if both have the same key.
+func mergeCustomPayloads(primaryPayload map[string][]byte, secondaryPayload map[string][]byte) map[string][]byte {
+	if len(secondaryPayload) == 0 {
+		return primaryPayload
+	}
+	merged := make(map[string][]byte, len(primaryPayload)+len(secondaryPayload))
+	for key, value := range secondaryPayload {
+		merged[key] = value
+	}
+	for key, value := range primaryPayload {
+		merged[key] = value
+	}
+	return merged
+}
+
+// countWarnings returns the number of warnings of a response, it only decodes the responses that have warnings.
+func countWarnings(response *frame.RawFrame) (int, error) {
+	if !response.Header.Flags.Contains(primitive.HeaderFlagWarning) {
+		return 0, nil
+	}
+	body, err := decodeResponseExtras(response)
+	if err != nil {
+		return 0, err
+	}
+	return len(body.Warnings), nil
+}
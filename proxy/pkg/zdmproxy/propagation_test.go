@@ -0,0 +1,86 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func newExtrasResponse(t *testing.T, warnings []string, customPayload map[string][]byte) *frame.RawFrame {
+	f := frame.NewFrame(primitive.ProtocolVersion4, 1, &message.VoidResult{})
+	f.SetWarnings(warnings)
+	f.SetCustomPayload(customPayload)
+	rawFrame, err := defaultCodec.ConvertToRawFrame(f)
+	require.Nil(t, err)
+	return rawFrame
+}
+
+func decodeExtras(t *testing.T, response *frame.RawFrame) *frame.Body {
+	body, err := defaultCodec.DecodeBody(response.Header, bytes.NewReader(response.Body))
+	require.Nil(t, err)
+	return body
+}
+
+func TestResponsePropagation(t *testing.T) {
+	origin := newExtrasResponse(t, []string{"origin warning", "shared warning"}, map[string][]byte{"k": {1}, "o": {1}})
+	target := newExtrasResponse(t, []string{"target warning", "shared warning"}, map[string][]byte{"k": {2}, "t": {2}})
+	plain := newExtrasResponse(t, nil, nil)
+
+	tests := []struct {
+		name           string
+		policy         common.PropagationPolicy
+		primaryCluster common.ClusterType
+		response       *frame.RawFrame
+		origin         *frame.RawFrame
+		target         *frame.RawFrame
+		warnings       []string
+		customPayload  map[string][]byte
+		same           bool
+	}{
+		{"primary only, primary response", common.PropagationPolicyPrimaryOnly, common.ClusterTypeOrigin,
+			origin, origin, target, nil, nil, true},
+		{"primary only, secondary response", common.PropagationPolicyPrimaryOnly, common.ClusterTypeTarget,
+			origin, origin, target,
+			[]string{"target warning", "shared warning"}, map[string][]byte{"k": {2}, "t": {2}}, false},
+		{"primary only, single response", common.PropagationPolicyPrimaryOnly, common.ClusterTypeOrigin,
+			target, nil, target, nil, nil, true},
+		{"merge", common.PropagationPolicyMerge, common.ClusterTypeOrigin,
+			origin, origin, target,
+			[]string{"origin warning", "shared warning", "target warning"},
+			map[string][]byte{"k": {1}, "o": {1}, "t": {2}}, false},
+		{"strip", common.PropagationPolicyStrip, common.ClusterTypeOrigin,
+			origin, origin, target, nil, nil, false},
+		{"strip without extras", common.PropagationPolicyStrip, common.ClusterTypeOrigin,
+			plain, plain, nil, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			propagation := newResponsePropagation(tt.policy, tt.policy, tt.primaryCluster)
+			response, err := propagation.apply(tt.response, tt.origin, tt.target)
+			require.Nil(t, err)
+			if tt.same {
+				require.Same(t, tt.response, response)
+				return
+			}
+			body := decodeExtras(t, response)
+			require.Equal(t, tt.warnings, body.Warnings)
+			require.Equal(t, tt.customPayload, body.CustomPayload)
+			require.Equal(t, len(tt.warnings) > 0, response.Header.Flags.Contains(primitive.HeaderFlagWarning))
+			require.IsType(t, &message.VoidResult{}, body.Message)
+		})
+	}
+}
+
+func TestCountWarnings(t *testing.T) {
+	count, err := countWarnings(newExtrasResponse(t, []string{"a", "b"}, nil))
+	require.Nil(t, err)
+	require.Equal(t, 2, count)
+
+	count, err = countWarnings(newExtrasResponse(t, nil, map[string][]byte{"k": {1}}))
+	require.Nil(t, err)
+	require.Equal(t, 0, count)
+}
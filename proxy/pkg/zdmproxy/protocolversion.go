@@ -0,0 +1,126 @@
+package zdmproxy
+
+import (
+	"context"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"time"
+)
+
+// protocolVersionTranslator translates the frames of a client connection that uses a protocol version that the cluster
+// of a connector doesn't support, see ZDM_PROTOCOL_VERSION_TRANSLATION_ENABLED. Requests are translated to the version
+// of the cluster and responses (including events) back to the version of the client.
+type protocolVersionTranslator struct {
+	clientVersion  primitive.ProtocolVersion
+	clusterVersion primitive.ProtocolVersion
+}
+
+// newProtocolVersionTranslator returns nil if the frames of the client don't need to be translated for a cluster whose
+// highest supported version is clusterMaxVersion (0 if it is unknown). Only v4 clients of v3 clusters are translated,
+// the differences between these versions can be handled by re-encoding the frames, this isn't the case for v5 (which
+// changes the framing) nor for the DSE versions.
+func newProtocolVersionTranslator(
+	clientVersion primitive.ProtocolVersion, clusterMaxVersion primitive.ProtocolVersion) *protocolVersionTranslator {
+	if clientVersion != primitive.ProtocolVersion4 || clusterMaxVersion != primitive.ProtocolVersion3 {
+		return nil
+	}
+	return &protocolVersionTranslator{
+		clientVersion:  clientVersion,
+		clusterVersion: clusterMaxVersion,
+	}
+}
+
+func (recv *protocolVersionTranslator) translateRequest(request *frame.RawFrame) (*frame.RawFrame, error) {
+	return translateFrame(request, recv.clusterVersion)
+}
+
+// translateResponse translates a response of the cluster to the version of the client, a SERVER_ERROR is returned to
+// the client if the response can't be translated.
+func (recv *protocolVersionTranslator) translateResponse(response *frame.RawFrame) (*frame.RawFrame, error) {
+	translated, err := translateFrame(response, recv.clientVersion)
+	if err == nil {
+		return translated, nil
+	}
+	errorResponse, err2 := defaultCodec.ConvertToRawFrame(frame.NewFrame(
+		recv.clientVersion, response.Header.StreamId, &message.ServerError{
+			ErrorMessage: fmt.Sprintf("Could not translate the response from protocol version %v to %v: %v",
+				response.Header.Version, recv.clientVersion, err)}))
+	if err2 != nil {
+		return nil, fmt.Errorf("could not encode error response (%v): %w", err, err2)
+	}
+	return errorResponse, err
+}
+
+// translateFrame decodes the frame and encodes it again with the given version. Whatever can't be encoded with the
+// older version is removed (custom payloads and warnings) or fails the translation (e.g. unset values).
+func translateFrame(f *frame.RawFrame, version primitive.ProtocolVersion) (*frame.RawFrame, error) {
+	if f.Header.Version == version {
+		return f, nil
+	}
+	decoded, err := defaultCodec.ConvertFromRawFrame(f)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %v frame: %w", f.Header.OpCode, err)
+	}
+	// the decoded frame shares the header of the raw frame
+	decoded.Header = decoded.Header.Clone()
+	decoded.Header.Version = version
+	if version < primitive.ProtocolVersion4 {
+		decoded.SetCustomPayload(nil)
+		decoded.SetWarnings(nil)
+	}
+	translated, err := defaultCodec.ConvertToRawFrame(decoded)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode %v frame with protocol version %v: %w",
+			f.Header.OpCode, version, err)
+	}
+	return translated, nil
+}
+
+// probeProtocolVersion returns the highest protocol version that the cluster supports. It sends an OPTIONS request
+// with v4 on a new connection, clusters that don't support v4 answer with a protocol error that is encoded with the
+// highest version that they support.
+func probeProtocolVersion(
+	connConfig ConnectionConfig, endpoint Endpoint, ctx context.Context) (primitive.ProtocolVersion, error) {
+	conn, _, err := openConnection(connConfig, endpoint, ctx, false)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	err = conn.SetDeadline(time.Now().Add(ccReadTimeout))
+	if err != nil {
+		return 0, err
+	}
+	response, err := exchangeFrame(conn, frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Options{}))
+	if err != nil {
+		return 0, err
+	}
+	switch msg := response.Body.Message.(type) {
+	case *message.Supported:
+		return primitive.ProtocolVersion4, nil
+	case *message.ProtocolError:
+		return response.Header.Version, nil
+	default:
+		return 0, fmt.Errorf("expected SUPPORTED or PROTOCOL_ERROR but got %v", msg)
+	}
+}
+
+func (cc *ClusterConnector) setProtocolVersionTranslator(translator *protocolVersionTranslator) {
+	cc.versionTranslator.Store(translator)
+}
+
+func (cc *ClusterConnector) getProtocolVersionTranslator() *protocolVersionTranslator {
+	return cc.versionTranslator.Load().(*protocolVersionTranslator)
+}
+
+// translateRequest returns the request translated to the protocol version of the cluster, requests that are already
+// encoded with that version are returned as is.
+func (cc *ClusterConnector) translateRequest(request *frame.RawFrame) (*frame.RawFrame, error) {
+	translator := cc.getProtocolVersionTranslator()
+	if translator == nil {
+		return request, nil
+	}
+	return translator.translateRequest(request)
+}
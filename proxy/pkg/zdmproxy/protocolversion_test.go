@@ -0,0 +1,79 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNewProtocolVersionTranslator(t *testing.T) {
+	require.NotNil(t, newProtocolVersionTranslator(primitive.ProtocolVersion4, primitive.ProtocolVersion3))
+	require.Nil(t, newProtocolVersionTranslator(primitive.ProtocolVersion4, primitive.ProtocolVersion4))
+	require.Nil(t, newProtocolVersionTranslator(primitive.ProtocolVersion3, primitive.ProtocolVersion3))
+	require.Nil(t, newProtocolVersionTranslator(primitive.ProtocolVersion4, 0))
+	require.Nil(t, newProtocolVersionTranslator(primitive.ProtocolVersion5, primitive.ProtocolVersion3))
+	require.Nil(t, newProtocolVersionTranslator(primitive.ProtocolVersionDse2, primitive.ProtocolVersion3))
+}
+
+func TestProtocolVersionTranslator_Request(t *testing.T) {
+	translator := newProtocolVersionTranslator(primitive.ProtocolVersion4, primitive.ProtocolVersion3)
+
+	request := frame.NewFrame(primitive.ProtocolVersion4, 7, &message.Query{
+		Query:   "SELECT * FROM ks.tb WHERE k = ?",
+		Options: &message.QueryOptions{PositionalValues: []*primitive.Value{primitive.NewValue([]byte{1})}},
+	})
+	request.SetCustomPayload(map[string][]byte{"key": {1}})
+	rawRequest, err := defaultCodec.ConvertToRawFrame(request)
+	require.Nil(t, err)
+
+	translated, err := translator.translateRequest(rawRequest)
+	require.Nil(t, err)
+	require.Equal(t, primitive.ProtocolVersion3, translated.Header.Version)
+	require.Equal(t, int16(7), translated.Header.StreamId)
+	require.False(t, translated.Header.Flags.Contains(primitive.HeaderFlagCustomPayload))
+	require.Equal(t, primitive.ProtocolVersion4, rawRequest.Header.Version)
+
+	decoded, err := defaultCodec.ConvertFromRawFrame(translated)
+	require.Nil(t, err)
+	require.Equal(t, request.Body.Message, decoded.Body.Message)
+
+	// a request that is already translated is not translated again
+	same, err := translator.translateRequest(translated)
+	require.Nil(t, err)
+	require.Same(t, translated, same)
+
+	// unset values don't exist in v3
+	unset, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 8, &message.Query{
+		Query:   "INSERT INTO ks.tb (k, v) VALUES (?, ?)",
+		Options: &message.QueryOptions{PositionalValues: []*primitive.Value{primitive.NewValue([]byte{1}), primitive.NewUnsetValue()}},
+	}))
+	require.Nil(t, err)
+	_, err = translator.translateRequest(unset)
+	require.NotNil(t, err)
+}
+
+func TestProtocolVersionTranslator_Response(t *testing.T) {
+	translator := newProtocolVersionTranslator(primitive.ProtocolVersion4, primitive.ProtocolVersion3)
+
+	response, err := defaultCodec.ConvertToRawFrame(
+		frame.NewFrame(primitive.ProtocolVersion3, 3, &message.VoidResult{}))
+	require.Nil(t, err)
+	translated, err := translator.translateResponse(response)
+	require.Nil(t, err)
+	require.Equal(t, primitive.ProtocolVersion4, translated.Header.Version)
+	require.Equal(t, int16(3), translated.Header.StreamId)
+
+	invalid := &frame.RawFrame{
+		Header: &frame.Header{Version: primitive.ProtocolVersion3, StreamId: 5, OpCode: primitive.OpCodeResult},
+		Body:   []byte{0, 0},
+	}
+	translated, err = translator.translateResponse(invalid)
+	require.NotNil(t, err)
+	require.Equal(t, primitive.ProtocolVersion4, translated.Header.Version)
+	require.Equal(t, int16(5), translated.Header.StreamId)
+	decoded, err := defaultCodec.ConvertFromRawFrame(translated)
+	require.Nil(t, err)
+	require.IsType(t, &message.ServerError{}, decoded.Body.Message)
+}
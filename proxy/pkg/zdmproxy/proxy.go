@@ -5,16 +5,23 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/audit"
 	"github.com/datastax/zdm-proxy/proxy/pkg/common"
 	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/fleet"
+	"github.com/datastax/zdm-proxy/proxy/pkg/journal"
 	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
 	"github.com/datastax/zdm-proxy/proxy/pkg/metrics/noopmetrics"
 	"github.com/datastax/zdm-proxy/proxy/pkg/metrics/prommetrics"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics/statsdmetrics"
+	"github.com/datastax/zdm-proxy/proxy/pkg/secrets"
 	"github.com/jpillora/backoff"
 	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 	"math/rand"
 	"net"
+	"net/http"
+	"net/url"
 	"runtime"
 	"sync"
 	"sync/atomic"
@@ -25,6 +32,9 @@ type ZdmProxy struct {
 	Conf           *config.Config
 	TopologyConfig *common.TopologyConfig
 
+	// logger of the proxy and of the components that it creates, see NewZdmProxyWithLogger
+	logger *log.Entry
+
 	originConnectionConfig ConnectionConfig
 	targetConnectionConfig ConnectionConfig
 
@@ -32,9 +42,98 @@ type ZdmProxy struct {
 
 	timeUuidGenerator TimeUuidGenerator
 
-	primaryCluster    common.ClusterType
-	readMode          common.ReadMode
-	systemQueriesMode common.SystemQueriesMode
+	primaryCluster     common.ClusterType
+	readMode           common.ReadMode
+	shadowModeEnabled  bool
+	systemQueriesMode  common.SystemQueriesMode
+	lwtPolicy          common.LwtPolicy
+	counterWritePolicy common.CounterWritePolicy
+	ddlPolicy          common.DdlPolicy
+	eventSourcePolicy  common.EventSourcePolicy
+	ipFamilyPreference common.IpFamilyPreference
+
+	systemQueriesBypass *common.SystemQueriesBypass
+
+	warningsPolicy      common.PropagationPolicy
+	customPayloadPolicy common.PropagationPolicy
+
+	credentialMapper *CredentialMapper
+	secretStore      *secrets.Store
+
+	// nil unless ZDM_TARGET_CIRCUIT_BREAKER_ENABLED is true
+	targetCircuitBreaker *CircuitBreaker
+
+	// nil unless ZDM_MIGRATION_PHASE is set
+	migrationPhaseTracker *migrationPhaseTracker
+
+	// nil unless ZDM_FLEET_CONFIG_BACKEND is set
+	fleetWatcher *fleet.Watcher
+
+	// nil unless ZDM_SCHEMA_DRIFT_CHECK_INTERVAL_MS is greater than 0
+	schemaDriftDetector *schemaDriftDetector
+
+	// nil unless ZDM_FAILED_WRITES_JOURNAL_ENABLED is true
+	failedWritesJournal *journal.FileJournal
+
+	// nil unless ZDM_AUDIT_LOG_SINKS is set or sinks are registered with AddAuditSink
+	auditLog   *audit.Log
+	auditSinks []audit.Sink
+
+	retryPolicies *RetryPolicies
+
+	requestTimeouts *common.RequestTimeouts
+
+	// nil unless ZDM_PROXY_INTROSPECTION_ENABLED is true
+	introspectionTables *IntrospectionTables
+
+	// nil unless ZDM_DEBUG_ENDPOINT_ENABLED is true
+	diagnostics *diagnostics
+
+	// nil unless ZDM_QUERY_REWRITE_RULES_FILE is set
+	queryRewriteRules []*common.QueryRewriteRule
+	queryRewriter     *queryRewriter
+
+	// nil unless ZDM_TARGET_NAME_MAPPING is set
+	targetNameMapping *common.NameMapping
+	targetNameMapper  *targetNameMapper
+
+	// nil unless ZDM_TABLE_ROUTING_RULES is set
+	tableRouting *common.TableRouting
+
+	// nil unless ZDM_ORIGIN_CONSISTENCY_OVERRIDE or ZDM_TARGET_CONSISTENCY_OVERRIDE is set
+	consistencyOverrides *consistencyOverrides
+
+	// nil unless ZDM_PROXY_ANSWER_OPTIONS is true
+	optionsResponder *optionsResponder
+
+	// nil unless ZDM_FAULT_INJECTION_ENABLED is true and faults are configured for the cluster
+	originFaultInjector *faultInjector
+	targetFaultInjector *faultInjector
+
+	// registered with AddInterceptor, interceptorChain is nil if there are none
+	interceptors     []Interceptor
+	interceptorChain *interceptorChain
+
+	// registered with SetDialer, the connections are opened with net.Dialer if they are nil
+	originDialer Dialer
+	targetDialer Dialer
+
+	// built-in policies of ZDM_ORIGIN_HOST_SELECTION_POLICY and ZDM_TARGET_HOST_SELECTION_POLICY unless they are
+	// replaced with SetHostSelectionPolicy
+	originHostSelectionPolicy HostSelectionPolicy
+	targetHostSelectionPolicy HostSelectionPolicy
+
+	// registered with AddClientConnectListener and AddClusterDownListener
+	clientConnectListeners []ClientConnectListener
+	clusterDownListeners   []ClusterDownListener
+
+	// registered with SetMetricsRegisterer, the Prometheus metrics are registered with prometheus.DefaultRegisterer
+	// if it is nil
+	metricsRegisterer prometheus.Registerer
+
+	// nil if the connections are opened with a dialer registered with SetDialer or if ZDM_DNS_CACHE_MAX_TTL_MS is 0
+	originDnsCache *dnsCache
+	targetDnsCache *dnsCache
 
 	proxyRand *rand.Rand
 
@@ -42,6 +141,8 @@ type ZdmProxy struct {
 
 	// Listener that enables the proxy to listen for clients on the port specified in the configuration
 	clientListener net.Listener
+	// nil unless ZDM_PROXY_LISTEN_SOCKET_PATH is set
+	socketListener net.Listener
 	listenerLock   *sync.Mutex
 	listenerClosed bool
 
@@ -66,12 +167,15 @@ type ZdmProxy struct {
 	readNumWorkers            int
 	writeNumWorkers           int
 	listenerNumWorkers        int
+	schedulerShards           int
 
 	requestResponseScheduler *Scheduler
 	writeScheduler           *Scheduler
 	readScheduler            *Scheduler
 	listenerScheduler        *Scheduler
 
+	writeBufferPool *bufferPool
+
 	clientHandlersShutdownRequestCtx      context.Context
 	clientHandlersShutdownRequestCancelFn context.CancelFunc
 	globalClientHandlersWg                *sync.WaitGroup
@@ -80,8 +184,14 @@ type ZdmProxy struct {
 }
 
 func NewZdmProxy(conf *config.Config) (*ZdmProxy, error) {
+	return NewZdmProxyWithLogger(conf, log.StandardLogger())
+}
+
+// NewZdmProxyWithLogger is NewZdmProxy for a proxy that logs with the provided logger instead of the standard logger.
+func NewZdmProxyWithLogger(conf *config.Config, logger *log.Logger) (*ZdmProxy, error) {
 	zdmProxy := &ZdmProxy{
-		Conf: conf,
+		Conf:   conf,
+		logger: log.NewEntry(logger),
 	}
 	err := zdmProxy.initializeGlobalStructures()
 	if err != nil {
@@ -91,13 +201,65 @@ func NewZdmProxy(conf *config.Config) (*ZdmProxy, error) {
 	return zdmProxy, nil
 }
 
+// AddInterceptor registers a request interceptor, it must be called before Start. Interceptors are called in the order
+// in which they are registered.
+func (p *ZdmProxy) AddInterceptor(interceptor Interceptor) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.interceptors = append(p.interceptors, interceptor)
+}
+
+// AddAuditSink registers a destination of the audit events in addition to the sinks of ZDM_AUDIT_LOG_SINKS, it must
+// be called before Start. The sink is closed when the proxy shuts down.
+func (p *ZdmProxy) AddAuditSink(sink audit.Sink) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.auditSinks = append(p.auditSinks, sink)
+}
+
+// SetDialer registers the dialer that opens the connections to a cluster, it must be called before Start. The egress
+// proxy of the cluster, if one is configured, is reached with this dialer.
+func (p *ZdmProxy) SetDialer(clusterType common.ClusterType, dialer Dialer) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	switch clusterType {
+	case common.ClusterTypeOrigin:
+		p.originDialer = dialer
+	case common.ClusterTypeTarget:
+		p.targetDialer = dialer
+	}
+}
+
+// SetHostSelectionPolicy registers the policy that selects the host of a cluster that each client connection is
+// assigned to, it must be called before Start. It replaces the policy of ZDM_ORIGIN_HOST_SELECTION_POLICY or
+// ZDM_TARGET_HOST_SELECTION_POLICY and only takes effect if host assignment is enabled for the cluster.
+func (p *ZdmProxy) SetHostSelectionPolicy(clusterType common.ClusterType, policy HostSelectionPolicy) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	switch clusterType {
+	case common.ClusterTypeOrigin:
+		p.originHostSelectionPolicy = policy
+	case common.ClusterTypeTarget:
+		p.targetHostSelectionPolicy = policy
+	}
+}
+
+// SetMetricsRegisterer registers the Prometheus registerer of the proxy metrics, it must be called before Start. The
+// HTTP handler of the metric handler serves the metrics of the registerer if it is also a prometheus.Gatherer (e.g. a
+// *prometheus.Registry). It has no effect if the Prometheus sink is disabled.
+func (p *ZdmProxy) SetMetricsRegisterer(registerer prometheus.Registerer) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.metricsRegisterer = registerer
+}
+
 func (p *ZdmProxy) GetMetricHandler() *metrics.MetricHandler {
 	return p.metricHandler
 }
 
 // Start starts up the proxy and start listening for client connections.
 func (p *ZdmProxy) Start(ctx context.Context) error {
-	log.Infof("Validating config...")
+	p.logger.Infof("Validating config...")
 	err := p.Conf.Validate()
 	if err != nil {
 		return fmt.Errorf("invalid config: %w", err)
@@ -111,6 +273,26 @@ func (p *ZdmProxy) Start(ctx context.Context) error {
 		return fmt.Errorf("could not create timeuuid generator: %w", err)
 	}
 
+	p.lock.Lock()
+	p.queryRewriter = newQueryRewriter(p.queryRewriteRules, p.Conf.QueryRewriteDryRun, p.timeUuidGenerator)
+	p.targetNameMapper = newTargetNameMapper(p.targetNameMapping, p.timeUuidGenerator)
+	p.lock.Unlock()
+
+	err = p.resolveSecrets(ctx)
+	if err != nil {
+		return err
+	}
+
+	logRedactionMode, err := p.Conf.ParseLogRedaction()
+	if err != nil {
+		return err
+	}
+	logRedactor, err := newLogRedactor(logRedactionMode, p.secretStore.GetString(p.Conf.LogRedactionHashKey))
+	if err != nil {
+		return err
+	}
+	setLogRedactor(logRedactor)
+
 	p.lock.Lock()
 	p.proxyTlsConfig, err = p.Conf.ParseProxyTlsConfig(true)
 	p.lock.Unlock()
@@ -121,14 +303,14 @@ func (p *ZdmProxy) Start(ctx context.Context) error {
 
 	var serverSideTlsConfig *tls.Config
 	if p.proxyTlsConfig.TlsEnabled {
-		serverSideTlsConfig, err = getServerSideTlsConfigFromProxyClusterTlsConfig(p.proxyTlsConfig)
+		serverSideTlsConfig, err = getServerSideTlsConfigFromProxyClusterTlsConfig(p.proxyTlsConfig, p.secretStore, p.logger)
 
 		if err != nil {
 			return fmt.Errorf("could not create server side tls.Config object: %w", err)
 		}
 	}
 
-	log.Infof("Starting proxy...")
+	p.logger.Infof("Starting proxy...")
 
 	err = p.initializeControlConnections(ctx)
 	if err != nil {
@@ -145,7 +327,7 @@ func (p *ZdmProxy) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to initialize proxy, could not get assigned origin hosts: %w", err)
 	}
 
-	log.Infof("Initialized origin control connection. Cluster Name: %v, Hosts: %v, Assigned Hosts: %v.",
+	p.logger.Infof("Initialized origin control connection. Cluster Name: %v, Hosts: %v, Assigned Hosts: %v.",
 		p.originControlConn.GetClusterName(), originHosts, originAssignedHosts)
 
 	targetHosts, err := p.targetControlConn.GetHostsInLocalDatacenter()
@@ -158,7 +340,7 @@ func (p *ZdmProxy) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to initialize proxy, could not get assigned target hosts: %w", err)
 	}
 
-	log.Infof("Initialized target control connection. Cluster Name: %v, Hosts: %v, Assigned Hosts: %v.",
+	p.logger.Infof("Initialized target control connection. Cluster Name: %v, Hosts: %v, Assigned Hosts: %v.",
 		p.targetControlConn.GetClusterName(), targetHosts, targetAssignedHosts)
 
 	err = p.initializeMetricHandler()
@@ -166,15 +348,134 @@ func (p *ZdmProxy) Start(ctx context.Context) error {
 		return err
 	}
 
+	p.startFleetWatcher(ctx)
+	if p.schemaDriftDetector != nil {
+		p.schemaDriftDetector.run(p.controlConnShutdownCtx, p.controlConnShutdownWg)
+	}
+
 	err = p.acceptConnectionsFromClients(p.Conf.ProxyListenAddress, p.Conf.ProxyListenPort, serverSideTlsConfig)
 	if err != nil {
 		return err
 	}
 
-	log.Infof("Proxy connected and ready to accept queries on %v:%d", p.Conf.ProxyListenAddress, p.Conf.ProxyListenPort)
+	if p.Conf.ProxyListenSocketPath != "" {
+		err = p.acceptConnectionsFromUnixSocket(p.Conf.ProxyListenSocketPath, serverSideTlsConfig)
+		if err != nil {
+			p.closeClientListeners()
+			return err
+		}
+		p.logger.Infof("Proxy connected and ready to accept queries on %v and on unix socket %v",
+			JoinHostPort(p.Conf.ProxyListenAddress, p.Conf.ProxyListenPort), p.Conf.ProxyListenSocketPath)
+		return nil
+	}
+
+	p.logger.Infof("Proxy connected and ready to accept queries on %v", JoinHostPort(p.Conf.ProxyListenAddress, p.Conf.ProxyListenPort))
+	return nil
+}
+
+// resolveSecrets fetches every setting that refers to a secret and, if there is at least one,
+// refreshes them periodically so that new connections use rotated secrets.
+func (p *ZdmProxy) resolveSecrets(ctx context.Context) error {
+	settings := []string{
+		p.Conf.OriginUsername, p.Conf.OriginPassword, p.Conf.TargetUsername, p.Conf.TargetPassword,
+		p.Conf.OriginTlsServerCaPath, p.Conf.OriginTlsClientCertPath, p.Conf.OriginTlsClientKeyPath,
+		p.Conf.TargetTlsServerCaPath, p.Conf.TargetTlsClientCertPath, p.Conf.TargetTlsClientKeyPath,
+		p.Conf.ProxyTlsCaPath, p.Conf.ProxyTlsCertPath, p.Conf.ProxyTlsKeyPath,
+		p.Conf.OriginEgressProxyUsername, p.Conf.OriginEgressProxyPassword,
+		p.Conf.TargetEgressProxyUsername, p.Conf.TargetEgressProxyPassword,
+		p.Conf.LogRedactionHashKey, p.Conf.FleetConfigToken,
+	}
+	if p.credentialMapper != nil {
+		for _, mapping := range p.credentialMapper.mappings {
+			settings = append(settings, mapping.ClientPassword,
+				mapping.OriginUsername, mapping.OriginPassword, mapping.TargetUsername, mapping.TargetPassword)
+		}
+	}
+
+	for _, setting := range settings {
+		if _, err := p.secretStore.Resolve(ctx, setting); err != nil {
+			return err
+		}
+	}
+
+	if p.secretStore.HasSecrets() {
+		refreshInterval := time.Duration(p.Conf.SecretsRefreshIntervalMs) * time.Millisecond
+		p.logger.Infof("Secrets resolved, they will be refreshed every %v.", refreshInterval)
+		if refreshInterval > 0 {
+			p.secretStore.RefreshPeriodically(p.controlConnShutdownCtx, refreshInterval, p.controlConnShutdownWg)
+		}
+	}
 	return nil
 }
 
+// getClusterCredentials returns the configured credentials of a cluster, with the latest values of the secrets
+// they refer to.
+func (p *ZdmProxy) getClusterCredentials(clusterType common.ClusterType) *AuthCredentials {
+	if clusterType == common.ClusterTypeTarget {
+		return &AuthCredentials{
+			Username: p.secretStore.GetString(p.Conf.TargetUsername),
+			Password: p.secretStore.GetString(p.Conf.TargetPassword),
+		}
+	}
+	return &AuthCredentials{
+		Username: p.secretStore.GetString(p.Conf.OriginUsername),
+		Password: p.secretStore.GetString(p.Conf.OriginPassword),
+	}
+}
+
+// createDialer returns the dialer registered with SetDialer for the cluster, or net.Dialer with a DNS cache, wrapped
+// by the dialer of the egress proxy of the cluster if one is configured.
+func (p *ZdmProxy) createDialer(clusterType common.ClusterType) (Dialer, error) {
+	p.lock.RLock()
+	dialer := p.originDialer
+	if clusterType == common.ClusterTypeTarget {
+		dialer = p.targetDialer
+	}
+	p.lock.RUnlock()
+
+	var proxyUrl *url.URL
+	var username, password string
+	var err error
+	if clusterType == common.ClusterTypeTarget {
+		proxyUrl, err = p.Conf.ParseTargetEgressProxyUrl()
+		username, password = p.Conf.TargetEgressProxyUsername, p.Conf.TargetEgressProxyPassword
+	} else {
+		proxyUrl, err = p.Conf.ParseOriginEgressProxyUrl()
+		username, password = p.Conf.OriginEgressProxyUsername, p.Conf.OriginEgressProxyPassword
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if dialer == nil {
+		dialer = &net.Dialer{}
+		if p.Conf.DnsCacheMaxTtlMs > 0 {
+			cache := newDnsCache(getDefaultDnsClient().LookupHost,
+				time.Duration(p.Conf.DnsCacheMinTtlMs)*time.Millisecond,
+				time.Duration(p.Conf.DnsCacheMaxTtlMs)*time.Millisecond, p.logger)
+			p.lock.Lock()
+			if clusterType == common.ClusterTypeTarget {
+				p.targetDnsCache = cache
+			} else {
+				p.originDnsCache = cache
+			}
+			p.lock.Unlock()
+			dialer = &dnsCachingDialer{cache: cache, forward: dialer}
+		}
+	}
+	if proxyUrl == nil {
+		return dialer, nil
+	}
+
+	p.logger.Infof("Connections to %v will be opened through egress proxy %v.", clusterType, proxyUrl)
+	dialer, err = newEgressProxyDialer(
+		proxyUrl, p.secretStore.GetString(username), p.secretStore.GetString(password), dialer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid egress proxy configuration for %v: %w", clusterType, err)
+	}
+	return dialer, nil
+}
+
 func (p *ZdmProxy) initializeControlConnections(ctx context.Context) error {
 	var err error
 
@@ -183,7 +484,7 @@ func (p *ZdmProxy) initializeControlConnections(ctx context.Context) error {
 		return fmt.Errorf("failed to parse topology config: %w", err)
 	}
 
-	log.Infof("Parsed Topology Config: %v", topologyConfig)
+	p.logger.Infof("Parsed Topology Config: %v", topologyConfig)
 	p.lock.Lock()
 	p.TopologyConfig = topologyConfig
 	p.lock.Unlock()
@@ -194,7 +495,7 @@ func (p *ZdmProxy) initializeControlConnections(ctx context.Context) error {
 	}
 
 	if parsedOriginContactPoints != nil {
-		log.Infof("Parsed Origin contact points: %v", parsedOriginContactPoints)
+		p.logger.Infof("Parsed Origin contact points: %v", parsedOriginContactPoints)
 	}
 
 	parsedTargetContactPoints, err := p.Conf.ParseTargetContactPoints()
@@ -203,7 +504,7 @@ func (p *ZdmProxy) initializeControlConnections(ctx context.Context) error {
 	}
 
 	if parsedTargetContactPoints != nil {
-		log.Infof("Parsed Target contact points: %v", parsedTargetContactPoints)
+		p.logger.Infof("Parsed Target contact points: %v", parsedTargetContactPoints)
 	}
 
 	originTlsConfig, err := p.Conf.ParseOriginTlsConfig(true)
@@ -211,6 +512,11 @@ func (p *ZdmProxy) initializeControlConnections(ctx context.Context) error {
 		return err
 	}
 
+	originDialer, err := p.createDialer(common.ClusterTypeOrigin)
+	if err != nil {
+		return err
+	}
+
 	// Initialize origin connection configuration and control connection endpoint configuration
 	originConnectionConfig, err := InitializeConnectionConfig(originTlsConfig,
 		parsedOriginContactPoints,
@@ -218,6 +524,10 @@ func (p *ZdmProxy) initializeControlConnections(ctx context.Context) error {
 		p.Conf.OriginConnectionTimeoutMs,
 		common.ClusterTypeOrigin,
 		p.Conf.OriginLocalDatacenter,
+		p.secretStore,
+		p.ipFamilyPreference,
+		originDialer,
+		p.logger,
 		ctx)
 	if err != nil {
 		return fmt.Errorf("error initializing the connection configuration or control connection for Origin: %w", err)
@@ -232,6 +542,11 @@ func (p *ZdmProxy) initializeControlConnections(ctx context.Context) error {
 		return err
 	}
 
+	targetDialer, err := p.createDialer(common.ClusterTypeTarget)
+	if err != nil {
+		return err
+	}
+
 	// Initialize target connection configuration and control connection endpoint configuration
 	targetConnectionConfig, err := InitializeConnectionConfig(targetTlsConfig,
 		parsedTargetContactPoints,
@@ -239,6 +554,10 @@ func (p *ZdmProxy) initializeControlConnections(ctx context.Context) error {
 		p.Conf.TargetConnectionTimeoutMs,
 		common.ClusterTypeTarget,
 		p.Conf.TargetLocalDatacenter,
+		p.secretStore,
+		p.ipFamilyPreference,
+		targetDialer,
+		p.logger,
 		ctx)
 	if err != nil {
 		return fmt.Errorf("error initializing the connection configuration or control connection for Target: %w", err)
@@ -247,10 +566,34 @@ func (p *ZdmProxy) initializeControlConnections(ctx context.Context) error {
 	p.targetConnectionConfig = targetConnectionConfig
 	p.lock.Unlock()
 
+	originRemoteDatacenters, err := p.Conf.ParseOriginRemoteDatacenters()
+	if err != nil {
+		return err
+	}
+	targetRemoteDatacenters, err := p.Conf.ParseTargetRemoteDatacenters()
+	if err != nil {
+		return err
+	}
+
+	p.lock.RLock()
+	originHostSelectionPolicy := p.originHostSelectionPolicy
+	targetHostSelectionPolicy := p.targetHostSelectionPolicy
+	p.lock.RUnlock()
+	if p.Conf.OriginEnableHostAssignment {
+		p.logger.Infof("Origin host selection policy: %v", originHostSelectionPolicy.Name())
+	}
+	if p.Conf.TargetEnableHostAssignment {
+		p.logger.Infof("Target host selection policy: %v", targetHostSelectionPolicy.Name())
+	}
+
 	originControlConn := NewControlConn(
-		p.controlConnShutdownCtx, p.Conf.OriginPort, p.originConnectionConfig,
-		p.Conf.OriginUsername, p.Conf.OriginPassword, p.Conf, topologyConfig, p.proxyRand)
+		p.controlConnShutdownCtx, p.Conf.OriginPort, p.originConnectionConfig, originRemoteDatacenters,
+		func() *AuthCredentials { return p.getClusterCredentials(common.ClusterTypeOrigin) },
+		p.Conf, topologyConfig, p.proxyRand, originHostSelectionPolicy, p.logger)
 
+	if len(p.clusterDownListeners) > 0 {
+		originControlConn.clusterDownListener = p.notifyClusterDown
+	}
 	if err := originControlConn.Start(p.controlConnShutdownWg, ctx); err != nil {
 		return fmt.Errorf("failed to initialize origin control connection: %w", err)
 	}
@@ -260,9 +603,13 @@ func (p *ZdmProxy) initializeControlConnections(ctx context.Context) error {
 	p.lock.Unlock()
 
 	targetControlConn := NewControlConn(
-		p.controlConnShutdownCtx, p.Conf.TargetPort, p.targetConnectionConfig,
-		p.Conf.TargetUsername, p.Conf.TargetPassword, p.Conf, topologyConfig, p.proxyRand)
+		p.controlConnShutdownCtx, p.Conf.TargetPort, p.targetConnectionConfig, targetRemoteDatacenters,
+		func() *AuthCredentials { return p.getClusterCredentials(common.ClusterTypeTarget) },
+		p.Conf, topologyConfig, p.proxyRand, targetHostSelectionPolicy, p.logger)
 
+	if len(p.clusterDownListeners) > 0 {
+		targetControlConn.clusterDownListener = p.notifyClusterDown
+	}
 	if err := targetControlConn.Start(p.controlConnShutdownWg, ctx); err != nil {
 		return fmt.Errorf("failed to initialize target control connection: %w", err)
 	}
@@ -271,6 +618,15 @@ func (p *ZdmProxy) initializeControlConnections(ctx context.Context) error {
 	p.targetControlConn = targetControlConn
 	p.lock.Unlock()
 
+	if p.Conf.ProxyAnswerOptions {
+		overrides, err := p.Conf.ParseProxySupportedOptions()
+		if err != nil {
+			return err
+		}
+		p.optionsResponder = newOptionsResponder(originControlConn, targetControlConn, overrides)
+		p.logger.Infof("OPTIONS requests will be answered by the proxy.")
+	}
+
 	return nil
 }
 
@@ -278,14 +634,37 @@ func (p *ZdmProxy) initializeMetricHandler() error {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
-	// This is the Prometheus-specific implementation of the MetricFactory object that will be provided to the global MetricHandler object
-	// To switch to a different implementation, change the type instantiated here to another one that implements
-	// metrics.MetricFactory.
-	// You will also need to change the HTTP handler, see runner.go.
+	// The MetricFactory implementations are selected with ZDM_METRICS_SINKS, when several sinks are enabled every
+	// metric is created in each of them and the HTTP handler is the one of the Prometheus factory.
+	// To add a different implementation, create another type that implements metrics.MetricFactory.
 
 	var metricFactory metrics.MetricFactory
 	if p.Conf.MetricsEnabled {
-		metricFactory = prommetrics.NewPrometheusMetricFactory(prometheus.DefaultRegisterer)
+		sinksConfig, err := p.Conf.ParseMetricsSinks()
+		if err != nil {
+			return err
+		}
+		factories := make([]metrics.MetricFactory, 0)
+		if sinksConfig.Prometheus {
+			registerer := p.metricsRegisterer
+			if registerer == nil {
+				registerer = prometheus.DefaultRegisterer
+			}
+			factories = append(factories, prommetrics.NewPrometheusMetricFactory(registerer, p.logger))
+		}
+		if sinksConfig.Statsd != nil {
+			statsdFactory, err := statsdmetrics.NewStatsdMetricFactory(sinksConfig.Statsd, p.logger)
+			if err != nil {
+				return err
+			}
+			p.logger.Infof("Sending metrics to statsd: %v", sinksConfig.Statsd)
+			factories = append(factories, statsdFactory)
+		}
+		if len(factories) == 1 {
+			metricFactory = factories[0]
+		} else {
+			metricFactory = metrics.NewMultiMetricFactory(factories...)
+		}
 	} else {
 		metricFactory = noopmetrics.NewNoopMetricFactory()
 	}
@@ -299,9 +678,224 @@ func (p *ZdmProxy) initializeMetricHandler() error {
 		metricFactory, p.originBuckets, p.targetBuckets, p.asyncBuckets, proxyMetrics,
 		p.CreateOriginNodeMetrics, p.CreateTargetNodeMetrics, p.CreateAsyncNodeMetrics)
 
+	p.writeBufferPool = newBufferPool(p.Conf.WriteBufferPoolMaxBufferSizeBytes, proxyMetrics.BufferPoolHits, proxyMetrics.BufferPoolMisses)
+
+	if p.originDnsCache != nil {
+		p.originDnsCache.SetFailureCounter(proxyMetrics.DnsResolutionFailuresOrigin)
+	}
+	if p.targetDnsCache != nil {
+		p.targetDnsCache.SetFailureCounter(proxyMetrics.DnsResolutionFailuresTarget)
+	}
+
+	if len(p.interceptors) > 0 {
+		p.interceptorChain, err = newInterceptorChain(p.interceptors, proxyMetrics.Interceptors)
+		if err != nil {
+			return err
+		}
+		p.logger.Infof("Registered %d request interceptor(s).", len(p.interceptors))
+	}
+
+	err = p.initializeFailedWritesJournal(metricFactory)
+	if err != nil {
+		return err
+	}
+	err = p.initializeAuditLog(metricFactory)
+	if err != nil {
+		return err
+	}
+	err = p.initializeFaultInjection(metricFactory)
+	if err != nil {
+		return err
+	}
+	err = p.initializeMigrationPhase(metricFactory)
+	if err != nil {
+		return err
+	}
+	err = p.initializeFleetWatcher(metricFactory)
+	if err != nil {
+		return err
+	}
+	return p.initializeSchemaDriftDetector(metricFactory)
+}
+
+// initializeFailedWritesJournal opens the journal of writes that are applied to ORIGIN but not to TARGET,
+// it must be called while holding the lock.
+func (p *ZdmProxy) initializeFailedWritesJournal(metricFactory metrics.MetricFactory) error {
+	journalConfig, err := p.Conf.ParseFailedWritesJournalConfig()
+	if err != nil {
+		return err
+	}
+	if journalConfig == nil {
+		return nil
+	}
+
+	p.failedWritesJournal, err = journal.NewFileJournal(journalConfig, metricFactory, p.logger)
+	if err != nil {
+		return err
+	}
+	p.logger.Infof("Writes that are applied to %v but not to %v are journaled: %v",
+		common.ClusterTypeOrigin, common.ClusterTypeTarget, journalConfig)
 	return nil
 }
 
+// initializeAuditLog creates the sinks of ZDM_AUDIT_LOG_SINKS and starts the audit log if there is at least one sink,
+// it must be called while holding the lock.
+func (p *ZdmProxy) initializeAuditLog(metricFactory metrics.MetricFactory) error {
+	auditLogConfig, err := p.Conf.ParseAuditLogConfig()
+	if err != nil {
+		return err
+	}
+
+	sinks := make([]audit.Sink, 0, len(p.auditSinks)+3)
+	closeSinks := func() {
+		for _, sink := range sinks {
+			_ = sink.Close()
+		}
+	}
+	if auditLogConfig.FilePath != "" {
+		fileSink, err := audit.NewFileSink(auditLogConfig.FilePath)
+		if err != nil {
+			return err
+		}
+		sinks = append(sinks, fileSink)
+	}
+	if auditLogConfig.SyslogAddress != "" {
+		syslogSink, err := audit.NewSyslogSink(auditLogConfig.SyslogNetwork, auditLogConfig.SyslogAddress)
+		if err != nil {
+			closeSinks()
+			return err
+		}
+		sinks = append(sinks, syslogSink)
+	}
+	if auditLogConfig.KafkaRestUrl != "" {
+		sinks = append(sinks, audit.NewKafkaRestSink(auditLogConfig.KafkaRestUrl, auditLogConfig.KafkaTopic))
+	}
+	sinks = append(sinks, p.auditSinks...)
+	if len(sinks) == 0 {
+		return nil
+	}
+
+	p.auditLog, err = audit.NewLog(auditLogConfig, sinks, metricFactory, p.logger)
+	if err != nil {
+		closeSinks()
+		return err
+	}
+	sinkNames := make([]string, 0, len(sinks))
+	for _, sink := range sinks {
+		sinkNames = append(sinkNames, sink.Name())
+	}
+	p.logger.Infof("Audit log enabled with sinks %v: %v", sinkNames, auditLogConfig)
+	return nil
+}
+
+// initializeFaultInjection creates the fault injectors of the clusters that have faults configured, it must be called
+// while holding the lock.
+func (p *ZdmProxy) initializeFaultInjection(metricFactory metrics.MetricFactory) error {
+	originConfig, err := p.Conf.ParseOriginFaultInjectionConfig()
+	if err != nil {
+		return err
+	}
+	targetConfig, err := p.Conf.ParseTargetFaultInjectionConfig()
+	if err != nil {
+		return err
+	}
+
+	if originConfig != nil {
+		p.originFaultInjector, err = newFaultInjector(common.ClusterTypeOrigin, originConfig, metricFactory)
+		if err != nil {
+			return err
+		}
+		p.logger.Warnf("Injecting faults in the responses of %v, this must not be enabled in production: %v",
+			common.ClusterTypeOrigin, originConfig)
+	}
+	if targetConfig != nil {
+		p.targetFaultInjector, err = newFaultInjector(common.ClusterTypeTarget, targetConfig, metricFactory)
+		if err != nil {
+			return err
+		}
+		p.logger.Warnf("Injecting faults in the responses of %v, this must not be enabled in production: %v",
+			common.ClusterTypeTarget, targetConfig)
+	}
+	return nil
+}
+
+// initializeMigrationPhase creates the tracker of the migration phase if ZDM_MIGRATION_PHASE is set, it must be called
+// while holding the lock.
+func (p *ZdmProxy) initializeMigrationPhase(metricFactory metrics.MetricFactory) error {
+	phase, err := p.Conf.ParseMigrationPhase()
+	if err != nil || phase == common.MigrationPhaseUndefined {
+		return err
+	}
+	p.migrationPhaseTracker, err = newMigrationPhaseTracker(phase, metricFactory)
+	if err != nil {
+		return fmt.Errorf("failed to create migration phase metrics: %w", err)
+	}
+	p.logger.Infof("Migration phase: %v.", phase)
+	return nil
+}
+
+// initializeFleetWatcher creates the watcher of the shared fleet state if ZDM_FLEET_CONFIG_BACKEND is set, it must be
+// called while holding the lock and after the secrets are resolved. The watcher is started by startFleetWatcher.
+func (p *ZdmProxy) initializeFleetWatcher(metricFactory metrics.MetricFactory) error {
+	fleetConfig, err := p.Conf.ParseFleetConfig()
+	if err != nil || fleetConfig == nil {
+		return err
+	}
+
+	var backend fleet.Backend
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	token := p.secretStore.GetString(fleetConfig.Token)
+	switch fleetConfig.Backend {
+	case config.FleetConfigBackendEtcd:
+		backend = fleet.NewEtcdBackend(fleetConfig.Endpoint, fleetConfig.Key, token, httpClient)
+	case config.FleetConfigBackendConsul:
+		backend = fleet.NewConsulBackend(fleetConfig.Endpoint, fleetConfig.Key, token, httpClient)
+	default:
+		return fmt.Errorf("unknown fleet config backend %v", fleetConfig.Backend)
+	}
+	p.fleetWatcher, err = fleet.NewWatcher(backend, fleetConfig.PollInterval, p.applyFleetState, metricFactory, p.logger)
+	if err != nil {
+		return fmt.Errorf("failed to create fleet config metrics: %w", err)
+	}
+	p.logger.Infof("Runtime state is shared with the fleet: %v", fleetConfig)
+	return nil
+}
+
+// initializeSchemaDriftDetector creates the detector of the schema differences between ORIGIN and TARGET if
+// ZDM_SCHEMA_DRIFT_CHECK_INTERVAL_MS is greater than 0, it must be called after the control connections are
+// initialized. The detector is started with the fleet watcher.
+func (p *ZdmProxy) initializeSchemaDriftDetector(metricFactory metrics.MetricFactory) error {
+	if p.Conf.SchemaDriftCheckIntervalMs <= 0 {
+		return nil
+	}
+	keyspaces := p.Conf.ParseSchemaDriftKeyspaces()
+	var err error
+	p.schemaDriftDetector, err = newSchemaDriftDetector(p.originControlConn, p.targetControlConn, keyspaces,
+		p.targetNameMapping, time.Duration(p.Conf.SchemaDriftCheckIntervalMs)*time.Millisecond, metricFactory, p.logger)
+	if err != nil {
+		return fmt.Errorf("failed to create schema drift metrics: %w", err)
+	}
+	if keyspaces == nil {
+		p.logger.Infof("Comparing the schemas of ORIGIN and TARGET every %vms.", p.Conf.SchemaDriftCheckIntervalMs)
+	} else {
+		p.logger.Infof("Comparing the schemas of keyspaces %v of ORIGIN and TARGET every %vms.",
+			keyspaces, p.Conf.SchemaDriftCheckIntervalMs)
+	}
+	return nil
+}
+
+// startFleetWatcher applies the current fleet state before the proxy accepts client connections and then keeps
+// polling the backend. The proxy starts with its own settings if the backend is not reachable.
+func (p *ZdmProxy) startFleetWatcher(ctx context.Context) {
+	if p.fleetWatcher == nil {
+		return
+	}
+	if _, err := p.fleetWatcher.Poll(ctx); err != nil {
+		p.logger.Warnf("Starting with the local settings: %v", err)
+	}
+	p.fleetWatcher.Run(p.controlConnShutdownCtx, p.controlConnShutdownWg)
+}
+
 func (p *ZdmProxy) initializeGlobalStructures() error {
 	p.lock = &sync.RWMutex{}
 
@@ -322,11 +916,141 @@ func (p *ZdmProxy) initializeGlobalStructures() error {
 		return err
 	}
 
+	p.shadowModeEnabled, err = p.Conf.ParseShadowModeEnabled()
+	if err != nil {
+		return err
+	}
+
 	p.systemQueriesMode, err = p.Conf.ParseSystemQueriesMode()
 	if err != nil {
 		return err
 	}
 
+	p.systemQueriesBypass, err = p.Conf.ParseSystemQueriesBypass()
+	if err != nil {
+		return err
+	}
+
+	p.lwtPolicy, err = p.Conf.ParseLwtPolicy()
+	if err != nil {
+		return err
+	}
+
+	p.counterWritePolicy, err = p.Conf.ParseCounterWritePolicy()
+	if err != nil {
+		return err
+	}
+
+	p.ddlPolicy, err = p.Conf.ParseDdlPolicy()
+	if err != nil {
+		return err
+	}
+
+	p.eventSourcePolicy, err = p.Conf.ParseEventSourcePolicy()
+	if err != nil {
+		return err
+	}
+
+	originHostSelectionPolicy, err := p.Conf.ParseOriginHostSelectionPolicy()
+	if err != nil {
+		return err
+	}
+	p.originHostSelectionPolicy, err = NewHostSelectionPolicy(originHostSelectionPolicy)
+	if err != nil {
+		return err
+	}
+
+	targetHostSelectionPolicy, err := p.Conf.ParseTargetHostSelectionPolicy()
+	if err != nil {
+		return err
+	}
+	p.targetHostSelectionPolicy, err = NewHostSelectionPolicy(targetHostSelectionPolicy)
+	if err != nil {
+		return err
+	}
+
+	p.ipFamilyPreference, err = p.Conf.ParseIpFamilyPreference()
+	if err != nil {
+		return err
+	}
+
+	p.warningsPolicy, err = p.Conf.ParseWarningsPolicy()
+	if err != nil {
+		return err
+	}
+
+	p.customPayloadPolicy, err = p.Conf.ParseCustomPayloadPolicy()
+	if err != nil {
+		return err
+	}
+
+	targetCircuitBreakerConfig, err := p.Conf.ParseTargetCircuitBreakerConfig()
+	if err != nil {
+		return err
+	}
+	if targetCircuitBreakerConfig != nil {
+		p.targetCircuitBreaker = NewCircuitBreaker(targetCircuitBreakerConfig, p.logger)
+		p.logger.Infof("Writes are no longer sent to %v while its circuit breaker is open (%v).",
+			common.ClusterTypeTarget, targetCircuitBreakerConfig)
+	}
+
+	p.retryPolicies, err = NewRetryPolicies(p.Conf)
+	if err != nil {
+		return err
+	}
+
+	p.requestTimeouts, err = p.Conf.ParseRequestTimeouts()
+	if err != nil {
+		return err
+	}
+
+	p.queryRewriteRules, err = p.Conf.ParseQueryRewriteRules()
+	if err != nil {
+		return err
+	}
+	if len(p.queryRewriteRules) > 0 {
+		p.logger.Infof("Loaded %d query rewrite rule(s), dry run: %v.", len(p.queryRewriteRules), p.Conf.QueryRewriteDryRun)
+	}
+
+	p.targetNameMapping, err = p.Conf.ParseTargetNameMapping()
+	if err != nil {
+		return err
+	}
+	if p.targetNameMapping != nil {
+		p.logger.Infof("Mapping %d keyspace(s) and %d table(s) to different names on %v.",
+			len(p.targetNameMapping.Keyspaces), len(p.targetNameMapping.Tables), common.ClusterTypeTarget)
+	}
+
+	p.tableRouting, err = p.Conf.ParseTableRoutingRules()
+	if err != nil {
+		return err
+	}
+	if p.tableRouting != nil {
+		p.logger.Infof("Routing the requests of %d keyspace(s) and %d table(s) to a single cluster.",
+			len(p.tableRouting.Keyspaces), len(p.tableRouting.Tables))
+	}
+
+	originConsistencyOverride, err := p.Conf.ParseOriginConsistencyOverride()
+	if err != nil {
+		return err
+	}
+	targetConsistencyOverride, err := p.Conf.ParseTargetConsistencyOverride()
+	if err != nil {
+		return err
+	}
+	p.consistencyOverrides = newConsistencyOverrides(originConsistencyOverride, targetConsistencyOverride)
+
+	p.secretStore = secrets.NewDefaultStore(p.logger)
+
+	if p.Conf.ProxyClientAuthEnabled() {
+		credentialMappings, err := p.Conf.ParseCredentialMappings()
+		if err != nil {
+			return err
+		}
+		p.credentialMapper = NewCredentialMapper(credentialMappings, p.secretStore)
+		p.logger.Infof("Client credentials are validated by the proxy using %d credential mapping(s).", len(credentialMappings))
+	}
+
 	defaultReadWorkers := maxProcs * 8
 	defaultWriteWorkers := maxProcs * 4
 	if p.readMode == common.ReadModeDualAsyncOnSecondary {
@@ -338,42 +1062,51 @@ func (p *ZdmProxy) initializeGlobalStructures() error {
 	if p.requestResponseNumWorkers == -1 {
 		p.requestResponseNumWorkers = maxProcs * 4 // default
 	} else if p.requestResponseNumWorkers <= 0 {
-		log.Warnf("Invalid number of request / response workers %d, using GOMAXPROCS * 4 (%d).", p.requestResponseNumWorkers, maxProcs*4)
+		p.logger.Warnf("Invalid number of request / response workers %d, using GOMAXPROCS * 4 (%d).", p.requestResponseNumWorkers, maxProcs*4)
 		p.requestResponseNumWorkers = maxProcs * 4
 	}
-	log.Infof("Using %d request / response workers.", p.requestResponseNumWorkers)
+	p.logger.Infof("Using %d request / response workers.", p.requestResponseNumWorkers)
 
 	p.writeNumWorkers = p.Conf.WriteMaxWorkers
 	if p.writeNumWorkers == -1 {
 		p.writeNumWorkers = defaultWriteWorkers // default
 	} else if p.writeNumWorkers <= 0 {
-		log.Warnf("Invalid number of write workers %d, using default (%d).", p.writeNumWorkers, defaultWriteWorkers)
+		p.logger.Warnf("Invalid number of write workers %d, using default (%d).", p.writeNumWorkers, defaultWriteWorkers)
 		p.writeNumWorkers = defaultWriteWorkers
 	}
-	log.Infof("Using %d write workers.", p.writeNumWorkers)
+	p.logger.Infof("Using %d write workers.", p.writeNumWorkers)
 
 	p.readNumWorkers = p.Conf.ReadMaxWorkers
 	if p.readNumWorkers == -1 {
 		p.readNumWorkers = defaultReadWorkers // default
 	} else if p.readNumWorkers <= 0 {
-		log.Warnf("Invalid number of read workers %d, using default (%d).", p.readNumWorkers, defaultReadWorkers)
+		p.logger.Warnf("Invalid number of read workers %d, using default (%d).", p.readNumWorkers, defaultReadWorkers)
 		p.readNumWorkers = defaultReadWorkers
 	}
-	log.Infof("Using %d read workers.", p.readNumWorkers)
+	p.logger.Infof("Using %d read workers.", p.readNumWorkers)
 
 	p.listenerNumWorkers = p.Conf.ListenerMaxWorkers
 	if p.listenerNumWorkers == -1 {
 		p.listenerNumWorkers = maxProcs // default
 	} else if p.listenerNumWorkers <= 0 {
-		log.Warnf("Invalid number of connection listener workers %d, using GOMAXPROCS (%d).", p.listenerNumWorkers, maxProcs)
+		p.logger.Warnf("Invalid number of connection listener workers %d, using GOMAXPROCS (%d).", p.listenerNumWorkers, maxProcs)
 		p.listenerNumWorkers = maxProcs
 	}
-	log.Infof("Using %d listener workers.", p.listenerNumWorkers)
+	p.logger.Infof("Using %d listener workers.", p.listenerNumWorkers)
+
+	p.schedulerShards = p.Conf.SchedulerShards
+	if p.schedulerShards == -1 {
+		p.schedulerShards = maxProcs // default
+	} else if p.schedulerShards <= 0 {
+		p.logger.Warnf("Invalid number of scheduler shards %d, using GOMAXPROCS (%d).", p.schedulerShards, maxProcs)
+		p.schedulerShards = maxProcs
+	}
+	p.logger.Infof("Using %d scheduler shards.", p.schedulerShards)
 
-	p.requestResponseScheduler = NewScheduler(p.requestResponseNumWorkers)
-	p.writeScheduler = NewScheduler(p.writeNumWorkers)
-	p.readScheduler = NewScheduler(p.readNumWorkers)
-	p.listenerScheduler = NewScheduler(p.listenerNumWorkers)
+	p.requestResponseScheduler = NewShardedScheduler(p.requestResponseNumWorkers, p.schedulerShards)
+	p.writeScheduler = NewShardedScheduler(p.writeNumWorkers, p.schedulerShards)
+	p.readScheduler = NewShardedScheduler(p.readNumWorkers, p.schedulerShards)
+	p.listenerScheduler = NewShardedScheduler(p.listenerNumWorkers, p.schedulerShards)
 
 	p.lock.Lock()
 	defer p.lock.Unlock()
@@ -381,7 +1114,13 @@ func (p *ZdmProxy) initializeGlobalStructures() error {
 	p.globalClientHandlersWg = &sync.WaitGroup{}
 	p.clientHandlersShutdownRequestCtx, p.clientHandlersShutdownRequestCancelFn = context.WithCancel(context.Background())
 
-	p.PreparedStatementCache = NewPreparedStatementCache()
+	p.PreparedStatementCache = NewPreparedStatementCache(p.Conf.ProxyMaxPreparedStatementCacheSize, p.logger)
+	if p.Conf.ProxyIntrospectionEnabled {
+		p.introspectionTables = NewIntrospectionTables(p.Conf, p.PreparedStatementCache)
+	}
+	if p.Conf.DebugEndpointEnabled {
+		p.diagnostics = newDiagnostics()
+	}
 
 	p.controlConnShutdownCtx, p.controlConnCancelFn = context.WithCancel(context.Background())
 	p.controlConnShutdownWg = &sync.WaitGroup{}
@@ -392,21 +1131,21 @@ func (p *ZdmProxy) initializeGlobalStructures() error {
 	if err != nil {
 		return fmt.Errorf("failed to parse origin latency buckets: %w", err)
 	} else {
-		log.Infof("Parsed Origin latency buckets: %v", p.originBuckets)
+		p.logger.Infof("Parsed Origin latency buckets: %v", p.originBuckets)
 	}
 
 	p.targetBuckets, err = p.Conf.ParseTargetBuckets()
 	if err != nil {
 		return fmt.Errorf("failed to parse target latency buckets: %w", err)
 	} else {
-		log.Infof("Parsed Target latency buckets: %v", p.targetBuckets)
+		p.logger.Infof("Parsed Target latency buckets: %v", p.targetBuckets)
 	}
 
 	p.asyncBuckets, err = p.Conf.ParseAsyncBuckets()
 	if err != nil {
 		return fmt.Errorf("failed to parse async latency buckets: %w", err)
 	} else {
-		log.Infof("Parsed Async latency buckets: %v", p.asyncBuckets)
+		p.logger.Infof("Parsed Async latency buckets: %v", p.asyncBuckets)
 	}
 
 	p.activeClients = 0
@@ -418,16 +1157,21 @@ func (p *ZdmProxy) initializeGlobalStructures() error {
 func (p *ZdmProxy) acceptConnectionsFromClients(address string, port int, serverSideTlsConfig *tls.Config) error {
 
 	protocol := "tcp"
-	listenAddr := fmt.Sprintf("%s:%d", address, port)
+	listenAddr := JoinHostPort(address, port)
 
-	var l net.Listener
-	var err error
-	if serverSideTlsConfig == nil {
-		l, err = net.Listen(protocol, listenAddr)
-	} else {
-		l, err = tls.Listen(protocol, listenAddr, serverSideTlsConfig)
+	proxyProtocolMode, err := p.Conf.ParseProxyListenProxyProtocol()
+	if err != nil {
+		return err
 	}
 
+	// TCP keepalive detects the connections of clients that went away without closing them, the read of the request
+	// listener of these connections fails once the keepalive probes are not answered
+	keepAlive := time.Duration(p.Conf.ProxyClientTcpKeepAliveMs) * time.Millisecond
+	if keepAlive == 0 {
+		keepAlive = -1 // disabled
+	}
+	listenConfig := net.ListenConfig{KeepAlive: keepAlive}
+	l, err := listenConfig.Listen(context.Background(), protocol, listenAddr)
 	if err != nil {
 		return err
 	}
@@ -436,18 +1180,46 @@ func (p *ZdmProxy) acceptConnectionsFromClients(address string, port int, server
 	p.clientListener = l
 	p.listenerLock.Unlock()
 
+	p.serveClientListener(l, fmt.Sprintf("port %d", port), serverSideTlsConfig, proxyProtocolMode)
+	return nil
+}
+
+// acceptConnectionsFromUnixSocket is like acceptConnectionsFromClients but for clients that connect to the unix socket
+// at ZDM_PROXY_LISTEN_SOCKET_PATH, the socket file is removed when the proxy shuts down.
+func (p *ZdmProxy) acceptConnectionsFromUnixSocket(path string, serverSideTlsConfig *tls.Config) error {
+	permissions, err := p.Conf.ParseProxyListenSocketPermissions()
+	if err != nil {
+		return err
+	}
+
+	proxyProtocolMode, err := p.Conf.ParseProxyListenSocketProxyProtocol()
+	if err != nil {
+		return err
+	}
+
+	l, err := listenOnUnixSocket(path, permissions)
+	if err != nil {
+		return err
+	}
+
+	p.listenerLock.Lock()
+	p.socketListener = l
+	p.listenerLock.Unlock()
+
+	p.serveClientListener(l, fmt.Sprintf("unix socket %v", path), serverSideTlsConfig, proxyProtocolMode)
+	return nil
+}
+
+// serveClientListener accepts client connections until the listener is closed. The PROXY protocol header and the TLS
+// handshake (both are optional) are handled in handleNewConnection so that a slow client can't block the listener.
+func (p *ZdmProxy) serveClientListener(
+	l net.Listener, listenerDescription string, serverSideTlsConfig *tls.Config,
+	proxyProtocolMode common.ProxyProtocolMode) {
 	p.listenerShutdownWg.Add(1)
 
 	go func() {
 		defer p.listenerShutdownWg.Done()
-		defer func() {
-			p.listenerLock.Lock()
-			defer p.listenerLock.Unlock()
-			if !p.listenerClosed {
-				p.listenerClosed = true
-				l.Close()
-			}
-		}()
+		defer p.closeClientListeners()
 		for {
 			conn, err := l.Accept()
 			if err != nil {
@@ -456,52 +1228,84 @@ func (p *ZdmProxy) acceptConnectionsFromClients(address string, port int, server
 				p.listenerLock.Unlock()
 
 				if listenerClosed {
-					log.Debugf("Shutting down client listener on port %d", port)
+					p.logger.Debugf("Shutting down client listener on %v", listenerDescription)
 					return
 				}
 
-				log.Errorf("Error while listening for new connections: %v", err)
+				p.logger.Errorf("Error while listening for new connections: %v", err)
 				continue
 			}
 
 			currentClients := atomic.LoadInt32(&p.activeClients)
 			if int(currentClients) >= p.Conf.ProxyMaxClientConnections {
-				log.Warnf(
+				p.logger.Warnf(
 					"Refusing client connection from %v because max clients threshold has been hit (%v).",
 					conn.RemoteAddr(), p.Conf.ProxyMaxClientConnections)
 				err = conn.Close()
 				if err != nil {
-					log.Warnf("Error closing client connection from %v: %v", conn.RemoteAddr(), err)
+					p.logger.Warnf("Error closing client connection from %v: %v", conn.RemoteAddr(), err)
 				}
 				continue
 			}
 
 			atomic.AddInt32(&p.activeClients, 1)
-			log.Infof("Accepted connection from %v", conn.RemoteAddr())
+			p.logger.Infof("Accepted connection from %v", conn.RemoteAddr())
 
 			p.listenerScheduler.Schedule(func() {
-				p.handleNewConnection(conn)
+				p.handleNewConnection(conn, serverSideTlsConfig, proxyProtocolMode)
 			})
 		}
 	}()
+}
 
-	return nil
+func (p *ZdmProxy) closeClientListeners() {
+	p.listenerLock.Lock()
+	defer p.listenerLock.Unlock()
+	if p.listenerClosed {
+		return
+	}
+	p.listenerClosed = true
+	for _, l := range []net.Listener{p.clientListener, p.socketListener} {
+		if l != nil {
+			l.Close()
+		}
+	}
 }
 
 // handleNewConnection creates the client handler and connectors for the new client connection
-func (p *ZdmProxy) handleNewConnection(clientConn net.Conn) {
+func (p *ZdmProxy) handleNewConnection(
+	clientConn net.Conn, serverSideTlsConfig *tls.Config, proxyProtocolMode common.ProxyProtocolMode) {
 
 	errFunc := func(e error) {
-		log.Errorf("Client Handler could not be created: %v", e)
+		p.logger.Errorf("Client Handler could not be created: %v", e)
 		clientConn.Close()
 		atomic.AddInt32(&p.activeClients, -1)
 	}
 
+	var err error
+	if proxyProtocolMode != common.ProxyProtocolModeDisabled {
+		loadBalancerAddr := clientConn.RemoteAddr()
+		ppConn, err := readProxyProtocolHeader(
+			clientConn, proxyProtocolMode, time.Duration(p.Conf.ProxyProtocolHeaderTimeoutMs)*time.Millisecond)
+		if err != nil {
+			errFunc(err)
+			return
+		}
+		clientConn = ppConn
+		p.logger.Debugf("Connection from %v is from client %v according to the PROXY protocol header.",
+			loadBalancerAddr, clientConn.RemoteAddr())
+	}
+
+	if serverSideTlsConfig != nil {
+		clientConn = tls.Server(clientConn, serverSideTlsConfig)
+	}
+	proxyMetrics := p.metricHandler.GetProxyMetrics()
+	clientConn = newMeteredConn(clientConn, proxyMetrics.ClientBytesReceived, proxyMetrics.ClientBytesSent)
+
 	// there is a ClientHandler for each connection made by a client
 
 	var originEndpoint Endpoint
 	var originHost *Host
-	var err error
 	if p.Conf.OriginEnableHostAssignment {
 		originHost, err = p.originControlConn.NextAssignedHost()
 		if err != nil {
@@ -512,7 +1316,7 @@ func (p *ZdmProxy) handleNewConnection(clientConn net.Conn) {
 	} else {
 		originEndpoint = p.originControlConn.GetCurrentContactPoint()
 		if originEndpoint == nil {
-			log.Warnf("Origin ControlConnection current endpoint is nil, "+
+			p.logger.Warnf("Origin ControlConnection current endpoint is nil, "+
 				"falling back to first origin contact point (%v) for client connection %v.",
 				p.originConnectionConfig.GetContactPoints()[0].String(), clientConn.RemoteAddr().String())
 		}
@@ -523,6 +1327,9 @@ func (p *ZdmProxy) handleNewConnection(clientConn net.Conn) {
 	if p.Conf.TargetEnableHostAssignment {
 		targetHost, err = p.targetControlConn.NextAssignedHost()
 		if err != nil {
+			if originHost != nil {
+				p.originControlConn.ReleaseAssignedHost(originHost)
+			}
 			errFunc(err)
 			return
 		}
@@ -530,7 +1337,7 @@ func (p *ZdmProxy) handleNewConnection(clientConn net.Conn) {
 	} else {
 		targetEndpoint = p.targetControlConn.GetCurrentContactPoint()
 		if targetEndpoint == nil {
-			log.Warnf("Target ControlConnection current endpoint is nil, "+
+			p.logger.Warnf("Target ControlConnection current endpoint is nil, "+
 				"falling back to first target contact point (%v) for client connection %v.",
 				p.targetConnectionConfig.GetContactPoints()[0].String(), clientConn.RemoteAddr().String())
 		}
@@ -538,6 +1345,14 @@ func (p *ZdmProxy) handleNewConnection(clientConn net.Conn) {
 
 	originCassandraConnInfo := NewClusterConnectionInfo(p.originConnectionConfig, originEndpoint, true)
 	targetCassandraConnInfo := NewClusterConnectionInfo(p.targetConnectionConfig, targetEndpoint, false)
+	originCredentials := p.getClusterCredentials(common.ClusterTypeOrigin)
+	targetCredentials := p.getClusterCredentials(common.ClusterTypeTarget)
+	connectionSettings := p.openClientConnectionSettings()
+	targetCircuitBreaker := p.targetCircuitBreaker
+	if connectionSettings.primaryCluster != common.ClusterTypeOrigin {
+		// writes can only be skipped on the secondary cluster
+		targetCircuitBreaker = nil
+	}
 	clientHandler, err := NewClientHandler(
 		clientConn,
 		originCassandraConnInfo,
@@ -546,77 +1361,239 @@ func (p *ZdmProxy) handleNewConnection(clientConn net.Conn) {
 		p.targetControlConn,
 		p.Conf,
 		p.TopologyConfig,
-		p.Conf.TargetUsername,
-		p.Conf.TargetPassword,
-		p.Conf.OriginUsername,
-		p.Conf.OriginPassword,
+		targetCredentials.Username,
+		targetCredentials.Password,
+		originCredentials.Username,
+		originCredentials.Password,
 		p.PreparedStatementCache,
 		p.metricHandler,
 		p.globalClientHandlersWg,
 		p.requestResponseScheduler,
 		p.readScheduler,
 		p.writeScheduler,
+		p.writeBufferPool,
 		p.requestResponseNumWorkers,
 		p.clientHandlersShutdownRequestCtx,
 		originHost,
 		targetHost,
 		p.timeUuidGenerator,
-		p.readMode,
-		p.primaryCluster,
-		p.systemQueriesMode)
-
-	if err != nil {
+		connectionSettings,
+		p.migrationPhaseTracker,
+		p.systemQueriesMode,
+		p.systemQueriesBypass,
+		p.lwtPolicy,
+		p.counterWritePolicy,
+		p.ddlPolicy,
+		p.tableRouting,
+		p.eventSourcePolicy,
+		p.warningsPolicy,
+		p.customPayloadPolicy,
+		p.credentialMapper,
+		targetCircuitBreaker,
+		p.failedWritesJournal,
+		p.auditLog,
+		p.retryPolicies,
+		p.requestTimeouts,
+		p.introspectionTables,
+		p.diagnostics,
+		p.queryRewriter,
+		p.targetNameMapper,
+		p.consistencyOverrides,
+		p.optionsResponder,
+		p.originFaultInjector,
+		p.targetFaultInjector,
+		p.interceptorChain,
+		p.logger)
+
+	if err != nil {
+		if p.migrationPhaseTracker != nil {
+			p.migrationPhaseTracker.closeClientConnection(connectionSettings.migrationPhase)
+		}
+		if originHost != nil {
+			p.originControlConn.ReleaseAssignedHost(originHost)
+		}
+		if targetHost != nil {
+			p.targetControlConn.ReleaseAssignedHost(targetHost)
+		}
 		errFunc(err)
 		return
 	}
 
-	log.Tracef("ClientHandler created")
+	p.logger.Tracef("ClientHandler created")
+	p.notifyClientConnect(clientConn.RemoteAddr())
 	clientHandler.run(&p.activeClients)
 }
 
-func (p *ZdmProxy) Shutdown() {
-	log.Info("Initiating proxy shutdown...")
-
-	log.Debug("Requesting shutdown of the client listener...")
+// GetClientListenerAddress returns the address of the client listener
+// or an empty string if the proxy is not accepting client connections.
+func (p *ZdmProxy) GetClientListenerAddress() string {
 	p.listenerLock.Lock()
-	if !p.listenerClosed {
-		p.listenerClosed = true
-		if p.clientListener != nil {
-			p.clientListener.Close()
-		}
+	defer p.listenerLock.Unlock()
+	if p.listenerClosed || p.clientListener == nil {
+		return ""
 	}
-	p.listenerLock.Unlock()
+	return p.clientListener.Addr().String()
+}
+
+func (p *ZdmProxy) Shutdown() {
+	p.logger.Info("Initiating proxy shutdown...")
+
+	p.logger.Debug("Requesting shutdown of the client listener...")
+	p.closeClientListeners()
 
 	p.listenerShutdownWg.Wait()
 
-	log.Debug("Requesting shutdown of the client handlers...")
+	p.logger.Debug("Requesting shutdown of the client handlers...")
 	p.clientHandlersShutdownRequestCancelFn()
 
-	log.Debug("Waiting until all client handlers are done...")
+	p.logger.Debug("Waiting until all client handlers are done...")
 	p.globalClientHandlersWg.Wait()
 
-	log.Debug("Requesting shutdown of the control connections...")
+	p.logger.Debug("Requesting shutdown of the control connections...")
 	p.controlConnCancelFn()
 
-	log.Debug("Waiting until control connections done...")
+	p.logger.Debug("Waiting until control connections done...")
 	p.controlConnShutdownWg.Wait()
 
-	log.Debug("Shutting down the schedulers and metrics handler...")
+	p.logger.Debug("Shutting down the schedulers and metrics handler...")
 	p.requestResponseScheduler.Shutdown()
 	p.writeScheduler.Shutdown()
 	p.readScheduler.Shutdown()
 	p.listenerScheduler.Shutdown()
 
-	p.lock.Lock()
-	if p.metricHandler != nil {
-		err := p.metricHandler.UnregisterAllMetrics()
+	p.lock.Lock()
+	if p.failedWritesJournal != nil {
+		err := p.failedWritesJournal.Close()
+		if err != nil {
+			p.logger.Warnf("Failed to close failed writes journal: %v.", err)
+		}
+	}
+	if p.auditLog != nil {
+		err := p.auditLog.Close()
+		if err != nil {
+			p.logger.Warnf("Failed to close audit log: %v.", err)
+		}
+	}
+	if p.metricHandler != nil {
+		err := p.metricHandler.UnregisterAllMetrics()
+		if err != nil {
+			p.logger.Warnf("Failed to unregister metrics: %v.", err)
+		}
+	}
+	p.lock.Unlock()
+
+	p.logger.Info("Proxy shutdown complete.")
+}
+
+// GetTargetCircuitBreaker returns nil if the TARGET circuit breaker is disabled.
+func (p *ZdmProxy) GetTargetCircuitBreaker() *CircuitBreaker {
+	return p.targetCircuitBreaker
+}
+
+// GetMigrationPhaseStatus returns nil if ZDM_MIGRATION_PHASE is not set.
+func (p *ZdmProxy) GetMigrationPhaseStatus() *MigrationPhaseStatus {
+	if p.migrationPhaseTracker == nil {
+		return nil
+	}
+	return p.migrationPhaseTracker.status()
+}
+
+// SetMigrationPhase moves the migration to the given phase, which must be the next or the previous phase. The client
+// connections that are already open keep the behavior of their phase, so clients have to reconnect (e.g. with a
+// rolling restart) for the transition to be complete.
+func (p *ZdmProxy) SetMigrationPhase(phase common.MigrationPhase) error {
+	if p.migrationPhaseTracker == nil {
+		return errors.New("the migration phase is not tracked, see ZDM_MIGRATION_PHASE")
+	}
+	previous, err := p.migrationPhaseTracker.transition(phase)
+	if err != nil {
+		return err
+	}
+	if previous != phase {
+		p.logger.Infof("Migration phase changed from %v to %v, new client connections use the settings of %v.",
+			previous, phase, phase)
+	}
+	return nil
+}
+
+// GetFleetStatus returns nil if ZDM_FLEET_CONFIG_BACKEND is not set.
+func (p *ZdmProxy) GetFleetStatus() *fleet.Status {
+	if p.fleetWatcher == nil {
+		return nil
+	}
+	return p.fleetWatcher.Status()
+}
+
+// GetSchemaDriftReport returns nil if ZDM_SCHEMA_DRIFT_CHECK_INTERVAL_MS is 0.
+func (p *ZdmProxy) GetSchemaDriftReport() *SchemaDriftReport {
+	if p.schemaDriftDetector == nil {
+		return nil
+	}
+	return p.schemaDriftDetector.report()
+}
+
+// CheckSchemaDrift compares the schemas of ORIGIN and TARGET now instead of waiting for the next periodic comparison
+// and returns the new report, it returns an error if ZDM_SCHEMA_DRIFT_CHECK_INTERVAL_MS is 0 or if the schema of a
+// cluster could not be fetched.
+func (p *ZdmProxy) CheckSchemaDrift(ctx context.Context) (*SchemaDriftReport, error) {
+	if p.schemaDriftDetector == nil {
+		return nil, fmt.Errorf("schema drift detection is disabled, see ZDM_SCHEMA_DRIFT_CHECK_INTERVAL_MS")
+	}
+	if _, err := p.schemaDriftDetector.check(ctx); err != nil {
+		return nil, err
+	}
+	return p.schemaDriftDetector.report(), nil
+}
+
+// applyFleetState applies the fields of the shared fleet state that are set. The initial state can move the migration
+// to any phase because no client connection is open yet, the changes after that are regular transitions.
+func (p *ZdmProxy) applyFleetState(state *fleet.State, initial bool) error {
+	if state.MigrationPhase != "" {
+		phase, err := config.ParseMigrationPhaseName(state.MigrationPhase)
+		if err != nil {
+			return err
+		}
+		if p.migrationPhaseTracker == nil {
+			return errors.New("the fleet state sets the migration phase but it is not tracked, see ZDM_MIGRATION_PHASE")
+		}
+		if initial {
+			if previous := p.migrationPhaseTracker.reset(phase); previous != phase {
+				p.logger.Infof("Migration phase set to %v by the fleet state (was %v).", phase, previous)
+			}
+		} else if err = p.SetMigrationPhase(phase); err != nil {
+			return err
+		}
+	}
+
+	if state.TargetCircuitBreakerOverride != "" {
+		override, err := ParseCircuitBreakerOverride(state.TargetCircuitBreakerOverride)
 		if err != nil {
-			log.Warnf("Failed to unregister metrics: %v.", err)
+			return err
+		}
+		if p.targetCircuitBreaker == nil {
+			return errors.New("the fleet state sets the TARGET circuit breaker override but the circuit breaker " +
+				"is disabled, see ZDM_TARGET_CIRCUIT_BREAKER_ENABLED")
+		}
+		if p.targetCircuitBreaker.GetStatus().Override != override {
+			p.logger.Infof("TARGET circuit breaker override %v set by the fleet state.", override)
+			p.targetCircuitBreaker.SetOverride(override)
 		}
 	}
-	p.lock.Unlock()
+	return nil
+}
 
-	log.Info("Proxy shutdown complete.")
+// openClientConnectionSettings returns the settings of a new client connection.
+func (p *ZdmProxy) openClientConnectionSettings() *clientConnectionSettings {
+	if p.migrationPhaseTracker != nil {
+		return p.migrationPhaseTracker.openClientConnection()
+	}
+	return &clientConnectionSettings{
+		migrationPhase:      common.MigrationPhaseUndefined,
+		primaryCluster:      p.primaryCluster,
+		readMode:            p.readMode,
+		shadowModeEnabled:   p.shadowModeEnabled,
+		originWritesEnabled: true,
+	}
 }
 
 func (p *ZdmProxy) GetOriginControlConn() *ControlConn {
@@ -633,16 +1610,43 @@ func (p *ZdmProxy) GetTargetControlConn() *ControlConn {
 	return p.targetControlConn
 }
 
+// SubscribeToTopologyEvents registers a listener for the topology events of both ORIGIN and TARGET,
+// it returns an error if the control connections were not started yet.
+func (p *ZdmProxy) SubscribeToTopologyEvents(listener TopologyEventListener) (unsubscribe func(), err error) {
+	originControlConn := p.GetOriginControlConn()
+	targetControlConn := p.GetTargetControlConn()
+	if originControlConn == nil || targetControlConn == nil {
+		return nil, errors.New("could not subscribe to topology events because the control connections were not started")
+	}
+
+	unsubscribeOrigin := originControlConn.SubscribeToTopologyEvents(listener)
+	unsubscribeTarget := targetControlConn.SubscribeToTopologyEvents(listener)
+	return func() {
+		unsubscribeOrigin()
+		unsubscribeTarget()
+	}, nil
+}
+
 func Run(conf *config.Config, ctx context.Context) (*ZdmProxy, error) {
-	zdmProxy, err := NewZdmProxy(conf)
+	return RunFunc(func() (*ZdmProxy, error) { return NewZdmProxy(conf) }, ctx, log.StandardLogger())
+}
+
+func RunWithRetries(conf *config.Config, ctx context.Context, b *backoff.Backoff) (*ZdmProxy, error) {
+	return RunWithRetriesFunc(func() (*ZdmProxy, error) { return NewZdmProxy(conf) }, ctx, b, log.StandardLogger())
+}
+
+// RunFunc is Run for a proxy that is customized before it is started, e.g. with AddInterceptor. The startup failures
+// are logged with the provided logger.
+func RunFunc(newProxy func() (*ZdmProxy, error), ctx context.Context, logger *log.Logger) (*ZdmProxy, error) {
+	zdmProxy, err := newProxy()
 	if err != nil {
-		log.Errorf("Couldn't create proxy: %v.", err)
+		logger.Errorf("Couldn't create proxy: %v.", err)
 		return nil, err
 	}
 
 	err = zdmProxy.Start(ctx)
 	if err != nil {
-		log.Errorf("Couldn't start proxy: %v.", err)
+		logger.Errorf("Couldn't start proxy: %v.", err)
 		zdmProxy.Shutdown()
 		return nil, err
 	}
@@ -650,21 +1654,24 @@ func Run(conf *config.Config, ctx context.Context) (*ZdmProxy, error) {
 	return zdmProxy, nil
 }
 
-func RunWithRetries(conf *config.Config, ctx context.Context, b *backoff.Backoff) (*ZdmProxy, error) {
-	log.Info("Attempting to start the proxy...")
+// RunWithRetriesFunc is RunWithRetries for a proxy that is customized before it is started, newProxy is called for
+// each attempt because a proxy that failed to start can't be started again.
+func RunWithRetriesFunc(
+	newProxy func() (*ZdmProxy, error), ctx context.Context, b *backoff.Backoff, logger *log.Logger) (*ZdmProxy, error) {
+	logger.Info("Attempting to start the proxy...")
 	for {
-		zdmProxy, err := Run(conf, ctx)
+		zdmProxy, err := RunFunc(newProxy, ctx, logger)
 		if zdmProxy != nil {
 			return zdmProxy, nil
 		}
 
 		nextDuration := b.Duration()
 		if !errors.Is(err, ShutdownErr) {
-			log.Errorf("Couldn't start proxy, retrying in %v: %v.", nextDuration, err)
+			logger.Errorf("Couldn't start proxy, retrying in %v: %v.", nextDuration, err)
 		}
 		timedOut, _ := sleepWithContext(nextDuration, ctx, nil)
 		if !timedOut {
-			log.Info("Cancellation detected. Aborting proxy startup...")
+			logger.Info("Cancellation detected. Aborting proxy startup...")
 			return nil, ShutdownErr
 		}
 	}
@@ -718,6 +1725,96 @@ func (p *ZdmProxy) CreateProxyMetrics(metricFactory metrics.MetricFactory) (*met
 		return nil, err
 	}
 
+	psCacheHitCount, err := metricFactory.GetOrCreateCounter(metrics.PSCacheHitCount)
+	if err != nil {
+		return nil, err
+	}
+
+	psCacheEvictionCount, err := metricFactory.GetOrCreateCounter(metrics.PSCacheEvictionCount)
+	if err != nil {
+		return nil, err
+	}
+
+	psCacheRePrepareOrigin, err := metricFactory.GetOrCreateCounter(metrics.PSCacheRePrepareOrigin)
+	if err != nil {
+		return nil, err
+	}
+
+	psCacheRePrepareTarget, err := metricFactory.GetOrCreateCounter(metrics.PSCacheRePrepareTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	psCacheRePrepareFailedOrigin, err := metricFactory.GetOrCreateCounter(metrics.PSCacheRePrepareFailedOrigin)
+	if err != nil {
+		return nil, err
+	}
+
+	psCacheRePrepareFailedTarget, err := metricFactory.GetOrCreateCounter(metrics.PSCacheRePrepareFailedTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	retriesOrigin, err := metricFactory.GetOrCreateCounter(metrics.RetriesOrigin)
+	if err != nil {
+		return nil, err
+	}
+
+	retriesTarget, err := metricFactory.GetOrCreateCounter(metrics.RetriesTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	failoversOrigin, err := metricFactory.GetOrCreateCounter(metrics.RequestConnectionFailoversOrigin)
+	if err != nil {
+		return nil, err
+	}
+
+	failoversTarget, err := metricFactory.GetOrCreateCounter(metrics.RequestConnectionFailoversTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	failoversFailedOrigin, err := metricFactory.GetOrCreateCounter(metrics.RequestConnectionFailoversFailedOrigin)
+	if err != nil {
+		return nil, err
+	}
+
+	failoversFailedTarget, err := metricFactory.GetOrCreateCounter(metrics.RequestConnectionFailoversFailedTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	consistencyLevelOverridesOrigin, err := metricFactory.GetOrCreateCounter(metrics.ConsistencyLevelOverridesOrigin)
+	if err != nil {
+		return nil, err
+	}
+
+	consistencyLevelOverridesTarget, err := metricFactory.GetOrCreateCounter(metrics.ConsistencyLevelOverridesTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	responseWarningsOrigin, err := metricFactory.GetOrCreateCounter(metrics.ResponseWarningsOrigin)
+	if err != nil {
+		return nil, err
+	}
+
+	responseWarningsTarget, err := metricFactory.GetOrCreateCounter(metrics.ResponseWarningsTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	speculativeReadWins, err := metricFactory.GetOrCreateCounter(metrics.SpeculativeReadWins)
+	if err != nil {
+		return nil, err
+	}
+
+	speculativeReadLosses, err := metricFactory.GetOrCreateCounter(metrics.SpeculativeReadLosses)
+	if err != nil {
+		return nil, err
+	}
+
 	proxyReadsOriginDuration, err := metricFactory.GetOrCreateHistogram(metrics.ProxyReadsOriginDuration, p.originBuckets)
 	if err != nil {
 		return nil, err
@@ -733,6 +1830,36 @@ func (p *ZdmProxy) CreateProxyMetrics(metricFactory metrics.MetricFactory) (*met
 		return nil, err
 	}
 
+	readResponseRowsOrigin, err := metricFactory.GetOrCreateHistogram(metrics.ReadResponseRowsOrigin, metrics.ReadResponseRowsBuckets)
+	if err != nil {
+		return nil, err
+	}
+
+	readResponseRowsTarget, err := metricFactory.GetOrCreateHistogram(metrics.ReadResponseRowsTarget, metrics.ReadResponseRowsBuckets)
+	if err != nil {
+		return nil, err
+	}
+
+	readResponseRowsAsync, err := metricFactory.GetOrCreateHistogram(metrics.ReadResponseRowsAsync, metrics.ReadResponseRowsBuckets)
+	if err != nil {
+		return nil, err
+	}
+
+	readResponseBytesOrigin, err := metricFactory.GetOrCreateHistogram(metrics.ReadResponseBytesOrigin, metrics.ReadResponseBytesBuckets)
+	if err != nil {
+		return nil, err
+	}
+
+	readResponseBytesTarget, err := metricFactory.GetOrCreateHistogram(metrics.ReadResponseBytesTarget, metrics.ReadResponseBytesBuckets)
+	if err != nil {
+		return nil, err
+	}
+
+	readResponseBytesAsync, err := metricFactory.GetOrCreateHistogram(metrics.ReadResponseBytesAsync, metrics.ReadResponseBytesBuckets)
+	if err != nil {
+		return nil, err
+	}
+
 	inFlightReadsOrigin, err := metricFactory.GetOrCreateGauge(metrics.InFlightReadsOrigin)
 	if err != nil {
 		return nil, err
@@ -755,21 +1882,201 @@ func (p *ZdmProxy) CreateProxyMetrics(metricFactory metrics.MetricFactory) (*met
 		return nil, err
 	}
 
+	clientBytesReceived, err := metricFactory.GetOrCreateCounter(metrics.ClientBytesReceived)
+	if err != nil {
+		return nil, err
+	}
+
+	clientBytesSent, err := metricFactory.GetOrCreateCounter(metrics.ClientBytesSent)
+	if err != nil {
+		return nil, err
+	}
+
+	idleClientConnectionsClosed, err := metricFactory.GetOrCreateCounter(metrics.IdleClientConnectionsClosed)
+	if err != nil {
+		return nil, err
+	}
+
+	lwtRequestCount, err := metricFactory.GetOrCreateCounter(metrics.LwtRequestCount)
+	if err != nil {
+		return nil, err
+	}
+
+	lwtAppliedMismatchCount, err := metricFactory.GetOrCreateCounter(metrics.LwtAppliedMismatchCount)
+	if err != nil {
+		return nil, err
+	}
+
+	counterWriteCount, err := metricFactory.GetOrCreateCounter(metrics.CounterWriteCount)
+	if err != nil {
+		return nil, err
+	}
+
+	targetCircuitBreakerSkippedWrites, err := metricFactory.GetOrCreateCounter(metrics.TargetCircuitBreakerSkippedWrites)
+	if err != nil {
+		return nil, err
+	}
+
+	bufferPoolHits, err := metricFactory.GetOrCreateCounter(metrics.BufferPoolHits)
+	if err != nil {
+		return nil, err
+	}
+
+	bufferPoolMisses, err := metricFactory.GetOrCreateCounter(metrics.BufferPoolMisses)
+	if err != nil {
+		return nil, err
+	}
+
+	oversizedRequestFrames, err := metricFactory.GetOrCreateCounter(metrics.OversizedRequestFrames)
+	if err != nil {
+		return nil, err
+	}
+
+	oversizedResponseFrames, err := metricFactory.GetOrCreateCounter(metrics.OversizedResponseFrames)
+	if err != nil {
+		return nil, err
+	}
+
+	dnsResolutionFailuresOrigin, err := metricFactory.GetOrCreateCounter(metrics.DnsResolutionFailuresOrigin)
+	if err != nil {
+		return nil, err
+	}
+
+	dnsResolutionFailuresTarget, err := metricFactory.GetOrCreateCounter(metrics.DnsResolutionFailuresTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	datacenterFailoverActiveOrigin, err := metricFactory.GetOrCreateGaugeFunc(metrics.DatacenterFailoverActiveOrigin, func() float64 {
+		if controlConn := p.GetOriginControlConn(); controlConn != nil && controlConn.GetRemoteDatacenter() != "" {
+			return 1
+		}
+		return 0
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	datacenterFailoverActiveTarget, err := metricFactory.GetOrCreateGaugeFunc(metrics.DatacenterFailoverActiveTarget, func() float64 {
+		if controlConn := p.GetTargetControlConn(); controlConn != nil && controlConn.GetRemoteDatacenter() != "" {
+			return 1
+		}
+		return 0
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	targetCircuitBreakerOpen, err := metricFactory.GetOrCreateGaugeFunc(metrics.TargetCircuitBreakerOpen, func() float64 {
+		if p.targetCircuitBreaker != nil && p.targetCircuitBreaker.IsOpen() {
+			return 1
+		}
+		return 0
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	shadowWrites, err := metricFactory.GetOrCreateCounter(metrics.ShadowWrites)
+	if err != nil {
+		return nil, err
+	}
+
+	shadowWritesSkipped, err := metricFactory.GetOrCreateCounter(metrics.ShadowWritesSkipped)
+	if err != nil {
+		return nil, err
+	}
+
+	requestDeadlinesExceeded, err := metricFactory.GetOrCreateCounter(metrics.RequestDeadlinesExceeded)
+	if err != nil {
+		return nil, err
+	}
+
 	proxyMetrics := &metrics.ProxyMetrics{
-		FailedReadsOrigin:        failedReadsOrigin,
-		FailedReadsTarget:        failedReadsTarget,
-		FailedWritesOnOrigin:     failedWritesOnOrigin,
-		FailedWritesOnTarget:     failedWritesOnTarget,
-		FailedWritesOnBoth:       failedWritesOnBoth,
-		PSCacheSize:              psCacheSize,
-		PSCacheMissCount:         psCacheMissCount,
-		ProxyReadsOriginDuration: proxyReadsOriginDuration,
-		ProxyReadsTargetDuration: proxyReadsTargetDuration,
-		ProxyWritesDuration:      proxyWritesDuration,
-		InFlightReadsOrigin:      inFlightReadsOrigin,
-		InFlightReadsTarget:      inFlightReadsTarget,
-		InFlightWrites:           inFlightWrites,
-		OpenClientConnections:    openClientConnections,
+		FailedReadsOrigin:            failedReadsOrigin,
+		FailedReadsTarget:            failedReadsTarget,
+		FailedWritesOnOrigin:         failedWritesOnOrigin,
+		FailedWritesOnTarget:         failedWritesOnTarget,
+		FailedWritesOnBoth:           failedWritesOnBoth,
+		PSCacheSize:                  psCacheSize,
+		PSCacheMissCount:             psCacheMissCount,
+		PSCacheHitCount:              psCacheHitCount,
+		PSCacheEvictionCount:         psCacheEvictionCount,
+		PSCacheRePrepareOrigin:       psCacheRePrepareOrigin,
+		PSCacheRePrepareTarget:       psCacheRePrepareTarget,
+		PSCacheRePrepareFailedOrigin: psCacheRePrepareFailedOrigin,
+		PSCacheRePrepareFailedTarget: psCacheRePrepareFailedTarget,
+		RetriesOrigin:                retriesOrigin,
+		RetriesTarget:                retriesTarget,
+		SpeculativeReadWins:          speculativeReadWins,
+		SpeculativeReadLosses:        speculativeReadLosses,
+		ProxyReadsOriginDuration:     proxyReadsOriginDuration,
+		ProxyReadsTargetDuration:     proxyReadsTargetDuration,
+		ProxyWritesDuration:          proxyWritesDuration,
+		ReadResponseRowsOrigin:       readResponseRowsOrigin,
+		ReadResponseRowsTarget:       readResponseRowsTarget,
+		ReadResponseRowsAsync:        readResponseRowsAsync,
+		ReadResponseBytesOrigin:      readResponseBytesOrigin,
+		ReadResponseBytesTarget:      readResponseBytesTarget,
+		ReadResponseBytesAsync:       readResponseBytesAsync,
+		InFlightReadsOrigin:          inFlightReadsOrigin,
+		InFlightReadsTarget:          inFlightReadsTarget,
+		InFlightWrites:               inFlightWrites,
+		OpenClientConnections:        openClientConnections,
+		ClientBytesReceived:          clientBytesReceived,
+		ClientBytesSent:              clientBytesSent,
+		IdleClientConnectionsClosed:  idleClientConnectionsClosed,
+		LwtRequestCount:              lwtRequestCount,
+		LwtAppliedMismatchCount:      lwtAppliedMismatchCount,
+		CounterWriteCount:            counterWriteCount,
+
+		ConsistencyLevelOverridesOrigin: consistencyLevelOverridesOrigin,
+		ConsistencyLevelOverridesTarget: consistencyLevelOverridesTarget,
+
+		ResponseWarningsOrigin: responseWarningsOrigin,
+		ResponseWarningsTarget: responseWarningsTarget,
+
+		RequestConnectionFailoversOrigin:       failoversOrigin,
+		RequestConnectionFailoversTarget:       failoversTarget,
+		RequestConnectionFailoversFailedOrigin: failoversFailedOrigin,
+		RequestConnectionFailoversFailedTarget: failoversFailedTarget,
+
+		DatacenterFailoverActiveOrigin: datacenterFailoverActiveOrigin,
+		DatacenterFailoverActiveTarget: datacenterFailoverActiveTarget,
+
+		BufferPoolHits:   bufferPoolHits,
+		BufferPoolMisses: bufferPoolMisses,
+
+		OversizedRequestFrames:  oversizedRequestFrames,
+		OversizedResponseFrames: oversizedResponseFrames,
+
+		DnsResolutionFailuresOrigin: dnsResolutionFailuresOrigin,
+		DnsResolutionFailuresTarget: dnsResolutionFailuresTarget,
+
+		TargetCircuitBreakerSkippedWrites: targetCircuitBreakerSkippedWrites,
+		TargetCircuitBreakerOpen:          targetCircuitBreakerOpen,
+
+		ShadowWrites:        shadowWrites,
+		ShadowWritesSkipped: shadowWritesSkipped,
+
+		RequestDeadlinesExceeded: requestDeadlinesExceeded,
+	}
+
+	if p.Conf.MetricsTableRequestsEnabled {
+		allowList, err := p.Conf.ParseTableRequestsAllowList()
+		if err != nil {
+			return nil, err
+		}
+		proxyMetrics.TableRequests = metrics.NewTableMetrics(
+			metricFactory, allowList, p.Conf.MetricsTableRequestsMaxTables)
+	}
+
+	if len(p.interceptors) > 0 {
+		proxyMetrics.Interceptors = metrics.NewInterceptorMetrics(metricFactory)
+	}
+
+	if p.Conf.MetricsApplicationsEnabled {
+		proxyMetrics.Applications = metrics.NewApplicationMetrics(metricFactory, p.Conf.MetricsApplicationsMax)
 	}
 
 	return proxyMetrics, nil
@@ -832,6 +2139,26 @@ func (p *ZdmProxy) CreateOriginNodeMetrics(
 		return nil, err
 	}
 
+	originBytesReceived, err := metrics.CreateCounterNodeMetric(metricFactory, originNodeDescription, metrics.OriginBytesReceived)
+	if err != nil {
+		return nil, err
+	}
+
+	originBytesSent, err := metrics.CreateCounterNodeMetric(metricFactory, originNodeDescription, metrics.OriginBytesSent)
+	if err != nil {
+		return nil, err
+	}
+
+	originHeartbeatFailures, err := metrics.CreateCounterNodeMetric(metricFactory, originNodeDescription, metrics.OriginHeartbeatFailures)
+	if err != nil {
+		return nil, err
+	}
+
+	originStreamIdsExhausted, err := metrics.CreateCounterNodeMetric(metricFactory, originNodeDescription, metrics.OriginStreamIdsExhausted)
+	if err != nil {
+		return nil, err
+	}
+
 	// inflight requests metric for non async requests are implemented as proxy level metrics (not node metrics)
 	inflightRequests, err := noopmetrics.NewNoopMetricFactory().GetOrCreateGauge(nil)
 	if err != nil {
@@ -839,18 +2166,22 @@ func (p *ZdmProxy) CreateOriginNodeMetrics(
 	}
 
 	return &metrics.NodeMetricsInstance{
-		ClientTimeouts:    originClientTimeouts,
-		ReadTimeouts:      originReadTimeouts,
-		ReadFailures:      originReadFailures,
-		WriteTimeouts:     originWriteTimeouts,
-		WriteFailures:     originWriteFailures,
-		UnpreparedErrors:  originUnpreparedErrors,
-		OverloadedErrors:  originOverloadedErrors,
-		UnavailableErrors: originUnavailableErrors,
-		OtherErrors:       originOtherErrors,
-		RequestDuration:   originRequestDuration,
-		OpenConnections:   openOriginConnections,
-		InFlightRequests:  inflightRequests,
+		ClientTimeouts:     originClientTimeouts,
+		ReadTimeouts:       originReadTimeouts,
+		ReadFailures:       originReadFailures,
+		WriteTimeouts:      originWriteTimeouts,
+		WriteFailures:      originWriteFailures,
+		UnpreparedErrors:   originUnpreparedErrors,
+		OverloadedErrors:   originOverloadedErrors,
+		UnavailableErrors:  originUnavailableErrors,
+		OtherErrors:        originOtherErrors,
+		RequestDuration:    originRequestDuration,
+		OpenConnections:    openOriginConnections,
+		BytesReceived:      originBytesReceived,
+		BytesSent:          originBytesSent,
+		HeartbeatFailures:  originHeartbeatFailures,
+		InFlightRequests:   inflightRequests,
+		StreamIdsExhausted: originStreamIdsExhausted,
 	}, nil
 }
 
@@ -911,24 +2242,48 @@ func (p *ZdmProxy) CreateAsyncNodeMetrics(
 		return nil, err
 	}
 
+	asyncBytesReceived, err := metrics.CreateCounterNodeMetric(metricFactory, asyncNodeDescription, metrics.AsyncBytesReceived)
+	if err != nil {
+		return nil, err
+	}
+
+	asyncBytesSent, err := metrics.CreateCounterNodeMetric(metricFactory, asyncNodeDescription, metrics.AsyncBytesSent)
+	if err != nil {
+		return nil, err
+	}
+
+	asyncHeartbeatFailures, err := metrics.CreateCounterNodeMetric(metricFactory, asyncNodeDescription, metrics.AsyncHeartbeatFailures)
+	if err != nil {
+		return nil, err
+	}
+
 	inflightRequestsAsync, err := metrics.CreateGaugeNodeMetric(metricFactory, asyncNodeDescription, metrics.InFlightRequestsAsync)
 	if err != nil {
 		return nil, err
 	}
 
+	asyncStreamIdsExhausted, err := metrics.CreateCounterNodeMetric(metricFactory, asyncNodeDescription, metrics.AsyncStreamIdsExhausted)
+	if err != nil {
+		return nil, err
+	}
+
 	return &metrics.NodeMetricsInstance{
-		ClientTimeouts:    asyncClientTimeouts,
-		ReadTimeouts:      asyncReadTimeouts,
-		ReadFailures:      asyncReadFailures,
-		WriteTimeouts:     asyncWriteTimeouts,
-		WriteFailures:     asyncWriteFailures,
-		UnpreparedErrors:  asyncUnpreparedErrors,
-		OverloadedErrors:  asyncOverloadedErrors,
-		UnavailableErrors: asyncUnavailableErrors,
-		OtherErrors:       asyncOtherErrors,
-		RequestDuration:   asyncRequestDuration,
-		OpenConnections:   openAsyncConnections,
-		InFlightRequests:  inflightRequestsAsync,
+		ClientTimeouts:     asyncClientTimeouts,
+		ReadTimeouts:       asyncReadTimeouts,
+		ReadFailures:       asyncReadFailures,
+		WriteTimeouts:      asyncWriteTimeouts,
+		WriteFailures:      asyncWriteFailures,
+		UnpreparedErrors:   asyncUnpreparedErrors,
+		OverloadedErrors:   asyncOverloadedErrors,
+		UnavailableErrors:  asyncUnavailableErrors,
+		OtherErrors:        asyncOtherErrors,
+		RequestDuration:    asyncRequestDuration,
+		OpenConnections:    openAsyncConnections,
+		BytesReceived:      asyncBytesReceived,
+		BytesSent:          asyncBytesSent,
+		HeartbeatFailures:  asyncHeartbeatFailures,
+		InFlightRequests:   inflightRequestsAsync,
+		StreamIdsExhausted: asyncStreamIdsExhausted,
 	}, nil
 }
 
@@ -989,6 +2344,26 @@ func (p *ZdmProxy) CreateTargetNodeMetrics(
 		return nil, err
 	}
 
+	targetBytesReceived, err := metrics.CreateCounterNodeMetric(metricFactory, targetNodeDescription, metrics.TargetBytesReceived)
+	if err != nil {
+		return nil, err
+	}
+
+	targetBytesSent, err := metrics.CreateCounterNodeMetric(metricFactory, targetNodeDescription, metrics.TargetBytesSent)
+	if err != nil {
+		return nil, err
+	}
+
+	targetHeartbeatFailures, err := metrics.CreateCounterNodeMetric(metricFactory, targetNodeDescription, metrics.TargetHeartbeatFailures)
+	if err != nil {
+		return nil, err
+	}
+
+	targetStreamIdsExhausted, err := metrics.CreateCounterNodeMetric(metricFactory, targetNodeDescription, metrics.TargetStreamIdsExhausted)
+	if err != nil {
+		return nil, err
+	}
+
 	// inflight requests metric for non async requests are implemented as proxy level metrics (not node metrics)
 	inflightRequests, err := noopmetrics.NewNoopMetricFactory().GetOrCreateGauge(nil)
 	if err != nil {
@@ -996,17 +2371,21 @@ func (p *ZdmProxy) CreateTargetNodeMetrics(
 	}
 
 	return &metrics.NodeMetricsInstance{
-		ClientTimeouts:    targetClientTimeouts,
-		ReadTimeouts:      targetReadTimeouts,
-		ReadFailures:      targetReadFailures,
-		WriteTimeouts:     targetWriteTimeouts,
-		WriteFailures:     targetWriteFailures,
-		UnpreparedErrors:  targetUnpreparedErrors,
-		OverloadedErrors:  targetOverloadedErrors,
-		UnavailableErrors: targetUnavailableErrors,
-		OtherErrors:       targetOtherErrors,
-		RequestDuration:   targetRequestDuration,
-		OpenConnections:   openTargetConnections,
-		InFlightRequests:  inflightRequests,
+		ClientTimeouts:     targetClientTimeouts,
+		ReadTimeouts:       targetReadTimeouts,
+		ReadFailures:       targetReadFailures,
+		WriteTimeouts:      targetWriteTimeouts,
+		WriteFailures:      targetWriteFailures,
+		UnpreparedErrors:   targetUnpreparedErrors,
+		OverloadedErrors:   targetOverloadedErrors,
+		UnavailableErrors:  targetUnavailableErrors,
+		OtherErrors:        targetOtherErrors,
+		RequestDuration:    targetRequestDuration,
+		OpenConnections:    openTargetConnections,
+		BytesReceived:      targetBytesReceived,
+		BytesSent:          targetBytesSent,
+		HeartbeatFailures:  targetHeartbeatFailures,
+		InFlightRequests:   inflightRequests,
+		StreamIdsExhausted: targetStreamIdsExhausted,
 	}, nil
 }
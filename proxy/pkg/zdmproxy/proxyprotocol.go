@@ -0,0 +1,203 @@
+package zdmproxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Header formats are described in https://www.haproxy.org/download/1.8/doc/proxy-protocol.txt
+var (
+	proxyProtocolV1Prefix    = []byte("PROXY ")
+	proxyProtocolV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+const (
+	// includes the CRLF
+	proxyProtocolV1MaxHeaderLength = 107
+	proxyProtocolV2HeaderLength    = 16
+
+	proxyProtocolV2CommandLocal = 0x0
+	proxyProtocolV2CommandProxy = 0x1
+
+	proxyProtocolV2FamilyInet  = 0x1
+	proxyProtocolV2FamilyInet6 = 0x2
+)
+
+// proxyProtocolConn is a client connection whose remote and local addresses come from the PROXY protocol header that
+// was sent by the load balancer in front of the proxy.
+type proxyProtocolConn struct {
+	net.Conn
+	reader     *bufio.Reader
+	remoteAddr net.Addr
+	localAddr  net.Addr
+}
+
+func (recv *proxyProtocolConn) Read(b []byte) (int, error) {
+	return recv.reader.Read(b)
+}
+
+func (recv *proxyProtocolConn) RemoteAddr() net.Addr {
+	if recv.remoteAddr != nil {
+		return recv.remoteAddr
+	}
+	return recv.Conn.RemoteAddr()
+}
+
+func (recv *proxyProtocolConn) LocalAddr() net.Addr {
+	if recv.localAddr != nil {
+		return recv.localAddr
+	}
+	return recv.Conn.LocalAddr()
+}
+
+// readProxyProtocolHeader reads the PROXY protocol (v1 or v2) header of a new client connection and returns a
+// connection whose RemoteAddr is the address of the client that connected to the load balancer.
+//
+// With ProxyProtocolModeOptional, connections that don't start with a header are accepted as they are. CQL and TLS
+// clients always send the first bytes so looking at the first byte of the connection is enough to detect the header.
+func readProxyProtocolHeader(
+	conn net.Conn, mode common.ProxyProtocolMode, timeout time.Duration) (net.Conn, error) {
+
+	if timeout > 0 {
+		if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+			return nil, err
+		}
+	}
+
+	reader := bufio.NewReader(conn)
+	ppConn := &proxyProtocolConn{Conn: conn, reader: reader}
+	firstByte, err := reader.Peek(1)
+	if err != nil {
+		return nil, fmt.Errorf("could not read PROXY protocol header from %v: %w", conn.RemoteAddr(), err)
+	}
+
+	switch firstByte[0] {
+	case proxyProtocolV1Prefix[0]:
+		err = ppConn.readV1Header()
+	case proxyProtocolV2Signature[0]:
+		err = ppConn.readV2Header()
+	default:
+		if mode == common.ProxyProtocolModeRequired {
+			err = errors.New("PROXY protocol header is required but the connection doesn't start with one")
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid PROXY protocol header from %v: %w", conn.RemoteAddr(), err)
+	}
+
+	if timeout > 0 {
+		if err = conn.SetReadDeadline(time.Time{}); err != nil {
+			return nil, err
+		}
+	}
+	return ppConn, nil
+}
+
+// readV1Header parses a header like "PROXY TCP4 192.168.0.1 192.168.0.11 56324 9042\r\n".
+func (recv *proxyProtocolConn) readV1Header() error {
+	var line []byte
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) >= proxyProtocolV1MaxHeaderLength {
+			return errors.New("v1 header is too long")
+		}
+		b, err := recv.reader.ReadByte()
+		if err != nil {
+			return err
+		}
+		line = append(line, b)
+	}
+
+	if !bytes.HasPrefix(line, proxyProtocolV1Prefix) {
+		return fmt.Errorf("unexpected v1 header: %q", line)
+	}
+	fields := strings.Fields(string(line))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return fmt.Errorf("unexpected v1 header: %q", line)
+	}
+
+	srcAddr, err := parseProxyProtocolV1Address(fields[2], fields[4])
+	if err != nil {
+		return err
+	}
+	dstAddr, err := parseProxyProtocolV1Address(fields[3], fields[5])
+	if err != nil {
+		return err
+	}
+	recv.remoteAddr, recv.localAddr = srcAddr, dstAddr
+	return nil
+}
+
+func parseProxyProtocolV1Address(ip string, port string) (*net.TCPAddr, error) {
+	parsedIp := net.ParseIP(ip)
+	if parsedIp == nil {
+		return nil, fmt.Errorf("invalid address in v1 header: %v", ip)
+	}
+	parsedPort, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port in v1 header: %v", port)
+	}
+	return &net.TCPAddr{IP: parsedIp, Port: int(parsedPort)}, nil
+}
+
+// readV2Header parses the binary header, TLVs are ignored.
+func (recv *proxyProtocolConn) readV2Header() error {
+	header := make([]byte, proxyProtocolV2HeaderLength)
+	if _, err := io.ReadFull(recv.reader, header); err != nil {
+		return err
+	}
+	if !bytes.Equal(header[:len(proxyProtocolV2Signature)], proxyProtocolV2Signature) {
+		return errors.New("invalid v2 signature")
+	}
+	if header[12]>>4 != 2 {
+		return fmt.Errorf("unsupported version in v2 header: %v", header[12]>>4)
+	}
+	command := header[12] & 0x0F
+	family := header[13] >> 4
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(recv.reader, payload); err != nil {
+		return err
+	}
+
+	switch command {
+	case proxyProtocolV2CommandLocal:
+		// health checks of the load balancer, the connection addresses are the real ones
+		return nil
+	case proxyProtocolV2CommandProxy:
+	default:
+		return fmt.Errorf("unsupported command in v2 header: %v", command)
+	}
+
+	var ipLength int
+	switch family {
+	case proxyProtocolV2FamilyInet:
+		ipLength = net.IPv4len
+	case proxyProtocolV2FamilyInet6:
+		ipLength = net.IPv6len
+	default:
+		// UNSPEC and UNIX addresses are not useful, keep the connection addresses
+		return nil
+	}
+	if len(payload) < 2*ipLength+4 {
+		return fmt.Errorf("v2 header is too short for address family %v", family)
+	}
+
+	srcIp := net.IP(payload[:ipLength])
+	dstIp := net.IP(payload[ipLength : 2*ipLength])
+	srcPort := binary.BigEndian.Uint16(payload[2*ipLength:])
+	dstPort := binary.BigEndian.Uint16(payload[2*ipLength+2:])
+	recv.remoteAddr = &net.TCPAddr{IP: srcIp, Port: int(srcPort)}
+	recv.localAddr = &net.TCPAddr{IP: dstIp, Port: int(dstPort)}
+	return nil
+}
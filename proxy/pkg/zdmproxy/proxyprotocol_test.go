@@ -0,0 +1,84 @@
+package zdmproxy
+
+import (
+	"encoding/binary"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+func newProxyProtocolV2Header(command byte, family byte, srcIp net.IP, dstIp net.IP, srcPort uint16, dstPort uint16) []byte {
+	payload := append(append([]byte{}, srcIp...), dstIp...)
+	payload = append(payload, 0, 0, 0, 0)
+	binary.BigEndian.PutUint16(payload[len(payload)-4:], srcPort)
+	binary.BigEndian.PutUint16(payload[len(payload)-2:], dstPort)
+	header := append([]byte{}, proxyProtocolV2Signature...)
+	header = append(header, 0x20|command, family<<4|0x1, 0, 0)
+	binary.BigEndian.PutUint16(header[14:], uint16(len(payload)))
+	return append(header, payload...)
+}
+
+func TestReadProxyProtocolHeader(t *testing.T) {
+	tests := []struct {
+		name               string
+		data               []byte
+		mode               common.ProxyProtocolMode
+		expectedRemoteAddr string
+		expectedErr        string
+	}{
+		{"v1 tcp4", []byte("PROXY TCP4 192.168.0.1 10.0.0.1 56324 14002\r\nCQL"), common.ProxyProtocolModeRequired,
+			"192.168.0.1:56324", ""},
+		{"v1 tcp6", []byte("PROXY TCP6 fd00::1 fd00::2 56324 14002\r\nCQL"), common.ProxyProtocolModeOptional,
+			"[fd00::1]:56324", ""},
+		{"v1 unknown", []byte("PROXY UNKNOWN\r\nCQL"), common.ProxyProtocolModeRequired, "pipe", ""},
+		{"v1 invalid", []byte("PROXY TCP4 192.168.0.1\r\nCQL"), common.ProxyProtocolModeRequired, "",
+			"unexpected v1 header"},
+		{"v2 inet", append(newProxyProtocolV2Header(proxyProtocolV2CommandProxy, proxyProtocolV2FamilyInet,
+			net.ParseIP("192.168.0.1").To4(), net.ParseIP("10.0.0.1").To4(), 56324, 14002), []byte("CQL")...),
+			common.ProxyProtocolModeRequired, "192.168.0.1:56324", ""},
+		{"v2 inet6", append(newProxyProtocolV2Header(proxyProtocolV2CommandProxy, proxyProtocolV2FamilyInet6,
+			net.ParseIP("fd00::1"), net.ParseIP("fd00::2"), 56324, 14002), []byte("CQL")...),
+			common.ProxyProtocolModeRequired, "[fd00::1]:56324", ""},
+		{"v2 local", append(newProxyProtocolV2Header(proxyProtocolV2CommandLocal, proxyProtocolV2FamilyInet,
+			net.ParseIP("192.168.0.1").To4(), net.ParseIP("10.0.0.1").To4(), 56324, 14002), []byte("CQL")...),
+			common.ProxyProtocolModeRequired, "pipe", ""},
+		{"no header optional", []byte("CQL"), common.ProxyProtocolModeOptional, "pipe", ""},
+		{"no header required", []byte("CQL"), common.ProxyProtocolModeRequired, "",
+			"PROXY protocol header is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverConn, clientConn := net.Pipe()
+			defer serverConn.Close()
+			go func() {
+				_, _ = clientConn.Write(tt.data)
+				clientConn.Close()
+			}()
+
+			conn, err := readProxyProtocolHeader(serverConn, tt.mode, time.Second)
+			if tt.expectedErr != "" {
+				require.NotNil(t, err)
+				require.Contains(t, err.Error(), tt.expectedErr)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tt.expectedRemoteAddr, conn.RemoteAddr().String())
+			data, err := ioutil.ReadAll(conn)
+			require.Nil(t, err)
+			require.Equal(t, "CQL", string(data))
+		})
+	}
+}
+
+func TestReadProxyProtocolHeaderTimeout(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	_, err := readProxyProtocolHeader(serverConn, common.ProxyProtocolModeRequired, 50*time.Millisecond)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "could not read PROXY protocol header")
+}
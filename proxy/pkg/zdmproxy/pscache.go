@@ -1,11 +1,13 @@
 package zdmproxy
 
 import (
+	"container/list"
 	"encoding/hex"
 	"fmt"
 	"github.com/datastax/go-cassandra-native-protocol/message"
 	log "github.com/sirupsen/logrus"
 	"sync"
+	"sync/atomic"
 )
 
 type PreparedStatementCache struct {
@@ -14,28 +16,53 @@ type PreparedStatementCache struct {
 
 	interceptedCache map[string]PreparedData // Map containing the prepared queries for intercepted requests
 
-	lock *sync.RWMutex
+	maxSize  int                          // Maximum number of entries (regular and intercepted) before the least recently used one is evicted
+	lru      *list.List                   // Recency list of *psCacheEntry, the front element is the most recently stored or promoted entry
+	elements map[psCacheKey]*list.Element // Map containing the recency list element of each entry
+
+	lock   *sync.RWMutex
+	logger *log.Entry
+}
+
+type psCacheKey struct {
+	preparedId  string
+	intercepted bool
+}
+
+// psCacheEntry is an element of the recency list. The lookups only hold the read lock so they don't move the entry in
+// the list, they set referenced instead and the entry is moved to the front when it reaches the back of the list
+// (second chance eviction).
+type psCacheEntry struct {
+	key        psCacheKey
+	referenced int32
 }
 
-func NewPreparedStatementCache() *PreparedStatementCache {
+func NewPreparedStatementCache(maxSize int, logger *log.Entry) *PreparedStatementCache {
 	return &PreparedStatementCache{
 		cache:            make(map[string]PreparedData),
 		index:            make(map[string]string),
 		interceptedCache: make(map[string]PreparedData),
+		maxSize:          maxSize,
+		lru:              list.New(),
+		elements:         make(map[psCacheKey]*list.Element),
 		lock:             &sync.RWMutex{},
+		logger:           logger,
 	}
 }
 
-func (psc PreparedStatementCache) GetPreparedStatementCacheSize() float64 {
+func (psc *PreparedStatementCache) GetPreparedStatementCacheSize() float64 {
 	psc.lock.RLock()
 	defer psc.lock.RUnlock()
 
 	return float64(len(psc.cache) + len(psc.interceptedCache))
 }
 
+// Store adds an entry to the cache and returns the number of least recently used entries that were evicted to make
+// room for it. Evicted statements are not prepared again by the proxy, the next EXECUTE for an evicted prepared id
+// results in an UNPREPARED response which makes the client driver prepare the statement again.
 func (psc *PreparedStatementCache) Store(
 	originPreparedResult *message.PreparedResult, targetPreparedResult *message.PreparedResult,
-	prepareRequestInfo *PrepareRequestInfo) {
+	prepareRequestInfo *PrepareRequestInfo) int {
 
 	originPrepareIdStr := string(originPreparedResult.PreparedQueryId)
 	targetPrepareIdStr := string(targetPreparedResult.PreparedQueryId)
@@ -44,29 +71,58 @@ func (psc *PreparedStatementCache) Store(
 
 	psc.cache[originPrepareIdStr] = NewPreparedData(originPreparedResult, targetPreparedResult, prepareRequestInfo)
 	psc.index[targetPrepareIdStr] = originPrepareIdStr
+	psc.touch(psCacheKey{preparedId: originPrepareIdStr, intercepted: false})
 
-	log.Debugf("Storing PS cache entry: {OriginPreparedId=%v, TargetPreparedId: %v, RequestInfo: %v}",
+	psc.logger.Debugf("Storing PS cache entry: {OriginPreparedId=%v, TargetPreparedId: %v, RequestInfo: %v}",
 		hex.EncodeToString(originPreparedResult.PreparedQueryId), hex.EncodeToString(targetPreparedResult.PreparedQueryId), prepareRequestInfo)
+	return psc.evict()
 }
 
-func (psc *PreparedStatementCache) StoreIntercepted(preparedResult *message.PreparedResult, prepareRequestInfo *PrepareRequestInfo) {
+// StoreIntercepted adds an entry for an intercepted request to the cache and returns the number of least recently used
+// entries that were evicted to make room for it.
+func (psc *PreparedStatementCache) StoreIntercepted(preparedResult *message.PreparedResult, prepareRequestInfo *PrepareRequestInfo) int {
 	prepareIdStr := string(preparedResult.PreparedQueryId)
 	psc.lock.Lock()
 	defer psc.lock.Unlock()
 
 	preparedData := NewPreparedData(preparedResult, preparedResult, prepareRequestInfo)
 	psc.interceptedCache[prepareIdStr] = preparedData
+	psc.touch(psCacheKey{preparedId: prepareIdStr, intercepted: true})
 
-	log.Debugf("Storing intercepted PS cache entry: {PreparedId=%v, RequestInfo: %v}",
+	psc.logger.Debugf("Storing intercepted PS cache entry: {PreparedId=%v, RequestInfo: %v}",
 		hex.EncodeToString(preparedResult.PreparedQueryId), prepareRequestInfo)
+	return psc.evict()
 }
 
 func (psc *PreparedStatementCache) Get(originPreparedId []byte) (PreparedData, bool) {
 	psc.lock.RLock()
 	defer psc.lock.RUnlock()
 	data, ok := psc.cache[string(originPreparedId)]
-	if !ok {
-		data, ok = psc.interceptedCache[string(originPreparedId)]
+	if ok {
+		psc.markUsed(psCacheKey{preparedId: string(originPreparedId), intercepted: false})
+		return data, true
+	}
+	data, ok = psc.interceptedCache[string(originPreparedId)]
+	if ok {
+		psc.markUsed(psCacheKey{preparedId: string(originPreparedId), intercepted: true})
+	}
+	return data, ok
+}
+
+// GetIntercepted is like Get but it returns the entry of the intercepted request first. The prepared ids of the
+// intercepted requests are computed by the proxy so they can be the same as the prepared id that a cluster returned
+// for the same statement to a client whose system queries are not intercepted (ZDM_SYSTEM_QUERIES_BYPASS_CLIENTS).
+func (psc *PreparedStatementCache) GetIntercepted(preparedId []byte) (PreparedData, bool) {
+	psc.lock.RLock()
+	defer psc.lock.RUnlock()
+	data, ok := psc.interceptedCache[string(preparedId)]
+	if ok {
+		psc.markUsed(psCacheKey{preparedId: string(preparedId), intercepted: true})
+		return data, true
+	}
+	data, ok = psc.cache[string(preparedId)]
+	if ok {
+		psc.markUsed(psCacheKey{preparedId: string(preparedId), intercepted: false})
 	}
 	return data, ok
 }
@@ -83,14 +139,90 @@ func (psc *PreparedStatementCache) GetByTargetPreparedId(targetPreparedId []byte
 
 	data, ok := psc.cache[originPreparedId]
 	if !ok {
-		log.Errorf("Could not get prepared data by target id even though there is an entry on the index map. "+
+		psc.logger.Errorf("Could not get prepared data by target id even though there is an entry on the index map. "+
 			"This is most likely a bug. OriginPreparedId = %v, TargetPreparedId = %v", originPreparedId, targetPreparedId)
 		return nil, false
 	}
 
+	psc.markUsed(psCacheKey{preparedId: originPreparedId, intercepted: false})
 	return data, true
 }
 
+// GetAll returns the prepared statements that are not intercepted by the proxy, roughly the most recently used one
+// first (the entries that were only looked up since they were stored are not promoted yet). The recency of the entries
+// is not changed.
+func (psc *PreparedStatementCache) GetAll() []PreparedData {
+	psc.lock.RLock()
+	defer psc.lock.RUnlock()
+
+	result := make([]PreparedData, 0, len(psc.cache))
+	for element := psc.lru.Front(); element != nil; element = element.Next() {
+		key := element.Value.(*psCacheEntry).key
+		if key.intercepted {
+			continue
+		}
+		if data, ok := psc.cache[key.preparedId]; ok {
+			result = append(result, data)
+		}
+	}
+	return result
+}
+
+// touch moves the entry to the front of the recency list. Must be called while holding the write lock.
+func (psc *PreparedStatementCache) touch(key psCacheKey) {
+	if element, ok := psc.elements[key]; ok {
+		atomic.StoreInt32(&element.Value.(*psCacheEntry).referenced, 0)
+		psc.lru.MoveToFront(element)
+		return
+	}
+	psc.elements[key] = psc.lru.PushFront(&psCacheEntry{key: key})
+}
+
+// markUsed records that the entry was looked up, it is promoted by evict. Must be called while holding the read lock,
+// the flag is only written when it is not set yet so that lookups of a hot entry don't keep writing to it.
+func (psc *PreparedStatementCache) markUsed(key psCacheKey) {
+	if element, ok := psc.elements[key]; ok {
+		entry := element.Value.(*psCacheEntry)
+		if atomic.LoadInt32(&entry.referenced) == 0 {
+			atomic.StoreInt32(&entry.referenced, 1)
+		}
+	}
+}
+
+// evict removes least recently used entries until the cache is within its maximum size and returns the number of
+// removed entries. Entries that were looked up since they were last moved to the front are moved to the front again
+// instead of being removed. Must be called while holding the write lock.
+func (psc *PreparedStatementCache) evict() int {
+	evicted := 0
+	for psc.maxSize > 0 && psc.lru.Len() > psc.maxSize {
+		element := psc.lru.Back()
+		entry := element.Value.(*psCacheEntry)
+		if atomic.LoadInt32(&entry.referenced) != 0 {
+			atomic.StoreInt32(&entry.referenced, 0)
+			psc.lru.MoveToFront(element)
+			continue
+		}
+		key := entry.key
+		psc.lru.Remove(element)
+		delete(psc.elements, key)
+		if key.intercepted {
+			delete(psc.interceptedCache, key.preparedId)
+		} else {
+			if data, ok := psc.cache[key.preparedId]; ok {
+				targetPrepareIdStr := string(data.GetTargetPreparedId())
+				if psc.index[targetPrepareIdStr] == key.preparedId {
+					delete(psc.index, targetPrepareIdStr)
+				}
+			}
+			delete(psc.cache, key.preparedId)
+		}
+		evicted++
+		psc.logger.Debugf("Evicted PS cache entry: {PreparedId=%v, Intercepted=%v}",
+			hex.EncodeToString([]byte(key.preparedId)), key.intercepted)
+	}
+	return evicted
+}
+
 type PreparedData interface {
 	GetOriginPreparedId() []byte
 	GetTargetPreparedId() []byte
@@ -0,0 +1,103 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+)
+
+func TestPreparedStatementCacheEviction(t *testing.T) {
+	psCache := NewPreparedStatementCache(2, log.NewEntry(log.StandardLogger()))
+	prepareRequestInfo := NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, false), nil, false, "", "")
+
+	require.Equal(t, 0, psCache.Store(
+		&message.PreparedResult{PreparedQueryId: []byte("ORIGIN_1")},
+		&message.PreparedResult{PreparedQueryId: []byte("TARGET_1")},
+		prepareRequestInfo))
+	require.Equal(t, 0, psCache.Store(
+		&message.PreparedResult{PreparedQueryId: []byte("ORIGIN_2")},
+		&message.PreparedResult{PreparedQueryId: []byte("TARGET_2")},
+		prepareRequestInfo))
+
+	// ORIGIN_1 becomes the most recently used entry so ORIGIN_2 is evicted next
+	_, ok := psCache.Get([]byte("ORIGIN_1"))
+	require.True(t, ok)
+
+	require.Equal(t, 1, psCache.StoreIntercepted(
+		&message.PreparedResult{PreparedQueryId: []byte("INTERCEPTED_1")},
+		prepareRequestInfo))
+	require.Equal(t, float64(2), psCache.GetPreparedStatementCacheSize())
+
+	_, ok = psCache.Get([]byte("ORIGIN_2"))
+	require.False(t, ok)
+	_, ok = psCache.GetByTargetPreparedId([]byte("TARGET_2"))
+	require.False(t, ok)
+
+	data, ok := psCache.GetByTargetPreparedId([]byte("TARGET_1"))
+	require.True(t, ok)
+	require.Equal(t, []byte("ORIGIN_1"), data.GetOriginPreparedId())
+
+	// ORIGIN_1 was used after INTERCEPTED_1 was stored so the intercepted entry is evicted
+	require.Equal(t, 1, psCache.Store(
+		&message.PreparedResult{PreparedQueryId: []byte("ORIGIN_3")},
+		&message.PreparedResult{PreparedQueryId: []byte("TARGET_3")},
+		prepareRequestInfo))
+	_, ok = psCache.Get([]byte("INTERCEPTED_1"))
+	require.False(t, ok)
+	_, ok = psCache.Get([]byte("ORIGIN_1"))
+	require.True(t, ok)
+	_, ok = psCache.Get([]byte("ORIGIN_3"))
+	require.True(t, ok)
+	require.Equal(t, float64(2), psCache.GetPreparedStatementCacheSize())
+}
+
+func TestPreparedStatementCacheStoreExistingEntry(t *testing.T) {
+	psCache := NewPreparedStatementCache(1, log.NewEntry(log.StandardLogger()))
+	prepareRequestInfo := NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, false), nil, false, "", "")
+
+	for i := 0; i < 3; i++ {
+		require.Equal(t, 0, psCache.Store(
+			&message.PreparedResult{PreparedQueryId: []byte("ORIGIN")},
+			&message.PreparedResult{PreparedQueryId: []byte("TARGET")},
+			prepareRequestInfo))
+	}
+	require.Equal(t, float64(1), psCache.GetPreparedStatementCacheSize())
+}
+
+func TestPreparedStatementCacheConcurrentLookups(t *testing.T) {
+	psCache := NewPreparedStatementCache(10, log.NewEntry(log.StandardLogger()))
+	prepareRequestInfo := NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, false), nil, false, "", "")
+	store := func(i int) {
+		psCache.Store(
+			&message.PreparedResult{PreparedQueryId: []byte(fmt.Sprintf("ORIGIN_%d", i))},
+			&message.PreparedResult{PreparedQueryId: []byte(fmt.Sprintf("TARGET_%d", i))},
+			prepareRequestInfo)
+	}
+	store(0)
+
+	// the entry that is looked up keeps being promoted while the other entries are evicted
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				_, ok := psCache.Get([]byte("ORIGIN_0"))
+				assert.True(t, ok)
+				psCache.GetByTargetPreparedId([]byte("TARGET_5"))
+			}
+		}()
+	}
+	for i := 1; i <= 100; i++ {
+		store(i)
+		_, ok := psCache.Get([]byte("ORIGIN_0"))
+		require.True(t, ok)
+	}
+	wg.Wait()
+	require.Equal(t, float64(10), psCache.GetPreparedStatementCacheSize())
+	require.Len(t, psCache.GetAll(), 10)
+}
@@ -86,12 +86,28 @@ type QueryInfo interface {
 	// This will always be false for non-INSERT statements or batches not containing INSERT statements.
 	hasNowFunctionCalls() bool
 
+	// Whether the query references at least one table name that is not qualified with a keyspace name.
+	hasUnqualifiedTableNames() bool
+
+	// Whether the query is a lightweight transaction, i.e. an INSERT with IF NOT EXISTS or an UPDATE/DELETE with an IF
+	// clause, or a BATCH containing at least one of them.
+	isConditional() bool
+
 	replaceNowFunctionCallsWithLiteral() (QueryInfo, []*term)
 	replaceNowFunctionCallsWithPositionalBindMarkers() (QueryInfo, []*term)
 	replaceNowFunctionCallsWithNamedBindMarkers() (QueryInfo, []*term)
+
+	// Returns a new QueryInfo object where every unqualified table name is qualified with the request keyspace
+	// (getRequestKeyspace()). If there is no request keyspace or no unqualified table names then it returns the same object.
+	qualifyTableNames() QueryInfo
+
+	// Returns a new QueryInfo object where the table names and the keyspace name of a USE statement are replaced with
+	// the names returned by mapName. Unqualified table names are resolved with the request keyspace and they are
+	// qualified if they are mapped. If no name is mapped then it returns the same object.
+	mapTableNames(mapName func(keyspaceName string, tableName string) (string, string)) QueryInfo
 }
 
-func inspectCqlQuery(query string, currentKeyspace string, timeUuidGenerator TimeUuidGenerator) QueryInfo {
+func inspectCqlQuery(query string, currentKeyspace string, timeUuidGenerator TimeUuidGenerator, logger *log.Entry) QueryInfo {
 	is := antlr.NewInputStream(query)
 	lexer := lexerPool.Get().(*parser.SimplifiedCqlLexer)
 	defer lexerPool.Put(lexer)
@@ -105,6 +121,7 @@ func inspectCqlQuery(query string, currentKeyspace string, timeUuidGenerator Tim
 		statementType:     statementTypeOther,
 		timeUuidGenerator: timeUuidGenerator,
 		requestKeyspace:   currentKeyspace,
+		logger:            logger,
 	}
 	antlr.ParseTreeWalkerDefault.Walk(listener, cqlParser.CqlStatement())
 	return listener
@@ -139,6 +156,10 @@ type parsedStatement struct {
 	statementIndex int
 	statementType  statementType
 	terms          []*term
+
+	// The table targeted by this statement. The keyspace name is the request keyspace if the table name is not qualified.
+	keyspaceName string
+	tableName    string
 }
 
 func (recv *parsedStatement) ShallowClone() *parsedStatement {
@@ -146,6 +167,8 @@ func (recv *parsedStatement) ShallowClone() *parsedStatement {
 		statementIndex: recv.statementIndex,
 		statementType:  recv.statementType,
 		terms:          recv.terms,
+		keyspaceName:   recv.keyspaceName,
+		tableName:      recv.tableName,
 	}
 }
 
@@ -314,6 +337,13 @@ type cqlListener struct {
 	positionalBindMarkers bool
 	namedBindMarkers      bool
 	nowFunctionCalls      bool
+	conditional           bool
+
+	// Start indexes of the table names that are not qualified with a keyspace name
+	unqualifiedTableNameIndexes []int
+
+	// Table names and keyspace name of a USE statement, in the order in which they appear in the query
+	tableNameRefs []*tableNameRef
 
 	// internal counters
 	currentPositionalIndex int
@@ -322,6 +352,8 @@ type cqlListener struct {
 	timeUuidGenerator TimeUuidGenerator
 
 	requestKeyspace string
+
+	logger *log.Entry
 }
 
 func (l *cqlListener) getQuery() string {
@@ -372,6 +404,14 @@ func (l *cqlListener) hasNowFunctionCalls() bool {
 	return l.nowFunctionCalls
 }
 
+func (l *cqlListener) hasUnqualifiedTableNames() bool {
+	return len(l.unqualifiedTableNameIndexes) > 0
+}
+
+func (l *cqlListener) isConditional() bool {
+	return l.conditional
+}
+
 func (l *cqlListener) EnterCqlStatement(ctx *parser.CqlStatementContext) {
 	if ctx.GetChildCount() == 0 {
 		return
@@ -395,11 +435,11 @@ func (l *cqlListener) EnterCqlStatement(ctx *parser.CqlStatementContext) {
 }
 
 func (l *cqlListener) ExitSelectStatement(ctx *parser.SelectStatementContext) {
-	if !isSystemKeyspace(l.getApplicableKeyspace()) {
-		return
-	}
-
-	if !isLocalTable(l.getTableName()) && !isPeersV1Table(l.getTableName()) && !isPeersV2Table(l.getTableName()) {
+	if isSystemKeyspace(l.getApplicableKeyspace()) {
+		if !isLocalTable(l.getTableName()) && !isPeersV1Table(l.getTableName()) && !isPeersV2Table(l.getTableName()) {
+			return
+		}
+	} else if !isIntrospectionKeyspace(l.getApplicableKeyspace()) {
 		return
 	}
 
@@ -412,19 +452,19 @@ func (l *cqlListener) ExitSelectStatement(ctx *parser.SelectStatementContext) {
 		case antlr.TerminalNode:
 			if typedChild.GetSymbol().GetTokenType() == parser.SimplifiedCqlParserK_JSON ||
 				typedChild.GetSymbol().GetTokenType() == parser.SimplifiedCqlParserK_DISTINCT {
-				log.Warnf("Proxy does not support 'JSON' or 'DISTINCT' for system.local and system.peers queries: %v", ctx.GetText())
+				l.logger.Warnf("Proxy does not support 'JSON' or 'DISTINCT' for system.local and system.peers queries: %v", ctx.GetText())
 				return
 			}
 		case *parser.SelectClauseContext:
 			parsedSelectClause, err := extractSelectClause(typedChild)
 			if err != nil {
-				log.Warnf("Proxy could not parse select clause of system.local/system.peers query: %v", err.Error())
+				l.logger.Warnf("Proxy could not parse select clause of system.local/system.peers query: %v", err.Error())
 				return
 			}
 			l.parsedSelectClause = parsedSelectClause
 			return
 		default:
-			log.Errorf("Proxy could not parse SELECT query for system.local/peers: %v", ctx.GetText())
+			l.logger.Errorf("Proxy could not parse SELECT query for system.local/peers: %v", ctx.GetText())
 			return
 		}
 	}
@@ -432,12 +472,15 @@ func (l *cqlListener) ExitSelectStatement(ctx *parser.SelectStatementContext) {
 
 func (l *cqlListener) EnterInsertStatement(ctx *parser.InsertStatementContext) {
 	parsedStmt := &parsedStatement{statementIndex: l.currentBatchChildIndex, statementType: statementTypeInsert}
+	parsedStmt.keyspaceName, parsedStmt.tableName = l.extractStatementTableName(ctx.TableName())
 	for _, childCtx := range ctx.GetChildren() {
 		switch childCtx.(type) {
 		case parser.ITermsContext:
 			parsedStmt.terms = append(parsedStmt.terms, l.extractTerms(childCtx)...)
 		case parser.IUsingClauseContext:
 			parsedStmt.terms = append(parsedStmt.terms, l.extractUsingClauseBindMarkers(childCtx)...)
+		case antlr.TerminalNode:
+			l.checkConditional(childCtx)
 		}
 	}
 
@@ -447,6 +490,7 @@ func (l *cqlListener) EnterInsertStatement(ctx *parser.InsertStatementContext) {
 
 func (l *cqlListener) EnterUpdateStatement(ctx *parser.UpdateStatementContext) {
 	parsedStmt := &parsedStatement{statementIndex: l.currentBatchChildIndex, statementType: statementTypeUpdate}
+	parsedStmt.keyspaceName, parsedStmt.tableName = l.extractStatementTableName(ctx.TableName())
 
 	for _, childCtx := range ctx.GetChildren() {
 		switch childCtx.(type) {
@@ -467,6 +511,8 @@ func (l *cqlListener) EnterUpdateStatement(ctx *parser.UpdateStatementContext) {
 		case parser.IConditionsContext:
 			conditionTerms := l.extractConditionsTerms(childCtx)
 			parsedStmt.terms = append(parsedStmt.terms, conditionTerms...)
+		case antlr.TerminalNode:
+			l.checkConditional(childCtx)
 		}
 	}
 
@@ -476,6 +522,7 @@ func (l *cqlListener) EnterUpdateStatement(ctx *parser.UpdateStatementContext) {
 
 func (l *cqlListener) EnterDeleteStatement(ctx *parser.DeleteStatementContext) {
 	parsedStmt := &parsedStatement{statementIndex: l.currentBatchChildIndex, statementType: statementTypeDelete}
+	parsedStmt.keyspaceName, parsedStmt.tableName = l.extractStatementTableName(ctx.TableName())
 
 	for _, childCtx := range ctx.GetChildren() {
 		switch childCtx.(type) {
@@ -501,6 +548,8 @@ func (l *cqlListener) EnterDeleteStatement(ctx *parser.DeleteStatementContext) {
 		case parser.IConditionsContext:
 			conditionTerms := l.extractConditionsTerms(childCtx)
 			parsedStmt.terms = append(parsedStmt.terms, conditionTerms...)
+		case antlr.TerminalNode:
+			l.checkConditional(childCtx)
 		}
 	}
 
@@ -508,6 +557,14 @@ func (l *cqlListener) EnterDeleteStatement(ctx *parser.DeleteStatementContext) {
 	l.currentBatchChildIndex++
 }
 
+// checkConditional marks the query as a lightweight transaction if the token is the IF keyword
+// of an INSERT, UPDATE or DELETE statement.
+func (l *cqlListener) checkConditional(ctx antlr.Tree) {
+	if terminalNode, ok := ctx.(antlr.TerminalNode); ok && terminalNode.GetSymbol().GetTokenType() == parser.SimplifiedCqlParserK_IF {
+		l.conditional = true
+	}
+}
+
 func (l *cqlListener) EnterBatchStatement(ctx *parser.BatchStatementContext) {
 	usingClauseCtx := ctx.UsingClause()
 	if usingClauseCtx != nil {
@@ -517,22 +574,56 @@ func (l *cqlListener) EnterBatchStatement(ctx *parser.BatchStatementContext) {
 }
 
 func (l *cqlListener) EnterUseStatement(ctx *parser.UseStatementContext) {
-	l.keyspaceName = extractIdentifier(ctx.KeyspaceName().(*parser.KeyspaceNameContext).Identifier().(*parser.IdentifierContext))
+	keyspaceNameCtx := ctx.KeyspaceName().(*parser.KeyspaceNameContext)
+	l.keyspaceName = extractIdentifier(keyspaceNameCtx.Identifier().(*parser.IdentifierContext))
+	l.tableNameRefs = append(l.tableNameRefs, &tableNameRef{
+		startIndex:   keyspaceNameCtx.GetStart().GetStart(),
+		stopIndex:    keyspaceNameCtx.GetStop().GetStop(),
+		keyspaceName: l.keyspaceName,
+	})
 }
 
 func (l *cqlListener) EnterTableName(ctx *parser.TableNameContext) {
-	qualifiedId := ctx.GetChild(0)
 	// Note: this will capture the *last* table name in a BATCH statement
-	if qualifiedId.GetChildCount() == 1 {
-		identifierContext := qualifiedId.GetChild(0).(*parser.IdentifierContext)
-		l.tableName = extractIdentifier(identifierContext)
+	keyspaceName, tableName := extractTableName(ctx)
+	l.tableName = tableName
+	if keyspaceName == "" {
+		l.unqualifiedTableNameIndexes = append(l.unqualifiedTableNameIndexes, ctx.GetStart().GetStart())
 	} else {
-		// 3 children: keyspaceName, token DOT, identifier
-		keyspaceNameContext := qualifiedId.GetChild(0)
-		l.keyspaceName = extractIdentifier(keyspaceNameContext.GetChild(0).(*parser.IdentifierContext))
-		identifierContext := qualifiedId.GetChild(2).(*parser.IdentifierContext)
-		l.tableName = extractIdentifier(identifierContext)
+		l.keyspaceName = keyspaceName
 	}
+	l.tableNameRefs = append(l.tableNameRefs, &tableNameRef{
+		startIndex:   ctx.GetStart().GetStart(),
+		stopIndex:    ctx.GetStop().GetStop(),
+		keyspaceName: keyspaceName,
+		tableName:    tableName,
+	})
+}
+
+// extractStatementTableName returns the keyspace and table names of an INSERT, UPDATE or DELETE statement,
+// using the request keyspace if the table name is not qualified.
+func (l *cqlListener) extractStatementTableName(ctx parser.ITableNameContext) (string, string) {
+	tableNameCtx, ok := ctx.(*parser.TableNameContext)
+	if !ok {
+		return "", ""
+	}
+	keyspaceName, tableName := extractTableName(tableNameCtx)
+	if keyspaceName == "" {
+		keyspaceName = l.requestKeyspace
+	}
+	return keyspaceName, tableName
+}
+
+// extractTableName returns the keyspace name (empty if the table name is not qualified) and the table name.
+func extractTableName(ctx *parser.TableNameContext) (string, string) {
+	qualifiedId := ctx.GetChild(0)
+	if qualifiedId.GetChildCount() == 1 {
+		return "", extractIdentifier(qualifiedId.GetChild(0).(*parser.IdentifierContext))
+	}
+	// 3 children: keyspaceName, token DOT, identifier
+	keyspaceNameContext := qualifiedId.GetChild(0)
+	keyspaceName := extractIdentifier(keyspaceNameContext.GetChild(0).(*parser.IdentifierContext))
+	return keyspaceName, extractIdentifier(qualifiedId.GetChild(2).(*parser.IdentifierContext))
 }
 
 func extractSelectClause(selectClauseCtx *parser.SelectClauseContext) (*selectClause, error) {
@@ -657,7 +748,7 @@ func (l *cqlListener) extractBindMarker(bindMarkerCtx antlr.Tree) *term {
 		}
 	}
 
-	log.Errorf("Could not parse bind marker: %T", bindMarkerCtx)
+	l.logger.Errorf("Could not parse bind marker: %T", bindMarkerCtx)
 	return nil
 }
 
@@ -799,6 +890,11 @@ func extractIdentifier(identifierContext *parser.IdentifierContext) string {
 	}
 }
 
+// formatIdentifier returns a quoted CQL identifier so that the case of the provided name is preserved.
+func formatIdentifier(name string) string {
+	return "\"" + strings.ReplaceAll(name, "\"", "\"\"") + "\""
+}
+
 func (l *cqlListener) replaceFunctionCalls(replacementFunc func(query string, functionCall *functionCall) (string, replacementType)) (QueryInfo, []*term) {
 	if !l.hasNowFunctionCalls() {
 		return l, make([]*term, 0)
@@ -852,6 +948,8 @@ func (l *cqlListener) replaceFunctionCalls(replacementFunc func(query string, fu
 	newQueryInfo.parsedStatements = newParsedStatements
 	newQueryInfo.namedBindMarkers = namedMarkers
 	newQueryInfo.positionalBindMarkers = positionalMarkers
+	// table name indexes are not valid for the new query string, tables have to be qualified before replacing function calls
+	newQueryInfo.unqualifiedTableNameIndexes = nil
 	return newQueryInfo, replacedTerms
 }
 
@@ -885,6 +983,78 @@ func (l *cqlListener) replaceNowFunctionCallsWithNamedBindMarkers() (QueryInfo,
 	})
 }
 
+func (l *cqlListener) qualifyTableNames() QueryInfo {
+	if !l.hasUnqualifiedTableNames() || l.requestKeyspace == "" {
+		return l
+	}
+
+	// antlr indexes are rune based so work with runes instead of bytes
+	query := []rune(l.query)
+	qualifier := []rune(formatIdentifier(l.requestKeyspace) + ".")
+	result := make([]rune, 0, len(query)+len(qualifier)*len(l.unqualifiedTableNameIndexes))
+	i := 0
+	for _, tableNameIndex := range l.unqualifiedTableNameIndexes {
+		result = append(result, query[i:tableNameIndex]...)
+		result = append(result, qualifier...)
+		i = tableNameIndex
+	}
+	result = append(result, query[i:]...)
+
+	// parse the new query again so that the indexes of terms and function calls are correct
+	return inspectCqlQuery(string(result), l.requestKeyspace, l.timeUuidGenerator, l.logger)
+}
+
+// tableNameRef is the position of a (possibly qualified) table name in a query, or of a keyspace name if tableName
+// is empty. The indexes are rune based and stopIndex is inclusive.
+type tableNameRef struct {
+	startIndex   int
+	stopIndex    int
+	keyspaceName string
+	tableName    string
+}
+
+func (l *cqlListener) mapTableNames(mapName func(keyspaceName string, tableName string) (string, string)) QueryInfo {
+	// antlr indexes are rune based so work with runes instead of bytes
+	query := []rune(l.query)
+	result := make([]rune, 0, len(query))
+	i := 0
+	mapped := false
+	for _, ref := range l.tableNameRefs {
+		var newName string
+		if ref.tableName == "" {
+			newKeyspaceName, _ := mapName(ref.keyspaceName, "")
+			if newKeyspaceName == ref.keyspaceName {
+				continue
+			}
+			newName = formatIdentifier(newKeyspaceName)
+		} else {
+			keyspaceName := ref.keyspaceName
+			if keyspaceName == "" {
+				keyspaceName = l.requestKeyspace
+			}
+			if keyspaceName == "" {
+				continue
+			}
+			newKeyspaceName, newTableName := mapName(keyspaceName, ref.tableName)
+			if newKeyspaceName == keyspaceName && newTableName == ref.tableName {
+				continue
+			}
+			newName = formatIdentifier(newKeyspaceName) + "." + formatIdentifier(newTableName)
+		}
+		result = append(result, query[i:ref.startIndex]...)
+		result = append(result, []rune(newName)...)
+		i = ref.stopIndex + 1
+		mapped = true
+	}
+	if !mapped {
+		return l
+	}
+	result = append(result, query[i:]...)
+
+	// parse the new query again so that the indexes of terms and function calls are correct
+	return inspectCqlQuery(string(result), l.requestKeyspace, l.timeUuidGenerator, l.logger)
+}
+
 func (l *cqlListener) shallowClone() *cqlListener {
 	return &cqlListener{
 		BaseSimplifiedCqlListener: l.BaseSimplifiedCqlListener,
@@ -896,11 +1066,13 @@ func (l *cqlListener) shallowClone() *cqlListener {
 		positionalBindMarkers:     l.positionalBindMarkers,
 		namedBindMarkers:          l.namedBindMarkers,
 		nowFunctionCalls:          l.nowFunctionCalls,
+		conditional:               l.conditional,
 		currentPositionalIndex:    l.currentPositionalIndex,
 		currentBatchChildIndex:    l.currentBatchChildIndex,
 		timeUuidGenerator:         l.timeUuidGenerator,
 		requestKeyspace:           l.requestKeyspace,
 		parsedSelectClause:        l.parsedSelectClause,
+		logger:                    l.logger,
 	}
 }
 
@@ -2,6 +2,7 @@ package zdmproxy
 
 import (
 	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -289,7 +290,7 @@ func TestInspectCqlQuery(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			timeUuidGenerator, err := GetDefaultTimeUuidGenerator()
 			require.Nil(t, err)
-			actual := inspectCqlQuery(tt.query, "", timeUuidGenerator)
+			actual := inspectCqlQuery(tt.query, "", timeUuidGenerator, log.NewEntry(log.StandardLogger()))
 			if actual.getStatementType() != tt.statementType {
 				t.Errorf("inspectCqlQuery().isSelectStatement() actual = %v, expected %v", actual.getStatementType(), tt.statementType)
 			}
@@ -553,7 +554,7 @@ func TestNowFunctionCalls(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
-			info := inspectCqlQuery(tt.query, "", &fakeTimeUuidGenerator{uid: tt.replacement})
+			info := inspectCqlQuery(tt.query, "", &fakeTimeUuidGenerator{uid: tt.replacement}, log.NewEntry(log.StandardLogger()))
 			assert.Equal(t, tt.statementType, info.getStatementType())
 			assert.Equal(t, tt.hasNow, info.hasNowFunctionCalls())
 
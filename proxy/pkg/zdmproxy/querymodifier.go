@@ -9,11 +9,12 @@ import (
 )
 
 type QueryModifier struct {
-	timeUuidGenerator TimeUuidGenerator
+	timeUuidGenerator       TimeUuidGenerator
+	writeTimestampGenerator *writeTimestampGenerator
 }
 
 func NewQueryModifier(timeUuidGenerator TimeUuidGenerator) *QueryModifier {
-	return &QueryModifier{timeUuidGenerator: timeUuidGenerator}
+	return &QueryModifier{timeUuidGenerator: timeUuidGenerator, writeTimestampGenerator: defaultWriteTimestampGenerator}
 }
 
 // replaceQueryString modifies the incoming request in certain conditions:
@@ -55,7 +56,131 @@ func (recv *QueryModifier) replaceQueryString(currentKeyspace string, context *f
 	if err != nil {
 		return nil, nil, fmt.Errorf("could not convert modified frame to raw frame: %w", err)
 	}
-	return NewInitializedFrameDecodeContext(newRawFrame, newFrame, newStatementsQueryData), replacedTerms, nil
+	return NewInitializedFrameDecodeContext(newRawFrame, newFrame, newStatementsQueryData, context.logger), replacedTerms, nil
+}
+
+// qualifyQueryString modifies the incoming request in certain conditions:
+//   * the request is a QUERY, PREPARE or BATCH
+//   * and it references table names that are not qualified with a keyspace name
+//   * and there is a keyspace set for this request (USE statement or keyspace flag)
+func (recv *QueryModifier) qualifyQueryString(currentKeyspace string, context *frameDecodeContext) (*frameDecodeContext, error) {
+	switch context.GetRawFrame().Header.OpCode {
+	case primitive.OpCodeQuery, primitive.OpCodePrepare, primitive.OpCodeBatch:
+	default:
+		return context, nil
+	}
+
+	decodedFrame, statementsQueryData, err := context.GetOrDecodeAndInspect(currentKeyspace, recv.timeUuidGenerator)
+	if err != nil {
+		if errors.Is(err, NotInspectableErr) {
+			return context, nil
+		}
+		return nil, fmt.Errorf("could not check whether query needs to be qualified for a '%v' request: %w",
+			context.GetRawFrame().Header.OpCode.String(), err)
+	}
+
+	newStatementsQueryData := make([]*statementQueryData, 0, len(statementsQueryData))
+	qualified := false
+	for _, stmtQueryData := range statementsQueryData {
+		newQueryData := stmtQueryData.queryData.qualifyTableNames()
+		if newQueryData != stmtQueryData.queryData {
+			qualified = true
+		}
+		newStatementsQueryData = append(
+			newStatementsQueryData,
+			&statementQueryData{statementIndex: stmtQueryData.statementIndex, queryData: newQueryData})
+	}
+
+	if !qualified {
+		return context, nil
+	}
+
+	newFrame := decodedFrame.Clone()
+	switch newMsg := newFrame.Body.Message.(type) {
+	case *message.Query:
+		newMsg.Query = newStatementsQueryData[0].queryData.getQuery()
+	case *message.Prepare:
+		newMsg.Query = newStatementsQueryData[0].queryData.getQuery()
+	case *message.Batch:
+		for _, newStmtQueryData := range newStatementsQueryData {
+			if newStmtQueryData.statementIndex >= len(newMsg.Children) {
+				return nil, fmt.Errorf("new query data statement index (%v) is greater or equal than "+
+					"number of batch child statements (%v)", newStmtQueryData.statementIndex, len(newMsg.Children))
+			}
+			newMsg.Children[newStmtQueryData.statementIndex].QueryOrId = newStmtQueryData.queryData.getQuery()
+		}
+	default:
+		return nil, fmt.Errorf("expected Query, Prepare or Batch in cloned frame but got %v instead",
+			newFrame.Body.Message.GetOpCode())
+	}
+
+	newRawFrame, err := defaultCodec.ConvertToRawFrame(newFrame)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert qualified frame to raw frame: %w", err)
+	}
+	return NewInitializedFrameDecodeContext(newRawFrame, newFrame, newStatementsQueryData, context.logger), nil
+}
+
+// injectWriteTimestamp sets a proxy generated default timestamp on a QUERY, EXECUTE or BATCH request that
+// doesn't have one so that ORIGIN and TARGET apply the write with the same timestamp.
+// A USING TIMESTAMP clause in the query string still takes precedence over the default timestamp.
+func (recv *QueryModifier) injectWriteTimestamp(context *frameDecodeContext) (*frameDecodeContext, error) {
+	switch context.GetRawFrame().Header.OpCode {
+	case primitive.OpCodeQuery, primitive.OpCodeExecute, primitive.OpCodeBatch:
+	default:
+		return context, nil
+	}
+
+	if !context.GetRawFrame().Header.Version.SupportsQueryFlag(primitive.QueryFlagDefaultTimestamp) {
+		return context, nil
+	}
+
+	decodedFrame, err := context.GetOrDecodeFrame()
+	if err != nil {
+		return nil, fmt.Errorf("could not decode '%v' request to inject write timestamp: %w",
+			context.GetRawFrame().Header.OpCode.String(), err)
+	}
+
+	switch msg := decodedFrame.Body.Message.(type) {
+	case *message.Query:
+		if msg.Options != nil && msg.Options.DefaultTimestamp != nil {
+			return context, nil
+		}
+	case *message.Execute:
+		if msg.Options != nil && msg.Options.DefaultTimestamp != nil {
+			return context, nil
+		}
+	case *message.Batch:
+		if msg.DefaultTimestamp != nil {
+			return context, nil
+		}
+	}
+
+	timestamp := &primitive.NillableInt64{Value: recv.writeTimestampGenerator.next()}
+	newFrame := decodedFrame.Clone()
+	switch newMsg := newFrame.Body.Message.(type) {
+	case *message.Query:
+		if newMsg.Options == nil {
+			newMsg.Options = &message.QueryOptions{}
+		}
+		newMsg.Options.DefaultTimestamp = timestamp
+	case *message.Execute:
+		if newMsg.Options == nil {
+			newMsg.Options = &message.QueryOptions{}
+		}
+		newMsg.Options.DefaultTimestamp = timestamp
+	case *message.Batch:
+		newMsg.DefaultTimestamp = timestamp
+	default:
+		return nil, fmt.Errorf("expected Query, Execute or Batch in cloned frame but got %v instead",
+			newFrame.Body.Message.GetOpCode())
+	}
+
+	newRawFrame, err := defaultCodec.ConvertToRawFrame(newFrame)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert frame with injected write timestamp to raw frame: %w", err)
+	}
+	return NewInitializedFrameDecodeContext(newRawFrame, newFrame, context.statementsQueryData, context.logger), nil
 }
 
 func (recv *QueryModifier) replaceQueryInBatchMessage(
@@ -4,8 +4,13 @@ import (
 	"github.com/datastax/go-cassandra-native-protocol/frame"
 	"github.com/datastax/go-cassandra-native-protocol/message"
 	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestReplaceQueryString(t *testing.T) {
@@ -153,7 +158,7 @@ func TestReplaceQueryString(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			context := &frameDecodeContext{frame: test.f}
+			context := &frameDecodeContext{frame: test.f, logger: log.NewEntry(log.StandardLogger())}
 			timeUuidGenerator, err := GetDefaultTimeUuidGenerator()
 			require.Nil(t, err)
 			statementsQueryData, err := context.GetOrInspectAllStatements("", timeUuidGenerator)
@@ -239,6 +244,156 @@ func TestReplaceQueryString(t *testing.T) {
 
 }
 
+func TestQualifyQueryString(t *testing.T) {
+	tests := []struct {
+		name            string
+		f               *frame.RawFrame
+		currentKeyspace string
+		expectedQueries map[int]string
+	}{
+		{"OpCodeQuery SELECT unqualified",
+			mockQueryFrame(t, "SELECT blah FROM t2"), "ks1",
+			map[int]string{0: "SELECT blah FROM \"ks1\".t2"}},
+		{"OpCodeQuery SELECT qualified",
+			mockQueryFrame(t, "SELECT blah FROM ks2.t2"), "ks1",
+			nil},
+		{"OpCodeQuery SELECT no keyspace",
+			mockQueryFrame(t, "SELECT blah FROM t2"), "",
+			nil},
+		{"OpCodeQuery INSERT case sensitive keyspace",
+			mockQueryFrame(t, "INSERT INTO blah (a, b) VALUES (now(), 1)"), "MyKs",
+			map[int]string{0: "INSERT INTO \"MyKs\".blah (a, b) VALUES (now(), 1)"}},
+		{"OpCodePrepare UPDATE",
+			mockPrepareFrame(t, "UPDATE blah SET a = ? WHERE b = ?"), "ks1",
+			map[int]string{0: "UPDATE \"ks1\".blah SET a = ? WHERE b = ?"}},
+		{"OpCodePrepare with keyspace flag",
+			mockPrepareFrameWithKeyspace(t, "DELETE FROM blah WHERE b = ?", "ks2"), "ks1",
+			map[int]string{0: "DELETE FROM \"ks2\".blah WHERE b = ?"}},
+		{"OpCodeQuery BATCH",
+			mockQueryFrame(t, "BEGIN BATCH INSERT INTO blah (a) VALUES (1); UPDATE ks2.blah SET a = 2 WHERE b = 1; DELETE FROM blah2 WHERE b = 1; APPLY BATCH"), "ks1",
+			map[int]string{0: "BEGIN BATCH INSERT INTO \"ks1\".blah (a) VALUES (1); UPDATE ks2.blah SET a = 2 WHERE b = 1; DELETE FROM \"ks1\".blah2 WHERE b = 1; APPLY BATCH"}},
+		{"OpCodeBatch Mixed Prepared and Simple",
+			mockBatchWithChildren(t, []*message.BatchChild{
+				{QueryOrId: "INSERT INTO blah (a) VALUES (1)"},
+				{QueryOrId: []byte{0x01}},
+				{QueryOrId: "INSERT INTO ks2.blah (a) VALUES (1)"},
+			}), "ks1",
+			map[int]string{0: "INSERT INTO \"ks1\".blah (a) VALUES (1)"}},
+		{"OpCodeExecute",
+			mockExecuteFrame(t, "abc"), "ks1",
+			nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			context := NewFrameDecodeContext(test.f, log.NewEntry(log.StandardLogger()))
+			timeUuidGenerator, err := GetDefaultTimeUuidGenerator()
+			require.Nil(t, err)
+			queryModifier := NewQueryModifier(timeUuidGenerator)
+			newContext, err := queryModifier.qualifyQueryString(test.currentKeyspace, context)
+			require.Nil(t, err)
+			if len(test.expectedQueries) == 0 {
+				require.Same(t, context, newContext)
+				return
+			}
+
+			require.NotEqual(t, context.frame.Body, newContext.frame.Body)
+			require.Equal(t, context.frame.Header.StreamId, newContext.frame.Header.StreamId)
+			decodedFrame, err := defaultCodec.ConvertFromRawFrame(newContext.GetRawFrame())
+			require.Nil(t, err)
+			for idx, expectedQuery := range test.expectedQueries {
+				switch msg := decodedFrame.Body.Message.(type) {
+				case *message.Query:
+					require.Equal(t, expectedQuery, msg.Query)
+				case *message.Prepare:
+					require.Equal(t, expectedQuery, msg.Query)
+				case *message.Batch:
+					require.Equal(t, expectedQuery, msg.Children[idx].QueryOrId)
+				default:
+					require.Fail(t, "unexpected message type")
+				}
+			}
+			for _, stmtQueryData := range newContext.statementsQueryData {
+				require.False(t, stmtQueryData.queryData.getKeyspaceName() == "")
+			}
+		})
+	}
+}
+
+func TestInjectWriteTimestamp(t *testing.T) {
+	existingTimestamp := &primitive.NillableInt64{Value: 1234}
+	tests := []struct {
+		name              string
+		f                 *frame.RawFrame
+		expectedInjected  bool
+		expectedTimestamp *primitive.NillableInt64
+	}{
+		{"OpCodeQuery", mockQueryFrame(t, "INSERT INTO ks.tb (a) VALUES (1)"), true, nil},
+		{"OpCodeQuery with timestamp",
+			mockFrame(t, &message.Query{
+				Query:   "INSERT INTO ks.tb (a) VALUES (1)",
+				Options: &message.QueryOptions{DefaultTimestamp: existingTimestamp}}, primitive.ProtocolVersion4),
+			false, existingTimestamp},
+		{"OpCodeExecute", mockExecuteFrame(t, "abc"), true, nil},
+		{"OpCodeBatch", mockBatch(t, "INSERT INTO ks.tb (a) VALUES (1)"), true, nil},
+		{"OpCodeBatch with timestamp",
+			mockFrame(t, &message.Batch{
+				Children:         []*message.BatchChild{{QueryOrId: "INSERT INTO ks.tb (a) VALUES (1)"}},
+				DefaultTimestamp: existingTimestamp}, primitive.ProtocolVersion4),
+			false, existingTimestamp},
+		{"OpCodePrepare", mockPrepareFrame(t, "INSERT INTO ks.tb (a) VALUES (?)"), false, nil},
+		{"OpCodeQuery protocol v2",
+			mockFrame(t, &message.Query{Query: "INSERT INTO ks.tb (a) VALUES (1)"}, primitive.ProtocolVersion2),
+			false, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			context := NewFrameDecodeContext(test.f, log.NewEntry(log.StandardLogger()))
+			timeUuidGenerator, err := GetDefaultTimeUuidGenerator()
+			require.Nil(t, err)
+			queryModifier := NewQueryModifier(timeUuidGenerator)
+			before := time.Now().UnixNano() / int64(time.Microsecond)
+			newContext, err := queryModifier.injectWriteTimestamp(context)
+			require.Nil(t, err)
+			if !test.expectedInjected {
+				require.Same(t, context, newContext)
+				if test.expectedTimestamp == nil {
+					return
+				}
+			}
+
+			decodedFrame, err := defaultCodec.ConvertFromRawFrame(newContext.GetRawFrame())
+			require.Nil(t, err)
+			var timestamp *primitive.NillableInt64
+			switch msg := decodedFrame.Body.Message.(type) {
+			case *message.Query:
+				timestamp = msg.Options.DefaultTimestamp
+			case *message.Execute:
+				timestamp = msg.Options.DefaultTimestamp
+			case *message.Batch:
+				timestamp = msg.DefaultTimestamp
+			default:
+				require.Fail(t, "unexpected message type")
+			}
+			require.NotNil(t, timestamp)
+			if test.expectedTimestamp != nil {
+				require.Equal(t, test.expectedTimestamp.Value, timestamp.Value)
+			} else {
+				require.GreaterOrEqual(t, timestamp.Value, before)
+			}
+		})
+	}
+}
+
+func TestWriteTimestampGeneratorIsMonotonic(t *testing.T) {
+	generator := &writeTimestampGenerator{}
+	last := generator.next()
+	for i := 0; i < 1000; i++ {
+		next := generator.next()
+		require.Greater(t, next, last)
+		last = next
+	}
+}
+
 func contains(s []int, e int) bool {
 	for _, a := range s {
 		if a == e {
@@ -247,3 +402,50 @@ func contains(s []int, e int) bool {
 	}
 	return false
 }
+
+func TestShouldInjectWriteTimestamp(t *testing.T) {
+	insert := "INSERT INTO ks.tb (a) VALUES (1)"
+	lwtInsert := "INSERT INTO ks.tb (a) VALUES (1) IF NOT EXISTS"
+	counterUpdate := "UPDATE ks.counters SET c = c + 1 WHERE a = 1"
+	plainBatch := mockBatchWithChildren(t, []*message.BatchChild{{QueryOrId: insert}, {QueryOrId: insert}})
+	tests := []struct {
+		name        string
+		f           *frame.RawFrame
+		requestInfo RequestInfo
+		enabled     bool
+		expected    bool
+	}{
+		{"write", mockQueryFrame(t, insert), NewWriteRequestInfo(forwardToBoth), true, true},
+		{"disabled", mockQueryFrame(t, insert), NewWriteRequestInfo(forwardToBoth), false, false},
+		{"write to a single cluster", mockQueryFrame(t, insert), NewWriteRequestInfo(forwardToTarget), true, false},
+		{"lightweight transaction", mockQueryFrame(t, lwtInsert), NewConditionalRequestInfo(forwardToBoth), true, false},
+		{"counter update", mockQueryFrame(t, counterUpdate), NewCounterWriteRequestInfo(forwardToBoth), true, false},
+		{"schema change", mockQueryFrame(t, "CREATE TABLE ks.tb2 (a int PRIMARY KEY)"), NewSchemaChangeRequestInfo(forwardToBoth, true), true, false},
+		{"other statement", mockQueryFrame(t, "USE ks"), NewGenericRequestInfo(forwardToBoth, true, true), true, false},
+		{"BATCH", plainBatch, NewBatchRequestInfo(map[int]PreparedData{}, nil, 2), true, true},
+		{"BATCH with lightweight transaction", mockBatchWithChildren(t, []*message.BatchChild{{QueryOrId: insert}, {QueryOrId: lwtInsert}}),
+			NewBatchRequestInfo(map[int]PreparedData{}, nil, 2), true, false},
+		{"BATCH with counter update", mockBatchWithChildren(t, []*message.BatchChild{{QueryOrId: counterUpdate}}),
+			NewBatchRequestInfo(map[int]PreparedData{}, nil, 1), true, false},
+		{"conditional BATCH", plainBatch, NewConditionalBatchRequestInfo(map[int]PreparedData{}, 2, forwardToBoth), true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeUuidGenerator, err := GetDefaultTimeUuidGenerator()
+			require.Nil(t, err)
+			conf := config.New()
+			conf.InjectWriteTimestamp = tt.enabled
+			counterTables := &atomic.Value{}
+			counterTables.Store(map[string]bool{"ks.counters": true})
+			ch := &ClientHandler{
+				conf:              conf,
+				primaryCluster:    common.ClusterTypeOrigin,
+				originControlConn: &ControlConn{counterTables: counterTables},
+				timeUuidGenerator: timeUuidGenerator,
+			}
+			actual, err := ch.shouldInjectWriteTimestamp(NewFrameDecodeContext(tt.f, log.NewEntry(log.StandardLogger())), tt.requestInfo, "")
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+// queryRewriter applies the rules of ZDM_QUERY_REWRITE_RULES_FILE to the query strings of the QUERY, PREPARE and
+// BATCH requests that are forwarded to each cluster. With ZDM_QUERY_REWRITE_DRY_RUN the rewrites are only logged.
+//
+// The rules are selected with the statement type, keyspace and table of the query sent by the client and they are
+// applied in the order in which they are defined, each one to the result of the previous one.
+type queryRewriter struct {
+	rules             []*common.QueryRewriteRule
+	dryRun            bool
+	timeUuidGenerator TimeUuidGenerator
+}
+
+func newQueryRewriter(
+	rules []*common.QueryRewriteRule, dryRun bool, timeUuidGenerator TimeUuidGenerator) *queryRewriter {
+	if len(rules) == 0 {
+		return nil
+	}
+	return &queryRewriter{
+		rules:             rules,
+		dryRun:            dryRun,
+		timeUuidGenerator: timeUuidGenerator,
+	}
+}
+
+// rewriteRequest returns the request that should be sent to the provided cluster, which is the same request if no
+// rule changed it.
+func (recv *queryRewriter) rewriteRequest(
+	request *frame.RawFrame, clusterType common.ClusterType, currentKeyspace string,
+	logger *log.Entry) (*frame.RawFrame, error) {
+
+	switch request.Header.OpCode {
+	case primitive.OpCodeQuery, primitive.OpCodePrepare, primitive.OpCodeBatch:
+	default:
+		return request, nil
+	}
+
+	decodedFrame, err := defaultCodec.ConvertFromRawFrame(request)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode '%v' request to apply query rewrite rules: %w",
+			request.Header.OpCode.String(), err)
+	}
+
+	rewritten := false
+	switch msg := decodedFrame.Body.Message.(type) {
+	case *message.Query:
+		keyspace := currentKeyspace
+		if msg.Options != nil && msg.Options.Keyspace != "" {
+			keyspace = msg.Options.Keyspace
+		}
+		msg.Query, rewritten = recv.rewriteQuery(msg.Query, keyspace, clusterType, logger)
+	case *message.Prepare:
+		keyspace := currentKeyspace
+		if msg.Keyspace != "" {
+			keyspace = msg.Keyspace
+		}
+		msg.Query, rewritten = recv.rewriteQuery(msg.Query, keyspace, clusterType, logger)
+	case *message.Batch:
+		keyspace := currentKeyspace
+		if msg.Keyspace != "" {
+			keyspace = msg.Keyspace
+		}
+		for _, child := range msg.Children {
+			query, ok := child.QueryOrId.(string)
+			if !ok {
+				continue
+			}
+			newQuery, childRewritten := recv.rewriteQuery(query, keyspace, clusterType, logger)
+			if childRewritten {
+				child.QueryOrId = newQuery
+				rewritten = true
+			}
+		}
+	}
+
+	if !rewritten || recv.dryRun {
+		return request, nil
+	}
+
+	newRequest, err := defaultCodec.ConvertToRawFrame(decodedFrame)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert rewritten frame to raw frame: %w", err)
+	}
+	return newRequest, nil
+}
+
+// rewriteQuery applies the rules to a single statement and returns the new query string and whether it changed.
+func (recv *queryRewriter) rewriteQuery(
+	query string, keyspace string, clusterType common.ClusterType, logger *log.Entry) (string, bool) {
+
+	var queryInfo QueryInfo
+	newQuery := query
+	var appliedRules []string
+	for _, rule := range recv.rules {
+		if (clusterType == common.ClusterTypeOrigin && !rule.ApplyToOrigin) ||
+			(clusterType == common.ClusterTypeTarget && !rule.ApplyToTarget) {
+			continue
+		}
+		if len(rule.StatementTypes) > 0 || rule.Keyspace != "" || rule.Table != "" {
+			if queryInfo == nil {
+				queryInfo = inspectCqlQuery(query, keyspace, recv.timeUuidGenerator, logger)
+			}
+			if !queryRewriteRuleMatches(rule, queryInfo) {
+				continue
+			}
+		}
+
+		replacedQuery := rule.MatchRegexp.ReplaceAllString(newQuery, rule.Replace)
+		if replacedQuery != newQuery {
+			newQuery = replacedQuery
+			appliedRules = append(appliedRules, rule.Name)
+		}
+	}
+
+	if len(appliedRules) == 0 {
+		return query, false
+	}
+	if recv.dryRun {
+		logger.Infof("Query rewrite dry run: rules %v would rewrite query for %v from '%v' to '%v'.",
+			appliedRules, clusterType, redactedStatement(query), redactedStatement(newQuery))
+	} else {
+		logger.Debugf("Rules %v rewrote query for %v from '%v' to '%v'.",
+			appliedRules, clusterType, redactedStatement(query), redactedStatement(newQuery))
+	}
+	return newQuery, true
+}
+
+func queryRewriteRuleMatches(rule *common.QueryRewriteRule, queryInfo QueryInfo) bool {
+	if len(rule.StatementTypes) > 0 {
+		statementType := strings.ToUpper(string(queryInfo.getStatementType()))
+		matches := false
+		for _, ruleStatementType := range rule.StatementTypes {
+			if ruleStatementType == statementType {
+				matches = true
+				break
+			}
+		}
+		if !matches {
+			return false
+		}
+	}
+	if rule.Keyspace != "" && rule.Keyspace != queryInfo.getApplicableKeyspace() {
+		return false
+	}
+	if rule.Table != "" && rule.Table != queryInfo.getTableName() {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+)
+
+func newTestQueryRewriter(t *testing.T, dryRun bool) *queryRewriter {
+	timeUuidGenerator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+	return newQueryRewriter([]*common.QueryRewriteRule{
+		{
+			Name:          "rename_ks",
+			Keyspace:      "ks_old",
+			MatchRegexp:   regexp.MustCompile(`\bks_old\.`),
+			Replace:       "ks_new.",
+			ApplyToTarget: true,
+		},
+		{
+			Name:           "strip_compact_storage",
+			StatementTypes: []string{"OTHER"},
+			MatchRegexp:    regexp.MustCompile(`(?i)\s+WITH COMPACT STORAGE`),
+			Replace:        "",
+			ApplyToOrigin:  true,
+			ApplyToTarget:  true,
+		},
+	}, dryRun, timeUuidGenerator)
+}
+
+func TestQueryRewriter_RewriteRequest(t *testing.T) {
+	rewriter := newTestQueryRewriter(t, false)
+	logger := log.WithFields(log.Fields{})
+
+	getQuery := func(request *frame.RawFrame) interface{} {
+		decodedFrame, err := defaultCodec.ConvertFromRawFrame(request)
+		require.Nil(t, err)
+		switch msg := decodedFrame.Body.Message.(type) {
+		case *message.Query:
+			return msg.Query
+		case *message.Prepare:
+			return msg.Query
+		case *message.Batch:
+			queries := make([]interface{}, 0, len(msg.Children))
+			for _, child := range msg.Children {
+				queries = append(queries, child.QueryOrId)
+			}
+			return queries
+		default:
+			return nil
+		}
+	}
+
+	tests := []struct {
+		name            string
+		request         *frame.RawFrame
+		currentKeyspace string
+		expectedOrigin  interface{}
+		expectedTarget  interface{}
+	}{
+		{"query", mockQueryFrame(t, "SELECT * FROM ks_old.tb"), "",
+			"SELECT * FROM ks_old.tb", "SELECT * FROM ks_new.tb"},
+		{"other keyspace", mockQueryFrame(t, "SELECT * FROM ks_other.tb WHERE a = 'ks_old.'"), "",
+			"SELECT * FROM ks_other.tb WHERE a = 'ks_old.'", "SELECT * FROM ks_other.tb WHERE a = 'ks_old.'"},
+		{"prepare", mockFrame(t, &message.Prepare{Query: "INSERT INTO ks_old.tb (a) VALUES (?)"}, primitive.ProtocolVersion4), "",
+			"INSERT INTO ks_old.tb (a) VALUES (?)", "INSERT INTO ks_new.tb (a) VALUES (?)"},
+		{"batch", mockBatchWithChildren(t, []*message.BatchChild{
+			{QueryOrId: "INSERT INTO ks_old.tb (a) VALUES (1)"},
+			{QueryOrId: []byte("prepared_id")},
+			{QueryOrId: "INSERT INTO ks_other.tb (a) VALUES (1)"},
+		}), "",
+			[]interface{}{"INSERT INTO ks_old.tb (a) VALUES (1)", []byte("prepared_id"), "INSERT INTO ks_other.tb (a) VALUES (1)"},
+			[]interface{}{"INSERT INTO ks_new.tb (a) VALUES (1)", []byte("prepared_id"), "INSERT INTO ks_other.tb (a) VALUES (1)"}},
+		{"statement type", mockQueryFrame(t, "CREATE TABLE ks_other.tb (a int PRIMARY KEY) WITH COMPACT STORAGE"), "",
+			"CREATE TABLE ks_other.tb (a int PRIMARY KEY)", "CREATE TABLE ks_other.tb (a int PRIMARY KEY)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originRequest, err := rewriter.rewriteRequest(tt.request, common.ClusterTypeOrigin, tt.currentKeyspace, logger)
+			require.Nil(t, err)
+			require.Equal(t, tt.expectedOrigin, getQuery(originRequest))
+			targetRequest, err := rewriter.rewriteRequest(tt.request, common.ClusterTypeTarget, tt.currentKeyspace, logger)
+			require.Nil(t, err)
+			require.Equal(t, tt.expectedTarget, getQuery(targetRequest))
+			require.Equal(t, tt.request.Header.StreamId, targetRequest.Header.StreamId)
+		})
+	}
+
+	executeRequest := mockExecuteFrame(t, "prepared_id")
+	newRequest, err := rewriter.rewriteRequest(executeRequest, common.ClusterTypeTarget, "ks_old", logger)
+	require.Nil(t, err)
+	require.Same(t, executeRequest, newRequest)
+}
+
+func TestQueryRewriter_DryRun(t *testing.T) {
+	rewriter := newTestQueryRewriter(t, true)
+	request := mockQueryFrame(t, "SELECT * FROM ks_old.tb")
+	newRequest, err := rewriter.rewriteRequest(request, common.ClusterTypeTarget, "", log.WithFields(log.Fields{}))
+	require.Nil(t, err)
+	require.Same(t, request, newRequest)
+}
@@ -0,0 +1,64 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// readResponseTracker tracks the number of rows and the size of the responses that a cluster returns to reads and
+// logs a warning for the responses that are larger than ZDM_PROXY_LARGE_RESPONSE_WARNING_THRESHOLD_BYTES.
+type readResponseTracker struct {
+	cluster               string
+	rows                  metrics.Histogram
+	bytes                 metrics.Histogram
+	warningThresholdBytes int
+}
+
+func newReadResponseTracker(
+	cluster string, rows metrics.Histogram, bytes metrics.Histogram, warningThresholdBytes int) *readResponseTracker {
+	return &readResponseTracker{
+		cluster:               cluster,
+		rows:                  rows,
+		bytes:                 bytes,
+		warningThresholdBytes: warningThresholdBytes,
+	}
+}
+
+// isTrackedRead returns true for the requests whose responses are tracked, writes (including lightweight
+// transactions, which also return rows) are not.
+func isTrackedRead(requestInfo RequestInfo) bool {
+	return requestInfo.ShouldBeTrackedInMetrics() && isRead(requestInfo)
+}
+
+func (recv *readResponseTracker) track(
+	response *frame.RawFrame, result *message.RowsResult, requestInfo RequestInfo, logger *log.Entry) {
+	size := len(response.Body)
+	recv.rows.Observe(float64(len(result.Data)))
+	recv.bytes.Observe(float64(size))
+
+	if recv.warningThresholdBytes <= 0 || size <= recv.warningThresholdBytes {
+		return
+	}
+	keyspace, table, ok := getReadResponseTable(result, requestInfo)
+	if !ok {
+		keyspace, table = "unknown", "unknown"
+	}
+	logger.Warnf("Response of %v to a read of %v.%v is larger than "+
+		"ZDM_PROXY_LARGE_RESPONSE_WARNING_THRESHOLD_BYTES (%d bytes): %d bytes, %d rows.",
+		recv.cluster, keyspace, table, recv.warningThresholdBytes, size, len(result.Data))
+}
+
+// getReadResponseTable returns the table of the result metadata or, if the response doesn't have result metadata (e.g.
+// because the client asked to skip it), the table of the prepared statement.
+func getReadResponseTable(result *message.RowsResult, requestInfo RequestInfo) (string, string, bool) {
+	if result.Metadata != nil && len(result.Metadata.Columns) > 0 && result.Metadata.Columns[0].Table != "" {
+		column := result.Metadata.Columns[0]
+		return column.Keyspace, column.Table, true
+	}
+	if executeRequestInfo, ok := unwrapRequestInfo(requestInfo).(*ExecuteRequestInfo); ok {
+		return getPreparedStatementTable(executeRequestInfo.GetPreparedData())
+	}
+	return "", "", false
+}
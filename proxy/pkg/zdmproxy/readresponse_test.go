@@ -0,0 +1,101 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type fakeHistogram struct {
+	values []float64
+}
+
+func (recv *fakeHistogram) Track(begin time.Time) {}
+
+func (recv *fakeHistogram) Observe(value float64) {
+	recv.values = append(recv.values, value)
+}
+
+func TestIsTrackedRead(t *testing.T) {
+	require.True(t, isTrackedRead(NewGenericRequestInfo(forwardToOrigin, false, true)))
+	require.True(t, isTrackedRead(NewGenericRequestInfo(forwardToTarget, true, true)))
+	require.False(t, isTrackedRead(NewGenericRequestInfo(forwardToBoth, false, true)))
+	require.False(t, isTrackedRead(NewGenericRequestInfo(forwardToOrigin, false, false)))
+	require.False(t, isTrackedRead(NewConditionalRequestInfo(forwardToBoth)))
+	require.False(t, isTrackedRead(NewConditionalRequestInfo(forwardToOrigin)))
+	require.False(t, isTrackedRead(NewCounterWriteRequestInfo(forwardToTarget)))
+	require.False(t, isTrackedRead(NewWriteRequestInfo(forwardToTarget)))
+	require.False(t, isTrackedRead(NewSchemaChangeRequestInfo(forwardToOrigin, true)))
+	require.False(t, isTrackedRead(&originDecommissionedRequestInfo{NewWriteRequestInfo(forwardToBoth)}))
+}
+
+func TestReadResponseTracker(t *testing.T) {
+	output := &bytes.Buffer{}
+	logger := log.New()
+	logger.SetOutput(output)
+	entry := log.NewEntry(logger)
+
+	rows, size := &fakeHistogram{}, &fakeHistogram{}
+	tracker := newReadResponseTracker("ORIGIN", rows, size, 100)
+	requestInfo := NewGenericRequestInfo(forwardToOrigin, false, true)
+	result := &message.RowsResult{
+		Metadata: &message.RowsMetadata{
+			ColumnCount: 1,
+			Columns:     []*message.ColumnMetadata{{Keyspace: "ks", Table: "tb", Name: "c", Type: datatype.Varchar}},
+		},
+		Data: message.RowSet{{[]byte("a")}, {[]byte("b")}},
+	}
+
+	tracker.track(&frame.RawFrame{Body: make([]byte, 100)}, result, requestInfo, entry)
+	require.Equal(t, []float64{2}, rows.values)
+	require.Equal(t, []float64{100}, size.values)
+	require.Empty(t, output.String())
+
+	tracker.track(&frame.RawFrame{Body: make([]byte, 101)}, result, requestInfo, entry)
+	require.Equal(t, []float64{2, 2}, rows.values)
+	require.Equal(t, []float64{100, 101}, size.values)
+	require.Contains(t, output.String(), "level=warning")
+	require.Contains(t, output.String(), "Response of ORIGIN to a read of ks.tb is larger than")
+	require.Contains(t, output.String(), "101 bytes, 2 rows")
+
+	output.Reset()
+	disabled := newReadResponseTracker("ORIGIN", rows, size, 0)
+	disabled.track(&frame.RawFrame{Body: make([]byte, 1000)}, result, requestInfo, entry)
+	require.Empty(t, output.String())
+}
+
+func TestGetReadResponseTable(t *testing.T) {
+	preparedData := &preparedDataImpl{
+		prepareRequestInfo: NewPrepareRequestInfo(NewGenericRequestInfo(forwardToOrigin, false, true), nil, false, "", ""),
+		originVariablesMetadata: &message.VariablesMetadata{
+			Columns: []*message.ColumnMetadata{{Keyspace: "ks", Table: "prepared", Name: "k", Type: datatype.Int}},
+		},
+	}
+	executeRequestInfo := NewExecuteRequestInfo(preparedData)
+
+	keyspace, table, ok := getReadResponseTable(&message.RowsResult{
+		Metadata: &message.RowsMetadata{
+			ColumnCount: 1,
+			Columns:     []*message.ColumnMetadata{{Keyspace: "ks", Table: "tb", Name: "c", Type: datatype.Varchar}},
+		},
+	}, executeRequestInfo)
+	require.True(t, ok)
+	require.Equal(t, "ks", keyspace)
+	require.Equal(t, "tb", table)
+
+	// the client skipped the result metadata
+	keyspace, table, ok = getReadResponseTable(
+		&message.RowsResult{Metadata: &message.RowsMetadata{ColumnCount: 1}}, executeRequestInfo)
+	require.True(t, ok)
+	require.Equal(t, "ks", keyspace)
+	require.Equal(t, "prepared", table)
+
+	_, _, ok = getReadResponseTable(
+		&message.RowsResult{Metadata: &message.RowsMetadata{ColumnCount: 1}}, NewGenericRequestInfo(forwardToOrigin, false, true))
+	require.False(t, ok)
+}
@@ -0,0 +1,260 @@
+package zdmproxy
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+const redactedValue = "***"
+
+var uuidLiteralRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+
+// logRedactor masks the literals and bound values of the statements written to the logs so that they can be shipped
+// to a centralized logging system without leaking the data of the application.
+type logRedactor struct {
+	mode    common.LogRedactionMode
+	hashKey []byte
+}
+
+// the redactor is process wide, like the log level, because statements are also logged by code that doesn't have
+// access to the proxy (e.g. the CQL parser)
+var activeLogRedactor atomic.Value
+
+func init() {
+	activeLogRedactor.Store(&logRedactor{mode: common.LogRedactionModeNone})
+}
+
+// newLogRedactor returns a redactor for mode. Hashes are HMAC-SHA256 so that values with low entropy can't be
+// recovered without the key, a random key is generated if hashKey is empty which means that hashes can only be
+// correlated between the log lines of the same process.
+func newLogRedactor(mode common.LogRedactionMode, hashKey string) (*logRedactor, error) {
+	key := []byte(hashKey)
+	if mode == common.LogRedactionModeHash && len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("could not generate log redaction hash key: %w", err)
+		}
+	}
+	return &logRedactor{mode: mode, hashKey: key}, nil
+}
+
+func setLogRedactor(redactor *logRedactor) {
+	activeLogRedactor.Store(redactor)
+}
+
+func getLogRedactor() *logRedactor {
+	return activeLogRedactor.Load().(*logRedactor)
+}
+
+func (recv *logRedactor) isEnabled() bool {
+	return recv.mode != common.LogRedactionModeNone
+}
+
+func (recv *logRedactor) mask(value []byte) string {
+	switch recv.mode {
+	case common.LogRedactionModeHash:
+		mac := hmac.New(sha256.New, recv.hashKey)
+		mac.Write(value)
+		return "#" + hex.EncodeToString(mac.Sum(nil)[:8])
+	case common.LogRedactionModeLength:
+		return fmt.Sprintf("<%d bytes>", len(value))
+	default:
+		return redactedValue
+	}
+}
+
+// redactStatement replaces the literals of a CQL statement (strings, numbers, blobs, durations and UUIDs) with their
+// masked value. Identifiers, keywords, comments and bind markers are kept so that the statement is still useful.
+func (recv *logRedactor) redactStatement(query string) string {
+	if !recv.isEnabled() {
+		return query
+	}
+	var sb strings.Builder
+	sb.Grow(len(query))
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'':
+			end := scanQuoted(query, i, '\'')
+			sb.WriteString(recv.mask([]byte(strings.ReplaceAll(query[i+1:end-1], "''", "'"))))
+			i = end
+		case c == '"':
+			end := scanQuoted(query, i, '"')
+			sb.WriteString(query[i:end])
+			i = end
+		case strings.HasPrefix(query[i:], "$$"):
+			end := strings.Index(query[i+2:], "$$")
+			if end < 0 {
+				end = len(query)
+			} else {
+				end += i + 4
+			}
+			sb.WriteString(recv.mask([]byte(strings.TrimSuffix(query[i+2:end], "$$"))))
+			i = end
+		case strings.HasPrefix(query[i:], "--") || strings.HasPrefix(query[i:], "//"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query)
+			} else {
+				end += i
+			}
+			sb.WriteString(query[i:end])
+			i = end
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query)
+			} else {
+				end += i + 4
+			}
+			sb.WriteString(query[i:end])
+			i = end
+		case isIdentifierChar(c):
+			if uuid := uuidLiteralRegex.FindString(query[i:]); uuid != "" {
+				sb.WriteString(recv.mask([]byte(uuid)))
+				i += len(uuid)
+				continue
+			}
+			end := i
+			for end < len(query) && isIdentifierChar(query[end]) {
+				end++
+			}
+			if c >= '0' && c <= '9' {
+				// numbers with a fraction or an exponent, e.g. 1.5e-3
+				for end < len(query) && (query[end] == '.' || isIdentifierChar(query[end]) ||
+					((query[end] == '-' || query[end] == '+') && (query[end-1] == 'e' || query[end-1] == 'E'))) {
+					end++
+				}
+				sb.WriteString(recv.mask([]byte(query[i:end])))
+			} else {
+				sb.WriteString(query[i:end])
+			}
+			i = end
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return sb.String()
+}
+
+// redactValue returns the masked representation of a bound value, null and unset values are not masked.
+func (recv *logRedactor) redactValue(value *primitive.Value) string {
+	if value == nil {
+		return "<nil>"
+	}
+	switch value.Type {
+	case primitive.ValueTypeNull:
+		return "NULL"
+	case primitive.ValueTypeUnset:
+		return "UNSET"
+	default:
+		return recv.mask(value.Contents)
+	}
+}
+
+func (recv *logRedactor) redactQueryOptions(options *message.QueryOptions) string {
+	if options == nil {
+		return "[]"
+	}
+	values := make([]string, 0, len(options.PositionalValues)+len(options.NamedValues))
+	for _, value := range options.PositionalValues {
+		values = append(values, recv.redactValue(value))
+	}
+	for name, value := range options.NamedValues {
+		values = append(values, name+"="+recv.redactValue(value))
+	}
+	return "[" + strings.Join(values, ", ") + "]"
+}
+
+// scanQuoted returns the index after the closing quote of the string or quoted identifier that starts at start,
+// doubled quotes are escaped quotes.
+func scanQuoted(query string, start int, quote byte) int {
+	for i := start + 1; i < len(query); i++ {
+		if query[i] == quote {
+			if i+1 < len(query) && query[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(query)
+}
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// redactedStatement formats a statement with the active redactor, it implements fmt.Stringer so that the statement is
+// only redacted if the log level is enabled.
+type redactedStatement string
+
+func (recv redactedStatement) String() string {
+	return getLogRedactor().redactStatement(string(recv))
+}
+
+// redactedFrame formats a decoded frame with the active redactor, the statements and bound values of QUERY, PREPARE,
+// EXECUTE and BATCH messages are masked.
+type redactedFrame struct {
+	frame *frame.Frame
+}
+
+func (recv redactedFrame) String() string {
+	redactor := getLogRedactor()
+	if !redactor.isEnabled() || recv.frame == nil {
+		return fmt.Sprintf("%v", recv.frame)
+	}
+	var msg string
+	switch typedMsg := recv.frame.Body.Message.(type) {
+	case *message.Query:
+		msg = fmt.Sprintf("QUERY %v %v", redactor.redactStatement(typedMsg.Query), redactor.redactQueryOptions(typedMsg.Options))
+	case *message.Prepare:
+		msg = fmt.Sprintf("PREPARE (%v, %v)", redactor.redactStatement(typedMsg.Query), typedMsg.Keyspace)
+	case *message.Execute:
+		msg = fmt.Sprintf("%v %v", typedMsg, redactor.redactQueryOptions(typedMsg.Options))
+	case *message.Batch:
+		children := make([]string, 0, len(typedMsg.Children))
+		for _, child := range typedMsg.Children {
+			var statement string
+			if query, ok := child.QueryOrId.(string); ok {
+				statement = redactor.redactStatement(query)
+			} else {
+				statement = fmt.Sprintf("%x", child.QueryOrId)
+			}
+			values := make([]string, 0, len(child.Values))
+			for _, value := range child.Values {
+				values = append(values, redactor.redactValue(value))
+			}
+			children = append(children, fmt.Sprintf("%v [%v]", statement, strings.Join(values, ", ")))
+		}
+		msg = fmt.Sprintf("%v {%v}", typedMsg, strings.Join(children, "; "))
+	default:
+		msg = fmt.Sprintf("%v", typedMsg)
+	}
+	return fmt.Sprintf("{header: %v, body: {tracing id: %v, payload: <%d entries>, warnings: %v, message: %v}}",
+		recv.frame.Header, recv.frame.Body.TracingId, len(recv.frame.Body.CustomPayload), recv.frame.Body.Warnings, msg)
+}
+
+// redactedRawFrame formats a raw frame without its body when redaction is enabled, the body can contain statements
+// and bound values.
+type redactedRawFrame struct {
+	frame *frame.RawFrame
+}
+
+func (recv redactedRawFrame) String() string {
+	if !getLogRedactor().isEnabled() || recv.frame == nil {
+		return fmt.Sprintf("%v", recv.frame)
+	}
+	return fmt.Sprintf("{header: %v, body: <%d bytes>}", recv.frame.Header, len(recv.frame.Body))
+}
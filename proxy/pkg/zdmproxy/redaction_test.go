@@ -0,0 +1,95 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLogRedactor_RedactStatement(t *testing.T) {
+	redactor, err := newLogRedactor(common.LogRedactionModeFull, "")
+	require.Nil(t, err)
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{"no literals", "SELECT * FROM ks.t WHERE k = ? AND c = :c", "SELECT * FROM ks.t WHERE k = ? AND c = :c"},
+		{"string", "INSERT INTO t (k, v) VALUES ('john''s', 'x')", "INSERT INTO t (k, v) VALUES (***, ***)"},
+		{"numbers", "UPDATE t SET a = -12, b = 1.5e-3, c = 0xCAFE WHERE k = 3", "UPDATE t SET a = -***, b = ***, c = *** WHERE k = ***"},
+		{"uuids", "SELECT * FROM t WHERE id IN (123e4567-e89b-12d3-a456-426614174000, dd5bab90-4a13-11ec-81d3-0242ac130003)",
+			"SELECT * FROM t WHERE id IN (***, ***)"},
+		{"duration", "SELECT * FROM t USING TIMEOUT 10ms", "SELECT * FROM t USING TIMEOUT ***"},
+		{"pg string", "INSERT INTO t (k) VALUES ($$secret$$)", "INSERT INTO t (k) VALUES (***)"},
+		{"quoted identifiers and comments", `SELECT "v'1" FROM t2 /* 'x' */ WHERE k = 'y' -- 'z'`,
+			`SELECT "v'1" FROM t2 /* 'x' */ WHERE k = *** -- 'z'`},
+		{"unterminated string", "SELECT * FROM t WHERE k = 'abc", "SELECT * FROM t WHERE k = ***"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, redactor.redactStatement(tt.query))
+		})
+	}
+}
+
+func TestLogRedactor_Modes(t *testing.T) {
+	query := "SELECT * FROM t WHERE k = 'abc' AND c = 'abc'"
+
+	none, err := newLogRedactor(common.LogRedactionModeNone, "")
+	require.Nil(t, err)
+	require.Equal(t, query, none.redactStatement(query))
+
+	length, err := newLogRedactor(common.LogRedactionModeLength, "")
+	require.Nil(t, err)
+	require.Equal(t, "SELECT * FROM t WHERE k = <3 bytes> AND c = <3 bytes>", length.redactStatement(query))
+	require.Equal(t, "<4 bytes>", length.redactValue(primitive.NewValue([]byte{0, 0, 0, 1})))
+	require.Equal(t, "NULL", length.redactValue(primitive.NewNullValue()))
+
+	hash, err := newLogRedactor(common.LogRedactionModeHash, "key")
+	require.Nil(t, err)
+	redacted := hash.redactStatement(query)
+	require.NotContains(t, redacted, "abc")
+	hashes := strings.Split(strings.TrimPrefix(redacted, "SELECT * FROM t WHERE k = "), " AND c = ")
+	require.Equal(t, 2, len(hashes))
+	require.Equal(t, hashes[0], hashes[1], "the same value has the same hash")
+	require.Equal(t, 17, len(hashes[0]))
+	require.Equal(t, hashes[0], hash.redactValue(primitive.NewValue([]byte("abc"))))
+
+	otherKey, err := newLogRedactor(common.LogRedactionModeHash, "other")
+	require.Nil(t, err)
+	require.NotEqual(t, hashes[0], otherKey.redactValue(primitive.NewValue([]byte("abc"))))
+}
+
+func TestRedactedFrame(t *testing.T) {
+	defer setLogRedactor(getLogRedactor())
+	query := frame.NewFrame(primitive.ProtocolVersion4, 1, &message.Query{
+		Query: "INSERT INTO t (k, v) VALUES ('secret', ?)",
+		Options: &message.QueryOptions{
+			PositionalValues: []*primitive.Value{primitive.NewValue([]byte("bound"))},
+		},
+	})
+	rawQuery, err := defaultCodec.ConvertToRawFrame(query)
+	require.Nil(t, err)
+
+	redactor, err := newLogRedactor(common.LogRedactionModeFull, "")
+	require.Nil(t, err)
+	setLogRedactor(redactor)
+	formatted := redactedFrame{query}.String()
+	require.Contains(t, formatted, "message: QUERY INSERT INTO t (k, v) VALUES (***, ?) [***]")
+	require.NotContains(t, formatted, "secret")
+	require.NotContains(t, formatted, "bound")
+	formatted = redactedRawFrame{rawQuery}.String()
+	require.True(t, strings.HasSuffix(formatted, "body: <"+strconv.Itoa(len(rawQuery.Body))+" bytes>}"), formatted)
+
+	redactor, err = newLogRedactor(common.LogRedactionModeNone, "")
+	require.Nil(t, err)
+	setLogRedactor(redactor)
+	require.Equal(t, query.String(), redactedFrame{query}.String())
+	require.Equal(t, rawQuery.String(), redactedRawFrame{rawQuery}.String())
+}
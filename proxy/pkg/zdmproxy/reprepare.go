@@ -0,0 +1,144 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"encoding/hex"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+)
+
+// handleRePrepare transparently re-prepares EXECUTE requests that failed with UNPREPARED on ORIGIN or TARGET.
+//
+// When a cluster returns UNPREPARED for a statement that is in the prepared statement cache, the statement is prepared
+// again on that cluster and the EXECUTE is retried once. The UNPREPARED response is only returned to the client
+// if the re-preparation fails.
+//
+// Returns the response that should be set on the request context or nil if the response was consumed
+// (i.e. a PREPARE or the retried EXECUTE is now in flight).
+func (ch *ClientHandler) handleRePrepare(
+	reqCtx *requestContextImpl, response *frame.RawFrame, clusterType common.ClusterType) *frame.RawFrame {
+
+	if !ch.conf.ReprepareOnUnprepared {
+		return response
+	}
+
+	executeRequestInfo, ok := unwrapRequestInfo(reqCtx.GetRequestInfo()).(*ExecuteRequestInfo)
+	if !ok {
+		return response
+	}
+
+	var connector *ClusterConnector
+	var expectedPreparedId []byte
+	var executeRequest *frame.RawFrame
+	preparedData := executeRequestInfo.GetPreparedData()
+	switch clusterType {
+	case common.ClusterTypeOrigin:
+		connector = ch.originCassandraConnector
+		expectedPreparedId = preparedData.GetOriginPreparedId()
+		executeRequest = reqCtx.originRequest
+	case common.ClusterTypeTarget:
+		connector = ch.targetCassandraConnector
+		expectedPreparedId = preparedData.GetTargetPreparedId()
+		executeRequest = reqCtx.targetRequest
+	default:
+		return response
+	}
+
+	switch reqCtx.GetRePrepareState(clusterType) {
+	case RePrepareNone:
+		if response.Header.OpCode != primitive.OpCodeError || executeRequest == nil {
+			return response
+		}
+		errMsg, err := decodeError(response)
+		if err != nil {
+			reqCtx.logger.Warnf("Could not decode error from %v while checking for UNPREPARED: %v", clusterType, err)
+			return response
+		}
+		if _, ok = errMsg.(*message.Unprepared); !ok {
+			return response
+		}
+
+		prepareRequestInfo := preparedData.GetPrepareRequestInfo()
+		prepareFrame := frame.NewFrame(response.Header.Version, response.Header.StreamId, &message.Prepare{
+			Query:    prepareRequestInfo.GetQuery(),
+			Keyspace: prepareRequestInfo.GetKeyspace(),
+		})
+		prepareRawFrame, err := defaultCodec.ConvertToRawFrame(prepareFrame)
+		if err != nil {
+			reqCtx.logger.Errorf("Could not re-prepare statement on %v because convert raw frame failed: %v", clusterType, err)
+			return response
+		}
+		if ch.queryRewriter != nil {
+			prepareRawFrame, err = ch.queryRewriter.rewriteRequest(prepareRawFrame, clusterType, reqCtx.keyspace, reqCtx.logger)
+			if err != nil {
+				reqCtx.logger.Errorf("Could not re-prepare statement on %v: %v", clusterType, err)
+				return response
+			}
+		}
+		if clusterType == common.ClusterTypeTarget && ch.targetNameMapper != nil {
+			prepareRawFrame, err = ch.targetNameMapper.mapRequest(prepareRawFrame, reqCtx.keyspace, reqCtx.logger)
+			if err != nil {
+				reqCtx.logger.Errorf("Could not re-prepare statement on %v: %v", clusterType, err)
+				return response
+			}
+		}
+
+		if !reqCtx.StartRePrepare(clusterType, response) {
+			return response
+		}
+
+		getRePrepareCounter(ch.metricHandler.GetProxyMetrics(), clusterType, false).Add(1)
+		reqCtx.logger.Debugf("Received UNPREPARED from %v for prepared ID %s, re-preparing it.",
+			clusterType, hex.EncodeToString(expectedPreparedId))
+		connector.sendRequestToCluster(prepareRawFrame)
+		return nil
+	case RePreparePending:
+		unpreparedResponse, ok := reqCtx.FinishRePrepare(clusterType)
+		if !ok {
+			return response
+		}
+
+		if response.Header.OpCode == primitive.OpCodeResult {
+			body, err := defaultCodec.DecodeBody(response.Header, bytes.NewReader(response.Body))
+			if err != nil {
+				reqCtx.logger.Warnf("Could not decode re-prepare response from %v: %v", clusterType, err)
+			} else if preparedResult, ok := body.Message.(*message.PreparedResult); !ok {
+				reqCtx.logger.Warnf("Expected PREPARED result when re-preparing statement on %v but got %v.",
+					clusterType, body.Message)
+			} else if !bytes.Equal(preparedResult.PreparedQueryId, expectedPreparedId) {
+				reqCtx.logger.Warnf("Re-prepared statement on %v has a different prepared ID (%s) than the cached one (%s).",
+					clusterType, hex.EncodeToString(preparedResult.PreparedQueryId), hex.EncodeToString(expectedPreparedId))
+			} else {
+				reqCtx.logger.Debugf("Re-prepared statement with prepared ID %s on %v, retrying EXECUTE.",
+					hex.EncodeToString(expectedPreparedId), clusterType)
+				connector.sendRequestToCluster(executeRequest)
+				return nil
+			}
+		} else {
+			reqCtx.logger.Warnf("Could not re-prepare statement with prepared ID %s on %v, returning UNPREPARED. Response: %v",
+				hex.EncodeToString(expectedPreparedId), clusterType, response.Header.OpCode.String())
+		}
+
+		getRePrepareCounter(ch.metricHandler.GetProxyMetrics(), clusterType, true).Add(1)
+		return unpreparedResponse
+	default:
+		return response
+	}
+}
+
+func getRePrepareCounter(proxyMetrics *metrics.ProxyMetrics, clusterType common.ClusterType, failed bool) metrics.Counter {
+	if clusterType == common.ClusterTypeTarget {
+		if failed {
+			return proxyMetrics.PSCacheRePrepareFailedTarget
+		}
+		return proxyMetrics.PSCacheRePrepareTarget
+	}
+
+	if failed {
+		return proxyMetrics.PSCacheRePrepareFailedOrigin
+	}
+	return proxyMetrics.PSCacheRePrepareOrigin
+}
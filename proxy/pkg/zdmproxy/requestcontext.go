@@ -89,9 +89,17 @@ type RequestContext interface {
 	GetRequestInfo() RequestInfo
 }
 
+const (
+	RePrepareNone = iota
+	RePreparePending
+	RePrepareDone
+)
+
 type requestContextImpl struct {
 	request               *frame.RawFrame
 	requestInfo           RequestInfo
+	originRequest         *frame.RawFrame
+	targetRequest         *frame.RawFrame
 	originResponse        *frame.RawFrame
 	targetResponse        *frame.RawFrame
 	state                 int
@@ -99,12 +107,47 @@ type requestContextImpl struct {
 	lock                  *sync.Mutex
 	startTime             time.Time
 	customResponseChannel chan *customResponse
+
+	// keyspace of the connection when the request was received
+	keyspace string
+
+	// state of the automatic re-preparation of EXECUTE requests for each cluster, see ClientHandler.handleRePrepare
+	originRePrepareState int
+	targetRePrepareState int
+	originUnprepared     *frame.RawFrame
+	targetUnprepared     *frame.RawFrame
+
+	// number of retries of each cluster, see ClientHandler.handleRetry
+	originRetries int
+	targetRetries int
+
+	// cluster to which a speculative read was sent, see ClientHandler.scheduleSpeculativeRead. It is the cluster of the
+	// read if the speculative read was sent to another host of that cluster, speculativeWon is then set if the response
+	// of the speculative read is the one that is returned to the client.
+	speculativeCluster common.ClusterType
+	speculativeWon     bool
+
+	// pages received for a DSE continuous paging request, nil for other requests
+	continuousPaging *continuousPagingState
+
+	// time at which the deadline set by the client elapses (zero if it didn't set one) and the timeout that the
+	// request would have had without it, see ClientHandler.setRequestDeadline
+	deadline       time.Time
+	requestTimeout time.Duration
+
+	// logger with the fields of the client connection and the request_id of this request
+	logger *log.Entry
 }
 
-func NewRequestContext(req *frame.RawFrame, requestInfo RequestInfo, startTime time.Time, customResponseChannel chan *customResponse) *requestContextImpl {
+func NewRequestContext(
+	req *frame.RawFrame, originRequest *frame.RawFrame, targetRequest *frame.RawFrame, requestInfo RequestInfo,
+	keyspace string, startTime time.Time, customResponseChannel chan *customResponse,
+	logger *log.Entry) *requestContextImpl {
 	return &requestContextImpl{
 		request:               req,
 		requestInfo:           requestInfo,
+		originRequest:         originRequest,
+		targetRequest:         targetRequest,
 		originResponse:        nil,
 		targetResponse:        nil,
 		state:                 RequestPending,
@@ -112,6 +155,11 @@ func NewRequestContext(req *frame.RawFrame, requestInfo RequestInfo, startTime t
 		lock:                  &sync.Mutex{},
 		startTime:             startTime,
 		customResponseChannel: customResponseChannel,
+		keyspace:              keyspace,
+		originRePrepareState:  RePrepareNone,
+		targetRePrepareState:  RePrepareNone,
+		speculativeCluster:    common.ClusterTypeNone,
+		logger:                logger,
 	}
 }
 
@@ -123,6 +171,16 @@ func (recv *requestContextImpl) SetTimer(timer *time.Timer) {
 	recv.timer = timer
 }
 
+// ResetTimer restarts the request timeout if the request is still pending.
+func (recv *requestContextImpl) ResetTimer(timeout time.Duration) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	if recv.state == RequestPending && recv.timer != nil {
+		recv.timer.Reset(timeout)
+	}
+}
+
 func (recv *requestContextImpl) SetTimeout(nodeMetrics *metrics.NodeMetrics, req *frame.RawFrame) bool {
 	recv.lock.Lock()
 	defer recv.lock.Unlock()
@@ -188,7 +246,7 @@ func (recv *requestContextImpl) SetResponse(nodeMetrics *metrics.NodeMetrics, f
 		recv.timer.Stop() // if timer is not stopped, there's a memory leak because the timer callback holds references!
 	}
 
-	log.Tracef("Received response from %v for query with stream id %d", connectorType, f.Header.StreamId)
+	recv.logger.Tracef("Received response from %v for query with stream id %d", connectorType, f.Header.StreamId)
 
 	if recv.GetRequestInfo().ShouldBeTrackedInMetrics() {
 		switch connectorType {
@@ -198,13 +256,165 @@ func (recv *requestContextImpl) SetResponse(nodeMetrics *metrics.NodeMetrics, f
 			nodeMetrics.TargetMetrics.RequestDuration.Track(recv.startTime)
 		case ClusterConnectorTypeAsync:
 		default:
-			log.Errorf("could not recognize connector type %v", connectorType)
+			recv.logger.Errorf("could not recognize connector type %v", connectorType)
 		}
 	}
 
 	return finished
 }
 
+// GetRePrepareState returns the re-prepare state of the provided cluster.
+func (recv *requestContextImpl) GetRePrepareState(cluster common.ClusterType) int {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	if cluster == common.ClusterTypeTarget {
+		return recv.targetRePrepareState
+	}
+	return recv.originRePrepareState
+}
+
+// StartRePrepare marks the provided cluster as re-preparing and stores the UNPREPARED response so that it can be
+// returned if the re-preparation fails.
+// Returns false if the request is not pending anymore or if a re-prepare was already attempted for this cluster.
+func (recv *requestContextImpl) StartRePrepare(cluster common.ClusterType, unprepared *frame.RawFrame) bool {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	if recv.state != RequestPending {
+		return false
+	}
+
+	switch cluster {
+	case common.ClusterTypeOrigin:
+		if recv.originRePrepareState != RePrepareNone {
+			return false
+		}
+		recv.originRePrepareState = RePreparePending
+		recv.originUnprepared = unprepared
+	case common.ClusterTypeTarget:
+		if recv.targetRePrepareState != RePrepareNone {
+			return false
+		}
+		recv.targetRePrepareState = RePreparePending
+		recv.targetUnprepared = unprepared
+	default:
+		return false
+	}
+	return true
+}
+
+// FinishRePrepare marks the re-preparation of the provided cluster as done and returns the UNPREPARED response that
+// triggered it. Returns false if the request is not pending anymore or if there wasn't a pending re-prepare.
+func (recv *requestContextImpl) FinishRePrepare(cluster common.ClusterType) (*frame.RawFrame, bool) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	if recv.state != RequestPending {
+		return nil, false
+	}
+
+	switch cluster {
+	case common.ClusterTypeOrigin:
+		if recv.originRePrepareState != RePreparePending {
+			return nil, false
+		}
+		recv.originRePrepareState = RePrepareDone
+		return recv.originUnprepared, true
+	case common.ClusterTypeTarget:
+		if recv.targetRePrepareState != RePreparePending {
+			return nil, false
+		}
+		recv.targetRePrepareState = RePrepareDone
+		return recv.targetUnprepared, true
+	default:
+		return nil, false
+	}
+}
+
+// StartRetry increments the number of retries of the provided cluster and returns it.
+// Returns false if the request is not pending anymore or if maxRetries was reached.
+func (recv *requestContextImpl) StartRetry(cluster common.ClusterType, maxRetries int) (int, bool) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	if recv.state != RequestPending {
+		return 0, false
+	}
+
+	var retries *int
+	switch cluster {
+	case common.ClusterTypeOrigin:
+		retries = &recv.originRetries
+	case common.ClusterTypeTarget:
+		retries = &recv.targetRetries
+	default:
+		return 0, false
+	}
+	if *retries >= maxRetries {
+		return 0, false
+	}
+	*retries++
+	return *retries, true
+}
+
+// IsPending returns false if the request already finished, timed out or was canceled.
+func (recv *requestContextImpl) IsPending() bool {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.state == RequestPending
+}
+
+// StartSpeculativeRead records that a speculative read is about to be sent to the provided cluster.
+// Returns false if the request is not pending anymore or if a speculative read was already sent.
+func (recv *requestContextImpl) StartSpeculativeRead(cluster common.ClusterType) bool {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	if recv.state != RequestPending || recv.speculativeCluster != common.ClusterTypeNone {
+		return false
+	}
+	recv.speculativeCluster = cluster
+	return true
+}
+
+// SetSpeculativeResponse is SetResponse for the response of a speculative read that was sent to another host of the
+// cluster of the read.
+func (recv *requestContextImpl) SetSpeculativeResponse(nodeMetrics *metrics.NodeMetrics, f *frame.RawFrame,
+	cluster common.ClusterType, connectorType ClusterConnectorType) bool {
+	if !recv.SetResponse(nodeMetrics, f, cluster, connectorType) {
+		return false
+	}
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.speculativeWon = true
+	return true
+}
+
+// isSpeculativeReadOnOtherHost returns true if the speculative read was sent to another host of the cluster of the
+// read instead of the other cluster.
+func (recv *requestContextImpl) isSpeculativeReadOnOtherHost() bool {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	switch recv.requestInfo.GetForwardDecision() {
+	case forwardToOrigin:
+		return recv.speculativeCluster == common.ClusterTypeOrigin
+	case forwardToTarget:
+		return recv.speculativeCluster == common.ClusterTypeTarget
+	default:
+		return false
+	}
+}
+
+// CancelSpeculativeRead undoes StartSpeculativeRead when the speculative read could not be sent.
+func (recv *requestContextImpl) CancelSpeculativeRead() {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if recv.state == RequestPending {
+		recv.speculativeCluster = common.ClusterTypeNone
+	}
+}
+
 func (recv *requestContextImpl) updateInternalState(f *frame.RawFrame, cluster common.ClusterType) (state int, updated bool) {
 	recv.lock.Lock()
 	defer recv.lock.Unlock()
@@ -220,15 +430,17 @@ func (recv *requestContextImpl) updateInternalState(f *frame.RawFrame, cluster c
 	case common.ClusterTypeTarget:
 		recv.targetResponse = f
 	default:
-		log.Errorf("could not recognize cluster type %v", cluster)
+		recv.logger.Errorf("could not recognize cluster type %v", cluster)
 	}
 
 	done := false
 	switch recv.requestInfo.GetForwardDecision() {
 	case forwardToTarget:
-		done = recv.targetResponse != nil
+		done = recv.targetResponse != nil ||
+			(recv.speculativeCluster == common.ClusterTypeOrigin && recv.originResponse != nil)
 	case forwardToOrigin:
-		done = recv.originResponse != nil
+		done = recv.originResponse != nil ||
+			(recv.speculativeCluster == common.ClusterTypeTarget && recv.targetResponse != nil)
 	case forwardToBoth:
 		done = recv.originResponse != nil && recv.targetResponse != nil
 	case forwardToNone:
@@ -236,7 +448,7 @@ func (recv *requestContextImpl) updateInternalState(f *frame.RawFrame, cluster c
 	case forwardToAsyncOnly:
 		done = true
 	default:
-		log.Errorf("unrecognized decision %v", recv.requestInfo.GetForwardDecision())
+		recv.logger.Errorf("unrecognized decision %v", recv.requestInfo.GetForwardDecision())
 	}
 
 	if done {
@@ -254,6 +466,9 @@ type asyncRequestContextImpl struct {
 	expectedResponse bool
 	startTime        time.Time
 	requestInfo      RequestInfo
+
+	// set for speculative reads, see ClientHandler.scheduleSpeculativeRead
+	speculativeReqCtx *requestContextImpl
 }
 
 func NewAsyncRequestContext(requestInfo RequestInfo, streamId int16, expectedResponse bool, startTime time.Time) *asyncRequestContextImpl {
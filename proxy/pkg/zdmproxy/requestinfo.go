@@ -6,12 +6,25 @@ type RequestInfo interface {
 	GetForwardDecision() forwardDecision
 	ShouldAlsoBeSentAsync() bool
 	ShouldBeTrackedInMetrics() bool
+
+	// IsConditional returns true if the request is a lightweight transaction (conditional write).
+	IsConditional() bool
+
+	// IsCounterWrite returns true if the request updates a counter table.
+	IsCounterWrite() bool
+
+	// IsWrite returns true if the request is an INSERT, UPDATE, DELETE or BATCH, whatever the cluster(s) it is sent to.
+	IsWrite() bool
 }
 
 type baseRequestInfo struct {
 	forwardDecision       forwardDecision
 	shouldAlsoBeSentAsync bool
 	trackMetrics          bool
+	write                 bool
+	conditional           bool
+	counterWrite          bool
+	schemaChange          bool
 }
 
 func newBaseRequestInfo(decision forwardDecision, shouldBeSentAsync bool, trackMetrics bool) *baseRequestInfo {
@@ -30,6 +43,18 @@ func (recv *baseRequestInfo) ShouldBeTrackedInMetrics() bool {
 	return recv.trackMetrics
 }
 
+func (recv *baseRequestInfo) IsConditional() bool {
+	return recv.conditional
+}
+
+func (recv *baseRequestInfo) IsCounterWrite() bool {
+	return recv.counterWrite
+}
+
+func (recv *baseRequestInfo) IsWrite() bool {
+	return recv.write
+}
+
 type GenericRequestInfo struct {
 	*baseRequestInfo
 }
@@ -38,9 +63,40 @@ func NewGenericRequestInfo(decision forwardDecision, shouldBeSentAsync bool, tra
 	return &GenericRequestInfo{baseRequestInfo: newBaseRequestInfo(decision, shouldBeSentAsync, trackMetrics)}
 }
 
+// NewWriteRequestInfo creates the request info of an INSERT, UPDATE, DELETE or BATCH statement (QUERY or PREPARE)
+// that is neither a lightweight transaction nor a counter update.
+func NewWriteRequestInfo(decision forwardDecision) *GenericRequestInfo {
+	baseRequestInfo := newBaseRequestInfo(decision, false, true)
+	baseRequestInfo.write = true
+	return &GenericRequestInfo{baseRequestInfo: baseRequestInfo}
+}
+
+// NewConditionalRequestInfo creates the request info of a lightweight transaction (QUERY or PREPARE).
+func NewConditionalRequestInfo(decision forwardDecision) *GenericRequestInfo {
+	baseRequestInfo := newBaseRequestInfo(decision, false, true)
+	baseRequestInfo.write = true
+	baseRequestInfo.conditional = true
+	return &GenericRequestInfo{baseRequestInfo: baseRequestInfo}
+}
+
+// NewCounterWriteRequestInfo creates the request info of an UPDATE on a counter table (QUERY or PREPARE).
+func NewCounterWriteRequestInfo(decision forwardDecision) *GenericRequestInfo {
+	baseRequestInfo := newBaseRequestInfo(decision, false, true)
+	baseRequestInfo.write = true
+	baseRequestInfo.counterWrite = true
+	return &GenericRequestInfo{baseRequestInfo: baseRequestInfo}
+}
+
+// NewSchemaChangeRequestInfo creates the request info of a DDL statement (QUERY or PREPARE).
+func NewSchemaChangeRequestInfo(decision forwardDecision, trackMetrics bool) *GenericRequestInfo {
+	baseRequestInfo := newBaseRequestInfo(decision, false, trackMetrics)
+	baseRequestInfo.schemaChange = true
+	return &GenericRequestInfo{baseRequestInfo: baseRequestInfo}
+}
+
 func (recv *GenericRequestInfo) String() string {
-	return fmt.Sprintf("GenericRequestInfo{forwardDecision: %v, shouldAlsoBeSentAsync=%v, trackMetrics=%v}",
-		recv.forwardDecision, recv.shouldAlsoBeSentAsync, recv.trackMetrics)
+	return fmt.Sprintf("GenericRequestInfo{forwardDecision: %v, shouldAlsoBeSentAsync=%v, trackMetrics=%v, write=%v, conditional=%v, counterWrite=%v, schemaChange=%v}",
+		recv.forwardDecision, recv.shouldAlsoBeSentAsync, recv.trackMetrics, recv.write, recv.conditional, recv.counterWrite, recv.schemaChange)
 }
 
 type PrepareRequestInfo struct {
@@ -67,7 +123,7 @@ func NewPrepareRequestInfo(
 
 func (recv *PrepareRequestInfo) String() string {
 	return fmt.Sprintf("PrepareRequestInfo{baseRequestInfo: %v, query: %v, keyspace: %v}",
-		recv.baseRequestInfo, recv.query, recv.keyspace)
+		recv.baseRequestInfo, redactedStatement(recv.query), recv.keyspace)
 }
 
 func (recv *PrepareRequestInfo) ShouldAlsoBeSentAsync() bool {
@@ -78,6 +134,18 @@ func (recv *PrepareRequestInfo) ShouldBeTrackedInMetrics() bool {
 	return false
 }
 
+func (recv *PrepareRequestInfo) IsConditional() bool {
+	return false // the PREPARE request itself is not a conditional write, see GetBaseRequestInfo()
+}
+
+func (recv *PrepareRequestInfo) IsCounterWrite() bool {
+	return false
+}
+
+func (recv *PrepareRequestInfo) IsWrite() bool {
+	return false
+}
+
 func (recv *PrepareRequestInfo) GetQuery() string {
 	return recv.query
 }
@@ -133,6 +201,18 @@ func (recv *ExecuteRequestInfo) ShouldBeTrackedInMetrics() bool {
 	return recv.preparedData.GetPrepareRequestInfo().GetBaseRequestInfo().ShouldBeTrackedInMetrics()
 }
 
+func (recv *ExecuteRequestInfo) IsConditional() bool {
+	return recv.preparedData.GetPrepareRequestInfo().GetBaseRequestInfo().IsConditional()
+}
+
+func (recv *ExecuteRequestInfo) IsCounterWrite() bool {
+	return recv.preparedData.GetPrepareRequestInfo().GetBaseRequestInfo().IsCounterWrite()
+}
+
+func (recv *ExecuteRequestInfo) IsWrite() bool {
+	return recv.preparedData.GetPrepareRequestInfo().GetBaseRequestInfo().IsWrite()
+}
+
 // InterceptedRequestInfo on its own means that this intercepted request is a QUERY request.
 // This can also be the base request field of a PrepareRequestInfo object in which case the intercepted request will be
 // a PREPARE (or EXECUTE if it's a ExecuteRequestInfo).
@@ -163,20 +243,118 @@ func (recv *InterceptedRequestInfo) GetParsedSelectClause() *selectClause {
 	return recv.parsedSelectClause
 }
 
+// BatchRequestInfo holds the prepared data of the batch child statements that are prepared statements and the forward
+// decision of each child statement.
+//
+// A BATCH is split into an ORIGIN sub-batch and a TARGET sub-batch when its child statements are not all
+// destined to the same cluster, e.g. when some of them write to tables of ZDM_TABLE_ROUTING_RULES.
 type BatchRequestInfo struct {
-	preparedDataByStmtIdx map[int]PreparedData
+	preparedDataByStmtIdx    map[int]PreparedData
+	forwardDecisionByStmtIdx map[int]forwardDecision
+	forwardDecision          forwardDecision
+	conditional              bool
+	counterWrite             bool
+}
+
+// NewBatchRequestInfo creates the request info of a BATCH. The forward decision of a prepared child statement is the
+// one of its PREPARE request, forwardDecisionByQueryStmtIdx contains the forward decisions of the child query strings
+// that are not sent to both clusters.
+func NewBatchRequestInfo(
+	preparedDataByStmtIdx map[int]PreparedData, forwardDecisionByQueryStmtIdx map[int]forwardDecision,
+	numberOfStatements int) *BatchRequestInfo {
+	forwardDecisionByStmtIdx := make(map[int]forwardDecision, numberOfStatements)
+	originStmts := 0
+	targetStmts := 0
+	for stmtIdx := 0; stmtIdx < numberOfStatements; stmtIdx++ {
+		stmtForwardDecision := forwardToBoth
+		if decision, ok := forwardDecisionByQueryStmtIdx[stmtIdx]; ok {
+			stmtForwardDecision = decision
+		} else if preparedData, ok := preparedDataByStmtIdx[stmtIdx]; ok {
+			switch decision := preparedData.GetPrepareRequestInfo().GetBaseRequestInfo().GetForwardDecision(); decision {
+			case forwardToOrigin, forwardToTarget:
+				stmtForwardDecision = decision
+			}
+		}
+		switch stmtForwardDecision {
+		case forwardToOrigin:
+			originStmts++
+		case forwardToTarget:
+			targetStmts++
+		}
+		forwardDecisionByStmtIdx[stmtIdx] = stmtForwardDecision
+	}
+
+	// send BATCH to both (using origin's prepared IDs) unless every child statement is destined to the same cluster
+	fwdDecision := forwardToBoth
+	if numberOfStatements > 0 && originStmts == numberOfStatements {
+		fwdDecision = forwardToOrigin
+	} else if numberOfStatements > 0 && targetStmts == numberOfStatements {
+		fwdDecision = forwardToTarget
+	}
+
+	return &BatchRequestInfo{
+		preparedDataByStmtIdx:    preparedDataByStmtIdx,
+		forwardDecisionByStmtIdx: forwardDecisionByStmtIdx,
+		forwardDecision:          fwdDecision,
+	}
 }
 
-func NewBatchRequestInfo(preparedDataByStmtIdx map[int]PreparedData) *BatchRequestInfo {
-	return &BatchRequestInfo{preparedDataByStmtIdx: preparedDataByStmtIdx}
+// NewConditionalBatchRequestInfo creates the request info of a BATCH that contains at least one lightweight transaction.
+// Conditional batches are never split, every child statement is sent according to the provided forward decision.
+func NewConditionalBatchRequestInfo(
+	preparedDataByStmtIdx map[int]PreparedData, numberOfStatements int, decision forwardDecision) *BatchRequestInfo {
+	batchRequestInfo := newUnsplitBatchRequestInfo(preparedDataByStmtIdx, numberOfStatements, decision)
+	batchRequestInfo.conditional = true
+	return batchRequestInfo
+}
+
+// NewCounterBatchRequestInfo creates the request info of a BATCH that updates counter tables.
+// Like conditional batches, counter batches are never split.
+func NewCounterBatchRequestInfo(
+	preparedDataByStmtIdx map[int]PreparedData, numberOfStatements int, decision forwardDecision) *BatchRequestInfo {
+	batchRequestInfo := newUnsplitBatchRequestInfo(preparedDataByStmtIdx, numberOfStatements, decision)
+	batchRequestInfo.counterWrite = true
+	return batchRequestInfo
+}
+
+func newUnsplitBatchRequestInfo(
+	preparedDataByStmtIdx map[int]PreparedData, numberOfStatements int, decision forwardDecision) *BatchRequestInfo {
+	forwardDecisionByStmtIdx := make(map[int]forwardDecision, numberOfStatements)
+	for stmtIdx := 0; stmtIdx < numberOfStatements; stmtIdx++ {
+		forwardDecisionByStmtIdx[stmtIdx] = decision
+	}
+	return &BatchRequestInfo{
+		preparedDataByStmtIdx:    preparedDataByStmtIdx,
+		forwardDecisionByStmtIdx: forwardDecisionByStmtIdx,
+		forwardDecision:          decision,
+	}
 }
 
 func (recv *BatchRequestInfo) String() string {
-	return fmt.Sprintf("BatchRequestInfo{PreparedDataByStmtIdx: %v}", recv.preparedDataByStmtIdx)
+	return fmt.Sprintf("BatchRequestInfo{PreparedDataByStmtIdx: %v, ForwardDecisionByStmtIdx: %v, Conditional: %v, CounterWrite: %v}",
+		recv.preparedDataByStmtIdx, recv.forwardDecisionByStmtIdx, recv.conditional, recv.counterWrite)
 }
 
 func (recv *BatchRequestInfo) GetForwardDecision() forwardDecision {
-	return forwardToBoth // always send BATCH to both, use origin's prepared IDs
+	return recv.forwardDecision
+}
+
+// IsSplit returns true if the BATCH contains child statements that must only be sent to ORIGIN or TARGET
+// while it is being sent to both clusters.
+func (recv *BatchRequestInfo) IsSplit() bool {
+	if recv.forwardDecision != forwardToBoth {
+		return false
+	}
+	for _, stmtForwardDecision := range recv.forwardDecisionByStmtIdx {
+		if stmtForwardDecision != forwardToBoth {
+			return true
+		}
+	}
+	return false
+}
+
+func (recv *BatchRequestInfo) GetForwardDecisionByStmtIdx() map[int]forwardDecision {
+	return recv.forwardDecisionByStmtIdx
 }
 
 func (recv *BatchRequestInfo) ShouldAlsoBeSentAsync() bool {
@@ -187,6 +365,101 @@ func (recv *BatchRequestInfo) ShouldBeTrackedInMetrics() bool {
 	return true
 }
 
+func (recv *BatchRequestInfo) IsConditional() bool {
+	return recv.conditional
+}
+
+func (recv *BatchRequestInfo) IsCounterWrite() bool {
+	return recv.counterWrite
+}
+
+func (recv *BatchRequestInfo) IsWrite() bool {
+	return true
+}
+
 func (recv *BatchRequestInfo) GetPreparedDataByStmtIdx() map[int]PreparedData {
 	return recv.preparedDataByStmtIdx
 }
+
+// targetSkippedRequestInfo is a write that is only forwarded to ORIGIN because the TARGET circuit breaker is open.
+// It is excluded from the read and write metrics, TargetCircuitBreakerSkippedWrites tracks these requests instead.
+type targetSkippedRequestInfo struct {
+	RequestInfo
+}
+
+func (recv *targetSkippedRequestInfo) String() string {
+	return fmt.Sprintf("targetSkippedRequestInfo{%v}", recv.RequestInfo)
+}
+
+func (recv *targetSkippedRequestInfo) GetForwardDecision() forwardDecision {
+	return forwardToOrigin
+}
+
+func (recv *targetSkippedRequestInfo) ShouldBeTrackedInMetrics() bool {
+	return false
+}
+
+// shadowedRequestInfo is a write that is forwarded to ORIGIN and mirrored to TARGET through the async connector
+// because shadow mode is enabled. The client only waits for the ORIGIN response, the TARGET response is discarded.
+type shadowedRequestInfo struct {
+	RequestInfo
+}
+
+func (recv *shadowedRequestInfo) String() string {
+	return fmt.Sprintf("shadowedRequestInfo{%v}", recv.RequestInfo)
+}
+
+func (recv *shadowedRequestInfo) GetForwardDecision() forwardDecision {
+	return forwardToOrigin
+}
+
+func (recv *shadowedRequestInfo) ShouldAlsoBeSentAsync() bool {
+	return true
+}
+
+// originDecommissionedRequestInfo is a write that is only forwarded to TARGET because the connection was opened in the
+// ORIGIN_DECOMMISSIONED migration phase.
+type originDecommissionedRequestInfo struct {
+	RequestInfo
+}
+
+func (recv *originDecommissionedRequestInfo) String() string {
+	return fmt.Sprintf("originDecommissionedRequestInfo{%v}", recv.RequestInfo)
+}
+
+func (recv *originDecommissionedRequestInfo) GetForwardDecision() forwardDecision {
+	return forwardToTarget
+}
+
+// unwrapRequestInfo returns the request info that was created by the parser.
+func unwrapRequestInfo(requestInfo RequestInfo) RequestInfo {
+	switch wrapped := requestInfo.(type) {
+	case *targetSkippedRequestInfo:
+		return unwrapRequestInfo(wrapped.RequestInfo)
+	case *shadowedRequestInfo:
+		return unwrapRequestInfo(wrapped.RequestInfo)
+	case *originDecommissionedRequestInfo:
+		return unwrapRequestInfo(wrapped.RequestInfo)
+	case *continuousPagingRequestInfo:
+		return unwrapRequestInfo(wrapped.RequestInfo)
+	case *pagedRequestInfo:
+		return unwrapRequestInfo(wrapped.RequestInfo)
+	default:
+		return requestInfo
+	}
+}
+
+// getMetricsForwardDecision returns the forward decision that is used to track the request in the read and write
+// metrics, writes are tracked as writes even if they are only sent to one cluster, e.g. shadowed writes for which the
+// client only waits for ORIGIN, the writes of a decommissioned ORIGIN that are only sent to TARGET and the PRIMARY_ONLY
+// lightweight transactions.
+func getMetricsForwardDecision(requestInfo RequestInfo) forwardDecision {
+	decision := requestInfo.GetForwardDecision()
+	switch decision {
+	case forwardToOrigin, forwardToTarget:
+		if !isRead(requestInfo) {
+			return forwardToBoth
+		}
+	}
+	return decision
+}
@@ -0,0 +1,129 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeoutCustomPayloadKey can be set in the custom payload of a request to raise its timeout (in milliseconds,
+// as an ASCII decimal number). The same can be achieved with a /* zdm-timeout-ms=<value> */ comment in the query.
+const TimeoutCustomPayloadKey = "zdm-timeout-ms"
+
+// DeadlineCustomPayloadKey can be set in the custom payload of a QUERY, EXECUTE or BATCH request to give it a time
+// budget (in milliseconds since the proxy received it, as an ASCII decimal number) when
+// ZDM_PROXY_REQUEST_DEADLINES_ENABLED is true. The budget can only lower the timeout of the request.
+const DeadlineCustomPayloadKey = "zdm-deadline-ms"
+
+var timeoutCommentHintRegex = regexp.MustCompile(`/\*\s*zdm-timeout-ms\s*=\s*(\d+)\s*\*/`)
+
+// getRequestTimeout returns the timeout of the request based on its type (read, write, PREPARE or DDL). Clients can
+// raise it, up to ZDM_PROXY_MAX_REQUEST_TIMEOUT_OVERRIDE_MS, with a custom payload or a query comment.
+func getRequestTimeout(
+	timeouts *common.RequestTimeouts, frameContext *frameDecodeContext, requestInfo RequestInfo) time.Duration {
+
+	switch frameContext.GetRawFrame().Header.OpCode {
+	case primitive.OpCodeQuery, primitive.OpCodePrepare, primitive.OpCodeExecute, primitive.OpCodeBatch:
+	default:
+		return timeouts.Default
+	}
+
+	decodedFrame, err := frameContext.GetOrDecodeFrame()
+	if err != nil {
+		return timeouts.Default
+	}
+
+	var query string
+	switch msg := decodedFrame.Body.Message.(type) {
+	case *message.Query:
+		query = msg.Query
+	case *message.Prepare:
+		query = msg.Query
+	case *message.Execute:
+		if executeRequestInfo, ok := unwrapRequestInfo(requestInfo).(*ExecuteRequestInfo); ok {
+			query = executeRequestInfo.GetPreparedData().GetPrepareRequestInfo().GetQuery()
+		}
+	}
+
+	var timeout time.Duration
+	switch {
+	case decodedFrame.Header.OpCode == primitive.OpCodePrepare:
+		timeout = timeouts.Prepare
+	case isDdlQuery(query):
+		timeout = timeouts.Ddl
+	case isRead(requestInfo):
+		timeout = timeouts.Read
+	default:
+		timeout = timeouts.Write
+	}
+
+	override := getRequestTimeoutOverride(decodedFrame, query)
+	if override > timeouts.MaxOverride {
+		override = timeouts.MaxOverride
+	}
+	if override > timeout {
+		return override
+	}
+	return timeout
+}
+
+// isRead returns true if the request is sent to a single cluster and is neither a write (INSERT, UPDATE, DELETE or
+// BATCH) nor a schema change. Writes are never reads, even if they are only sent to one cluster.
+func isRead(requestInfo RequestInfo) bool {
+	if requestInfo.IsWrite() || isSchemaChangeRequest(requestInfo) {
+		return false
+	}
+	switch requestInfo.GetForwardDecision() {
+	case forwardToOrigin, forwardToTarget:
+		return true
+	default:
+		return false
+	}
+}
+
+// getRequestTimeoutOverride returns the timeout requested by the client, the custom payload takes precedence over
+// the query comment. Returns 0 if the client didn't request a timeout.
+func getRequestTimeoutOverride(decodedFrame *frame.Frame, query string) time.Duration {
+	if value, ok := decodedFrame.Body.CustomPayload[TimeoutCustomPayloadKey]; ok {
+		return parseTimeoutOverride(string(value))
+	}
+	if match := timeoutCommentHintRegex.FindStringSubmatch(query); match != nil {
+		return parseTimeoutOverride(match[1])
+	}
+	return 0
+}
+
+func parseTimeoutOverride(value string) time.Duration {
+	timeoutMs, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || timeoutMs <= 0 {
+		return 0
+	}
+	return time.Duration(timeoutMs) * time.Millisecond
+}
+
+// getRequestDeadlineBudget returns the budget set by the client with DeadlineCustomPayloadKey, 0 if there isn't one.
+func getRequestDeadlineBudget(frameContext *frameDecodeContext) time.Duration {
+	header := frameContext.GetRawFrame().Header
+	switch header.OpCode {
+	case primitive.OpCodeQuery, primitive.OpCodeExecute, primitive.OpCodeBatch:
+	default:
+		return 0
+	}
+	if !header.Flags.Contains(primitive.HeaderFlagCustomPayload) {
+		return 0
+	}
+
+	decodedFrame, err := frameContext.GetOrDecodeFrame()
+	if err != nil {
+		return 0
+	}
+	if value, ok := decodedFrame.Body.CustomPayload[DeadlineCustomPayloadKey]; ok {
+		return parseTimeoutOverride(string(value))
+	}
+	return 0
+}
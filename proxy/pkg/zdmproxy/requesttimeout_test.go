@@ -0,0 +1,104 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestGetRequestTimeout(t *testing.T) {
+	timeouts := &common.RequestTimeouts{
+		Default:     10 * time.Second,
+		Read:        2 * time.Second,
+		Write:       5 * time.Second,
+		Prepare:     3 * time.Second,
+		Ddl:         time.Minute,
+		MaxOverride: 2 * time.Minute,
+	}
+	readInfo := NewGenericRequestInfo(forwardToOrigin, true, true)
+	writeInfo := NewGenericRequestInfo(forwardToBoth, false, true)
+
+	newFrameContext := func(msg message.Message, customPayload map[string][]byte) *frameDecodeContext {
+		f := frame.NewFrame(primitive.ProtocolVersion4, 1, msg)
+		f.SetCustomPayload(customPayload)
+		rawFrame, err := defaultCodec.ConvertToRawFrame(f)
+		require.Nil(t, err)
+		return NewFrameDecodeContext(rawFrame, log.NewEntry(log.StandardLogger()))
+	}
+
+	tests := []struct {
+		name          string
+		msg           message.Message
+		customPayload map[string][]byte
+		requestInfo   RequestInfo
+		expected      time.Duration
+	}{
+		{"read", &message.Query{Query: "SELECT * FROM ks.t"}, nil, readInfo, 2 * time.Second},
+		{"write", &message.Query{Query: "INSERT INTO ks.t (a) VALUES (1)"}, nil, writeInfo, 5 * time.Second},
+		{"lwt", &message.Query{Query: "INSERT INTO ks.t (a) VALUES (1) IF NOT EXISTS"}, nil,
+			NewConditionalRequestInfo(forwardToOrigin), 5 * time.Second},
+		{"routed write", &message.Query{Query: "INSERT INTO ks.t (a) VALUES (1)"}, nil,
+			NewWriteRequestInfo(forwardToTarget), 5 * time.Second},
+		{"ddl", &message.Query{Query: "/* migration */ CREATE TABLE ks.t (a int PRIMARY KEY)"}, nil, writeInfo, time.Minute},
+		{"prepare", &message.Prepare{Query: "SELECT * FROM ks.t"}, nil,
+			NewPrepareRequestInfo(readInfo, nil, false, "SELECT * FROM ks.t", ""), 3 * time.Second},
+		{"options", &message.Options{}, nil, writeInfo, 10 * time.Second},
+		{"comment hint", &message.Query{Query: "SELECT /* zdm-timeout-ms=30000 */ * FROM ks.t"}, nil, readInfo,
+			30 * time.Second},
+		{"custom payload", &message.Query{Query: "SELECT * FROM ks.t"},
+			map[string][]byte{TimeoutCustomPayloadKey: []byte("45000")}, readInfo, 45 * time.Second},
+		{"override capped", &message.Query{Query: "SELECT /* zdm-timeout-ms=9999999 */ * FROM ks.t"}, nil, readInfo,
+			2 * time.Minute},
+		{"override can't lower the timeout", &message.Query{Query: "CREATE TABLE /* zdm-timeout-ms=1 */ ks.t (a int PRIMARY KEY)"},
+			nil, writeInfo, time.Minute},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			frameContext := newFrameContext(test.msg, test.customPayload)
+			require.Equal(t, test.expected, getRequestTimeout(timeouts, frameContext, test.requestInfo))
+		})
+	}
+}
+
+func TestGetRequestDeadlineBudget(t *testing.T) {
+	newFrameContext := func(msg message.Message, customPayload map[string][]byte) *frameDecodeContext {
+		f := frame.NewFrame(primitive.ProtocolVersion4, 1, msg)
+		f.SetCustomPayload(customPayload)
+		rawFrame, err := defaultCodec.ConvertToRawFrame(f)
+		require.Nil(t, err)
+		return NewFrameDecodeContext(rawFrame, log.NewEntry(log.StandardLogger()))
+	}
+	deadline := func(value string) map[string][]byte {
+		return map[string][]byte{DeadlineCustomPayloadKey: []byte(value)}
+	}
+
+	tests := []struct {
+		name          string
+		msg           message.Message
+		customPayload map[string][]byte
+		expected      time.Duration
+	}{
+		{"query", &message.Query{Query: "SELECT * FROM ks.t"}, deadline("250"), 250 * time.Millisecond},
+		{"execute", &message.Execute{QueryId: []byte{1}}, deadline("40"), 40 * time.Millisecond},
+		{"batch", &message.Batch{Children: []*message.BatchChild{{QueryOrId: "INSERT INTO ks.t (a) VALUES (1)"}}},
+			deadline(" 100 "), 100 * time.Millisecond},
+		{"no custom payload", &message.Query{Query: "SELECT * FROM ks.t"}, nil, 0},
+		{"other key", &message.Query{Query: "SELECT * FROM ks.t"},
+			map[string][]byte{TimeoutCustomPayloadKey: []byte("250")}, 0},
+		{"invalid", &message.Query{Query: "SELECT * FROM ks.t"}, deadline("soon"), 0},
+		{"negative", &message.Query{Query: "SELECT * FROM ks.t"}, deadline("-5"), 0},
+		{"prepare", &message.Prepare{Query: "SELECT * FROM ks.t"}, deadline("250"), 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, getRequestDeadlineBudget(newFrameContext(test.msg, test.customPayload)))
+		})
+	}
+}
@@ -6,6 +6,9 @@ type Response struct {
 	responseFrame *frame.RawFrame
 	connectorType ClusterConnectorType
 	requestFrame  *frame.RawFrame
+
+	// request context of the read that this speculative response belongs to, nil for other responses
+	speculativeReqCtx *requestContextImpl
 }
 
 func NewResponse(f *frame.RawFrame, connectorType ClusterConnectorType) *Response {
@@ -16,6 +19,16 @@ func NewResponse(f *frame.RawFrame, connectorType ClusterConnectorType) *Respons
 	}
 }
 
+func NewSpeculativeResponse(
+	f *frame.RawFrame, connectorType ClusterConnectorType, reqCtx *requestContextImpl) *Response {
+	return &Response{
+		responseFrame:     f,
+		connectorType:     connectorType,
+		requestFrame:      nil,
+		speculativeReqCtx: reqCtx,
+	}
+}
+
 func NewTimeoutResponse(requestFrame *frame.RawFrame, async bool) *Response {
 	var connectorType ClusterConnectorType
 	if async {
@@ -0,0 +1,219 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"strings"
+	"time"
+)
+
+// IdempotentCustomPayloadKey can be set in the custom payload of a request to tell the proxy whether the request is
+// idempotent ("true") or not (any other value), it takes precedence over ZDM_RETRY_IDEMPOTENT_TABLES.
+const IdempotentCustomPayloadKey = "zdm-idempotent"
+
+// RetryPolicies contains the retry policy of each cluster and the tables whose writes are idempotent.
+type RetryPolicies struct {
+	Origin           *common.RetryPolicy
+	Target           *common.RetryPolicy
+	IdempotentTables map[string]bool // <keyspace>.<table>
+}
+
+func NewRetryPolicies(conf *config.Config) (*RetryPolicies, error) {
+	originPolicy, err := conf.ParseOriginRetryPolicy()
+	if err != nil {
+		return nil, err
+	}
+	targetPolicy, err := conf.ParseTargetRetryPolicy()
+	if err != nil {
+		return nil, err
+	}
+	tables, err := conf.ParseRetryIdempotentTables()
+	if err != nil {
+		return nil, err
+	}
+	idempotentTables := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		idempotentTables[table] = true
+	}
+	return &RetryPolicies{
+		Origin:           originPolicy,
+		Target:           targetPolicy,
+		IdempotentTables: idempotentTables,
+	}, nil
+}
+
+// handleRetry retries requests that failed on ORIGIN or TARGET with a transient error.
+//
+// IS_BOOTSTRAPPING errors are always retried because the coordinator did not execute the request. OVERLOADED,
+// READ_TIMEOUT and WRITE_TIMEOUT errors are retried for reads and for idempotent writes, whatever the cluster(s) that
+// the write is sent to: schema changes and the writes that are only sent to one cluster (e.g. PRIMARY_ONLY lightweight
+// transactions, routed tables or a decommissioned ORIGIN) must be idempotent too. The request is sent again after the
+// backoff delay of the cluster's retry policy: reads are sent to another host of the local datacenter through the
+// alternate host connection (see alternateHost), writes and the reads that can't be sent there are sent on the same
+// connection.
+//
+// Returns the response that should be set on the request context or nil if the response was consumed
+// (i.e. a retry is scheduled).
+func (ch *ClientHandler) handleRetry(
+	reqCtx *requestContextImpl, response *frame.RawFrame, clusterType common.ClusterType) *frame.RawFrame {
+
+	if ch.retryPolicies == nil || response.Header.OpCode != primitive.OpCodeError {
+		return response
+	}
+
+	var policy *common.RetryPolicy
+	var connector *ClusterConnector
+	var request *frame.RawFrame
+	switch clusterType {
+	case common.ClusterTypeOrigin:
+		policy = ch.retryPolicies.Origin
+		connector = ch.originCassandraConnector
+		request = reqCtx.originRequest
+	case common.ClusterTypeTarget:
+		policy = ch.retryPolicies.Target
+		connector = ch.targetCassandraConnector
+		request = reqCtx.targetRequest
+	default:
+		return response
+	}
+	if policy == nil || policy.MaxAttempts == 0 || request == nil {
+		return response
+	}
+	if reqCtx.continuousPaging != nil && reqCtx.continuousPaging.hasForwardedPages() {
+		// a retry would send the pages that the client already received again
+		return response
+	}
+
+	requestInfo := unwrapRequestInfo(reqCtx.GetRequestInfo())
+	if !requestInfo.ShouldBeTrackedInMetrics() {
+		// only reads and writes are retried, not the requests that the proxy handles itself (PREPARE, USE, etc.)
+		return response
+	}
+
+	errMsg, err := decodeError(response)
+	if err != nil {
+		reqCtx.logger.Warnf("Could not decode error from %v while checking if the request can be retried: %v",
+			clusterType, err)
+		return response
+	}
+	switch errMsg.GetErrorCode() {
+	case primitive.ErrorCodeIsBootstrapping:
+	case primitive.ErrorCodeOverloaded, primitive.ErrorCodeReadTimeout, primitive.ErrorCodeWriteTimeout:
+		if !isRead(requestInfo) && !ch.isIdempotentWrite(reqCtx, requestInfo) {
+			return response
+		}
+	default:
+		return response
+	}
+
+	attempt, ok := reqCtx.StartRetry(clusterType, policy.MaxAttempts)
+	if !ok {
+		return response
+	}
+	delay := policy.GetDelay(attempt)
+	if !reqCtx.deadline.IsZero() && time.Now().Add(delay).After(reqCtx.deadline) {
+		// the response of the retry would arrive after the deadline of the client
+		return response
+	}
+
+	getRetryCounter(ch.metricHandler.GetProxyMetrics(), clusterType).Add(1)
+	reqCtx.logger.Debugf("Received %v from %v, retrying request in %v (attempt %d of %d).",
+		errMsg.GetErrorCode(), clusterType, delay, attempt, policy.MaxAttempts)
+
+	// the connectors are closed once every request is done so the retry is tracked as an in flight request
+	ch.clientHandlerRequestWaitGroup.Add(1)
+	time.AfterFunc(delay, func() {
+		defer ch.clientHandlerRequestWaitGroup.Done()
+		if !reqCtx.IsPending() {
+			return
+		}
+		if isRead(requestInfo) {
+			if alternateConnector := connector.getAlternateHostConnector(); alternateConnector != nil {
+				reqCtx.logger.Tracef("Retrying request on another host of %v.", clusterType)
+				alternateConnector.sendRequestToCluster(request)
+				return
+			}
+		}
+		connector.sendRequestToCluster(request)
+	})
+	return nil
+}
+
+// isIdempotentWrite returns true if the custom payload of the request marks it as idempotent or, if the custom
+// payload doesn't say anything, if every table that it writes to is in ZDM_RETRY_IDEMPOTENT_TABLES.
+// Lightweight transactions and counter updates, including those in a BATCH, are never idempotent and schema changes
+// are only idempotent if the custom payload says so.
+func (ch *ClientHandler) isIdempotentWrite(reqCtx *requestContextImpl, requestInfo RequestInfo) bool {
+	if requestInfo.IsConditional() || requestInfo.IsCounterWrite() {
+		return false
+	}
+
+	decodedFrame, err := defaultCodec.ConvertFromRawFrame(reqCtx.request)
+	if err != nil {
+		reqCtx.logger.Warnf("Could not decode request while checking if it is idempotent: %v", err)
+		return false
+	}
+	frameContext := NewInitializedFrameDecodeContext(reqCtx.request, decodedFrame, nil, reqCtx.logger)
+	if _, ok := requestInfo.(*BatchRequestInfo); ok {
+		conditionalOrCounterWrite, err := ch.isConditionalOrCounterBatch(frameContext, reqCtx.keyspace)
+		if err != nil || conditionalOrCounterWrite {
+			return false
+		}
+	}
+	if value, ok := decodedFrame.Body.CustomPayload[IdempotentCustomPayloadKey]; ok {
+		return strings.EqualFold(string(value), "true")
+	}
+	if len(ch.retryPolicies.IdempotentTables) == 0 || isSchemaChangeRequest(requestInfo) {
+		return false
+	}
+
+	tables := make([]string, 0)
+	switch castedRequestInfo := requestInfo.(type) {
+	case *ExecuteRequestInfo:
+		keyspace, table, ok := getPreparedStatementTable(castedRequestInfo.GetPreparedData())
+		if !ok {
+			return false
+		}
+		tables = append(tables, keyspace+"."+table)
+	case *BatchRequestInfo:
+		for _, preparedData := range castedRequestInfo.GetPreparedDataByStmtIdx() {
+			keyspace, table, ok := getPreparedStatementTable(preparedData)
+			if !ok {
+				return false
+			}
+			tables = append(tables, keyspace+"."+table)
+		}
+		stmtsQueryData, err := frameContext.GetOrInspectAllStatements(reqCtx.keyspace, ch.timeUuidGenerator)
+		if err != nil {
+			return false
+		}
+		for _, stmtQueryData := range stmtsQueryData {
+			tables = append(tables,
+				stmtQueryData.queryData.getApplicableKeyspace()+"."+stmtQueryData.queryData.getTableName())
+		}
+	default:
+		stmtQueryData, err := frameContext.GetOrInspectStatement(reqCtx.keyspace, ch.timeUuidGenerator)
+		if err != nil {
+			return false
+		}
+		tables = append(tables,
+			stmtQueryData.queryData.getApplicableKeyspace()+"."+stmtQueryData.queryData.getTableName())
+	}
+
+	for _, table := range tables {
+		if !ch.retryPolicies.IdempotentTables[table] {
+			return false
+		}
+	}
+	return len(tables) > 0
+}
+
+func getRetryCounter(proxyMetrics *metrics.ProxyMetrics, clusterType common.ClusterType) metrics.Counter {
+	if clusterType == common.ClusterTypeTarget {
+		return proxyMetrics.RetriesTarget
+	}
+	return proxyMetrics.RetriesOrigin
+}
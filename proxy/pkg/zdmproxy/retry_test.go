@@ -0,0 +1,130 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewRetryPolicies(t *testing.T) {
+	conf := config.New()
+	conf.OriginRetryMaxAttempts = 0
+	conf.OriginRetryBaseDelayMs = 100
+	conf.OriginRetryMaxDelayMs = 1000
+	conf.TargetRetryMaxAttempts = 3
+	conf.TargetRetryBaseDelayMs = 50
+	conf.TargetRetryMaxDelayMs = 300
+	conf.RetryIdempotentTables = "ks1.t1, ks2.t2"
+
+	retryPolicies, err := NewRetryPolicies(conf)
+	require.Nil(t, err)
+	require.Equal(t, &RetryPolicies{
+		Origin: &common.RetryPolicy{MaxAttempts: 0, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second},
+		Target: &common.RetryPolicy{MaxAttempts: 3, BaseDelay: 50 * time.Millisecond, MaxDelay: 300 * time.Millisecond},
+		IdempotentTables: map[string]bool{
+			"ks1.t1": true,
+			"ks2.t2": true,
+		},
+	}, retryPolicies)
+
+	conf.TargetRetryMaxDelayMs = 10
+	_, err = NewRetryPolicies(conf)
+	require.Equal(t, "invalid value for ZDM_TARGET_RETRY_MAX_DELAY_MS (10); "+
+		"it must not be lower than ZDM_TARGET_RETRY_BASE_DELAY_MS (50)", err.Error())
+
+	conf.TargetRetryMaxDelayMs = 300
+	conf.RetryIdempotentTables = "t1"
+	_, err = NewRetryPolicies(conf)
+	require.Equal(t, "invalid value for ZDM_RETRY_IDEMPOTENT_TABLES (t1); "+
+		"expected a comma separated list of <keyspace>.<table>", err.Error())
+}
+
+func TestRetryPolicy_GetDelay(t *testing.T) {
+	policy := &common.RetryPolicy{MaxAttempts: 5, BaseDelay: 50 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	require.Equal(t, 50*time.Millisecond, policy.GetDelay(1))
+	require.Equal(t, 100*time.Millisecond, policy.GetDelay(2))
+	require.Equal(t, 200*time.Millisecond, policy.GetDelay(3))
+	require.Equal(t, 300*time.Millisecond, policy.GetDelay(4))
+	require.Equal(t, 300*time.Millisecond, policy.GetDelay(5))
+}
+
+func TestRequestContext_StartRetry(t *testing.T) {
+	reqCtx := NewRequestContext(nil, nil, nil, NewGenericRequestInfo(forwardToBoth, false, true), "",
+		time.Now(), nil, nil)
+
+	attempt, ok := reqCtx.StartRetry(common.ClusterTypeTarget, 2)
+	require.True(t, ok)
+	require.Equal(t, 1, attempt)
+	attempt, ok = reqCtx.StartRetry(common.ClusterTypeTarget, 2)
+	require.True(t, ok)
+	require.Equal(t, 2, attempt)
+	_, ok = reqCtx.StartRetry(common.ClusterTypeTarget, 2)
+	require.False(t, ok)
+
+	attempt, ok = reqCtx.StartRetry(common.ClusterTypeOrigin, 2)
+	require.True(t, ok)
+	require.Equal(t, 1, attempt)
+
+	require.True(t, reqCtx.Cancel(nil))
+	_, ok = reqCtx.StartRetry(common.ClusterTypeOrigin, 2)
+	require.False(t, ok)
+}
+
+func TestIsIdempotentWrite(t *testing.T) {
+	insert := "INSERT INTO ks.tb (a) VALUES (1)"
+	withIdempotentPayload := func(f *frame.RawFrame) *frame.RawFrame {
+		decodedFrame, err := defaultCodec.ConvertFromRawFrame(f)
+		require.Nil(t, err)
+		decodedFrame.SetCustomPayload(map[string][]byte{IdempotentCustomPayloadKey: []byte("true")})
+		rawFrame, err := defaultCodec.ConvertToRawFrame(decodedFrame)
+		require.Nil(t, err)
+		return rawFrame
+	}
+	lwtBatch := mockBatchWithChildren(t, []*message.BatchChild{
+		{QueryOrId: insert}, {QueryOrId: "INSERT INTO ks.tb (a) VALUES (2) IF NOT EXISTS"}})
+	createTable := mockQueryFrame(t, "CREATE TABLE ks.tb2 (a int PRIMARY KEY)")
+	tests := []struct {
+		name        string
+		f           *frame.RawFrame
+		requestInfo RequestInfo
+		expected    bool
+	}{
+		{"idempotent table", mockQueryFrame(t, insert), NewWriteRequestInfo(forwardToBoth), true},
+		{"write to a single cluster", mockQueryFrame(t, insert), NewWriteRequestInfo(forwardToOrigin), true},
+		{"other table", mockQueryFrame(t, "INSERT INTO ks.other (a) VALUES (1)"), NewWriteRequestInfo(forwardToBoth),
+			false},
+		{"lightweight transaction", withIdempotentPayload(mockQueryFrame(t, insert+" IF NOT EXISTS")),
+			NewConditionalRequestInfo(forwardToOrigin), false},
+		{"counter update", mockQueryFrame(t, "UPDATE ks.tb SET c = c + 1 WHERE a = 1"),
+			NewCounterWriteRequestInfo(forwardToOrigin), false},
+		{"BATCH", mockBatchWithChildren(t, []*message.BatchChild{{QueryOrId: insert}}),
+			NewBatchRequestInfo(map[int]PreparedData{}, nil, 1), true},
+		{"BATCH with lightweight transaction", withIdempotentPayload(lwtBatch),
+			NewBatchRequestInfo(map[int]PreparedData{}, nil, 2), false},
+		{"schema change", createTable, NewSchemaChangeRequestInfo(forwardToOrigin, true), false},
+		{"idempotent schema change", withIdempotentPayload(createTable), NewSchemaChangeRequestInfo(forwardToOrigin, true),
+			true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeUuidGenerator, err := GetDefaultTimeUuidGenerator()
+			require.Nil(t, err)
+			counterTables := &atomic.Value{}
+			counterTables.Store(map[string]bool{})
+			ch := &ClientHandler{
+				retryPolicies:     &RetryPolicies{IdempotentTables: map[string]bool{"ks.tb": true, "ks.tb2": true}},
+				primaryCluster:    common.ClusterTypeOrigin,
+				originControlConn: &ControlConn{counterTables: counterTables},
+				timeUuidGenerator: timeUuidGenerator,
+			}
+			reqCtx := &requestContextImpl{request: tt.f, requestInfo: tt.requestInfo, logger: log.NewEntry(log.New())}
+			require.Equal(t, tt.expected, ch.isIdempotentWrite(reqCtx, tt.requestInfo))
+		})
+	}
+}
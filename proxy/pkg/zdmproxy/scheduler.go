@@ -1,40 +1,89 @@
 package zdmproxy
 
-import "sync"
+import (
+	"sync"
+	"sync/atomic"
+)
 
+// Scheduler runs tasks on a fixed number of workers. The workers are split in shards that have their own queue so
+// that the connections scheduling tasks at the same time don't all contend on a single channel. Each connection
+// gets a shard with NextShard and its tasks spill over to the other shards when the queue of that shard is full.
 type Scheduler struct {
-	queue chan func()
-	wg    *sync.WaitGroup
+	shards    []chan func()
+	nextShard uint32
+	wg        *sync.WaitGroup
 }
 
 func NewScheduler(workers int) *Scheduler {
+	return NewShardedScheduler(workers, 1)
+}
+
+func NewShardedScheduler(workers int, shards int) *Scheduler {
+	if shards > workers {
+		shards = workers
+	}
+	if shards < 1 {
+		shards = 1
+	}
+
 	scheduler := &Scheduler{
-		queue: make(chan func(), workers),
-		wg:    &sync.WaitGroup{},
+		shards: make([]chan func(), shards),
+		wg:     &sync.WaitGroup{},
 	}
 
-	for i := 0; i < workers; i++ {
-		scheduler.wg.Add(1)
-		go func() {
-			defer scheduler.wg.Done()
-			for {
-				task, ok := <-scheduler.queue
-				if !ok {
-					return
+	for i := 0; i < shards; i++ {
+		shardWorkers := workers / shards
+		if i < workers%shards {
+			shardWorkers++
+		}
+		queue := make(chan func(), shardWorkers)
+		scheduler.shards[i] = queue
+		for j := 0; j < shardWorkers; j++ {
+			scheduler.wg.Add(1)
+			go func() {
+				defer scheduler.wg.Done()
+				for {
+					task, ok := <-queue
+					if !ok {
+						return
+					}
+					task()
 				}
-				task()
-			}
-		}()
+			}()
+		}
 	}
 
 	return scheduler
 }
 
+// NextShard returns the shard to be used by a new connection, shards are assigned in a round robin fashion.
+func (recv *Scheduler) NextShard() int {
+	return int((atomic.AddUint32(&recv.nextShard, 1) - 1) % uint32(len(recv.shards)))
+}
+
+// Schedule queues a task that isn't bound to a connection.
 func (recv *Scheduler) Schedule(task func()) {
-	recv.queue <- task
+	recv.ScheduleOnShard(recv.NextShard(), task)
+}
+
+// ScheduleOnShard queues a task on the provided shard or, if its queue is full, on the first shard that has room.
+// It blocks until the task is queued on the provided shard if every queue is full.
+func (recv *Scheduler) ScheduleOnShard(shard int, task func()) {
+	if len(recv.shards) > 1 {
+		for i := 0; i < len(recv.shards); i++ {
+			select {
+			case recv.shards[(shard+i)%len(recv.shards)] <- task:
+				return
+			default:
+			}
+		}
+	}
+	recv.shards[shard] <- task
 }
 
 func (recv *Scheduler) Shutdown() {
-	close(recv.queue)
+	for _, queue := range recv.shards {
+		close(queue)
+	}
 	recv.wg.Wait()
 }
@@ -0,0 +1,68 @@
+package zdmproxy
+
+import (
+	"github.com/stretchr/testify/require"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestShardedScheduler_RunsAllTasks(t *testing.T) {
+	scheduler := NewShardedScheduler(8, 4)
+	require.Equal(t, 4, len(scheduler.shards))
+
+	var executed int32
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 1000; i++ {
+		wg.Add(1)
+		scheduler.ScheduleOnShard(i%4, func() {
+			defer wg.Done()
+			atomic.AddInt32(&executed, 1)
+		})
+	}
+	wg.Wait()
+	scheduler.Shutdown()
+	require.Equal(t, int32(1000), executed)
+}
+
+func TestShardedScheduler_Shards(t *testing.T) {
+	// there can't be more shards than workers
+	scheduler := NewShardedScheduler(2, 4)
+	require.Equal(t, 2, len(scheduler.shards))
+	require.Equal(t, 0, scheduler.NextShard())
+	require.Equal(t, 1, scheduler.NextShard())
+	require.Equal(t, 0, scheduler.NextShard())
+	scheduler.Shutdown()
+
+	scheduler = NewScheduler(4)
+	require.Equal(t, 1, len(scheduler.shards))
+	require.Equal(t, 4, cap(scheduler.shards[0]))
+	scheduler.Shutdown()
+}
+
+func TestShardedScheduler_SpillsOverWhenShardIsFull(t *testing.T) {
+	scheduler := NewShardedScheduler(2, 2)
+	defer scheduler.Shutdown()
+
+	// block the worker of shard 0 and fill its queue
+	blocked := make(chan bool)
+	started := make(chan bool)
+	scheduler.ScheduleOnShard(0, func() {
+		started <- true
+		<-blocked
+	})
+	<-started
+	scheduler.ScheduleOnShard(0, func() {})
+
+	done := make(chan bool)
+	scheduler.ScheduleOnShard(0, func() {
+		done <- true
+	})
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "task should have been executed by the worker of another shard")
+	}
+	close(blocked)
+}
@@ -0,0 +1,476 @@
+package zdmproxy
+
+import (
+	"context"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	SchemaDifferenceMissingKeyspace    = "MISSING_KEYSPACE"
+	SchemaDifferenceMissingTable       = "MISSING_TABLE"
+	SchemaDifferenceMissingColumn      = "MISSING_COLUMN"
+	SchemaDifferenceColumnTypeMismatch = "COLUMN_TYPE_MISMATCH"
+	SchemaDifferenceColumnKindMismatch = "COLUMN_KIND_MISMATCH"
+	SchemaDifferenceMissingType        = "MISSING_TYPE"
+	SchemaDifferenceTypeFieldsMismatch = "TYPE_FIELDS_MISMATCH"
+)
+
+var schemaDifferenceKinds = []string{
+	SchemaDifferenceMissingKeyspace,
+	SchemaDifferenceMissingTable,
+	SchemaDifferenceMissingColumn,
+	SchemaDifferenceColumnTypeMismatch,
+	SchemaDifferenceColumnKindMismatch,
+	SchemaDifferenceMissingType,
+	SchemaDifferenceTypeFieldsMismatch,
+}
+
+// SchemaDifference is a difference between the schemas of ORIGIN and TARGET. The names are those of ORIGIN (or of
+// TARGET if the object only exists on TARGET), Cluster is the cluster that misses the object of a MISSING_* difference.
+type SchemaDifference struct {
+	Kind     string             `json:"kind"`
+	Cluster  common.ClusterType `json:"cluster,omitempty"`
+	Keyspace string             `json:"keyspace"`
+	Table    string             `json:"table,omitempty"`
+	Type     string             `json:"type,omitempty"`
+	Column   string             `json:"column,omitempty"`
+	Origin   string             `json:"origin,omitempty"`
+	Target   string             `json:"target,omitempty"`
+}
+
+func (recv *SchemaDifference) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(recv.Kind)
+	sb.WriteString(" ")
+	sb.WriteString(recv.Keyspace)
+	if recv.Table != "" {
+		sb.WriteString(".")
+		sb.WriteString(recv.Table)
+	}
+	if recv.Type != "" {
+		sb.WriteString(".")
+		sb.WriteString(recv.Type)
+	}
+	if recv.Column != "" {
+		sb.WriteString(".")
+		sb.WriteString(recv.Column)
+	}
+	if recv.Cluster != "" {
+		sb.WriteString(fmt.Sprintf(" on %v", recv.Cluster))
+	}
+	if recv.Origin != "" || recv.Target != "" {
+		sb.WriteString(fmt.Sprintf(" (ORIGIN: %v, TARGET: %v)", recv.Origin, recv.Target))
+	}
+	return sb.String()
+}
+
+// SchemaDriftReport is the result of the last schema comparison (ZDM_SCHEMA_DRIFT_CHECK_INTERVAL_MS). The differences
+// of the last successful comparison are kept when a comparison fails.
+type SchemaDriftReport struct {
+	Keyspaces   []string            `json:"keyspaces,omitempty"`
+	LastCheck   *time.Time          `json:"last_check,omitempty"`
+	LastError   string              `json:"last_error,omitempty"`
+	Differences []*SchemaDifference `json:"differences"`
+}
+
+type schemaName struct {
+	keyspace string
+	name     string
+}
+
+type schemaColumn struct {
+	kind string
+	typ  string
+}
+
+// schemaMetadata is the part of the schema of a cluster that matters to the statements of the clients: the columns
+// of the tables (including the materialized views) and the fields of the user defined types.
+type schemaMetadata struct {
+	tables map[schemaName]map[string]*schemaColumn
+	types  map[schemaName][]string // "name type" of each field, in order
+}
+
+// fetchSchema reads the columns and the user defined types of all the keyspaces from system_schema, clusters that
+// don't have the system_schema keyspace (Cassandra 2.x) are not supported.
+func (cc *ControlConn) fetchSchema(ctx context.Context) (*schemaMetadata, error) {
+	conn, _ := cc.getConnAndContactPoint()
+	if conn == nil {
+		return nil, fmt.Errorf("the control connection to %v is not open", cc.connConfig.GetClusterType())
+	}
+
+	schema := &schemaMetadata{
+		tables: make(map[schemaName]map[string]*schemaColumn),
+		types:  make(map[schemaName][]string),
+	}
+	rs, err := conn.Query("SELECT keyspace_name, table_name, column_name, kind, type FROM system_schema.columns",
+		GetDefaultGenericTypeCodec(), ccProtocolVersion, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch the columns of %v: %w", cc.connConfig.GetClusterType(), err)
+	}
+	for _, row := range rs.Rows {
+		keyspaceName, _ := parseNillableString(row, "keyspace_name")
+		tableName, _ := parseNillableString(row, "table_name")
+		columnName, _ := parseNillableString(row, "column_name")
+		if keyspaceName == nil || tableName == nil || columnName == nil {
+			continue
+		}
+		column := &schemaColumn{}
+		if kind, _ := parseNillableString(row, "kind"); kind != nil {
+			column.kind = *kind
+		}
+		if typ, _ := parseNillableString(row, "type"); typ != nil {
+			column.typ = *typ
+		}
+		table := schemaName{keyspace: *keyspaceName, name: *tableName}
+		if schema.tables[table] == nil {
+			schema.tables[table] = make(map[string]*schemaColumn)
+		}
+		schema.tables[table][*columnName] = column
+	}
+
+	rs, err = conn.Query("SELECT keyspace_name, type_name, field_names, field_types FROM system_schema.types",
+		GetDefaultGenericTypeCodec(), ccProtocolVersion, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch the user defined types of %v: %w", cc.connConfig.GetClusterType(), err)
+	}
+	for _, row := range rs.Rows {
+		keyspaceName, _ := parseNillableString(row, "keyspace_name")
+		typeName, _ := parseNillableString(row, "type_name")
+		if keyspaceName == nil || typeName == nil {
+			continue
+		}
+		fieldNames, _ := parseNillableStringSlice(row, "field_names")
+		fieldTypes, _ := parseNillableStringSlice(row, "field_types")
+		fields := make([]string, len(fieldNames))
+		for i, fieldName := range fieldNames {
+			fields[i] = fieldName
+			if i < len(fieldTypes) {
+				fields[i] += " " + fieldTypes[i]
+			}
+		}
+		schema.types[schemaName{keyspace: *keyspaceName, name: *typeName}] = fields
+	}
+	return schema, nil
+}
+
+// keyspaces returns the keyspaces that have tables or types.
+func (recv *schemaMetadata) keyspaces() map[string]bool {
+	keyspaces := make(map[string]bool)
+	for table := range recv.tables {
+		keyspaces[table.keyspace] = true
+	}
+	for typ := range recv.types {
+		keyspaces[typ.keyspace] = true
+	}
+	return keyspaces
+}
+
+func isSchemaDriftSystemKeyspace(keyspace string) bool {
+	return isSystemKeyspace(keyspace) || strings.HasPrefix(keyspace, "system_") ||
+		strings.HasPrefix(keyspace, "dse_") || keyspace == "solr_admin"
+}
+
+// compareSchemas returns the differences between the schemas of ORIGIN and TARGET for the given ORIGIN keyspaces, or
+// for all the non-system keyspaces if keyspaces is nil. The ORIGIN names are translated with the name mapping
+// (ZDM_TARGET_NAME_MAPPING) before they are looked up on TARGET.
+func compareSchemas(
+	origin *schemaMetadata, target *schemaMetadata, keyspaces []string, nameMapping *common.NameMapping) []*SchemaDifference {
+	mapName := func(keyspace string, name string) (string, string) {
+		if nameMapping == nil {
+			return keyspace, name
+		}
+		return nameMapping.MapName(keyspace, name)
+	}
+
+	var originKeyspaces map[string]bool
+	if keyspaces == nil {
+		originKeyspaces = make(map[string]bool)
+		for keyspace := range origin.keyspaces() {
+			if !isSchemaDriftSystemKeyspace(keyspace) {
+				originKeyspaces[keyspace] = true
+			}
+		}
+	} else {
+		originKeyspaces = make(map[string]bool, len(keyspaces))
+		for _, keyspace := range keyspaces {
+			originKeyspaces[keyspace] = true
+		}
+	}
+	targetKeyspaces := make(map[string]bool, len(originKeyspaces))
+	for keyspace := range originKeyspaces {
+		targetKeyspace, _ := mapName(keyspace, "")
+		targetKeyspaces[targetKeyspace] = true
+	}
+	existingOriginKeyspaces := origin.keyspaces()
+	existingTargetKeyspaces := target.keyspaces()
+
+	differences := make([]*SchemaDifference, 0)
+	for keyspace := range originKeyspaces {
+		targetKeyspace, _ := mapName(keyspace, "")
+		if !existingOriginKeyspaces[keyspace] && existingTargetKeyspaces[targetKeyspace] {
+			differences = append(differences, &SchemaDifference{
+				Kind: SchemaDifferenceMissingKeyspace, Cluster: common.ClusterTypeOrigin, Keyspace: keyspace})
+		} else if existingOriginKeyspaces[keyspace] && !existingTargetKeyspaces[targetKeyspace] {
+			differences = append(differences, &SchemaDifference{
+				Kind: SchemaDifferenceMissingKeyspace, Cluster: common.ClusterTypeTarget, Keyspace: keyspace})
+		}
+	}
+	inScope := func(originKeyspace string, targetKeyspace string) bool {
+		// the tables of a keyspace that is missing on one of the clusters are not reported one by one
+		return existingOriginKeyspaces[originKeyspace] && existingTargetKeyspaces[targetKeyspace]
+	}
+
+	comparedTargetTables := make(map[schemaName]bool)
+	for table, originColumns := range origin.tables {
+		if !originKeyspaces[table.keyspace] {
+			continue
+		}
+		if targetKeyspace, _ := mapName(table.keyspace, ""); !inScope(table.keyspace, targetKeyspace) {
+			continue
+		}
+		targetKeyspace, targetTable := mapName(table.keyspace, table.name)
+		targetName := schemaName{keyspace: targetKeyspace, name: targetTable}
+		comparedTargetTables[targetName] = true
+		targetColumns, ok := target.tables[targetName]
+		if !ok {
+			differences = append(differences, &SchemaDifference{
+				Kind: SchemaDifferenceMissingTable, Cluster: common.ClusterTypeTarget,
+				Keyspace: table.keyspace, Table: table.name})
+			continue
+		}
+		differences = append(differences, compareColumns(table, originColumns, targetColumns)...)
+	}
+	for table := range target.tables {
+		if targetKeyspaces[table.keyspace] && !comparedTargetTables[table] &&
+			existingOriginKeyspaces[originKeyspaceOf(nameMapping, table.keyspace)] {
+			differences = append(differences, &SchemaDifference{
+				Kind: SchemaDifferenceMissingTable, Cluster: common.ClusterTypeOrigin,
+				Keyspace: table.keyspace, Table: table.name})
+		}
+	}
+
+	for typ, originFields := range origin.types {
+		if !originKeyspaces[typ.keyspace] {
+			continue
+		}
+		targetKeyspace, _ := mapName(typ.keyspace, "")
+		if !inScope(typ.keyspace, targetKeyspace) {
+			continue
+		}
+		targetFields, ok := target.types[schemaName{keyspace: targetKeyspace, name: typ.name}]
+		if !ok {
+			differences = append(differences, &SchemaDifference{
+				Kind: SchemaDifferenceMissingType, Cluster: common.ClusterTypeTarget,
+				Keyspace: typ.keyspace, Type: typ.name})
+		} else if strings.Join(originFields, ", ") != strings.Join(targetFields, ", ") {
+			differences = append(differences, &SchemaDifference{
+				Kind: SchemaDifferenceTypeFieldsMismatch, Keyspace: typ.keyspace, Type: typ.name,
+				Origin: strings.Join(originFields, ", "), Target: strings.Join(targetFields, ", ")})
+		}
+	}
+	for typ := range target.types {
+		originKeyspace := originKeyspaceOf(nameMapping, typ.keyspace)
+		if !targetKeyspaces[typ.keyspace] || !inScope(originKeyspace, typ.keyspace) {
+			continue
+		}
+		if _, ok := origin.types[schemaName{keyspace: originKeyspace, name: typ.name}]; !ok {
+			differences = append(differences, &SchemaDifference{
+				Kind: SchemaDifferenceMissingType, Cluster: common.ClusterTypeOrigin,
+				Keyspace: typ.keyspace, Type: typ.name})
+		}
+	}
+
+	sort.Slice(differences, func(i, j int) bool {
+		return differences[i].String() < differences[j].String()
+	})
+	return differences
+}
+
+func originKeyspaceOf(nameMapping *common.NameMapping, targetKeyspace string) string {
+	if nameMapping == nil {
+		return targetKeyspace
+	}
+	return nameMapping.OriginKeyspace(targetKeyspace)
+}
+
+func compareColumns(
+	table schemaName, originColumns map[string]*schemaColumn, targetColumns map[string]*schemaColumn) []*SchemaDifference {
+	differences := make([]*SchemaDifference, 0)
+	for name, originColumn := range originColumns {
+		targetColumn, ok := targetColumns[name]
+		switch {
+		case !ok:
+			differences = append(differences, &SchemaDifference{
+				Kind: SchemaDifferenceMissingColumn, Cluster: common.ClusterTypeTarget,
+				Keyspace: table.keyspace, Table: table.name, Column: name})
+		case originColumn.typ != targetColumn.typ:
+			differences = append(differences, &SchemaDifference{
+				Kind: SchemaDifferenceColumnTypeMismatch, Keyspace: table.keyspace, Table: table.name, Column: name,
+				Origin: originColumn.typ, Target: targetColumn.typ})
+		case originColumn.kind != targetColumn.kind:
+			differences = append(differences, &SchemaDifference{
+				Kind: SchemaDifferenceColumnKindMismatch, Keyspace: table.keyspace, Table: table.name, Column: name,
+				Origin: originColumn.kind, Target: targetColumn.kind})
+		}
+	}
+	for name := range targetColumns {
+		if _, ok := originColumns[name]; !ok {
+			differences = append(differences, &SchemaDifference{
+				Kind: SchemaDifferenceMissingColumn, Cluster: common.ClusterTypeOrigin,
+				Keyspace: table.keyspace, Table: table.name, Column: name})
+		}
+	}
+	return differences
+}
+
+// schemaDriftDetector compares the schemas of ORIGIN and TARGET every ZDM_SCHEMA_DRIFT_CHECK_INTERVAL_MS with the
+// control connections and exposes the differences as metrics and as a report.
+type schemaDriftDetector struct {
+	originControlConn *ControlConn
+	targetControlConn *ControlConn
+	keyspaces         []string
+	nameMapping       *common.NameMapping
+	interval          time.Duration
+
+	lock        *sync.Mutex
+	differences []*SchemaDifference
+	lastError   string
+
+	lastCheck int64 // unix nanoseconds, accessed atomically
+
+	differencesByKind map[string]metrics.Gauge
+	checks            metrics.Counter
+	errors            metrics.Counter
+
+	logger *log.Entry
+}
+
+func newSchemaDriftDetector(
+	originControlConn *ControlConn, targetControlConn *ControlConn, keyspaces []string,
+	nameMapping *common.NameMapping, interval time.Duration,
+	metricFactory metrics.MetricFactory, logger *log.Entry) (*schemaDriftDetector, error) {
+	checks, err := metricFactory.GetOrCreateCounter(metrics.SchemaDriftChecks)
+	if err != nil {
+		return nil, err
+	}
+	errors, err := metricFactory.GetOrCreateCounter(metrics.SchemaDriftCheckErrors)
+	if err != nil {
+		return nil, err
+	}
+	differencesByKind := make(map[string]metrics.Gauge, len(schemaDifferenceKinds))
+	for _, kind := range schemaDifferenceKinds {
+		differencesByKind[kind], err = metricFactory.GetOrCreateGauge(
+			metrics.SchemaDriftDifferences.WithLabels(map[string]string{metrics.SchemaDriftKindLabel: kind}))
+		if err != nil {
+			return nil, err
+		}
+	}
+	detector := &schemaDriftDetector{
+		originControlConn: originControlConn,
+		targetControlConn: targetControlConn,
+		keyspaces:         keyspaces,
+		nameMapping:       nameMapping,
+		interval:          interval,
+		lock:              &sync.Mutex{},
+		differences:       make([]*SchemaDifference, 0),
+		differencesByKind: differencesByKind,
+		checks:            checks,
+		errors:            errors,
+		logger:            logger,
+	}
+	_, err = metricFactory.GetOrCreateGaugeFunc(metrics.SchemaDriftLastCheck, func() float64 {
+		return float64(atomic.LoadInt64(&detector.lastCheck)) / float64(time.Second)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return detector, nil
+}
+
+// check fetches the schemas of both clusters and compares them, it returns the new differences.
+func (recv *schemaDriftDetector) check(ctx context.Context) ([]*SchemaDifference, error) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	recv.checks.Add(1)
+	originSchema, err := recv.originControlConn.fetchSchema(ctx)
+	if err == nil {
+		var targetSchema *schemaMetadata
+		targetSchema, err = recv.targetControlConn.fetchSchema(ctx)
+		if err == nil {
+			differences := compareSchemas(originSchema, targetSchema, recv.keyspaces, recv.nameMapping)
+			recv.setDifferences(differences)
+			recv.lastError = ""
+			atomic.StoreInt64(&recv.lastCheck, time.Now().UnixNano())
+			return differences, nil
+		}
+	}
+	recv.errors.Add(1)
+	recv.lastError = err.Error()
+	return nil, err
+}
+
+// setDifferences updates the metrics and logs the differences that were not found by the previous check, it must be
+// called while holding the lock.
+func (recv *schemaDriftDetector) setDifferences(differences []*SchemaDifference) {
+	previous := make(map[string]bool, len(recv.differences))
+	for _, difference := range recv.differences {
+		previous[difference.String()] = true
+		recv.differencesByKind[difference.Kind].Subtract(1)
+	}
+	for _, difference := range differences {
+		if !previous[difference.String()] {
+			recv.logger.Warnf("Schema drift between ORIGIN and TARGET: %v.", difference)
+		}
+		recv.differencesByKind[difference.Kind].Add(1)
+	}
+	if len(differences) == 0 && len(recv.differences) > 0 {
+		recv.logger.Infof("The schemas of ORIGIN and TARGET match again.")
+	}
+	recv.differences = differences
+}
+
+// run checks the schemas every interval until ctx is canceled. An error is only logged once until the next
+// successful check.
+func (recv *schemaDriftDetector) run(ctx context.Context, wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		lastError := ""
+		for {
+			_, err := recv.check(ctx)
+			if err == nil {
+				lastError = ""
+			} else if ctx.Err() == nil && err.Error() != lastError {
+				recv.logger.Warnf("Could not compare the schemas of ORIGIN and TARGET: %v", err)
+				lastError = err.Error()
+			}
+			if timedOut, _ := sleepWithContext(recv.interval, ctx, nil); !timedOut {
+				return
+			}
+		}
+	}()
+}
+
+func (recv *schemaDriftDetector) report() *SchemaDriftReport {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	report := &SchemaDriftReport{
+		Keyspaces:   recv.keyspaces,
+		LastError:   recv.lastError,
+		Differences: recv.differences,
+	}
+	if lastCheck := atomic.LoadInt64(&recv.lastCheck); lastCheck > 0 {
+		lastCheckTime := time.Unix(0, lastCheck)
+		report.LastCheck = &lastCheckTime
+	}
+	return report
+}
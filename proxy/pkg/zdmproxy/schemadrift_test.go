@@ -0,0 +1,96 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func newTestSchema() *schemaMetadata {
+	return &schemaMetadata{
+		tables: map[schemaName]map[string]*schemaColumn{},
+		types:  map[schemaName][]string{},
+	}
+}
+
+func (recv *schemaMetadata) withColumn(keyspace string, table string, column string, kind string, typ string) *schemaMetadata {
+	name := schemaName{keyspace: keyspace, name: table}
+	if recv.tables[name] == nil {
+		recv.tables[name] = map[string]*schemaColumn{}
+	}
+	recv.tables[name][column] = &schemaColumn{kind: kind, typ: typ}
+	return recv
+}
+
+func (recv *schemaMetadata) withType(keyspace string, typ string, fields ...string) *schemaMetadata {
+	recv.types[schemaName{keyspace: keyspace, name: typ}] = fields
+	return recv
+}
+
+func TestCompareSchemas(t *testing.T) {
+	origin := newTestSchema().
+		withColumn("ks", "users", "id", "partition_key", "uuid").
+		withColumn("ks", "users", "name", "regular", "text").
+		withColumn("ks", "users", "email", "regular", "text").
+		withColumn("ks", "events", "id", "partition_key", "uuid").
+		withColumn("ks", "events", "ts", "clustering", "timestamp").
+		withColumn("ks", "orders", "id", "partition_key", "uuid").
+		withColumn("ks2", "tb", "id", "partition_key", "int").
+		withColumn("system_auth", "roles", "role", "partition_key", "text").
+		withType("ks", "address", "street text", "city text").
+		withType("ks", "phone", "number text")
+	target := newTestSchema().
+		withColumn("ks", "users", "id", "partition_key", "uuid").
+		withColumn("ks", "users", "name", "regular", "int").
+		withColumn("ks", "users", "age", "regular", "int").
+		withColumn("ks", "events", "id", "partition_key", "uuid").
+		withColumn("ks", "events", "ts", "regular", "timestamp").
+		withColumn("ks", "logs", "id", "partition_key", "uuid").
+		withType("ks", "address", "street text", "zip text").
+		withType("ks", "point", "x int", "y int")
+
+	differences := compareSchemas(origin, target, nil, nil)
+	actual := make([]string, len(differences))
+	for i, difference := range differences {
+		actual[i] = difference.String()
+	}
+	require.Equal(t, []string{
+		"COLUMN_KIND_MISMATCH ks.events.ts (ORIGIN: clustering, TARGET: regular)",
+		"COLUMN_TYPE_MISMATCH ks.users.name (ORIGIN: text, TARGET: int)",
+		"MISSING_COLUMN ks.users.age on ORIGIN",
+		"MISSING_COLUMN ks.users.email on TARGET",
+		"MISSING_KEYSPACE ks2 on TARGET",
+		"MISSING_TABLE ks.logs on ORIGIN",
+		"MISSING_TABLE ks.orders on TARGET",
+		"MISSING_TYPE ks.phone on TARGET",
+		"MISSING_TYPE ks.point on ORIGIN",
+		"TYPE_FIELDS_MISMATCH ks.address (ORIGIN: street text, city text, TARGET: street text, zip text)",
+	}, actual)
+
+	// only the keyspaces in scope are compared
+	differences = compareSchemas(origin, target, []string{"ks2"}, nil)
+	require.Len(t, differences, 1)
+	require.Equal(t, &SchemaDifference{
+		Kind: SchemaDifferenceMissingKeyspace, Cluster: common.ClusterTypeTarget, Keyspace: "ks2"}, differences[0])
+}
+
+func TestCompareSchemasWithNameMapping(t *testing.T) {
+	origin := newTestSchema().
+		withColumn("ks", "users", "id", "partition_key", "uuid").
+		withColumn("ks", "orders", "id", "partition_key", "uuid")
+	target := newTestSchema().
+		withColumn("ks_target", "users", "id", "partition_key", "uuid").
+		withColumn("ks_target", "orders_v2", "id", "partition_key", "uuid")
+	nameMapping := &common.NameMapping{
+		Keyspaces: map[string]string{"ks": "ks_target"},
+		Tables: map[common.QualifiedTableName]common.QualifiedTableName{
+			{Keyspace: "ks", Table: "orders"}: {Keyspace: "ks_target", Table: "orders_v2"},
+		},
+	}
+	require.Empty(t, compareSchemas(origin, target, nil, nameMapping))
+
+	target.withColumn("ks_target", "orders_v2", "total", "regular", "decimal")
+	differences := compareSchemas(origin, target, nil, nameMapping)
+	require.Len(t, differences, 1)
+	require.Equal(t, "MISSING_COLUMN ks.orders.total on ORIGIN", differences[0].String())
+}
@@ -0,0 +1,170 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"time"
+)
+
+// isSpeculativeReadCandidate returns true for the reads on user tables that are sent to a single cluster, i.e. the
+// reads that are mirrored to the secondary cluster in dual reads mode. System queries are not candidates because the
+// hosts and the clusters return different results for them.
+func isSpeculativeReadCandidate(requestInfo RequestInfo) bool {
+	if !requestInfo.ShouldBeTrackedInMetrics() || !requestInfo.ShouldAlsoBeSentAsync() || requestInfo.IsConditional() {
+		return false
+	}
+	if _, shadowed := requestInfo.(*shadowedRequestInfo); shadowed {
+		return false
+	}
+	switch requestInfo.GetForwardDecision() {
+	case forwardToOrigin, forwardToTarget:
+		return true
+	default:
+		return false
+	}
+}
+
+// scheduleSpeculativeRead sends the read again if its cluster did not respond within ZDM_SPECULATIVE_READ_THRESHOLD_MS.
+// The first successful response is returned to the client.
+//
+// In dual reads mode the speculative read is sent to the secondary cluster through the async connector, otherwise it is
+// sent to another host of the cluster of the read through the alternate host connection (see alternateHost). Both
+// assign their own stream ids: the client can reuse the stream id of the read as soon as it receives the first
+// response while the other one is still in flight.
+func (ch *ClientHandler) scheduleSpeculativeRead(
+	reqCtx *requestContextImpl, originRequest *frame.RawFrame, targetRequest *frame.RawFrame,
+	requestTimeout time.Duration) {
+
+	readCluster, request := common.ClusterTypeOrigin, originRequest
+	if reqCtx.GetRequestInfo().GetForwardDecision() == forwardToTarget {
+		readCluster, request = common.ClusterTypeTarget, targetRequest
+	}
+	secondaryCluster := ch.asyncReadsEnabled && ch.asyncConnector != nil && ch.asyncConnector.clusterType != readCluster
+	if secondaryCluster {
+		request = targetRequest
+		if ch.asyncConnector.clusterType == common.ClusterTypeOrigin {
+			request = originRequest
+		}
+	}
+
+	ch.clientHandlerRequestWaitGroup.Add(1)
+	time.AfterFunc(ch.speculativeReadThreshold, func() {
+		defer ch.clientHandlerRequestWaitGroup.Done()
+		if secondaryCluster {
+			ch.sendSpeculativeReadToSecondaryCluster(reqCtx, request, requestTimeout)
+		} else {
+			ch.sendSpeculativeReadToOtherHost(reqCtx, request, readCluster)
+		}
+	})
+}
+
+func (ch *ClientHandler) sendSpeculativeReadToSecondaryCluster(
+	reqCtx *requestContextImpl, request *frame.RawFrame, requestTimeout time.Duration) {
+	asyncConnector := ch.asyncConnector
+	if !reqCtx.StartSpeculativeRead(asyncConnector.clusterType) {
+		return
+	}
+
+	reqCtx.logger.Tracef("No response after %v, sending speculative read to %v.",
+		ch.speculativeReadThreshold, asyncConnector.clusterType)
+	ch.clientHandlerRequestWaitGroup.Add(1)
+	sent := asyncConnector.sendSpeculativeRequest(reqCtx, request.Clone(), requestTimeout, func() {
+		ch.clientHandlerRequestWaitGroup.Done()
+	})
+	if !sent {
+		ch.clientHandlerRequestWaitGroup.Done()
+		reqCtx.CancelSpeculativeRead()
+	}
+}
+
+func (ch *ClientHandler) sendSpeculativeReadToOtherHost(
+	reqCtx *requestContextImpl, request *frame.RawFrame, clusterType common.ClusterType) {
+	connector := ch.originCassandraConnector
+	if clusterType == common.ClusterTypeTarget {
+		connector = ch.targetCassandraConnector
+	}
+	if !reqCtx.IsPending() {
+		// the alternate host connection is not opened for a read that already finished
+		return
+	}
+	alternateConnector := connector.getAlternateHostConnector()
+	if alternateConnector == nil || !reqCtx.StartSpeculativeRead(clusterType) {
+		return
+	}
+
+	reqCtx.logger.Tracef("No response after %v, sending speculative read to another host of %v.",
+		ch.speculativeReadThreshold, clusterType)
+	if !alternateConnector.sendSpeculativeReadWithStreamId(reqCtx, request) {
+		reqCtx.CancelSpeculativeRead()
+	}
+}
+
+// handleSpeculativeReadResponse finishes the read with the response of the speculative read unless the read already
+// finished or the speculative read failed, in which case the response of the primary cluster is awaited.
+func (ch *ClientHandler) handleSpeculativeReadResponse(response *Response, clusterType common.ClusterType) {
+	reqCtx := response.speculativeReqCtx
+	if reqCtx.GetRequestInfo().ShouldBeTrackedInMetrics() {
+		trackClusterErrorMetrics(response.responseFrame, response.connectorType, ch.nodeMetrics, ch.logger)
+	}
+	if !isResponseSuccessful(response.responseFrame) {
+		reqCtx.logger.Debugf("Speculative read on %v failed, waiting for the response of the primary cluster.",
+			clusterType)
+		return
+	}
+
+	holder := getOrCreateRequestContextHolder(ch.requestContextHolders, response.GetStreamId())
+	if holder.Get() != reqCtx {
+		// the read already finished, the client may have reused the stream id
+		return
+	}
+	var finished bool
+	if reqCtx.isSpeculativeReadOnOtherHost() {
+		finished = reqCtx.SetSpeculativeResponse(ch.nodeMetrics, response.responseFrame, clusterType, response.connectorType)
+	} else {
+		finished = reqCtx.SetResponse(ch.nodeMetrics, response.responseFrame, clusterType, response.connectorType)
+	}
+	if finished {
+		ch.finishRequest(holder, reqCtx)
+	}
+}
+
+// getSpeculativeReadResponse returns the response of the speculative read if it was received before the response of
+// the primary cluster and tracks whether the speculative read won.
+func (ch *ClientHandler) getSpeculativeReadResponse(reqCtx *requestContextImpl) (*frame.RawFrame, common.ClusterType, bool) {
+	if reqCtx.speculativeCluster == common.ClusterTypeNone {
+		return nil, common.ClusterTypeNone, false
+	}
+
+	proxyMetrics := ch.metricHandler.GetProxyMetrics()
+	if reqCtx.isSpeculativeReadOnOtherHost() {
+		// the response of the host that responded first is the response of the cluster, it is returned as usual
+		response := reqCtx.originResponse
+		if reqCtx.speculativeCluster == common.ClusterTypeTarget {
+			response = reqCtx.targetResponse
+		}
+		if reqCtx.speculativeWon {
+			proxyMetrics.SpeculativeReadWins.Add(1)
+			reqCtx.logger.Tracef("Returning the response of the speculative read from another host of %v.",
+				reqCtx.speculativeCluster)
+		} else if response != nil {
+			proxyMetrics.SpeculativeReadLosses.Add(1)
+		}
+		return nil, common.ClusterTypeNone, false
+	}
+
+	primaryResponse, speculativeResponse := reqCtx.originResponse, reqCtx.targetResponse
+	if reqCtx.speculativeCluster == common.ClusterTypeOrigin {
+		primaryResponse, speculativeResponse = reqCtx.targetResponse, reqCtx.originResponse
+	}
+
+	if primaryResponse != nil || speculativeResponse == nil {
+		if primaryResponse != nil {
+			proxyMetrics.SpeculativeReadLosses.Add(1)
+		}
+		return nil, common.ClusterTypeNone, false
+	}
+
+	proxyMetrics.SpeculativeReadWins.Add(1)
+	reqCtx.logger.Tracef("Returning the response of the speculative read from %v.", reqCtx.speculativeCluster)
+	return speculativeResponse, reqCtx.speculativeCluster, true
+}
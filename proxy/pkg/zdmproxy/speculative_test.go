@@ -0,0 +1,74 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestIsSpeculativeReadCandidate(t *testing.T) {
+	require.True(t, isSpeculativeReadCandidate(NewGenericRequestInfo(forwardToOrigin, true, true)))
+	require.True(t, isSpeculativeReadCandidate(NewGenericRequestInfo(forwardToTarget, true, true)))
+	require.False(t, isSpeculativeReadCandidate(NewGenericRequestInfo(forwardToOrigin, false, true)), "system query")
+	require.False(t, isSpeculativeReadCandidate(NewGenericRequestInfo(forwardToBoth, false, true)), "write")
+	require.False(t, isSpeculativeReadCandidate(NewConditionalRequestInfo(forwardToOrigin)), "lwt")
+	require.False(t, isSpeculativeReadCandidate(
+		&shadowedRequestInfo{RequestInfo: NewGenericRequestInfo(forwardToBoth, true, false)}), "shadowed write")
+}
+
+func TestRequestContext_SpeculativeRead(t *testing.T) {
+	request := &frame.RawFrame{Header: &frame.Header{}}
+	response := &frame.RawFrame{Header: &frame.Header{}}
+	newReqCtx := func() *requestContextImpl {
+		return NewRequestContext(request, request, request, NewGenericRequestInfo(forwardToOrigin, true, true), "",
+			time.Now(), nil, nil)
+	}
+
+	reqCtx := newReqCtx()
+	_, updated := reqCtx.updateInternalState(response, common.ClusterTypeTarget)
+	require.True(t, updated)
+	require.True(t, reqCtx.IsPending(), "a response from the secondary cluster without speculative read is ignored")
+
+	reqCtx = newReqCtx()
+	require.True(t, reqCtx.StartSpeculativeRead(common.ClusterTypeTarget))
+	require.False(t, reqCtx.StartSpeculativeRead(common.ClusterTypeTarget))
+	state, _ := reqCtx.updateInternalState(response, common.ClusterTypeTarget)
+	require.Equal(t, RequestDone, state)
+	require.False(t, reqCtx.StartSpeculativeRead(common.ClusterTypeTarget))
+
+	reqCtx = newReqCtx()
+	require.True(t, reqCtx.StartSpeculativeRead(common.ClusterTypeTarget))
+	reqCtx.CancelSpeculativeRead()
+	_, _ = reqCtx.updateInternalState(response, common.ClusterTypeTarget)
+	require.True(t, reqCtx.IsPending())
+}
+
+func TestRequestContext_SpeculativeReadOnOtherHost(t *testing.T) {
+	request := &frame.RawFrame{Header: &frame.Header{}}
+	response := &frame.RawFrame{Header: &frame.Header{}}
+	newReqCtx := func() *requestContextImpl {
+		// not tracked in metrics so that no node metrics are needed
+		return NewRequestContext(request, request, request, NewGenericRequestInfo(forwardToOrigin, true, false), "",
+			time.Now(), nil, log.NewEntry(log.StandardLogger()))
+	}
+
+	reqCtx := newReqCtx()
+	require.True(t, reqCtx.StartSpeculativeRead(common.ClusterTypeOrigin))
+	require.True(t, reqCtx.isSpeculativeReadOnOtherHost())
+	require.True(t, reqCtx.SetSpeculativeResponse(nil, response, common.ClusterTypeOrigin, ClusterConnectorTypeOrigin))
+	require.True(t, reqCtx.speculativeWon)
+	require.False(t, reqCtx.SetResponse(nil, response, common.ClusterTypeOrigin, ClusterConnectorTypeOrigin))
+
+	reqCtx = newReqCtx()
+	require.True(t, reqCtx.StartSpeculativeRead(common.ClusterTypeOrigin))
+	require.True(t, reqCtx.SetResponse(nil, response, common.ClusterTypeOrigin, ClusterConnectorTypeOrigin))
+	require.False(t, reqCtx.SetSpeculativeResponse(nil, response, common.ClusterTypeOrigin, ClusterConnectorTypeOrigin))
+	require.False(t, reqCtx.speculativeWon, "the speculative read lost against the host that received the read")
+
+	reqCtx = newReqCtx()
+	require.True(t, reqCtx.StartSpeculativeRead(common.ClusterTypeTarget))
+	require.False(t, reqCtx.isSpeculativeReadOnOtherHost())
+}
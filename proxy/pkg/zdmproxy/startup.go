@@ -51,7 +51,7 @@ func (ch *ClientHandler) handleSecondaryHandshakeStartup(
 		forwardToSecondary = forwardToTarget
 	}
 
-	log.Infof("Initiating startup between %v and %v (%v)", clientIPAddress, clusterAddress, logIdentifier)
+	ch.logger.Infof("Initiating startup between %v and %v (%v)", clientIPAddress, clusterAddress, logIdentifier)
 	phase := 1
 	attempts := 0
 
@@ -111,12 +111,13 @@ func (ch *ClientHandler) handleSecondaryHandshakeStartup(
 			overallRequestStartTime := time.Now()
 			channel := make(chan *customResponse, 1)
 			err := ch.executeRequest(
-				NewFrameDecodeContext(request),
+				NewFrameDecodeContext(request, ch.logger),
 				NewGenericRequestInfo(forwardToSecondary, asyncConnector, false),
 				ch.LoadCurrentKeyspace(),
 				overallRequestStartTime,
 				channel,
-				requestTimeout)
+				requestTimeout,
+				ch.newRequestLogger(request))
 
 			if err != nil {
 				return fmt.Errorf("unable to send secondary (%v) handshake frame to %v: %w", logIdentifier, clusterAddress, err)
@@ -139,7 +140,7 @@ func (ch *ClientHandler) handleSecondaryHandshakeStartup(
 		}
 
 		newPhase, parsedFrame, done, err := handleSecondaryHandshakeResponse(
-			phase, response, clientIPAddress, clusterAddress, logIdentifier)
+			phase, response, clientIPAddress, clusterAddress, logIdentifier, ch.logger)
 		if err != nil {
 			return err
 		}
@@ -162,7 +163,7 @@ func (ch *ClientHandler) handleSecondaryHandshakeStartup(
 
 func handleSecondaryHandshakeResponse(
 	phase int, f *frame.RawFrame, clientIPAddress net.Addr,
-	clusterAddress net.Addr, logIdentifier string) (int, *frame.Frame, bool, error) {
+	clusterAddress net.Addr, logIdentifier string, logger *log.Entry) (int, *frame.Frame, bool, error) {
 	parsedFrame, err := defaultCodec.ConvertFromRawFrame(f)
 	if err != nil {
 		return phase, nil, false, fmt.Errorf("could not decode frame from %v: %w", clusterAddress, err)
@@ -171,16 +172,16 @@ func handleSecondaryHandshakeResponse(
 	done := false
 	switch f.Header.OpCode {
 	case primitive.OpCodeAuthenticate:
-		log.Debugf("Received AUTHENTICATE for secondary handshake (%v)", logIdentifier)
+		logger.Debugf("Received AUTHENTICATE for secondary handshake (%v)", logIdentifier)
 		return 2, parsedFrame, false, nil
 	case primitive.OpCodeAuthChallenge:
-		log.Debugf("Received AUTH_CHALLENGE for secondary handshake (%v)", logIdentifier)
+		logger.Debugf("Received AUTH_CHALLENGE for secondary handshake (%v)", logIdentifier)
 	case primitive.OpCodeReady:
 		done = true
-		log.Debugf("%v (%v) did not request authorization for client %v", clusterAddress, logIdentifier, clientIPAddress)
+		logger.Debugf("%v (%v) did not request authorization for client %v", clusterAddress, logIdentifier, clientIPAddress)
 	case primitive.OpCodeAuthSuccess:
 		done = true
-		log.Debugf("%s successfully authenticated with %v (%v)", clientIPAddress, clusterAddress, logIdentifier)
+		logger.Debugf("%s successfully authenticated with %v (%v)", clientIPAddress, clusterAddress, logIdentifier)
 	default:
 		authErrorMsg, ok := parsedFrame.Body.Message.(*message.AuthenticationError)
 		if ok {
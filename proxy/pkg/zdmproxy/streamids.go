@@ -0,0 +1,518 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"net"
+	"sync"
+	"time"
+)
+
+// maxStreamIdsV2 is the number of stream ids of a connection with protocol v1 or v2
+const maxStreamIdsV2 = 128
+
+// maxStreamIdsV3 is the number of stream ids of a connection with protocol v3 or later
+const maxStreamIdsV3 = 32768
+
+// streamIdMapper assigns the stream ids of the requests sent on an ORIGIN or TARGET request connection when
+// ZDM_STREAM_ID_VIRTUALIZATION_ENABLED is true. The stream id of the client request is restored in the response so
+// the stream ids of the client connection and of each request connection are independent.
+type streamIdMapper struct {
+	lock            *sync.Mutex
+	clientStreamIds map[int16]streamIdAssignment
+	released        []int16
+	next            int
+	maxStreamIds    int
+
+	// incremented by acquire so that a stream id that was released and acquired again is not mistaken for the
+	// request it was assigned to before, see releaseLost
+	sequence uint64
+
+	// set once a stream id overflow connection doesn't receive new requests, see retire
+	retired bool
+}
+
+// streamIdAssignment is the client stream id of the request that a stream id is assigned to
+type streamIdAssignment struct {
+	clientStreamId int16
+	sequence       uint64
+
+	// set for the speculative reads sent on an alternate host connection, see sendSpeculativeReadWithStreamId
+	speculativeReqCtx *requestContextImpl
+}
+
+func newStreamIdMapper(maxStreamIds int) *streamIdMapper {
+	return &streamIdMapper{
+		lock:            &sync.Mutex{},
+		clientStreamIds: make(map[int16]streamIdAssignment),
+		maxStreamIds:    maxStreamIds,
+	}
+}
+
+// acquire returns a stream id that is not in use on the connection for a request with the given client stream id. It
+// returns false if all the stream ids that the protocol version allows are in use.
+func (recv *streamIdMapper) acquire(clientStreamId int16, version primitive.ProtocolVersion) (int16, bool) {
+	return recv.acquireForSpeculativeRead(clientStreamId, version, nil)
+}
+
+// acquireForSpeculativeRead is acquire for a speculative read, the request context of the read is returned by release.
+func (recv *streamIdMapper) acquireForSpeculativeRead(
+	clientStreamId int16, version primitive.ProtocolVersion, speculativeReqCtx *requestContextImpl) (int16, bool) {
+	maxStreamIds := recv.maxStreamIds
+	if version < primitive.ProtocolVersion3 && maxStreamIds > maxStreamIdsV2 {
+		maxStreamIds = maxStreamIdsV2
+	}
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if recv.retired {
+		return -1, false
+	}
+
+	var streamId int16
+	if n := len(recv.released); n > 0 {
+		streamId = recv.released[n-1]
+		recv.released = recv.released[:n-1]
+	} else if recv.next < maxStreamIds {
+		streamId = int16(recv.next)
+		recv.next++
+	} else {
+		return -1, false
+	}
+	recv.sequence++
+	recv.clientStreamIds[streamId] = streamIdAssignment{
+		clientStreamId:    clientStreamId,
+		sequence:          recv.sequence,
+		speculativeReqCtx: speculativeReqCtx,
+	}
+	return streamId, true
+}
+
+// release frees the stream id of a response and returns the client request it was assigned to. The last return
+// value is true if the mapper is retired and this was the last stream id in use.
+func (recv *streamIdMapper) release(streamId int16) (streamIdAssignment, bool, bool) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	assignment, ok := recv.clientStreamIds[streamId]
+	if !ok {
+		return streamIdAssignment{}, false, false
+	}
+	delete(recv.clientStreamIds, streamId)
+	recv.released = append(recv.released, streamId)
+	return assignment, true, recv.retired && len(recv.clientStreamIds) == 0
+}
+
+// inUse returns the stream ids that are currently assigned to a request.
+func (recv *streamIdMapper) inUse() map[int16]streamIdAssignment {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	inUse := make(map[int16]streamIdAssignment, len(recv.clientStreamIds))
+	for streamId, assignment := range recv.clientStreamIds {
+		inUse[streamId] = assignment
+	}
+	return inUse
+}
+
+// releaseLost frees the stream ids returned by inUse that are still assigned to the same requests, i.e. the requests
+// that never received a response, and returns how many of them were released.
+func (recv *streamIdMapper) releaseLost(lost map[int16]streamIdAssignment) int {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	released := 0
+	for streamId, assignment := range lost {
+		if current, ok := recv.clientStreamIds[streamId]; ok && current.sequence == assignment.sequence {
+			delete(recv.clientStreamIds, streamId)
+			recv.released = append(recv.released, streamId)
+			released++
+		}
+	}
+	return released
+}
+
+// retire stops assigning stream ids, it returns true if none of them is in use.
+func (recv *streamIdMapper) retire() bool {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.retired = true
+	return len(recv.clientStreamIds) == 0
+}
+
+// streamIdOverflow tracks the additional request connections that an ORIGIN or TARGET connector opens when all the
+// stream ids of its connection are in use, see ZDM_STREAM_ID_OVERFLOW_CONNECTION_ENABLED.
+//
+// At most one overflow connection receives new requests. It is retired when the client changes the current keyspace
+// so that the requests are not executed with the keyspace that was set when it was opened, a retired connection is
+// closed once the responses of its requests are received and a new one is opened the next time it is needed.
+type streamIdOverflow struct {
+	lock *sync.Mutex
+
+	// set by enableStreamIdOverflow once the client handshake is done
+	handshake connectionHandshake
+
+	opening bool
+	closed  bool
+	current *ClusterConnector
+
+	// all the overflow connectors that were started, including the retired ones
+	connectors []*ClusterConnector
+}
+
+func newStreamIdOverflow() *streamIdOverflow {
+	return &streamIdOverflow{
+		lock: &sync.Mutex{},
+	}
+}
+
+// sendRequestWithStreamId sends a request that was already translated with a stream id of this connection. If all of
+// them are in use the request is sent on the stream id overflow connection, or an OVERLOADED error is returned to the
+// client if it can't be sent there either.
+func (cc *ClusterConnector) sendRequestWithStreamId(clientRequest *frame.RawFrame, request *frame.RawFrame) {
+	if cc.enqueueWithStreamId(request) {
+		return
+	}
+
+	nodeMetricsInstance, err := GetNodeMetricsByClusterConnector(cc.nodeMetrics, cc.connectorType)
+	if err == nil {
+		nodeMetricsInstance.StreamIdsExhausted.Add(1)
+	}
+
+	if cc.streamIdOverflow != nil && !cc.isUseRequest(request) {
+		overflowConnector := cc.getStreamIdOverflowConnector()
+		if overflowConnector != nil && overflowConnector.enqueueWithStreamId(request) {
+			return
+		}
+	}
+
+	cc.logger.Debugf("[%s] All the stream ids of the request connection to %v are in use, rejecting %v request.",
+		cc.connectorType, cc.clusterType, clientRequest.Header.OpCode)
+	cc.rejectRequest(clientRequest, &message.Overloaded{
+		ErrorMessage: fmt.Sprintf("All the stream ids of the request connection to %v are in use", cc.clusterType),
+	})
+}
+
+func (cc *ClusterConnector) enqueueWithStreamId(request *frame.RawFrame) bool {
+	streamId, ok := cc.streamIds.acquire(request.Header.StreamId, request.Header.Version)
+	if !ok {
+		return false
+	}
+
+	// the request frame can be shared with the other cluster so its header is not modified
+	header := request.Header.Clone()
+	header.StreamId = streamId
+	cc.writeCoalescer.Enqueue(&frame.RawFrame{Header: header, Body: request.Body})
+	return true
+}
+
+// sendSpeculativeReadWithStreamId sends a speculative read on an alternate host connection, its response is forwarded
+// to the client handler with the request context of the read. It returns false if the read was not sent.
+func (cc *ClusterConnector) sendSpeculativeReadWithStreamId(reqCtx *requestContextImpl, clientRequest *frame.RawFrame) bool {
+	request, err := cc.translateRequest(clientRequest)
+	if err != nil {
+		cc.logger.Errorf("[%s] Discarding speculative read because it could not be translated: %v.", cc.connectorType, err)
+		return false
+	}
+	streamId, ok := cc.streamIds.acquireForSpeculativeRead(request.Header.StreamId, request.Header.Version, reqCtx)
+	if !ok {
+		return false
+	}
+
+	header := request.Header.Clone()
+	header.StreamId = streamId
+	cc.writeCoalescer.Enqueue(&frame.RawFrame{Header: header, Body: request.Body})
+	return true
+}
+
+// releaseLostStreamIds is called when the request connection failed over. The requests that were written to the lost
+// connection never receive a response so their stream ids are released once the longest request timeout elapsed, the
+// client handler returned a timeout error to the client by then.
+func (cc *ClusterConnector) releaseLostStreamIds(requestTimeout time.Duration) {
+	lost := cc.streamIds.inUse()
+	if len(lost) == 0 {
+		return
+	}
+	time.AfterFunc(requestTimeout, func() {
+		if released := cc.streamIds.releaseLost(lost); released > 0 {
+			cc.logger.Debugf("[%s] Released %d stream ids of the requests that were lost when the request connection "+
+				"to %v failed over.", cc.connectorType, released, cc.clusterType)
+		}
+	})
+}
+
+// restoreClientStreamId replaces the stream id of a response with the stream id of the client request and returns the
+// request context of the read if the request is a speculative read. It returns false if the stream id is not assigned
+// to a request, the response is discarded in that case.
+func (cc *ClusterConnector) restoreClientStreamId(response *frame.RawFrame) (*requestContextImpl, bool) {
+	if response.Header.StreamId < 0 {
+		// events and heartbeats
+		return nil, true
+	}
+
+	assignment, ok, drained := cc.streamIds.release(response.Header.StreamId)
+	if !ok {
+		cc.logger.Warnf("[%s] Discarding response with stream id %d from %v because it was not assigned to a request.",
+			cc.connectorType, response.Header.StreamId, cc.clusterType)
+		return nil, false
+	}
+	if drained {
+		cc.logger.Debugf("[%s] Closing retired request connection to %v.", cc.connectorType, cc.clusterType)
+		cc.Shutdown()
+	}
+	response.Header.StreamId = assignment.clientStreamId
+	return assignment.speculativeReqCtx, true
+}
+
+// rejectRequest returns an error to the client handler for a request that was not sent.
+func (cc *ClusterConnector) rejectRequest(request *frame.RawFrame, errMsg message.Error) {
+	response, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(request.Header.Version, request.Header.StreamId, errMsg))
+	if err != nil {
+		cc.logger.Errorf("[%s] Could not convert frame (%v) to raw frame: %v", cc.connectorType, errMsg, err)
+		return
+	}
+	cc.rejectedResponses(NewResponse(response, cc.connectorType))
+}
+
+// isUseRequest returns true if the request is a USE statement or if it can't be decoded. These requests are not sent
+// on the stream id overflow connection because the current keyspace of the connection of the connector would not be
+// changed.
+func (cc *ClusterConnector) isUseRequest(request *frame.RawFrame) bool {
+	var query string
+	switch request.Header.OpCode {
+	case primitive.OpCodeQuery, primitive.OpCodeExecute:
+	default:
+		return false
+	}
+
+	body, err := defaultCodec.DecodeBody(request.Header, bytes.NewReader(request.Body))
+	if err != nil {
+		return true
+	}
+	switch msg := body.Message.(type) {
+	case *message.Query:
+		query = msg.Query
+	case *message.Execute:
+		var preparedData PreparedData
+		var ok bool
+		if cc.clusterType == common.ClusterTypeTarget {
+			preparedData, ok = cc.psCache.GetByTargetPreparedId(msg.QueryId)
+		} else {
+			preparedData, ok = cc.psCache.Get(msg.QueryId)
+		}
+		if !ok {
+			return false
+		}
+		query = preparedData.GetPrepareRequestInfo().GetQuery()
+	default:
+		return true
+	}
+	return inspectCqlQuery(query, "", nil, cc.logger).getStatementType() == statementTypeUse
+}
+
+// getStreamIdOverflowConnector returns the overflow connector that receives the requests, or nil if it is not open
+// yet. In that case it is opened in the background so that the requests received after it is ready can use it.
+func (cc *ClusterConnector) getStreamIdOverflowConnector() *ClusterConnector {
+	overflow := cc.streamIdOverflow
+	overflow.lock.Lock()
+	defer overflow.lock.Unlock()
+	if overflow.current != nil && !overflow.current.IsShutdown() {
+		return overflow.current
+	}
+	overflow.current = nil
+	if overflow.handshake == nil || overflow.opening || overflow.closed || cc.IsShutdown() {
+		return nil
+	}
+
+	overflow.opening = true
+	cc.clientHandlerRequestWg.Add(1)
+	go func() {
+		defer cc.clientHandlerRequestWg.Done()
+		cc.openStreamIdOverflowConnector(overflow.handshake)
+	}()
+	return nil
+}
+
+func (cc *ClusterConnector) openStreamIdOverflowConnector(handshake connectionHandshake) {
+	cc.logger.Infof("[%s] All the stream ids of the request connection to %v are in use, "+
+		"opening a stream id overflow connection.", cc.connectorType, cc.clusterType)
+	connector, err := cc.newStreamIdOverflowConnector(handshake)
+
+	overflow := cc.streamIdOverflow
+	overflow.lock.Lock()
+	defer overflow.lock.Unlock()
+	overflow.opening = false
+	if err != nil {
+		cc.logger.Warnf("[%s] Could not open stream id overflow connection to %v: %v.", cc.connectorType, cc.clusterType, err)
+		return
+	}
+	if overflow.closed || cc.IsShutdown() {
+		connector.Shutdown()
+		return
+	}
+	connector.run()
+	overflow.current = connector
+	overflow.connectors = append(overflow.connectors, connector)
+}
+
+// newStreamIdOverflowConnector opens a connection to the host of this connector and replays the client handshake on
+// it. The overflow connector forwards the responses to the client handler like this connector but errors on its
+// connection only shut it down.
+func (cc *ClusterConnector) newStreamIdOverflowConnector(handshake connectionHandshake) (*ClusterConnector, error) {
+	conn, _, err := openConnectionToCluster(cc.connInfo, cc.clusterConnContext, cc.connectorType, cc.nodeMetrics, cc.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	err = handshake(conn, time.Duration(cc.connInfo.connConfig.GetConnectionTimeoutMs())*time.Millisecond)
+	if err != nil {
+		closeConnectionToCluster(conn, cc.clusterType, cc.connectorType, cc.nodeMetrics, cc.logger)
+		return nil, fmt.Errorf("could not initialize connection: %w", err)
+	}
+	return cc.newAdditionalConnector(conn, cc.conf.RequestConnectionMaxStreamIds)
+}
+
+// newAdditionalConnector returns a connector that sends requests on an additional connection of this connector, its
+// stream ids are always virtualized. It is used by the stream id overflow and alternate host connectors.
+func (cc *ClusterConnector) newAdditionalConnector(conn net.Conn, maxStreamIds int) (*ClusterConnector, error) {
+	nodeMetricsInstance, err := GetNodeMetricsByClusterConnector(cc.nodeMetrics, cc.connectorType)
+	if err != nil {
+		closeConnectionToCluster(conn, cc.clusterType, cc.connectorType, cc.nodeMetrics, cc.logger)
+		return nil, err
+	}
+	additionalConn := newMeteredConn(conn, nodeMetricsInstance.BytesReceived, nodeMetricsInstance.BytesSent)
+
+	// derived from the context of this connector so that the additional connection is closed with it
+	additionalConnCtx, additionalConnCancelFn := context.WithCancel(cc.clusterConnContext)
+	go func() {
+		<-additionalConnCtx.Done()
+		closeConnectionToCluster(additionalConn, cc.clusterType, cc.connectorType, cc.nodeMetrics, cc.logger)
+	}()
+
+	lastReadNanos := time.Now().UnixNano()
+	return &ClusterConnector{
+		conf:                   cc.conf,
+		connection:             additionalConn,
+		clusterType:            cc.clusterType,
+		connectorType:          cc.connectorType,
+		psCache:                cc.psCache,
+		nodeMetrics:            cc.nodeMetrics,
+		oversizedResponses:     cc.oversizedResponses,
+		responseWarnings:       cc.responseWarnings,
+		clientHandlerWg:        cc.clientHandlerWg,
+		clientHandlerRequestWg: cc.clientHandlerRequestWg,
+		clusterConnContext:     additionalConnCtx,
+		cancelFunc:             additionalConnCancelFn,
+		writeCoalescer: NewWriteCoalescer(
+			cc.conf,
+			additionalConn,
+			cc.clientHandlerWg,
+			additionalConnCtx,
+			additionalConnCancelFn,
+			string(cc.connectorType),
+			true,
+			false,
+			cc.writeScheduler,
+			cc.writeBufferPool,
+			cc.logger),
+		responseChan:                cc.responseChan,
+		responseReadBufferSizeBytes: cc.responseReadBufferSizeBytes,
+		doneChan:                    make(chan bool),
+		readScheduler:               cc.readScheduler,
+		readShard:                   cc.readScheduler.NextShard(),
+		asyncConnectorState:         ConnectorStateReady,
+		handshakeDone:               cc.handshakeDone,
+		heartbeatResponses:          make(chan *frame.RawFrame, 1),
+		lastReadNanos:               &lastReadNanos,
+		faultInjector:               cc.faultInjector,
+		versionTranslator:           cc.versionTranslator,
+		connInfo:                    cc.connInfo,
+		writeScheduler:              cc.writeScheduler,
+		writeBufferPool:             cc.writeBufferPool,
+		streamIds:                   newStreamIdMapper(maxStreamIds),
+		rejectedResponses:           cc.rejectedResponses,
+		logger:                      cc.logger,
+	}, nil
+}
+
+// retireStreamIdOverflowConnector stops sending requests on the current overflow connection, see streamIdOverflow.
+func (cc *ClusterConnector) retireStreamIdOverflowConnector() {
+	if cc.streamIdOverflow == nil {
+		return
+	}
+	overflow := cc.streamIdOverflow
+	overflow.lock.Lock()
+	defer overflow.lock.Unlock()
+	if overflow.current == nil {
+		return
+	}
+	if overflow.current.streamIds.retire() {
+		overflow.current.Shutdown()
+	}
+	overflow.current = nil
+}
+
+// closeStreamIdOverflowConnectors closes the write queues of the overflow connectors, it is called by the client
+// handler once no more requests are sent.
+func (cc *ClusterConnector) closeStreamIdOverflowConnectors() {
+	if cc.streamIdOverflow == nil {
+		return
+	}
+	overflow := cc.streamIdOverflow
+	overflow.lock.Lock()
+	overflow.closed = true
+	connectors := overflow.connectors
+	overflow.lock.Unlock()
+	for _, connector := range connectors {
+		connector.writeCoalescer.Close()
+	}
+}
+
+// waitForStreamIdOverflowConnectors waits until the overflow connectors stop sending responses to the client handler.
+// It is called when the response loop of this connector exits, the overflow connections are closed at that point
+// because their context is derived from the context of this connector.
+func (cc *ClusterConnector) waitForStreamIdOverflowConnectors() {
+	if cc.streamIdOverflow == nil {
+		return
+	}
+	overflow := cc.streamIdOverflow
+	overflow.lock.Lock()
+	overflow.closed = true
+	connectors := overflow.connectors
+	overflow.lock.Unlock()
+	for _, connector := range connectors {
+		connector.Shutdown()
+		<-connector.doneChan
+	}
+}
+
+// enableStreamIdOverflow is called when the client handshake is done, i.e., when the STARTUP request, the credentials
+// and the prepared statements that have to be replayed on the overflow connections are known.
+func (ch *ClientHandler) enableStreamIdOverflow() {
+	for _, connector := range []*ClusterConnector{ch.originCassandraConnector, ch.targetCassandraConnector} {
+		if connector.streamIdOverflow == nil {
+			continue
+		}
+		clusterType := connector.clusterType
+		connector.streamIdOverflow.lock.Lock()
+		connector.streamIdOverflow.handshake = func(conn net.Conn, timeout time.Duration) error {
+			return ch.replayHandshake(conn, clusterType, timeout)
+		}
+		connector.streamIdOverflow.lock.Unlock()
+	}
+}
+
+// sendRejectedResponse forwards the response of a request that a cluster connector did not send, see
+// ClusterConnector.rejectRequest. The response channel is closed once the response loops of the connectors exit so
+// this is checked like it is for the request timeouts.
+func (ch *ClientHandler) sendRejectedResponse(response *Response) {
+	go func() {
+		ch.closedRespChannelLock.RLock()
+		defer ch.closedRespChannelLock.RUnlock()
+		if !ch.closedRespChannel {
+			ch.respChannel <- response
+		}
+	}()
+}
@@ -0,0 +1,123 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestStreamIdMapper_AcquireRelease(t *testing.T) {
+	mapper := newStreamIdMapper(2)
+
+	// the same client stream id can be in use more than once, e.g. when a request is re-prepared
+	first, ok := mapper.acquire(100, primitive.ProtocolVersion4)
+	require.True(t, ok)
+	second, ok := mapper.acquire(100, primitive.ProtocolVersion4)
+	require.True(t, ok)
+	require.NotEqual(t, first, second)
+
+	_, ok = mapper.acquire(200, primitive.ProtocolVersion4)
+	require.False(t, ok)
+
+	assignment, ok, drained := mapper.release(second)
+	require.True(t, ok)
+	require.False(t, drained)
+	require.Equal(t, int16(100), assignment.clientStreamId)
+
+	_, ok, _ = mapper.release(second)
+	require.False(t, ok)
+
+	third, ok := mapper.acquire(200, primitive.ProtocolVersion4)
+	require.True(t, ok)
+	require.Equal(t, second, third)
+
+	assignment, ok, _ = mapper.release(third)
+	require.True(t, ok)
+	require.Equal(t, int16(200), assignment.clientStreamId)
+}
+
+func TestStreamIdMapper_ProtocolV2(t *testing.T) {
+	mapper := newStreamIdMapper(32768)
+	for i := 0; i < maxStreamIdsV2; i++ {
+		streamId, ok := mapper.acquire(int16(i), primitive.ProtocolVersion2)
+		require.True(t, ok)
+		require.Equal(t, int16(i), streamId)
+	}
+	_, ok := mapper.acquire(0, primitive.ProtocolVersion2)
+	require.False(t, ok)
+}
+
+func TestStreamIdMapper_Retire(t *testing.T) {
+	mapper := newStreamIdMapper(10)
+	require.True(t, newStreamIdMapper(10).retire())
+
+	first, ok := mapper.acquire(1, primitive.ProtocolVersion4)
+	require.True(t, ok)
+	second, ok := mapper.acquire(2, primitive.ProtocolVersion4)
+	require.True(t, ok)
+
+	require.False(t, mapper.retire())
+	_, ok = mapper.acquire(3, primitive.ProtocolVersion4)
+	require.False(t, ok)
+
+	_, ok, drained := mapper.release(first)
+	require.True(t, ok)
+	require.False(t, drained)
+	_, ok, drained = mapper.release(second)
+	require.True(t, ok)
+	require.True(t, drained)
+}
+
+func TestStreamIdMapper_ReleaseLost(t *testing.T) {
+	mapper := newStreamIdMapper(10)
+	lost, ok := mapper.acquire(1, primitive.ProtocolVersion4)
+	require.True(t, ok)
+	answered, ok := mapper.acquire(2, primitive.ProtocolVersion4)
+	require.True(t, ok)
+	inUse := mapper.inUse()
+	require.Equal(t, 2, len(inUse))
+
+	// the stream id of the answered request is assigned to a new request before the lost ones are released
+	_, ok, _ = mapper.release(answered)
+	require.True(t, ok)
+	reused, ok := mapper.acquire(3, primitive.ProtocolVersion4)
+	require.True(t, ok)
+	require.Equal(t, answered, reused)
+
+	require.Equal(t, 1, mapper.releaseLost(inUse))
+	_, ok, _ = mapper.release(lost)
+	require.False(t, ok)
+	assignment, ok, _ := mapper.release(reused)
+	require.True(t, ok)
+	require.Equal(t, int16(3), assignment.clientStreamId)
+}
+
+func TestClusterConnector_IsUseRequest(t *testing.T) {
+	psCache := NewPreparedStatementCache(10, log.NewEntry(log.StandardLogger()))
+	cc := &ClusterConnector{clusterType: common.ClusterTypeOrigin, psCache: psCache}
+	newRequest := func(msg message.Message) *frame.RawFrame {
+		f, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 1, msg))
+		require.Nil(t, err)
+		return f
+	}
+
+	require.True(t, cc.isUseRequest(newRequest(&message.Query{Query: "USE ks1"})))
+	require.True(t, cc.isUseRequest(newRequest(&message.Query{Query: "use \"Ks1\""})))
+	require.False(t, cc.isUseRequest(newRequest(&message.Query{Query: "SELECT * FROM ks1.tb1"})))
+	require.False(t, cc.isUseRequest(newRequest(&message.Prepare{Query: "USE ks1"})))
+	require.False(t, cc.isUseRequest(newRequest(&message.Execute{QueryId: []byte{1}})))
+
+	useRequestInfo := NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), nil, false, "USE ks1", "")
+	psCache.Store(
+		&message.PreparedResult{PreparedQueryId: []byte{2}}, &message.PreparedResult{PreparedQueryId: []byte{3}},
+		useRequestInfo)
+	require.True(t, cc.isUseRequest(newRequest(&message.Execute{QueryId: []byte{2}})))
+
+	cc.clusterType = common.ClusterTypeTarget
+	require.False(t, cc.isUseRequest(newRequest(&message.Execute{QueryId: []byte{2}})))
+	require.True(t, cc.isUseRequest(newRequest(&message.Execute{QueryId: []byte{3}})))
+}
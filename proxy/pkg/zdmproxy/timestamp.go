@@ -0,0 +1,27 @@
+package zdmproxy
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+var defaultWriteTimestampGenerator = &writeTimestampGenerator{}
+
+// writeTimestampGenerator generates client side write timestamps (microseconds since epoch).
+// Timestamps are strictly increasing so two writes sent by this proxy instance never share the same timestamp.
+type writeTimestampGenerator struct {
+	lastTimestamp int64
+}
+
+func (recv *writeTimestampGenerator) next() int64 {
+	for {
+		last := atomic.LoadInt64(&recv.lastTimestamp)
+		next := time.Now().UnixNano() / int64(time.Microsecond)
+		if next <= last {
+			next = last + 1
+		}
+		if atomic.CompareAndSwapInt64(&recv.lastTimestamp, last, next) {
+			return next
+		}
+	}
+}
@@ -5,43 +5,87 @@ import (
 	"crypto/x509"
 	"fmt"
 	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/secrets"
 	log "github.com/sirupsen/logrus"
 	"io/ioutil"
 	"runtime"
 )
 
-func loadTlsFile(filePath string) ([]byte, error) {
-	var file []byte
-	var err error
-	if filePath != "" {
-		file, err = ioutil.ReadFile(filePath)
-		if err != nil {
-			return nil, fmt.Errorf("could not load file with path %s due to: %v", filePath, err)
+// loadTlsFiles returns the contents of the files at filePaths. The paths that refer to secrets return the latest values
+// of those secrets, all of them from the same refresh.
+func loadTlsFiles(secretStore *secrets.Store, filePaths ...string) ([][]byte, error) {
+	secretValues := secretStore.GetAll(filePaths...)
+	files := make([][]byte, len(filePaths))
+	for i, filePath := range filePaths {
+		if secrets.IsReference(filePath) {
+			if secretValues[i] == nil {
+				return nil, fmt.Errorf("secret %s was not resolved", filePath)
+			}
+			files[i] = secretValues[i]
+		} else if filePath != "" {
+			file, err := ioutil.ReadFile(filePath)
+			if err != nil {
+				return nil, fmt.Errorf("could not load file with path %s due to: %v", filePath, err)
+			}
+			files[i] = file
+		}
+	}
+	return files, nil
+}
+
+func anyTlsSecret(filePaths ...string) bool {
+	for _, filePath := range filePaths {
+		if secrets.IsReference(filePath) {
+			return true
 		}
 	}
-	return file, err
+	return false
 }
 
-func getClientSideTlsConfigFromProxyClusterTlsConfig(clusterTlsConfig *common.ClusterTlsConfig, clusterType common.ClusterType) (*tls.Config, error) {
+func getClientSideTlsConfigFromProxyClusterTlsConfig(
+	clusterTlsConfig *common.ClusterTlsConfig, clusterType common.ClusterType, secretStore *secrets.Store,
+	logger *log.Entry) (*tls.Config, error) {
 	// create tls config object using the values provided in the cluster security config
-	serverCAFile, err := loadTlsFile(clusterTlsConfig.ServerCaPath)
-	if err != nil {
-		return nil, err
+	loadTlsConfig := func() (*tls.Config, error) {
+		files, err := loadTlsFiles(secretStore,
+			clusterTlsConfig.ServerCaPath, clusterTlsConfig.ClientCertPath, clusterTlsConfig.ClientKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		// currently not supporting server hostname verification for non-Astra clusters
+		return getClientSideTlsConfig(files[0], files[1], files[2], "", "", clusterType, logger)
 	}
-	clientCertFile, err := loadTlsFile(clusterTlsConfig.ClientCertPath)
+	tlsConfig, err := loadTlsConfig()
 	if err != nil {
 		return nil, err
 	}
-	clientKeyFile, err := loadTlsFile(clusterTlsConfig.ClientKeyPath)
-	if err != nil {
-		return nil, err
+
+	if anyTlsSecret(clusterTlsConfig.ServerCaPath, clusterTlsConfig.ClientCertPath, clusterTlsConfig.ClientKeyPath) {
+		// load the CA, the certificate and the key on every handshake so that new connections use rotated secrets
+		tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			latestTlsConfig, err := loadTlsConfig()
+			if err != nil {
+				return err
+			}
+			return latestTlsConfig.VerifyConnection(cs)
+		}
+		if len(tlsConfig.Certificates) > 0 {
+			tlsConfig.Certificates = nil
+			tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				latestTlsConfig, err := loadTlsConfig()
+				if err != nil {
+					return nil, err
+				}
+				return &latestTlsConfig.Certificates[0], nil
+			}
+		}
 	}
-	// currently not supporting server hostname verification for non-Astra clusters
-	return getClientSideTlsConfig(serverCAFile, clientCertFile, clientKeyFile, "", "", clusterType)
+	return tlsConfig, nil
 }
 
 func getClientSideTlsConfig(
-	caCert []byte, cert []byte, key []byte, serverName string, dnsName string, clusterType common.ClusterType) (*tls.Config, error) {
+	caCert []byte, cert []byte, key []byte, serverName string, dnsName string, clusterType common.ClusterType,
+	logger *log.Entry) (*tls.Config, error) {
 
 	rootCAs, err := x509.SystemCertPool()
 	if err != nil {
@@ -61,14 +105,14 @@ func getClientSideTlsConfig(
 		}
 	} else {
 		// this should be caught when validating the configuration
-		log.Warnf("Using TLS for %s, but CA cert was not specified", clusterType)
+		logger.Warnf("Using TLS for %s, but CA cert was not specified", clusterType)
 	}
 
 	var clientCerts []tls.Certificate
 	if cert == nil && key == nil {
-		log.Debugf("Using one-way TLS for %s.", clusterType)
+		logger.Debugf("Using one-way TLS for %s.", clusterType)
 	} else {
-		log.Debugf("Using mutual TLS for %s.", clusterType)
+		logger.Debugf("Using mutual TLS for %s.", clusterType)
 		// if using mTLS, both client cert and client key have to be specified
 		if cert == nil {
 			return nil, fmt.Errorf("using mutual TLS for %s, but Client certificate was not specified", clusterType)
@@ -124,26 +168,34 @@ func getClientSideVerifyConnectionCallback(certificateDnsName string, rootCAs *x
 	}
 }
 
-func getServerSideTlsConfigFromProxyClusterTlsConfig(proxyTlsConfig *common.ProxyTlsConfig) (*tls.Config, error) {
+func getServerSideTlsConfigFromProxyClusterTlsConfig(
+	proxyTlsConfig *common.ProxyTlsConfig, secretStore *secrets.Store, logger *log.Entry) (*tls.Config, error) {
 	// create tls config object using the values provided in the cluster security config
-	proxyCaFile, err := loadTlsFile(proxyTlsConfig.ProxyCaPath)
-	if err != nil {
-		return nil, err
+	loadTlsConfig := func() (*tls.Config, error) {
+		files, err := loadTlsFiles(secretStore,
+			proxyTlsConfig.ProxyCaPath, proxyTlsConfig.ProxyCertPath, proxyTlsConfig.ProxyKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		// currently not supporting server hostname verification for client connections
+		return getServerSideTlsConfig(files[0], files[1], files[2], proxyTlsConfig.ClientAuth, logger)
 	}
-	proxyCertFile, err := loadTlsFile(proxyTlsConfig.ProxyCertPath)
+	tlsConfig, err := loadTlsConfig()
 	if err != nil {
 		return nil, err
 	}
-	proxyCertKey, err := loadTlsFile(proxyTlsConfig.ProxyKeyPath)
-	if err != nil {
-		return nil, err
+
+	if anyTlsSecret(proxyTlsConfig.ProxyCaPath, proxyTlsConfig.ProxyCertPath, proxyTlsConfig.ProxyKeyPath) {
+		// build the configuration of every handshake from the latest CA, certificate and key
+		tlsConfig.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return loadTlsConfig()
+		}
 	}
-	// currently not supporting server hostname verification for client connections
-	return getServerSideTlsConfig(proxyCaFile, proxyCertFile, proxyCertKey, proxyTlsConfig.ClientAuth)
+	return tlsConfig, nil
 }
 
 func getServerSideTlsConfig(
-	caCert []byte, cert []byte, key []byte, clientAuth bool) (*tls.Config, error) {
+	caCert []byte, cert []byte, key []byte, clientAuth bool, logger *log.Entry) (*tls.Config, error) {
 
 	// if proxy TLS is used, proxy CA must always be specified
 	rootCAs := x509.NewCertPool()
@@ -159,9 +211,9 @@ func getServerSideTlsConfig(
 	serverCerts := []tls.Certificate{serverCert}
 
 	if clientAuth {
-		log.Debug("Using mutual TLS for client connections.")
+		logger.Debug("Using mutual TLS for client connections.")
 	} else {
-		log.Debug("Using one-way TLS for client connections.")
+		logger.Debug("Using one-way TLS for client connections.")
 	}
 
 	return getServerSideTlsConfigFromParsedCerts(rootCAs, serverCerts, clientAuth), nil
@@ -0,0 +1,119 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/google/uuid"
+)
+
+type TopologyEventType string
+
+const (
+	TopologyEventHostAdded   = TopologyEventType("HOST_ADDED")
+	TopologyEventHostRemoved = TopologyEventType("HOST_REMOVED")
+	TopologyEventHostUp      = TopologyEventType("HOST_UP")
+	TopologyEventHostDown    = TopologyEventType("HOST_DOWN")
+)
+
+// TopologyEvent is a change of the topology of ORIGIN or TARGET detected by its control connection.
+//
+// Added and removed hosts are detected when the control connection refreshes the topology (after a TOPOLOGY_CHANGE
+// event or a reconnection), hosts that go up or down are reported by the STATUS_CHANGE events of the cluster.
+type TopologyEvent struct {
+	Cluster common.ClusterType
+	Type    TopologyEventType
+	Host    *Host
+}
+
+// TopologyEventListener is called by the control connection for every topology event of its cluster.
+// It must not block, events are delivered sequentially from the goroutines of the control connection.
+type TopologyEventListener func(event *TopologyEvent)
+
+// SubscribeToTopologyEvents registers a listener that is called for every topology change of the cluster
+// detected after this call. The returned function unsubscribes the listener.
+func (cc *ControlConn) SubscribeToTopologyEvents(listener TopologyEventListener) (unsubscribe func()) {
+	cc.topologyLock.Lock()
+	defer cc.topologyLock.Unlock()
+	id := cc.nextTopologyListenerId
+	cc.nextTopologyListenerId++
+	cc.topologyListeners[id] = listener
+	return func() {
+		cc.topologyLock.Lock()
+		defer cc.topologyLock.Unlock()
+		delete(cc.topologyListeners, id)
+	}
+}
+
+// notifyTopologyListeners must be called without holding topologyLock so that listeners can use the ControlConn.
+func (cc *ControlConn) notifyTopologyListeners(events []*TopologyEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	cc.topologyLock.RLock()
+	listeners := make([]TopologyEventListener, 0, len(cc.topologyListeners))
+	for _, listener := range cc.topologyListeners {
+		listeners = append(listeners, listener)
+	}
+	cc.topologyLock.RUnlock()
+
+	for _, event := range events {
+		cc.logger.Debugf("Topology event from %v: %v %v", event.Cluster, event.Type, event.Host)
+		for _, listener := range listeners {
+			listener(event)
+		}
+	}
+}
+
+// computeHostChanges returns the topology events of the hosts that were added or removed between two refreshes,
+// nothing is returned for the first refresh of the topology.
+func computeHostChanges(
+	clusterType common.ClusterType, oldHostsById map[uuid.UUID]*Host, newHostsById map[uuid.UUID]*Host) []*TopologyEvent {
+	if len(oldHostsById) == 0 {
+		return nil
+	}
+
+	events := make([]*TopologyEvent, 0)
+	for id, h := range newHostsById {
+		if _, found := oldHostsById[id]; !found {
+			events = append(events, &TopologyEvent{Cluster: clusterType, Type: TopologyEventHostAdded, Host: h})
+		}
+	}
+	for id, h := range oldHostsById {
+		if _, found := newHostsById[id]; !found {
+			events = append(events, &TopologyEvent{Cluster: clusterType, Type: TopologyEventHostRemoved, Host: h})
+		}
+	}
+	return events
+}
+
+func (cc *ControlConn) handleStatusChangeEvent(event *message.StatusChangeEvent) {
+	var eventType TopologyEventType
+	switch event.ChangeType {
+	case primitive.StatusChangeTypeUp:
+		eventType = TopologyEventHostUp
+	case primitive.StatusChangeTypeDown:
+		eventType = TopologyEventHostDown
+	default:
+		return
+	}
+
+	var host *Host
+	cc.topologyLock.RLock()
+	for _, h := range cc.hostsInLocalDcById {
+		if h.Address.Equal(event.Address.Addr) && h.Port == int(event.Address.Port) {
+			host = h
+			break
+		}
+	}
+	cc.topologyLock.RUnlock()
+
+	if host == nil {
+		cc.logger.Debugf("Received status change event from %v for an unknown host, skipping: %v",
+			cc.connConfig.GetClusterType(), event)
+		return
+	}
+
+	cc.notifyTopologyListeners([]*TopologyEvent{{Cluster: cc.connConfig.GetClusterType(), Type: eventType, Host: host}})
+}
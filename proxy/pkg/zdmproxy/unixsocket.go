@@ -0,0 +1,38 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// listenOnUnixSocket creates the unix socket that is used by clients running on the same host (e.g. sidecar
+// deployments) and applies the configured permissions to it. The socket file is removed when the listener is closed.
+//
+// A socket file left behind by a proxy instance that didn't shut down cleanly is removed but any other kind of file
+// at the same path is left untouched and an error is returned.
+func listenOnUnixSocket(path string, permissions os.FileMode) (net.Listener, error) {
+	fileInfo, err := os.Lstat(path)
+	if err == nil {
+		if fileInfo.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("could not listen on unix socket %v: file exists and it is not a socket", path)
+		}
+		if err = os.Remove(path); err != nil {
+			return nil, fmt.Errorf("could not remove stale unix socket %v: %w", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("could not listen on unix socket %v: %w", path, err)
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.Chmod(path, permissions)
+	if err != nil {
+		l.Close()
+		return nil, fmt.Errorf("could not set permissions of unix socket %v: %w", path, err)
+	}
+	return l, nil
+}
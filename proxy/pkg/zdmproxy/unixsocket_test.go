@@ -0,0 +1,46 @@
+package zdmproxy
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenOnUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zdm-proxy-socket")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "zdm-proxy.sock")
+
+	l, err := listenOnUnixSocket(path, 0600)
+	require.Nil(t, err)
+	fileInfo, err := os.Stat(path)
+	require.Nil(t, err)
+	require.Equal(t, os.FileMode(0600), fileInfo.Mode().Perm())
+
+	conn, err := net.Dial("unix", path)
+	require.Nil(t, err)
+	conn.Close()
+
+	require.Nil(t, l.Close())
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+
+	// a stale socket file is replaced
+	staleListener, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	require.Nil(t, err)
+	staleListener.SetUnlinkOnClose(false)
+	require.Nil(t, staleListener.Close())
+	l, err = listenOnUnixSocket(path, 0660)
+	require.Nil(t, err)
+	require.Nil(t, l.Close())
+
+	// other files are not removed
+	require.Nil(t, ioutil.WriteFile(path, []byte("data"), 0600))
+	_, err = listenOnUnixSocket(path, 0660)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "it is not a socket")
+}